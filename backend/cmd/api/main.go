@@ -3,13 +3,21 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/quocdaijr/finance-management-backend/internal/api/handlers"
 	"github.com/quocdaijr/finance-management-backend/internal/api/routes"
 	"github.com/quocdaijr/finance-management-backend/internal/config"
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
 	"github.com/quocdaijr/finance-management-backend/internal/domain/services"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure"
 	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/database"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/exchangerates"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/idempotency"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/migrations"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/prices"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/storage"
+	"github.com/quocdaijr/finance-management-backend/internal/jobs"
 	"github.com/quocdaijr/finance-management-backend/internal/repository"
 	infraServices "github.com/quocdaijr/finance-management-backend/internal/services"
 )
@@ -24,11 +32,25 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// Convert legacy float amount columns before AutoMigrate adds the minor-unit columns
+	if err := migrations.RunMoneyMigrations(db); err != nil {
+		log.Fatal("Failed to run money migrations:", err)
+	}
+
 	// Run database migrations
 	err = db.AutoMigrate(
 		&models.User{},
 		&models.Account{},
 		&models.Transaction{},
+		&models.TransactionSplit{},
+		&models.Tag{},
+		&models.Payee{},
+		&models.PayeeAlias{},
+		&models.Rule{},
+		&models.DuplicateDismissal{},
+		&models.ImportProfile{},
+		&models.Attachment{},
+		&models.ExchangeRate{},
 		&models.Budget{},
 		&models.PasswordResetToken{},
 		&models.EmailVerificationToken{},
@@ -37,6 +59,19 @@ func main() {
 		&models.Notification{},
 		&models.Category{},
 		&models.BalanceHistory{},
+		&models.JournalEntry{},
+		&models.Posting{},
+		&models.Reconciliation{},
+		&models.CreditCard{},
+		&models.CreditCardStatement{},
+		&models.Loan{},
+		&models.LoanPayment{},
+		&models.Security{},
+		&models.SecurityPrice{},
+		&models.InvestmentTransaction{},
+		&models.Lot{},
+		&models.RealizedGain{},
+		&models.IdempotencyKey{},
 		// Sprint 4/5: Multi-user collaboration models
 		&models.Organization{},
 		&models.Department{},
@@ -65,6 +100,26 @@ func main() {
 		log.Printf("Warning: Failed to seed database: %v", err)
 	}
 
+	// Backfill opening balance entries for accounts created before the ledger
+	if err := migrations.RunLedgerMigrations(db); err != nil {
+		log.Fatal("Failed to run ledger migrations:", err)
+	}
+
+	// Fill in the current and available balances of accounts synced before they were tracked
+	if err := migrations.RunTransactionStatusMigrations(db); err != nil {
+		log.Fatal("Failed to run transaction status migrations:", err)
+	}
+
+	// Record the original amount and currency of transactions made before they were tracked
+	if err := migrations.RunTransactionCurrencyMigrations(db); err != nil {
+		log.Fatal("Failed to run transaction currency migrations:", err)
+	}
+
+	// Move comma-separated tags to tags and their join tables
+	if err := migrations.RunTagMigrations(db); err != nil {
+		log.Fatal("Failed to run tag migrations:", err)
+	}
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	accountRepo := repository.NewAccountRepository(db)
@@ -76,6 +131,8 @@ func main() {
 	notificationRepo := repository.NewNotificationRepository(db)
 	categoryRepo := repository.NewCategoryRepository(db)
 	balanceHistoryRepo := repository.NewBalanceHistoryRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	reconciliationRepo := repository.NewReconciliationRepository(db)
 	taxRepo := repository.NewTaxRepository(db)
 	reportRepo := repository.NewReportRepository(db)
 	// Sprint 5: Collaboration repositories
@@ -89,6 +146,30 @@ func main() {
 	userRoleRepo := repository.NewUserRoleRepository(db)
 	auditRepo := repository.NewPermissionAuditLogRepository(db)
 	activityLogRepo := repository.NewActivityLogRepository(db)
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	exchangeRateRepo := repository.NewExchangeRateRepository(db)
+	creditCardRepo := repository.NewCreditCardRepository(db)
+	loanRepo := repository.NewLoanRepository(db)
+	investmentRepo := repository.NewInvestmentRepository(db)
+	securityPriceRepo := repository.NewSecurityPriceRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	payeeRepo := repository.NewPayeeRepository(db)
+	ruleRepo := repository.NewRuleRepository(db)
+	duplicateRepo := repository.NewDuplicateRepository(db)
+	importProfileRepo := repository.NewImportProfileRepository(db)
+
+	// Initialize attachment storage
+	blobStore, err := storage.NewLocalBlobStore(cfg.AttachmentDir)
+	if err != nil {
+		log.Fatal("Failed to initialize attachment storage:", err)
+	}
+	if cfg.AttachmentSigningSecret == "" {
+		log.Fatal("ATTACHMENT_SIGNING_SECRET must be set to sign attachment download links")
+	}
+
+	// Initialize idempotency key storage
+	idempotencyStore := newIdempotencyStore(cfg, idempotencyRepo)
 
 	// Initialize email service
 	environment := os.Getenv("ENVIRONMENT")
@@ -109,18 +190,33 @@ func main() {
 	// Initialize services
 	userService := services.NewUserService(userRepo)
 	authService := services.NewAuthService(userRepo, tokenRepo, emailService, cfg)
-	accountService := services.NewAccountService(accountRepo)
-	transactionService := services.NewTransactionService(transactionRepo, accountRepo, db)
-	budgetService := services.NewBudgetService(budgetRepo)
-	exportService := infraServices.NewExportService(transactionRepo, accountRepo, budgetRepo)
-	importService := infraServices.NewImportService(transactionRepo, accountRepo)
-	recurringService := services.NewRecurringTransactionService(recurringRepo, transactionRepo, accountRepo)
-	goalService := services.NewGoalService(goalRepo, accountRepo)
+	ledgerService := services.NewLedgerService(ledgerRepo, accountRepo, balanceHistoryRepo)
+	currencyService := services.NewCurrencyService(userRepo, exchangeRateRepo, exchangeRateProviders(cfg)...)
+	accountService := services.NewAccountService(accountRepo, ledgerRepo, ledgerService, currencyService, db)
+	transactionService := services.NewTransactionService(transactionRepo, accountRepo, tagRepo, payeeRepo, ruleRepo, ledgerService, currencyService, db)
+	reconciliationService := services.NewReconciliationService(reconciliationRepo, accountRepo, ledgerService, db)
+	creditCardService := services.NewCreditCardService(creditCardRepo, accountRepo, ledgerRepo, notificationRepo, currencyService)
+	loanService := services.NewLoanService(loanRepo, accountRepo, ledgerService, db)
+	investmentService := services.NewInvestmentService(investmentRepo, securityPriceRepo, accountRepo, taxRepo, ledgerService, currencyService, db, priceProviders(cfg)...)
+	balanceAuditService := services.NewBalanceAuditService(accountRepo, transactionRepo, ledgerRepo, ledgerService, db)
+	attachmentService := services.NewAttachmentService(attachmentRepo, transactionRepo, blobStore, []byte(cfg.AttachmentSigningSecret))
+	trashService := services.NewTrashService(transactionService, attachmentService, transactionRepo, accountRepo, budgetRepo, goalRepo, recurringRepo,
+		time.Duration(cfg.TrashRetentionDays)*24*time.Hour)
+	budgetService := services.NewBudgetService(budgetRepo, currencyService)
+	exportService := infraServices.NewExportService(transactionRepo, accountRepo, budgetRepo, attachmentRepo, categoryRepo, investmentRepo, blobStore)
+	importService := infraServices.NewImportService(transactionRepo, accountRepo, tagRepo, payeeRepo, ruleRepo, categoryRepo, importProfileRepo, ledgerRepo, balanceHistoryRepo, investmentService, db)
+	recurringService := services.NewRecurringTransactionService(recurringRepo, transactionRepo, accountRepo, tagRepo, ruleRepo, ledgerService, db)
+	goalService := services.NewGoalService(goalRepo, accountRepo, ledgerService, db)
 	notificationService := services.NewNotificationService(notificationRepo, budgetRepo, goalRepo, recurringRepo)
 	categoryService := services.NewCategoryService(categoryRepo)
-	budgetAlertService := services.NewBudgetAlertService(budgetRepo, transactionRepo, notificationRepo)
+	tagService := services.NewTagService(tagRepo)
+	payeeService := services.NewPayeeService(payeeRepo, currencyService)
+	ruleService := services.NewRuleService(ruleRepo, tagRepo, transactionRepo, accountRepo, transactionService, db)
+	importProfileService := services.NewImportProfileService(importProfileRepo, accountRepo)
+	duplicateService := services.NewDuplicateService(transactionRepo, duplicateRepo, transactionService, db)
+	budgetAlertService := services.NewBudgetAlertService(budgetRepo, transactionRepo, notificationRepo, currencyService)
 	searchService := services.NewSearchService(transactionRepo, accountRepo, budgetRepo, goalRepo)
-	taxService := services.NewTaxService(taxRepo)
+	taxService := services.NewTaxService(taxRepo, currencyService)
 	reportService := services.NewReportService(reportRepo)
 	// Sprint 5: Collaboration services
 	permissionService := services.NewPermissionService(accountMemberRepo, roleRepo, userRoleRepo, auditRepo)
@@ -132,16 +228,29 @@ func main() {
 	userHandler := handlers.NewUserHandler(userService)
 	authHandler := handlers.NewAuthHandler(authService)
 	accountHandler := handlers.NewAccountHandler(accountService)
-	transactionHandler := handlers.NewTransactionHandler(transactionService, budgetAlertService)
+	transactionHandler := handlers.NewTransactionHandler(transactionService, budgetAlertService, duplicateService)
 	budgetHandler := handlers.NewBudgetHandler(budgetService)
 	exportHandler := handlers.NewExportHandler(exportService)
 	importHandler := handlers.NewImportHandler(importService)
+	importProfileHandler := handlers.NewImportProfileHandler(importProfileService)
 	recurringHandler := handlers.NewRecurringTransactionHandler(recurringService)
 	goalHandler := handlers.NewGoalHandler(goalService)
 	notificationHandler := handlers.NewNotificationHandler(notificationService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	payeeHandler := handlers.NewPayeeHandler(payeeService)
+	ruleHandler := handlers.NewRuleHandler(ruleService)
+	duplicateHandler := handlers.NewDuplicateHandler(duplicateService)
 	balanceHistoryHandler := handlers.NewBalanceHistoryHandler(balanceHistoryRepo, accountRepo)
-	currencyHandler := handlers.NewCurrencyHandler()
+	currencyHandler := handlers.NewCurrencyHandler(currencyService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	reconciliationHandler := handlers.NewReconciliationHandler(reconciliationService)
+	trashHandler := handlers.NewTrashHandler(trashService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService)
+	creditCardHandler := handlers.NewCreditCardHandler(creditCardService)
+	loanHandler := handlers.NewLoanHandler(loanService)
+	investmentHandler := handlers.NewInvestmentHandler(investmentService)
+	adminHandler := handlers.NewAdminHandler(balanceAuditService)
 	searchHandler := handlers.NewSearchHandler(searchService)
 	taxHandler := handlers.NewTaxHandler(taxService)
 	reportHandler := handlers.NewReportHandler(reportService)
@@ -153,6 +262,7 @@ func main() {
 	// Setup router with all routes
 	router := routes.SetupRouter(&routes.RouterConfig{
 		Config:                cfg,
+		IdempotencyStore:      idempotencyStore,
 		AuthHandler:           authHandler,
 		AccountHandler:        accountHandler,
 		TransactionHandler:    transactionHandler,
@@ -160,12 +270,25 @@ func main() {
 		UserHandler:           userHandler,
 		ExportHandler:         exportHandler,
 		ImportHandler:         importHandler,
+		ImportProfileHandler:  importProfileHandler,
 		RecurringHandler:      recurringHandler,
 		GoalHandler:           goalHandler,
 		NotificationHandler:   notificationHandler,
 		CategoryHandler:       categoryHandler,
 		BalanceHistoryHandler: balanceHistoryHandler,
 		CurrencyHandler:       currencyHandler,
+		LedgerHandler:         ledgerHandler,
+		ReconciliationHandler: reconciliationHandler,
+		TrashHandler:          trashHandler,
+		AttachmentHandler:     attachmentHandler,
+		CreditCardHandler:     creditCardHandler,
+		LoanHandler:           loanHandler,
+		InvestmentHandler:     investmentHandler,
+		AdminHandler:          adminHandler,
+		TagHandler:            tagHandler,
+		PayeeHandler:          payeeHandler,
+		RuleHandler:           ruleHandler,
+		DuplicateHandler:      duplicateHandler,
 		SearchHandler:         searchHandler,
 		TaxHandler:            taxHandler,
 		ReportHandler:         reportHandler,
@@ -175,9 +298,52 @@ func main() {
 		SharingHandler:        sharingHandler,
 	})
 
+	// Start background jobs
+	scheduler := jobs.NewScheduler()
+	scheduler.Register("post scheduled transactions", 15*time.Minute, transactionService.PostDueScheduled)
+	scheduler.Register("purge expired trash", time.Hour, trashService.PurgeExpired)
+	scheduler.Register("post loan payments", time.Hour, loanService.PostDuePayments)
+	scheduler.Register("close credit card statements", time.Hour, creditCardService.GenerateStatements)
+	scheduler.Register("refresh exchange rates", time.Duration(cfg.ExchangeRateRefreshHours)*time.Hour, currencyService.RefreshRates)
+	scheduler.Register("refresh security prices", time.Duration(cfg.PriceRefreshHours)*time.Hour, investmentService.RefreshPrices)
+	scheduler.Register("purge expired idempotency keys", time.Hour, idempotency.NewDBStore(idempotencyRepo).PurgeExpired)
+	scheduler.Start()
+	defer scheduler.Stop()
+
 	// Start server
 	log.Println("Server starting on :8080")
 	if err := router.Run(":8080"); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// exchangeRateProviders returns the configured sources of exchange rates
+func exchangeRateProviders(cfg *config.Config) []exchangerates.Provider {
+	var providers []exchangerates.Provider
+	if cfg.ExchangeRateFile != "" {
+		providers = append(providers, exchangerates.NewFileProvider(cfg.ExchangeRateFile))
+	}
+	if cfg.ExchangeRateProviderURL != "" {
+		providers = append(providers, exchangerates.NewHTTPProvider(cfg.ExchangeRateProviderURL))
+	}
+	return providers
+}
+
+// priceProviders returns the configured sources of security prices
+func priceProviders(cfg *config.Config) []prices.Provider {
+	var providers []prices.Provider
+	if cfg.PriceFile != "" {
+		providers = append(providers, prices.NewFileProvider(cfg.PriceFile))
+	}
+	return providers
+}
+
+// newIdempotencyStore keeps idempotency keys in Redis, or in the database when Redis is unreachable
+func newIdempotencyStore(cfg *config.Config, repo *repository.IdempotencyRepository) idempotency.Store {
+	redisClient, err := infrastructure.NewRedisClient(cfg)
+	if err != nil {
+		log.Println("Idempotency keys will be stored in the database")
+		return idempotency.NewDBStore(repo)
+	}
+	return idempotency.NewRedisStore(redisClient)
+}
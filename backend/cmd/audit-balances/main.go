@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/quocdaijr/finance-management-backend/internal/config"
+	"github.com/quocdaijr/finance-management-backend/internal/domain/services"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/database"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+)
+
+// Recomputes account balances from their opening balance and transactions and
+// reports accounts that have drifted. Exits with status 1 when drift remains.
+//
+//	go run ./cmd/audit-balances                # report all accounts
+//	go run ./cmd/audit-balances -user 42       # report accounts of one user
+//	go run ./cmd/audit-balances -fix           # write correction entries
+func main() {
+	userID := flag.Uint("user", 0, "only audit accounts of this user ID (0 = all users)")
+	fix := flag.Bool("fix", false, "write correction entries for drifted accounts")
+	flag.Parse()
+
+	// Load config
+	cfg := config.LoadConfig()
+
+	// Initialize DB
+	db, err := database.NewPostgresDB(cfg)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	accountRepo := repository.NewAccountRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	ledgerService := services.NewLedgerService(ledgerRepo, accountRepo, repository.NewBalanceHistoryRepository(db))
+	auditService := services.NewBalanceAuditService(accountRepo, repository.NewTransactionRepository(db), ledgerRepo, ledgerService, db)
+
+	report, err := auditService.Audit(*userID, *fix)
+	if err != nil {
+		log.Fatal("Failed to audit balances:", err)
+	}
+
+	for _, drift := range report.Drifted {
+		log.Printf("account %d (user %d, %s): stored %.2f, ledger %.2f, expected %.2f %s, correction %.2f",
+			drift.AccountID, drift.UserID, drift.Name, drift.StoredBalance, drift.LedgerBalance,
+			drift.ExpectedBalance, drift.Currency, drift.Correction)
+	}
+	log.Printf("Checked %d accounts, %d drifted", report.AccountsChecked, len(report.Drifted))
+
+	if len(report.Drifted) > 0 && !report.Fixed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/quocdaijr/finance-management-backend/internal/config"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/database"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/migrations"
+)
+
+// Rebuilds balance history from existing transactions and ledger adjustments.
+//
+//	go run ./cmd/backfill-balance-history            # all accounts
+//	go run ./cmd/backfill-balance-history -user 42   # accounts of one user
+func main() {
+	userID := flag.Uint("user", 0, "only backfill accounts of this user ID (0 = all users)")
+	flag.Parse()
+
+	// Load config
+	cfg := config.LoadConfig()
+
+	// Initialize DB
+	db, err := database.NewPostgresDB(cfg)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	if _, err := migrations.BackfillBalanceHistory(db, *userID); err != nil {
+		log.Fatal("Failed to backfill balance history:", err)
+	}
+}
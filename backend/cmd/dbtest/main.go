@@ -30,6 +30,12 @@ func main() {
 		&models.User{},
 		&models.Account{},
 		&models.Transaction{},
+		&models.Tag{},
+		&models.Payee{},
+		&models.PayeeAlias{},
+		&models.Rule{},
+		&models.DuplicateDismissal{},
+		&models.ImportProfile{},
 		&models.Budget{},
 	)
 	if err != nil {
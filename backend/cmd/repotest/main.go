@@ -25,6 +25,12 @@ func main() {
 		&models.User{},
 		&models.Account{},
 		&models.Transaction{},
+		&models.Tag{},
+		&models.Payee{},
+		&models.PayeeAlias{},
+		&models.Rule{},
+		&models.DuplicateDismissal{},
+		&models.ImportProfile{},
 		&models.Budget{},
 	)
 	if err != nil {
@@ -79,14 +85,14 @@ func main() {
 		fmt.Printf("Found %d budgets for user %s\n", len(budgets), user.Username)
 
 		// Test summary operations
-		accountSummary, err := accountRepo.GetSummary(user.ID)
+		accountSummary, err := accountRepo.GetSummary(user.ID, "USD", models.StaticExchangeRates{})
 		if err != nil {
 			log.Fatal("Failed to get account summary:", err)
 		}
 		fmt.Printf("Account Summary - Total Assets: $%.2f, Net Worth: $%.2f\n", 
 			accountSummary.TotalAssets, accountSummary.NetWorth)
 
-		budgetSummary, err := budgetRepo.GetSummary(user.ID)
+		budgetSummary, err := budgetRepo.GetSummary(user.ID, "USD", models.StaticExchangeRates{})
 		if err != nil {
 			log.Fatal("Failed to get budget summary:", err)
 		}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -154,6 +155,10 @@ func (h *AccountHandler) Delete(c *gin.Context) {
 
 	// Delete account
 	if err := h.accountService.Delete(uint(id), userID); err != nil {
+		if errors.Is(err, models.ErrAccountHasTransactions) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocdaijr/finance-management-backend/internal/domain/services"
+)
+
+// AdminHandler handles HTTP requests for system maintenance
+type AdminHandler struct {
+	balanceAuditService *services.BalanceAuditService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(balanceAuditService *services.BalanceAuditService) *AdminHandler {
+	return &AdminHandler{
+		balanceAuditService: balanceAuditService,
+	}
+}
+
+// AuditBalances handles reporting accounts whose balance has drifted from their transactions
+func (h *AdminHandler) AuditBalances(c *gin.Context) {
+	h.auditBalances(c, false)
+}
+
+// FixBalances handles writing correction entries for drifted accounts
+func (h *AdminHandler) FixBalances(c *gin.Context) {
+	h.auditBalances(c, true)
+}
+
+// auditBalances audits the accounts of the user_id query parameter, or of every user without it
+func (h *AdminHandler) auditBalances(c *gin.Context, fix bool) {
+	var userID uint
+	if value := c.Query("user_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		userID = uint(id)
+	}
+
+	report, err := h.balanceAuditService.Audit(userID, fix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
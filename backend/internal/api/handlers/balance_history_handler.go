@@ -62,7 +62,7 @@ func (h *BalanceHistoryHandler) GetAccountHistory(c *gin.Context) {
 
 	var response []interface{}
 	for _, h := range history {
-		response = append(response, h.ToResponse())
+		response = append(response, h.ToResponse(account.Currency))
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -96,7 +96,7 @@ func (h *BalanceHistoryHandler) GetDailyBalances(c *gin.Context) {
 		}
 	}
 
-	balances, err := h.balanceHistoryRepo.GetDailyBalances(userID, uint(accountID), days)
+	balances, err := h.balanceHistoryRepo.GetDailyBalances(userID, uint(accountID), account.Currency, days)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
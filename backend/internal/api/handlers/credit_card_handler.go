@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/domain/services"
+	"github.com/quocdaijr/finance-management-backend/internal/utils"
+)
+
+// CreditCardHandler handles HTTP requests for credit card details and statements
+type CreditCardHandler struct {
+	creditCardService *services.CreditCardService
+}
+
+// NewCreditCardHandler creates a new credit card handler
+func NewCreditCardHandler(creditCardService *services.CreditCardService) *CreditCardHandler {
+	return &CreditCardHandler{
+		creditCardService: creditCardService,
+	}
+}
+
+// Get handles getting the card details and utilization of a credit account
+func (h *CreditCardHandler) Get(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	card, err := h.creditCardService.Get(uint(accountID), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, card)
+}
+
+// Save handles setting the card details of a credit account
+func (h *CreditCardHandler) Save(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	var req models.CreditCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	card, err := h.creditCardService.Save(uint(accountID), userID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, card)
+}
+
+// Delete handles removing the card details of a credit account
+func (h *CreditCardHandler) Delete(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	if err := h.creditCardService.Delete(uint(accountID), userID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Credit card details deleted successfully"})
+}
+
+// GetStatements handles listing the statements of a credit account
+func (h *CreditCardHandler) GetStatements(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	statements, err := h.creditCardService.GetStatements(uint(accountID), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response := make([]*models.CreditCardStatementResponse, 0, len(statements))
+	for i := range statements {
+		response = append(response, statements[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetUtilization handles reporting the credit utilization across all cards of a user
+func (h *CreditCardHandler) GetUtilization(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	summary, err := h.creditCardService.GetUtilization(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// handleError maps credit card service errors to HTTP responses
+func (h *CreditCardHandler) handleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "account not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+	case "credit card not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Credit card details not found"})
+	case "account is not a credit account":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
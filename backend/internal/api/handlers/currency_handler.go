@@ -3,17 +3,24 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/domain/services"
 )
 
 // CurrencyHandler handles HTTP requests for currencies
-type CurrencyHandler struct{}
+type CurrencyHandler struct {
+	currencyService *services.CurrencyService
+}
 
 // NewCurrencyHandler creates a new currency handler
-func NewCurrencyHandler() *CurrencyHandler {
-	return &CurrencyHandler{}
+func NewCurrencyHandler(currencyService *services.CurrencyService) *CurrencyHandler {
+	return &CurrencyHandler{
+		currencyService: currencyService,
+	}
 }
 
 // GetAll returns all supported currencies
@@ -35,11 +42,12 @@ func (h *CurrencyHandler) GetByCode(c *gin.Context) {
 	c.JSON(http.StatusOK, currency)
 }
 
-// Convert handles currency conversion
+// Convert handles currency conversion at the rate in effect on the optional
+// date parameter, or today
 func (h *CurrencyHandler) Convert(c *gin.Context) {
 	amountStr := c.Query("amount")
-	from := c.Query("from")
-	to := c.Query("to")
+	from := strings.ToUpper(c.Query("from"))
+	to := strings.ToUpper(c.Query("to"))
 	
 	if amountStr == "" || from == "" || to == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "amount, from, and to parameters are required"})
@@ -51,14 +59,63 @@ func (h *CurrencyHandler) Convert(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
 		return
 	}
-	
-	converted := models.ConvertAmount(amount, from, to)
-	
+
+	date, ok := parseRateDate(c)
+	if !ok {
+		return
+	}
+
+	rate, err := h.currencyService.Quote(from, to, date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	converted := models.ConvertMoneyWithRate(models.NewMoney(amount, from), from, to, rate.Rate)
+
 	c.JSON(http.StatusOK, gin.H{
 		"original_amount":   amount,
 		"original_currency": from,
-		"converted_amount":  converted,
+		"converted_amount":  converted.Float(to),
 		"target_currency":   to,
+		"rate":              rate.Rate,
+		"rate_date":         rate.Date.Format("2006-01-02"),
+		"rate_source":       rate.Source,
 	})
 }
 
+// GetRates returns the stored rates from a base currency in effect on the
+// optional date parameter, or today
+func (h *CurrencyHandler) GetRates(c *gin.Context) {
+	base := strings.ToUpper(c.DefaultQuery("base", "EUR"))
+
+	date, ok := parseRateDate(c)
+	if !ok {
+		return
+	}
+
+	rates, err := h.currencyService.GetRates(base, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// parseRateDate reads the optional date query parameter, writing an error
+// response when it is invalid
+func parseRateDate(c *gin.Context) (time.Time, bool) {
+	dateStr := c.Query("date")
+	if dateStr == "" {
+		return time.Now(), true
+	}
+
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return time.Time{}, false
+	}
+	return date, true
+}
+
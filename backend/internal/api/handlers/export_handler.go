@@ -64,6 +64,45 @@ func (h *ExportHandler) ExportTransactionsCSV(c *gin.Context) {
 	c.Data(http.StatusOK, "text/csv", data)
 }
 
+// ExportTransactionsQIF exports transactions to QIF
+func (h *ExportHandler) ExportTransactionsQIF(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse optional date filters
+	var startDate, endDate *time.Time
+
+	if startStr := c.Query("start_date"); startStr != "" {
+		if t, err := time.Parse("2006-01-02", startStr); err == nil {
+			startDate = &t
+		}
+	}
+
+	if endStr := c.Query("end_date"); endStr != "" {
+		if t, err := time.Parse("2006-01-02", endStr); err == nil {
+			endDate = &t
+		}
+	}
+
+	data, err := h.exportService.ExportTransactionsQIF(userID, startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export transactions"})
+		return
+	}
+
+	filename := fmt.Sprintf("transactions_%s.qif", time.Now().Format("2006-01-02"))
+
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Content-Type", "application/qif")
+	c.Header("Content-Length", fmt.Sprintf("%d", len(data)))
+
+	c.Data(http.StatusOK, "application/qif", data)
+}
+
 // ExportTransactionsJSON exports transactions to JSON
 func (h *ExportHandler) ExportTransactionsJSON(c *gin.Context) {
 	userID, err := utils.GetUserIDFromContext(c)
@@ -133,3 +172,30 @@ func (h *ExportHandler) ExportAccountsCSV(c *gin.Context) {
 	c.Data(http.StatusOK, "text/csv", data)
 }
 
+
+// ExportArchive exports all data including attachments as a zip archive
+func (h *ExportHandler) ExportArchive(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Export everything
+	data, err := h.exportService.ExportArchive(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+		return
+	}
+
+	// Generate filename with current date
+	filename := fmt.Sprintf("export_%s.zip", time.Now().Format("2006-01-02"))
+
+	// Set headers for file download
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Length", fmt.Sprintf("%d", len(data)))
+
+	c.Data(http.StatusOK, "application/zip", data)
+}
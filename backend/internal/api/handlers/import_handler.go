@@ -1,7 +1,14 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/quocdaijr/finance-management-backend/internal/services"
@@ -55,9 +62,25 @@ func (h *ImportHandler) ImportTransactionsCSV(c *gin.Context) {
 	}
 	defer src.Close()
 
-	// Import transactions
-	result, err := h.importService.ImportTransactionsCSV(userID, src)
+	// Import transactions, skipping probable duplicates unless allowed
+	opts := services.ImportOptions{AllowDuplicates: c.Query("allow_duplicates") == "true"}
+
+	// Read the file with the given import profile, else the one matching its header
+	if profileID := c.DefaultQuery("profile_id", c.PostForm("profile_id")); profileID != "" {
+		id, err := strconv.ParseUint(profileID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile ID"})
+			return
+		}
+		opts.ProfileID = uint(id)
+	}
+
+	result, err := h.importService.ImportTransactionsCSV(userID, src, opts)
 	if err != nil {
+		if err.Error() == "import profile not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Import profile not found"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -68,8 +91,183 @@ func (h *ImportHandler) ImportTransactionsCSV(c *gin.Context) {
 	})
 }
 
-// GetImportTemplate returns a sample CSV template
+// ImportOFX handles importing bank and credit card statements from an OFX or QFX file
+func (h *ImportHandler) ImportOFX(c *gin.Context) {
+	h.importStatementFile(c, services.ParseOFX, "OFX or QFX", ".ofx", ".qfx")
+}
+
+// ImportCAMT053 handles importing the statements of an ISO 20022 camt.053 file
+func (h *ImportHandler) ImportCAMT053(c *gin.Context) {
+	h.importStatementFile(c, services.ParseCAMT053, "camt.053", ".xml")
+}
+
+// ImportMT940 handles importing the statements of a SWIFT MT940 file
+func (h *ImportHandler) ImportMT940(c *gin.Context) {
+	h.importStatementFile(c, services.ParseMT940, "MT940", ".sta", ".mt940", ".940", ".txt")
+}
+
+// importStatementFile imports the bank statements of an uploaded file of
+// the given kind, read by parse
+func (h *ImportHandler) importStatementFile(c *gin.Context, parse func(io.Reader) ([]services.BankStatement, error), kind string, exts ...string) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	opts, err := statementImportOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	src, ok := openUpload(c, kind, exts...)
+	if !ok {
+		return
+	}
+	defer src.Close()
+
+	statements, err := parse(src)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.importService.ImportStatements(userID, statements, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Import completed",
+		"statements": results,
+	})
+}
+
+// ImportQIF handles importing the accounts, categories and transactions of
+// a QIF file. Dates are read month first unless date_order is dmy.
+func (h *ImportHandler) ImportQIF(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	opts, err := statementImportOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	src, ok := openUpload(c, "QIF", ".qif")
+	if !ok {
+		return
+	}
+	defer src.Close()
+
+	file, err := services.ParseQIF(src, c.Query("date_order") == "dmy")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.importService.ImportQIF(userID, file, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Import completed",
+		"statements": results,
+	})
+}
+
+// statementImportOptions reads the options of a bank statement import: the
+// allow_duplicates and create_accounts query flags and the account_map form
+// field, a JSON object of statement account numbers or IBANs, or QIF account
+// names, to account IDs
+func statementImportOptions(c *gin.Context) (services.ImportOptions, error) {
+	opts := services.ImportOptions{
+		AllowDuplicates: c.Query("allow_duplicates") == "true",
+		CreateAccounts:  c.Query("create_accounts") == "true",
+	}
+	if accountMap := c.PostForm("account_map"); accountMap != "" {
+		if err := json.Unmarshal([]byte(accountMap), &opts.AccountMap); err != nil {
+			return opts, errors.New("invalid account_map: must map account numbers to account IDs")
+		}
+	}
+	return opts, nil
+}
+
+// openUpload opens the uploaded file of a request when it has one of the
+// given extensions and is at most 5MB, responding with an error otherwise
+func openUpload(c *gin.Context, kind string, extensions ...string) (multipart.File, bool) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return nil, false
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	allowed := false
+	for _, e := range extensions {
+		if ext == e {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only " + kind + " files are allowed"})
+		return nil, false
+	}
+
+	if file.Size > 5*1024*1024 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File size too large (max 5MB)"})
+		return nil, false
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return nil, false
+	}
+	return src, true
+}
+
+// GetImportTemplate returns a sample CSV template, in the layout of an import
+// profile when one is given
 func (h *ImportHandler) GetImportTemplate(c *gin.Context) {
+	// Templates of import profiles follow the layout of the profile
+	if profileID := c.Query("profile_id"); profileID != "" {
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		id, err := strconv.ParseUint(profileID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile ID"})
+			return
+		}
+
+		data, err := h.importService.CSVTemplate(userID, uint(id))
+		if err != nil {
+			if err.Error() == "import profile not found" {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Import profile not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Content-Disposition", "attachment; filename=import_template.csv")
+		c.Data(http.StatusOK, "text/csv", data)
+		return
+	}
+
 	template := `date,amount,type,category,description,account,tags
 2024-01-15,1500.00,income,Salary,Monthly salary,Main Account,work
 2024-01-16,50.00,expense,Food & Dining,Lunch with colleagues,Main Account,food
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/domain/services"
+	"github.com/quocdaijr/finance-management-backend/internal/utils"
+)
+
+// InvestmentHandler handles HTTP requests for securities, investment transactions and holdings
+type InvestmentHandler struct {
+	investmentService *services.InvestmentService
+}
+
+// NewInvestmentHandler creates a new investment handler
+func NewInvestmentHandler(investmentService *services.InvestmentService) *InvestmentHandler {
+	return &InvestmentHandler{
+		investmentService: investmentService,
+	}
+}
+
+// GetSecurities handles listing the securities of the user
+func (h *InvestmentHandler) GetSecurities(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	securities, err := h.investmentService.GetSecurities(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, securities)
+}
+
+// CreateSecurity handles adding a security
+func (h *InvestmentHandler) CreateSecurity(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.SecurityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	security, err := h.investmentService.CreateSecurity(userID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, security)
+}
+
+// DeleteSecurity handles deleting a security that has never been traded
+func (h *InvestmentHandler) DeleteSecurity(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid security ID"})
+		return
+	}
+
+	if err := h.investmentService.DeleteSecurity(uint(id), userID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Security deleted successfully"})
+}
+
+// GetTransactions handles listing investment transactions, optionally filtered by account_id
+func (h *InvestmentHandler) GetTransactions(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID, err := accountIDQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	transactions, err := h.investmentService.GetTransactions(userID, accountID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response := make([]*models.InvestmentTransactionResponse, 0, len(transactions))
+	for i := range transactions {
+		response = append(response, transactions[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RecordTransaction handles recording a buy, sell, dividend or split
+func (h *InvestmentHandler) RecordTransaction(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.InvestmentTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transaction, err := h.investmentService.RecordTransaction(userID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, transaction.ToResponse())
+}
+
+// GetHoldings handles valuing the holdings of the user, optionally for a single account_id
+func (h *InvestmentHandler) GetHoldings(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID, err := accountIDQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	portfolio, err := h.investmentService.GetHoldings(userID, accountID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, portfolio)
+}
+
+// GetRealizedGains handles listing the gains realized during a year, the current one by default
+func (h *InvestmentHandler) GetRealizedGains(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(time.Now().Year())))
+	if err != nil || year < 1900 || year > 2100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year"})
+		return
+	}
+
+	gains, err := h.investmentService.GetRealizedGains(userID, year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gains)
+}
+
+// accountIDQuery parses the optional account_id query parameter
+func accountIDQuery(c *gin.Context) (*uint, error) {
+	accountIDStr := c.Query("account_id")
+	if accountIDStr == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(accountIDStr, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	accountID := uint(id)
+	return &accountID, nil
+}
+
+// handleError maps investment service errors to HTTP responses
+func (h *InvestmentHandler) handleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "account not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+	case "security not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Security not found"})
+	case "security already exists", "security has transactions and cannot be deleted",
+		"holding was changed by another transaction, please try again":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
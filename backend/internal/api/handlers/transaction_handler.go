@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
 	"github.com/quocdaijr/finance-management-backend/internal/domain/services"
@@ -14,16 +16,19 @@ import (
 type TransactionHandler struct {
 	transactionService   *services.TransactionService
 	budgetAlertService   *services.BudgetAlertService
+	duplicateService     *services.DuplicateService
 }
 
 // NewTransactionHandler creates a new transaction handler
 func NewTransactionHandler(
 	transactionService *services.TransactionService,
 	budgetAlertService *services.BudgetAlertService,
+	duplicateService *services.DuplicateService,
 ) *TransactionHandler {
 	return &TransactionHandler{
 		transactionService:   transactionService,
 		budgetAlertService:   budgetAlertService,
+		duplicateService:     duplicateService,
 	}
 }
 
@@ -46,19 +51,50 @@ func (h *TransactionHandler) Create(c *gin.Context) {
 	// Create transaction
 	transaction, err := h.transactionService.Create(userID, &req)
 	if err != nil {
+		if isSplitError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, models.ErrPayeeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Check budget alerts after expense transaction (async to not slow down response)
-	if transaction.Type == "expense" && h.budgetAlertService != nil {
+	// Check budget alerts after posted expense transaction (async to not slow down response)
+	if transaction.Type == "expense" && transaction.IsPosted() && h.budgetAlertService != nil {
+		categories := []string{transaction.Category}
+		if transaction.IsSplit() {
+			categories = categories[:0]
+			for _, split := range transaction.Splits {
+				categories = append(categories, split.Category)
+			}
+		}
 		go func() {
-			h.budgetAlertService.CheckBudgetsAfterTransaction(userID, transaction.Category)
+			for _, category := range categories {
+				h.budgetAlertService.CheckBudgetsAfterTransaction(userID, category)
+			}
 		}()
 	}
 
+	// Warn when the transaction is probably already recorded
+	response := transaction.ToResponse()
+	if h.duplicateService != nil {
+		if duplicates, err := h.duplicateService.FindDuplicates(transaction); err == nil && len(duplicates) > 0 {
+			ids := make([]string, 0, len(duplicates))
+			for _, d := range duplicates {
+				response.DuplicateOf = append(response.DuplicateOf, d.ID)
+				ids = append(ids, strconv.FormatUint(uint64(d.ID), 10))
+			}
+			response.Warnings = append(response.Warnings,
+				"Probable duplicate of existing transaction(s) "+strings.Join(ids, ", "))
+		}
+	}
+
 	// Return response
-	c.JSON(http.StatusCreated, transaction.ToResponse())
+	c.JSON(http.StatusCreated, response)
 }
 
 // GetByID handles getting a transaction by ID
@@ -166,6 +202,18 @@ func (h *TransactionHandler) Update(c *gin.Context) {
 	// Update transaction
 	transaction, err := h.transactionService.Update(uint(id), userID, &req)
 	if err != nil {
+		if isSplitError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, models.ErrPayeeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, models.ErrTransactionReconciled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -192,6 +240,10 @@ func (h *TransactionHandler) Delete(c *gin.Context) {
 
 	// Delete transaction
 	if err := h.transactionService.Delete(uint(id), userID); err != nil {
+		if errors.Is(err, models.ErrTransactionReconciled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -209,6 +261,44 @@ func (h *TransactionHandler) GetCategories(c *gin.Context) {
 	c.JSON(http.StatusOK, categories)
 }
 
+// UpdateStatus handles moving a transaction to another status
+func (h *TransactionHandler) UpdateStatus(c *gin.Context) {
+	// Get user ID from context
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Get transaction ID from URL
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	// Bind request body
+	var req models.TransactionStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Update status
+	transaction, err := h.transactionService.UpdateStatus(uint(id), userID, req.Status)
+	if err != nil {
+		if errors.Is(err, models.ErrTransactionReconciled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, transaction.ToResponse())
+}
+
 // GetSummary handles getting a summary of transactions for a specific period
 func (h *TransactionHandler) GetSummary(c *gin.Context) {
 	// Get user ID from context
@@ -221,8 +311,18 @@ func (h *TransactionHandler) GetSummary(c *gin.Context) {
 	// Get period from query parameter
 	period := c.DefaultQuery("period", "month")
 
+	// Get optional status filter, posted transactions by default
+	statuses := c.QueryArray("status")
+	for _, status := range statuses {
+		if status != models.TransactionStatusScheduled && status != models.TransactionStatusPending &&
+			status != models.TransactionStatusCleared && status != models.TransactionStatusVoid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+	}
+
 	// Get summary
-	summary, err := h.transactionService.GetSummary(userID, period)
+	summary, err := h.transactionService.GetSummary(userID, period, statuses)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -258,3 +358,43 @@ func (h *TransactionHandler) Transfer(c *gin.Context) {
 	// Return response
 	c.JSON(http.StatusCreated, response)
 }
+
+// Bulk handles applying one action to many transactions at once
+func (h *TransactionHandler) Bulk(c *gin.Context) {
+	// Get user ID from context
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Bind request body
+	var req models.BulkTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Apply the action
+	response, err := h.transactionService.Bulk(userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "account not found", "tax category not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, response)
+}
+
+// isSplitError reports whether err is a split line validation error
+func isSplitError(err error) bool {
+	return errors.Is(err, models.ErrSplitTooFewLines) ||
+		errors.Is(err, models.ErrSplitLineAmount) ||
+		errors.Is(err, models.ErrSplitSumMismatch) ||
+		errors.Is(err, models.ErrSplitCategoryEmpty)
+}
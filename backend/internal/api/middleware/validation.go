@@ -24,14 +24,15 @@ func SanitizeInput() gin.HandlerFunc {
 	}
 }
 
-// ValidateContentType ensures requests have the correct content type
+// ValidateContentType ensures requests have the correct content type. Bodies
+// must be JSON, except file uploads which are sent as multipart forms.
 func ValidateContentType() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if c.Request.Method == "POST" || c.Request.Method == "PUT" || c.Request.Method == "PATCH" {
+		if (c.Request.Method == "POST" || c.Request.Method == "PUT" || c.Request.Method == "PATCH") && c.Request.ContentLength != 0 {
 			contentType := c.GetHeader("Content-Type")
-			if !strings.Contains(contentType, "application/json") {
+			if !strings.Contains(contentType, "application/json") && !strings.HasPrefix(contentType, "multipart/form-data") {
 				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "Content-Type must be application/json",
+					"error": "Content-Type must be application/json or multipart/form-data",
 				})
 				c.Abort()
 				return
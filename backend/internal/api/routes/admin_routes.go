@@ -33,4 +33,12 @@ func SetupAdminRoutes(api *gin.RouterGroup, rc *RouterConfig) {
 		categories.PUT("/:id", rc.CategoryHandler.Update)
 		categories.DELETE("/:id", rc.CategoryHandler.Delete)
 	}
+
+	// System maintenance, authorized by the admin key instead of a user token
+	admin := api.Group("/admin")
+	admin.Use(middleware.AdminMiddleware(rc.Config))
+	{
+		admin.GET("/balance-audit", rc.AdminHandler.AuditBalances)
+		admin.POST("/balance-audit/fix", rc.AdminHandler.FixBalances)
+	}
 }
@@ -9,20 +9,36 @@ import (
 func SetupDataRoutes(api *gin.RouterGroup, rc *RouterConfig) {
 	protected := api.Group("")
 	protected.Use(middleware.AuthMiddleware(rc.Config))
+	protected.Use(idempotencyMiddleware(rc))
 
 	// Export routes
 	export := protected.Group("/export")
 	{
 		export.GET("/transactions/csv", rc.ExportHandler.ExportTransactionsCSV)
 		export.GET("/transactions/json", rc.ExportHandler.ExportTransactionsJSON)
+		export.GET("/transactions/qif", rc.ExportHandler.ExportTransactionsQIF)
 		export.GET("/accounts/csv", rc.ExportHandler.ExportAccountsCSV)
+		export.GET("/archive", rc.ExportHandler.ExportArchive)
 	}
 
 	// Import routes
 	importGroup := protected.Group("/import")
 	{
 		importGroup.POST("/transactions/csv", rc.ImportHandler.ImportTransactionsCSV)
+		importGroup.POST("/transactions/ofx", rc.ImportHandler.ImportOFX)
+		importGroup.POST("/transactions/qif", rc.ImportHandler.ImportQIF)
+		importGroup.POST("/transactions/camt053", rc.ImportHandler.ImportCAMT053)
+		importGroup.POST("/transactions/mt940", rc.ImportHandler.ImportMT940)
 		importGroup.GET("/template", rc.ImportHandler.GetImportTemplate)
+
+		profiles := importGroup.Group("/profiles")
+		{
+			profiles.GET("", rc.ImportProfileHandler.GetAll)
+			profiles.POST("", rc.ImportProfileHandler.Create)
+			profiles.GET("/:id", rc.ImportProfileHandler.GetByID)
+			profiles.PUT("/:id", rc.ImportProfileHandler.Update)
+			profiles.DELETE("/:id", rc.ImportProfileHandler.Delete)
+		}
 	}
 
 	// Search routes
@@ -7,8 +7,12 @@ import (
 
 // SetupFinancialRoutes configures core financial routes (accounts, transactions, budgets)
 func SetupFinancialRoutes(api *gin.RouterGroup, rc *RouterConfig) {
+	// Attachment downloads are authorized by their signed link
+	api.GET("/attachments/:id/download", rc.AttachmentHandler.Download)
+
 	protected := api.Group("")
 	protected.Use(middleware.AuthMiddleware(rc.Config))
+	protected.Use(idempotencyMiddleware(rc))
 
 	// Account routes
 	accounts := protected.Group("/accounts")
@@ -17,9 +21,38 @@ func SetupFinancialRoutes(api *gin.RouterGroup, rc *RouterConfig) {
 		accounts.POST("", rc.AccountHandler.Create)
 		accounts.GET("/types", rc.AccountHandler.GetTypes)
 		accounts.GET("/summary", rc.AccountHandler.GetSummary)
+		accounts.GET("/credit-utilization", rc.CreditCardHandler.GetUtilization)
 		accounts.GET("/:id", rc.AccountHandler.GetByID)
 		accounts.PUT("/:id", rc.AccountHandler.Update)
 		accounts.DELETE("/:id", rc.AccountHandler.Delete)
+		accounts.GET("/:id/credit-card", rc.CreditCardHandler.Get)
+		accounts.PUT("/:id/credit-card", rc.CreditCardHandler.Save)
+		accounts.DELETE("/:id/credit-card", rc.CreditCardHandler.Delete)
+		accounts.GET("/:id/credit-card/statements", rc.CreditCardHandler.GetStatements)
+		accounts.GET("/:id/loan", rc.LoanHandler.Get)
+		accounts.PUT("/:id/loan", rc.LoanHandler.Save)
+		accounts.DELETE("/:id/loan", rc.LoanHandler.Delete)
+		accounts.GET("/:id/loan/schedule", rc.LoanHandler.GetSchedule)
+		accounts.GET("/:id/loan/payments", rc.LoanHandler.GetPayments)
+		accounts.POST("/:id/loan/payments", rc.LoanHandler.PayExtra)
+		accounts.POST("/:id/loan/scenarios", rc.LoanHandler.CompareScenarios)
+	}
+
+	// Security routes
+	securities := protected.Group("/securities")
+	{
+		securities.GET("", rc.InvestmentHandler.GetSecurities)
+		securities.POST("", rc.InvestmentHandler.CreateSecurity)
+		securities.DELETE("/:id", rc.InvestmentHandler.DeleteSecurity)
+	}
+
+	// Investment routes
+	investments := protected.Group("/investments")
+	{
+		investments.GET("/transactions", rc.InvestmentHandler.GetTransactions)
+		investments.POST("/transactions", rc.InvestmentHandler.RecordTransaction)
+		investments.GET("/holdings", rc.InvestmentHandler.GetHoldings)
+		investments.GET("/gains", rc.InvestmentHandler.GetRealizedGains)
 	}
 
 	// Transaction routes
@@ -28,12 +61,56 @@ func SetupFinancialRoutes(api *gin.RouterGroup, rc *RouterConfig) {
 		transactions.GET("", rc.TransactionHandler.GetAll)
 		transactions.POST("", rc.TransactionHandler.Create)
 		transactions.POST("/transfer", rc.TransactionHandler.Transfer)
+		transactions.POST("/bulk", rc.TransactionHandler.Bulk)
 		transactions.GET("/search", rc.TransactionHandler.Search)
 		transactions.GET("/categories", rc.TransactionHandler.GetCategories)
 		transactions.GET("/summary", rc.TransactionHandler.GetSummary)
+		transactions.GET("/duplicates", rc.DuplicateHandler.GetSuspected)
+		transactions.POST("/duplicates/merge", rc.DuplicateHandler.Merge)
+		transactions.POST("/duplicates/dismiss", rc.DuplicateHandler.Dismiss)
 		transactions.GET("/:id", rc.TransactionHandler.GetByID)
 		transactions.PUT("/:id", rc.TransactionHandler.Update)
+		transactions.PATCH("/:id/status", rc.TransactionHandler.UpdateStatus)
 		transactions.DELETE("/:id", rc.TransactionHandler.Delete)
+		transactions.GET("/:id/attachments", rc.AttachmentHandler.GetByTransaction)
+		transactions.POST("/:id/attachments", rc.AttachmentHandler.Upload)
+		transactions.DELETE("/:id/attachments/:attachmentId", rc.AttachmentHandler.Delete)
+	}
+
+	// Tag routes
+	tags := protected.Group("/tags")
+	{
+		tags.GET("", rc.TagHandler.GetAll)
+		tags.POST("", rc.TagHandler.Create)
+		tags.POST("/merge", rc.TagHandler.Merge)
+		tags.GET("/:id", rc.TagHandler.GetByID)
+		tags.PUT("/:id", rc.TagHandler.Update)
+		tags.DELETE("/:id", rc.TagHandler.Delete)
+	}
+
+	// Payee routes
+	payees := protected.Group("/payees")
+	{
+		payees.GET("", rc.PayeeHandler.GetAll)
+		payees.POST("", rc.PayeeHandler.Create)
+		payees.POST("/merge", rc.PayeeHandler.Merge)
+		payees.GET("/spending", rc.PayeeHandler.GetSpending)
+		payees.GET("/:id", rc.PayeeHandler.GetByID)
+		payees.PUT("/:id", rc.PayeeHandler.Update)
+		payees.DELETE("/:id", rc.PayeeHandler.Delete)
+		payees.GET("/:id/spending", rc.PayeeHandler.GetSpending)
+	}
+
+	// Rule routes
+	rules := protected.Group("/rules")
+	{
+		rules.GET("", rc.RuleHandler.GetAll)
+		rules.POST("", rc.RuleHandler.Create)
+		rules.PUT("/order", rc.RuleHandler.Reorder)
+		rules.POST("/run", rc.RuleHandler.Run)
+		rules.GET("/:id", rc.RuleHandler.GetByID)
+		rules.PUT("/:id", rc.RuleHandler.Update)
+		rules.DELETE("/:id", rc.RuleHandler.Delete)
 	}
 
 	// Budget routes
@@ -56,11 +133,39 @@ func SetupFinancialRoutes(api *gin.RouterGroup, rc *RouterConfig) {
 		balanceHistory.GET("/account/:id/daily", rc.BalanceHistoryHandler.GetDailyBalances)
 	}
 
+	// Ledger routes
+	ledger := protected.Group("/ledger")
+	{
+		ledger.GET("/entries", rc.LedgerHandler.GetEntries)
+		ledger.GET("/entries/:id", rc.LedgerHandler.GetEntryByID)
+		ledger.GET("/integrity", rc.LedgerHandler.CheckIntegrity)
+	}
+
+	// Reconciliation routes
+	reconciliations := protected.Group("/reconciliations")
+	{
+		reconciliations.GET("", rc.ReconciliationHandler.GetByAccount)
+		reconciliations.POST("", rc.ReconciliationHandler.Start)
+		reconciliations.GET("/:id", rc.ReconciliationHandler.GetByID)
+		reconciliations.POST("/:id/clear", rc.ReconciliationHandler.SetCleared)
+		reconciliations.POST("/:id/finish", rc.ReconciliationHandler.Finish)
+		reconciliations.DELETE("/:id", rc.ReconciliationHandler.Cancel)
+	}
+
+	// Trash routes
+	trash := protected.Group("/trash")
+	{
+		trash.GET("", rc.TrashHandler.GetAll)
+		trash.POST("/:type/:id/restore", rc.TrashHandler.Restore)
+		trash.DELETE("/:type/:id", rc.TrashHandler.Purge)
+	}
+
 	// Currency routes
 	currencies := protected.Group("/currencies")
 	{
 		currencies.GET("", rc.CurrencyHandler.GetAll)
 		currencies.GET("/:code", rc.CurrencyHandler.GetByCode)
 		currencies.GET("/convert", rc.CurrencyHandler.Convert)
+		currencies.GET("/rates", rc.CurrencyHandler.GetRates)
 	}
 }
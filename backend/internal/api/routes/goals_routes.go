@@ -9,6 +9,7 @@ import (
 func SetupGoalRoutes(api *gin.RouterGroup, rc *RouterConfig) {
 	protected := api.Group("")
 	protected.Use(middleware.AuthMiddleware(rc.Config))
+	protected.Use(idempotencyMiddleware(rc))
 
 	// Financial goals routes
 	goals := protected.Group("/goals")
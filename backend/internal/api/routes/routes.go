@@ -1,11 +1,14 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/quocdaijr/finance-management-backend/internal/api/handlers"
 	"github.com/quocdaijr/finance-management-backend/internal/api/middleware"
 	"github.com/quocdaijr/finance-management-backend/internal/config"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/idempotency"
 	pkgMiddleware "github.com/quocdaijr/finance-management-backend/pkg/middleware"
 )
 
@@ -13,6 +16,9 @@ import (
 type RouterConfig struct {
 	Config *config.Config
 
+	// IdempotencyStore keeps responses of requests sent with an Idempotency-Key
+	IdempotencyStore idempotency.Store
+
 	// Core handlers
 	AuthHandler        *handlers.AuthHandler
 	AccountHandler     *handlers.AccountHandler
@@ -29,15 +35,28 @@ type RouterConfig struct {
 	ReportHandler *handlers.ReportHandler
 
 	// Data operations
-	ExportHandler *handlers.ExportHandler
-	ImportHandler *handlers.ImportHandler
-	SearchHandler *handlers.SearchHandler
+	ExportHandler        *handlers.ExportHandler
+	ImportHandler        *handlers.ImportHandler
+	ImportProfileHandler *handlers.ImportProfileHandler
+	SearchHandler        *handlers.SearchHandler
 
 	// Supporting features
 	NotificationHandler   *handlers.NotificationHandler
 	CategoryHandler       *handlers.CategoryHandler
 	BalanceHistoryHandler *handlers.BalanceHistoryHandler
 	CurrencyHandler       *handlers.CurrencyHandler
+	LedgerHandler         *handlers.LedgerHandler
+	ReconciliationHandler *handlers.ReconciliationHandler
+	TrashHandler          *handlers.TrashHandler
+	AttachmentHandler     *handlers.AttachmentHandler
+	CreditCardHandler     *handlers.CreditCardHandler
+	LoanHandler           *handlers.LoanHandler
+	InvestmentHandler     *handlers.InvestmentHandler
+	AdminHandler          *handlers.AdminHandler
+	TagHandler            *handlers.TagHandler
+	PayeeHandler          *handlers.PayeeHandler
+	RuleHandler           *handlers.RuleHandler
+	DuplicateHandler      *handlers.DuplicateHandler
 
 	// Sprint 5: Collaboration handlers
 	HouseholdHandler      *handlers.HouseholdHandler
@@ -53,7 +72,7 @@ func SetupRouter(rc *RouterConfig) *gin.Engine {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.IdempotencyKeyHeader},
 		AllowCredentials: true,
 	}))
 	router.Use(middleware.SanitizeInput())
@@ -76,8 +95,13 @@ func SetupRouter(rc *RouterConfig) *gin.Engine {
 	SetupReportingRoutes(api, rc)       // Tax & custom reports
 	SetupDataRoutes(api, rc)            // Import, export, search
 	SetupNotificationRoutes(api, rc)    // Notifications & alerts
-	SetupAdminRoutes(api, rc)           // Categories, user management
+	SetupAdminRoutes(api, rc)           // Categories, user management, balance audit
 	SetupCollaborationRoutes(api, rc)   // Sprint 5: Households, sharing, collaboration
 
 	return router
 }
+
+// idempotencyMiddleware makes the mutating routes of a group safe to retry with an Idempotency-Key
+func idempotencyMiddleware(rc *RouterConfig) gin.HandlerFunc {
+	return middleware.IdempotencyMiddleware(rc.IdempotencyStore, time.Duration(rc.Config.IdempotencyKeyTTLHours)*time.Hour)
+}
@@ -9,20 +9,35 @@ import (
 )
 
 type Config struct {
-	DBHost           string
-	DBUser           string
-	DBPassword       string
-	DBName           string
-	DBPort           string
-	DBSSLMode        string
-	UseSQLite        bool
-	JWTSecret        string
-	JWTRefreshSecret string
-	JWTExpiryHours   int
-	AppName          string
-	RedisAddr        string
-	RedisPassword    string
-	RedisDB          int
+	DBHost             string
+	DBUser             string
+	DBPassword         string
+	DBName             string
+	DBPort             string
+	DBSSLMode          string
+	UseSQLite          bool
+	JWTSecret          string
+	JWTRefreshSecret   string
+	JWTExpiryHours     int
+	AppName            string
+	RedisAddr          string
+	RedisPassword      string
+	RedisDB            int
+	TrashRetentionDays int
+	AttachmentDir      string
+
+	AttachmentSigningSecret string
+
+	ExchangeRateProviderURL  string
+	ExchangeRateFile         string
+	ExchangeRateRefreshHours int
+
+	PriceFile         string
+	PriceRefreshHours int
+
+	AdminAPIKey string
+
+	IdempotencyKeyTTLHours int
 }
 
 func LoadConfig() *Config {
@@ -34,24 +49,58 @@ func LoadConfig() *Config {
 
 	jwtExpiryHours, _ := strconv.Atoi(getEnv("JWT_EXPIRY_HOURS", "24"))
 	redisDB, _ := strconv.Atoi(getEnv("REDIS_DB", "0"))
+	trashRetentionDays, _ := strconv.Atoi(getEnv("TRASH_RETENTION_DAYS", "30"))
+	if trashRetentionDays <= 0 {
+		trashRetentionDays = 30
+	}
+
+	exchangeRateRefreshHours, _ := strconv.Atoi(getEnv("EXCHANGE_RATE_REFRESH_HOURS", "12"))
+	if exchangeRateRefreshHours <= 0 {
+		exchangeRateRefreshHours = 12
+	}
+
+	priceRefreshHours, _ := strconv.Atoi(getEnv("PRICE_REFRESH_HOURS", "24"))
+	if priceRefreshHours <= 0 {
+		priceRefreshHours = 24
+	}
+
+	idempotencyKeyTTLHours, _ := strconv.Atoi(getEnv("IDEMPOTENCY_KEY_TTL_HOURS", "24"))
+	if idempotencyKeyTTLHours <= 0 {
+		idempotencyKeyTTLHours = 24
+	}
 
 	useSQLite := getEnv("USE_SQLITE", "false") == "true"
 
 	return &Config{
-		DBHost:           getEnv("DB_HOST", "localhost"),
-		DBUser:           getEnv("DB_USER", "postgres"),
-		DBPassword:       getEnv("DB_PASSWORD", "postgres"),
-		DBName:           getEnv("DB_NAME", "finance-management"),
-		DBPort:           getEnv("DB_PORT", "5432"),
-		DBSSLMode:        getEnv("DB_SSLMODE", "disable"),
-		UseSQLite:        useSQLite,
-		JWTSecret:        getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
-		JWTRefreshSecret: getEnv("JWT_REFRESH_SECRET", "your-refresh-secret-change-in-production"),
-		JWTExpiryHours:   jwtExpiryHours,
-		AppName:          getEnv("APP_NAME", "Finance Management"),
-		RedisAddr:        getEnv("REDIS_ADDR", "localhost:6379"),
-		RedisPassword:    getEnv("REDIS_PASSWORD", ""),
-		RedisDB:          redisDB,
+		DBHost:             getEnv("DB_HOST", "localhost"),
+		DBUser:             getEnv("DB_USER", "postgres"),
+		DBPassword:         getEnv("DB_PASSWORD", "postgres"),
+		DBName:             getEnv("DB_NAME", "finance-management"),
+		DBPort:             getEnv("DB_PORT", "5432"),
+		DBSSLMode:          getEnv("DB_SSLMODE", "disable"),
+		UseSQLite:          useSQLite,
+		JWTSecret:          getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+		JWTRefreshSecret:   getEnv("JWT_REFRESH_SECRET", "your-refresh-secret-change-in-production"),
+		JWTExpiryHours:     jwtExpiryHours,
+		AppName:            getEnv("APP_NAME", "Finance Management"),
+		RedisAddr:          getEnv("REDIS_ADDR", "localhost:6379"),
+		RedisPassword:      getEnv("REDIS_PASSWORD", ""),
+		RedisDB:            redisDB,
+		TrashRetentionDays: trashRetentionDays,
+		AttachmentDir:      getEnv("ATTACHMENT_DIR", "uploads/attachments"),
+
+		AttachmentSigningSecret: getEnv("ATTACHMENT_SIGNING_SECRET", ""),
+
+		ExchangeRateProviderURL:  getEnv("EXCHANGE_RATE_PROVIDER_URL", ""),
+		ExchangeRateFile:         getEnv("EXCHANGE_RATE_FILE", ""),
+		ExchangeRateRefreshHours: exchangeRateRefreshHours,
+
+		PriceFile:         getEnv("PRICE_FILE", ""),
+		PriceRefreshHours: priceRefreshHours,
+
+		AdminAPIKey: getEnv("ADMIN_API_KEY", ""),
+
+		IdempotencyKeyTTLHours: idempotencyKeyTTLHours,
 	}
 }
 
@@ -1,42 +1,73 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"strconv"
 	"time"
+
+	"gorm.io/gorm"
 )
 
-// Account represents a financial account
+// ErrAccountHasTransactions is returned when trashing an account that still has transactions
+var ErrAccountHasTransactions = errors.New("account still has transactions, delete or move them first")
+
+// Account represents a financial account. Balance includes every posted
+// transaction; CurrentBalance leaves out pending transactions and
+// AvailableBalance leaves out pending income only, so pending spending is
+// already taken off what can be spent.
 type Account struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `gorm:"not null;index:idx_accounts_user_id" json:"user_id"`
-	Name      string    `gorm:"not null" json:"name"`
-	Type      string    `gorm:"not null;index:idx_accounts_type" json:"type"` // checking, savings, credit, investment, etc.
-	Balance   float64   `gorm:"not null" json:"balance"`
-	Currency  string    `gorm:"not null;default:USD" json:"currency"`
-	IsDefault bool      `gorm:"not null;default:false;index:idx_accounts_is_default" json:"is_default"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	UserID           uint           `gorm:"not null;index:idx_accounts_user_id" json:"user_id"`
+	Name             string         `gorm:"not null" json:"name"`
+	Type             string         `gorm:"not null;index:idx_accounts_type" json:"type"` // checking, savings, credit, investment, etc.
+	Balance          Money          `gorm:"column:balance_minor;not null" json:"-"`       // Minor units of Currency
+	CurrentBalance   Money          `gorm:"column:current_balance_minor;not null;default:0" json:"-"`
+	AvailableBalance Money          `gorm:"column:available_balance_minor;not null;default:0" json:"-"`
+	Currency         string         `gorm:"not null;default:USD" json:"currency"`
+	IsDefault        bool           `gorm:"not null;default:false;index:idx_accounts_is_default" json:"is_default"`
+	ExternalNumber   string         `gorm:"index:idx_accounts_external_number" json:"external_number"` // Account number at the bank, to match imported statements
+	CreatedAt        time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"` // Set while the account is in the trash
+}
+
+// MarshalJSON writes the balances in major units under the names they had
+// before amounts were stored in minor units, so raw accounts serialize as
+// they always have
+func (a Account) MarshalJSON() ([]byte, error) {
+	type account Account
+	return json.Marshal(struct {
+		account
+		Balance          float64 `json:"balance"`
+		CurrentBalance   float64 `json:"current_balance"`
+		AvailableBalance float64 `json:"available_balance"`
+	}{account(a), a.Balance.Float(a.Currency), a.CurrentBalance.Float(a.Currency), a.AvailableBalance.Float(a.Currency)})
 }
 
 // AccountResponse is the response model for an account
 type AccountResponse struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Type      string    `json:"type"`
-	Balance   float64   `json:"balance"`
-	Currency  string    `json:"currency"`
-	IsDefault bool      `json:"is_default"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Type             string    `json:"type"`
+	Balance          float64   `json:"balance"`
+	CurrentBalance   float64   `json:"current_balance"`
+	AvailableBalance float64   `json:"available_balance"`
+	Currency         string    `json:"currency"`
+	IsDefault        bool      `json:"is_default"`
+	ExternalNumber   string    `json:"external_number,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
 
 // AccountRequest is the request model for creating/updating an account
 type AccountRequest struct {
-	Name      string  `json:"name" binding:"required"`
-	Type      string  `json:"type" binding:"required"`
-	Balance   float64 `json:"balance" binding:"required"`
-	Currency  string  `json:"currency" binding:"required"`
-	IsDefault bool    `json:"is_default"`
+	Name           string  `json:"name" binding:"required"`
+	Type           string  `json:"type" binding:"required"`
+	Balance        float64 `json:"balance" binding:"required"`
+	Currency       string  `json:"currency" binding:"required"`
+	IsDefault      bool    `json:"is_default"`
+	ExternalNumber string  `json:"external_number"` // Account number at the bank, to match imported statements
 }
 
 // AccountType represents an account type
@@ -51,18 +82,22 @@ type AccountSummary struct {
 	TotalAssets      float64 `json:"total_assets"`
 	TotalLiabilities float64 `json:"total_liabilities"`
 	NetWorth         float64 `json:"net_worth"`
+	Currency         string  `json:"currency"`
 }
 
 // ToResponse converts an Account to an AccountResponse
 func (a *Account) ToResponse() *AccountResponse {
 	return &AccountResponse{
-		ID:        strconv.FormatUint(uint64(a.ID), 10),
-		Name:      a.Name,
-		Type:      a.Type,
-		Balance:   a.Balance,
-		Currency:  a.Currency,
-		IsDefault: a.IsDefault,
-		CreatedAt: a.CreatedAt,
-		UpdatedAt: a.UpdatedAt,
+		ID:               strconv.FormatUint(uint64(a.ID), 10),
+		Name:             a.Name,
+		Type:             a.Type,
+		Balance:          a.Balance.Float(a.Currency),
+		CurrentBalance:   a.CurrentBalance.Float(a.Currency),
+		AvailableBalance: a.AvailableBalance.Float(a.Currency),
+		Currency:         a.Currency,
+		IsDefault:        a.IsDefault,
+		ExternalNumber:   a.ExternalNumber,
+		CreatedAt:        a.CreatedAt,
+		UpdatedAt:        a.UpdatedAt,
 	}
 }
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxAttachmentSize is the largest file that can be attached to a transaction
+const MaxAttachmentSize = 10 * 1024 * 1024
+
+// AllowedAttachmentTypes are the MIME types accepted for attachments, as
+// detected from the file contents
+var AllowedAttachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// Attachment errors
+var (
+	ErrAttachmentTooLarge     = errors.New("attachment is too large (max 10MB)")
+	ErrAttachmentTypeRejected = errors.New("attachment type is not allowed, upload an image or PDF")
+	ErrAttachmentLinkInvalid  = errors.New("download link is invalid or has expired")
+	ErrAttachmentNotFound     = errors.New("attachment not found")
+)
+
+// Attachment is a file such as a receipt or invoice attached to a transaction.
+// The file itself lives in the blob store under StorageKey.
+type Attachment struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        uint      `gorm:"not null;index:idx_attachments_user_id" json:"user_id"`
+	TransactionID uint      `gorm:"not null;index:idx_attachments_transaction_id" json:"transaction_id"`
+	FileName      string    `gorm:"not null" json:"file_name"`
+	ContentType   string    `gorm:"not null" json:"content_type"`
+	Size          int64     `gorm:"not null" json:"size"`
+	Checksum      string    `gorm:"not null;size:64" json:"checksum"` // Hex SHA-256 of the file contents
+	StorageKey    string    `gorm:"not null;uniqueIndex:idx_attachments_storage_key" json:"-"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// AttachmentResponse is the response model for an attachment
+type AttachmentResponse struct {
+	ID            uint       `json:"id"`
+	TransactionID uint       `json:"transaction_id"`
+	FileName      string     `json:"file_name"`
+	ContentType   string     `json:"content_type"`
+	Size          int64      `json:"size"`
+	Checksum      string     `json:"checksum"`
+	DownloadURL   string     `json:"download_url,omitempty"`
+	URLExpiresAt  *time.Time `json:"url_expires_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ToResponse converts an Attachment to AttachmentResponse
+func (a *Attachment) ToResponse() *AttachmentResponse {
+	return &AttachmentResponse{
+		ID:            a.ID,
+		TransactionID: a.TransactionID,
+		FileName:      a.FileName,
+		ContentType:   a.ContentType,
+		Size:          a.Size,
+		Checksum:      a.Checksum,
+		CreatedAt:     a.CreatedAt,
+	}
+}
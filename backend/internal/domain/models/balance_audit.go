@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// BalanceAuditReport lists the accounts whose stored or ledger balance differs
+// from the balance recomputed from their opening balance and transactions
+type BalanceAuditReport struct {
+	AccountsChecked int             `json:"accounts_checked"`
+	Drifted         []*BalanceDrift `json:"drifted"`
+	Fixed           bool            `json:"fixed"` // Corrections were written for every drifted account
+	CheckedAt       time.Time       `json:"checked_at"`
+}
+
+// BalanceDrift is an account whose balances do not agree
+type BalanceDrift struct {
+	AccountID       uint    `json:"account_id"`
+	UserID          uint    `json:"user_id"`
+	Name            string  `json:"name"`
+	Currency        string  `json:"currency"`
+	StoredBalance   float64 `json:"stored_balance"`   // Balance saved on the account
+	LedgerBalance   float64 `json:"ledger_balance"`   // Sum of the account postings
+	ExpectedBalance float64 `json:"expected_balance"` // Opening balance plus transactions and adjustments
+	Difference      float64 `json:"difference"`       // Expected minus stored balance
+	Correction      float64 `json:"correction"`       // Amount of the correction entry written, if any
+}
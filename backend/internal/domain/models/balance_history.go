@@ -4,13 +4,22 @@ import (
 	"time"
 )
 
+// Balance history change types
+const (
+	BalanceChangeIncome     = "income"
+	BalanceChangeExpense    = "expense"
+	BalanceChangeTransfer   = "transfer"
+	BalanceChangeAdjustment = "adjustment"
+	BalanceChangeCheckpoint = "checkpoint" // Balance reported by the bank on an imported statement
+)
+
 // BalanceHistory represents a snapshot of account balance at a point in time
 type BalanceHistory struct {
 	ID              uint      `gorm:"primaryKey" json:"id"`
 	UserID          uint      `gorm:"not null;index" json:"user_id"`
 	AccountID       uint      `gorm:"not null;index" json:"account_id"`
-	Balance         float64   `gorm:"not null" json:"balance"`
-	ChangeAmount    float64   `json:"change_amount"`    // Amount changed from previous
+	Balance         Money     `gorm:"column:balance_minor;not null" json:"-"`       // Minor units of the account currency
+	ChangeAmount    Money     `gorm:"column:change_amount_minor;not null;default:0" json:"-"` // Amount changed from previous
 	ChangeType      string    `json:"change_type"`      // "income", "expense", "transfer", "adjustment"
 	TransactionID   *uint     `json:"transaction_id"`   // Related transaction if any
 	Description     string    `json:"description"`
@@ -18,6 +27,30 @@ type BalanceHistory struct {
 	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
 }
 
+// BalanceChange describes what caused an account balance to change
+type BalanceChange struct {
+	ChangeType    string
+	TransactionID *uint
+	Description   string
+}
+
+// NewTransactionBalanceChange describes the balance change caused by recording a transaction
+func NewTransactionBalanceChange(t *Transaction) BalanceChange {
+	changeType := BalanceChangeExpense
+	switch t.Type {
+	case "income":
+		changeType = BalanceChangeIncome
+	case "transfer":
+		changeType = BalanceChangeTransfer
+	}
+
+	return BalanceChange{
+		ChangeType:    changeType,
+		TransactionID: &t.ID,
+		Description:   t.Description,
+	}
+}
+
 // BalanceHistoryResponse is the API response model
 type BalanceHistoryResponse struct {
 	ID            uint      `json:"id"`
@@ -29,13 +62,14 @@ type BalanceHistoryResponse struct {
 	RecordedAt    time.Time `json:"recorded_at"`
 }
 
-// ToResponse converts a BalanceHistory to BalanceHistoryResponse
-func (h *BalanceHistory) ToResponse() *BalanceHistoryResponse {
+// ToResponse converts a BalanceHistory to BalanceHistoryResponse in the
+// currency of its account
+func (h *BalanceHistory) ToResponse(currency string) *BalanceHistoryResponse {
 	return &BalanceHistoryResponse{
 		ID:           h.ID,
 		AccountID:    h.AccountID,
-		Balance:      h.Balance,
-		ChangeAmount: h.ChangeAmount,
+		Balance:      h.Balance.Float(currency),
+		ChangeAmount: h.ChangeAmount.Float(currency),
 		ChangeType:   h.ChangeType,
 		Description:  h.Description,
 		RecordedAt:   h.RecordedAt,
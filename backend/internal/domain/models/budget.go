@@ -1,25 +1,41 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Budget represents a budget for a specific category
 type Budget struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	UserID       uint      `gorm:"not null;index:idx_budgets_user_id;index:idx_budgets_user_period,priority:1" json:"user_id"`
-	Name         string    `gorm:"not null" json:"name"`
-	Amount       float64   `gorm:"not null" json:"amount"`
-	Spent        float64   `gorm:"not null;default:0" json:"spent"`
-	Category     string    `gorm:"not null;index:idx_budgets_category" json:"category"`
-	Period       string    `gorm:"not null;index:idx_budgets_period;index:idx_budgets_user_period,priority:2" json:"period"` // monthly, quarterly, yearly
-	StartDate    time.Time `gorm:"not null;index:idx_budgets_start_date" json:"start_date"`
-	EndDate      time.Time `gorm:"not null;index:idx_budgets_end_date" json:"end_date"`
-	HouseholdID  *uint     `gorm:"index:idx_budgets_household_id" json:"household_id"` // For household budgets
-	DepartmentID *uint     `gorm:"index:idx_budgets_department_id" json:"department_id"` // For department budgets
-	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	UserID       uint           `gorm:"not null;index:idx_budgets_user_id;index:idx_budgets_user_period,priority:1" json:"user_id"`
+	Name         string         `gorm:"not null" json:"name"`
+	Amount       Money          `gorm:"column:amount_minor;not null" json:"-"`          // Minor units of Currency
+	Spent        Money          `gorm:"column:spent_minor;not null;default:0" json:"-"` // Minor units of Currency
+	Currency     string         `gorm:"not null;default:USD" json:"currency"`
+	Category     string         `gorm:"not null;index:idx_budgets_category" json:"category"`
+	Period       string         `gorm:"not null;index:idx_budgets_period;index:idx_budgets_user_period,priority:2" json:"period"` // monthly, quarterly, yearly
+	StartDate    time.Time      `gorm:"not null;index:idx_budgets_start_date" json:"start_date"`
+	EndDate      time.Time      `gorm:"not null;index:idx_budgets_end_date" json:"end_date"`
+	HouseholdID  *uint          `gorm:"index:idx_budgets_household_id" json:"household_id"`   // For household budgets
+	DepartmentID *uint          `gorm:"index:idx_budgets_department_id" json:"department_id"` // For department budgets
+	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"` // Set while the budget is in the trash
+}
+
+// MarshalJSON writes the amounts in major units under the names they had
+// before amounts were stored in minor units
+func (b Budget) MarshalJSON() ([]byte, error) {
+	type budget Budget
+	return json.Marshal(struct {
+		budget
+		Amount float64 `json:"amount"`
+		Spent  float64 `json:"spent"`
+	}{budget(b), b.Amount.Float(b.Currency), b.Spent.Float(b.Currency)})
 }
 
 // BudgetResponse is the response model for a budget
@@ -28,6 +44,7 @@ type BudgetResponse struct {
 	Name         string    `json:"name"`
 	Amount       float64   `json:"amount"`
 	Spent        float64   `json:"spent"`
+	Currency     string    `json:"currency"`
 	Category     string    `json:"category"`
 	Period       string    `json:"period"`
 	StartDate    time.Time `json:"start_date"`
@@ -43,6 +60,7 @@ type BudgetRequest struct {
 	Name         string    `json:"name" binding:"required"`
 	Amount       float64   `json:"amount" binding:"required"`
 	Spent        float64   `json:"spent"`
+	Currency     string    `json:"currency"`
 	Category     string    `json:"category" binding:"required"`
 	Period       string    `json:"period" binding:"required,oneof=monthly quarterly yearly"`
 	StartDate    time.Time `json:"start_date" binding:"required"`
@@ -58,13 +76,14 @@ type BudgetPeriod struct {
 
 // BudgetSummary represents a summary of budgets
 type BudgetSummary struct {
-	TotalBudgeted     float64 `json:"total_budgeted"`
-	TotalSpent        float64 `json:"total_spent"`
-	TotalRemaining    float64 `json:"total_remaining"`
-	OverallProgress   int     `json:"overall_progress"`
-	TotalBudgets      int     `json:"total_budgets"`
-	BudgetsNearLimit  int     `json:"budgets_near_limit"`
-	BudgetsOverLimit  int     `json:"budgets_over_limit"`
+	TotalBudgeted    float64 `json:"total_budgeted"`
+	TotalSpent       float64 `json:"total_spent"`
+	TotalRemaining   float64 `json:"total_remaining"`
+	OverallProgress  int     `json:"overall_progress"`
+	TotalBudgets     int     `json:"total_budgets"`
+	BudgetsNearLimit int     `json:"budgets_near_limit"`
+	BudgetsOverLimit int     `json:"budgets_over_limit"`
+	Currency         string  `json:"currency"`
 }
 
 // ToResponse converts a Budget to a BudgetResponse
@@ -72,8 +91,9 @@ func (b *Budget) ToResponse() *BudgetResponse {
 	return &BudgetResponse{
 		ID:           fmt.Sprintf("%d", b.ID),
 		Name:         b.Name,
-		Amount:       b.Amount,
-		Spent:        b.Spent,
+		Amount:       b.Amount.Float(b.Currency),
+		Spent:        b.Spent.Float(b.Currency),
+		Currency:     b.Currency,
 		Category:     b.Category,
 		Period:       b.Period,
 		StartDate:    b.StartDate,
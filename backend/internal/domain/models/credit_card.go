@@ -0,0 +1,231 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// AccountTypeCredit is the account type of credit card accounts
+const AccountTypeCredit = "credit"
+
+// Credit card statement statuses
+const (
+	StatementStatusOpen        = "open"         // Due date not reached and not paid in full
+	StatementStatusMinimumPaid = "minimum_paid" // Due date passed with at least the minimum paid
+	StatementStatusPaid        = "paid"         // Closing balance paid in full
+	StatementStatusOverdue     = "overdue"      // Due date passed without the minimum paid
+)
+
+// CreditCard holds the card details of a credit account. The account balance
+// stays signed: money owed on the card is a negative balance.
+type CreditCard struct {
+	ID                    uint      `gorm:"primaryKey" json:"id"`
+	UserID                uint      `gorm:"not null;index:idx_credit_cards_user_id" json:"user_id"`
+	AccountID             uint      `gorm:"not null;uniqueIndex:idx_credit_cards_account_id" json:"account_id"`
+	CreditLimit           Money     `gorm:"column:credit_limit_minor;not null" json:"credit_limit_minor"`                               // Minor units of the account currency
+	StatementClosingDay   int       `gorm:"not null" json:"statement_closing_day"`                                                      // 1-31, the last day of shorter months is used instead
+	PaymentDueDay         int       `gorm:"not null" json:"payment_due_day"`                                                            // 1-31, in the month after the statement closes
+	APR                   float64   `gorm:"not null;default:0" json:"apr"`                                                              // Annual percentage rate, e.g. 19.99
+	MinimumPaymentPercent float64   `gorm:"not null;default:0" json:"minimum_payment_percent"`                                          // Share of the closing balance due by the due date
+	MinimumPaymentAmount  Money     `gorm:"column:minimum_payment_amount_minor;not null;default:0" json:"minimum_payment_amount_minor"` // Floor of the minimum payment
+	CreatedAt             time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt             time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// CreditCardStatement is a snapshot of a credit card at the end of a
+// statement cycle. Payments are the money paid into the card after the
+// statement closed and before the next one did.
+type CreditCardStatement struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `gorm:"not null;index:idx_credit_card_statements_user_id" json:"user_id"`
+	AccountID      uint       `gorm:"not null;uniqueIndex:idx_credit_card_statements_account_closing,priority:1" json:"account_id"`
+	PeriodStart    time.Time  `gorm:"not null" json:"period_start"`
+	ClosingDate    time.Time  `gorm:"not null;uniqueIndex:idx_credit_card_statements_account_closing,priority:2" json:"closing_date"`
+	DueDate        time.Time  `gorm:"not null;index:idx_credit_card_statements_due_date" json:"due_date"`
+	ClosingBalance Money      `gorm:"column:closing_balance_minor;not null" json:"closing_balance_minor"` // Amount owed when the statement closed
+	MinimumPayment Money      `gorm:"column:minimum_payment_minor;not null" json:"minimum_payment_minor"`
+	PaidAmount     Money      `gorm:"column:paid_amount_minor;not null;default:0" json:"paid_amount_minor"`
+	Currency       string     `gorm:"not null;default:USD" json:"currency"`
+	Status         string     `gorm:"not null;default:open;index:idx_credit_card_statements_status" json:"status"`
+	ReminderSentAt *time.Time `json:"reminder_sent_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// CreditCardRequest is the request model for setting the card details of a credit account
+type CreditCardRequest struct {
+	CreditLimit           float64 `json:"credit_limit" binding:"required,gt=0"`
+	StatementClosingDay   int     `json:"statement_closing_day" binding:"required,min=1,max=31"`
+	PaymentDueDay         int     `json:"payment_due_day" binding:"required,min=1,max=31"`
+	APR                   float64 `json:"apr" binding:"min=0,max=100"`
+	MinimumPaymentPercent float64 `json:"minimum_payment_percent" binding:"min=0,max=100"`
+	MinimumPaymentAmount  float64 `json:"minimum_payment_amount" binding:"min=0"`
+}
+
+// CreditCardResponse is the response model for a credit card with its current utilization
+type CreditCardResponse struct {
+	AccountID             uint                         `json:"account_id"`
+	CreditLimit           float64                      `json:"credit_limit"`
+	StatementClosingDay   int                          `json:"statement_closing_day"`
+	PaymentDueDay         int                          `json:"payment_due_day"`
+	APR                   float64                      `json:"apr"`
+	MinimumPaymentPercent float64                      `json:"minimum_payment_percent"`
+	MinimumPaymentAmount  float64                      `json:"minimum_payment_amount"`
+	Currency              string                       `json:"currency"`
+	Balance               float64                      `json:"balance"`          // Amount currently owed
+	AvailableCredit       float64                      `json:"available_credit"` // Limit left to spend, negative when over the limit
+	Utilization           float64                      `json:"utilization"`      // Percentage of the limit in use
+	NextClosingDate       time.Time                    `json:"next_closing_date"`
+	LatestStatement       *CreditCardStatementResponse `json:"latest_statement,omitempty"`
+}
+
+// CreditCardStatementResponse is the response model for a credit card statement
+type CreditCardStatementResponse struct {
+	ID               uint       `json:"id"`
+	AccountID        uint       `json:"account_id"`
+	PeriodStart      time.Time  `json:"period_start"`
+	ClosingDate      time.Time  `json:"closing_date"`
+	DueDate          time.Time  `json:"due_date"`
+	ClosingBalance   float64    `json:"closing_balance"`
+	MinimumPayment   float64    `json:"minimum_payment"`
+	PaidAmount       float64    `json:"paid_amount"`
+	RemainingBalance float64    `json:"remaining_balance"`
+	Currency         string     `json:"currency"`
+	Status           string     `json:"status"`
+	ReminderSentAt   *time.Time `json:"reminder_sent_at"`
+}
+
+// CreditUtilizationSummary reports how much of the combined credit limit of
+// a user's cards is in use, converted into the reporting currency
+type CreditUtilizationSummary struct {
+	TotalLimit      float64               `json:"total_limit"`
+	TotalBalance    float64               `json:"total_balance"`
+	AvailableCredit float64               `json:"available_credit"`
+	Utilization     float64               `json:"utilization"`
+	Currency        string                `json:"currency"`
+	Cards           []*CreditCardResponse `json:"cards"`
+}
+
+// CycleDay returns the date of a statement cycle day in a month, using the
+// last day of the month when the month is shorter
+func CycleDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// ClosingDateBefore returns the last statement closing date whose day has
+// fully passed at t
+func (c *CreditCard) ClosingDateBefore(t time.Time) time.Time {
+	closing := CycleDay(t.Year(), t.Month(), c.StatementClosingDay, t.Location())
+	if !closing.AddDate(0, 0, 1).After(t) {
+		return closing
+	}
+	return c.PreviousClosingDate(closing)
+}
+
+// PreviousClosingDate returns the statement closing date in the month before closing
+func (c *CreditCard) PreviousClosingDate(closing time.Time) time.Time {
+	firstOfMonth := time.Date(closing.Year(), closing.Month(), 1, 0, 0, 0, 0, closing.Location())
+	previous := firstOfMonth.AddDate(0, -1, 0)
+	return CycleDay(previous.Year(), previous.Month(), c.StatementClosingDay, closing.Location())
+}
+
+// NextClosingDate returns the statement closing date in the month after closing
+func (c *CreditCard) NextClosingDate(closing time.Time) time.Time {
+	firstOfMonth := time.Date(closing.Year(), closing.Month(), 1, 0, 0, 0, 0, closing.Location())
+	next := firstOfMonth.AddDate(0, 1, 0)
+	return CycleDay(next.Year(), next.Month(), c.StatementClosingDay, closing.Location())
+}
+
+// DueDateFor returns the first payment due day after a statement closing date
+func (c *CreditCard) DueDateFor(closing time.Time) time.Time {
+	due := CycleDay(closing.Year(), closing.Month(), c.PaymentDueDay, closing.Location())
+	if due.After(closing) {
+		return due
+	}
+	firstOfMonth := time.Date(closing.Year(), closing.Month(), 1, 0, 0, 0, 0, closing.Location())
+	next := firstOfMonth.AddDate(0, 1, 0)
+	return CycleDay(next.Year(), next.Month(), c.PaymentDueDay, closing.Location())
+}
+
+// MinimumPaymentFor returns the minimum payment due on an owed balance: the
+// percentage of the balance, but at least the minimum amount and at most the
+// balance itself
+func (c *CreditCard) MinimumPaymentFor(owed Money) Money {
+	if owed <= 0 {
+		return 0
+	}
+	minimum := Money(math.Round(float64(owed) * c.MinimumPaymentPercent / 100))
+	if minimum < c.MinimumPaymentAmount {
+		minimum = c.MinimumPaymentAmount
+	}
+	if minimum > owed {
+		minimum = owed
+	}
+	return minimum
+}
+
+// Owed returns the amount owed on a credit account with the given signed balance
+func Owed(balance Money) Money {
+	if balance >= 0 {
+		return 0
+	}
+	return -balance
+}
+
+// Utilization returns the percentage of the credit limit in use at an owed amount
+func (c *CreditCard) Utilization(owed Money) float64 {
+	return UtilizationPercent(owed, c.CreditLimit)
+}
+
+// UtilizationPercent returns owed as a percentage of limit, rounded to two decimals
+func UtilizationPercent(owed, limit Money) float64 {
+	if limit <= 0 {
+		return 0
+	}
+	return math.Round(float64(owed)/float64(limit)*10000) / 100
+}
+
+// Remaining returns how much of the closing balance is still unpaid
+func (s *CreditCardStatement) Remaining() Money {
+	if s.PaidAmount >= s.ClosingBalance {
+		return 0
+	}
+	return s.ClosingBalance - s.PaidAmount
+}
+
+// StatusAt works out the status of the statement at a point in time from
+// the amount paid against it
+func (s *CreditCardStatement) StatusAt(t time.Time) string {
+	switch {
+	case s.Remaining() == 0:
+		return StatementStatusPaid
+	case t.Before(s.DueDate.AddDate(0, 0, 1)):
+		return StatementStatusOpen
+	case s.PaidAmount >= s.MinimumPayment:
+		return StatementStatusMinimumPaid
+	default:
+		return StatementStatusOverdue
+	}
+}
+
+// ToResponse converts a CreditCardStatement to CreditCardStatementResponse
+func (s *CreditCardStatement) ToResponse() *CreditCardStatementResponse {
+	return &CreditCardStatementResponse{
+		ID:               s.ID,
+		AccountID:        s.AccountID,
+		PeriodStart:      s.PeriodStart,
+		ClosingDate:      s.ClosingDate,
+		DueDate:          s.DueDate,
+		ClosingBalance:   s.ClosingBalance.Float(s.Currency),
+		MinimumPayment:   s.MinimumPayment.Float(s.Currency),
+		PaidAmount:       s.PaidAmount.Float(s.Currency),
+		RemainingBalance: s.Remaining().Float(s.Currency),
+		Currency:         s.Currency,
+		Status:           s.Status,
+		ReminderSentAt:   s.ReminderSentAt,
+	}
+}
@@ -1,12 +1,17 @@
 package models
 
+import (
+	"errors"
+	"time"
+)
+
 // Currency represents a supported currency
 type Currency struct {
 	Code     string  `json:"code"`
 	Name     string  `json:"name"`
 	Symbol   string  `json:"symbol"`
 	Decimals int     `json:"decimals"`
-	Rate     float64 `json:"rate"` // Exchange rate to USD
+	Rate     float64 `json:"rate"` // Fallback exchange rate to USD, used when no dated rate is stored
 }
 
 // SupportedCurrencies returns the list of supported currencies
@@ -64,17 +69,67 @@ func ConvertAmount(amount float64, fromCurrency, toCurrency string) float64 {
 	return usdAmount * to.Rate
 }
 
-// FormatAmount formats an amount with the currency symbol
-func FormatAmount(amount float64, currencyCode string) string {
-	currency := GetCurrencyByCode(currencyCode)
-	if currency == nil {
-		return "$" + formatNumber(amount, 2)
+// ErrExchangeRateNotFound is returned when no rate is known for a currency pair
+var ErrExchangeRateNotFound = errors.New("exchange rate not found")
+
+// ExchangeRateSource looks up exchange rates. Rate returns how many units of
+// toCurrency one unit of fromCurrency was worth on the given date.
+type ExchangeRateSource interface {
+	Rate(fromCurrency, toCurrency string, date time.Time) (float64, error)
+}
+
+// StaticExchangeRates is an ExchangeRateSource that uses the fixed rates of
+// SupportedCurrencies whatever the date
+type StaticExchangeRates struct{}
+
+// Rate returns the fixed rate between two supported currencies
+func (StaticExchangeRates) Rate(fromCurrency, toCurrency string, date time.Time) (float64, error) {
+	if fromCurrency == toCurrency {
+		return 1, nil
+	}
+
+	from := GetCurrencyByCode(fromCurrency)
+	to := GetCurrencyByCode(toCurrency)
+	if from == nil || to == nil {
+		return 0, ErrExchangeRateNotFound
+	}
+	return to.Rate / from.Rate, nil
+}
+
+// ConvertMoneyAt converts Money at the rate in effect on date, rounding the
+// result to the target currency's minor unit
+func ConvertMoneyAt(rates ExchangeRateSource, amount Money, fromCurrency, toCurrency string, date time.Time) (Money, error) {
+	if fromCurrency == toCurrency {
+		return amount, nil
+	}
+
+	rate, err := rates.Rate(fromCurrency, toCurrency, date)
+	if err != nil {
+		return 0, err
 	}
-	return currency.Symbol + formatNumber(amount, currency.Decimals)
+	return ConvertMoneyWithRate(amount, fromCurrency, toCurrency, rate), nil
 }
 
-func formatNumber(amount float64, decimals int) string {
-	format := "%." + string(rune('0'+decimals)) + "f"
-	return string([]byte(format))
+// ConvertMoneyWithRate converts Money at the given rate, rounding the result
+// to the target currency's minor unit
+func ConvertMoneyWithRate(amount Money, fromCurrency, toCurrency string, rate float64) Money {
+	return NewMoney(amount.Float(fromCurrency)*rate, toCurrency)
 }
 
+// ConvertMoney converts Money from one currency to another, rounding the
+// result to the target currency's minor unit
+func ConvertMoney(amount Money, fromCurrency, toCurrency string) Money {
+	if fromCurrency == toCurrency {
+		return amount
+	}
+	return NewMoney(ConvertAmount(amount.Float(fromCurrency), fromCurrency, toCurrency), toCurrency)
+}
+
+// FormatAmount formats an amount with the currency symbol
+func FormatAmount(amount Money, currencyCode string) string {
+	currency := GetCurrencyByCode(currencyCode)
+	if currency == nil {
+		return "$" + amount.Format(currencyCode)
+	}
+	return currency.Symbol + amount.Format(currencyCode)
+}
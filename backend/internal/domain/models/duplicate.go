@@ -0,0 +1,108 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Probable duplicates are transactions of the same account, type and amount
+// whose dates are at most DuplicateDateWindow apart and whose descriptions
+// are at least DuplicateSimilarity alike
+const (
+	DuplicateDateWindow = 3 * 24 * time.Hour
+	DuplicateSimilarity = 0.6
+)
+
+// DuplicateDismissal records a pair of transactions the user reviewed and
+// confirmed are not duplicates. The lower transaction ID comes first.
+type DuplicateDismissal struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        uint      `gorm:"not null;index:idx_duplicate_dismissals_user_id" json:"user_id"`
+	TransactionID uint      `gorm:"not null;uniqueIndex:idx_duplicate_dismissals_pair,priority:1" json:"transaction_id"`
+	DuplicateID   uint      `gorm:"not null;uniqueIndex:idx_duplicate_dismissals_pair,priority:2" json:"duplicate_id"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// DuplicatePairRequest is the request model for merging or dismissing a
+// pair of suspected duplicates. On merge TransactionID is kept and
+// DuplicateID goes to the trash.
+type DuplicatePairRequest struct {
+	TransactionID uint `json:"transaction_id" binding:"required"`
+	DuplicateID   uint `json:"duplicate_id" binding:"required"`
+}
+
+// DuplicatePair is a pair of transactions suspected to be duplicates. The
+// earlier created transaction comes first.
+type DuplicatePair struct {
+	Transaction *TransactionResponse `json:"transaction"`
+	Duplicate   *TransactionResponse `json:"duplicate"`
+	Similarity  float64              `json:"similarity"` // Description similarity from 0 to 1
+}
+
+// IsProbableDuplicate reports whether two transactions are probably the same
+// transaction recorded twice
+func IsProbableDuplicate(a, b *Transaction) bool {
+	if a.AccountID != b.AccountID || a.Type != b.Type || a.Amount != b.Amount || a.Currency != b.Currency {
+		return false
+	}
+	gap := a.Date.Sub(b.Date)
+	if gap < 0 {
+		gap = -gap
+	}
+	if gap > DuplicateDateWindow {
+		return false
+	}
+	return DescriptionSimilarity(a.Description, b.Description) >= DuplicateSimilarity
+}
+
+// DescriptionSimilarity rates how alike two transaction descriptions are
+// from 0 to 1, comparing the letter pairs of their normalized forms so that
+// reference codes and small spelling differences count little
+func DescriptionSimilarity(a, b string) float64 {
+	a, b = similarityForm(a), similarityForm(b)
+	if a == b {
+		return 1
+	}
+
+	pairsA, pairsB := letterPairs(a), letterPairs(b)
+	if len(pairsA)+len(pairsB) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(pairsA))
+	for _, pair := range pairsA {
+		counts[pair]++
+	}
+	shared := 0
+	for _, pair := range pairsB {
+		if counts[pair] > 0 {
+			counts[pair]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(pairsA)+len(pairsB))
+}
+
+// similarityForm normalizes a description for comparison like a payee name,
+// falling back to the lower-case description when nothing is left
+func similarityForm(description string) string {
+	if normalized := NormalizePayeeName(description); normalized != "" {
+		return normalized
+	}
+	return strings.ToLower(strings.TrimSpace(description))
+}
+
+// letterPairs returns the adjacent letter pairs of each word of s
+func letterPairs(s string) []string {
+	var pairs []string
+	for _, word := range strings.Fields(s) {
+		runes := []rune(word)
+		if len(runes) == 1 {
+			pairs = append(pairs, word)
+			continue
+		}
+		for i := 0; i+1 < len(runes); i++ {
+			pairs = append(pairs, string(runes[i:i+2]))
+		}
+	}
+	return pairs
+}
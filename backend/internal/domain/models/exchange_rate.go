@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Exchange rate sources other than the configured providers
+const (
+	ExchangeRateSourceStatic = "static" // Fixed fallback rates of SupportedCurrencies
+)
+
+// ExchangeRate is the value of one unit of BaseCurrency in QuoteCurrency on a
+// date. Rates are stored per day; lookups for a date without a rate use the
+// nearest earlier one.
+type ExchangeRate struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	BaseCurrency  string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_pair_date,priority:1" json:"base_currency"`
+	QuoteCurrency string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_pair_date,priority:2" json:"quote_currency"`
+	Date          time.Time `gorm:"not null;uniqueIndex:idx_exchange_rates_pair_date,priority:3" json:"date"` // Midnight UTC, see RateDate
+	Rate          float64   `gorm:"not null" json:"rate"`
+	Source        string    `gorm:"not null" json:"source"` // Provider the rate came from
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"-"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime" json:"-"`
+}
+
+// Inverse returns the rate for the opposite direction of the pair
+func (r *ExchangeRate) Inverse() *ExchangeRate {
+	return &ExchangeRate{
+		BaseCurrency:  r.QuoteCurrency,
+		QuoteCurrency: r.BaseCurrency,
+		Date:          r.Date,
+		Rate:          1 / r.Rate,
+		Source:        r.Source,
+	}
+}
+
+// RateDate truncates a time to the UTC day exchange rates are stored under
+func RateDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -1,30 +1,45 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Goal represents a financial savings goal
 type Goal struct {
-	ID            uint       `gorm:"primaryKey" json:"id"`
-	UserID        uint       `gorm:"not null;index:idx_goals_user_id" json:"user_id"`
-	Name          string     `gorm:"not null" json:"name"`
-	Description   string     `json:"description"`
-	TargetAmount  float64    `gorm:"not null" json:"target_amount"`
-	CurrentAmount float64    `gorm:"default:0" json:"current_amount"`
-	Currency      string     `gorm:"default:'USD'" json:"currency"`
-	Category      string     `gorm:"index:idx_goals_category" json:"category"` // e.g., vacation, emergency, car, home, education
-	Icon          string     `json:"icon"`     // Emoji or icon name
-	Color         string     `json:"color"`    // Hex color for UI display
-	TargetDate    *time.Time `gorm:"index:idx_goals_target_date" json:"target_date"`
-	StartDate     time.Time  `gorm:"not null" json:"start_date"`
-	AccountID     *uint      `json:"account_id"` // Optional linked account
-	HouseholdID   *uint      `gorm:"index:idx_goals_household_id" json:"household_id"` // For shared household goals
-	IsCompleted   bool       `gorm:"default:false;index:idx_goals_is_completed" json:"is_completed"`
-	CompletedAt   *time.Time `json:"completed_at"`
-	Priority      int        `gorm:"default:0;index:idx_goals_priority" json:"priority"` // 0=low, 1=medium, 2=high
-	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt     time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	UserID        uint           `gorm:"not null;index:idx_goals_user_id" json:"user_id"`
+	Name          string         `gorm:"not null" json:"name"`
+	Description   string         `json:"description"`
+	TargetAmount  Money          `gorm:"column:target_amount_minor;not null" json:"-"`   // Minor units of Currency
+	CurrentAmount Money          `gorm:"column:current_amount_minor;default:0" json:"-"` // Minor units of Currency
+	Currency      string         `gorm:"default:'USD'" json:"currency"`
+	Category      string         `gorm:"index:idx_goals_category" json:"category"` // e.g., vacation, emergency, car, home, education
+	Icon          string         `json:"icon"`                                     // Emoji or icon name
+	Color         string         `json:"color"`                                    // Hex color for UI display
+	TargetDate    *time.Time     `gorm:"index:idx_goals_target_date" json:"target_date"`
+	StartDate     time.Time      `gorm:"not null" json:"start_date"`
+	AccountID     *uint          `json:"account_id"`                                       // Optional linked account
+	HouseholdID   *uint          `gorm:"index:idx_goals_household_id" json:"household_id"` // For shared household goals
+	IsCompleted   bool           `gorm:"default:false;index:idx_goals_is_completed" json:"is_completed"`
+	CompletedAt   *time.Time     `json:"completed_at"`
+	Priority      int            `gorm:"default:0;index:idx_goals_priority" json:"priority"` // 0=low, 1=medium, 2=high
+	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"` // Set while the goal is in the trash
+}
+
+// MarshalJSON writes the amounts in major units under the names they had
+// before amounts were stored in minor units
+func (g Goal) MarshalJSON() ([]byte, error) {
+	type goal Goal
+	return json.Marshal(struct {
+		goal
+		TargetAmount  float64 `json:"target_amount"`
+		CurrentAmount float64 `json:"current_amount"`
+	}{goal(g), g.TargetAmount.Float(g.Currency), g.CurrentAmount.Float(g.Currency)})
 }
 
 // GoalRequest is the request model for creating/updating a goal
@@ -83,6 +98,7 @@ type GoalSummary struct {
 	TotalTargetAmount float64 `json:"total_target_amount"`
 	TotalSavedAmount  float64 `json:"total_saved_amount"`
 	OverallProgress   float64 `json:"overall_progress"`
+	Currency          string  `json:"currency"`
 }
 
 // ToResponse converts a Goal to GoalResponse
@@ -90,7 +106,7 @@ func (g *Goal) ToResponse() *GoalResponse {
 	// Calculate progress percentage
 	progressPercent := float64(0)
 	if g.TargetAmount > 0 {
-		progressPercent = (g.CurrentAmount / g.TargetAmount) * 100
+		progressPercent = (float64(g.CurrentAmount) / float64(g.TargetAmount)) * 100
 		if progressPercent > 100 {
 			progressPercent = 100
 		}
@@ -116,8 +132,8 @@ func (g *Goal) ToResponse() *GoalResponse {
 		ID:              g.ID,
 		Name:            g.Name,
 		Description:     g.Description,
-		TargetAmount:    g.TargetAmount,
-		CurrentAmount:   g.CurrentAmount,
+		TargetAmount:    g.TargetAmount.Float(g.Currency),
+		CurrentAmount:   g.CurrentAmount.Float(g.Currency),
 		Currency:        g.Currency,
 		Category:        g.Category,
 		Icon:            g.Icon,
@@ -130,7 +146,7 @@ func (g *Goal) ToResponse() *GoalResponse {
 		CompletedAt:     g.CompletedAt,
 		Priority:        g.Priority,
 		ProgressPercent: progressPercent,
-		RemainingAmount: remaining,
+		RemainingAmount: remaining.Float(g.Currency),
 		DaysRemaining:   daysRemaining,
 		CreatedAt:       g.CreatedAt,
 		UpdatedAt:       g.UpdatedAt,
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the response to a mutating request sent with an
+// Idempotency-Key header, so that a retry of the request gets the original
+// response instead of being applied twice
+type IdempotencyKey struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key" json:"user_id"`
+	Key         string    `gorm:"column:idempotency_key;size:255;not null;uniqueIndex:idx_idempotency_keys_user_key" json:"key"`
+	Fingerprint string    `gorm:"size:64;not null" json:"fingerprint"`   // SHA-256 of the method, path and body of the request
+	StatusCode  int       `gorm:"not null;default:0" json:"status_code"` // Zero while the first request is in progress
+	ContentType string    `json:"content_type"`
+	Response    []byte    `json:"-"`
+	ExpiresAt   time.Time `gorm:"not null;index:idx_idempotency_keys_expires_at" json:"expires_at"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Completed reports whether the response of the first request has been stored
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
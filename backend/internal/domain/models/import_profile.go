@@ -0,0 +1,253 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sign conventions of import profiles: how a row tells income from expense
+const (
+	ImportSignTypeColumn      = "type_column"      // Unsigned amount and a type column of income or expense
+	ImportSignNegativeExpense = "negative_expense" // Signed amount; negative amounts are expenses
+	ImportSignNegativeIncome  = "negative_income"  // Signed amount; negative amounts are income, as in card statements
+	ImportSignDebitCredit     = "debit_credit"     // Separate debit and credit amount columns
+)
+
+// ImportProfile is a saved layout of the CSV files of a bank: the column
+// each field is read from and how dates and amounts are written. Columns
+// are named by their header in the file; empty columns are not read.
+type ImportProfile struct {
+	ID               uint   `gorm:"primaryKey" json:"id"`
+	UserID           uint   `gorm:"not null;index:idx_import_profiles_user_id" json:"user_id"`
+	Name             string `gorm:"not null;size:100" json:"name"`
+	Delimiter        string `gorm:"not null;size:1" json:"delimiter"`
+	DecimalSeparator string `gorm:"not null;size:1" json:"decimal_separator"`
+	DateFormat       string `json:"date_format"` // e.g. DD/MM/YYYY; common formats are tried when empty
+	SignConvention   string `gorm:"not null;default:type_column" json:"sign_convention"`
+	SkipRows         int    `gorm:"not null;default:0" json:"skip_rows"` // Preamble lines before the header
+	AccountID        *uint  `json:"account_id"`                          // Account for rows that name none
+
+	DateColumn        string `gorm:"not null" json:"date_column"`
+	AmountColumn      string `json:"amount_column"`
+	DebitColumn       string `json:"debit_column"`
+	CreditColumn      string `json:"credit_column"`
+	TypeColumn        string `json:"type_column"`
+	DescriptionColumn string `json:"description_column"`
+	PayeeColumn       string `json:"payee_column"`
+	CategoryColumn    string `json:"category_column"`
+	AccountColumn     string `json:"account_column"`
+	TagsColumn        string `json:"tags_column"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// ImportProfileRequest is the request model for creating/updating an import profile
+type ImportProfileRequest struct {
+	Name             string `json:"name" binding:"required,max=100"`
+	Delimiter        string `json:"delimiter" binding:"omitempty,len=1"`
+	DecimalSeparator string `json:"decimal_separator" binding:"omitempty,oneof=. ,"`
+	DateFormat       string `json:"date_format"`
+	SignConvention   string `json:"sign_convention" binding:"omitempty,oneof=type_column negative_expense negative_income debit_credit"`
+	SkipRows         int    `json:"skip_rows" binding:"min=0,max=100"`
+	AccountID        *uint  `json:"account_id"`
+
+	DateColumn        string `json:"date_column" binding:"required"`
+	AmountColumn      string `json:"amount_column"`
+	DebitColumn       string `json:"debit_column"`
+	CreditColumn      string `json:"credit_column"`
+	TypeColumn        string `json:"type_column"`
+	DescriptionColumn string `json:"description_column"`
+	PayeeColumn       string `json:"payee_column"`
+	CategoryColumn    string `json:"category_column"`
+	AccountColumn     string `json:"account_column"`
+	TagsColumn        string `json:"tags_column"`
+}
+
+// DefaultImportProfile returns the layout of the import template: comma
+// separated, with amount, type and date columns
+func DefaultImportProfile() *ImportProfile {
+	return &ImportProfile{
+		Name:              "Default",
+		Delimiter:         ",",
+		DecimalSeparator:  ".",
+		SignConvention:    ImportSignTypeColumn,
+		DateColumn:        "date",
+		AmountColumn:      "amount",
+		TypeColumn:        "type",
+		DescriptionColumn: "description",
+		PayeeColumn:       "payee",
+		CategoryColumn:    "category",
+		AccountColumn:     "account",
+		TagsColumn:        "tags",
+	}
+}
+
+// Validate checks that a profile has the columns its sign convention reads
+// and a date format that can be parsed
+func (p *ImportProfile) Validate() error {
+	switch p.SignConvention {
+	case ImportSignTypeColumn:
+		if p.AmountColumn == "" || p.TypeColumn == "" {
+			return errors.New("amount and type columns are required")
+		}
+	case ImportSignNegativeExpense, ImportSignNegativeIncome:
+		if p.AmountColumn == "" {
+			return errors.New("amount column is required")
+		}
+	case ImportSignDebitCredit:
+		if p.DebitColumn == "" || p.CreditColumn == "" {
+			return errors.New("debit and credit columns are required")
+		}
+	default:
+		return fmt.Errorf("invalid sign convention: %s", p.SignConvention)
+	}
+	if p.Delimiter == p.DecimalSeparator {
+		return errors.New("delimiter and decimal separator must differ")
+	}
+
+	// The format must give the year, month and day
+	if p.DateFormat != "" {
+		layout := p.DateLayout()
+		sample := time.Date(2024, time.November, 23, 0, 0, 0, 0, time.UTC)
+		if parsed, err := time.Parse(layout, sample.Format(layout)); err != nil || !parsed.Equal(sample) {
+			return fmt.Errorf("invalid date format: %s", p.DateFormat)
+		}
+	}
+	return nil
+}
+
+// Columns returns the columns the profile reads in the order of its template
+func (p *ImportProfile) Columns() []string {
+	candidates := []string{
+		p.DateColumn, p.AmountColumn, p.DebitColumn, p.CreditColumn, p.TypeColumn,
+		p.DescriptionColumn, p.PayeeColumn, p.CategoryColumn, p.AccountColumn, p.TagsColumn,
+	}
+	var columns []string
+	for _, column := range candidates {
+		if column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// RequiredColumns returns the columns a file must have to be read with the
+// profile: the date and the amount columns of its sign convention
+func (p *ImportProfile) RequiredColumns() []string {
+	switch p.SignConvention {
+	case ImportSignTypeColumn:
+		return []string{p.AmountColumn, p.TypeColumn, p.DateColumn}
+	case ImportSignDebitCredit:
+		return []string{p.DebitColumn, p.CreditColumn, p.DateColumn}
+	default:
+		return []string{p.AmountColumn, p.DateColumn}
+	}
+}
+
+// MissingColumn returns the first of columns a header lacks, or "" when it
+// has them all. Headers compare case-insensitively.
+func MissingColumn(header []string, columns []string) string {
+	present := make(map[string]bool, len(header))
+	for _, column := range header {
+		present[strings.ToLower(strings.TrimSpace(column))] = true
+	}
+	for _, column := range columns {
+		if !present[strings.ToLower(column)] {
+			return column
+		}
+	}
+	return ""
+}
+
+// Matches reports whether a header has every column the profile reads, so
+// that files of the bank can be told apart by their header
+func (p *ImportProfile) Matches(header []string) bool {
+	return MissingColumn(header, p.Columns()) == ""
+}
+
+// DateLayout converts the date format of a profile, written with YYYY, YY,
+// MM, M, DD, D, HH, mm and ss, to a time layout
+func (p *ImportProfile) DateLayout() string {
+	return importDateTokens.Replace(p.DateFormat)
+}
+
+// importDateTokens replaces date format tokens with time layout elements;
+// longer tokens come first so they win
+var importDateTokens = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MM", "01",
+	"DD", "02",
+	"HH", "15",
+	"mm", "04",
+	"ss", "05",
+	"M", "1",
+	"D", "2",
+)
+
+// ParseDate parses a date as written in files of the profile
+func (p *ImportProfile) ParseDate(value string) (time.Time, error) {
+	if p.DateFormat != "" {
+		date, err := time.Parse(p.DateLayout(), value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date format: %s (expected %s)", value, p.DateFormat)
+		}
+		return date, nil
+	}
+
+	for _, layout := range []string{
+		"2006-01-02",
+		"01/02/2006",
+		"02/01/2006",
+		"2006-01-02T15:04:05Z",
+		"2006-01-02 15:04:05",
+	} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date format: %s", value)
+}
+
+// ParseAmount parses a signed amount as written in files of the profile,
+// dropping thousands separators and currency symbols. Amounts in
+// parentheses or with a trailing minus are negative.
+func (p *ImportProfile) ParseAmount(value string, currencyCode string) (Money, error) {
+	raw := value
+	negative := false
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = value[1 : len(value)-1]
+	}
+	if strings.HasSuffix(value, "-") {
+		negative = true
+		value = strings.TrimSuffix(value, "-")
+	}
+
+	decimal := '.'
+	if p.DecimalSeparator == "," {
+		decimal = ','
+	}
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '-', r == '+':
+			return r
+		case r == decimal:
+			return '.'
+		}
+		return -1
+	}, value)
+
+	amount, err := ParseMoney(value, currencyCode)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount: %s", raw)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
@@ -0,0 +1,461 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// AccountTypeInvestment is the account type of brokerage and other investment accounts
+const AccountTypeInvestment = "investment"
+
+// Investment transaction types
+const (
+	InvestmentTypeBuy      = "buy"
+	InvestmentTypeSell     = "sell"
+	InvestmentTypeDividend = "dividend"
+	InvestmentTypeSplit    = "split"
+)
+
+// Methods of choosing the lots a sale is taken from
+const (
+	LotMethodFIFO     = "fifo"     // Oldest lots first
+	LotMethodLIFO     = "lifo"     // Newest lots first
+	LotMethodSpecific = "specific" // Lots named in the request
+)
+
+// Holding periods of realized gains
+const (
+	GainTermShort = "short_term"
+	GainTermLong  = "long_term"
+)
+
+// Categories realized gains and dividends are booked under. Gains use tax
+// categories of the same name with tax type capital_gain.
+const (
+	ShortTermGainsCategory = "Capital Gains (Short-Term)"
+	LongTermGainsCategory  = "Capital Gains (Long-Term)"
+	DividendCategory       = "Dividends"
+)
+
+// TaxTypeCapitalGain is the tax type of tax categories holding realized gains
+const TaxTypeCapitalGain = "capital_gain"
+
+// sharesScale is the number of stored units per share, allowing fractional
+// shares down to a millionth
+const sharesScale = 1000000
+
+// ErrInsufficientShares is returned when a sale is larger than the shares held
+var ErrInsufficientShares = errors.New("not enough shares held")
+
+// Shares is a quantity of a security in millionths of a share
+type Shares int64
+
+// NewShares converts a share count to Shares, rounding to the nearest millionth
+func NewShares(quantity float64) Shares {
+	return Shares(math.Round(quantity * sharesScale))
+}
+
+// Float converts Shares back to a share count
+func (s Shares) Float() float64 {
+	return float64(s) / sharesScale
+}
+
+// String formats Shares as a share count without trailing zeros
+func (s Shares) String() string {
+	return strconv.FormatFloat(s.Float(), 'f', -1, 64)
+}
+
+// Security is a stock, fund or other instrument a user holds. Prices are
+// looked up by Symbol and Currency.
+type Security struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_securities_user_symbol,priority:1" json:"user_id"`
+	Symbol    string    `gorm:"not null;uniqueIndex:idx_securities_user_symbol,priority:2" json:"symbol"`
+	Name      string    `gorm:"not null" json:"name"`
+	Type      string    `gorm:"not null;default:stock" json:"type"` // stock, etf, mutual_fund, bond, crypto, other
+	Currency  string    `gorm:"not null;default:USD" json:"currency"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// SecurityPrice is the closing price of one share of a symbol on a date.
+// Prices are market data shared by every user holding the symbol; lookups
+// for a date without a price use the nearest earlier one.
+type SecurityPrice struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	Symbol    string    `gorm:"not null;uniqueIndex:idx_security_prices_symbol_date,priority:1" json:"symbol"`
+	Currency  string    `gorm:"size:3;not null;uniqueIndex:idx_security_prices_symbol_date,priority:2" json:"currency"`
+	Date      time.Time `gorm:"not null;uniqueIndex:idx_security_prices_symbol_date,priority:3" json:"date"` // Midnight UTC, see RateDate
+	Price     float64   `gorm:"not null" json:"price"`
+	Source    string    `gorm:"not null" json:"source"` // Provider the price came from
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"-"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"-"`
+}
+
+// InvestmentTransaction is a buy, sell, dividend or split of a security in an
+// investment account. Buys and sells move cash between the account and the
+// holding's cost basis in one journal entry; a dividend is recorded as an
+// income Transaction.
+type InvestmentTransaction struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	UserID         uint      `gorm:"not null;index:idx_investment_transactions_user_id" json:"user_id"`
+	AccountID      uint      `gorm:"not null;index:idx_investment_transactions_account_id" json:"account_id"`
+	SecurityID     uint      `gorm:"not null;index:idx_investment_transactions_security_id" json:"security_id"`
+	Type           string    `gorm:"not null" json:"type"`
+	Date           time.Time `gorm:"not null;index:idx_investment_transactions_date" json:"date"`
+	Quantity       Shares    `gorm:"column:quantity_micro;not null;default:0" json:"quantity_micro"` // Change in shares held
+	Price          float64   `gorm:"not null;default:0" json:"price"`                                // Per share, buys and sells only
+	Amount         Money     `gorm:"column:amount_minor;not null;default:0" json:"amount_minor"`     // Cash paid, received or distributed
+	Fees           Money     `gorm:"column:fees_minor;not null;default:0" json:"fees_minor"`
+	Currency       string    `gorm:"not null;default:USD" json:"currency"`
+	SplitRatio     float64   `gorm:"not null;default:0" json:"split_ratio"` // New shares per old share
+	LotMethod      string    `json:"lot_method,omitempty"`
+	RealizedGain   Money     `gorm:"column:realized_gain_minor;not null;default:0" json:"realized_gain_minor"`
+	JournalEntryID *uint     `json:"journal_entry_id"`
+	TransactionID  *uint     `json:"transaction_id"` // Income transaction of a dividend
+	Notes          string    `json:"notes"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	Security *Security `gorm:"foreignKey:SecurityID" json:"-"`
+}
+
+// Lot is a block of shares bought together. Sales reduce Remaining and take
+// the matching share of RemainingCost; splits change the share counts but
+// not the cost.
+type Lot struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	UserID           uint      `gorm:"not null;index:idx_lots_user_id" json:"user_id"`
+	AccountID        uint      `gorm:"not null;index:idx_lots_account_security,priority:1" json:"account_id"`
+	SecurityID       uint      `gorm:"not null;index:idx_lots_account_security,priority:2" json:"security_id"`
+	BuyTransactionID uint      `gorm:"not null" json:"buy_transaction_id"`
+	AcquiredAt       time.Time `gorm:"not null" json:"acquired_at"`
+	Quantity         Shares    `gorm:"column:quantity_micro;not null" json:"quantity_micro"`
+	Remaining        Shares    `gorm:"column:remaining_micro;not null" json:"remaining_micro"`
+	CostBasis        Money     `gorm:"column:cost_basis_minor;not null" json:"cost_basis_minor"` // Price paid plus fees
+	RemainingCost    Money     `gorm:"column:remaining_cost_minor;not null" json:"remaining_cost_minor"`
+	Currency         string    `gorm:"not null;default:USD" json:"currency"`
+	CreatedAt        time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// RealizedGain is the gain or loss of selling shares from one lot
+type RealizedGain struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	UserID            uint      `gorm:"not null;index:idx_realized_gains_user_sold,priority:1" json:"user_id"`
+	AccountID         uint      `gorm:"not null" json:"account_id"`
+	SecurityID        uint      `gorm:"not null" json:"security_id"`
+	SellTransactionID uint      `gorm:"not null;index:idx_realized_gains_sell_transaction_id" json:"sell_transaction_id"`
+	LotID             uint      `gorm:"not null;index:idx_realized_gains_lot_id" json:"lot_id"`
+	Quantity          Shares    `gorm:"column:quantity_micro;not null" json:"quantity_micro"`
+	Proceeds          Money     `gorm:"column:proceeds_minor;not null" json:"proceeds_minor"` // After fees
+	CostBasis         Money     `gorm:"column:cost_basis_minor;not null" json:"cost_basis_minor"`
+	Gain              Money     `gorm:"column:gain_minor;not null" json:"gain_minor"` // Negative for a loss
+	Currency          string    `gorm:"not null;default:USD" json:"currency"`
+	AcquiredAt        time.Time `gorm:"not null" json:"acquired_at"`
+	SoldAt            time.Time `gorm:"not null;index:idx_realized_gains_user_sold,priority:2" json:"sold_at"`
+	Term              string    `gorm:"not null" json:"term"`
+	TaxCategoryID     uint      `gorm:"not null" json:"tax_category_id"`
+	CreatedAt         time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	Security    *Security    `gorm:"foreignKey:SecurityID" json:"-"`
+	TaxCategory *TaxCategory `gorm:"foreignKey:TaxCategoryID" json:"-"`
+}
+
+// SecurityRequest is the request model for adding a security
+type SecurityRequest struct {
+	Symbol   string `json:"symbol" binding:"required,max=20"`
+	Name     string `json:"name" binding:"required"`
+	Type     string `json:"type" binding:"omitempty,oneof=stock etf mutual_fund bond crypto other"`
+	Currency string `json:"currency" binding:"omitempty,len=3"`
+}
+
+// LotSelection names a lot and how many of its shares a sale takes
+type LotSelection struct {
+	LotID    uint    `json:"lot_id" binding:"required"`
+	Quantity float64 `json:"quantity" binding:"required,gt=0"`
+}
+
+// InvestmentTransactionRequest is the request model for recording an
+// investment transaction. Buys and sells need a quantity and price, a
+// dividend an amount and a split the ratio of new shares per old share.
+type InvestmentTransactionRequest struct {
+	AccountID  uint           `json:"account_id" binding:"required"`
+	SecurityID uint           `json:"security_id" binding:"required"`
+	Type       string         `json:"type" binding:"required,oneof=buy sell dividend split"`
+	Date       time.Time      `json:"date"`
+	Quantity   float64        `json:"quantity" binding:"min=0"`
+	Price      float64        `json:"price" binding:"min=0"`
+	Amount     float64        `json:"amount" binding:"min=0"`
+	Fees       float64        `json:"fees" binding:"min=0"`
+	SplitRatio float64        `json:"split_ratio" binding:"min=0"`
+	LotMethod  string         `json:"lot_method" binding:"omitempty,oneof=fifo lifo specific"`
+	Lots       []LotSelection `json:"lots" binding:"omitempty,dive"`
+	Notes      string         `json:"notes"`
+}
+
+// InvestmentTransactionResponse is the response model for an investment transaction
+type InvestmentTransactionResponse struct {
+	ID             uint      `json:"id"`
+	AccountID      uint      `json:"account_id"`
+	SecurityID     uint      `json:"security_id"`
+	Symbol         string    `json:"symbol,omitempty"`
+	Type           string    `json:"type"`
+	Date           time.Time `json:"date"`
+	Quantity       float64   `json:"quantity"`
+	Price          float64   `json:"price"`
+	Amount         float64   `json:"amount"`
+	Fees           float64   `json:"fees"`
+	Currency       string    `json:"currency"`
+	SplitRatio     float64   `json:"split_ratio,omitempty"`
+	LotMethod      string    `json:"lot_method,omitempty"`
+	RealizedGain   float64   `json:"realized_gain"`
+	JournalEntryID *uint     `json:"journal_entry_id"`
+	TransactionID  *uint     `json:"transaction_id"`
+	Notes          string    `json:"notes"`
+}
+
+// LotResponse is the response model for an open lot
+type LotResponse struct {
+	ID             uint      `json:"id"`
+	AcquiredAt     time.Time `json:"acquired_at"`
+	Quantity       float64   `json:"quantity"`
+	Remaining      float64   `json:"remaining"`
+	CostBasis      float64   `json:"cost_basis"` // Of the remaining shares
+	MarketValue    float64   `json:"market_value"`
+	UnrealizedGain float64   `json:"unrealized_gain"`
+	Term           string    `json:"term"` // Term a sale today would have
+}
+
+// HoldingResponse is the response model for the shares of one security held in an account
+type HoldingResponse struct {
+	AccountID      uint           `json:"account_id"`
+	SecurityID     uint           `json:"security_id"`
+	Symbol         string         `json:"symbol"`
+	Name           string         `json:"name"`
+	Quantity       float64        `json:"quantity"`
+	CostBasis      float64        `json:"cost_basis"`
+	Price          float64        `json:"price"`
+	PriceDate      *time.Time     `json:"price_date"`
+	PriceSource    string         `json:"price_source"` // Provider of the price, or "trade" for the last trade price
+	MarketValue    float64        `json:"market_value"`
+	UnrealizedGain float64        `json:"unrealized_gain"`
+	Currency       string         `json:"currency"`
+	Lots           []*LotResponse `json:"lots"`
+}
+
+// PortfolioAccountResponse is the value of one investment account
+type PortfolioAccountResponse struct {
+	AccountID   uint    `json:"account_id"`
+	Name        string  `json:"name"`
+	Cash        float64 `json:"cash"`
+	MarketValue float64 `json:"market_value"`
+	TotalValue  float64 `json:"total_value"`
+	Currency    string  `json:"currency"`
+}
+
+// PortfolioResponse is the response model for the holdings of a user
+type PortfolioResponse struct {
+	Accounts       []*PortfolioAccountResponse `json:"accounts"`
+	Holdings       []*HoldingResponse          `json:"holdings"`
+	CostBasis      float64                     `json:"cost_basis"`
+	MarketValue    float64                     `json:"market_value"`
+	UnrealizedGain float64                     `json:"unrealized_gain"`
+	Currency       string                      `json:"currency"` // Of the totals
+}
+
+// RealizedGainResponse is the response model for a realized gain
+type RealizedGainResponse struct {
+	ID                uint      `json:"id"`
+	AccountID         uint      `json:"account_id"`
+	SecurityID        uint      `json:"security_id"`
+	Symbol            string    `json:"symbol,omitempty"`
+	SellTransactionID uint      `json:"sell_transaction_id"`
+	LotID             uint      `json:"lot_id"`
+	Quantity          float64   `json:"quantity"`
+	Proceeds          float64   `json:"proceeds"`
+	CostBasis         float64   `json:"cost_basis"`
+	Gain              float64   `json:"gain"`
+	Currency          string    `json:"currency"`
+	AcquiredAt        time.Time `json:"acquired_at"`
+	SoldAt            time.Time `json:"sold_at"`
+	Term              string    `json:"term"`
+	TaxCategoryID     uint      `json:"tax_category_id"`
+}
+
+// RealizedGainsResponse is the response model for the realized gains of a year
+type RealizedGainsResponse struct {
+	Year      int                     `json:"year"`
+	ShortTerm float64                 `json:"short_term"`
+	LongTerm  float64                 `json:"long_term"`
+	Total     float64                 `json:"total"`
+	Currency  string                  `json:"currency"`
+	Gains     []*RealizedGainResponse `json:"gains"`
+}
+
+// LotSale is the part of a sale taken from one lot
+type LotSale struct {
+	Lot      *Lot
+	Quantity Shares
+}
+
+// SelectLots picks the lots a sale of quantity shares is taken from. Lots
+// must be ordered oldest first. The specific method takes exactly the
+// selections given; FIFO and LIFO take whole lots until the last one covers
+// the rest.
+func SelectLots(lots []Lot, quantity Shares, method string, selections []LotSelection) ([]LotSale, error) {
+	var available Shares
+	for _, lot := range lots {
+		available += lot.Remaining
+	}
+	if quantity > available {
+		return nil, ErrInsufficientShares
+	}
+
+	var sales []LotSale
+	switch method {
+	case LotMethodSpecific:
+		if len(selections) == 0 {
+			return nil, errors.New("lots must be selected for a specific lot sale")
+		}
+		byID := make(map[uint]*Lot, len(lots))
+		for i := range lots {
+			byID[lots[i].ID] = &lots[i]
+		}
+		taken := make(map[uint]Shares)
+		var total Shares
+		for _, selection := range selections {
+			lot, ok := byID[selection.LotID]
+			if !ok {
+				return nil, fmt.Errorf("lot %d is not an open lot of this holding", selection.LotID)
+			}
+			shares := NewShares(selection.Quantity)
+			taken[lot.ID] += shares
+			if taken[lot.ID] > lot.Remaining {
+				return nil, fmt.Errorf("lot %d: %w", lot.ID, ErrInsufficientShares)
+			}
+			total += shares
+			sales = append(sales, LotSale{Lot: lot, Quantity: shares})
+		}
+		if total != quantity {
+			return nil, errors.New("selected lots do not add up to the quantity sold")
+		}
+		return sales, nil
+	case LotMethodFIFO, LotMethodLIFO:
+		order := make([]int, len(lots))
+		for i := range lots {
+			order[i] = i
+		}
+		if method == LotMethodLIFO {
+			sort.SliceStable(order, func(i, j int) bool { return order[i] > order[j] })
+		}
+		remaining := quantity
+		for _, i := range order {
+			if remaining == 0 {
+				break
+			}
+			shares := lots[i].Remaining
+			if shares > remaining {
+				shares = remaining
+			}
+			if shares == 0 {
+				continue
+			}
+			sales = append(sales, LotSale{Lot: &lots[i], Quantity: shares})
+			remaining -= shares
+		}
+		return sales, nil
+	default:
+		return nil, fmt.Errorf("unknown lot method %q", method)
+	}
+}
+
+// CostOf returns the cost basis of selling shares from the lot. Selling all
+// remaining shares takes all remaining cost so rounding never leaves cost behind.
+func (l *Lot) CostOf(shares Shares) Money {
+	if shares >= l.Remaining {
+		return l.RemainingCost
+	}
+	return Money(math.Round(float64(l.RemainingCost) * float64(shares) / float64(l.Remaining)))
+}
+
+// GainTerm returns the holding period of shares acquired and sold on the
+// given dates. Shares held for more than a year are long term.
+func GainTerm(acquiredAt, soldAt time.Time) string {
+	if soldAt.After(acquiredAt.AddDate(1, 0, 0)) {
+		return GainTermLong
+	}
+	return GainTermShort
+}
+
+// GainCategory returns the category realized gains of a term are booked under
+func GainCategory(term string) string {
+	if term == GainTermLong {
+		return LongTermGainsCategory
+	}
+	return ShortTermGainsCategory
+}
+
+// HoldingLedgerName returns the ledger account holding the cost basis of a
+// security in an investment account
+func HoldingLedgerName(accountID, securityID uint) string {
+	return fmt.Sprintf("holding:%d:%d", accountID, securityID)
+}
+
+// ValueOf returns what shares are worth at a price per share
+func ValueOf(shares Shares, price float64, currencyCode string) Money {
+	return NewMoney(shares.Float()*price, currencyCode)
+}
+
+// ToResponse converts an InvestmentTransaction to InvestmentTransactionResponse
+func (t *InvestmentTransaction) ToResponse() *InvestmentTransactionResponse {
+	response := &InvestmentTransactionResponse{
+		ID:             t.ID,
+		AccountID:      t.AccountID,
+		SecurityID:     t.SecurityID,
+		Type:           t.Type,
+		Date:           t.Date,
+		Quantity:       t.Quantity.Float(),
+		Price:          t.Price,
+		Amount:         t.Amount.Float(t.Currency),
+		Fees:           t.Fees.Float(t.Currency),
+		Currency:       t.Currency,
+		SplitRatio:     t.SplitRatio,
+		LotMethod:      t.LotMethod,
+		RealizedGain:   t.RealizedGain.Float(t.Currency),
+		JournalEntryID: t.JournalEntryID,
+		TransactionID:  t.TransactionID,
+		Notes:          t.Notes,
+	}
+	if t.Security != nil {
+		response.Symbol = t.Security.Symbol
+	}
+	return response
+}
+
+// ToResponse converts a RealizedGain to RealizedGainResponse
+func (g *RealizedGain) ToResponse() *RealizedGainResponse {
+	response := &RealizedGainResponse{
+		ID:                g.ID,
+		AccountID:         g.AccountID,
+		SecurityID:        g.SecurityID,
+		SellTransactionID: g.SellTransactionID,
+		LotID:             g.LotID,
+		Quantity:          g.Quantity.Float(),
+		Proceeds:          g.Proceeds.Float(g.Currency),
+		CostBasis:         g.CostBasis.Float(g.Currency),
+		Gain:              g.Gain.Float(g.Currency),
+		Currency:          g.Currency,
+		AcquiredAt:        g.AcquiredAt,
+		SoldAt:            g.SoldAt,
+		Term:              g.Term,
+		TaxCategoryID:     g.TaxCategoryID,
+	}
+	if g.Security != nil {
+		response.Symbol = g.Security.Symbol
+	}
+	return response
+}
@@ -0,0 +1,251 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Journal entry types
+const (
+	JournalEntryTypeTransaction      = "transaction"
+	JournalEntryTypeTransfer         = "transfer"
+	JournalEntryTypeRecurring        = "recurring"
+	JournalEntryTypeGoalContribution = "goal_contribution"
+	JournalEntryTypeOpeningBalance   = "opening_balance"
+	JournalEntryTypeAdjustment       = "adjustment"
+	JournalEntryTypeInvestment       = "investment"
+	JournalEntryTypeCorrection       = "balance_correction"
+)
+
+// Nominal ledger accounts used as the counterpart of postings to user accounts
+const (
+	LedgerEquityOpeningBalances = "equity:opening-balances"
+	LedgerEquityAdjustments     = "equity:adjustments"
+	LedgerEquityGoalAllocations = "equity:goal-allocations"
+	LedgerEquityCorrections     = "equity:balance-corrections"
+	LedgerEquityConversions     = "equity:currency-conversions" // Counterpart of each side of a transfer between currencies
+	LedgerEquityTransfers       = "equity:transfers"            // Counterpart of a transfer leg posted without its other leg, or filed under TransferCategory
+)
+
+// ErrUnbalancedEntry is returned when the postings of a journal entry do not sum to zero
+var ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+
+// JournalEntry is a balanced double-entry record. Every transaction, transfer,
+// recurring run and goal contribution is backed by exactly one entry.
+type JournalEntry struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index:idx_journal_entries_user_id" json:"user_id"`
+	Type        string    `gorm:"not null;index:idx_journal_entries_type" json:"type"`
+	Description string    `json:"description"`
+	Date        time.Time `gorm:"not null;index:idx_journal_entries_date" json:"date"`
+	GoalID      *uint     `gorm:"index:idx_journal_entries_goal_id" json:"goal_id"`
+	Postings    []Posting `gorm:"foreignKey:JournalEntryID" json:"postings"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Posting is one leg of a journal entry. Amounts are signed minor units:
+// debits are positive and credits are negative, so the postings of a
+// balanced entry sum to zero per currency. Postings to a user Account carry
+// its AccountID and the account balance is the sum of those postings.
+type Posting struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	JournalEntryID uint   `gorm:"not null;index:idx_postings_journal_entry_id" json:"journal_entry_id"`
+	UserID         uint   `gorm:"not null;index:idx_postings_user_id" json:"user_id"`
+	AccountID      *uint  `gorm:"index:idx_postings_account_id" json:"account_id"`
+	LedgerAccount  string `gorm:"not null;index:idx_postings_ledger_account" json:"ledger_account"` // e.g. account:12, expense:Shopping, income:Salary
+	Amount         Money  `gorm:"column:amount_minor;not null" json:"amount_minor"`
+	Currency       string `gorm:"not null;default:USD" json:"currency"`
+}
+
+// PostingResponse is the response model for a posting
+type PostingResponse struct {
+	ID            uint    `json:"id"`
+	AccountID     *uint   `json:"account_id"`
+	LedgerAccount string  `json:"ledger_account"`
+	Debit         float64 `json:"debit"`
+	Credit        float64 `json:"credit"`
+	Currency      string  `json:"currency"`
+}
+
+// JournalEntryResponse is the response model for a journal entry
+type JournalEntryResponse struct {
+	ID          uint               `json:"id"`
+	Type        string             `json:"type"`
+	Description string             `json:"description"`
+	Date        time.Time          `json:"date"`
+	GoalID      *uint              `json:"goal_id,omitempty"`
+	Postings    []*PostingResponse `json:"postings"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// LedgerCurrencyTotals holds the debit and credit totals for one currency
+type LedgerCurrencyTotals struct {
+	Currency   string  `json:"currency"`
+	Debits     float64 `json:"debits"`
+	Credits    float64 `json:"credits"`
+	Difference float64 `json:"difference"`
+}
+
+// LedgerAccountMismatch describes an account whose stored balance differs from its postings
+type LedgerAccountMismatch struct {
+	AccountID     uint    `json:"account_id"`
+	AccountName   string  `json:"account_name"`
+	Currency      string  `json:"currency"`
+	StoredBalance float64 `json:"stored_balance"`
+	PostedBalance float64 `json:"posted_balance"`
+}
+
+// LedgerIntegrityReport is the result of verifying a user's books
+type LedgerIntegrityReport struct {
+	Balanced          bool                    `json:"balanced"`
+	EntryCount        int64                   `json:"entry_count"`
+	Currencies        []LedgerCurrencyTotals  `json:"currencies"`
+	UnbalancedEntries []uint                  `json:"unbalanced_entries"`
+	AccountMismatches []LedgerAccountMismatch `json:"account_mismatches"`
+	CheckedAt         time.Time               `json:"checked_at"`
+}
+
+// AccountLedgerName returns the ledger account name for a user Account
+func AccountLedgerName(accountID uint) string {
+	return fmt.Sprintf("account:%d", accountID)
+}
+
+// GoalLedgerName returns the ledger account name for a goal
+func GoalLedgerName(goalID uint) string {
+	return fmt.Sprintf("goal:%d", goalID)
+}
+
+// CategoryLedgerName returns the nominal income or expense ledger account for
+// a category. Money filed under TransferCategory stays with the user and is
+// posted to transfer equity instead.
+func CategoryLedgerName(transactionType, category string) string {
+	if category == "" {
+		category = "Uncategorized"
+	}
+	if category == TransferCategory {
+		return LedgerEquityTransfers
+	}
+	if transactionType == "income" {
+		return "income:" + category
+	}
+	return "expense:" + category
+}
+
+// NewAccountPosting creates a posting against a user Account
+func NewAccountPosting(userID, accountID uint, amount Money, currency string) Posting {
+	return Posting{
+		UserID:        userID,
+		AccountID:     &accountID,
+		LedgerAccount: AccountLedgerName(accountID),
+		Amount:        amount,
+		Currency:      currency,
+	}
+}
+
+// NewLedgerPosting creates a posting against a nominal ledger account
+func NewLedgerPosting(userID uint, ledgerAccount string, amount Money, currency string) Posting {
+	return Posting{
+		UserID:        userID,
+		LedgerAccount: ledgerAccount,
+		Amount:        amount,
+		Currency:      currency,
+	}
+}
+
+// NewTransactionJournalEntry builds the journal entry for an income or expense
+// transaction. Income debits the account and credits the income category;
+// anything else credits the account and debits the expense category. Split
+// transactions get one category posting per split line.
+func NewTransactionJournalEntry(t *Transaction, entryType string) *JournalEntry {
+	sign := Money(1)
+	if t.Type != "income" {
+		sign = -1
+	}
+
+	postings := []Posting{NewAccountPosting(t.UserID, t.AccountID, sign*t.Amount, t.Currency)}
+	if t.IsSplit() {
+		for _, split := range t.Splits {
+			postings = append(postings, NewLedgerPosting(t.UserID, CategoryLedgerName(t.Type, split.Category), -sign*split.Amount, t.Currency))
+		}
+	} else {
+		postings = append(postings, NewLedgerPosting(t.UserID, CategoryLedgerName(t.Type, t.Category), -sign*t.Amount, t.Currency))
+	}
+
+	return &JournalEntry{
+		UserID:      t.UserID,
+		Type:        entryType,
+		Description: t.Description,
+		Date:        t.Date,
+		Postings:    postings,
+	}
+}
+
+// NewTransferLegJournalEntry builds the journal entry for one leg of a
+// transfer on its own, as for transfers recorded before the ledger existed.
+// The outgoing leg credits the account and the incoming leg debits it, with
+// the opposite leg on transfer equity.
+func NewTransferLegJournalEntry(t *Transaction, outgoing bool, entryType string) *JournalEntry {
+	sign := Money(1)
+	if outgoing {
+		sign = -1
+	}
+
+	return &JournalEntry{
+		UserID:      t.UserID,
+		Type:        entryType,
+		Description: t.Description,
+		Date:        t.Date,
+		Postings: []Posting{
+			NewAccountPosting(t.UserID, t.AccountID, sign*t.Amount, t.Currency),
+			NewLedgerPosting(t.UserID, LedgerEquityTransfers, -sign*t.Amount, t.Currency),
+		},
+	}
+}
+
+// Validate checks that the entry has at least two postings and balances per currency
+func (e *JournalEntry) Validate() error {
+	if len(e.Postings) < 2 {
+		return ErrUnbalancedEntry
+	}
+
+	totals := MoneyTotals{}
+	for _, p := range e.Postings {
+		totals.Add(p.Currency, p.Amount)
+	}
+	for _, total := range totals {
+		if total != 0 {
+			return ErrUnbalancedEntry
+		}
+	}
+	return nil
+}
+
+// ToResponse converts a JournalEntry to JournalEntryResponse
+func (e *JournalEntry) ToResponse() *JournalEntryResponse {
+	postings := make([]*PostingResponse, 0, len(e.Postings))
+	for _, p := range e.Postings {
+		response := &PostingResponse{
+			ID:            p.ID,
+			AccountID:     p.AccountID,
+			LedgerAccount: p.LedgerAccount,
+			Currency:      p.Currency,
+		}
+		if p.Amount >= 0 {
+			response.Debit = p.Amount.Float(p.Currency)
+		} else {
+			response.Credit = (-p.Amount).Float(p.Currency)
+		}
+		postings = append(postings, response)
+	}
+
+	return &JournalEntryResponse{
+		ID:          e.ID,
+		Type:        e.Type,
+		Description: e.Description,
+		Date:        e.Date,
+		GoalID:      e.GoalID,
+		Postings:    postings,
+		CreatedAt:   e.CreatedAt,
+	}
+}
@@ -0,0 +1,321 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// AccountTypeLoan is the account type of loan and mortgage accounts
+const AccountTypeLoan = "loan"
+
+// Loan statuses
+const (
+	LoanStatusActive  = "active"
+	LoanStatusPaidOff = "paid_off"
+)
+
+// Default categories of the two parts of a loan payment
+const (
+	DefaultLoanPrincipalCategory = "Loan Principal"
+	DefaultLoanInterestCategory  = "Loan Interest"
+)
+
+// maxAmortizationPayments bounds a schedule so that a payment barely above
+// the interest cannot produce an endless one
+const maxAmortizationPayments = 1200
+
+// ErrLoanPaymentTooLow is returned when a payment does not cover the interest it accrues
+var ErrLoanPaymentTooLow = errors.New("loan payment does not cover the interest")
+
+// Loan holds the terms of a loan account. The account balance stays signed:
+// the principal still owed is a negative balance. Payments are made from
+// PaymentAccountID; the principal part moves to the loan account as a
+// transfer and the interest part is booked as an expense.
+type Loan struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	UserID            uint      `gorm:"not null;index:idx_loans_user_id" json:"user_id"`
+	AccountID         uint      `gorm:"not null;uniqueIndex:idx_loans_account_id" json:"account_id"`
+	PaymentAccountID  uint      `gorm:"not null;index:idx_loans_payment_account_id" json:"payment_account_id"`
+	Principal         Money     `gorm:"column:principal_minor;not null" json:"principal_minor"` // Amount originally borrowed, in minor units of the account currency
+	InterestRate      float64   `gorm:"not null" json:"interest_rate"`                          // Annual rate in percent, compounded monthly
+	TermMonths        int       `gorm:"not null" json:"term_months"`
+	FirstPaymentDate  time.Time `gorm:"not null" json:"first_payment_date"` // Later payments fall on the same day of the month
+	MonthlyPayment    Money     `gorm:"column:monthly_payment_minor;not null" json:"monthly_payment_minor"`
+	ExtraPayment      Money     `gorm:"column:extra_payment_minor;not null;default:0" json:"extra_payment_minor"` // Paid towards principal with every payment
+	PrincipalCategory string    `gorm:"not null" json:"principal_category"`
+	InterestCategory  string    `gorm:"not null" json:"interest_category"`
+	PaymentsMade      int       `gorm:"not null;default:0" json:"payments_made"`
+	NextPaymentDate   time.Time `gorm:"not null;index:idx_loans_next_payment_date" json:"next_payment_date"`
+	Status            string    `gorm:"not null;default:active;index:idx_loans_status" json:"status"`
+	CreatedAt         time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// LoanPayment is a payment posted for a loan. Extra payments made outside the
+// schedule have no interest and a zero Number.
+type LoanPayment struct {
+	ID                     uint      `gorm:"primaryKey" json:"id"`
+	LoanID                 uint      `gorm:"not null;index:idx_loan_payments_loan_id" json:"loan_id"`
+	UserID                 uint      `gorm:"not null;index:idx_loan_payments_user_id" json:"user_id"`
+	Number                 int       `gorm:"not null" json:"number"`
+	Date                   time.Time `gorm:"not null" json:"date"`
+	Principal              Money     `gorm:"column:principal_minor;not null" json:"principal_minor"`
+	Interest               Money     `gorm:"column:interest_minor;not null" json:"interest_minor"`
+	Balance                Money     `gorm:"column:balance_minor;not null" json:"balance_minor"` // Principal still owed after the payment
+	Currency               string    `gorm:"not null;default:USD" json:"currency"`
+	PrincipalTransactionID *uint     `json:"principal_transaction_id"` // Outgoing leg of the principal transfer
+	InterestTransactionID  *uint     `json:"interest_transaction_id"`
+	CreatedAt              time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// LoanRequest is the request model for setting the terms of a loan account
+type LoanRequest struct {
+	PaymentAccountID  uint      `json:"payment_account_id" binding:"required"`
+	Principal         float64   `json:"principal" binding:"required,gt=0"`
+	InterestRate      float64   `json:"interest_rate" binding:"min=0,max=100"`
+	TermMonths        int       `json:"term_months" binding:"required,min=1,max=1200"`
+	FirstPaymentDate  time.Time `json:"first_payment_date" binding:"required"`
+	ExtraPayment      float64   `json:"extra_payment" binding:"min=0"`
+	PrincipalCategory string    `json:"principal_category"`
+	InterestCategory  string    `json:"interest_category"`
+}
+
+// LoanExtraPaymentRequest is the request model for a one-off payment towards principal
+type LoanExtraPaymentRequest struct {
+	Amount float64   `json:"amount" binding:"required,gt=0"`
+	Date   time.Time `json:"date"`
+}
+
+// LoanScenario is a what-if payment plan for a loan
+type LoanScenario struct {
+	ExtraPayment float64 `json:"extra_payment" binding:"min=0"` // Added to every remaining payment
+	LumpSum      float64 `json:"lump_sum" binding:"min=0"`      // Paid towards principal right away
+}
+
+// LoanScenariosRequest is the request model for comparing payment plans of a loan
+type LoanScenariosRequest struct {
+	Scenarios []LoanScenario `json:"scenarios" binding:"required,min=1,max=20,dive"`
+}
+
+// LoanResponse is the response model for a loan
+type LoanResponse struct {
+	AccountID         uint      `json:"account_id"`
+	PaymentAccountID  uint      `json:"payment_account_id"`
+	Principal         float64   `json:"principal"`
+	InterestRate      float64   `json:"interest_rate"`
+	TermMonths        int       `json:"term_months"`
+	FirstPaymentDate  time.Time `json:"first_payment_date"`
+	MonthlyPayment    float64   `json:"monthly_payment"`
+	ExtraPayment      float64   `json:"extra_payment"`
+	PrincipalCategory string    `json:"principal_category"`
+	InterestCategory  string    `json:"interest_category"`
+	PaymentsMade      int       `json:"payments_made"`
+	NextPaymentDate   time.Time `json:"next_payment_date"`
+	Status            string    `json:"status"`
+	Currency          string    `json:"currency"`
+	RemainingBalance  float64   `json:"remaining_balance"`
+}
+
+// LoanPaymentResponse is the response model for a posted loan payment
+type LoanPaymentResponse struct {
+	ID        uint      `json:"id"`
+	Number    int       `json:"number"`
+	Date      time.Time `json:"date"`
+	Principal float64   `json:"principal"`
+	Interest  float64   `json:"interest"`
+	Total     float64   `json:"total"`
+	Balance   float64   `json:"balance"`
+	Currency  string    `json:"currency"`
+}
+
+// AmortizationRow is one payment of an amortization schedule
+type AmortizationRow struct {
+	Number    int
+	Date      time.Time
+	Principal Money
+	Interest  Money
+	Balance   Money // Principal still owed after the payment
+}
+
+// AmortizationSchedule is the remaining payments of a loan until it is paid off
+type AmortizationSchedule struct {
+	Rows          []AmortizationRow
+	TotalInterest Money
+	TotalPaid     Money
+}
+
+// AmortizationRowResponse is the response model for a scheduled payment
+type AmortizationRowResponse struct {
+	Number    int       `json:"number"`
+	Date      time.Time `json:"date"`
+	Payment   float64   `json:"payment"`
+	Principal float64   `json:"principal"`
+	Interest  float64   `json:"interest"`
+	Balance   float64   `json:"balance"`
+}
+
+// AmortizationScheduleResponse is the response model for the remaining schedule of a loan
+type AmortizationScheduleResponse struct {
+	RemainingBalance float64                    `json:"remaining_balance"`
+	MonthlyPayment   float64                    `json:"monthly_payment"`
+	ExtraPayment     float64                    `json:"extra_payment"`
+	TotalInterest    float64                    `json:"total_interest"`
+	TotalPaid        float64                    `json:"total_paid"`
+	PayoffDate       *time.Time                 `json:"payoff_date"`
+	Currency         string                     `json:"currency"`
+	Payments         []*AmortizationRowResponse `json:"payments"`
+}
+
+// LoanScenarioResult is the outcome of a payment plan compared to the current one
+type LoanScenarioResult struct {
+	ExtraPayment     float64    `json:"extra_payment"`
+	LumpSum          float64    `json:"lump_sum"`
+	RemainingBalance float64    `json:"remaining_balance"`
+	TotalInterest    float64    `json:"total_interest"`
+	PayoffDate       *time.Time `json:"payoff_date"`
+	Payments         int        `json:"payments"`
+	InterestSaved    float64    `json:"interest_saved"`
+	MonthsSaved      int        `json:"months_saved"`
+}
+
+// LoanScenariosResponse is the response model for comparing payment plans of a loan
+type LoanScenariosResponse struct {
+	Currency  string                `json:"currency"`
+	Current   *LoanScenarioResult   `json:"current"`
+	Scenarios []*LoanScenarioResult `json:"scenarios"`
+}
+
+// MonthlyRate returns the monthly interest rate of the loan as a fraction
+func (l *Loan) MonthlyRate() float64 {
+	return l.InterestRate / 100 / 12
+}
+
+// InterestOn returns the interest a month accrues on an owed balance
+func (l *Loan) InterestOn(owed Money) Money {
+	return Money(math.Round(float64(owed) * l.MonthlyRate()))
+}
+
+// PaymentDate returns the date of the payment n months after the first one,
+// using the last day of the month when the month is shorter
+func (l *Loan) PaymentDate(n int) time.Time {
+	first := l.FirstPaymentDate
+	month := time.Date(first.Year(), first.Month()+time.Month(n), 1, 0, 0, 0, 0, first.Location())
+	return CycleDay(month.Year(), month.Month(), first.Day(), first.Location())
+}
+
+// Amortize builds the schedule that pays off an owed balance with the
+// regular payment plus extra, starting with payment number next
+func (l *Loan) Amortize(owed Money, next int, extra Money) (*AmortizationSchedule, error) {
+	schedule := &AmortizationSchedule{Rows: []AmortizationRow{}}
+	balance := owed
+	for n := next; balance > 0; n++ {
+		if len(schedule.Rows) >= maxAmortizationPayments {
+			return nil, ErrLoanPaymentTooLow
+		}
+
+		interest := l.InterestOn(balance)
+		principal := l.MonthlyPayment + extra - interest
+		if principal <= 0 {
+			return nil, ErrLoanPaymentTooLow
+		}
+		if principal > balance {
+			principal = balance
+		}
+		balance -= principal
+
+		schedule.Rows = append(schedule.Rows, AmortizationRow{
+			Number:    n + 1,
+			Date:      l.PaymentDate(n),
+			Principal: principal,
+			Interest:  interest,
+			Balance:   balance,
+		})
+		schedule.TotalInterest += interest
+		schedule.TotalPaid += principal + interest
+	}
+	return schedule, nil
+}
+
+// PayoffDate returns the date of the last payment, or nil when nothing is owed
+func (s *AmortizationSchedule) PayoffDate() *time.Time {
+	if len(s.Rows) == 0 {
+		return nil
+	}
+	date := s.Rows[len(s.Rows)-1].Date
+	return &date
+}
+
+// LoanPaymentAmount returns the fixed monthly payment that pays off principal
+// over months at an annual rate in percent
+func LoanPaymentAmount(principal Money, annualRate float64, months int) Money {
+	if months <= 0 {
+		return principal
+	}
+	rate := annualRate / 100 / 12
+	if rate == 0 {
+		return Money(math.Ceil(float64(principal) / float64(months)))
+	}
+	payment := float64(principal) * rate / (1 - math.Pow(1+rate, -float64(months)))
+	return Money(math.Ceil(payment))
+}
+
+// ToResponse converts a Loan to LoanResponse
+func (l *Loan) ToResponse(account *Account) *LoanResponse {
+	return &LoanResponse{
+		AccountID:         l.AccountID,
+		PaymentAccountID:  l.PaymentAccountID,
+		Principal:         l.Principal.Float(account.Currency),
+		InterestRate:      l.InterestRate,
+		TermMonths:        l.TermMonths,
+		FirstPaymentDate:  l.FirstPaymentDate,
+		MonthlyPayment:    l.MonthlyPayment.Float(account.Currency),
+		ExtraPayment:      l.ExtraPayment.Float(account.Currency),
+		PrincipalCategory: l.PrincipalCategory,
+		InterestCategory:  l.InterestCategory,
+		PaymentsMade:      l.PaymentsMade,
+		NextPaymentDate:   l.NextPaymentDate,
+		Status:            l.Status,
+		Currency:          account.Currency,
+		RemainingBalance:  Owed(account.Balance).Float(account.Currency),
+	}
+}
+
+// ToResponse converts a LoanPayment to LoanPaymentResponse
+func (p *LoanPayment) ToResponse() *LoanPaymentResponse {
+	return &LoanPaymentResponse{
+		ID:        p.ID,
+		Number:    p.Number,
+		Date:      p.Date,
+		Principal: p.Principal.Float(p.Currency),
+		Interest:  p.Interest.Float(p.Currency),
+		Total:     (p.Principal + p.Interest).Float(p.Currency),
+		Balance:   p.Balance.Float(p.Currency),
+		Currency:  p.Currency,
+	}
+}
+
+// ToResponse converts an AmortizationSchedule to AmortizationScheduleResponse
+func (s *AmortizationSchedule) ToResponse(loan *Loan, owed Money, currency string) *AmortizationScheduleResponse {
+	response := &AmortizationScheduleResponse{
+		RemainingBalance: owed.Float(currency),
+		MonthlyPayment:   loan.MonthlyPayment.Float(currency),
+		ExtraPayment:     loan.ExtraPayment.Float(currency),
+		TotalInterest:    s.TotalInterest.Float(currency),
+		TotalPaid:        s.TotalPaid.Float(currency),
+		PayoffDate:       s.PayoffDate(),
+		Currency:         currency,
+		Payments:         make([]*AmortizationRowResponse, 0, len(s.Rows)),
+	}
+	for _, row := range s.Rows {
+		response.Payments = append(response.Payments, &AmortizationRowResponse{
+			Number:    row.Number,
+			Date:      row.Date,
+			Payment:   (row.Principal + row.Interest).Float(currency),
+			Principal: row.Principal.Float(currency),
+			Interest:  row.Interest.Float(currency),
+			Balance:   row.Balance.Float(currency),
+		})
+	}
+	return response
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact monetary amount stored as integer minor units
+// (cents for USD, whole yen for JPY). The number of minor units per major
+// unit comes from the Decimals of the currency the amount is denominated in,
+// so a Money value is only meaningful together with its currency code.
+type Money int64
+
+// ErrInvalidAmount is returned when an amount cannot be parsed
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// CurrencyDecimals returns the number of minor unit digits for a currency,
+// defaulting to 2 for unknown currencies
+func CurrencyDecimals(currencyCode string) int {
+	if currency := GetCurrencyByCode(currencyCode); currency != nil {
+		return currency.Decimals
+	}
+	return 2
+}
+
+// minorUnitScale returns the number of minor units in one major unit
+func minorUnitScale(currencyCode string) int64 {
+	scale := int64(1)
+	for i := 0; i < CurrencyDecimals(currencyCode); i++ {
+		scale *= 10
+	}
+	return scale
+}
+
+// NewMoney converts an amount in major units to Money, rounding half away
+// from zero to the currency's minor unit
+func NewMoney(amount float64, currencyCode string) Money {
+	return Money(math.Round(amount * float64(minorUnitScale(currencyCode))))
+}
+
+// ParseMoney parses a plain decimal string such as "-1234.56" into Money
+// without going through floating point. Extra fractional digits beyond the
+// currency's minor unit are rounded half away from zero.
+func ParseMoney(value string, currencyCode string) (Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := false
+	switch value[0] {
+	case '-':
+		negative = true
+		value = value[1:]
+	case '+':
+		value = value[1:]
+	}
+
+	whole, frac, _ := strings.Cut(value, ".")
+	if whole == "" && frac == "" {
+		return 0, ErrInvalidAmount
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	for _, part := range []string{whole, frac} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return 0, ErrInvalidAmount
+			}
+		}
+	}
+
+	decimals := CurrencyDecimals(currencyCode)
+	roundUp := false
+	if len(frac) > decimals {
+		roundUp = frac[decimals] >= '5'
+		frac = frac[:decimals]
+	}
+	frac += strings.Repeat("0", decimals-len(frac))
+
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	if roundUp {
+		units++
+	}
+	if negative {
+		units = -units
+	}
+
+	return Money(units), nil
+}
+
+// Float returns the amount in major units. Use it only at presentation
+// boundaries; arithmetic should stay in Money.
+func (m Money) Float(currencyCode string) float64 {
+	return float64(m) / float64(minorUnitScale(currencyCode))
+}
+
+// Format returns the amount as a fixed-point decimal string, e.g. "-12.30"
+func (m Money) Format(currencyCode string) string {
+	decimals := CurrencyDecimals(currencyCode)
+	units := int64(m)
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	digits := strconv.FormatInt(units, 10)
+	if decimals == 0 {
+		return sign + digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-decimals] + "." + digits[len(digits)-decimals:]
+}
+
+// Abs returns the absolute value of the amount
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// MoneyTotals accumulates amounts keyed by currency so that sums stay exact
+// even when the amounts are denominated in different currencies
+type MoneyTotals map[string]Money
+
+// Add adds an amount in the given currency
+func (t MoneyTotals) Add(currencyCode string, amount Money) {
+	t[currencyCode] += amount
+}
+
+// Sum converts every per-currency total into currencyCode and adds them up
+func (t MoneyTotals) Sum(currencyCode string) Money {
+	var total Money
+	for code, amount := range t {
+		total += ConvertMoney(amount, code, currencyCode)
+	}
+	return total
+}
+
+// ReportingCurrency picks the currency totals should be reported in: the
+// shared currency when every amount is in the same currency, otherwise USD
+func ReportingCurrency(totals ...MoneyTotals) string {
+	currency := ""
+	for _, t := range totals {
+		for code := range t {
+			if currency != "" && currency != code {
+				return "USD"
+			}
+			currency = code
+		}
+	}
+	if currency == "" {
+		return "USD"
+	}
+	return currency
+}
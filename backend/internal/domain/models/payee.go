@@ -0,0 +1,231 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Payee alias match types
+const (
+	PayeeMatchExact      = "exact"       // Normalized description equals the normalized pattern
+	PayeeMatchContains   = "contains"    // Normalized description contains the normalized pattern
+	PayeeMatchStartsWith = "starts_with" // Normalized description starts with the normalized pattern
+	PayeeMatchRegex      = "regex"       // Description matches the pattern, ignoring case
+)
+
+// ErrPayeeNotFound is returned when a payee does not exist or belongs to another user
+var ErrPayeeNotFound = errors.New("payee not found")
+
+// Payee is a merchant or person a user pays or is paid by
+type Payee struct {
+	ID              uint         `gorm:"primaryKey" json:"id"`
+	UserID          uint         `gorm:"not null;uniqueIndex:idx_payees_user_name,priority:1" json:"user_id"`
+	Name            string       `gorm:"not null;size:255;uniqueIndex:idx_payees_user_name,priority:2" json:"name"`
+	DefaultCategory string       `json:"default_category"` // Category given to transactions without one
+	Aliases         []PayeeAlias `gorm:"foreignKey:PayeeID" json:"aliases,omitempty"`
+	CreatedAt       time.Time    `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// PayeeAlias is another name or a pattern that identifies a payee in transaction descriptions
+type PayeeAlias struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PayeeID   uint      `gorm:"not null;index:idx_payee_aliases_payee_id" json:"payee_id"`
+	Pattern   string    `gorm:"not null" json:"pattern"`
+	MatchType string    `gorm:"not null;default:exact" json:"match_type"` // exact, contains, starts_with or regex
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// PayeeAliasRequest is the request model for a payee alias
+type PayeeAliasRequest struct {
+	Pattern   string `json:"pattern" binding:"required"`
+	MatchType string `json:"match_type" binding:"omitempty,oneof=exact contains starts_with regex"`
+}
+
+// PayeeRequest is the request model for creating/updating a payee. Aliases
+// replace the aliases of the payee.
+type PayeeRequest struct {
+	Name            string              `json:"name" binding:"required,max=255"`
+	DefaultCategory string              `json:"default_category"`
+	Aliases         []PayeeAliasRequest `json:"aliases" binding:"omitempty,dive"`
+}
+
+// MergePayeesRequest is the request model for merging duplicate payees into another payee
+type MergePayeesRequest struct {
+	SourceIDs []uint `json:"source_ids" binding:"required,min=1"`
+	TargetID  uint   `json:"target_id" binding:"required"`
+}
+
+// PayeeResponse is the response model for a payee
+type PayeeResponse struct {
+	ID               uint                 `json:"id"`
+	Name             string               `json:"name"`
+	DefaultCategory  string               `json:"default_category"`
+	Aliases          []PayeeAliasResponse `json:"aliases"`
+	TransactionCount int64                `json:"transaction_count"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+}
+
+// PayeeAliasResponse is the response model for a payee alias
+type PayeeAliasResponse struct {
+	ID        uint   `json:"id"`
+	Pattern   string `json:"pattern"`
+	MatchType string `json:"match_type"`
+}
+
+// PayeeSpendingReport totals the transactions of each payee over a period
+type PayeeSpendingReport struct {
+	Currency  string           `json:"currency"`
+	StartDate time.Time        `json:"start_date"`
+	EndDate   time.Time        `json:"end_date"`
+	Payees    []*PayeeSpending `json:"payees"`
+}
+
+// PayeeSpending is what was spent at and received from one payee, ordered
+// by spending in a report
+type PayeeSpending struct {
+	PayeeID  uint                  `json:"payee_id"`
+	Name     string                `json:"name"`
+	Spent    float64               `json:"spent"`
+	Received float64               `json:"received"`
+	Count    int                   `json:"count"`
+	LastDate time.Time             `json:"last_date"`
+	ByMonth  []*PayeeMonthSpending `json:"by_month"`
+}
+
+// PayeeMonthSpending is what was spent at and received from a payee in one month
+type PayeeMonthSpending struct {
+	Month    string  `json:"month"` // YYYY-MM
+	Spent    float64 `json:"spent"`
+	Received float64 `json:"received"`
+}
+
+// ToResponse converts a Payee to a PayeeResponse
+func (p *Payee) ToResponse(transactionCount int64) *PayeeResponse {
+	aliases := make([]PayeeAliasResponse, 0, len(p.Aliases))
+	for _, alias := range p.Aliases {
+		aliases = append(aliases, PayeeAliasResponse{ID: alias.ID, Pattern: alias.Pattern, MatchType: alias.MatchType})
+	}
+
+	return &PayeeResponse{
+		ID:               p.ID,
+		Name:             p.Name,
+		DefaultCategory:  p.DefaultCategory,
+		Aliases:          aliases,
+		TransactionCount: transactionCount,
+		CreatedAt:        p.CreatedAt,
+		UpdatedAt:        p.UpdatedAt,
+	}
+}
+
+// payeeReference matches the reference codes card processors append to
+// merchant names, such as "*2K4" in "AMZN Mktp US*2K4"
+var payeeReference = regexp.MustCompile(`[*#].*$`)
+
+// payeeDomain matches a web domain ending such as ".com" in "Amazon.com"
+var payeeDomain = regexp.MustCompile(`(?i)\.(com|net|org|co|io)(\.[a-z]{2})?\b`)
+
+// PayeeDisplayName cleans a transaction description into a payee name by
+// dropping reference codes, web domain endings and store or terminal numbers
+func PayeeDisplayName(description string) string {
+	name := payeeReference.ReplaceAllString(description, "")
+	name = payeeDomain.ReplaceAllString(name, "")
+
+	var words []string
+	for _, word := range strings.Fields(name) {
+		if countDigits(word) < 3 {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// NormalizePayeeName reduces a payee name or transaction description to the
+// lower-case words that identify the payee, so that variants of the same
+// merchant name compare equal
+func NormalizePayeeName(name string) string {
+	name = strings.ToLower(PayeeDisplayName(name))
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// countDigits counts the digits in s
+func countDigits(s string) int {
+	count := 0
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			count++
+		}
+	}
+	return count
+}
+
+// Matches reports whether a transaction description matches the alias
+func (a *PayeeAlias) Matches(description string) bool {
+	normalized := NormalizePayeeName(description)
+	switch a.MatchType {
+	case PayeeMatchContains:
+		pattern := NormalizePayeeName(a.Pattern)
+		return pattern != "" && strings.Contains(" "+normalized+" ", " "+pattern+" ")
+	case PayeeMatchStartsWith:
+		pattern := NormalizePayeeName(a.Pattern)
+		return pattern != "" && (normalized == pattern || strings.HasPrefix(normalized, pattern+" "))
+	case PayeeMatchRegex:
+		re, err := regexp.Compile("(?i)" + a.Pattern)
+		return err == nil && re.MatchString(description)
+	default:
+		return normalized != "" && normalized == NormalizePayeeName(a.Pattern)
+	}
+}
+
+// MatchPayee finds the payee a transaction description belongs to. Payee
+// names and exact aliases are tried first, then contains and starts_with
+// patterns from the longest down, then regular expressions.
+func MatchPayee(payees []Payee, description string) *Payee {
+	normalized := NormalizePayeeName(description)
+	if normalized == "" {
+		return nil
+	}
+
+	type candidate struct {
+		payee *Payee
+		alias *PayeeAlias
+	}
+	var patterns, regexes []candidate
+	for i := range payees {
+		payee := &payees[i]
+		if NormalizePayeeName(payee.Name) == normalized {
+			return payee
+		}
+		for j := range payee.Aliases {
+			alias := &payee.Aliases[j]
+			switch alias.MatchType {
+			case PayeeMatchContains, PayeeMatchStartsWith:
+				patterns = append(patterns, candidate{payee, alias})
+			case PayeeMatchRegex:
+				regexes = append(regexes, candidate{payee, alias})
+			default:
+				if alias.Matches(description) {
+					return payee
+				}
+			}
+		}
+	}
+
+	// The most specific pattern wins
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return len(patterns[i].alias.Pattern) > len(patterns[j].alias.Pattern)
+	})
+	for _, c := range append(patterns, regexes...) {
+		if c.alias.Matches(description) {
+			return c.payee
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Reconciliation statuses
+const (
+	ReconciliationStatusInProgress = "in_progress"
+	ReconciliationStatusCompleted  = "completed"
+)
+
+// ErrTransactionReconciled is returned when a reconciled transaction is edited or deleted
+var ErrTransactionReconciled = errors.New("transaction has been reconciled and is locked")
+
+// Reconciliation is a session matching an account against a bank statement.
+// Transactions the bank has processed are ticked as cleared; once the cleared
+// balance equals the statement balance the session is completed and its
+// cleared transactions are locked.
+type Reconciliation struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	UserID           uint       `gorm:"not null;index:idx_reconciliations_user_id" json:"user_id"`
+	AccountID        uint       `gorm:"not null;index:idx_reconciliations_account_id" json:"account_id"`
+	Status           string     `gorm:"not null;default:in_progress;index:idx_reconciliations_status" json:"status"`
+	StatementDate    time.Time  `gorm:"not null" json:"statement_date"`
+	StatementBalance Money      `gorm:"column:statement_balance_minor;not null" json:"statement_balance_minor"` // Minor units of the account currency
+	ClearedBalance   Money      `gorm:"column:cleared_balance_minor;not null;default:0" json:"cleared_balance_minor"`
+	Adjustment       Money      `gorm:"column:adjustment_minor;not null;default:0" json:"adjustment_minor"` // Balance adjustment posted on completion
+	Currency         string     `gorm:"not null;default:USD" json:"currency"`
+	ClearedCount     int        `gorm:"not null;default:0" json:"cleared_count"`
+	CompletedAt      *time.Time `json:"completed_at"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// StartReconciliationRequest is the request model for starting a reconciliation
+type StartReconciliationRequest struct {
+	AccountID        uint      `json:"account_id" binding:"required"`
+	StatementDate    time.Time `json:"statement_date" binding:"required"`
+	StatementBalance float64   `json:"statement_balance"`
+}
+
+// ClearTransactionsRequest is the request model for ticking transactions during a reconciliation
+type ClearTransactionsRequest struct {
+	TransactionIDs []uint `json:"transaction_ids" binding:"required,min=1"`
+	Cleared        bool   `json:"cleared"`
+}
+
+// FinishReconciliationRequest is the request model for completing a reconciliation
+type FinishReconciliationRequest struct {
+	CreateAdjustment bool `json:"create_adjustment"` // Post the remaining difference as a balance adjustment
+}
+
+// ReconciliationResponse is the response model for a reconciliation
+type ReconciliationResponse struct {
+	ID               uint                   `json:"id"`
+	AccountID        uint                   `json:"account_id"`
+	Status           string                 `json:"status"`
+	StatementDate    time.Time              `json:"statement_date"`
+	StatementBalance float64                `json:"statement_balance"`
+	ClearedBalance   float64                `json:"cleared_balance"`
+	Difference       float64                `json:"difference"`
+	Adjustment       float64                `json:"adjustment"`
+	Currency         string                 `json:"currency"`
+	ClearedCount     int                    `json:"cleared_count"`
+	CompletedAt      *time.Time             `json:"completed_at"`
+	Transactions     []*TransactionResponse `json:"transactions,omitempty"` // Open transactions up to the statement date
+	CreatedAt        time.Time              `json:"created_at"`
+}
+
+// Difference returns how far the cleared balance is from the statement balance
+func (r *Reconciliation) Difference() Money {
+	return r.StatementBalance - r.ClearedBalance
+}
+
+// StatementCutoff returns the start of the day after the statement date; only
+// transactions dated before it can be cleared in this reconciliation
+func (r *Reconciliation) StatementCutoff() time.Time {
+	year, month, day := r.StatementDate.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, r.StatementDate.Location())
+}
+
+// ToResponse converts a Reconciliation to ReconciliationResponse
+func (r *Reconciliation) ToResponse() *ReconciliationResponse {
+	return &ReconciliationResponse{
+		ID:               r.ID,
+		AccountID:        r.AccountID,
+		Status:           r.Status,
+		StatementDate:    r.StatementDate,
+		StatementBalance: r.StatementBalance.Float(r.Currency),
+		ClearedBalance:   r.ClearedBalance.Float(r.Currency),
+		Difference:       r.Difference().Float(r.Currency),
+		Adjustment:       r.Adjustment.Float(r.Currency),
+		Currency:         r.Currency,
+		ClearedCount:     r.ClearedCount,
+		CompletedAt:      r.CompletedAt,
+		CreatedAt:        r.CreatedAt,
+	}
+}
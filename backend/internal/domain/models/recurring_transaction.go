@@ -1,19 +1,23 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // RecurringTransaction represents a recurring/scheduled transaction
 type RecurringTransaction struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	UserID      uint      `gorm:"not null;index:idx_recurring_user_id" json:"user_id"`
-	Amount      float64   `gorm:"not null" json:"amount"`
+	Amount      Money     `gorm:"column:amount_minor;not null" json:"-"` // Minor units of Currency
+	Currency    string    `gorm:"not null;default:USD" json:"currency"`
 	Description string    `json:"description"`
 	Category    string    `gorm:"index:idx_recurring_category" json:"category"`
 	Type        string    `gorm:"not null;index:idx_recurring_type" json:"type"` // 'income' or 'expense'
 	AccountID   uint      `gorm:"not null;index:idx_recurring_account_id" json:"account_id"`
-	Tags        string    `json:"tags"`
+	Tags        []Tag     `gorm:"many2many:recurring_transaction_tags" json:"tags,omitempty"`
 
 	// Recurrence settings
 	Frequency     string    `gorm:"not null;index:idx_recurring_frequency" json:"frequency"` // daily, weekly, monthly, yearly
@@ -33,6 +37,17 @@ type RecurringTransaction struct {
 
 	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt     time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"` // Set while the recurring transaction is in the trash
+}
+
+// MarshalJSON writes the amount in major units under the name it had
+// before amounts were stored in minor units
+func (r RecurringTransaction) MarshalJSON() ([]byte, error) {
+	type recurringTransaction RecurringTransaction
+	return json.Marshal(struct {
+		recurringTransaction
+		Amount float64 `json:"amount"`
+	}{recurringTransaction(r), r.Amount.Float(r.Currency)})
 }
 
 // RecurringTransactionRequest is the request model for creating/updating a recurring transaction
@@ -57,6 +72,7 @@ type RecurringTransactionRequest struct {
 type RecurringTransactionResponse struct {
 	ID            uint       `json:"id"`
 	Amount        float64    `json:"amount"`
+	Currency      string     `json:"currency"`
 	Description   string     `json:"description"`
 	Category      string     `json:"category"`
 	Type          string     `json:"type"`
@@ -80,21 +96,15 @@ type RecurringTransactionResponse struct {
 
 // ToResponse converts a RecurringTransaction to RecurringTransactionResponse
 func (r *RecurringTransaction) ToResponse() *RecurringTransactionResponse {
-	var tags []string
-	if r.Tags != "" {
-		tags = parseTags(r.Tags)
-	} else {
-		tags = []string{}
-	}
-
 	return &RecurringTransactionResponse{
 		ID:          r.ID,
-		Amount:      r.Amount,
+		Amount:      r.Amount.Float(r.Currency),
+		Currency:    r.Currency,
 		Description: r.Description,
 		Category:    r.Category,
 		Type:        r.Type,
 		AccountID:   r.AccountID,
-		Tags:        tags,
+		Tags:        TagNames(r.Tags),
 		Frequency:   r.Frequency,
 		Interval:    r.Interval,
 		DayOfWeek:   r.DayOfWeek,
@@ -0,0 +1,292 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TransferCategory is the category of transactions that move money between the user's own accounts
+const TransferCategory = "Transfer"
+
+// Rule is a user-defined rule that fills in transactions as they are
+// created, imported or generated by recurring transactions. Rules run in
+// ascending position; a transaction must meet every condition that is set
+// for the actions to apply.
+type Rule struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	UserID         uint   `gorm:"not null;index:idx_rules_user_position,priority:1" json:"user_id"`
+	Name           string `gorm:"not null;size:100" json:"name"`
+	Position       int    `gorm:"not null;default:0;index:idx_rules_user_position,priority:2" json:"position"`
+	IsActive       bool   `gorm:"default:true" json:"is_active"`
+	StopProcessing bool   `gorm:"not null;default:false" json:"stop_processing"` // Later rules are skipped when this rule matches
+
+	// Conditions
+	DescriptionContains string   `json:"description_contains"`
+	DescriptionRegex    string   `json:"description_regex"`
+	MinAmount           *float64 `json:"min_amount"` // In major units of the transaction currency
+	MaxAmount           *float64 `json:"max_amount"`
+	AccountID           *uint    `json:"account_id"`
+	TransactionType     string   `json:"transaction_type"` // income or expense
+	Weekdays            string   `json:"weekdays"`         // Comma-separated days of the transaction date, e.g. "sat,sun"
+
+	// Actions
+	SetCategory      string `json:"set_category"`
+	Tags             []Tag  `gorm:"many2many:rule_tags" json:"tags,omitempty"` // Added to matching transactions
+	SetTaxCategoryID *uint  `json:"set_tax_category_id"`
+	SetDescription   string `json:"set_description"`
+	MarkTransfer     bool   `gorm:"not null;default:false" json:"mark_transfer"` // File under TransferCategory
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// RuleRequest is the request model for creating/updating a rule
+type RuleRequest struct {
+	Name           string `json:"name" binding:"required,max=100"`
+	IsActive       *bool  `json:"is_active"` // Defaults to true
+	StopProcessing bool   `json:"stop_processing"`
+
+	DescriptionContains string   `json:"description_contains"`
+	DescriptionRegex    string   `json:"description_regex"`
+	MinAmount           *float64 `json:"min_amount" binding:"omitempty,min=0"`
+	MaxAmount           *float64 `json:"max_amount" binding:"omitempty,min=0"`
+	AccountID           *uint    `json:"account_id"`
+	TransactionType     string   `json:"transaction_type" binding:"omitempty,oneof=income expense"`
+	Weekdays            []string `json:"weekdays" binding:"omitempty,dive,oneof=sun mon tue wed thu fri sat"`
+
+	SetCategory      string   `json:"set_category"`
+	AddTags          []string `json:"add_tags"`
+	SetTaxCategoryID *uint    `json:"set_tax_category_id"`
+	SetDescription   string   `json:"set_description"`
+	MarkTransfer     bool     `json:"mark_transfer"`
+}
+
+// ReorderRulesRequest is the request model for changing the order rules run in
+type ReorderRulesRequest struct {
+	RuleIDs []uint `json:"rule_ids" binding:"required,min=1"` // Every rule of the user, first to run first
+}
+
+// RunRulesRequest is the request model for running rules over existing transactions
+type RunRulesRequest struct {
+	RuleIDs []uint                    `json:"rule_ids"` // Rules to run; all active rules when empty
+	Filter  *TransactionFilterRequest `json:"filter"`   // Transactions to run over; all when empty
+	Preview bool                      `json:"preview"`  // Report the changes without saving them
+}
+
+// RuleResponse is the response model for a rule
+type RuleResponse struct {
+	ID                  uint      `json:"id"`
+	Name                string    `json:"name"`
+	Position            int       `json:"position"`
+	IsActive            bool      `json:"is_active"`
+	StopProcessing      bool      `json:"stop_processing"`
+	DescriptionContains string    `json:"description_contains,omitempty"`
+	DescriptionRegex    string    `json:"description_regex,omitempty"`
+	MinAmount           *float64  `json:"min_amount,omitempty"`
+	MaxAmount           *float64  `json:"max_amount,omitempty"`
+	AccountID           *uint     `json:"account_id,omitempty"`
+	TransactionType     string    `json:"transaction_type,omitempty"`
+	Weekdays            []string  `json:"weekdays"`
+	SetCategory         string    `json:"set_category,omitempty"`
+	AddTags             []string  `json:"add_tags"`
+	SetTaxCategoryID    *uint     `json:"set_tax_category_id,omitempty"`
+	SetDescription      string    `json:"set_description,omitempty"`
+	MarkTransfer        bool      `json:"mark_transfer"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// RuleFieldChange is a field of a transaction changed by rules
+type RuleFieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// RuleChange lists what rules changed on one transaction
+type RuleChange struct {
+	TransactionID uint              `json:"transaction_id"`
+	Date          time.Time         `json:"date"`
+	Description   string            `json:"description"` // As it was before the rules ran
+	Rules         []string          `json:"rules"`       // Names of the rules that matched
+	Fields        []RuleFieldChange `json:"fields"`
+}
+
+// RuleRunResponse is the response model for running rules over existing transactions
+type RuleRunResponse struct {
+	Preview  bool          `json:"preview"`
+	Examined int           `json:"examined"`
+	Changed  int           `json:"changed"`
+	Changes  []*RuleChange `json:"changes"`
+}
+
+// ToResponse converts a Rule to a RuleResponse
+func (r *Rule) ToResponse() *RuleResponse {
+	return &RuleResponse{
+		ID:                  r.ID,
+		Name:                r.Name,
+		Position:            r.Position,
+		IsActive:            r.IsActive,
+		StopProcessing:      r.StopProcessing,
+		DescriptionContains: r.DescriptionContains,
+		DescriptionRegex:    r.DescriptionRegex,
+		MinAmount:           r.MinAmount,
+		MaxAmount:           r.MaxAmount,
+		AccountID:           r.AccountID,
+		TransactionType:     r.TransactionType,
+		Weekdays:            r.WeekdayList(),
+		SetCategory:         r.SetCategory,
+		AddTags:             TagNames(r.Tags),
+		SetTaxCategoryID:    r.SetTaxCategoryID,
+		SetDescription:      r.SetDescription,
+		MarkTransfer:        r.MarkTransfer,
+		CreatedAt:           r.CreatedAt,
+		UpdatedAt:           r.UpdatedAt,
+	}
+}
+
+// WeekdayList returns the days of the weekday condition
+func (r *Rule) WeekdayList() []string {
+	if r.Weekdays == "" {
+		return []string{}
+	}
+	return strings.Split(r.Weekdays, ",")
+}
+
+// HasActions reports whether the rule changes anything on a matching transaction
+func (r *Rule) HasActions() bool {
+	return r.SetCategory != "" || len(r.Tags) > 0 || r.SetTaxCategoryID != nil || r.SetDescription != "" || r.MarkTransfer
+}
+
+// weekdayNames are the names of the days of the week used by rule conditions
+var weekdayNames = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Matches reports whether a transaction meets every condition of the rule.
+// Transfers between accounts are never matched.
+func (r *Rule) Matches(t *Transaction) bool {
+	if t.Type == "transfer" {
+		return false
+	}
+	if r.DescriptionContains != "" && !strings.Contains(strings.ToLower(t.Description), strings.ToLower(r.DescriptionContains)) {
+		return false
+	}
+	if r.DescriptionRegex != "" {
+		re, err := regexp.Compile("(?i)" + r.DescriptionRegex)
+		if err != nil || !re.MatchString(t.Description) {
+			return false
+		}
+	}
+
+	amount := t.Amount.Float(t.Currency)
+	if r.MinAmount != nil && amount < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && amount > *r.MaxAmount {
+		return false
+	}
+
+	if r.AccountID != nil && t.AccountID != *r.AccountID {
+		return false
+	}
+	if r.TransactionType != "" && t.Type != r.TransactionType {
+		return false
+	}
+	if r.Weekdays != "" && !containsString(r.WeekdayList(), weekdayNames[t.Date.Weekday()]) {
+		return false
+	}
+	return true
+}
+
+// Apply applies the actions of the rule to a transaction. Split
+// transactions keep their category, which their split lines carry.
+func (r *Rule) Apply(t *Transaction) {
+	if !t.IsSplit() {
+		if r.SetCategory != "" {
+			t.Category = r.SetCategory
+		}
+		if r.MarkTransfer {
+			t.Category = TransferCategory
+		}
+	}
+	if r.SetDescription != "" {
+		t.Description = r.SetDescription
+	}
+	if r.SetTaxCategoryID != nil {
+		id := *r.SetTaxCategoryID
+		t.TaxCategoryID = &id
+	}
+
+	// Build a new slice so that tags shared with the caller are left alone
+	tags := append([]Tag{}, t.Tags...)
+	for _, tag := range r.Tags {
+		if !containsTagID(tags, tag.ID) {
+			tags = append(tags, tag)
+		}
+	}
+	t.Tags = tags
+}
+
+// ApplyRules applies the rules, in the order given, to a transaction and
+// returns the names of the rules that matched
+func ApplyRules(rules []Rule, t *Transaction) []string {
+	var matched []string
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Matches(t) {
+			continue
+		}
+		rule.Apply(t)
+		matched = append(matched, rule.Name)
+		if rule.StopProcessing {
+			break
+		}
+	}
+	return matched
+}
+
+// RuleChanges lists the fields that differ between a copy of a transaction
+// taken before rules ran and the transaction after
+func RuleChanges(before, after *Transaction) []RuleFieldChange {
+	var changes []RuleFieldChange
+	add := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, RuleFieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	add("category", before.Category, after.Category)
+	add("description", before.Description, after.Description)
+	add("tax_category_id", formatOptionalID(before.TaxCategoryID), formatOptionalID(after.TaxCategoryID))
+	add("tags", strings.Join(TagNames(before.Tags), ","), strings.Join(TagNames(after.Tags), ","))
+	return changes
+}
+
+// formatOptionalID formats an optional ID, empty when unset
+func formatOptionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+// containsTagID reports whether a tag with the ID is in tags
+func containsTagID(tags []Tag, id uint) bool {
+	for _, tag := range tags {
+		if tag.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether s is in list
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
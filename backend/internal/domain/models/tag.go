@@ -0,0 +1,93 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Tag is a label a user attaches to transactions and recurring transactions
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tags_user_name,priority:1" json:"user_id"`
+	Name      string    `gorm:"not null;size:100;uniqueIndex:idx_tags_user_name,priority:2" json:"name"`
+	Color     string    `gorm:"size:7" json:"color"` // Hex color code
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TagUsage counts what a tag is attached to
+type TagUsage struct {
+	Transactions          int64 `json:"transactions"`
+	RecurringTransactions int64 `json:"recurring_transactions"`
+}
+
+// TagResponse is the response model for a tag
+type TagResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	Usage     TagUsage  `json:"usage"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TagRequest is the request model for creating a tag
+type TagRequest struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	Color string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+// UpdateTagRequest is the request model for renaming or recoloring a tag
+type UpdateTagRequest struct {
+	Name  *string `json:"name" binding:"omitempty,max=100"`
+	Color *string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+// MergeTagsRequest is the request model for merging tags into another tag
+type MergeTagsRequest struct {
+	SourceIDs []uint `json:"source_ids" binding:"required,min=1"`
+	TargetID  uint   `json:"target_id" binding:"required"`
+}
+
+// ToResponse converts a Tag to a TagResponse
+func (t *Tag) ToResponse(usage TagUsage) *TagResponse {
+	return &TagResponse{
+		ID:        t.ID,
+		Name:      t.Name,
+		Color:     t.Color,
+		Usage:     usage,
+		CreatedAt: t.CreatedAt,
+		UpdatedAt: t.UpdatedAt,
+	}
+}
+
+// TagNames returns the names of tags in order
+func TagNames(tags []Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// NormalizeTagNames trims tag names and drops blanks and repeats
+func NormalizeTagNames(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !containsTag(normalized, name) {
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized
+}
+
+// containsTag reports whether tags holds tag, ignoring case and surrounding spaces
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(strings.TrimSpace(t), tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -42,6 +42,7 @@ type TaxReportResponse struct {
 	TotalIncome   float64                   `json:"total_income"`
 	TotalDeductions float64                 `json:"total_deductions"`
 	CapitalGains  float64                   `json:"capital_gains"`
+	Currency      string                    `json:"currency"`
 	ByCategory    []TaxCategorySummary      `json:"by_category"`
 	Transactions  []TaxTransactionSummary   `json:"transactions"`
 }
@@ -61,6 +62,7 @@ type TaxTransactionSummary struct {
 	Date            string  `json:"date"`
 	Description     string  `json:"description"`
 	Amount          float64 `json:"amount"`
+	Currency        string  `json:"currency"`
 	Category        string  `json:"category"`
 	TaxCategoryName string  `json:"tax_category_name"`
 	TaxType         string  `json:"tax_type"`
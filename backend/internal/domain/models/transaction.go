@@ -1,52 +1,121 @@
 package models
 
 import (
+	"encoding/json"
 	"strconv"
-	"strings"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// Transaction statuses. Scheduled transactions are future-dated and post to
+// the ledger when their date arrives; pending and cleared transactions are
+// posted; void transactions are kept for the record without affecting balances.
+const (
+	TransactionStatusScheduled = "scheduled"
+	TransactionStatusPending   = "pending"
+	TransactionStatusCleared   = "cleared"
+	TransactionStatusVoid      = "void"
 )
 
+// PostedTransactionStatuses are the statuses of transactions that affect balances and totals
+var PostedTransactionStatuses = []string{TransactionStatusPending, TransactionStatusCleared}
+
 // Transaction represents a financial transaction
 type Transaction struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	UserID         uint      `gorm:"not null;index:idx_transactions_user_id;index:idx_transactions_user_date,priority:1" json:"user_id"`
-	Amount         float64   `gorm:"not null" json:"amount"`
-	Description    string    `json:"description"`
-	Category       string    `gorm:"index:idx_transactions_category" json:"category"`
-	Type           string    `gorm:"not null;index:idx_transactions_type" json:"type"` // 'income' or 'expense'
-	Date           time.Time `gorm:"not null;index:idx_transactions_date;index:idx_transactions_user_date,priority:2" json:"date"`
-	AccountID      uint      `gorm:"not null;index:idx_transactions_account_id" json:"account_id"`
-	Tags           string    `json:"tags"` // Comma-separated tags
-	TaxCategoryID  *uint     `gorm:"index:idx_transactions_tax_category_id" json:"tax_category_id"` // Sprint 4: Tax category
-	OrganizationID *uint     `gorm:"index:idx_transactions_organization_id" json:"organization_id"` // For organization expenses
-	DepartmentID   *uint     `gorm:"index:idx_transactions_department_id" json:"department_id"` // For department expenses
-	CreatedAt      time.Time `gorm:"autoCreateTime;index:idx_transactions_created_at" json:"created_at"`
-	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID               uint               `gorm:"primaryKey" json:"id"`
+	UserID           uint               `gorm:"not null;index:idx_transactions_user_id;index:idx_transactions_user_date,priority:1" json:"user_id"`
+	Amount           Money              `gorm:"column:amount_minor;not null" json:"-"`   // Minor units of Currency
+	Currency         string             `gorm:"not null;default:USD" json:"currency"`    // Currency of the account at posting time
+	OriginalAmount   Money              `gorm:"column:original_amount_minor" json:"-"`   // Minor units of OriginalCurrency as entered
+	OriginalCurrency string             `gorm:"size:3" json:"original_currency"`         // Currency the transaction was made in
+	ExchangeRate     float64            `gorm:"not null;default:1" json:"exchange_rate"` // Units of Currency per unit of OriginalCurrency
+	Description      string             `json:"description"`
+	Category         string             `gorm:"index:idx_transactions_category" json:"category"`
+	Type             string             `gorm:"not null;index:idx_transactions_type" json:"type"` // 'income' or 'expense'
+	Date             time.Time          `gorm:"not null;index:idx_transactions_date;index:idx_transactions_user_date,priority:2" json:"date"`
+	AccountID        uint               `gorm:"not null;index:idx_transactions_account_id" json:"account_id"`
+	TaxCategoryID    *uint              `gorm:"index:idx_transactions_tax_category_id" json:"tax_category_id"`        // Sprint 4: Tax category
+	OrganizationID   *uint              `gorm:"index:idx_transactions_organization_id" json:"organization_id"`        // For organization expenses
+	DepartmentID     *uint              `gorm:"index:idx_transactions_department_id" json:"department_id"`            // For department expenses
+	JournalEntryID   *uint              `gorm:"index:idx_transactions_journal_entry_id" json:"journal_entry_id"`      // Ledger entry backing this transaction
+	Status           string             `gorm:"not null;default:pending;index:idx_transactions_status" json:"status"` // scheduled, pending, cleared or void
+	ReconciliationID *uint              `gorm:"index:idx_transactions_reconciliation_id" json:"reconciliation_id"`    // Reconciliation that cleared it
+	ReconciledAt     *time.Time         `json:"reconciled_at"`                                                        // Set when the reconciliation completes; locks the transaction
+	PayeeID          *uint              `gorm:"index:idx_transactions_payee_id" json:"payee_id"`                      // Merchant or person paid or paid by
+	ExternalID       string             `gorm:"index:idx_transactions_external_id" json:"external_id"`                // ID given by the bank, e.g. the OFX FITID
+	TaxCategory      *TaxCategory       `gorm:"foreignKey:TaxCategoryID" json:"-"`
+	Payee            *Payee             `gorm:"foreignKey:PayeeID" json:"-"`
+	Tags             []Tag              `gorm:"many2many:transaction_tags" json:"tags,omitempty"`
+	Splits           []TransactionSplit `gorm:"foreignKey:TransactionID" json:"splits,omitempty"` // Category lines when the amount is split
+	CreatedAt        time.Time          `gorm:"autoCreateTime;index:idx_transactions_created_at" json:"created_at"`
+	UpdatedAt        time.Time          `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt        gorm.DeletedAt     `gorm:"index" json:"-"` // Set while the transaction is in the trash
+}
+
+// MarshalJSON writes the amounts in major units under the names they had
+// before amounts were stored in minor units
+func (t Transaction) MarshalJSON() ([]byte, error) {
+	type transaction Transaction
+	originalCurrency := t.OriginalCurrency
+	if originalCurrency == "" {
+		originalCurrency = t.Currency
+	}
+	return json.Marshal(struct {
+		transaction
+		Amount         float64 `json:"amount"`
+		OriginalAmount float64 `json:"original_amount"`
+	}{transaction(t), t.Amount.Float(t.Currency), t.OriginalAmount.Float(originalCurrency)})
 }
 
 // TransactionResponse is the response model for a transaction
 type TransactionResponse struct {
-	ID          uint      `json:"id"`
-	Amount      float64   `json:"amount"`
-	Description string    `json:"description"`
-	Category    string    `json:"category"`
-	Type        string    `json:"type"`
-	Date        time.Time `json:"date"`
-	AccountID   string    `json:"account_id"`
-	Tags        []string  `json:"tags"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID               uint                        `json:"id"`
+	Amount           float64                     `json:"amount"`
+	Currency         string                      `json:"currency"`
+	OriginalAmount   float64                     `json:"original_amount"`
+	OriginalCurrency string                      `json:"original_currency"`
+	ExchangeRate     float64                     `json:"exchange_rate"`
+	Description      string                      `json:"description"`
+	Category         string                      `json:"category"`
+	Type             string                      `json:"type"`
+	Date             time.Time                   `json:"date"`
+	AccountID        string                      `json:"account_id"`
+	Tags             []string                    `json:"tags"`
+	PayeeID          *uint                       `json:"payee_id,omitempty"`
+	Payee            string                      `json:"payee,omitempty"`
+	ExternalID       string                      `json:"external_id,omitempty"`
+	JournalEntryID   *uint                       `json:"journal_entry_id,omitempty"`
+	Status           string                      `json:"status"`
+	Reconciled       bool                        `json:"reconciled"`
+	Splits           []*TransactionSplitResponse `json:"splits,omitempty"`
+	Attachments      []*AttachmentResponse       `json:"attachments,omitempty"`
+	CreatedAt        time.Time                   `json:"created_at"`
+	UpdatedAt        time.Time                   `json:"updated_at"`
+	DuplicateOf      []uint                      `json:"duplicate_of,omitempty"` // Existing transactions this one probably duplicates; set on create
+	Warnings         []string                    `json:"warnings,omitempty"`
 }
 
 // TransactionRequest is the request model for creating/updating a transaction
 type TransactionRequest struct {
-	Amount      float64   `json:"amount" binding:"required"`
-	Description string    `json:"description"`
-	Category    string    `json:"category"`
-	Type        string    `json:"type" binding:"required,oneof=income expense transfer"`
-	Date        time.Time `json:"date" binding:"required"`
-	AccountID   uint      `json:"account_id" binding:"required"`
-	Tags        []string  `json:"tags"`
+	Amount       float64                   `json:"amount" binding:"required"`
+	Currency     string                    `json:"currency" binding:"omitempty,len=3"`     // Currency of Amount and Splits; defaults to the account currency
+	ExchangeRate float64                   `json:"exchange_rate" binding:"omitempty,gt=0"` // Account currency per unit of Currency; defaults to the rate on Date
+	Description  string                    `json:"description"`
+	Category     string                    `json:"category"`
+	Type         string                    `json:"type" binding:"required,oneof=income expense transfer"`
+	Date         time.Time                 `json:"date" binding:"required"`
+	AccountID    uint                      `json:"account_id" binding:"required"`
+	Tags         []string                  `json:"tags"`
+	PayeeID      *uint                     `json:"payee_id"`                        // Payee of the transaction
+	Payee        string                    `json:"payee"`                           // Payee name, created when new; used when PayeeID is not set
+	Splits       []TransactionSplitRequest `json:"splits" binding:"omitempty,dive"` // Optional category lines that must sum to Amount
+	Status       string                    `json:"status" binding:"omitempty,oneof=scheduled pending cleared void"`
+}
+
+// TransactionStatusRequest is the request model for changing the status of a transaction
+type TransactionStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=scheduled pending cleared void"`
 }
 
 // TransferRequest is the request model for transfer transactions
@@ -57,6 +126,8 @@ type TransferRequest struct {
 	ToAccountID   uint      `json:"to_account_id" binding:"required"`
 	Date          time.Time `json:"date" binding:"required"`
 	Tags          []string  `json:"tags"`
+	ToAmount      float64   `json:"to_amount" binding:"omitempty,gt=0"`     // Amount received when the accounts differ in currency
+	ExchangeRate  float64   `json:"exchange_rate" binding:"omitempty,gt=0"` // Destination currency per unit of source currency; defaults to the rate on Date
 }
 
 // TransferResponse is the response model for a transfer transaction
@@ -77,6 +148,7 @@ type TransactionFilter struct {
 	MinAmount float64 `form:"min_amount"`
 	MaxAmount float64 `form:"max_amount"`
 	Tags      string  `form:"tags"`
+	Status    string  `form:"status"`
 	Page      int     `form:"page"`
 	PageSize  int     `form:"page_size"`
 	SortBy    string  `form:"sort_by"`
@@ -88,28 +160,34 @@ type TransactionFilterRequest struct {
 	PaginationRequest
 
 	// Date filters
-	StartDate *time.Time `form:"start_date" time_format:"2006-01-02"`
-	EndDate   *time.Time `form:"end_date" time_format:"2006-01-02"`
+	StartDate *time.Time `form:"start_date" json:"start_date" time_format:"2006-01-02"`
+	EndDate   *time.Time `form:"end_date" json:"end_date" time_format:"2006-01-02"`
 
 	// Amount filters
-	MinAmount *float64 `form:"min_amount" binding:"omitempty,min=0"`
-	MaxAmount *float64 `form:"max_amount" binding:"omitempty,min=0"`
+	MinAmount *float64 `form:"min_amount" json:"min_amount" binding:"omitempty,min=0"`
+	MaxAmount *float64 `form:"max_amount" json:"max_amount" binding:"omitempty,min=0"`
 
 	// Category filters (support multiple categories)
-	Categories []string `form:"categories"`
-	Category   string   `form:"category"` // Single category for backward compatibility
+	Categories []string `form:"categories" json:"categories"`
+	Category   string   `form:"category" json:"category"` // Single category for backward compatibility
 
 	// Type filter
-	Type string `form:"type" binding:"omitempty,oneof=income expense transfer"`
+	Type string `form:"type" json:"type" binding:"omitempty,oneof=income expense transfer"`
 
 	// Account filter
-	AccountID *uint `form:"account_id"`
+	AccountID *uint `form:"account_id" json:"account_id"`
 
 	// Search query
-	Search string `form:"search"`
+	Search string `form:"search" json:"search"`
 
 	// Tags filter
-	Tags []string `form:"tags"`
+	Tags []string `form:"tags" json:"tags"`
+
+	// Payee filter
+	PayeeID *uint `form:"payee_id" json:"payee_id"`
+
+	// Status filter (support multiple statuses)
+	Statuses []string `form:"status" json:"status" binding:"omitempty,dive,oneof=scheduled pending cleared void"`
 }
 
 // PaginatedTransactionResponse is the paginated response for transactions
@@ -123,11 +201,12 @@ type PaginatedTransactionResponse struct {
 
 // TransactionSummary represents a summary of transactions
 type TransactionSummary struct {
-	Income  float64                `json:"income"`
-	Expenses float64               `json:"expenses"`
-	Balance float64                `json:"balance"`
-	Count   int                    `json:"count"`
-	ByCategory []CategorySummary   `json:"by_category"`
+	Income     float64           `json:"income"`
+	Expenses   float64           `json:"expenses"`
+	Balance    float64           `json:"balance"`
+	Currency   string            `json:"currency"`
+	Count      int               `json:"count"`
+	ByCategory []CategorySummary `json:"by_category"`
 }
 
 // CategorySummary represents a summary of transactions by category
@@ -139,42 +218,91 @@ type CategorySummary struct {
 
 // ToResponse converts a Transaction to a TransactionResponse
 func (t *Transaction) ToResponse() *TransactionResponse {
-	// Convert comma-separated tags to slice
-	var tags []string
-	if t.Tags != "" {
-		tags = parseTags(t.Tags)
-	} else {
-		tags = []string{}
+	// Convert split lines
+	var splits []*TransactionSplitResponse
+	for i := range t.Splits {
+		splits = append(splits, t.Splits[i].ToResponse(t.Currency))
+	}
+
+	var payee string
+	if t.Payee != nil {
+		payee = t.Payee.Name
 	}
 
 	return &TransactionResponse{
-		ID:          t.ID,
-		Amount:      t.Amount,
-		Description: t.Description,
-		Category:    t.Category,
-		Type:        t.Type,
-		Date:        t.Date,
-		AccountID:   strconv.FormatUint(uint64(t.AccountID), 10),
-		Tags:        tags,
-		CreatedAt:   t.CreatedAt,
-		UpdatedAt:   t.UpdatedAt,
+		ID:               t.ID,
+		Amount:           t.Amount.Float(t.Currency),
+		Currency:         t.Currency,
+		OriginalAmount:   t.OriginalAmount.Float(t.OriginalCurrency),
+		OriginalCurrency: t.OriginalCurrency,
+		ExchangeRate:     t.ExchangeRate,
+		Description:      t.Description,
+		Category:         t.Category,
+		Type:             t.Type,
+		Date:             t.Date,
+		AccountID:        strconv.FormatUint(uint64(t.AccountID), 10),
+		Tags:             TagNames(t.Tags),
+		PayeeID:          t.PayeeID,
+		ExternalID:       t.ExternalID,
+		Payee:            payee,
+		JournalEntryID:   t.JournalEntryID,
+		Status:           t.Status,
+		Reconciled:       t.IsReconciled(),
+		Splits:           splits,
+		CreatedAt:        t.CreatedAt,
+		UpdatedAt:        t.UpdatedAt,
 	}
 }
 
-// Helper function to parse comma-separated tags
-func parseTags(tagString string) []string {
-	if tagString == "" {
-		return []string{}
+// BeforeSave records the booked amount as the original amount of
+// transactions that were made in the account currency
+func (t *Transaction) BeforeSave(tx *gorm.DB) error {
+	if t.OriginalCurrency == "" {
+		t.OriginalAmount = t.Amount
+		t.OriginalCurrency = t.Currency
+		t.ExchangeRate = 1
 	}
+	return nil
+}
+
+// IsForeignCurrency reports whether the transaction was made in a currency
+// other than the one of its account
+func (t *Transaction) IsForeignCurrency() bool {
+	return t.OriginalCurrency != "" && t.OriginalCurrency != t.Currency
+}
+
+// IsSplit reports whether the transaction amount is split across category lines
+func (t *Transaction) IsSplit() bool {
+	return len(t.Splits) > 0
+}
+
+// IsPosted reports whether the transaction is recorded in the ledger
+func (t *Transaction) IsPosted() bool {
+	return IsPostedTransactionStatus(t.Status)
+}
+
+// IsPostedTransactionStatus reports whether transactions with the status affect balances
+func IsPostedTransactionStatus(status string) bool {
+	return status == TransactionStatusPending || status == TransactionStatusCleared
+}
 
-	// Split by comma and trim spaces
-	var tags []string
-	for _, tag := range strings.Split(tagString, ",") {
-		trimmedTag := strings.TrimSpace(tag)
-		if trimmedTag != "" {
-			tags = append(tags, trimmedTag)
-		}
+// ResolveTransactionStatus returns the status a transaction dated date should
+// get when status is requested. Future-dated transactions are scheduled until
+// their date arrives, and scheduled transactions that are already due are pending.
+func ResolveTransactionStatus(status string, date, now time.Time) string {
+	if status == TransactionStatusVoid {
+		return status
 	}
+	if date.After(now) {
+		return TransactionStatusScheduled
+	}
+	if status == "" || status == TransactionStatusScheduled {
+		return TransactionStatusPending
+	}
+	return status
+}
 
-	return tags
+// IsReconciled reports whether the transaction was locked by a completed reconciliation
+func (t *Transaction) IsReconciled() bool {
+	return t.ReconciledAt != nil
 }
@@ -0,0 +1,44 @@
+package models
+
+// Bulk transaction actions
+const (
+	BulkActionRecategorize   = "recategorize"
+	BulkActionAddTags        = "add_tags"
+	BulkActionRemoveTags     = "remove_tags"
+	BulkActionMove           = "move"
+	BulkActionSetTaxCategory = "set_tax_category"
+	BulkActionDelete         = "delete"
+)
+
+// MaxBulkTransactions is the most transactions a single bulk request can change
+const MaxBulkTransactions = 1000
+
+// BulkTransactionRequest applies one action to a list of transactions or to
+// every transaction matching a filter
+type BulkTransactionRequest struct {
+	Action        string                    `json:"action" binding:"required,oneof=recategorize add_tags remove_tags move set_tax_category delete"`
+	IDs           []uint                    `json:"ids"`
+	Filter        *TransactionFilterRequest `json:"filter"`
+	Category      string                    `json:"category"`        // For recategorize
+	Tags          []string                  `json:"tags"`            // For add_tags and remove_tags
+	AccountID     uint                      `json:"account_id"`      // For move
+	TaxCategoryID *uint                     `json:"tax_category_id"` // For set_tax_category; null clears the tax category
+	DryRun        bool                      `json:"dry_run"`         // Report what would change without saving
+}
+
+// BulkTransactionResult is the outcome of a bulk action for one transaction
+type BulkTransactionResult struct {
+	ID      uint   `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BulkTransactionResponse is the response model for a bulk transaction action
+type BulkTransactionResponse struct {
+	Action    string                  `json:"action"`
+	DryRun    bool                    `json:"dry_run"`
+	Matched   int                     `json:"matched"`
+	Succeeded int                     `json:"succeeded"`
+	Failed    int                     `json:"failed"`
+	Results   []BulkTransactionResult `json:"results"`
+}
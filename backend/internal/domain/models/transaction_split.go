@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// SplitCategory is the category shown on a parent transaction whose amount is split across categories
+const SplitCategory = "Split"
+
+// Split validation errors
+var (
+	ErrSplitTooFewLines   = errors.New("a split transaction needs at least two lines")
+	ErrSplitLineAmount    = errors.New("split line amounts must be greater than zero")
+	ErrSplitSumMismatch   = errors.New("split line amounts must add up to the transaction amount")
+	ErrSplitCategoryEmpty = errors.New("split lines must have a category")
+)
+
+// TransactionSplit is one category line of a split transaction. The lines of
+// a transaction share its account, type, date and currency and their amounts
+// add up to the parent amount.
+type TransactionSplit struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	TransactionID uint         `gorm:"not null;index:idx_transaction_splits_transaction_id" json:"transaction_id"`
+	UserID        uint         `gorm:"not null;index:idx_transaction_splits_user_id" json:"user_id"`
+	Amount        Money        `gorm:"column:amount_minor;not null" json:"amount_minor"` // Minor units of the parent currency
+	Category      string       `gorm:"not null;index:idx_transaction_splits_category" json:"category"`
+	Description   string       `json:"description"`
+	Tags          []Tag        `gorm:"many2many:transaction_split_tags" json:"tags,omitempty"`
+	TaxCategoryID *uint        `gorm:"index:idx_transaction_splits_tax_category_id" json:"tax_category_id"`
+	TaxCategory   *TaxCategory `gorm:"foreignKey:TaxCategoryID" json:"-"`
+	CreatedAt     time.Time    `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TransactionSplitRequest is the request model for a split line
+type TransactionSplitRequest struct {
+	Amount        float64  `json:"amount" binding:"required,gt=0"`
+	Category      string   `json:"category" binding:"required"`
+	Description   string   `json:"description"`
+	Tags          []string `json:"tags"`
+	TaxCategoryID *uint    `json:"tax_category_id"`
+}
+
+// TransactionSplitResponse is the response model for a split line
+type TransactionSplitResponse struct {
+	ID            uint     `json:"id"`
+	Amount        float64  `json:"amount"`
+	Category      string   `json:"category"`
+	Description   string   `json:"description"`
+	Tags          []string `json:"tags"`
+	TaxCategoryID *uint    `json:"tax_category_id"`
+}
+
+// ToResponse converts a TransactionSplit to TransactionSplitResponse
+func (s *TransactionSplit) ToResponse(currency string) *TransactionSplitResponse {
+	return &TransactionSplitResponse{
+		ID:            s.ID,
+		Amount:        s.Amount.Float(currency),
+		Category:      s.Category,
+		Description:   s.Description,
+		Tags:          TagNames(s.Tags),
+		TaxCategoryID: s.TaxCategoryID,
+	}
+}
+
+// BuildTransactionSplits converts split requests into split lines in the
+// given currency and checks that they add up to the parent amount. Tags are
+// left for the caller to find or create.
+func BuildTransactionSplits(userID uint, amount Money, currency string, reqs []TransactionSplitRequest) ([]TransactionSplit, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+	if len(reqs) < 2 {
+		return nil, ErrSplitTooFewLines
+	}
+
+	splits := make([]TransactionSplit, 0, len(reqs))
+	var total Money
+	for _, req := range reqs {
+		lineAmount := NewMoney(req.Amount, currency)
+		if lineAmount <= 0 {
+			return nil, ErrSplitLineAmount
+		}
+		if strings.TrimSpace(req.Category) == "" {
+			return nil, ErrSplitCategoryEmpty
+		}
+
+		total += lineAmount
+		splits = append(splits, TransactionSplit{
+			UserID:        userID,
+			Amount:        lineAmount,
+			Category:      req.Category,
+			Description:   req.Description,
+			TaxCategoryID: req.TaxCategoryID,
+		})
+	}
+
+	if total != amount {
+		return nil, ErrSplitSumMismatch
+	}
+
+	return splits, nil
+}
+
+// ConvertTransactionSplits converts split lines into another currency at the
+// given rate. Any rounding difference goes to the last line so the lines still
+// add up to total, the converted parent amount.
+func ConvertTransactionSplits(splits []TransactionSplit, fromCurrency, toCurrency string, rate float64, total Money) {
+	if len(splits) == 0 || fromCurrency == toCurrency {
+		return
+	}
+
+	var converted Money
+	for i := range splits {
+		splits[i].Amount = ConvertMoneyWithRate(splits[i].Amount, fromCurrency, toCurrency, rate)
+		converted += splits[i].Amount
+	}
+	splits[len(splits)-1].Amount += total - converted
+}
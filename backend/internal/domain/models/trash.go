@@ -0,0 +1,38 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Trash item types
+const (
+	TrashTypeTransaction          = "transaction"
+	TrashTypeAccount              = "account"
+	TrashTypeBudget               = "budget"
+	TrashTypeGoal                 = "goal"
+	TrashTypeRecurringTransaction = "recurring_transaction"
+)
+
+// Trash errors
+var (
+	ErrTrashItemNotFound = errors.New("item not found in trash")
+	ErrInvalidTrashType  = errors.New("invalid trash item type")
+)
+
+// TrashItem is a soft-deleted record listed in a user's trash
+type TrashItem struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Amount    float64   `json:"amount,omitempty"`
+	Currency  string    `json:"currency,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"` // When the retention job deletes it for good
+}
+
+// TrashResponse is the response model for a user's trash
+type TrashResponse struct {
+	Items         []*TrashItem `json:"items"`
+	RetentionDays int          `json:"retention_days"`
+}
@@ -99,17 +99,27 @@ func (r *AccountRepository) GetSummary(userID uint) (*models.AccountSummary, err
 		NetWorth:         0,
 	}
 
-	// Calculate totals
+	// Calculate totals per currency in minor units
+	assets := models.MoneyTotals{}
+	liabilities := models.MoneyTotals{}
 	for _, a := range accounts {
 		if a.Balance > 0 {
-			summary.TotalAssets += a.Balance
+			assets.Add(a.Currency, a.Balance)
 		} else {
-			summary.TotalLiabilities += -a.Balance
+			liabilities.Add(a.Currency, -a.Balance)
 		}
 	}
 
+	// Convert totals into a single reporting currency
+	currency := models.ReportingCurrency(assets, liabilities)
+	totalAssets := assets.Sum(currency)
+	totalLiabilities := liabilities.Sum(currency)
+	summary.Currency = currency
+	summary.TotalAssets = totalAssets.Float(currency)
+	summary.TotalLiabilities = totalLiabilities.Float(currency)
+
 	// Calculate net worth
-	summary.NetWorth = summary.TotalAssets - summary.TotalLiabilities
+	summary.NetWorth = (totalAssets - totalLiabilities).Float(currency)
 
 	return summary, nil
 }
@@ -84,15 +84,17 @@ func (r *BudgetRepository) GetSummary(userID uint) (*models.BudgetSummary, error
 		BudgetsOverLimit: 0,
 	}
 
-	// Calculate totals
+	// Calculate totals per currency in minor units
+	budgeted := models.MoneyTotals{}
+	spent := models.MoneyTotals{}
 	for _, b := range budgets {
-		summary.TotalBudgeted += b.Amount
-		summary.TotalSpent += b.Spent
+		budgeted.Add(b.Currency, b.Amount)
+		spent.Add(b.Currency, b.Spent)
 
 		// Check if budget is near or over limit
 		percentSpent := 0
 		if b.Amount > 0 {
-			percentSpent = int((b.Spent / b.Amount) * 100)
+			percentSpent = int(b.Spent * 100 / b.Amount)
 		}
 
 		if percentSpent >= 100 {
@@ -102,10 +104,18 @@ func (r *BudgetRepository) GetSummary(userID uint) (*models.BudgetSummary, error
 		}
 	}
 
+	// Convert totals into a single reporting currency
+	currency := models.ReportingCurrency(budgeted, spent)
+	totalBudgeted := budgeted.Sum(currency)
+	totalSpent := spent.Sum(currency)
+	summary.Currency = currency
+	summary.TotalBudgeted = totalBudgeted.Float(currency)
+	summary.TotalSpent = totalSpent.Float(currency)
+
 	// Calculate remaining and overall progress
-	summary.TotalRemaining = summary.TotalBudgeted - summary.TotalSpent
-	if summary.TotalBudgeted > 0 {
-		summary.OverallProgress = int((summary.TotalSpent / summary.TotalBudgeted) * 100)
+	summary.TotalRemaining = (totalBudgeted - totalSpent).Float(currency)
+	if totalBudgeted > 0 {
+		summary.OverallProgress = int(totalSpent * 100 / totalBudgeted)
 		if summary.OverallProgress > 100 {
 			summary.OverallProgress = 100
 		}
@@ -80,15 +80,20 @@ func (r *TransactionRepository) GetSummary(userID uint, startDate, endDate time.
 		ByCategory: []models.CategorySummary{},
 	}
 
+	// Totals are kept per currency in minor units so the sums stay exact
+	income := models.MoneyTotals{}
+	expenses := models.MoneyTotals{}
+	categoryTotals := make(map[string]models.MoneyTotals)
+
 	// Map to store category summaries
 	categoryMap := make(map[string]*models.CategorySummary)
 
 	// Calculate totals and category summaries
 	for _, t := range transactions {
 		if t.Type == "income" {
-			summary.Income += t.Amount
+			income.Add(t.Currency, t.Amount)
 		} else {
-			summary.Expenses += t.Amount
+			expenses.Add(t.Currency, t.Amount)
 		}
 
 		// Update category summary
@@ -98,21 +103,29 @@ func (r *TransactionRepository) GetSummary(userID uint, startDate, endDate time.
 				Amount:   0,
 				Count:    0,
 			}
+			categoryTotals[t.Category] = models.MoneyTotals{}
 		}
 
 		if t.Type == "income" {
-			categoryMap[t.Category].Amount += t.Amount
+			categoryTotals[t.Category].Add(t.Currency, t.Amount)
 		} else {
-			categoryMap[t.Category].Amount -= t.Amount
+			categoryTotals[t.Category].Add(t.Currency, -t.Amount)
 		}
 		categoryMap[t.Category].Count++
 	}
 
+	// Convert totals into a single reporting currency
+	currency := models.ReportingCurrency(income, expenses)
+	summary.Currency = currency
+	summary.Income = income.Sum(currency).Float(currency)
+	summary.Expenses = expenses.Sum(currency).Float(currency)
+
 	// Calculate balance
-	summary.Balance = summary.Income - summary.Expenses
+	summary.Balance = (income.Sum(currency) - expenses.Sum(currency)).Float(currency)
 
 	// Convert category map to slice
-	for _, cs := range categoryMap {
+	for category, cs := range categoryMap {
+		cs.Amount = categoryTotals[category].Sum(currency).Float(currency)
 		summary.ByCategory = append(summary.ByCategory, *cs)
 	}
 
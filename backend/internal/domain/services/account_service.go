@@ -1,36 +1,72 @@
 package services
 
 import (
+	"errors"
+
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
 	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
 )
 
 // AccountService handles business logic for accounts
 type AccountService struct {
-	accountRepo *repository.AccountRepository
+	accountRepo     *repository.AccountRepository
+	ledgerRepo      *repository.LedgerRepository
+	ledgerService   *LedgerService
+	currencyService *CurrencyService
+	db              *gorm.DB
 }
 
 // NewAccountService creates a new account service
-func NewAccountService(accountRepo *repository.AccountRepository) *AccountService {
+func NewAccountService(
+	accountRepo *repository.AccountRepository,
+	ledgerRepo *repository.LedgerRepository,
+	ledgerService *LedgerService,
+	currencyService *CurrencyService,
+	db *gorm.DB,
+) *AccountService {
 	return &AccountService{
-		accountRepo: accountRepo,
+		accountRepo:     accountRepo,
+		ledgerRepo:      ledgerRepo,
+		ledgerService:   ledgerService,
+		currencyService: currencyService,
+		db:              db,
 	}
 }
 
 // Create creates a new account
 func (s *AccountService) Create(userID uint, req *models.AccountRequest) (*models.Account, error) {
+	openingBalance := models.NewMoney(req.Balance, req.Currency)
+
 	// Create account
 	account := &models.Account{
-		UserID:    userID,
-		Name:      req.Name,
-		Type:      req.Type,
-		Balance:   req.Balance,
-		Currency:  req.Currency,
-		IsDefault: req.IsDefault,
+		UserID:         userID,
+		Name:           req.Name,
+		Type:           req.Type,
+		Currency:       req.Currency,
+		IsDefault:      req.IsDefault,
+		ExternalNumber: req.ExternalNumber,
 	}
 
-	// Save account
-	if err := s.accountRepo.Create(account); err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Save account
+		if err := tx.Create(account).Error; err != nil {
+			return err
+		}
+
+		// Record the opening balance in the ledger
+		if err := s.ledgerService.PostOpeningBalance(tx, account, openingBalance); err != nil {
+			return err
+		}
+
+		// Derive account balance from its postings
+		return s.ledgerService.SyncAccountBalance(tx, account, models.BalanceChange{
+			ChangeType:  models.BalanceChangeAdjustment,
+			Description: "Opening balance",
+		})
+	})
+
+	if err != nil {
 		return nil, err
 	}
 
@@ -55,24 +91,68 @@ func (s *AccountService) Update(id uint, userID uint, req *models.AccountRequest
 		return nil, err
 	}
 
+	// Postings are denominated in the account currency, so it is fixed once money has moved
+	if req.Currency != account.Currency {
+		count, err := s.ledgerRepo.CountAccountPostings(account.ID)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errors.New("cannot change the currency of an account with ledger activity")
+		}
+	}
+
 	// Update account
 	account.Name = req.Name
 	account.Type = req.Type
-	account.Balance = req.Balance
 	account.Currency = req.Currency
 	account.IsDefault = req.IsDefault
+	account.ExternalNumber = req.ExternalNumber
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Save account details without touching the derived balance
+		if err := tx.Model(account).Select("name", "type", "currency", "is_default", "external_number").Updates(account).Error; err != nil {
+			return err
+		}
 
-	// Save account
-	if err := s.accountRepo.Update(account); err != nil {
+		// Record a balance edit as an adjustment entry, measured against the
+		// balance as it stands once no other change can reach the account
+		if err := s.ledgerService.LockAccounts(tx, account.ID); err != nil {
+			return err
+		}
+		current, err := s.accountRepo.WithTx(tx).GetByID(account.ID, userID)
+		if err != nil {
+			return err
+		}
+		delta := models.NewMoney(req.Balance, req.Currency) - current.Balance
+		if err := s.ledgerService.PostAdjustment(tx, account, delta, "Manual balance adjustment"); err != nil {
+			return err
+		}
+
+		// Derive account balance from its postings
+		return s.ledgerService.SyncAccountBalance(tx, account, models.BalanceChange{
+			ChangeType:  models.BalanceChangeAdjustment,
+			Description: "Manual balance adjustment",
+		})
+	})
+
+	if err != nil {
 		return nil, err
 	}
 
 	return account, nil
 }
 
-// Delete deletes an account
+// Delete moves an account to the trash. Accounts that still have
+// transactions are refused, so nothing is left pointing at a trashed account.
 func (s *AccountService) Delete(id uint, userID uint) error {
-	return s.accountRepo.Delete(id, userID)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// Hold the account so no transaction is added while it moves to the trash
+		if err := s.ledgerService.LockAccounts(tx, id); err != nil {
+			return err
+		}
+		return s.accountRepo.WithTx(tx).Delete(id, userID)
+	})
 }
 
 // GetAccountTypes gets all account types
@@ -80,7 +160,7 @@ func (s *AccountService) GetAccountTypes() []models.AccountType {
 	return s.accountRepo.GetAccountTypes()
 }
 
-// GetSummary gets a summary of accounts for a user
+// GetSummary gets a summary of accounts for a user in their preferred currency
 func (s *AccountService) GetSummary(userID uint) (*models.AccountSummary, error) {
-	return s.accountRepo.GetSummary(userID)
+	return s.accountRepo.GetSummary(userID, s.currencyService.PreferredCurrency(userID), s.currencyService.Rates())
 }
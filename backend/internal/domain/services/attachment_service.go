@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/storage"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// attachmentLinkTTL is how long a signed download link stays valid
+const attachmentLinkTTL = 15 * time.Minute
+
+// AttachmentService handles business logic for transaction attachments
+type AttachmentService struct {
+	attachmentRepo  *repository.AttachmentRepository
+	transactionRepo *repository.TransactionRepository
+	store           storage.BlobStore
+	signingKey      []byte
+}
+
+// NewAttachmentService creates a new attachment service. Download links are
+// signed with signingKey.
+func NewAttachmentService(
+	attachmentRepo *repository.AttachmentRepository,
+	transactionRepo *repository.TransactionRepository,
+	store storage.BlobStore,
+	signingKey []byte,
+) *AttachmentService {
+	return &AttachmentService{
+		attachmentRepo:  attachmentRepo,
+		transactionRepo: transactionRepo,
+		store:           store,
+		signingKey:      signingKey,
+	}
+}
+
+// Upload validates a file and attaches it to a transaction. The type is
+// detected from the contents rather than trusted from the client.
+func (s *AttachmentService) Upload(userID uint, transactionID uint, fileName string, r io.Reader) (*models.AttachmentResponse, error) {
+	// Check if transaction exists and belongs to user
+	if _, err := s.transactionRepo.GetByID(transactionID, userID); err != nil {
+		return nil, errors.New("transaction not found")
+	}
+
+	// Detect the content type from the first bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, errors.New("attachment is empty")
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !models.AllowedAttachmentTypes[contentType] {
+		return nil, models.ErrAttachmentTypeRejected
+	}
+
+	key, err := newAttachmentKey(userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the file while hashing and measuring it
+	hash := sha256.New()
+	counter := &countingReader{r: io.LimitReader(io.MultiReader(bytes.NewReader(head[:n]), r), models.MaxAttachmentSize+1)}
+	if err := s.store.Put(key, io.TeeReader(counter, hash)); err != nil {
+		return nil, err
+	}
+	if counter.n > models.MaxAttachmentSize {
+		_ = s.store.Delete(key)
+		return nil, models.ErrAttachmentTooLarge
+	}
+
+	attachment := &models.Attachment{
+		UserID:        userID,
+		TransactionID: transactionID,
+		FileName:      sanitizeFileName(fileName),
+		ContentType:   contentType,
+		Size:          counter.n,
+		Checksum:      hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:    key,
+	}
+	if err := s.attachmentRepo.Create(attachment); err != nil {
+		_ = s.store.Delete(key)
+		return nil, err
+	}
+
+	return s.withLink(attachment), nil
+}
+
+// GetByTransaction gets the attachments of a transaction with fresh download links
+func (s *AttachmentService) GetByTransaction(transactionID uint, userID uint) ([]*models.AttachmentResponse, error) {
+	if _, err := s.transactionRepo.GetByID(transactionID, userID); err != nil {
+		return nil, errors.New("transaction not found")
+	}
+
+	attachments, err := s.attachmentRepo.GetByTransactionID(transactionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]*models.AttachmentResponse, 0, len(attachments))
+	for i := range attachments {
+		response = append(response, s.withLink(&attachments[i]))
+	}
+	return response, nil
+}
+
+// Delete removes an attachment of a transaction and its file
+func (s *AttachmentService) Delete(id uint, transactionID uint, userID uint) error {
+	attachment, err := s.attachmentRepo.GetByID(id, userID)
+	if err != nil || attachment.TransactionID != transactionID {
+		return models.ErrAttachmentNotFound
+	}
+
+	if err := s.attachmentRepo.Delete(attachment.ID); err != nil {
+		return err
+	}
+	return s.store.Delete(attachment.StorageKey)
+}
+
+// OpenSigned checks a signed download link and opens the attachment it points
+// to. The caller must close the returned reader.
+func (s *AttachmentService) OpenSigned(id uint, expires int64, signature string) (*models.Attachment, io.ReadCloser, error) {
+	if time.Now().Unix() > expires {
+		return nil, nil, models.ErrAttachmentLinkInvalid
+	}
+
+	attachment, err := s.attachmentRepo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, models.ErrAttachmentLinkInvalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expected := s.sign(attachment.ID, attachment.UserID, expires)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, nil, models.ErrAttachmentLinkInvalid
+	}
+
+	file, err := s.store.Get(attachment.StorageKey)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return nil, nil, models.ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, file, nil
+}
+
+// PurgeOrphaned removes the attachments and files of transactions that have
+// been permanently deleted and returns how many were removed
+func (s *AttachmentService) PurgeOrphaned() (int, error) {
+	attachments, err := s.attachmentRepo.GetOrphaned()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, attachment := range attachments {
+		if err := s.store.Delete(attachment.StorageKey); err != nil {
+			return removed, err
+		}
+		if err := s.attachmentRepo.Delete(attachment.ID); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// withLink converts an attachment to a response with a signed download link
+func (s *AttachmentService) withLink(attachment *models.Attachment) *models.AttachmentResponse {
+	expiresAt := time.Now().Add(attachmentLinkTTL).Truncate(time.Second)
+	expires := expiresAt.Unix()
+
+	response := attachment.ToResponse()
+	response.DownloadURL = fmt.Sprintf("/api/v1/attachments/%d/download?expires=%d&signature=%s",
+		attachment.ID, expires, s.sign(attachment.ID, attachment.UserID, expires))
+	response.URLExpiresAt = &expiresAt
+	return response
+}
+
+// sign returns the HMAC signature of a download link
+func (s *AttachmentService) sign(id uint, userID uint, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "attachment:%d:%d:%d", id, userID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newAttachmentKey returns a unique, unguessable blob key for a new attachment
+func newAttachmentKey(userID uint, transactionID uint) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("attachments/%d/%d/%s", userID, transactionID, hex.EncodeToString(random)), nil
+}
+
+// sanitizeFileName keeps the base name of an uploaded file without control
+// characters or quotes so it is safe to echo back in headers
+func sanitizeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[len(name)-255:], "")
+	}
+	return name
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read reads from the underlying reader and counts the bytes
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/storage"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// testPNG is the smallest content that is detected as a PNG image
+var testPNG = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+// newTestAttachmentService wires an attachment service that stores files in a temporary directory
+func newTestAttachmentService(t *testing.T, db *gorm.DB) *AttachmentService {
+	store, err := storage.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create blob store: %v", err)
+	}
+	return NewAttachmentService(
+		repository.NewAttachmentRepository(db),
+		repository.NewTransactionRepository(db),
+		store,
+		[]byte("test-signing-key"),
+	)
+}
+
+// parseDownloadURL extracts the expiry and signature from a download link
+func parseDownloadURL(t *testing.T, link string) (int64, string) {
+	u, err := url.Parse(link)
+	assert.NoError(t, err)
+	expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	assert.NoError(t, err)
+	return expires, u.Query().Get("signature")
+}
+
+func TestAttachmentService_UploadAndDownload(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	transactionService := newTestTransactionService(db)
+	service := newTestAttachmentService(t, db)
+
+	transaction, err := transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 50.0, Type: "expense", Category: "Food", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+
+	// Execute
+	attachment, err := service.Upload(user.ID, transaction.ID, "../receipts/lunch.png", bytes.NewReader(testPNG))
+
+	// Assert
+	assert.NoError(t, err)
+	sum := sha256.Sum256(testPNG)
+	assert.Equal(t, "lunch.png", attachment.FileName)
+	assert.Equal(t, "image/png", attachment.ContentType)
+	assert.Equal(t, int64(len(testPNG)), attachment.Size)
+	assert.Equal(t, hex.EncodeToString(sum[:]), attachment.Checksum)
+
+	// The signed link opens the stored file
+	expires, signature := parseDownloadURL(t, attachment.DownloadURL)
+	stored, file, err := service.OpenSigned(attachment.ID, expires, signature)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, testPNG, data)
+	assert.Equal(t, attachment.Checksum, stored.Checksum)
+
+	// Tampered or expired links are refused
+	_, _, err = service.OpenSigned(attachment.ID, expires+1, signature)
+	assert.ErrorIs(t, err, models.ErrAttachmentLinkInvalid)
+	_, _, err = service.OpenSigned(attachment.ID, time.Now().Add(-time.Minute).Unix(), signature)
+	assert.ErrorIs(t, err, models.ErrAttachmentLinkInvalid)
+}
+
+func TestAttachmentService_UploadRejectsInvalidFiles(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	transactionService := newTestTransactionService(db)
+	service := newTestAttachmentService(t, db)
+
+	transaction, err := transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 50.0, Type: "expense", Category: "Food", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+
+	// A script renamed to .png is detected by its contents
+	_, err = service.Upload(user.ID, transaction.ID, "receipt.png", strings.NewReader("<script>alert(1)</script>"))
+	assert.ErrorIs(t, err, models.ErrAttachmentTypeRejected)
+
+	// Files over the size limit are not kept
+	oversized := io.MultiReader(bytes.NewReader(testPNG), io.LimitReader(zeroReader{}, models.MaxAttachmentSize))
+	_, err = service.Upload(user.ID, transaction.ID, "huge.png", oversized)
+	assert.ErrorIs(t, err, models.ErrAttachmentTooLarge)
+
+	// Other users' transactions cannot be attached to
+	_, err = service.Upload(user.ID+1, transaction.ID, "receipt.png", bytes.NewReader(testPNG))
+	assert.Error(t, err)
+
+	attachments, err := service.GetByTransaction(transaction.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, attachments)
+}
+
+func TestAttachmentService_PurgedWithTransaction(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	transactionService := newTestTransactionService(db)
+	service := newTestAttachmentService(t, db)
+	trashService := NewTrashService(transactionService, service,
+		repository.NewTransactionRepository(db), repository.NewAccountRepository(db), repository.NewBudgetRepository(db),
+		repository.NewGoalRepository(db), repository.NewRecurringTransactionRepository(db), 30*24*time.Hour)
+
+	transaction, err := transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 50.0, Type: "expense", Category: "Food", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+	attachment, err := service.Upload(user.ID, transaction.ID, "receipt.png", bytes.NewReader(testPNG))
+	assert.NoError(t, err)
+
+	// Trashing keeps the attachment so it comes back on restore
+	assert.NoError(t, transactionService.Delete(transaction.ID, user.ID))
+	removed, err := service.PurgeOrphaned()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+
+	// Purging the transaction removes the attachment and its file
+	assert.NoError(t, trashService.Purge(models.TrashTypeTransaction, transaction.ID, user.ID))
+
+	var count int64
+	db.Model(&models.Attachment{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	expires, signature := parseDownloadURL(t, attachment.DownloadURL)
+	_, _, err = service.OpenSigned(attachment.ID, expires, signature)
+	assert.ErrorIs(t, err, models.ErrAttachmentLinkInvalid)
+}
+
+// zeroReader is an endless stream of zero bytes
+type zeroReader struct{}
+
+// Read fills p with zeros
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// BalanceAuditService recomputes account balances from their opening balance
+// and transactions and corrects accounts that have drifted
+type BalanceAuditService struct {
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	ledgerRepo      *repository.LedgerRepository
+	ledgerService   *LedgerService
+	db              *gorm.DB
+	now             func() time.Time
+}
+
+// NewBalanceAuditService creates a new balance audit service
+func NewBalanceAuditService(
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	ledgerRepo *repository.LedgerRepository,
+	ledgerService *LedgerService,
+	db *gorm.DB,
+) *BalanceAuditService {
+	return &BalanceAuditService{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		ledgerRepo:      ledgerRepo,
+		ledgerService:   ledgerService,
+		db:              db,
+		now:             time.Now,
+	}
+}
+
+// Audit checks the accounts of a user, or of every user when userID is zero.
+// An account has drifted when its stored balance or the sum of its postings
+// differs from the expected balance. With fix set, a correction entry brings
+// the postings to the expected balance and the stored balance is synced.
+func (s *BalanceAuditService) Audit(userID uint, fix bool) (*models.BalanceAuditReport, error) {
+	accounts, err := s.accountRepo.FindAll(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.BalanceAuditReport{
+		AccountsChecked: len(accounts),
+		Drifted:         []*models.BalanceDrift{},
+		Fixed:           fix,
+		CheckedAt:       s.now(),
+	}
+
+	for i := range accounts {
+		account := &accounts[i]
+
+		expected, ledger, err := s.balances(s.db, account)
+		if err != nil {
+			return nil, err
+		}
+		if account.Balance == expected && ledger == expected {
+			continue
+		}
+
+		drift := &models.BalanceDrift{
+			AccountID:       account.ID,
+			UserID:          account.UserID,
+			Name:            account.Name,
+			Currency:        account.Currency,
+			StoredBalance:   account.Balance.Float(account.Currency),
+			LedgerBalance:   ledger.Float(account.Currency),
+			ExpectedBalance: expected.Float(account.Currency),
+			Difference:      (expected - account.Balance).Float(account.Currency),
+		}
+		report.Drifted = append(report.Drifted, drift)
+
+		if fix {
+			var correction models.Money
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				// Work out the correction again under the account lock, so a
+				// posting committed since the check above is not corrected away
+				if err := s.ledgerService.LockAccounts(tx, account.ID); err != nil {
+					return err
+				}
+				locked, err := s.accountRepo.WithTx(tx).GetByID(account.ID, account.UserID)
+				if err != nil {
+					return err
+				}
+				expected, ledger, err := s.balances(tx, locked)
+				if err != nil {
+					return err
+				}
+				correction = expected - ledger
+
+				if err := s.ledgerService.PostBalanceCorrection(tx, locked, correction); err != nil {
+					return err
+				}
+				return s.ledgerService.SyncAccountBalance(tx, locked, models.BalanceChange{
+					ChangeType:  models.BalanceChangeAdjustment,
+					Description: "Balance correction",
+				})
+			})
+			if err != nil {
+				return nil, err
+			}
+			drift.Correction = correction.Float(account.Currency)
+		}
+	}
+
+	return report, nil
+}
+
+// balances returns the expected balance of an account and the sum of its postings, read through db
+func (s *BalanceAuditService) balances(db *gorm.DB, account *models.Account) (expected, ledger models.Money, err error) {
+	expected, err = s.expectedBalance(db, account)
+	if err != nil {
+		return 0, 0, err
+	}
+	ledger, err = s.ledgerRepo.WithTx(db).GetAccountBalance(account.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return expected, ledger, nil
+}
+
+// expectedBalance adds up the opening balance, adjustments and investment
+// entries of an account and its posted transactions. Earlier corrections are
+// left out so that an account keeps being reported until its transactions
+// explain its balance.
+func (s *BalanceAuditService) expectedBalance(db *gorm.DB, account *models.Account) (models.Money, error) {
+	effects, err := s.ledgerRepo.WithTx(db).GetAccountEntryEffects(account.ID)
+	if err != nil {
+		return 0, err
+	}
+	transactions, err := s.transactionRepo.WithTx(db).GetPostedByAccount(account.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Entries of the account's own transactions are replaced by the transactions below
+	linked := make(map[uint]bool, len(transactions))
+	for _, t := range transactions {
+		if t.JournalEntryID != nil {
+			linked[*t.JournalEntryID] = true
+		}
+	}
+
+	var expected models.Money
+	var openedAt *time.Time
+	entryEffects := make(map[uint]models.Money, len(effects))
+	for i := range effects {
+		effect := effects[i]
+		entryEffects[effect.JournalEntryID] = effect.Amount
+		if effect.Type == models.JournalEntryTypeOpeningBalance && openedAt == nil {
+			openedAt = &effects[i].CreatedAt
+		}
+		if effect.Type == models.JournalEntryTypeCorrection || linked[effect.JournalEntryID] {
+			continue
+		}
+		expected += effect.Amount
+	}
+
+	for i := range transactions {
+		t := &transactions[i]
+
+		if t.JournalEntryID == nil {
+			// Transactions recorded before the ledger are part of the opening balance it was migrated with
+			if openedAt != nil && !t.CreatedAt.After(*openedAt) {
+				continue
+			}
+			effect, err := s.legacyEffect(db, t)
+			if err != nil {
+				return 0, err
+			}
+			expected += effect
+			continue
+		}
+
+		switch t.Type {
+		case "income":
+			expected += t.Amount
+		case "transfer":
+			// The posting on this account tells which leg of the transfer it is
+			posted, ok := entryEffects[*t.JournalEntryID]
+			if !ok {
+				effect, err := s.legacyEffect(db, t)
+				if err != nil {
+					return 0, err
+				}
+				expected += effect
+			} else if posted < 0 {
+				expected -= t.Amount
+			} else {
+				expected += t.Amount
+			}
+		default:
+			expected -= t.Amount
+		}
+	}
+
+	return expected, nil
+}
+
+// legacyEffect returns the balance effect of a transaction that was never posted to the ledger
+func (s *BalanceAuditService) legacyEffect(db *gorm.DB, t *models.Transaction) (models.Money, error) {
+	switch t.Type {
+	case "income":
+		return t.Amount, nil
+	case "transfer":
+		outgoing, err := s.transactionRepo.WithTx(db).IsOutgoingLegacyTransfer(t)
+		if err != nil {
+			return 0, err
+		}
+		if outgoing {
+			return -t.Amount, nil
+		}
+		return t.Amount, nil
+	default:
+		return -t.Amount, nil
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newTestBalanceAuditService wires a balance audit service against the test database
+func newTestBalanceAuditService(db *gorm.DB) *BalanceAuditService {
+	return NewBalanceAuditService(repository.NewAccountRepository(db), repository.NewTransactionRepository(db),
+		repository.NewLedgerRepository(db), newTestLedgerService(db), db)
+}
+
+func TestBalanceAuditService_ReportAndFixDrift(t *testing.T) {
+	// Setup - an expense and a transfer recorded through the ledger
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	checking := createTestAccount(t, db, user.ID, 1000.0)
+	savings := createTestAccount(t, db, user.ID, 500.0)
+
+	transactionService := newTestTransactionService(db)
+	_, err := transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 100.0, Type: "expense", Category: "Food", AccountID: checking.ID, Date: time.Now(),
+	})
+	assert.NoError(t, err)
+	_, err = transactionService.Transfer(user.ID, &models.TransferRequest{
+		Amount: 200.0, FromAccountID: checking.ID, ToAccountID: savings.ID, Date: time.Now(),
+	})
+	assert.NoError(t, err)
+
+	service := newTestBalanceAuditService(db)
+	report, err := service.Audit(user.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.AccountsChecked)
+	assert.Empty(t, report.Drifted)
+
+	// An income that never reached the ledger and a stored balance written out of band
+	assert.NoError(t, db.Create(&models.Transaction{
+		UserID: user.ID, AccountID: checking.ID, Type: "income", Category: "Salary", Currency: "USD",
+		Amount: models.NewMoney(50.0, "USD"), Status: models.TransactionStatusCleared,
+		Date: time.Now(), CreatedAt: time.Now().Add(time.Minute),
+	}).Error)
+	db.Model(&models.Account{}).Where("id = ?", savings.ID).Update("balance_minor", models.NewMoney(680.0, "USD"))
+
+	// Execute
+	report, err = service.Audit(user.ID, false)
+
+	// Assert - both accounts are reported and nothing is written
+	assert.NoError(t, err)
+	assert.False(t, report.Fixed)
+	assert.Len(t, report.Drifted, 2)
+	assert.Equal(t, checking.ID, report.Drifted[0].AccountID)
+	assert.Equal(t, 700.0, report.Drifted[0].StoredBalance)
+	assert.Equal(t, 700.0, report.Drifted[0].LedgerBalance)
+	assert.Equal(t, 750.0, report.Drifted[0].ExpectedBalance)
+	assert.Equal(t, 50.0, report.Drifted[0].Difference)
+	assert.Equal(t, savings.ID, report.Drifted[1].AccountID)
+	assert.Equal(t, 700.0, report.Drifted[1].LedgerBalance)
+	assert.Equal(t, 20.0, report.Drifted[1].Difference)
+	assert.Equal(t, 0.0, report.Drifted[1].Correction)
+
+	// Fixing posts a correction to checking and resyncs the savings balance
+	report, err = service.Audit(user.ID, true)
+	assert.NoError(t, err)
+	assert.True(t, report.Fixed)
+	assert.Equal(t, 50.0, report.Drifted[0].Correction)
+	assert.Equal(t, 0.0, report.Drifted[1].Correction)
+
+	var updatedChecking, updatedSavings models.Account
+	db.First(&updatedChecking, checking.ID)
+	db.First(&updatedSavings, savings.ID)
+	assert.Equal(t, models.NewMoney(750.0, "USD"), updatedChecking.Balance)
+	assert.Equal(t, models.NewMoney(700.0, "USD"), updatedSavings.Balance)
+
+	report, err = service.Audit(0, false)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Drifted)
+
+	integrity, err := newTestLedgerService(db).CheckIntegrity(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, integrity.Balanced)
+}
@@ -12,6 +12,7 @@ type BudgetAlertService struct {
 	budgetRepo       *repository.BudgetRepository
 	transactionRepo  *repository.TransactionRepository
 	notificationRepo *repository.NotificationRepository
+	currencyService  *CurrencyService
 }
 
 // NewBudgetAlertService creates a new budget alert service
@@ -19,11 +20,13 @@ func NewBudgetAlertService(
 	budgetRepo *repository.BudgetRepository,
 	transactionRepo *repository.TransactionRepository,
 	notificationRepo *repository.NotificationRepository,
+	currencyService *CurrencyService,
 ) *BudgetAlertService {
 	return &BudgetAlertService{
 		budgetRepo:       budgetRepo,
 		transactionRepo:  transactionRepo,
 		notificationRepo: notificationRepo,
+		currencyService:  currencyService,
 	}
 }
 
@@ -61,12 +64,15 @@ func (s *BudgetAlertService) CheckBudgetsAfterTransaction(userID uint, category
 		}
 
 		// Get spent amount for this budget
-		spent, err := s.transactionRepo.GetTotalSpentByCategory(userID, budget.Category, startDate, endDate)
+		spent, err := s.transactionRepo.GetTotalSpentByCategory(userID, budget.Category, budget.Currency, startDate, endDate, s.currencyService.Rates())
 		if err != nil {
 			continue
 		}
 
-		percentage := (spent / budget.Amount) * 100
+		if budget.Amount <= 0 {
+			continue
+		}
+		percentage := float64(spent) / float64(budget.Amount) * 100
 
 		// Check thresholds and create notifications
 		if percentage >= 100 {
@@ -10,13 +10,15 @@ import (
 
 // BudgetService handles business logic for budgets
 type BudgetService struct {
-	budgetRepo *repository.BudgetRepository
+	budgetRepo      *repository.BudgetRepository
+	currencyService *CurrencyService
 }
 
 // NewBudgetService creates a new budget service
-func NewBudgetService(budgetRepo *repository.BudgetRepository) *BudgetService {
+func NewBudgetService(budgetRepo *repository.BudgetRepository, currencyService *CurrencyService) *BudgetService {
 	return &BudgetService{
-		budgetRepo: budgetRepo,
+		budgetRepo:      budgetRepo,
+		currencyService: currencyService,
 	}
 }
 
@@ -61,12 +63,18 @@ func (s *BudgetService) Create(userID uint, req *models.BudgetRequest) (*models.
 	// - For DepartmentID: Check if user has department budget creation permission
 	// Example: permissionService.CheckPermission(userID, householdID, "budget", "create")
 
+	currency := "USD"
+	if req.Currency != "" {
+		currency = req.Currency
+	}
+
 	// Create budget
 	budget := &models.Budget{
 		UserID:       userID,
 		Name:         req.Name,
-		Amount:       req.Amount,
-		Spent:        req.Spent,
+		Amount:       models.NewMoney(req.Amount, currency),
+		Spent:        models.NewMoney(req.Spent, currency),
+		Currency:     currency,
 		Category:     req.Category,
 		Period:       req.Period,
 		StartDate:    req.StartDate,
@@ -111,9 +119,12 @@ func (s *BudgetService) Update(id uint, userID uint, req *models.BudgetRequest)
 	// Same as Create: verify user has permission before changing HouseholdID or DepartmentID
 
 	// Update budget
+	if req.Currency != "" {
+		budget.Currency = req.Currency
+	}
 	budget.Name = req.Name
-	budget.Amount = req.Amount
-	budget.Spent = req.Spent
+	budget.Amount = models.NewMoney(req.Amount, budget.Currency)
+	budget.Spent = models.NewMoney(req.Spent, budget.Currency)
 	budget.Category = req.Category
 	budget.Period = req.Period
 	budget.StartDate = req.StartDate
@@ -129,7 +140,7 @@ func (s *BudgetService) Update(id uint, userID uint, req *models.BudgetRequest)
 	return budget, nil
 }
 
-// Delete deletes a budget
+// Delete moves a budget to the trash
 func (s *BudgetService) Delete(id uint, userID uint) error {
 	return s.budgetRepo.Delete(id, userID)
 }
@@ -139,7 +150,7 @@ func (s *BudgetService) GetBudgetPeriods() []models.BudgetPeriod {
 	return s.budgetRepo.GetBudgetPeriods()
 }
 
-// GetSummary gets a summary of budgets for a user
+// GetSummary gets a summary of budgets for a user in their preferred currency
 func (s *BudgetService) GetSummary(userID uint) (*models.BudgetSummary, error) {
-	return s.budgetRepo.GetSummary(userID)
+	return s.budgetRepo.GetSummary(userID, s.currencyService.PreferredCurrency(userID), s.currencyService.Rates())
 }
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// billReminderDays is how many days before the due date a statement reminder is sent
+const billReminderDays = 5
+
+// CreditCardService handles credit card details, statements and utilization
+type CreditCardService struct {
+	creditCardRepo   *repository.CreditCardRepository
+	accountRepo      *repository.AccountRepository
+	ledgerRepo       *repository.LedgerRepository
+	notificationRepo *repository.NotificationRepository
+	currencyService  *CurrencyService
+	now              func() time.Time
+}
+
+// NewCreditCardService creates a new credit card service
+func NewCreditCardService(
+	creditCardRepo *repository.CreditCardRepository,
+	accountRepo *repository.AccountRepository,
+	ledgerRepo *repository.LedgerRepository,
+	notificationRepo *repository.NotificationRepository,
+	currencyService *CurrencyService,
+) *CreditCardService {
+	return &CreditCardService{
+		creditCardRepo:   creditCardRepo,
+		accountRepo:      accountRepo,
+		ledgerRepo:       ledgerRepo,
+		notificationRepo: notificationRepo,
+		currencyService:  currencyService,
+		now:              time.Now,
+	}
+}
+
+// Get gets the card details of a credit account with its current utilization
+func (s *CreditCardService) Get(accountID uint, userID uint) (*models.CreditCardResponse, error) {
+	account, card, err := s.getCard(accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(card, account)
+}
+
+// Save sets the card details of a credit account and closes any statement
+// that is already due under the new cycle
+func (s *CreditCardService) Save(accountID uint, userID uint, req *models.CreditCardRequest) (*models.CreditCardResponse, error) {
+	// Check if account exists and belongs to user
+	account, err := s.accountRepo.GetByID(accountID, userID)
+	if err != nil {
+		return nil, errors.New("account not found")
+	}
+	if account.Type != models.AccountTypeCredit {
+		return nil, errors.New("account is not a credit account")
+	}
+
+	card, err := s.creditCardRepo.GetByAccountID(account.ID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		card = &models.CreditCard{UserID: userID, AccountID: account.ID}
+	} else if err != nil {
+		return nil, err
+	}
+
+	card.CreditLimit = models.NewMoney(req.CreditLimit, account.Currency)
+	card.StatementClosingDay = req.StatementClosingDay
+	card.PaymentDueDay = req.PaymentDueDay
+	card.APR = req.APR
+	card.MinimumPaymentPercent = req.MinimumPaymentPercent
+	card.MinimumPaymentAmount = models.NewMoney(req.MinimumPaymentAmount, account.Currency)
+
+	if err := s.creditCardRepo.Save(card); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.refresh(card, account); err != nil {
+		return nil, err
+	}
+	return s.toResponse(card, account)
+}
+
+// Delete removes the card details and statements of a credit account; the
+// account and its transactions are kept
+func (s *CreditCardService) Delete(accountID uint, userID uint) error {
+	if _, _, err := s.getCard(accountID, userID); err != nil {
+		return err
+	}
+	return s.creditCardRepo.Delete(accountID, userID)
+}
+
+// GetStatements gets the statements of a credit account, most recent first
+func (s *CreditCardService) GetStatements(accountID uint, userID uint) ([]models.CreditCardStatement, error) {
+	if _, _, err := s.getCard(accountID, userID); err != nil {
+		return nil, err
+	}
+	return s.creditCardRepo.GetStatements(accountID, userID)
+}
+
+// GetUtilization reports the utilization of every card of a user and of all
+// of them together in the user's preferred currency
+func (s *CreditCardService) GetUtilization(userID uint) (*models.CreditUtilizationSummary, error) {
+	accounts, err := s.accountRepo.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := s.currencyService.PreferredCurrency(userID)
+	now := s.now()
+	summary := &models.CreditUtilizationSummary{
+		Currency: currency,
+		Cards:    make([]*models.CreditCardResponse, 0),
+	}
+
+	var totalLimit, totalOwed models.Money
+	for i := range accounts {
+		account := &accounts[i]
+		if account.Type != models.AccountTypeCredit {
+			continue
+		}
+		card, err := s.creditCardRepo.GetByAccountID(account.ID, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := s.toResponse(card, account)
+		if err != nil {
+			return nil, err
+		}
+		summary.Cards = append(summary.Cards, response)
+
+		limit, err := models.ConvertMoneyAt(s.currencyService.Rates(), card.CreditLimit, account.Currency, currency, now)
+		if err != nil {
+			return nil, err
+		}
+		owed, err := models.ConvertMoneyAt(s.currencyService.Rates(), models.Owed(account.Balance), account.Currency, currency, now)
+		if err != nil {
+			return nil, err
+		}
+		totalLimit += limit
+		totalOwed += owed
+	}
+
+	summary.TotalLimit = totalLimit.Float(currency)
+	summary.TotalBalance = totalOwed.Float(currency)
+	summary.AvailableCredit = (totalLimit - totalOwed).Float(currency)
+	summary.Utilization = models.UtilizationPercent(totalOwed, totalLimit)
+	return summary, nil
+}
+
+// GenerateStatements closes the statements of every card whose closing day
+// has passed, tracks the payments made against open statements and sends
+// reminders for statements coming due. Returns how many statements were closed.
+func (s *CreditCardService) GenerateStatements() (int, error) {
+	cards, err := s.creditCardRepo.GetActive()
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	var failures []string
+	for i := range cards {
+		card := &cards[i]
+		account, err := s.accountRepo.GetByID(card.AccountID, card.UserID)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("account %d: %v", card.AccountID, err))
+			continue
+		}
+
+		count, err := s.refresh(card, account)
+		closed += count
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("account %d: %v", card.AccountID, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return closed, fmt.Errorf("credit card statements failed: %s", strings.Join(failures, "; "))
+	}
+	return closed, nil
+}
+
+// refresh brings the statements of a card up to date and returns how many
+// statements were closed
+func (s *CreditCardService) refresh(card *models.CreditCard, account *models.Account) (int, error) {
+	now := s.now().UTC()
+
+	closed, err := s.closeStatements(card, account, now)
+	if err != nil {
+		return closed, err
+	}
+
+	statements, err := s.creditCardRepo.GetUnsettledStatements(account.ID)
+	if err != nil {
+		return closed, err
+	}
+	for i := range statements {
+		statement := &statements[i]
+		if err := s.trackPayments(card, statement, now); err != nil {
+			return closed, err
+		}
+		if err := s.sendReminder(account, statement, now); err != nil {
+			return closed, err
+		}
+	}
+	return closed, nil
+}
+
+// closeStatements creates a statement for every closing date that has passed
+// since the latest statement. A card without statements starts with the most
+// recent closing date rather than backfilling the whole account history.
+func (s *CreditCardService) closeStatements(card *models.CreditCard, account *models.Account, now time.Time) (int, error) {
+	lastClosing := card.ClosingDateBefore(now)
+
+	var closings []time.Time
+	latest, err := s.creditCardRepo.GetLatestStatement(account.ID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		closings = append(closings, lastClosing)
+	case err != nil:
+		return 0, err
+	default:
+		for closing := card.NextClosingDate(latest.ClosingDate); !closing.After(lastClosing); closing = card.NextClosingDate(closing) {
+			closings = append(closings, closing)
+		}
+	}
+
+	closed := 0
+	for _, closing := range closings {
+		// The closing day itself is part of the statement
+		balance, err := s.ledgerRepo.GetAccountBalanceBefore(account.ID, closing.AddDate(0, 0, 1))
+		if err != nil {
+			return closed, err
+		}
+
+		owed := models.Owed(balance)
+		statement := &models.CreditCardStatement{
+			UserID:         account.UserID,
+			AccountID:      account.ID,
+			PeriodStart:    card.PreviousClosingDate(closing).AddDate(0, 0, 1),
+			ClosingDate:    closing,
+			DueDate:        card.DueDateFor(closing),
+			ClosingBalance: owed,
+			MinimumPayment: card.MinimumPaymentFor(owed),
+			Currency:       account.Currency,
+		}
+		statement.Status = statement.StatusAt(now)
+
+		if err := s.creditCardRepo.CreateStatement(statement); err != nil {
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// trackPayments updates how much has been paid against a statement, which is
+// the money paid into the card from the day after the statement closed up to
+// and including the next closing day
+func (s *CreditCardService) trackPayments(card *models.CreditCard, statement *models.CreditCardStatement, now time.Time) error {
+	start := statement.ClosingDate.AddDate(0, 0, 1)
+	end := card.NextClosingDate(statement.ClosingDate).AddDate(0, 0, 1)
+	paid, err := s.ledgerRepo.GetAccountInflows(statement.AccountID, start, end)
+	if err != nil {
+		return err
+	}
+
+	previousPaid, previousStatus := statement.PaidAmount, statement.Status
+	statement.PaidAmount = paid
+	statement.Status = statement.StatusAt(now)
+	if statement.PaidAmount == previousPaid && statement.Status == previousStatus {
+		return nil
+	}
+	return s.creditCardRepo.UpdateStatement(statement)
+}
+
+// sendReminder raises a bill reminder once an open statement is coming due
+func (s *CreditCardService) sendReminder(account *models.Account, statement *models.CreditCardStatement, now time.Time) error {
+	if statement.Status != models.StatementStatusOpen || statement.ReminderSentAt != nil {
+		return nil
+	}
+	if now.Before(statement.DueDate.AddDate(0, 0, -billReminderDays)) {
+		return nil
+	}
+
+	// The minimum left to pay is what needs attention before the due date
+	minimumLeft := statement.MinimumPayment - statement.PaidAmount
+	priority := models.PriorityHigh
+	if minimumLeft <= 0 {
+		minimumLeft = 0
+		priority = models.PriorityMedium
+	}
+
+	statementID := statement.ID
+	expiresAt := statement.DueDate.AddDate(0, 0, 1)
+	notification := &models.Notification{
+		UserID: account.UserID,
+		Type:   models.NotificationTypeBillReminder,
+		Title:  fmt.Sprintf("Payment Due: %s", account.Name),
+		Message: fmt.Sprintf("Your %s statement balance of %s is due on %s. Minimum payment: %s.",
+			account.Name,
+			models.FormatAmount(statement.Remaining(), statement.Currency),
+			statement.DueDate.Format("Jan 2, 2006"),
+			models.FormatAmount(minimumLeft, statement.Currency)),
+		Priority:    priority,
+		ActionURL:   fmt.Sprintf("/accounts/%d", account.ID),
+		RelatedID:   &statementID,
+		RelatedType: "credit_card_statement",
+		ExpiresAt:   &expiresAt,
+	}
+	if err := s.notificationRepo.Create(notification); err != nil {
+		return err
+	}
+
+	statement.ReminderSentAt = &now
+	return s.creditCardRepo.UpdateStatement(statement)
+}
+
+// getCard gets a credit account of a user together with its card details
+func (s *CreditCardService) getCard(accountID uint, userID uint) (*models.Account, *models.CreditCard, error) {
+	account, err := s.accountRepo.GetByID(accountID, userID)
+	if err != nil {
+		return nil, nil, errors.New("account not found")
+	}
+
+	card, err := s.creditCardRepo.GetByAccountID(account.ID, userID)
+	if err != nil {
+		return nil, nil, errors.New("credit card not found")
+	}
+	return account, card, nil
+}
+
+// toResponse builds the response for a card with the utilization of its account
+func (s *CreditCardService) toResponse(card *models.CreditCard, account *models.Account) (*models.CreditCardResponse, error) {
+	owed := models.Owed(account.Balance)
+	response := &models.CreditCardResponse{
+		AccountID:             account.ID,
+		CreditLimit:           card.CreditLimit.Float(account.Currency),
+		StatementClosingDay:   card.StatementClosingDay,
+		PaymentDueDay:         card.PaymentDueDay,
+		APR:                   card.APR,
+		MinimumPaymentPercent: card.MinimumPaymentPercent,
+		MinimumPaymentAmount:  card.MinimumPaymentAmount.Float(account.Currency),
+		Currency:              account.Currency,
+		Balance:               owed.Float(account.Currency),
+		AvailableCredit:       (card.CreditLimit - owed).Float(account.Currency),
+		Utilization:           card.Utilization(owed),
+		NextClosingDate:       card.NextClosingDate(card.ClosingDateBefore(s.now().UTC())),
+	}
+
+	latest, err := s.creditCardRepo.GetLatestStatement(account.ID)
+	if err == nil {
+		response.LatestStatement = latest.ToResponse()
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newTestCreditCardService wires a credit card service whose clock is fixed at now
+func newTestCreditCardService(db *gorm.DB, now time.Time) *CreditCardService {
+	service := NewCreditCardService(repository.NewCreditCardRepository(db), repository.NewAccountRepository(db),
+		repository.NewLedgerRepository(db), repository.NewNotificationRepository(db), newTestCurrencyService(db))
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestCreditCardService_StatementCycle(t *testing.T) {
+	// Setup - a card closing on the 15th with payments due on the 10th
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	checking := createTestAccount(t, db, user.ID, 1000.0)
+	card := createTestAccount(t, db, user.ID, 0)
+	db.Model(card).Update("type", models.AccountTypeCredit)
+
+	transactionService := newTestTransactionService(db)
+	spend := func(amount float64, date time.Time) {
+		_, err := transactionService.Create(user.ID, &models.TransactionRequest{
+			Amount: amount, Type: "expense", Category: "Shopping", Date: date, AccountID: card.ID,
+		})
+		assert.NoError(t, err)
+	}
+	spend(300.0, time.Date(2024, 2, 20, 12, 0, 0, 0, time.UTC))
+	spend(200.0, time.Date(2024, 3, 15, 18, 0, 0, 0, time.UTC))
+	spend(50.0, time.Date(2024, 3, 18, 12, 0, 0, 0, time.UTC))
+	_, err := transactionService.Transfer(user.ID, &models.TransferRequest{
+		FromAccountID: checking.ID, ToAccountID: card.ID, Amount: 100.0,
+		Description: "Card payment", Date: time.Date(2024, 3, 18, 12, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+
+	service := newTestCreditCardService(db, time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC))
+	req := &models.CreditCardRequest{
+		CreditLimit: 1000.0, StatementClosingDay: 15, PaymentDueDay: 10,
+		APR: 19.99, MinimumPaymentPercent: 2, MinimumPaymentAmount: 25.0,
+	}
+
+	// Card details can only be set on credit accounts
+	_, err = service.Save(checking.ID, user.ID, req)
+	assert.EqualError(t, err, "account is not a credit account")
+
+	// Execute - setting the details closes the statement of the last cycle
+	response, err := service.Save(card.ID, user.ID, req)
+
+	// Assert - the closing day is included, spending after it is not
+	assert.NoError(t, err)
+	assert.Equal(t, 450.0, response.Balance)
+	assert.Equal(t, 550.0, response.AvailableCredit)
+	assert.Equal(t, 45.0, response.Utilization)
+	assert.Equal(t, "2024-04-15", response.NextClosingDate.Format("2006-01-02"))
+
+	statement := response.LatestStatement
+	assert.NotNil(t, statement)
+	assert.Equal(t, "2024-02-16", statement.PeriodStart.Format("2006-01-02"))
+	assert.Equal(t, "2024-03-15", statement.ClosingDate.Format("2006-01-02"))
+	assert.Equal(t, "2024-04-10", statement.DueDate.Format("2006-01-02"))
+	assert.Equal(t, 500.0, statement.ClosingBalance)
+	assert.Equal(t, 25.0, statement.MinimumPayment) // 2% is below the floor
+	assert.Equal(t, 100.0, statement.PaidAmount)
+	assert.Equal(t, models.StatementStatusOpen, statement.Status)
+
+	// A reminder is raised once the due date is close, and only once
+	service.now = func() time.Time { return time.Date(2024, 4, 6, 9, 0, 0, 0, time.UTC) }
+	closed, err := service.GenerateStatements()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, closed)
+	_, err = service.GenerateStatements()
+	assert.NoError(t, err)
+
+	var reminders []models.Notification
+	db.Where("user_id = ? AND type = ?", user.ID, models.NotificationTypeBillReminder).Find(&reminders)
+	assert.Len(t, reminders, 1)
+	assert.Equal(t, "credit_card_statement", reminders[0].RelatedType)
+	assert.Equal(t, statement.ID, *reminders[0].RelatedID)
+	assert.Equal(t, models.PriorityMedium, reminders[0].Priority)
+
+	// The next cycle closes after its closing day and settles the previous statement
+	service.now = func() time.Time { return time.Date(2024, 4, 16, 9, 0, 0, 0, time.UTC) }
+	closed, err = service.GenerateStatements()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, closed)
+
+	statements, err := service.GetStatements(card.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, statements, 2)
+	assert.Equal(t, "2024-04-15", statements[0].ClosingDate.Format("2006-01-02"))
+	assert.Equal(t, models.NewMoney(450.0, "USD"), statements[0].ClosingBalance)
+	assert.Equal(t, models.StatementStatusOpen, statements[0].Status)
+	assert.Equal(t, models.StatementStatusMinimumPaid, statements[1].Status)
+
+	// Utilization is reported across cards in the preferred currency
+	summary, err := service.GetUtilization(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, summary.Cards, 1)
+	assert.Equal(t, 1000.0, summary.TotalLimit)
+	assert.Equal(t, 45.0, summary.Utilization)
+}
+
+func TestCreditCard_CycleDates(t *testing.T) {
+	card := &models.CreditCard{StatementClosingDay: 31, PaymentDueDay: 25}
+
+	// Short months close on their last day
+	closing := card.ClosingDateBefore(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, "2024-02-29", closing.Format("2006-01-02"))
+	assert.Equal(t, "2024-03-31", card.NextClosingDate(closing).Format("2006-01-02"))
+	assert.Equal(t, "2024-03-25", card.DueDateFor(closing).Format("2006-01-02"))
+
+	// The minimum payment never exceeds what is owed
+	card.MinimumPaymentAmount = models.NewMoney(25.0, "USD")
+	assert.Equal(t, models.NewMoney(10.0, "USD"), card.MinimumPaymentFor(models.NewMoney(10.0, "USD")))
+	assert.Equal(t, models.Money(0), card.MinimumPaymentFor(0))
+}
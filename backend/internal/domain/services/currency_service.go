@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/exchangerates"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+)
+
+// defaultReportingCurrency is used for users without a supported preferred currency
+const defaultReportingCurrency = "USD"
+
+// rateRefreshTimeout bounds how long a single provider may take to respond
+const rateRefreshTimeout = time.Minute
+
+// CurrencyService handles exchange rates and the currency users report in.
+// It is itself an ExchangeRateSource backed by the dated rate store.
+type CurrencyService struct {
+	userRepo    *repository.UserRepository
+	rateRepo    *repository.ExchangeRateRepository
+	providers   []exchangerates.Provider
+	staticRates models.StaticExchangeRates
+}
+
+// NewCurrencyService creates a new currency service that fills the rate store from providers
+func NewCurrencyService(
+	userRepo *repository.UserRepository,
+	rateRepo *repository.ExchangeRateRepository,
+	providers ...exchangerates.Provider,
+) *CurrencyService {
+	return &CurrencyService{
+		userRepo:  userRepo,
+		rateRepo:  rateRepo,
+		providers: providers,
+	}
+}
+
+// Rates returns the exchange rate source used for conversions
+func (s *CurrencyService) Rates() models.ExchangeRateSource {
+	return s
+}
+
+// Rate returns how many units of toCurrency one unit of fromCurrency was worth on date
+func (s *CurrencyService) Rate(fromCurrency, toCurrency string, date time.Time) (float64, error) {
+	rate, err := s.Quote(fromCurrency, toCurrency, date)
+	if err != nil {
+		return 0, err
+	}
+	return rate.Rate, nil
+}
+
+// Quote gets the rate in effect on date together with the day and source it
+// comes from. Dates without a stored rate use the nearest earlier one; pairs
+// that were never stored fall back to the static rates of SupportedCurrencies.
+func (s *CurrencyService) Quote(fromCurrency, toCurrency string, date time.Time) (*models.ExchangeRate, error) {
+	if models.GetCurrencyByCode(fromCurrency) == nil || models.GetCurrencyByCode(toCurrency) == nil {
+		return nil, errors.New("unsupported currency")
+	}
+	if fromCurrency == toCurrency {
+		return &models.ExchangeRate{
+			BaseCurrency:  fromCurrency,
+			QuoteCurrency: toCurrency,
+			Date:          models.RateDate(date),
+			Rate:          1,
+		}, nil
+	}
+
+	rate, err := s.rateRepo.Find(fromCurrency, toCurrency, date)
+	if err == nil {
+		return rate, nil
+	}
+	if !errors.Is(err, models.ErrExchangeRateNotFound) {
+		return nil, err
+	}
+
+	// No stored rate for the pair on or before date
+	static, err := s.staticRates.Rate(fromCurrency, toCurrency, date)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ExchangeRate{
+		BaseCurrency:  fromCurrency,
+		QuoteCurrency: toCurrency,
+		Date:          models.RateDate(date),
+		Rate:          static,
+		Source:        models.ExchangeRateSourceStatic,
+	}, nil
+}
+
+// GetRates gets the stored rates from a base currency in effect on date
+func (s *CurrencyService) GetRates(baseCurrency string, date time.Time) ([]models.ExchangeRate, error) {
+	return s.rateRepo.GetByDate(baseCurrency, date)
+}
+
+// RefreshRates fetches rates from every provider and stores them, returning
+// how many rates were saved. A failing provider does not stop the others.
+func (s *CurrencyService) RefreshRates() (int, error) {
+	saved := 0
+	var failures []string
+	for _, provider := range s.providers {
+		ctx, cancel := context.WithTimeout(context.Background(), rateRefreshTimeout)
+		rates, err := provider.Fetch(ctx)
+		cancel()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", provider.Name(), err))
+			continue
+		}
+
+		if err := s.rateRepo.Upsert(rates); err != nil {
+			return saved, err
+		}
+		saved += len(rates)
+	}
+
+	if len(failures) > 0 {
+		return saved, fmt.Errorf("exchange rate providers failed: %s", strings.Join(failures, "; "))
+	}
+	return saved, nil
+}
+
+// PreferredCurrency returns the currency a user wants totals reported in
+func (s *CurrencyService) PreferredCurrency(userID uint) string {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || models.GetCurrencyByCode(user.PreferredCurrency) == nil {
+		return defaultReportingCurrency
+	}
+	return user.PreferredCurrency
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/exchangerates"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// testECBRates is an ECB reference rate file with two days of rates
+const testECBRates = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2024-01-04">
+			<Cube currency="USD" rate="1.20"/>
+			<Cube currency="GBP" rate="0.87"/>
+		</Cube>
+		<Cube time="2024-01-02">
+			<Cube currency="USD" rate="1.10"/>
+			<Cube currency="GBP" rate="0.86"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestCurrencyService_RefreshFromHTTPProvider(t *testing.T) {
+	// Setup - a local stub of the rate feed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(testECBRates))
+	}))
+	defer server.Close()
+
+	db := setupTestDB(t)
+	service := NewCurrencyService(repository.NewUserRepository(db), repository.NewExchangeRateRepository(db),
+		exchangerates.NewHTTPProvider(server.URL))
+
+	// Execute
+	saved, err := service.RefreshRates()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 4, saved)
+
+	// A date without rates uses the nearest earlier one
+	rate, err := service.Quote("EUR", "USD", time.Date(2024, 1, 3, 15, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 1.10, rate.Rate)
+	assert.Equal(t, "2024-01-02", rate.Date.Format("2006-01-02"))
+	assert.Equal(t, "http", rate.Source)
+
+	// Inverse and cross rates come from the stored euro rates
+	rate, err = service.Quote("USD", "EUR", time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.InDelta(t, 1/1.20, rate.Rate, 1e-9)
+
+	rate, err = service.Quote("USD", "GBP", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.87/1.20, rate.Rate, 1e-9)
+	assert.Equal(t, "2024-01-04", rate.Date.Format("2006-01-02"))
+
+	// Before the first stored rate the static rates are used and labelled as such
+	rate, err = service.Quote("EUR", "USD", time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, models.ExchangeRateSourceStatic, rate.Source)
+
+	// Refreshing again replaces rather than duplicates the stored rates
+	_, err = service.RefreshRates()
+	assert.NoError(t, err)
+	var count int64
+	db.Model(&models.ExchangeRate{}).Count(&count)
+	assert.Equal(t, int64(4), count)
+}
+
+func TestCurrencyService_RefreshFromFile(t *testing.T) {
+	// Setup - a CSV file and a provider that is down
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	csv := "date,base,quote,rate\n2024-03-01,EUR,USD,1.08\n2024-03-01,USD,JPY,150.25\n"
+	if err := os.WriteFile(path, []byte(csv), 0600); err != nil {
+		t.Fatalf("Failed to write rates file: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+	service := NewCurrencyService(repository.NewUserRepository(db), repository.NewExchangeRateRepository(db),
+		exchangerates.NewFileProvider(path), exchangerates.NewHTTPProvider(server.URL))
+
+	// Execute
+	saved, err := service.RefreshRates()
+
+	// Assert - the failing provider is reported without losing the file rates
+	assert.Error(t, err)
+	assert.Equal(t, 2, saved)
+
+	rate, err := service.Quote("EUR", "USD", time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 1.08, rate.Rate)
+	assert.Equal(t, "file:rates.csv", rate.Source)
+
+	// Foreign currency transactions use the stored rate for their date
+	transactionService := newTestTransactionServiceWithCurrency(db, service)
+	transaction, err := transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 100.0, Currency: "EUR", Type: "expense", Category: "Travel",
+		Date: time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1.08, transaction.ExchangeRate)
+	assert.Equal(t, models.NewMoney(108.0, "USD"), transaction.Amount)
+
+	// Budget spend converts spending in other currencies at the rate of its date
+	euroAccount := createTestAccount(t, db, user.ID, 0)
+	db.Model(euroAccount).Update("currency", "EUR")
+	_, err = transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 50.0, Type: "expense", Category: "Travel",
+		Date: time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC), AccountID: euroAccount.ID,
+	})
+	assert.NoError(t, err)
+
+	spent, err := transactionService.transactionRepo.GetTotalSpentByCategory(user.ID, "Travel", "USD",
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), service.Rates())
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(162.0, "USD"), spent)
+}
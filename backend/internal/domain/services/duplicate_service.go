@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// DuplicateService handles finding and reviewing probable duplicate transactions
+type DuplicateService struct {
+	transactionRepo    *repository.TransactionRepository
+	duplicateRepo      *repository.DuplicateRepository
+	transactionService *TransactionService
+	db                 *gorm.DB
+}
+
+// NewDuplicateService creates a new duplicate service
+func NewDuplicateService(
+	transactionRepo *repository.TransactionRepository,
+	duplicateRepo *repository.DuplicateRepository,
+	transactionService *TransactionService,
+	db *gorm.DB,
+) *DuplicateService {
+	return &DuplicateService{
+		transactionRepo:    transactionRepo,
+		duplicateRepo:      duplicateRepo,
+		transactionService: transactionService,
+		db:                 db,
+	}
+}
+
+// FindDuplicates gets the existing transactions a transaction probably duplicates
+func (s *DuplicateService) FindDuplicates(transaction *models.Transaction) ([]models.Transaction, error) {
+	duplicates, err := s.transactionRepo.FindProbableDuplicates(transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	dismissed, err := s.duplicateRepo.GetDismissed(transaction.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := duplicates[:0]
+	for _, d := range duplicates {
+		if !dismissed[duplicatePairKey(transaction.ID, d.ID)] {
+			kept = append(kept, d)
+		}
+	}
+	return kept, nil
+}
+
+// GetSuspected gets the pairs of a user's transactions dated on or after
+// since that are probably duplicates and were not dismissed
+func (s *DuplicateService) GetSuspected(userID uint, since time.Time) ([]*models.DuplicatePair, error) {
+	transactions, err := s.transactionRepo.GetDuplicateCandidates(userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	dismissed, err := s.duplicateRepo.GetDismissed(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Candidates come grouped by account, type and amount and ordered by
+	// date, so each transaction is compared only with the ones after it
+	// in its group that are inside the date window
+	pairs := []*models.DuplicatePair{}
+	for i := range transactions {
+		a := &transactions[i]
+		for j := i + 1; j < len(transactions); j++ {
+			b := &transactions[j]
+			if b.AccountID != a.AccountID || b.Type != a.Type || b.Currency != a.Currency || b.Amount != a.Amount ||
+				b.Date.Sub(a.Date) > models.DuplicateDateWindow {
+				break
+			}
+			if dismissed[duplicatePairKey(a.ID, b.ID)] || !models.IsProbableDuplicate(a, b) {
+				continue
+			}
+
+			first, second := a, b
+			if second.ID < first.ID {
+				first, second = second, first
+			}
+			pairs = append(pairs, &models.DuplicatePair{
+				Transaction: first.ToResponse(),
+				Duplicate:   second.ToResponse(),
+				Similarity:  models.DescriptionSimilarity(a.Description, b.Description),
+			})
+		}
+	}
+	return pairs, nil
+}
+
+// Merge keeps one transaction of a duplicate pair and moves the other to
+// the trash. The kept transaction gains the tags of the duplicate and its
+// payee when it has none.
+func (s *DuplicateService) Merge(userID uint, req *models.DuplicatePairRequest) (*models.Transaction, error) {
+	var transaction *models.Transaction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		found, duplicate, err := s.getPair(tx, userID, req)
+		if err != nil {
+			return err
+		}
+
+		// Lock the accounts of both transactions up front and read them
+		// again, so a concurrent delete of either waits for the merge
+		transaction, err = s.transactionService.lockTransaction(tx, found.ID, userID, duplicate.AccountID)
+		if err != nil {
+			return errors.New("transaction not found")
+		}
+		duplicate, err = s.transactionService.lockTransaction(tx, duplicate.ID, userID)
+		if err != nil {
+			return errors.New("transaction not found")
+		}
+		if transaction.Type == "transfer" || duplicate.Type == "transfer" {
+			return errors.New("transfers cannot be merged")
+		}
+
+		if len(duplicate.Tags) > 0 {
+			if err := tx.Model(transaction).Association("Tags").Append(duplicate.Tags); err != nil {
+				return err
+			}
+		}
+		if transaction.PayeeID == nil && duplicate.PayeeID != nil {
+			if err := tx.Model(transaction).Update("payee_id", *duplicate.PayeeID).Error; err != nil {
+				return err
+			}
+		}
+		return s.transactionService.deleteTransaction(tx, duplicate)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.transactionRepo.GetByID(transaction.ID, userID)
+}
+
+// Dismiss records that a pair of transactions are not duplicates so the
+// pair is no longer suspected
+func (s *DuplicateService) Dismiss(userID uint, req *models.DuplicatePairRequest) error {
+	if _, _, err := s.getPair(s.db, userID, req); err != nil {
+		return err
+	}
+	return s.duplicateRepo.Dismiss(userID, req.TransactionID, req.DuplicateID)
+}
+
+// getPair gets both transactions of a pair request through db
+func (s *DuplicateService) getPair(db *gorm.DB, userID uint, req *models.DuplicatePairRequest) (*models.Transaction, *models.Transaction, error) {
+	if req.TransactionID == req.DuplicateID {
+		return nil, nil, errors.New("a transaction cannot duplicate itself")
+	}
+
+	transactionRepo := s.transactionRepo.WithTx(db)
+	transaction, err := transactionRepo.GetByID(req.TransactionID, userID)
+	if err != nil {
+		return nil, nil, errors.New("transaction not found")
+	}
+	duplicate, err := transactionRepo.GetByID(req.DuplicateID, userID)
+	if err != nil {
+		return nil, nil, errors.New("transaction not found")
+	}
+	return transaction, duplicate, nil
+}
+
+// duplicatePairKey returns the key of a pair of transactions with the lower ID first
+func duplicatePairKey(a, b uint) [2]uint {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]uint{a, b}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateService_FindMergeAndDismiss(t *testing.T) {
+	// Setup - the same purchases recorded twice with slightly different details
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	transactionService := newTestTransactionService(db)
+	service := NewDuplicateService(repository.NewTransactionRepository(db), repository.NewDuplicateRepository(db), transactionService, db)
+	create := func(amount float64, description string, day int, tags ...string) *models.Transaction {
+		transaction, err := transactionService.Create(user.ID, &models.TransactionRequest{
+			Amount: amount, Type: "expense", Category: "Shopping", Description: description, AccountID: account.ID,
+			Date: time.Date(2024, 3, day, 12, 0, 0, 0, time.UTC), Tags: tags,
+		})
+		assert.NoError(t, err)
+		return transaction
+	}
+	order := create(25.0, "AMAZON MKTPLACE*1A2B3C", 1)
+	orderAgain := create(25.0, "Amazon Mktplace", 3, "online")
+	create(25.0, "Corner Cafe", 2)
+	create(25.0, "Amazon Mktplace", 10)
+	streaming := create(15.0, "NETFLIX.COM", 5)
+	streamingAgain := create(15.0, "Netflix", 6)
+
+	// Execute - find duplicates of a new transaction and list suspected pairs
+	duplicates, err := service.FindDuplicates(orderAgain)
+	assert.NoError(t, err)
+	assert.Len(t, duplicates, 1)
+	assert.Equal(t, order.ID, duplicates[0].ID)
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pairs, err := service.GetSuspected(user.ID, since)
+	assert.NoError(t, err)
+	assert.Len(t, pairs, 2)
+
+	// Assert - dismissed pairs are no longer suspected
+	err = service.Dismiss(user.ID, &models.DuplicatePairRequest{TransactionID: streamingAgain.ID, DuplicateID: streaming.ID})
+	assert.NoError(t, err)
+	duplicates, err = service.FindDuplicates(streamingAgain)
+	assert.NoError(t, err)
+	assert.Empty(t, duplicates)
+	pairs, err = service.GetSuspected(user.ID, since)
+	assert.NoError(t, err)
+	assert.Len(t, pairs, 1)
+	assert.Equal(t, order.ID, pairs[0].Transaction.ID)
+	assert.Equal(t, orderAgain.ID, pairs[0].Duplicate.ID)
+
+	// Merging keeps the tags of the duplicate and trashes it
+	_, err = service.Merge(user.ID, &models.DuplicatePairRequest{TransactionID: order.ID, DuplicateID: order.ID})
+	assert.Error(t, err)
+	merged, err := service.Merge(user.ID, &models.DuplicatePairRequest{TransactionID: order.ID, DuplicateID: orderAgain.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"online"}, models.TagNames(merged.Tags))
+
+	_, err = transactionService.GetByID(orderAgain.ID, user.ID)
+	assert.Error(t, err)
+	pairs, err = service.GetSuspected(user.ID, since)
+	assert.NoError(t, err)
+	assert.Empty(t, pairs)
+
+	var updated models.Account
+	db.First(&updated, account.ID)
+	assert.Equal(t, models.NewMoney(1000.0-25.0*3-15.0*2, "USD"), updated.Balance)
+}
+
+func TestDuplicateService_MergeConcurrentDelete(t *testing.T) {
+	// Setup - a purchase recorded twice
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	transactionService := newTestTransactionService(db)
+	service := NewDuplicateService(repository.NewTransactionRepository(db), repository.NewDuplicateRepository(db), transactionService, db)
+	var ids []uint
+	for _, description := range []string{"AMAZON MKTPLACE*1A2B3C", "Amazon Mktplace"} {
+		transaction, err := transactionService.Create(user.ID, &models.TransactionRequest{
+			Amount: 25.0, Type: "expense", Category: "Shopping", Description: description, AccountID: account.ID, Date: time.Now(),
+		})
+		assert.NoError(t, err)
+		ids = append(ids, transaction.ID)
+	}
+
+	// Execute - merge the pair while the duplicate is deleted
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, errs[0] = service.Merge(user.ID, &models.DuplicatePairRequest{TransactionID: ids[0], DuplicateID: ids[1]})
+	}()
+	go func() {
+		defer wg.Done()
+		errs[1] = transactionService.Delete(ids[1], user.ID)
+	}()
+	wg.Wait()
+
+	// Assert - the duplicate is trashed and its amount given back only once
+	if errs[0] != nil && errs[1] != nil {
+		t.Fatalf("both merge and delete failed: %v, %v", errs[0], errs[1])
+	}
+	var updated models.Account
+	db.First(&updated, account.ID)
+	assert.Equal(t, models.NewMoney(975.0, "USD"), updated.Balance)
+
+	report, err := transactionService.ledgerService.CheckIntegrity(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, report.Balanced)
+	assert.Empty(t, report.AccountMismatches)
+}
@@ -6,22 +6,29 @@ import (
 
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
 	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
 )
 
 // GoalService handles business logic for financial goals
 type GoalService struct {
-	goalRepo    *repository.GoalRepository
-	accountRepo *repository.AccountRepository
+	goalRepo      *repository.GoalRepository
+	accountRepo   *repository.AccountRepository
+	ledgerService *LedgerService
+	db            *gorm.DB
 }
 
 // NewGoalService creates a new goal service
 func NewGoalService(
 	goalRepo *repository.GoalRepository,
 	accountRepo *repository.AccountRepository,
+	ledgerService *LedgerService,
+	db *gorm.DB,
 ) *GoalService {
 	return &GoalService{
-		goalRepo:    goalRepo,
-		accountRepo: accountRepo,
+		goalRepo:      goalRepo,
+		accountRepo:   accountRepo,
+		ledgerService: ledgerService,
+		db:            db,
 	}
 }
 
@@ -50,8 +57,8 @@ func (s *GoalService) Create(userID uint, req *models.GoalRequest) (*models.Goal
 		UserID:        userID,
 		Name:          req.Name,
 		Description:   req.Description,
-		TargetAmount:  req.TargetAmount,
-		CurrentAmount: req.CurrentAmount,
+		TargetAmount:  models.NewMoney(req.TargetAmount, currency),
+		CurrentAmount: models.NewMoney(req.CurrentAmount, currency),
 		Currency:      currency,
 		Category:      req.Category,
 		Icon:          req.Icon,
@@ -110,8 +117,11 @@ func (s *GoalService) Update(id uint, userID uint, req *models.GoalRequest) (*mo
 	// Update fields
 	goal.Name = req.Name
 	goal.Description = req.Description
-	goal.TargetAmount = req.TargetAmount
-	goal.CurrentAmount = req.CurrentAmount
+	if req.Currency != "" {
+		goal.Currency = req.Currency
+	}
+	goal.TargetAmount = models.NewMoney(req.TargetAmount, goal.Currency)
+	goal.CurrentAmount = models.NewMoney(req.CurrentAmount, goal.Currency)
 	goal.Category = req.Category
 	goal.Icon = req.Icon
 	goal.Color = req.Color
@@ -119,10 +129,6 @@ func (s *GoalService) Update(id uint, userID uint, req *models.GoalRequest) (*mo
 	goal.AccountID = req.AccountID
 	goal.Priority = req.Priority
 
-	if req.Currency != "" {
-		goal.Currency = req.Currency
-	}
-
 	// Check completion status
 	if goal.CurrentAmount >= goal.TargetAmount && !goal.IsCompleted {
 		goal.IsCompleted = true
@@ -140,7 +146,7 @@ func (s *GoalService) Update(id uint, userID uint, req *models.GoalRequest) (*mo
 	return goal, nil
 }
 
-// Delete deletes a goal
+// Delete moves a goal to the trash
 func (s *GoalService) Delete(id uint, userID uint) error {
 	return s.goalRepo.Delete(id, userID)
 }
@@ -158,7 +164,8 @@ func (s *GoalService) Contribute(id uint, userID uint, amount float64, descripti
 	}
 
 	// Update current amount
-	goal.CurrentAmount += amount
+	previousAmount := goal.CurrentAmount
+	goal.CurrentAmount += models.NewMoney(amount, goal.Currency)
 	if goal.CurrentAmount < 0 {
 		goal.CurrentAmount = 0
 	}
@@ -173,7 +180,16 @@ func (s *GoalService) Contribute(id uint, userID uint, amount float64, descripti
 		goal.CompletedAt = nil
 	}
 
-	if err := s.goalRepo.Update(goal); err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(goal).Error; err != nil {
+			return err
+		}
+
+		// Record the contribution actually applied after clamping at zero
+		return s.ledgerService.PostGoalContribution(tx, goal, goal.CurrentAmount-previousAmount, description)
+	})
+
+	if err != nil {
 		return nil, err
 	}
 
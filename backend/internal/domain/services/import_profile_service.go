@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ImportProfileService handles business logic for CSV import profiles
+type ImportProfileService struct {
+	profileRepo *repository.ImportProfileRepository
+	accountRepo *repository.AccountRepository
+}
+
+// NewImportProfileService creates a new import profile service
+func NewImportProfileService(profileRepo *repository.ImportProfileRepository, accountRepo *repository.AccountRepository) *ImportProfileService {
+	return &ImportProfileService{
+		profileRepo: profileRepo,
+		accountRepo: accountRepo,
+	}
+}
+
+// GetAll gets all import profiles of a user
+func (s *ImportProfileService) GetAll(userID uint) ([]models.ImportProfile, error) {
+	return s.profileRepo.GetAll(userID)
+}
+
+// GetByID gets an import profile of a user
+func (s *ImportProfileService) GetByID(id uint, userID uint) (*models.ImportProfile, error) {
+	return s.getProfile(id, userID)
+}
+
+// Create creates a new import profile
+func (s *ImportProfileService) Create(userID uint, req *models.ImportProfileRequest) (*models.ImportProfile, error) {
+	profile := &models.ImportProfile{UserID: userID}
+	if err := s.apply(profile, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.profileRepo.Create(profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// Update updates the layout of an import profile
+func (s *ImportProfileService) Update(id uint, userID uint, req *models.ImportProfileRequest) (*models.ImportProfile, error) {
+	profile, err := s.getProfile(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(profile, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.profileRepo.Update(profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// Delete deletes an import profile
+func (s *ImportProfileService) Delete(id uint, userID uint) error {
+	err := s.profileRepo.Delete(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("import profile not found")
+	}
+	return err
+}
+
+// apply validates a profile request and copies it onto the profile. The
+// delimiter defaults to a comma, the decimal separator to a point and the
+// sign convention to a type column.
+func (s *ImportProfileService) apply(profile *models.ImportProfile, req *models.ImportProfileRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("import profile name is required")
+	}
+	if req.AccountID != nil {
+		if _, err := s.accountRepo.GetByID(*req.AccountID, profile.UserID); err != nil {
+			return errors.New("account not found")
+		}
+	}
+
+	profile.Name = name
+	profile.Delimiter = req.Delimiter
+	if profile.Delimiter == "" {
+		profile.Delimiter = ","
+	}
+	profile.DecimalSeparator = req.DecimalSeparator
+	if profile.DecimalSeparator == "" {
+		profile.DecimalSeparator = "."
+	}
+	profile.DateFormat = strings.TrimSpace(req.DateFormat)
+	profile.SignConvention = req.SignConvention
+	if profile.SignConvention == "" {
+		profile.SignConvention = models.ImportSignTypeColumn
+	}
+	profile.SkipRows = req.SkipRows
+	profile.AccountID = req.AccountID
+
+	profile.DateColumn = strings.TrimSpace(req.DateColumn)
+	profile.AmountColumn = strings.TrimSpace(req.AmountColumn)
+	profile.DebitColumn = strings.TrimSpace(req.DebitColumn)
+	profile.CreditColumn = strings.TrimSpace(req.CreditColumn)
+	profile.TypeColumn = strings.TrimSpace(req.TypeColumn)
+	profile.DescriptionColumn = strings.TrimSpace(req.DescriptionColumn)
+	profile.PayeeColumn = strings.TrimSpace(req.PayeeColumn)
+	profile.CategoryColumn = strings.TrimSpace(req.CategoryColumn)
+	profile.AccountColumn = strings.TrimSpace(req.AccountColumn)
+	profile.TagsColumn = strings.TrimSpace(req.TagsColumn)
+	if profile.DateColumn == "" {
+		return errors.New("date column is required")
+	}
+
+	return profile.Validate()
+}
+
+// getProfile gets an import profile of a user, reporting a missing profile as not found
+func (s *ImportProfileService) getProfile(id uint, userID uint) (*models.ImportProfile, error) {
+	profile, err := s.profileRepo.GetByID(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("import profile not found")
+	}
+	return profile, err
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportProfileService_CreateUpdateDelete(t *testing.T) {
+	// Setup - a bank writing semicolon separated files with decimal commas
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := NewImportProfileService(repository.NewImportProfileRepository(db), repository.NewAccountRepository(db))
+	req := &models.ImportProfileRequest{
+		Name: "Sparkasse", Delimiter: ";", DecimalSeparator: ",", DateFormat: "DD.MM.YYYY",
+		SignConvention: models.ImportSignDebitCredit, SkipRows: 2, AccountID: &account.ID,
+		DateColumn: " Buchungstag ", DebitColumn: "Soll", CreditColumn: "Haben", DescriptionColumn: "Verwendungszweck",
+	}
+
+	// Execute
+	profile, err := service.Create(user.ID, req)
+
+	// Assert - columns are trimmed and the profile reads files of the bank
+	assert.NoError(t, err)
+	assert.Equal(t, "Buchungstag", profile.DateColumn)
+	assert.Equal(t, []string{"Buchungstag", "Soll", "Haben", "Verwendungszweck"}, profile.Columns())
+	assert.True(t, profile.Matches([]string{"buchungstag", "Valuta", "Soll", "Haben", "Verwendungszweck"}))
+	assert.False(t, profile.Matches([]string{"Buchungstag", "Betrag", "Verwendungszweck"}))
+
+	date, err := profile.ParseDate("23.11.2024")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.November, 23, 0, 0, 0, 0, time.UTC), date)
+	for value, expected := range map[string]models.Money{"1.234,56": 123456, "(12,50)": -1250, "12,50-": -1250} {
+		amount, err := profile.ParseAmount(value, "EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, amount, value)
+	}
+
+	// Invalid profiles are rejected
+	_, err = service.Create(user.ID, &models.ImportProfileRequest{Name: "No type", DateColumn: "date", AmountColumn: "amount"})
+	assert.EqualError(t, err, "amount and type columns are required")
+	_, err = service.Create(user.ID, &models.ImportProfileRequest{
+		Name: "Bad date", DateColumn: "date", AmountColumn: "amount", SignConvention: models.ImportSignNegativeExpense, DateFormat: "MM/YYYY",
+	})
+	assert.EqualError(t, err, "invalid date format: MM/YYYY")
+	unknown := uint(9999)
+	_, err = service.Create(user.ID, &models.ImportProfileRequest{
+		Name: "Other account", DateColumn: "date", AmountColumn: "amount", SignConvention: models.ImportSignNegativeExpense, AccountID: &unknown,
+	})
+	assert.EqualError(t, err, "account not found")
+
+	// Update and delete
+	req.Name = "Sparkasse Giro"
+	req.SignConvention = models.ImportSignNegativeExpense
+	req.AmountColumn = "Betrag"
+	updated, err := service.Update(profile.ID, user.ID, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "Sparkasse Giro", updated.Name)
+	assert.Equal(t, []string{"Betrag", "Buchungstag"}, updated.RequiredColumns())
+
+	_, err = service.Update(profile.ID, user.ID+1, req)
+	assert.EqualError(t, err, "import profile not found")
+	assert.NoError(t, service.Delete(profile.ID, user.ID))
+	_, err = service.GetByID(profile.ID, user.ID)
+	assert.EqualError(t, err, "import profile not found")
+}
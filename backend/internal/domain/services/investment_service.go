@@ -0,0 +1,688 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/prices"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// priceRefreshTimeout bounds how long a single price provider may take to respond
+const priceRefreshTimeout = time.Minute
+
+// priceSourceTrade marks a holding valued at its last trade price because no market price is known
+const priceSourceTrade = "trade"
+
+// InvestmentService handles securities, investment transactions, lot cost
+// basis and the market value of holdings
+type InvestmentService struct {
+	investmentRepo  *repository.InvestmentRepository
+	priceRepo       *repository.SecurityPriceRepository
+	accountRepo     *repository.AccountRepository
+	taxRepo         *repository.TaxRepository
+	ledgerService   *LedgerService
+	currencyService *CurrencyService
+	db              *gorm.DB
+	providers       []prices.Provider
+	now             func() time.Time
+}
+
+// NewInvestmentService creates a new investment service that fills the price store from providers
+func NewInvestmentService(
+	investmentRepo *repository.InvestmentRepository,
+	priceRepo *repository.SecurityPriceRepository,
+	accountRepo *repository.AccountRepository,
+	taxRepo *repository.TaxRepository,
+	ledgerService *LedgerService,
+	currencyService *CurrencyService,
+	db *gorm.DB,
+	providers ...prices.Provider,
+) *InvestmentService {
+	return &InvestmentService{
+		investmentRepo:  investmentRepo,
+		priceRepo:       priceRepo,
+		accountRepo:     accountRepo,
+		taxRepo:         taxRepo,
+		ledgerService:   ledgerService,
+		currencyService: currencyService,
+		db:              db,
+		providers:       providers,
+		now:             time.Now,
+	}
+}
+
+// CreateSecurity adds a security a user can trade
+func (s *InvestmentService) CreateSecurity(userID uint, req *models.SecurityRequest) (*models.Security, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if symbol == "" {
+		return nil, errors.New("symbol is required")
+	}
+	if _, err := s.investmentRepo.GetSecurityBySymbol(symbol, userID); err == nil {
+		return nil, errors.New("security already exists")
+	}
+
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = "USD"
+	}
+	if models.GetCurrencyByCode(currency) == nil {
+		return nil, errors.New("unsupported currency")
+	}
+	securityType := req.Type
+	if securityType == "" {
+		securityType = "stock"
+	}
+
+	security := &models.Security{
+		UserID:   userID,
+		Symbol:   symbol,
+		Name:     req.Name,
+		Type:     securityType,
+		Currency: currency,
+	}
+	if err := s.investmentRepo.CreateSecurity(security); err != nil {
+		return nil, err
+	}
+	return security, nil
+}
+
+// GetSecurities gets the securities of a user
+func (s *InvestmentService) GetSecurities(userID uint) ([]models.Security, error) {
+	return s.investmentRepo.GetSecurities(userID)
+}
+
+// DeleteSecurity deletes a security that has never been traded
+func (s *InvestmentService) DeleteSecurity(id uint, userID uint) error {
+	security, err := s.investmentRepo.GetSecurityByID(id, userID)
+	if err != nil {
+		return errors.New("security not found")
+	}
+
+	count, err := s.investmentRepo.CountTransactions(security.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("security has transactions and cannot be deleted")
+	}
+	return s.investmentRepo.DeleteSecurity(security)
+}
+
+// GetTransactions gets the investment transactions of a user, optionally for a single account
+func (s *InvestmentService) GetTransactions(userID uint, accountID *uint) ([]models.InvestmentTransaction, error) {
+	if accountID != nil {
+		if _, err := s.accountRepo.GetByID(*accountID, userID); err != nil {
+			return nil, errors.New("account not found")
+		}
+	}
+	return s.investmentRepo.GetTransactions(userID, accountID)
+}
+
+// RecordTransaction records a buy, sell, dividend or split of a security in
+// an investment account. The security must be traded in the account currency.
+func (s *InvestmentService) RecordTransaction(userID uint, req *models.InvestmentTransactionRequest) (*models.InvestmentTransaction, error) {
+	account, err := s.accountRepo.GetByID(req.AccountID, userID)
+	if err != nil {
+		return nil, errors.New("account not found")
+	}
+	if account.Type != models.AccountTypeInvestment {
+		return nil, errors.New("account is not an investment account")
+	}
+	security, err := s.investmentRepo.GetSecurityByID(req.SecurityID, userID)
+	if err != nil {
+		return nil, errors.New("security not found")
+	}
+	if security.Currency != account.Currency {
+		return nil, errors.New("security must be traded in the account currency")
+	}
+
+	date := req.Date
+	if date.IsZero() {
+		date = s.now()
+	}
+	if date.After(s.now()) {
+		return nil, errors.New("investment transactions cannot be recorded for a future date")
+	}
+
+	transaction := &models.InvestmentTransaction{
+		UserID:     userID,
+		AccountID:  account.ID,
+		SecurityID: security.ID,
+		Type:       req.Type,
+		Date:       date,
+		Fees:       models.NewMoney(req.Fees, account.Currency),
+		Currency:   account.Currency,
+		Notes:      req.Notes,
+		Security:   security,
+	}
+	if transaction.Fees != 0 && req.Type != models.InvestmentTypeBuy && req.Type != models.InvestmentTypeSell {
+		return nil, errors.New("fees can only be recorded on buys and sells")
+	}
+
+	switch req.Type {
+	case models.InvestmentTypeBuy:
+		err = s.buy(account, security, transaction, req)
+	case models.InvestmentTypeSell:
+		err = s.sell(account, security, transaction, req)
+	case models.InvestmentTypeDividend:
+		err = s.dividend(account, security, transaction, req)
+	case models.InvestmentTypeSplit:
+		err = s.split(security, transaction, req)
+	default:
+		err = errors.New("invalid investment transaction type")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// buy pays for shares from the account cash and opens a lot whose cost
+// basis is the price paid plus fees
+func (s *InvestmentService) buy(account *models.Account, security *models.Security, transaction *models.InvestmentTransaction, req *models.InvestmentTransactionRequest) error {
+	quantity := models.NewShares(req.Quantity)
+	if quantity <= 0 || req.Price <= 0 {
+		return errors.New("quantity and price are required for a buy")
+	}
+	transaction.Quantity = quantity
+	transaction.Price = req.Price
+	transaction.Amount = models.ValueOf(quantity, req.Price, account.Currency)
+
+	cost := transaction.Amount + transaction.Fees
+
+	description := fmt.Sprintf("Buy %s %s", quantity.String(), security.Symbol)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.investmentRepo.WithTx(tx)
+
+		// Check the cash under the account lock so concurrent buys cannot overdraw it
+		account, err := s.lockAccount(tx, account)
+		if err != nil {
+			return err
+		}
+		if account.Balance < cost {
+			return errors.New("insufficient cash in account")
+		}
+
+		err = s.ledgerService.PostInvestment(tx, transaction, description,
+			models.NewAccountPosting(account.UserID, account.ID, -cost, account.Currency),
+			models.NewLedgerPosting(account.UserID, models.HoldingLedgerName(account.ID, security.ID), cost, account.Currency),
+		)
+		if err != nil {
+			return err
+		}
+		if err := repo.CreateTransaction(transaction); err != nil {
+			return err
+		}
+
+		lot := &models.Lot{
+			UserID:           account.UserID,
+			AccountID:        account.ID,
+			SecurityID:       security.ID,
+			BuyTransactionID: transaction.ID,
+			AcquiredAt:       transaction.Date,
+			Quantity:         quantity,
+			Remaining:        quantity,
+			CostBasis:        cost,
+			RemainingCost:    cost,
+			Currency:         account.Currency,
+		}
+		if err := repo.CreateLot(lot); err != nil {
+			return err
+		}
+
+		return s.ledgerService.SyncAccountBalance(tx, account, models.BalanceChange{
+			ChangeType:  models.BalanceChangeTransfer,
+			Description: description,
+		})
+	})
+}
+
+// sell takes shares from the open lots picked by the lot method, credits the
+// proceeds after fees to the account cash and books the gain of each lot
+// under the capital gains category of its holding period
+func (s *InvestmentService) sell(account *models.Account, security *models.Security, transaction *models.InvestmentTransaction, req *models.InvestmentTransactionRequest) error {
+	quantity := models.NewShares(req.Quantity)
+	if quantity <= 0 || req.Price <= 0 {
+		return errors.New("quantity and price are required for a sale")
+	}
+	method := req.LotMethod
+	if method == "" {
+		method = models.LotMethodFIFO
+	}
+
+	transaction.Quantity = -quantity
+	transaction.Price = req.Price
+	transaction.Amount = models.ValueOf(quantity, req.Price, account.Currency)
+	transaction.LotMethod = method
+
+	proceeds := transaction.Amount - transaction.Fees
+	if proceeds < 0 {
+		return errors.New("fees are more than the sale proceeds")
+	}
+
+	description := fmt.Sprintf("Sell %s %s", quantity.String(), security.Symbol)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.investmentRepo.WithTx(tx)
+
+		account, err := s.lockAccount(tx, account)
+		if err != nil {
+			return err
+		}
+		lots, err := repo.GetOpenLots(account.ID, security.ID, transaction.Date)
+		if err != nil {
+			return err
+		}
+		sales, err := models.SelectLots(lots, quantity, method, req.Lots)
+		if err != nil {
+			return err
+		}
+
+		// Split the proceeds over the lots by shares, the last lot taking what rounding leaves
+		gains := make([]*models.RealizedGain, 0, len(sales))
+		var totalCost models.Money
+		gainsByTerm := make(map[string]models.Money)
+		allocated := models.Money(0)
+		for i, sale := range sales {
+			share := proceeds - allocated
+			if i < len(sales)-1 {
+				share = models.Money(float64(proceeds) * float64(sale.Quantity) / float64(quantity))
+			}
+			allocated += share
+
+			cost := sale.Lot.CostOf(sale.Quantity)
+			term := models.GainTerm(sale.Lot.AcquiredAt, transaction.Date)
+			category, err := s.taxRepo.WithTx(tx).FindOrCreateCategory(account.UserID, models.GainCategory(term), models.TaxTypeCapitalGain)
+			if err != nil {
+				return err
+			}
+
+			gains = append(gains, &models.RealizedGain{
+				UserID:        account.UserID,
+				AccountID:     account.ID,
+				SecurityID:    security.ID,
+				LotID:         sale.Lot.ID,
+				Quantity:      sale.Quantity,
+				Proceeds:      share,
+				CostBasis:     cost,
+				Gain:          share - cost,
+				Currency:      account.Currency,
+				AcquiredAt:    sale.Lot.AcquiredAt,
+				SoldAt:        transaction.Date,
+				Term:          term,
+				TaxCategoryID: category.ID,
+			})
+			totalCost += cost
+			gainsByTerm[term] += share - cost
+			transaction.RealizedGain += share - cost
+		}
+
+		postings := []models.Posting{
+			models.NewAccountPosting(account.UserID, account.ID, proceeds, account.Currency),
+			models.NewLedgerPosting(account.UserID, models.HoldingLedgerName(account.ID, security.ID), -totalCost, account.Currency),
+		}
+		for _, term := range []string{models.GainTermShort, models.GainTermLong} {
+			postings = append(postings, models.NewLedgerPosting(account.UserID,
+				models.CategoryLedgerName("income", models.GainCategory(term)), -gainsByTerm[term], account.Currency))
+		}
+		if err := s.ledgerService.PostInvestment(tx, transaction, description, postings...); err != nil {
+			return err
+		}
+		if err := repo.CreateTransaction(transaction); err != nil {
+			return err
+		}
+
+		for i, sale := range sales {
+			lot := sale.Lot
+			updated, err := repo.UpdateLotRemaining(lot, lot.Quantity, lot.Remaining-sale.Quantity, lot.RemainingCost-gains[i].CostBasis)
+			if err != nil {
+				return err
+			}
+			if !updated {
+				return errors.New("holding was changed by another transaction, please try again")
+			}
+
+			gains[i].SellTransactionID = transaction.ID
+			if err := repo.CreateRealizedGain(gains[i]); err != nil {
+				return err
+			}
+		}
+
+		return s.ledgerService.SyncAccountBalance(tx, account, models.BalanceChange{
+			ChangeType:  models.BalanceChangeTransfer,
+			Description: description,
+		})
+	})
+}
+
+// dividend records a cash distribution as income of the account
+func (s *InvestmentService) dividend(account *models.Account, security *models.Security, transaction *models.InvestmentTransaction, req *models.InvestmentTransactionRequest) error {
+	amount := models.NewMoney(req.Amount, account.Currency)
+	if amount <= 0 {
+		return errors.New("amount is required for a dividend")
+	}
+	transaction.Amount = amount
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		account, err := s.lockAccount(tx, account)
+		if err != nil {
+			return err
+		}
+
+		income := &models.Transaction{
+			UserID:      account.UserID,
+			Amount:      amount,
+			Currency:    account.Currency,
+			Description: "Dividend from " + security.Symbol,
+			Category:    models.DividendCategory,
+			Type:        "income",
+			Date:        transaction.Date,
+			AccountID:   account.ID,
+			Status:      models.TransactionStatusPending,
+		}
+		if err := s.ledgerService.PostTransaction(tx, income, models.JournalEntryTypeTransaction); err != nil {
+			return err
+		}
+		if err := tx.Create(income).Error; err != nil {
+			return err
+		}
+		if err := s.ledgerService.SyncAccountBalance(tx, account, models.NewTransactionBalanceChange(income)); err != nil {
+			return err
+		}
+
+		transaction.TransactionID = &income.ID
+		transaction.JournalEntryID = income.JournalEntryID
+		return s.investmentRepo.WithTx(tx).CreateTransaction(transaction)
+	})
+}
+
+// lockAccount locks an account inside tx and reads it again, so its balance
+// is current for the rest of tx
+func (s *InvestmentService) lockAccount(tx *gorm.DB, account *models.Account) (*models.Account, error) {
+	if err := s.ledgerService.LockAccounts(tx, account.ID); err != nil {
+		return nil, err
+	}
+	return s.accountRepo.WithTx(tx).GetByID(account.ID, account.UserID)
+}
+
+// split multiplies the shares of every open lot by the split ratio. Cost
+// basis and acquisition dates are unchanged.
+func (s *InvestmentService) split(security *models.Security, transaction *models.InvestmentTransaction, req *models.InvestmentTransactionRequest) error {
+	if req.SplitRatio <= 0 || req.SplitRatio == 1 {
+		return errors.New("split ratio must be positive and other than 1")
+	}
+	transaction.SplitRatio = req.SplitRatio
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.investmentRepo.WithTx(tx)
+
+		lots, err := repo.GetOpenLots(transaction.AccountID, security.ID, transaction.Date)
+		if err != nil {
+			return err
+		}
+		if len(lots) == 0 {
+			return errors.New("no shares held to split")
+		}
+
+		for i := range lots {
+			lot := &lots[i]
+			quantity := models.Shares(math.Round(float64(lot.Quantity) * req.SplitRatio))
+			remaining := models.Shares(math.Round(float64(lot.Remaining) * req.SplitRatio))
+			updated, err := repo.UpdateLotRemaining(lot, quantity, remaining, lot.RemainingCost)
+			if err != nil {
+				return err
+			}
+			if !updated {
+				return errors.New("holding was changed by another transaction, please try again")
+			}
+			transaction.Quantity += remaining - lot.Remaining
+		}
+
+		return repo.CreateTransaction(transaction)
+	})
+}
+
+// GetHoldings values the open lots of a user, optionally for a single
+// account, at the latest known price. Holdings without a market price are
+// valued at their last trade price. Portfolio totals are in the user's
+// preferred currency.
+func (s *InvestmentService) GetHoldings(userID uint, accountID *uint) (*models.PortfolioResponse, error) {
+	var accounts []models.Account
+	if accountID != nil {
+		account, err := s.accountRepo.GetByID(*accountID, userID)
+		if err != nil {
+			return nil, errors.New("account not found")
+		}
+		accounts = append(accounts, *account)
+	} else {
+		all, err := s.accountRepo.GetAll(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range all {
+			if account.Type == models.AccountTypeInvestment {
+				accounts = append(accounts, account)
+			}
+		}
+	}
+
+	lots, err := s.investmentRepo.GetUserOpenLots(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	securities, err := s.investmentRepo.GetSecurities(userID)
+	if err != nil {
+		return nil, err
+	}
+	securityByID := make(map[uint]*models.Security, len(securities))
+	for i := range securities {
+		securityByID[securities[i].ID] = &securities[i]
+	}
+
+	now := s.now()
+	currency := s.currencyService.PreferredCurrency(userID)
+	response := &models.PortfolioResponse{
+		Accounts: []*models.PortfolioAccountResponse{},
+		Holdings: []*models.HoldingResponse{},
+		Currency: currency,
+	}
+
+	// Group the lots into holdings per account and security
+	type holdingKey struct{ accountID, securityID uint }
+	type holdingTotals struct {
+		quantity    models.Shares
+		cost, value models.Money
+	}
+	holdings := make(map[holdingKey]*models.HoldingResponse)
+	totals := make(map[*models.HoldingResponse]*holdingTotals)
+	marketValues := make(map[uint]models.Money)
+	var totalCost, totalValue models.Money
+	for _, lot := range lots {
+		security, ok := securityByID[lot.SecurityID]
+		if !ok {
+			continue
+		}
+
+		key := holdingKey{lot.AccountID, lot.SecurityID}
+		holding, ok := holdings[key]
+		if !ok {
+			price, err := s.priceOf(security, now)
+			if err != nil {
+				return nil, err
+			}
+			holding = &models.HoldingResponse{
+				AccountID:  lot.AccountID,
+				SecurityID: security.ID,
+				Symbol:     security.Symbol,
+				Name:       security.Name,
+				Currency:   lot.Currency,
+				Lots:       []*models.LotResponse{},
+			}
+			if price != nil {
+				holding.Price = price.Price
+				holding.PriceDate = &price.Date
+				holding.PriceSource = price.Source
+			}
+			holdings[key] = holding
+			totals[holding] = &holdingTotals{}
+			response.Holdings = append(response.Holdings, holding)
+		}
+
+		value := models.ValueOf(lot.Remaining, holding.Price, lot.Currency)
+		holding.Lots = append(holding.Lots, &models.LotResponse{
+			ID:             lot.ID,
+			AcquiredAt:     lot.AcquiredAt,
+			Quantity:       lot.Quantity.Float(),
+			Remaining:      lot.Remaining.Float(),
+			CostBasis:      lot.RemainingCost.Float(lot.Currency),
+			MarketValue:    value.Float(lot.Currency),
+			UnrealizedGain: (value - lot.RemainingCost).Float(lot.Currency),
+			Term:           models.GainTerm(lot.AcquiredAt, now),
+		})
+		totals[holding].quantity += lot.Remaining
+		totals[holding].cost += lot.RemainingCost
+		totals[holding].value += value
+		marketValues[lot.AccountID] += value
+
+		cost, err := models.ConvertMoneyAt(s.currencyService.Rates(), lot.RemainingCost, lot.Currency, currency, now)
+		if err != nil {
+			return nil, err
+		}
+		converted, err := models.ConvertMoneyAt(s.currencyService.Rates(), value, lot.Currency, currency, now)
+		if err != nil {
+			return nil, err
+		}
+		totalCost += cost
+		totalValue += converted
+	}
+	for holding, total := range totals {
+		holding.Quantity = total.quantity.Float()
+		holding.CostBasis = total.cost.Float(holding.Currency)
+		holding.MarketValue = total.value.Float(holding.Currency)
+		holding.UnrealizedGain = (total.value - total.cost).Float(holding.Currency)
+	}
+
+	for _, account := range accounts {
+		value := marketValues[account.ID]
+		response.Accounts = append(response.Accounts, &models.PortfolioAccountResponse{
+			AccountID:   account.ID,
+			Name:        account.Name,
+			Cash:        account.Balance.Float(account.Currency),
+			MarketValue: value.Float(account.Currency),
+			TotalValue:  (account.Balance + value).Float(account.Currency),
+			Currency:    account.Currency,
+		})
+	}
+
+	response.CostBasis = totalCost.Float(currency)
+	response.MarketValue = totalValue.Float(currency)
+	response.UnrealizedGain = (totalValue - totalCost).Float(currency)
+	return response, nil
+}
+
+// priceOf gets the latest market price of a security at or before date,
+// falling back to its last trade price adjusted for later splits. Returns
+// nil when neither is known.
+func (s *InvestmentService) priceOf(security *models.Security, date time.Time) (*models.SecurityPrice, error) {
+	price, err := s.priceRepo.Latest(security.Symbol, security.Currency, date)
+	if err == nil {
+		return price, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	trade, err := s.investmentRepo.GetLastTrade(security.ID, date)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Later splits change what the trade price is per share held today
+	splits, err := s.investmentRepo.GetSplits(trade.AccountID, security.ID, trade.Date)
+	if err != nil {
+		return nil, err
+	}
+	tradePrice := trade.Price
+	for _, split := range splits {
+		tradePrice /= split.SplitRatio
+	}
+	return &models.SecurityPrice{
+		Symbol:   security.Symbol,
+		Currency: security.Currency,
+		Date:     models.RateDate(trade.Date),
+		Price:    tradePrice,
+		Source:   priceSourceTrade,
+	}, nil
+}
+
+// GetRealizedGains gets the gains a user realized by selling shares during a
+// year, with totals per holding period in the user's preferred currency
+func (s *InvestmentService) GetRealizedGains(userID uint, year int) (*models.RealizedGainsResponse, error) {
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(year, 12, 31, 23, 59, 59, 0, time.UTC)
+	gains, err := s.investmentRepo.GetRealizedGains(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := s.currencyService.PreferredCurrency(userID)
+	response := &models.RealizedGainsResponse{
+		Year:     year,
+		Currency: currency,
+		Gains:    make([]*models.RealizedGainResponse, 0, len(gains)),
+	}
+	var shortTerm, longTerm models.Money
+	for i := range gains {
+		gain := &gains[i]
+		converted, err := models.ConvertMoneyAt(s.currencyService.Rates(), gain.Gain, gain.Currency, currency, gain.SoldAt)
+		if err != nil {
+			return nil, err
+		}
+		if gain.Term == models.GainTermLong {
+			longTerm += converted
+		} else {
+			shortTerm += converted
+		}
+		response.Gains = append(response.Gains, gain.ToResponse())
+	}
+
+	response.ShortTerm = shortTerm.Float(currency)
+	response.LongTerm = longTerm.Float(currency)
+	response.Total = (shortTerm + longTerm).Float(currency)
+	return response, nil
+}
+
+// RefreshPrices fetches prices from every configured provider and saves them,
+// returning how many were saved. A failing provider does not stop the others.
+func (s *InvestmentService) RefreshPrices() (int, error) {
+	saved := 0
+	var failures []string
+	for _, provider := range s.providers {
+		ctx, cancel := context.WithTimeout(context.Background(), priceRefreshTimeout)
+		fetched, err := provider.Fetch(ctx)
+		cancel()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", provider.Name(), err))
+			continue
+		}
+
+		if err := s.priceRepo.Upsert(fetched); err != nil {
+			return saved, err
+		}
+		saved += len(fetched)
+	}
+
+	if len(failures) > 0 {
+		return saved, fmt.Errorf("price providers failed: %s", strings.Join(failures, "; "))
+	}
+	return saved, nil
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/prices"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newTestInvestmentService wires an investment service whose clock is fixed at now
+func newTestInvestmentService(db *gorm.DB, now time.Time, providers ...prices.Provider) *InvestmentService {
+	service := NewInvestmentService(repository.NewInvestmentRepository(db), repository.NewSecurityPriceRepository(db),
+		repository.NewAccountRepository(db), repository.NewTaxRepository(db), newTestLedgerService(db), newTestCurrencyService(db), db, providers...)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+// createTestInvestmentAccount creates an investment account holding cash
+func createTestInvestmentAccount(t *testing.T, db *gorm.DB, userID uint, cash float64) *models.Account {
+	account := createTestAccount(t, db, userID, cash)
+	db.Model(account).Update("type", models.AccountTypeInvestment)
+	return account
+}
+
+func TestInvestmentService_LotMethodsAndRealizedGains(t *testing.T) {
+	// Setup - two lots of 10 shares bought a year apart, priced from a file
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestInvestmentAccount(t, db, user.ID, 10000.0)
+
+	priceFile := filepath.Join(t.TempDir(), "prices.csv")
+	err := os.WriteFile(priceFile, []byte("date,symbol,price,currency\n2024-05-30,AAPL,205.00,USD\n2024-05-31,AAPL,210.00,USD\n"), 0o600)
+	assert.NoError(t, err)
+	service := newTestInvestmentService(db, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), prices.NewFileProvider(priceFile))
+
+	security, err := service.CreateSecurity(user.ID, &models.SecurityRequest{Symbol: "aapl", Name: "Apple Inc."})
+	assert.NoError(t, err)
+	assert.Equal(t, "AAPL", security.Symbol)
+
+	record := func(req models.InvestmentTransactionRequest) *models.InvestmentTransaction {
+		req.AccountID = account.ID
+		req.SecurityID = security.ID
+		transaction, err := service.RecordTransaction(user.ID, &req)
+		assert.NoError(t, err)
+		return transaction
+	}
+	record(models.InvestmentTransactionRequest{Type: "buy", Quantity: 10, Price: 100, Fees: 5, Date: time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)})
+	record(models.InvestmentTransactionRequest{Type: "buy", Quantity: 10, Price: 150, Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)})
+
+	// Execute - sell from the oldest lot, the newest lot and then named lots
+	fifo := record(models.InvestmentTransactionRequest{Type: "sell", Quantity: 5, Price: 200, Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)})
+	lifo := record(models.InvestmentTransactionRequest{Type: "sell", Quantity: 5, Price: 200, LotMethod: "lifo", Date: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)})
+
+	var lots []models.Lot
+	db.Order("acquired_at ASC").Find(&lots)
+	specific := record(models.InvestmentTransactionRequest{Type: "sell", Quantity: 3, Price: 120, Fees: 3, LotMethod: "specific",
+		Lots: []models.LotSelection{{LotID: lots[0].ID, Quantity: 2}, {LotID: lots[1].ID, Quantity: 1}},
+		Date: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)})
+
+	// Assert - cost basis includes the buy fee and the gain term follows the holding period
+	assert.Equal(t, models.NewMoney(497.50, "USD"), fifo.RealizedGain)
+	assert.Equal(t, models.NewMoney(250.0, "USD"), lifo.RealizedGain)
+	assert.Equal(t, models.NewMoney(6.0, "USD"), specific.RealizedGain)
+
+	gains, err := service.GetRealizedGains(user.ID, 2024)
+	assert.NoError(t, err)
+	assert.Len(t, gains.Gains, 4)
+	assert.Equal(t, models.GainTermLong, gains.Gains[0].Term)
+	assert.Equal(t, models.GainTermShort, gains.Gains[1].Term)
+	assert.Equal(t, 37.0, gains.Gains[2].Gain)
+	assert.Equal(t, -31.0, gains.Gains[3].Gain)
+	assert.Equal(t, 534.50, gains.LongTerm)
+	assert.Equal(t, 219.0, gains.ShortTerm)
+
+	var updated models.Account
+	db.First(&updated, account.ID)
+	assert.Equal(t, models.NewMoney(9852.0, "USD"), updated.Balance)
+
+	// Selling more than is held fails without touching the lots
+	_, err = service.RecordTransaction(user.ID, &models.InvestmentTransactionRequest{
+		AccountID: account.ID, SecurityID: security.ID, Type: "sell", Quantity: 8, Price: 200,
+	})
+	assert.ErrorIs(t, err, models.ErrInsufficientShares)
+
+	// Holdings are valued at the latest price from the provider
+	saved, err := service.RefreshPrices()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, saved)
+
+	portfolio, err := service.GetHoldings(user.ID, nil)
+	assert.NoError(t, err)
+	assert.Len(t, portfolio.Holdings, 1)
+	holding := portfolio.Holdings[0]
+	assert.Equal(t, 7.0, holding.Quantity)
+	assert.Equal(t, 901.50, holding.CostBasis)
+	assert.Equal(t, 210.0, holding.Price)
+	assert.Equal(t, "file:prices.csv", holding.PriceSource)
+	assert.Equal(t, 1470.0, holding.MarketValue)
+	assert.Equal(t, 568.50, holding.UnrealizedGain)
+	assert.Len(t, holding.Lots, 2)
+	assert.Equal(t, 11322.0, portfolio.Accounts[0].TotalValue)
+
+	// Realized gains are reported as capital gains for tax
+	report, err := repository.NewTaxRepository(db).GetTaxReport(user.ID, 2024, models.StaticExchangeRates{})
+	assert.NoError(t, err)
+	assert.Equal(t, 753.50, report.CapitalGains)
+	assert.Len(t, report.ByCategory, 2)
+	assert.Equal(t, models.LongTermGainsCategory, report.ByCategory[0].CategoryName)
+	assert.Equal(t, models.TaxTypeCapitalGain, report.ByCategory[0].TaxType)
+
+	integrity, err := service.ledgerService.CheckIntegrity(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, integrity.Balanced)
+}
+
+func TestInvestmentService_SplitAndDividend(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestInvestmentAccount(t, db, user.ID, 2000.0)
+	checking := createTestAccount(t, db, user.ID, 100.0)
+
+	service := newTestInvestmentService(db, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	security, err := service.CreateSecurity(user.ID, &models.SecurityRequest{Symbol: "VTI", Name: "Total Market ETF", Type: "etf"})
+	assert.NoError(t, err)
+	_, err = service.CreateSecurity(user.ID, &models.SecurityRequest{Symbol: "VTI", Name: "Duplicate"})
+	assert.EqualError(t, err, "security already exists")
+
+	// Investment transactions need an investment account
+	_, err = service.RecordTransaction(user.ID, &models.InvestmentTransactionRequest{
+		AccountID: checking.ID, SecurityID: security.ID, Type: "buy", Quantity: 1, Price: 10,
+	})
+	assert.EqualError(t, err, "account is not an investment account")
+
+	_, err = service.RecordTransaction(user.ID, &models.InvestmentTransactionRequest{
+		AccountID: account.ID, SecurityID: security.ID, Type: "buy", Quantity: 10, Price: 100,
+		Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+
+	// Execute - a 2-for-1 split and a cash dividend
+	split, err := service.RecordTransaction(user.ID, &models.InvestmentTransactionRequest{
+		AccountID: account.ID, SecurityID: security.ID, Type: "split", SplitRatio: 2,
+		Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+	dividend, err := service.RecordTransaction(user.ID, &models.InvestmentTransactionRequest{
+		AccountID: account.ID, SecurityID: security.ID, Type: "dividend", Amount: 25,
+		Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+
+	// Assert - the split doubles the shares but keeps the cost
+	assert.Equal(t, models.NewShares(10), split.Quantity)
+	var lot models.Lot
+	db.First(&lot)
+	assert.Equal(t, models.NewShares(20), lot.Remaining)
+	assert.Equal(t, models.NewMoney(1000.0, "USD"), lot.RemainingCost)
+
+	// The dividend is income of the account
+	var income models.Transaction
+	db.First(&income, *dividend.TransactionID)
+	assert.Equal(t, "income", income.Type)
+	assert.Equal(t, models.DividendCategory, income.Category)
+	assert.Equal(t, models.NewMoney(25.0, "USD"), income.Amount)
+
+	// Without a market price holdings are valued at the last trade price per share
+	portfolio, err := service.GetHoldings(user.ID, &account.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "trade", portfolio.Holdings[0].PriceSource)
+	assert.Equal(t, 20.0, portfolio.Holdings[0].Quantity)
+	assert.Equal(t, 50.0, portfolio.Holdings[0].Price)
+	assert.Equal(t, 1000.0, portfolio.Holdings[0].MarketValue)
+
+	sale, err := service.RecordTransaction(user.ID, &models.InvestmentTransactionRequest{
+		AccountID: account.ID, SecurityID: security.ID, Type: "sell", Quantity: 20, Price: 60,
+		Date: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(200.0, "USD"), sale.RealizedGain)
+
+	var updated models.Account
+	db.First(&updated, account.ID)
+	assert.Equal(t, models.NewMoney(2225.0, "USD"), updated.Balance)
+
+	integrity, err := service.ledgerService.CheckIntegrity(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, integrity.Balanced)
+}
+
+func TestInvestmentService_ConcurrentBuys(t *testing.T) {
+	// Setup - cash for three of the buys below
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestInvestmentAccount(t, db, user.ID, 3000.0)
+
+	service := newTestInvestmentService(db, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	security, err := service.CreateSecurity(user.ID, &models.SecurityRequest{Symbol: "VTI", Name: "Total Market"})
+	assert.NoError(t, err)
+
+	// Execute - ten buys of 1000 at once
+	const buys = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, buys)
+	for i := 0; i < buys; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.RecordTransaction(user.ID, &models.InvestmentTransactionRequest{
+				AccountID: account.ID, SecurityID: security.ID, Type: "buy", Quantity: 4, Price: 250,
+				Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	// Assert - only the buys the cash covers go through
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.EqualError(t, err, "insufficient cash in account")
+		}
+	}
+	assert.Equal(t, 3, succeeded)
+
+	var updated models.Account
+	db.First(&updated, account.ID)
+	assert.Equal(t, models.Money(0), updated.Balance)
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// LedgerService handles business logic for the double-entry ledger
+type LedgerService struct {
+	ledgerRepo         *repository.LedgerRepository
+	accountRepo        *repository.AccountRepository
+	balanceHistoryRepo *repository.BalanceHistoryRepository
+}
+
+// NewLedgerService creates a new ledger service
+func NewLedgerService(
+	ledgerRepo *repository.LedgerRepository,
+	accountRepo *repository.AccountRepository,
+	balanceHistoryRepo *repository.BalanceHistoryRepository,
+) *LedgerService {
+	return &LedgerService{
+		ledgerRepo:         ledgerRepo,
+		accountRepo:        accountRepo,
+		balanceHistoryRepo: balanceHistoryRepo,
+	}
+}
+
+// PostTransaction records the journal entry for a transaction inside tx.
+// It must be called before the transaction row is saved.
+func (s *LedgerService) PostTransaction(tx *gorm.DB, t *models.Transaction, entryType string) error {
+	return s.ledgerRepo.WithTx(tx).PostTransaction(t, entryType)
+}
+
+// PostTransfer records a single balanced entry for both legs of a transfer
+// and links the two transactions to it. Legs in different currencies are
+// each balanced against currency conversion equity.
+func (s *LedgerService) PostTransfer(tx *gorm.DB, from, to *models.Transaction) error {
+	entry := &models.JournalEntry{
+		UserID:      from.UserID,
+		Type:        models.JournalEntryTypeTransfer,
+		Description: from.Description,
+		Date:        from.Date,
+		Postings: []models.Posting{
+			models.NewAccountPosting(from.UserID, from.AccountID, -from.Amount, from.Currency),
+			models.NewAccountPosting(to.UserID, to.AccountID, to.Amount, to.Currency),
+		},
+	}
+	if from.Currency != to.Currency {
+		entry.Postings = append(entry.Postings,
+			models.NewLedgerPosting(from.UserID, models.LedgerEquityConversions, from.Amount, from.Currency),
+			models.NewLedgerPosting(to.UserID, models.LedgerEquityConversions, -to.Amount, to.Currency),
+		)
+	}
+
+	if err := s.ledgerRepo.WithTx(tx).CreateEntry(entry); err != nil {
+		return err
+	}
+
+	from.JournalEntryID = &entry.ID
+	to.JournalEntryID = &entry.ID
+	return nil
+}
+
+// PostOpeningBalance records the starting balance of a new account against opening balance equity
+func (s *LedgerService) PostOpeningBalance(tx *gorm.DB, account *models.Account, balance models.Money) error {
+	if balance == 0 {
+		return nil
+	}
+	return s.postAccountEquity(tx, account, balance, models.JournalEntryTypeOpeningBalance,
+		models.LedgerEquityOpeningBalances, "Opening balance")
+}
+
+// PostAdjustment records a manual change of an account balance against adjustment equity
+func (s *LedgerService) PostAdjustment(tx *gorm.DB, account *models.Account, delta models.Money, description string) error {
+	if delta == 0 {
+		return nil
+	}
+	return s.postAccountEquity(tx, account, delta, models.JournalEntryTypeAdjustment,
+		models.LedgerEquityAdjustments, description)
+}
+
+// PostBalanceCorrection records a correction that brings the postings of an
+// account back in line with its transactions, against correction equity
+func (s *LedgerService) PostBalanceCorrection(tx *gorm.DB, account *models.Account, delta models.Money) error {
+	if delta == 0 {
+		return nil
+	}
+	return s.postAccountEquity(tx, account, delta, models.JournalEntryTypeCorrection,
+		models.LedgerEquityCorrections, "Balance correction")
+}
+
+// postAccountEquity posts amount to an account with the opposite leg on an equity ledger account
+func (s *LedgerService) postAccountEquity(tx *gorm.DB, account *models.Account, amount models.Money, entryType, equity, description string) error {
+	entry := &models.JournalEntry{
+		UserID:      account.UserID,
+		Type:        entryType,
+		Description: description,
+		Date:        time.Now(),
+		Postings: []models.Posting{
+			models.NewAccountPosting(account.UserID, account.ID, amount, account.Currency),
+			models.NewLedgerPosting(account.UserID, equity, -amount, account.Currency),
+		},
+	}
+	return s.ledgerRepo.WithTx(tx).CreateEntry(entry)
+}
+
+// PostGoalContribution records money allocated to (or withdrawn from) a goal
+func (s *LedgerService) PostGoalContribution(tx *gorm.DB, goal *models.Goal, amount models.Money, description string) error {
+	if amount == 0 {
+		return nil
+	}
+
+	if description == "" {
+		description = "Contribution to " + goal.Name
+	}
+
+	entry := &models.JournalEntry{
+		UserID:      goal.UserID,
+		Type:        models.JournalEntryTypeGoalContribution,
+		Description: description,
+		Date:        time.Now(),
+		GoalID:      &goal.ID,
+		Postings: []models.Posting{
+			models.NewLedgerPosting(goal.UserID, models.GoalLedgerName(goal.ID), amount, goal.Currency),
+			models.NewLedgerPosting(goal.UserID, models.LedgerEquityGoalAllocations, -amount, goal.Currency),
+		},
+	}
+	return s.ledgerRepo.WithTx(tx).CreateEntry(entry)
+}
+
+// PostInvestment records the journal entry of an investment buy or sell and
+// links the investment transaction to it. Zero postings are left out.
+func (s *LedgerService) PostInvestment(tx *gorm.DB, t *models.InvestmentTransaction, description string, postings ...models.Posting) error {
+	entry := &models.JournalEntry{
+		UserID:      t.UserID,
+		Type:        models.JournalEntryTypeInvestment,
+		Description: description,
+		Date:        t.Date,
+	}
+	for _, posting := range postings {
+		if posting.Amount != 0 {
+			entry.Postings = append(entry.Postings, posting)
+		}
+	}
+
+	if err := s.ledgerRepo.WithTx(tx).CreateEntry(entry); err != nil {
+		return err
+	}
+
+	t.JournalEntryID = &entry.ID
+	return nil
+}
+
+// ReverseTransaction removes the ledger effect of a transaction inside tx.
+// Journaled transactions have their entry deleted; transactions recorded
+// before the ledger existed get a reversing adjustment instead.
+func (s *LedgerService) ReverseTransaction(tx *gorm.DB, t *models.Transaction) error {
+	repo := s.ledgerRepo.WithTx(tx)
+
+	if t.JournalEntryID != nil {
+		return repo.DeleteEntry(*t.JournalEntryID, t.UserID)
+	}
+
+	entry, err := s.legacyEntry(tx, t, models.JournalEntryTypeAdjustment)
+	if err != nil {
+		return err
+	}
+	entry.Description = fmt.Sprintf("Reversal of transaction #%d", t.ID)
+	entry.Date = time.Now()
+	for i := range entry.Postings {
+		entry.Postings[i].Amount = -entry.Postings[i].Amount
+	}
+	return repo.CreateEntry(entry)
+}
+
+// PostTransferLeg records the journal entry of a transfer leg whose other
+// leg is not posted with it, such as a restored transfer recorded before
+// the ledger existed, and links the transaction to it
+func (s *LedgerService) PostTransferLeg(tx *gorm.DB, t *models.Transaction) error {
+	entry, err := s.legacyEntry(tx, t, models.JournalEntryTypeTransfer)
+	if err != nil {
+		return err
+	}
+	if err := s.ledgerRepo.WithTx(tx).CreateEntry(entry); err != nil {
+		return err
+	}
+
+	t.JournalEntryID = &entry.ID
+	return nil
+}
+
+// legacyEntry builds the journal entry of a transaction that has no entry of
+// its own. A transfer leg moves money in or out of its account depending on
+// which side of the transfer it is.
+func (s *LedgerService) legacyEntry(tx *gorm.DB, t *models.Transaction, entryType string) (*models.JournalEntry, error) {
+	if t.Type != "transfer" {
+		return models.NewTransactionJournalEntry(t, entryType), nil
+	}
+
+	outgoing, err := repository.NewTransactionRepository(tx).IsOutgoingLegacyTransfer(t)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewTransferLegJournalEntry(t, outgoing, entryType), nil
+}
+
+// GetEntryType returns the type of the journal entry backing a transaction
+func (s *LedgerService) GetEntryType(tx *gorm.DB, t *models.Transaction) (string, error) {
+	if t.JournalEntryID == nil {
+		return "", nil
+	}
+
+	entry, err := s.ledgerRepo.WithTx(tx).GetEntryByID(*t.JournalEntryID, t.UserID)
+	if err != nil {
+		return "", err
+	}
+	return entry.Type, nil
+}
+
+// LockAccounts locks the accounts a DB transaction is about to change. Call
+// it before reading balances or posting, and lock every account up front
+// when more than one is involved.
+func (s *LedgerService) LockAccounts(tx *gorm.DB, accountIDs ...uint) error {
+	return s.ledgerRepo.WithTx(tx).LockAccounts(accountIDs...)
+}
+
+// SyncAccountBalance derives the stored balance of an account from its
+// postings and records the change in balance history inside tx
+func (s *LedgerService) SyncAccountBalance(tx *gorm.DB, account *models.Account, change models.BalanceChange) error {
+	delta, err := s.ledgerRepo.WithTx(tx).SyncAccountBalance(account)
+	if err != nil {
+		return err
+	}
+	return s.recordBalanceChange(tx, account, delta, change)
+}
+
+// SyncAccountBalanceByID derives the stored balance of an account by ID and
+// records the change in balance history inside tx
+func (s *LedgerService) SyncAccountBalanceByID(tx *gorm.DB, accountID uint, change models.BalanceChange) error {
+	account, delta, err := s.ledgerRepo.WithTx(tx).SyncAccountBalanceByID(accountID)
+	if err != nil {
+		return err
+	}
+	return s.recordBalanceChange(tx, account, delta, change)
+}
+
+// recordBalanceChange writes a balance history row when the balance actually moved
+func (s *LedgerService) recordBalanceChange(tx *gorm.DB, account *models.Account, delta models.Money, change models.BalanceChange) error {
+	if delta == 0 {
+		return nil
+	}
+	return s.balanceHistoryRepo.WithTx(tx).RecordBalanceChange(
+		account.UserID,
+		account.ID,
+		account.Balance,
+		delta,
+		change.ChangeType,
+		change.TransactionID,
+		change.Description,
+	)
+}
+
+// GetEntries gets recent journal entries, optionally for a single account
+func (s *LedgerService) GetEntries(userID uint, accountID *uint, limit int) ([]models.JournalEntry, error) {
+	if accountID != nil {
+		if _, err := s.accountRepo.GetByID(*accountID, userID); err != nil {
+			return nil, errors.New("account not found")
+		}
+	}
+	return s.ledgerRepo.GetEntries(userID, accountID, limit)
+}
+
+// GetEntryByID gets a single journal entry
+func (s *LedgerService) GetEntryByID(id uint, userID uint) (*models.JournalEntry, error) {
+	return s.ledgerRepo.GetEntryByID(id, userID)
+}
+
+// CheckIntegrity verifies that a user's debits equal credits in every
+// currency, that every entry balances and that every stored account balance
+// matches the sum of its postings
+func (s *LedgerService) CheckIntegrity(userID uint) (*models.LedgerIntegrityReport, error) {
+	report := &models.LedgerIntegrityReport{
+		Balanced:          true,
+		Currencies:        []models.LedgerCurrencyTotals{},
+		UnbalancedEntries: []uint{},
+		AccountMismatches: []models.LedgerAccountMismatch{},
+		CheckedAt:         time.Now(),
+	}
+
+	// Count entries
+	count, err := s.ledgerRepo.CountEntries(userID)
+	if err != nil {
+		return nil, err
+	}
+	report.EntryCount = count
+
+	// Compare debits and credits per currency
+	totals, err := s.ledgerRepo.GetCurrencyTotals(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range totals {
+		difference := total.Debits - total.Credits
+		if difference != 0 {
+			report.Balanced = false
+		}
+		report.Currencies = append(report.Currencies, models.LedgerCurrencyTotals{
+			Currency:   total.Currency,
+			Debits:     total.Debits.Float(total.Currency),
+			Credits:    total.Credits.Float(total.Currency),
+			Difference: difference.Float(total.Currency),
+		})
+	}
+
+	// Find individual entries that do not balance
+	unbalanced, err := s.ledgerRepo.GetUnbalancedEntryIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(unbalanced) > 0 {
+		report.Balanced = false
+		sort.Slice(unbalanced, func(i, j int) bool { return unbalanced[i] < unbalanced[j] })
+		report.UnbalancedEntries = unbalanced
+	}
+
+	// Compare stored account balances with their postings
+	posted, err := s.ledgerRepo.GetPostedBalances(userID)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.accountRepo.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		if account.Balance == posted[account.ID] {
+			continue
+		}
+		report.Balanced = false
+		report.AccountMismatches = append(report.AccountMismatches, models.LedgerAccountMismatch{
+			AccountID:     account.ID,
+			AccountName:   account.Name,
+			Currency:      account.Currency,
+			StoredBalance: account.Balance.Float(account.Currency),
+			PostedBalance: posted[account.ID].Float(account.Currency),
+		})
+	}
+
+	return report, nil
+}
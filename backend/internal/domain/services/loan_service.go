@@ -0,0 +1,446 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// LoanService handles loan terms, amortization schedules and loan payments
+type LoanService struct {
+	loanRepo      *repository.LoanRepository
+	accountRepo   *repository.AccountRepository
+	ledgerService *LedgerService
+	db            *gorm.DB
+	now           func() time.Time
+}
+
+// NewLoanService creates a new loan service
+func NewLoanService(
+	loanRepo *repository.LoanRepository,
+	accountRepo *repository.AccountRepository,
+	ledgerService *LedgerService,
+	db *gorm.DB,
+) *LoanService {
+	return &LoanService{
+		loanRepo:      loanRepo,
+		accountRepo:   accountRepo,
+		ledgerService: ledgerService,
+		db:            db,
+		now:           time.Now,
+	}
+}
+
+// Get gets the terms and remaining balance of a loan account
+func (s *LoanService) Get(accountID uint, userID uint) (*models.LoanResponse, error) {
+	account, loan, err := s.getLoan(accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return loan.ToResponse(account), nil
+}
+
+// Save sets the terms of a loan account. The monthly payment is worked out
+// from the principal, rate and term; payments are posted from the first
+// payment date onwards.
+func (s *LoanService) Save(accountID uint, userID uint, req *models.LoanRequest) (*models.LoanResponse, error) {
+	// Check if account exists and belongs to user
+	account, err := s.accountRepo.GetByID(accountID, userID)
+	if err != nil {
+		return nil, errors.New("account not found")
+	}
+	if account.Type != models.AccountTypeLoan {
+		return nil, errors.New("account is not a loan account")
+	}
+
+	// Payments come out of another account in the same currency
+	if req.PaymentAccountID == account.ID {
+		return nil, errors.New("a loan cannot be paid from its own account")
+	}
+	paymentAccount, err := s.accountRepo.GetByID(req.PaymentAccountID, userID)
+	if err != nil {
+		return nil, errors.New("payment account not found")
+	}
+	if paymentAccount.Currency != account.Currency {
+		return nil, errors.New("payment account must use the loan currency")
+	}
+
+	loan, err := s.loanRepo.GetByAccountID(account.ID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		loan = &models.Loan{UserID: userID, AccountID: account.ID}
+	} else if err != nil {
+		return nil, err
+	}
+
+	loan.PaymentAccountID = paymentAccount.ID
+	loan.Principal = models.NewMoney(req.Principal, account.Currency)
+	loan.InterestRate = req.InterestRate
+	loan.TermMonths = req.TermMonths
+	loan.FirstPaymentDate = req.FirstPaymentDate
+	loan.MonthlyPayment = models.LoanPaymentAmount(loan.Principal, loan.InterestRate, loan.TermMonths)
+	loan.ExtraPayment = models.NewMoney(req.ExtraPayment, account.Currency)
+	loan.PrincipalCategory = req.PrincipalCategory
+	if loan.PrincipalCategory == "" {
+		loan.PrincipalCategory = models.DefaultLoanPrincipalCategory
+	}
+	loan.InterestCategory = req.InterestCategory
+	if loan.InterestCategory == "" {
+		loan.InterestCategory = models.DefaultLoanInterestCategory
+	}
+	loan.NextPaymentDate = loan.PaymentDate(loan.PaymentsMade)
+
+	// The payments must be able to pay off what is owed
+	owed := models.Owed(account.Balance)
+	loan.Status = models.LoanStatusActive
+	if owed == 0 {
+		loan.Status = models.LoanStatusPaidOff
+	}
+	if _, err := loan.Amortize(owed, loan.PaymentsMade, loan.ExtraPayment); err != nil {
+		return nil, err
+	}
+
+	if err := s.loanRepo.Save(loan); err != nil {
+		return nil, err
+	}
+	return loan.ToResponse(account), nil
+}
+
+// Delete removes the terms and payment records of a loan account; the
+// account and its transactions are kept
+func (s *LoanService) Delete(accountID uint, userID uint) error {
+	_, loan, err := s.getLoan(accountID, userID)
+	if err != nil {
+		return err
+	}
+	return s.loanRepo.Delete(loan)
+}
+
+// GetSchedule gets the remaining amortization schedule of a loan account
+func (s *LoanService) GetSchedule(accountID uint, userID uint) (*models.AmortizationScheduleResponse, error) {
+	account, loan, err := s.getLoan(accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	owed := models.Owed(account.Balance)
+	schedule, err := loan.Amortize(owed, loan.PaymentsMade, loan.ExtraPayment)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.ToResponse(loan, owed, account.Currency), nil
+}
+
+// GetPayments gets the payments posted for a loan account, most recent first
+func (s *LoanService) GetPayments(accountID uint, userID uint) ([]models.LoanPayment, error) {
+	_, loan, err := s.getLoan(accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.loanRepo.GetPayments(loan.ID)
+}
+
+// CompareScenarios works out the payoff of a loan under different extra
+// payments and compares each with the current plan
+func (s *LoanService) CompareScenarios(accountID uint, userID uint, req *models.LoanScenariosRequest) (*models.LoanScenariosResponse, error) {
+	account, loan, err := s.getLoan(accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	owed := models.Owed(account.Balance)
+	current, err := loan.Amortize(owed, loan.PaymentsMade, loan.ExtraPayment)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.LoanScenariosResponse{
+		Currency:  account.Currency,
+		Current:   scenarioResult(current, current, owed, loan.ExtraPayment, 0, account.Currency),
+		Scenarios: make([]*models.LoanScenarioResult, 0, len(req.Scenarios)),
+	}
+	for _, scenario := range req.Scenarios {
+		extra := models.NewMoney(scenario.ExtraPayment, account.Currency)
+		lumpSum := models.NewMoney(scenario.LumpSum, account.Currency)
+		if lumpSum > owed {
+			lumpSum = owed
+		}
+
+		schedule, err := loan.Amortize(owed-lumpSum, loan.PaymentsMade, extra)
+		if err != nil {
+			return nil, err
+		}
+		response.Scenarios = append(response.Scenarios, scenarioResult(schedule, current, owed-lumpSum, extra, lumpSum, account.Currency))
+	}
+	return response, nil
+}
+
+// scenarioResult summarizes a schedule against the current one
+func scenarioResult(schedule, current *models.AmortizationSchedule, owed, extra, lumpSum models.Money, currency string) *models.LoanScenarioResult {
+	return &models.LoanScenarioResult{
+		ExtraPayment:     extra.Float(currency),
+		LumpSum:          lumpSum.Float(currency),
+		RemainingBalance: owed.Float(currency),
+		TotalInterest:    schedule.TotalInterest.Float(currency),
+		PayoffDate:       schedule.PayoffDate(),
+		Payments:         len(schedule.Rows),
+		InterestSaved:    (current.TotalInterest - schedule.TotalInterest).Float(currency),
+		MonthsSaved:      len(current.Rows) - len(schedule.Rows),
+	}
+}
+
+// PayExtra posts a one-off payment towards the principal of a loan
+func (s *LoanService) PayExtra(accountID uint, userID uint, req *models.LoanExtraPaymentRequest) (*models.LoanPayment, error) {
+	account, loan, err := s.getLoan(accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	date := req.Date
+	if date.IsZero() {
+		date = s.now()
+	}
+	if date.After(s.now()) {
+		return nil, errors.New("extra payments cannot be made for a future date")
+	}
+
+	paymentAccount, err := s.accountRepo.GetByID(loan.PaymentAccountID, userID)
+	if err != nil {
+		return nil, errors.New("payment account not found")
+	}
+
+	amount := models.NewMoney(req.Amount, account.Currency)
+	return s.postPayment(loan, account, paymentAccount, 0, date, amount, 0)
+}
+
+// PostDuePayments posts every loan payment whose date has arrived and
+// returns how many were posted. Missed payments are caught up in order.
+func (s *LoanService) PostDuePayments() (int, error) {
+	now := s.now()
+	loans, err := s.loanRepo.GetDue(now)
+	if err != nil {
+		return 0, err
+	}
+
+	posted := 0
+	var failures []string
+	for i := range loans {
+		loan := &loans[i]
+		count, err := s.postDue(loan, now)
+		posted += count
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("loan %d: %v", loan.ID, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return posted, fmt.Errorf("loan payments failed: %s", strings.Join(failures, "; "))
+	}
+	return posted, nil
+}
+
+// postDue posts the payments of a loan that are due by now
+func (s *LoanService) postDue(loan *models.Loan, now time.Time) (int, error) {
+	account, err := s.accountRepo.GetByID(loan.AccountID, loan.UserID)
+	if err != nil {
+		return 0, err
+	}
+	paymentAccount, err := s.accountRepo.GetByID(loan.PaymentAccountID, loan.UserID)
+	if err != nil {
+		return 0, errors.New("payment account not found")
+	}
+
+	posted := 0
+	for loan.Status == models.LoanStatusActive && !loan.NextPaymentDate.After(now) {
+		owed := models.Owed(account.Balance)
+		interest := loan.InterestOn(owed)
+		principal := loan.MonthlyPayment + loan.ExtraPayment - interest
+		if principal > owed {
+			principal = owed
+		}
+		if principal < 0 {
+			principal = 0
+		}
+
+		if _, err := s.postPayment(loan, account, paymentAccount, loan.PaymentsMade+1, loan.NextPaymentDate, principal, interest); err != nil {
+			return posted, err
+		}
+		posted++
+	}
+	return posted, nil
+}
+
+// postPayment moves the principal part of a payment from the payment account
+// to the loan account and books the interest part as an expense, all in one
+// database transaction. Scheduled payments have a number and advance the
+// loan to its next payment date.
+func (s *LoanService) postPayment(loan *models.Loan, account, paymentAccount *models.Account, number int, date time.Time, principal, interest models.Money) (*models.LoanPayment, error) {
+	payment := &models.LoanPayment{
+		LoanID:    loan.ID,
+		UserID:    loan.UserID,
+		Number:    number,
+		Date:      date,
+		Principal: principal,
+		Interest:  interest,
+		Currency:  account.Currency,
+	}
+
+	description := fmt.Sprintf("%s payment %d", account.Name, number)
+	if number == 0 {
+		description = fmt.Sprintf("%s extra payment", account.Name)
+	}
+
+	updated := *loan
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock both accounts up front in ID order
+		if err := s.ledgerService.LockAccounts(tx, paymentAccount.ID, account.ID); err != nil {
+			return err
+		}
+
+		// Extra payments are checked against the balances read under the lock
+		if number == 0 {
+			if err := s.checkExtraPayment(tx, account, paymentAccount, principal); err != nil {
+				return err
+			}
+		}
+
+		if principal > 0 {
+			from := &models.Transaction{
+				UserID:      loan.UserID,
+				Amount:      principal,
+				Currency:    account.Currency,
+				Description: description,
+				Category:    loan.PrincipalCategory,
+				Type:        "transfer",
+				Date:        date,
+				AccountID:   paymentAccount.ID,
+				Status:      models.TransactionStatusPending,
+			}
+			to := &models.Transaction{
+				UserID:      loan.UserID,
+				Amount:      principal,
+				Currency:    account.Currency,
+				Description: description,
+				Category:    loan.PrincipalCategory,
+				Type:        "transfer",
+				Date:        date,
+				AccountID:   account.ID,
+				Status:      models.TransactionStatusPending,
+			}
+			if err := s.ledgerService.PostTransfer(tx, from, to); err != nil {
+				return err
+			}
+			if err := tx.Create(from).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(to).Error; err != nil {
+				return err
+			}
+			if err := s.ledgerService.SyncAccountBalance(tx, paymentAccount, models.NewTransactionBalanceChange(from)); err != nil {
+				return err
+			}
+			if err := s.ledgerService.SyncAccountBalance(tx, account, models.NewTransactionBalanceChange(to)); err != nil {
+				return err
+			}
+			payment.PrincipalTransactionID = &from.ID
+		}
+
+		if interest > 0 {
+			interestTransaction := &models.Transaction{
+				UserID:      loan.UserID,
+				Amount:      interest,
+				Currency:    account.Currency,
+				Description: description,
+				Category:    loan.InterestCategory,
+				Type:        "expense",
+				Date:        date,
+				AccountID:   paymentAccount.ID,
+				Status:      models.TransactionStatusPending,
+			}
+			if err := s.ledgerService.PostTransaction(tx, interestTransaction, models.JournalEntryTypeTransaction); err != nil {
+				return err
+			}
+			if err := tx.Create(interestTransaction).Error; err != nil {
+				return err
+			}
+			if err := s.ledgerService.SyncAccountBalance(tx, paymentAccount, models.NewTransactionBalanceChange(interestTransaction)); err != nil {
+				return err
+			}
+			payment.InterestTransactionID = &interestTransaction.ID
+		}
+
+		payment.Balance = models.Owed(account.Balance)
+		if err := s.loanRepo.WithTx(tx).CreatePayment(payment); err != nil {
+			return err
+		}
+
+		// Advance the schedule, claiming the payment so it is only posted once
+		if number > 0 {
+			updated.PaymentsMade = number
+			updated.NextPaymentDate = loan.PaymentDate(number)
+		}
+		if payment.Balance == 0 {
+			updated.Status = models.LoanStatusPaidOff
+		}
+		result := tx.Model(&models.Loan{}).
+			Where("id = ? AND payments_made = ?", loan.ID, loan.PaymentsMade).
+			Updates(map[string]interface{}{
+				"payments_made":     updated.PaymentsMade,
+				"next_payment_date": updated.NextPaymentDate,
+				"status":            updated.Status,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("loan payment has already been posted")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	*loan = updated
+	return payment, nil
+}
+
+// checkExtraPayment makes sure an extra payment neither overpays the loan
+// nor overdraws the payment account. Both accounts must be locked in tx.
+func (s *LoanService) checkExtraPayment(tx *gorm.DB, account, paymentAccount *models.Account, amount models.Money) error {
+	accountRepo := s.accountRepo.WithTx(tx)
+	current, err := accountRepo.GetByID(account.ID, account.UserID)
+	if err != nil {
+		return errors.New("account not found")
+	}
+	if amount > models.Owed(current.Balance) {
+		return errors.New("extra payment is more than the remaining balance")
+	}
+
+	payer, err := accountRepo.GetByID(paymentAccount.ID, paymentAccount.UserID)
+	if err != nil {
+		return errors.New("payment account not found")
+	}
+	if payer.Balance < amount {
+		return errors.New("insufficient balance in payment account")
+	}
+	return nil
+}
+
+// getLoan gets a loan account of a user together with its loan terms
+func (s *LoanService) getLoan(accountID uint, userID uint) (*models.Account, *models.Loan, error) {
+	account, err := s.accountRepo.GetByID(accountID, userID)
+	if err != nil {
+		return nil, nil, errors.New("account not found")
+	}
+
+	loan, err := s.loanRepo.GetByAccountID(account.ID, userID)
+	if err != nil {
+		return nil, nil, errors.New("loan not found")
+	}
+	return account, loan, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newTestLoanService wires a loan service whose clock is fixed at now
+func newTestLoanService(db *gorm.DB, now time.Time) *LoanService {
+	service := NewLoanService(repository.NewLoanRepository(db), repository.NewAccountRepository(db), newTestLedgerService(db), db)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestLoanService_PostDuePayments(t *testing.T) {
+	// Setup - 12,000 borrowed at 6% over a year, paid from checking
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	checking := createTestAccount(t, db, user.ID, 5000.0)
+	loanAccount := createTestAccount(t, db, user.ID, -12000.0)
+	db.Model(loanAccount).Update("type", models.AccountTypeLoan)
+
+	service := newTestLoanService(db, time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC))
+	loan, err := service.Save(loanAccount.ID, user.ID, &models.LoanRequest{
+		PaymentAccountID: checking.ID,
+		Principal:        12000.0,
+		InterestRate:     6,
+		TermMonths:       12,
+		FirstPaymentDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1032.80, loan.MonthlyPayment)
+
+	// Execute - both payments due so far are posted
+	posted, err := service.PostDuePayments()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 2, posted)
+
+	payments, err := service.GetPayments(loanAccount.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, payments, 2)
+	assert.Equal(t, models.NewMoney(55.14, "USD"), payments[0].Interest)
+	assert.Equal(t, models.NewMoney(977.66, "USD"), payments[0].Principal)
+	assert.Equal(t, models.NewMoney(60.0, "USD"), payments[1].Interest)
+	assert.Equal(t, models.NewMoney(972.80, "USD"), payments[1].Principal)
+
+	var updatedChecking, updatedLoan models.Account
+	db.First(&updatedChecking, checking.ID)
+	db.First(&updatedLoan, loanAccount.ID)
+	assert.Equal(t, models.NewMoney(2934.40, "USD"), updatedChecking.Balance)
+	assert.Equal(t, models.NewMoney(-10049.54, "USD"), updatedLoan.Balance)
+
+	// Interest is booked as an expense, principal as a transfer
+	var interest models.Transaction
+	db.First(&interest, *payments[0].InterestTransactionID)
+	assert.Equal(t, "expense", interest.Type)
+	assert.Equal(t, models.DefaultLoanInterestCategory, interest.Category)
+	var principal models.Transaction
+	db.First(&principal, *payments[0].PrincipalTransactionID)
+	assert.Equal(t, "transfer", principal.Type)
+	assert.Equal(t, models.DefaultLoanPrincipalCategory, principal.Category)
+
+	report, err := service.ledgerService.CheckIntegrity(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, report.Balanced)
+
+	// Nothing more is due until the next payment date
+	posted, err = service.PostDuePayments()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, posted)
+
+	schedule, err := service.GetSchedule(loanAccount.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, schedule.Payments, 10)
+	assert.Equal(t, 3, schedule.Payments[0].Number)
+	assert.Equal(t, "2024-04-01", schedule.Payments[0].Date.Format("2006-01-02"))
+	assert.Equal(t, 0.0, schedule.Payments[9].Balance)
+	assert.Equal(t, "2025-01-01", schedule.PayoffDate.Format("2006-01-02"))
+}
+
+func TestLoanService_ExtraPaymentsAndScenarios(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	checking := createTestAccount(t, db, user.ID, 5000.0)
+	loanAccount := createTestAccount(t, db, user.ID, -12000.0)
+	db.Model(loanAccount).Update("type", models.AccountTypeLoan)
+
+	service := newTestLoanService(db, time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	_, err := service.Save(loanAccount.ID, user.ID, &models.LoanRequest{
+		PaymentAccountID: checking.ID,
+		Principal:        12000.0,
+		InterestRate:     6,
+		TermMonths:       12,
+		FirstPaymentDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+
+	// Loan terms can only be set on loan accounts
+	_, err = service.Save(checking.ID, user.ID, &models.LoanRequest{PaymentAccountID: loanAccount.ID, Principal: 1, TermMonths: 1})
+	assert.EqualError(t, err, "account is not a loan account")
+
+	// Execute - compare paying more every month and a lump sum
+	result, err := service.CompareScenarios(loanAccount.ID, user.ID, &models.LoanScenariosRequest{
+		Scenarios: []models.LoanScenario{{ExtraPayment: 500.0}, {LumpSum: 12000.0}},
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 12, result.Current.Payments)
+	assert.Equal(t, 0, result.Current.MonthsSaved)
+	assert.Equal(t, 9, result.Scenarios[0].Payments)
+	assert.Equal(t, 3, result.Scenarios[0].MonthsSaved)
+	assert.Greater(t, result.Scenarios[0].InterestSaved, 0.0)
+	assert.Equal(t, 0, result.Scenarios[1].Payments)
+	assert.Nil(t, result.Scenarios[1].PayoffDate)
+	assert.Equal(t, result.Current.TotalInterest, result.Scenarios[1].InterestSaved)
+
+	// A one-off extra payment goes to principal only and shortens the schedule
+	payment, err := service.PayExtra(loanAccount.ID, user.ID, &models.LoanExtraPaymentRequest{Amount: 3000.0})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, payment.Number)
+	assert.Equal(t, models.Money(0), payment.Interest)
+	assert.Equal(t, models.NewMoney(9000.0, "USD"), payment.Balance)
+	assert.Nil(t, payment.InterestTransactionID)
+
+	schedule, err := service.GetSchedule(loanAccount.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 9000.0, schedule.RemainingBalance)
+	assert.Equal(t, 1, schedule.Payments[0].Number)
+	assert.Less(t, len(schedule.Payments), 12)
+
+	_, err = service.PayExtra(loanAccount.ID, user.ID, &models.LoanExtraPaymentRequest{Amount: 10000.0})
+	assert.EqualError(t, err, "extra payment is more than the remaining balance")
+}
+
+func TestLoanService_ConcurrentExtraPayments(t *testing.T) {
+	// Setup - checking covers three of the payments below
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	checking := createTestAccount(t, db, user.ID, 3000.0)
+	loanAccount := createTestAccount(t, db, user.ID, -12000.0)
+	db.Model(loanAccount).Update("type", models.AccountTypeLoan)
+
+	service := newTestLoanService(db, time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	_, err := service.Save(loanAccount.ID, user.ID, &models.LoanRequest{
+		PaymentAccountID: checking.ID,
+		Principal:        12000.0,
+		InterestRate:     6,
+		TermMonths:       12,
+		FirstPaymentDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+
+	// Execute - ten extra payments of 1000 at once
+	const payments = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, payments)
+	for i := 0; i < payments; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.PayExtra(loanAccount.ID, user.ID, &models.LoanExtraPaymentRequest{Amount: 1000.0})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	// Assert - only the payments the checking balance covers go through
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.EqualError(t, err, "insufficient balance in payment account")
+		}
+	}
+	assert.Equal(t, 3, succeeded)
+
+	var updatedChecking, updatedLoan models.Account
+	db.First(&updatedChecking, checking.ID)
+	db.First(&updatedLoan, loanAccount.ID)
+	assert.Equal(t, models.Money(0), updatedChecking.Balance)
+	assert.Equal(t, models.NewMoney(-9000.0, "USD"), updatedLoan.Balance)
+}
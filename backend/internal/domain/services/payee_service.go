@@ -0,0 +1,279 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// PayeeService handles business logic for payees
+type PayeeService struct {
+	payeeRepo       *repository.PayeeRepository
+	currencyService *CurrencyService
+}
+
+// NewPayeeService creates a new payee service
+func NewPayeeService(payeeRepo *repository.PayeeRepository, currencyService *CurrencyService) *PayeeService {
+	return &PayeeService{
+		payeeRepo:       payeeRepo,
+		currencyService: currencyService,
+	}
+}
+
+// GetAll gets all payees of a user with their transaction counts
+func (s *PayeeService) GetAll(userID uint) ([]*models.PayeeResponse, error) {
+	payees, err := s.payeeRepo.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.payeeRepo.CountTransactions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.PayeeResponse, 0, len(payees))
+	for i := range payees {
+		responses = append(responses, payees[i].ToResponse(counts[payees[i].ID]))
+	}
+	return responses, nil
+}
+
+// GetByID gets a payee of a user with its transaction count
+func (s *PayeeService) GetByID(id uint, userID uint) (*models.PayeeResponse, error) {
+	payee, err := s.getPayee(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(payee)
+}
+
+// Create creates a new payee with its aliases
+func (s *PayeeService) Create(userID uint, req *models.PayeeRequest) (*models.PayeeResponse, error) {
+	payee := &models.Payee{UserID: userID}
+	if err := s.apply(payee, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.payeeRepo.Create(payee); err != nil {
+		return nil, err
+	}
+	return payee.ToResponse(0), nil
+}
+
+// Update renames a payee, changes its default category and replaces its aliases
+func (s *PayeeService) Update(id uint, userID uint, req *models.PayeeRequest) (*models.PayeeResponse, error) {
+	payee, err := s.getPayee(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(payee, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.payeeRepo.Update(payee); err != nil {
+		return nil, err
+	}
+	return s.toResponse(payee)
+}
+
+// Delete deletes a payee. Its transactions are kept without a payee.
+func (s *PayeeService) Delete(id uint, userID uint) error {
+	err := s.payeeRepo.Delete(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ErrPayeeNotFound
+	}
+	return err
+}
+
+// Merge moves the transactions and aliases of duplicate payees to the target
+// payee and deletes the duplicates
+func (s *PayeeService) Merge(userID uint, req *models.MergePayeesRequest) (*models.PayeeResponse, error) {
+	target, err := s.getPayee(req.TargetID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceIDs := uniqueIDs(req.SourceIDs)
+	for _, id := range sourceIDs {
+		if id == target.ID {
+			return nil, errors.New("cannot merge a payee into itself")
+		}
+		if _, err := s.getPayee(id, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.payeeRepo.Merge(userID, sourceIDs, target.ID); err != nil {
+		return nil, err
+	}
+	merged, err := s.getPayee(target.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(merged)
+}
+
+// GetSpending reports what was spent at and received from each payee of a
+// user over a period, in the preferred currency of the user, ordered by
+// spending. With a payee ID only that payee is reported.
+func (s *PayeeService) GetSpending(userID uint, payeeID *uint, startDate, endDate time.Time) (*models.PayeeSpendingReport, error) {
+	if payeeID != nil {
+		if _, err := s.getPayee(*payeeID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	currency := s.currencyService.PreferredCurrency(userID)
+	transactions, err := s.payeeRepo.GetTransactionsForSpending(userID, payeeID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Totals are kept in minor units of the reporting currency so the sums stay exact
+	type monthTotals struct {
+		spent, received models.Money
+	}
+	type payeeTotals struct {
+		spending        *models.PayeeSpending
+		spent, received models.Money
+		months          map[string]*monthTotals
+	}
+	totals := make(map[uint]*payeeTotals)
+	rates := s.currencyService.Rates()
+	for _, t := range transactions {
+		amount, err := models.ConvertMoneyAt(rates, t.Amount, t.Currency, currency, t.Date)
+		if err != nil {
+			return nil, err
+		}
+
+		pt, ok := totals[*t.PayeeID]
+		if !ok {
+			pt = &payeeTotals{
+				spending: &models.PayeeSpending{PayeeID: *t.PayeeID, Name: t.Payee.Name},
+				months:   make(map[string]*monthTotals),
+			}
+			totals[*t.PayeeID] = pt
+		}
+		month := t.Date.Format("2006-01")
+		mt, ok := pt.months[month]
+		if !ok {
+			mt = &monthTotals{}
+			pt.months[month] = mt
+		}
+
+		if t.Type == "income" {
+			pt.received += amount
+			mt.received += amount
+		} else {
+			pt.spent += amount
+			mt.spent += amount
+		}
+		pt.spending.Count++
+		if t.Date.After(pt.spending.LastDate) {
+			pt.spending.LastDate = t.Date
+		}
+	}
+
+	report := &models.PayeeSpendingReport{
+		Currency:  currency,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Payees:    make([]*models.PayeeSpending, 0, len(totals)),
+	}
+	for _, pt := range totals {
+		pt.spending.Spent = pt.spent.Float(currency)
+		pt.spending.Received = pt.received.Float(currency)
+		pt.spending.ByMonth = make([]*models.PayeeMonthSpending, 0, len(pt.months))
+		for month, mt := range pt.months {
+			pt.spending.ByMonth = append(pt.spending.ByMonth, &models.PayeeMonthSpending{
+				Month:    month,
+				Spent:    mt.spent.Float(currency),
+				Received: mt.received.Float(currency),
+			})
+		}
+		sort.Slice(pt.spending.ByMonth, func(i, j int) bool {
+			return pt.spending.ByMonth[i].Month < pt.spending.ByMonth[j].Month
+		})
+		report.Payees = append(report.Payees, pt.spending)
+	}
+	sort.Slice(report.Payees, func(i, j int) bool {
+		if report.Payees[i].Spent != report.Payees[j].Spent {
+			return report.Payees[i].Spent > report.Payees[j].Spent
+		}
+		return report.Payees[i].Name < report.Payees[j].Name
+	})
+	return report, nil
+}
+
+// apply validates a payee request and copies it onto the payee
+func (s *PayeeService) apply(payee *models.Payee, req *models.PayeeRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("payee name is required")
+	}
+	if err := s.checkNameFree(payee.UserID, name, payee.ID); err != nil {
+		return err
+	}
+
+	aliases := make([]models.PayeeAlias, 0, len(req.Aliases))
+	for _, alias := range req.Aliases {
+		pattern := strings.TrimSpace(alias.Pattern)
+		matchType := alias.MatchType
+		if matchType == "" {
+			matchType = models.PayeeMatchExact
+		}
+		if pattern == "" {
+			return errors.New("alias pattern is required")
+		}
+		if matchType == models.PayeeMatchRegex {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return errors.New("invalid alias pattern: " + err.Error())
+			}
+		}
+		aliases = append(aliases, models.PayeeAlias{PayeeID: payee.ID, Pattern: pattern, MatchType: matchType})
+	}
+
+	payee.Name = name
+	payee.DefaultCategory = strings.TrimSpace(req.DefaultCategory)
+	payee.Aliases = aliases
+	return nil
+}
+
+// getPayee gets a payee of a user, reporting a missing payee as not found
+func (s *PayeeService) getPayee(id uint, userID uint) (*models.Payee, error) {
+	payee, err := s.payeeRepo.GetByID(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrPayeeNotFound
+	}
+	return payee, err
+}
+
+// checkNameFree fails when another payee of the user already has the name
+func (s *PayeeService) checkNameFree(userID uint, name string, id uint) error {
+	existing, err := s.payeeRepo.GetByName(userID, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != id {
+		return errors.New("payee already exists")
+	}
+	return nil
+}
+
+// toResponse converts a payee to a response with its current transaction count
+func (s *PayeeService) toResponse(payee *models.Payee) (*models.PayeeResponse, error) {
+	counts, err := s.payeeRepo.CountTransactions(payee.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return payee.ToResponse(counts[payee.ID]), nil
+}
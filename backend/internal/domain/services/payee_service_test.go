@@ -0,0 +1,101 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayeeService_ResolveMergeAndSpending(t *testing.T) {
+	// Setup - a payee with aliases and a default category
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := NewPayeeService(repository.NewPayeeRepository(db), newTestCurrencyService(db))
+	amazon, err := service.Create(user.ID, &models.PayeeRequest{
+		Name:            "Amazon",
+		DefaultCategory: "Shopping",
+		Aliases: []models.PayeeAliasRequest{
+			{Pattern: "AMZN", MatchType: models.PayeeMatchContains},
+			{Pattern: "amazon prime"},
+		},
+	})
+	assert.NoError(t, err)
+
+	_, err = service.Create(user.ID, &models.PayeeRequest{Name: "amazon"})
+	assert.EqualError(t, err, "payee already exists")
+	_, err = service.Create(user.ID, &models.PayeeRequest{Name: "Bad", Aliases: []models.PayeeAliasRequest{{Pattern: "(", MatchType: models.PayeeMatchRegex}}})
+	assert.Error(t, err)
+
+	transactionService := newTestTransactionService(db)
+	date := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	create := func(amount float64, description, category string) *models.Transaction {
+		transaction, err := transactionService.Create(user.ID, &models.TransactionRequest{
+			Amount: amount, Type: "expense", Category: category, Description: description, AccountID: account.ID, Date: date,
+		})
+		assert.NoError(t, err)
+		return transaction
+	}
+
+	// Execute - variants of the same merchant resolve to one payee
+	for _, description := range []string{"AMZN Mktp US*2K4", "Amazon.com", "AMAZON PRIME"} {
+		transaction := create(10.0, description, "")
+		assert.Equal(t, &amazon.ID, transaction.PayeeID, description)
+		assert.Equal(t, "Shopping", transaction.Category, description)
+	}
+
+	// Unknown payees are created from the description and learn its category
+	first := create(4.0, "STARBUCKS STORE 12345", "Coffee")
+	assert.Equal(t, "STARBUCKS STORE", first.Payee.Name)
+	second := create(5.0, "Starbucks Store #678", "")
+	assert.Equal(t, first.PayeeID, second.PayeeID)
+	assert.Equal(t, "Coffee", second.Category)
+	duplicate := create(6.0, "Starbucks Coffee", "Coffee")
+	assert.NotEqual(t, first.PayeeID, duplicate.PayeeID)
+
+	_, err = transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 1.0, Type: "expense", AccountID: account.ID, Date: date, PayeeID: uintPtr(9999),
+	})
+	assert.ErrorIs(t, err, models.ErrPayeeNotFound)
+
+	// Merge the duplicate; its name keeps matching as an alias
+	merged, err := service.Merge(user.ID, &models.MergePayeesRequest{SourceIDs: []uint{*duplicate.PayeeID}, TargetID: *first.PayeeID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), merged.TransactionCount)
+	assert.Equal(t, "Starbucks Coffee", merged.Aliases[0].Pattern)
+	assert.Equal(t, first.PayeeID, create(7.0, "STARBUCKS COFFEE", "").PayeeID)
+
+	_, err = service.Merge(user.ID, &models.MergePayeesRequest{SourceIDs: []uint{amazon.ID}, TargetID: amazon.ID})
+	assert.EqualError(t, err, "cannot merge a payee into itself")
+
+	// Assert - spending is totalled per payee, highest first
+	report, err := service.GetSpending(user.ID, nil, date.AddDate(0, -1, 0), date.AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", report.Currency)
+	assert.Len(t, report.Payees, 2)
+	assert.Equal(t, "Amazon", report.Payees[0].Name)
+	assert.Equal(t, 30.0, report.Payees[0].Spent)
+	assert.Equal(t, 3, report.Payees[0].Count)
+	assert.Equal(t, 22.0, report.Payees[1].Spent)
+	assert.Equal(t, "2024-03", report.Payees[1].ByMonth[0].Month)
+
+	report, err = service.GetSpending(user.ID, &amazon.ID, date.AddDate(0, -1, 0), date.AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	assert.Len(t, report.Payees, 1)
+
+	// Deleting a payee leaves its transactions without one
+	assert.NoError(t, service.Delete(amazon.ID, user.ID))
+	page, err := transactionService.GetPaginated(user.ID, &models.TransactionFilterRequest{PayeeID: &amazon.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), page.Total)
+	assert.ErrorIs(t, service.Delete(amazon.ID, user.ID), models.ErrPayeeNotFound)
+}
+
+// uintPtr returns a pointer to n
+func uintPtr(n uint) *uint {
+	return &n
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ReconciliationService handles business logic for reconciling accounts against bank statements
+type ReconciliationService struct {
+	reconciliationRepo *repository.ReconciliationRepository
+	accountRepo        *repository.AccountRepository
+	ledgerService      *LedgerService
+	db                 *gorm.DB
+}
+
+// NewReconciliationService creates a new reconciliation service
+func NewReconciliationService(
+	reconciliationRepo *repository.ReconciliationRepository,
+	accountRepo *repository.AccountRepository,
+	ledgerService *LedgerService,
+	db *gorm.DB,
+) *ReconciliationService {
+	return &ReconciliationService{
+		reconciliationRepo: reconciliationRepo,
+		accountRepo:        accountRepo,
+		ledgerService:      ledgerService,
+		db:                 db,
+	}
+}
+
+// Start starts a reconciliation of an account against a statement
+func (s *ReconciliationService) Start(userID uint, req *models.StartReconciliationRequest) (*models.Reconciliation, error) {
+	var reconciliation *models.Reconciliation
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the account so concurrent starts check for an open
+		// reconciliation one after another
+		if err := s.ledgerService.LockAccounts(tx, req.AccountID); err != nil {
+			return err
+		}
+
+		// Check if account exists and belongs to user
+		account, err := s.accountRepo.WithTx(tx).GetByID(req.AccountID, userID)
+		if err != nil {
+			return errors.New("account not found")
+		}
+
+		// Only one reconciliation can be open per account
+		repo := s.reconciliationRepo.WithTx(tx)
+		count, err := repo.CountInProgress(account.ID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("a reconciliation is already in progress for this account")
+		}
+
+		reconciliation = &models.Reconciliation{
+			UserID:           userID,
+			AccountID:        account.ID,
+			Status:           models.ReconciliationStatusInProgress,
+			StatementDate:    req.StatementDate,
+			StatementBalance: models.NewMoney(req.StatementBalance, account.Currency),
+			Currency:         account.Currency,
+		}
+		if err := repo.Create(reconciliation); err != nil {
+			return err
+		}
+		if err := s.calculateTotals(repo, reconciliation); err != nil {
+			return err
+		}
+		return repo.Update(reconciliation)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reconciliation, nil
+}
+
+// GetByID gets a reconciliation together with the transactions that can still be cleared
+func (s *ReconciliationService) GetByID(id uint, userID uint) (*models.ReconciliationResponse, error) {
+	reconciliation, err := s.reconciliationRepo.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := reconciliation.ToResponse()
+	if reconciliation.Status != models.ReconciliationStatusInProgress {
+		return response, nil
+	}
+
+	// Show the live difference and the open transactions up to the statement date
+	if err := s.calculateTotals(s.reconciliationRepo, reconciliation); err != nil {
+		return nil, err
+	}
+	transactions, err := s.reconciliationRepo.GetOpenTransactions(reconciliation.AccountID, reconciliation.StatementCutoff())
+	if err != nil {
+		return nil, err
+	}
+
+	response = reconciliation.ToResponse()
+	response.Transactions = make([]*models.TransactionResponse, 0, len(transactions))
+	for _, t := range transactions {
+		response.Transactions = append(response.Transactions, t.ToResponse())
+	}
+	return response, nil
+}
+
+// GetByAccount gets the reconciliation history of an account
+func (s *ReconciliationService) GetByAccount(accountID uint, userID uint) ([]models.Reconciliation, error) {
+	if _, err := s.accountRepo.GetByID(accountID, userID); err != nil {
+		return nil, errors.New("account not found")
+	}
+	return s.reconciliationRepo.GetByAccountID(accountID, userID)
+}
+
+// SetCleared ticks or unticks transactions in an open reconciliation and
+// returns the reconciliation with its updated difference
+func (s *ReconciliationService) SetCleared(id uint, userID uint, req *models.ClearTransactionsRequest) (*models.Reconciliation, error) {
+	var reconciliation *models.Reconciliation
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if reconciliation, err = s.lockInProgress(tx, id, userID); err != nil {
+			return err
+		}
+		repo := s.reconciliationRepo.WithTx(tx)
+
+		updated, err := repo.SetCleared(reconciliation, req.TransactionIDs, req.Cleared, reconciliation.StatementCutoff())
+		if err != nil {
+			return err
+		}
+		if updated != int64(len(req.TransactionIDs)) {
+			return errors.New("some transactions are not open on this account before the statement date")
+		}
+
+		// Clearing moves money between the current and available balances
+		if err := s.ledgerService.SyncAccountBalanceByID(tx, reconciliation.AccountID, models.BalanceChange{
+			ChangeType:  models.BalanceChangeAdjustment,
+			Description: "Reconciliation",
+		}); err != nil {
+			return err
+		}
+
+		if err := s.calculateTotals(repo, reconciliation); err != nil {
+			return err
+		}
+		return repo.Update(reconciliation)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reconciliation, nil
+}
+
+// Finish completes a reconciliation and locks its cleared transactions. A
+// remaining difference is rejected unless the caller asks for it to be posted
+// as a balance adjustment.
+func (s *ReconciliationService) Finish(id uint, userID uint, req *models.FinishReconciliationRequest) (*models.Reconciliation, error) {
+	var reconciliation *models.Reconciliation
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if reconciliation, err = s.lockInProgress(tx, id, userID); err != nil {
+			return err
+		}
+		repo := s.reconciliationRepo.WithTx(tx)
+
+		account, err := s.accountRepo.WithTx(tx).GetByID(reconciliation.AccountID, userID)
+		if err != nil {
+			return errors.New("account not found")
+		}
+
+		// Recalculate the difference inside the transaction
+		if err := s.calculateTotals(repo, reconciliation); err != nil {
+			return err
+		}
+
+		// Bring the books in line with the statement if asked to
+		difference := reconciliation.Difference()
+		if difference != 0 {
+			if !req.CreateAdjustment {
+				return fmt.Errorf("reconciliation is out of balance by %s", difference.Format(reconciliation.Currency))
+			}
+
+			description := "Reconciliation adjustment for statement of " + reconciliation.StatementDate.Format("2006-01-02")
+			if err := s.ledgerService.PostAdjustment(tx, account, difference, description); err != nil {
+				return err
+			}
+			if err := s.ledgerService.SyncAccountBalance(tx, account, models.BalanceChange{
+				ChangeType:  models.BalanceChangeAdjustment,
+				Description: description,
+			}); err != nil {
+				return err
+			}
+
+			reconciliation.Adjustment = difference
+			reconciliation.ClearedBalance += difference
+		}
+
+		// Lock the cleared transactions
+		now := time.Now()
+		locked, err := repo.LockCleared(reconciliation, reconciliation.StatementCutoff(), now)
+		if err != nil {
+			return err
+		}
+
+		reconciliation.ClearedCount = int(locked)
+		reconciliation.Status = models.ReconciliationStatusCompleted
+		reconciliation.CompletedAt = &now
+		return repo.Update(reconciliation)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reconciliation, nil
+}
+
+// Cancel discards an open reconciliation. Transactions keep their cleared status.
+func (s *ReconciliationService) Cancel(id uint, userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		reconciliation, err := s.lockInProgress(tx, id, userID)
+		if err != nil {
+			return err
+		}
+		return s.reconciliationRepo.WithTx(tx).Delete(reconciliation)
+	})
+}
+
+// lockInProgress locks the account of a reconciliation and reads the
+// reconciliation again with a lock inside tx, so concurrent changes of it
+// run one after another and each sees whether the last completed it
+func (s *ReconciliationService) lockInProgress(tx *gorm.DB, id uint, userID uint) (*models.Reconciliation, error) {
+	repo := s.reconciliationRepo.WithTx(tx)
+	reconciliation, err := repo.GetByID(id, userID)
+	if err != nil {
+		return nil, errors.New("reconciliation not found")
+	}
+	if err := s.ledgerService.LockAccounts(tx, reconciliation.AccountID); err != nil {
+		return nil, err
+	}
+
+	reconciliation, err = repo.GetByIDForUpdate(id, userID)
+	if err != nil {
+		return nil, errors.New("reconciliation not found")
+	}
+	if reconciliation.Status != models.ReconciliationStatusInProgress {
+		return nil, errors.New("reconciliation is already completed")
+	}
+	return reconciliation, nil
+}
+
+// calculateTotals recalculates the cleared balance and cleared count of a reconciliation
+func (s *ReconciliationService) calculateTotals(repo *repository.ReconciliationRepository, reconciliation *models.Reconciliation) error {
+	clearedBalance, err := repo.GetClearedBalance(reconciliation.AccountID)
+	if err != nil {
+		return err
+	}
+
+	count, err := repo.CountCleared(reconciliation.ID)
+	if err != nil {
+		return err
+	}
+
+	reconciliation.ClearedBalance = clearedBalance
+	reconciliation.ClearedCount = int(count)
+	return nil
+}
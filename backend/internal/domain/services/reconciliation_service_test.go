@@ -0,0 +1,159 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newTestReconciliationService wires a reconciliation service against the test database
+func newTestReconciliationService(db *gorm.DB) *ReconciliationService {
+	return NewReconciliationService(repository.NewReconciliationRepository(db), repository.NewAccountRepository(db), newTestLedgerService(db), db)
+}
+
+func TestReconciliationService_Finish(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	transactionService := newTestTransactionService(db)
+	service := newTestReconciliationService(db)
+
+	statementDate := time.Now()
+	cleared, err := transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 100.0, Type: "expense", Category: "Shopping", Date: statementDate.AddDate(0, 0, -2), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+	_, err = transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 50.0, Type: "expense", Category: "Shopping", Date: statementDate.AddDate(0, 0, -1), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+
+	// Execute - the statement only shows the first expense
+	reconciliation, err := service.Start(user.ID, &models.StartReconciliationRequest{
+		AccountID: account.ID, StatementDate: statementDate, StatementBalance: 900.0,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(1000.0, "USD"), reconciliation.ClearedBalance)
+
+	reconciliation, err = service.SetCleared(reconciliation.ID, user.ID, &models.ClearTransactionsRequest{
+		TransactionIDs: []uint{cleared.ID}, Cleared: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.Money(0), reconciliation.Difference())
+
+	reconciliation, err = service.Finish(reconciliation.ID, user.ID, &models.FinishReconciliationRequest{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReconciliationStatusCompleted, reconciliation.Status)
+	assert.Equal(t, 1, reconciliation.ClearedCount)
+
+	_, err = transactionService.Update(cleared.ID, user.ID, &models.TransactionRequest{
+		Amount: 120.0, Type: "expense", Category: "Shopping", Date: cleared.Date, AccountID: account.ID,
+	})
+	assert.ErrorIs(t, err, models.ErrTransactionReconciled)
+	assert.ErrorIs(t, transactionService.Delete(cleared.ID, user.ID), models.ErrTransactionReconciled)
+}
+
+func TestReconciliationService_Finish_Adjustment(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := newTestReconciliationService(db)
+
+	reconciliation, err := service.Start(user.ID, &models.StartReconciliationRequest{
+		AccountID: account.ID, StatementDate: time.Now(), StatementBalance: 995.0,
+	})
+	assert.NoError(t, err)
+
+	// Execute - an unexplained difference is rejected unless an adjustment is requested
+	_, err = service.Finish(reconciliation.ID, user.ID, &models.FinishReconciliationRequest{})
+	assert.Error(t, err)
+
+	reconciliation, err = service.Finish(reconciliation.ID, user.ID, &models.FinishReconciliationRequest{CreateAdjustment: true})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(-5.0, "USD"), reconciliation.Adjustment)
+
+	var updatedAccount models.Account
+	db.First(&updatedAccount, account.ID)
+	assert.Equal(t, models.NewMoney(995.0, "USD"), updatedAccount.Balance)
+
+	var history models.BalanceHistory
+	db.Where("account_id = ?", account.ID).Last(&history)
+	assert.Equal(t, models.BalanceChangeAdjustment, history.ChangeType)
+	assert.Equal(t, models.NewMoney(-5.0, "USD"), history.ChangeAmount)
+}
+
+func TestReconciliationService_ConcurrentStartAndFinish(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := newTestReconciliationService(db)
+	const workers = 5
+
+	// Execute - concurrent starts, then concurrent finishes with an adjustment
+	run := func(do func() error) []error {
+		var wg sync.WaitGroup
+		errs := make([]error, workers)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				errs[w] = do()
+			}(w)
+		}
+		wg.Wait()
+		return errs
+	}
+	var reconciliationID uint
+	var mu sync.Mutex
+	startErrs := run(func() error {
+		reconciliation, err := service.Start(user.ID, &models.StartReconciliationRequest{
+			AccountID: account.ID, StatementDate: time.Now(), StatementBalance: 990.0,
+		})
+		if err == nil {
+			mu.Lock()
+			reconciliationID = reconciliation.ID
+			mu.Unlock()
+		}
+		return err
+	})
+	finishErrs := run(func() error {
+		_, err := service.Finish(reconciliationID, user.ID, &models.FinishReconciliationRequest{CreateAdjustment: true})
+		return err
+	})
+
+	// Assert - one start and one finish win, and the adjustment is posted once
+	succeeded := func(errs []error) int {
+		count := 0
+		for _, err := range errs {
+			if err == nil {
+				count++
+			}
+		}
+		return count
+	}
+	assert.Equal(t, 1, succeeded(startErrs))
+	assert.Equal(t, 1, succeeded(finishErrs))
+
+	var adjustments int64
+	db.Model(&models.JournalEntry{}).Where("user_id = ? AND type = ?", user.ID, models.JournalEntryTypeAdjustment).Count(&adjustments)
+	assert.Equal(t, int64(1), adjustments)
+
+	var updatedAccount models.Account
+	db.First(&updatedAccount, account.ID)
+	assert.Equal(t, models.NewMoney(990.0, "USD"), updatedAccount.Balance)
+}
@@ -2,11 +2,11 @@ package services
 
 import (
 	"errors"
-	"strings"
 	"time"
 
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
 	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
 )
 
 // RecurringTransactionService handles business logic for recurring transactions
@@ -14,6 +14,10 @@ type RecurringTransactionService struct {
 	recurringRepo   *repository.RecurringTransactionRepository
 	transactionRepo *repository.TransactionRepository
 	accountRepo     *repository.AccountRepository
+	tagRepo         *repository.TagRepository
+	ruleRepo        *repository.RuleRepository
+	ledgerService   *LedgerService
+	db              *gorm.DB
 }
 
 // NewRecurringTransactionService creates a new recurring transaction service
@@ -21,18 +25,26 @@ func NewRecurringTransactionService(
 	recurringRepo *repository.RecurringTransactionRepository,
 	transactionRepo *repository.TransactionRepository,
 	accountRepo *repository.AccountRepository,
+	tagRepo *repository.TagRepository,
+	ruleRepo *repository.RuleRepository,
+	ledgerService *LedgerService,
+	db *gorm.DB,
 ) *RecurringTransactionService {
 	return &RecurringTransactionService{
 		recurringRepo:   recurringRepo,
 		transactionRepo: transactionRepo,
 		accountRepo:     accountRepo,
+		tagRepo:         tagRepo,
+		ruleRepo:        ruleRepo,
+		ledgerService:   ledgerService,
+		db:              db,
 	}
 }
 
 // Create creates a new recurring transaction
 func (s *RecurringTransactionService) Create(userID uint, req *models.RecurringTransactionRequest) (*models.RecurringTransaction, error) {
 	// Validate account exists
-	_, err := s.accountRepo.GetByID(req.AccountID, userID)
+	account, err := s.accountRepo.GetByID(req.AccountID, userID)
 	if err != nil {
 		return nil, errors.New("account not found")
 	}
@@ -43,6 +55,11 @@ func (s *RecurringTransactionService) Create(userID uint, req *models.RecurringT
 		interval = 1
 	}
 
+	tags, err := s.tagRepo.FindOrCreate(userID, req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
 	// Calculate next run date
 	nextRunDate := req.StartDate
 	if nextRunDate.Before(time.Now()) {
@@ -51,12 +68,13 @@ func (s *RecurringTransactionService) Create(userID uint, req *models.RecurringT
 
 	recurring := &models.RecurringTransaction{
 		UserID:      userID,
-		Amount:      req.Amount,
+		Amount:      models.NewMoney(req.Amount, account.Currency),
+		Currency:    account.Currency,
 		Description: req.Description,
 		Category:    req.Category,
 		Type:        req.Type,
 		AccountID:   req.AccountID,
-		Tags:        strings.Join(req.Tags, ","),
+		Tags:        tags,
 		Frequency:   req.Frequency,
 		Interval:    interval,
 		DayOfWeek:   req.DayOfWeek,
@@ -99,18 +117,24 @@ func (s *RecurringTransactionService) Update(id uint, userID uint, req *models.R
 	}
 
 	// Validate account
-	_, err = s.accountRepo.GetByID(req.AccountID, userID)
+	account, err := s.accountRepo.GetByID(req.AccountID, userID)
 	if err != nil {
 		return nil, errors.New("account not found")
 	}
 
+	tags, err := s.tagRepo.FindOrCreate(userID, req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
 	// Update fields
-	recurring.Amount = req.Amount
+	recurring.Amount = models.NewMoney(req.Amount, account.Currency)
+	recurring.Currency = account.Currency
 	recurring.Description = req.Description
 	recurring.Category = req.Category
 	recurring.Type = req.Type
 	recurring.AccountID = req.AccountID
-	recurring.Tags = strings.Join(req.Tags, ",")
+	recurring.Tags = tags
 	recurring.Frequency = req.Frequency
 	recurring.Interval = req.Interval
 	recurring.DayOfWeek = req.DayOfWeek
@@ -127,7 +151,7 @@ func (s *RecurringTransactionService) Update(id uint, userID uint, req *models.R
 	return recurring, nil
 }
 
-// Delete deletes a recurring transaction
+// Delete moves a recurring transaction to the trash
 func (s *RecurringTransactionService) Delete(id uint, userID uint) error {
 	return s.recurringRepo.Delete(id, userID)
 }
@@ -175,26 +199,20 @@ func (s *RecurringTransactionService) ProcessDue() (int, error) {
 		transaction := &models.Transaction{
 			UserID:      recurring.UserID,
 			Amount:      recurring.Amount,
+			Currency:    recurring.Currency,
 			Description: recurring.Description + " (Recurring)",
 			Category:    recurring.Category,
 			Type:        recurring.Type,
 			Date:        now,
 			AccountID:   recurring.AccountID,
 			Tags:        recurring.Tags,
+			Status:      models.TransactionStatusPending,
 		}
 
-		if err := s.transactionRepo.Create(transaction); err != nil {
+		if err := s.postTransaction(account, transaction); err != nil {
 			continue // Skip if failed to create
 		}
 
-		// Update account balance
-		if recurring.Type == "income" {
-			account.Balance += recurring.Amount
-		} else {
-			account.Balance -= recurring.Amount
-		}
-		s.accountRepo.Update(account)
-
 		// Update recurring transaction
 		recurring.LastRunDate = &now
 		recurring.NextRunDate = recurring.CalculateNextRunDate()
@@ -230,26 +248,20 @@ func (s *RecurringTransactionService) RunNow(id uint, userID uint) (*models.Tran
 	transaction := &models.Transaction{
 		UserID:      userID,
 		Amount:      recurring.Amount,
+		Currency:    recurring.Currency,
 		Description: recurring.Description + " (Manual Run)",
 		Category:    recurring.Category,
 		Type:        recurring.Type,
 		Date:        now,
 		AccountID:   recurring.AccountID,
 		Tags:        recurring.Tags,
+		Status:      models.TransactionStatusPending,
 	}
 
-	if err := s.transactionRepo.Create(transaction); err != nil {
+	if err := s.postTransaction(account, transaction); err != nil {
 		return nil, errors.New("failed to create transaction")
 	}
 
-	// Update account balance
-	if recurring.Type == "income" {
-		account.Balance += recurring.Amount
-	} else {
-		account.Balance -= recurring.Amount
-	}
-	s.accountRepo.Update(account)
-
 	// Update recurring transaction
 	recurring.LastRunDate = &now
 	recurring.TotalRuns++
@@ -258,3 +270,25 @@ func (s *RecurringTransactionService) RunNow(id uint, userID uint) (*models.Tran
 	return transaction, nil
 }
 
+// postTransaction runs the user's rules over a generated transaction and
+// saves it together with its journal entry, deriving the account balance
+// from the ledger in one DB transaction
+func (s *RecurringTransactionService) postTransaction(account *models.Account, transaction *models.Transaction) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		rules, err := s.ruleRepo.WithTx(tx).GetActive(transaction.UserID)
+		if err != nil {
+			return err
+		}
+		models.ApplyRules(rules, transaction)
+
+		if err := s.ledgerService.PostTransaction(tx, transaction, models.JournalEntryTypeRecurring); err != nil {
+			return err
+		}
+
+		if err := tx.Create(transaction).Error; err != nil {
+			return err
+		}
+
+		return s.ledgerService.SyncAccountBalance(tx, account, models.NewTransactionBalanceChange(transaction))
+	})
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// RuleService handles business logic for auto-categorization rules
+type RuleService struct {
+	ruleRepo           *repository.RuleRepository
+	tagRepo            *repository.TagRepository
+	transactionRepo    *repository.TransactionRepository
+	accountRepo        *repository.AccountRepository
+	transactionService *TransactionService
+	db                 *gorm.DB
+}
+
+// NewRuleService creates a new rule service
+func NewRuleService(
+	ruleRepo *repository.RuleRepository,
+	tagRepo *repository.TagRepository,
+	transactionRepo *repository.TransactionRepository,
+	accountRepo *repository.AccountRepository,
+	transactionService *TransactionService,
+	db *gorm.DB,
+) *RuleService {
+	return &RuleService{
+		ruleRepo:           ruleRepo,
+		tagRepo:            tagRepo,
+		transactionRepo:    transactionRepo,
+		accountRepo:        accountRepo,
+		transactionService: transactionService,
+		db:                 db,
+	}
+}
+
+// GetAll gets all rules of a user in the order they run
+func (s *RuleService) GetAll(userID uint) ([]models.Rule, error) {
+	return s.ruleRepo.GetAll(userID)
+}
+
+// GetByID gets a rule of a user
+func (s *RuleService) GetByID(id uint, userID uint) (*models.Rule, error) {
+	return s.getRule(id, userID)
+}
+
+// Create creates a new rule that runs after the existing rules
+func (s *RuleService) Create(userID uint, req *models.RuleRequest) (*models.Rule, error) {
+	rule := &models.Rule{UserID: userID}
+	if err := s.apply(rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.ruleRepo.Create(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// Update updates the conditions and actions of a rule
+func (s *RuleService) Update(id uint, userID uint, req *models.RuleRequest) (*models.Rule, error) {
+	rule, err := s.getRule(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.ruleRepo.Update(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// Delete deletes a rule
+func (s *RuleService) Delete(id uint, userID uint) error {
+	err := s.ruleRepo.Delete(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("rule not found")
+	}
+	return err
+}
+
+// Reorder changes the order rules run in. Every rule of the user must be listed once.
+func (s *RuleService) Reorder(userID uint, req *models.ReorderRulesRequest) ([]models.Rule, error) {
+	rules, err := s.ruleRepo.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := uniqueIDs(req.RuleIDs)
+	if len(ids) != len(req.RuleIDs) || len(ids) != len(rules) {
+		return nil, errors.New("every rule must be listed exactly once")
+	}
+	known := make(map[uint]bool, len(rules))
+	for _, rule := range rules {
+		known[rule.ID] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			return nil, errors.New("rule not found")
+		}
+	}
+
+	if err := s.ruleRepo.Reorder(userID, ids); err != nil {
+		return nil, err
+	}
+	return s.ruleRepo.GetAll(userID)
+}
+
+// Run runs rules over existing transactions of a user: the given rules in
+// the order they run, or all active rules. A preview reports the changes
+// without saving them.
+func (s *RuleService) Run(userID uint, req *models.RunRulesRequest) (*models.RuleRunResponse, error) {
+	rules, err := s.ruleRepo.GetActive(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.RuleIDs) > 0 {
+		if rules, err = s.selectRules(userID, uniqueIDs(req.RuleIDs)); err != nil {
+			return nil, err
+		}
+	}
+
+	filter := req.Filter
+	if filter == nil {
+		filter = &models.TransactionFilterRequest{}
+	}
+	ids, err := s.transactionRepo.GetIDsByFilter(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.RuleRunResponse{
+		Preview:  req.Preview,
+		Examined: len(ids),
+		Changes:  []*models.RuleChange{},
+	}
+	if len(rules) == 0 {
+		return response, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// New categories are reposted, so lock every account involved up front
+		if !req.Preview {
+			accountIDs, err := s.transactionRepo.WithTx(tx).GetAccountIDs(userID, ids)
+			if err != nil {
+				return err
+			}
+			if err := s.transactionService.ledgerService.LockAccounts(tx, accountIDs...); err != nil {
+				return err
+			}
+		}
+
+		for _, id := range ids {
+			transaction, err := s.transactionRepo.WithTx(tx).GetByID(id, userID)
+			if err != nil {
+				return err
+			}
+
+			// Reconciled transactions are locked
+			if transaction.IsReconciled() {
+				continue
+			}
+
+			before := *transaction
+			matched := models.ApplyRules(rules, transaction)
+			fields := models.RuleChanges(&before, transaction)
+			if len(fields) == 0 {
+				continue
+			}
+			response.Changes = append(response.Changes, &models.RuleChange{
+				TransactionID: transaction.ID,
+				Date:          transaction.Date,
+				Description:   before.Description,
+				Rules:         matched,
+				Fields:        fields,
+			})
+			if req.Preview {
+				continue
+			}
+
+			if transaction.Category != before.Category {
+				if err := s.transactionService.repostCategory(tx, transaction); err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(transaction).UpdateColumns(map[string]interface{}{
+				"category":         transaction.Category,
+				"description":      transaction.Description,
+				"tax_category_id":  transaction.TaxCategoryID,
+				"journal_entry_id": transaction.JournalEntryID,
+			}).Error; err != nil {
+				return err
+			}
+			// Rules only ever add tags
+			if added := transaction.Tags[len(before.Tags):]; len(added) > 0 {
+				if err := tx.Model(transaction).Association("Tags").Append(append([]models.Tag{}, added...)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response.Changed = len(response.Changes)
+	return response, nil
+}
+
+// selectRules gets the rules of a user with the given IDs in the order they
+// run, whether they are active or not
+func (s *RuleService) selectRules(userID uint, ids []uint) ([]models.Rule, error) {
+	all, err := s.ruleRepo.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	rules := make([]models.Rule, 0, len(ids))
+	for _, rule := range all {
+		if wanted[rule.ID] {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) != len(ids) {
+		return nil, errors.New("rule not found")
+	}
+	return rules, nil
+}
+
+// apply validates a rule request and copies it onto the rule
+func (s *RuleService) apply(rule *models.Rule, req *models.RuleRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("rule name is required")
+	}
+	if req.DescriptionRegex != "" {
+		if _, err := regexp.Compile(req.DescriptionRegex); err != nil {
+			return errors.New("invalid description regex: " + err.Error())
+		}
+	}
+	if req.MinAmount != nil && req.MaxAmount != nil && *req.MinAmount > *req.MaxAmount {
+		return errors.New("min amount cannot be greater than max amount")
+	}
+	if req.AccountID != nil {
+		if _, err := s.accountRepo.GetByID(*req.AccountID, rule.UserID); err != nil {
+			return errors.New("account not found")
+		}
+	}
+	if req.SetTaxCategoryID != nil {
+		if err := checkTaxCategory(s.db, rule.UserID, *req.SetTaxCategoryID); err != nil {
+			return err
+		}
+	}
+
+	tags, err := s.tagRepo.FindOrCreate(rule.UserID, req.AddTags)
+	if err != nil {
+		return err
+	}
+
+	rule.Name = name
+	rule.IsActive = req.IsActive == nil || *req.IsActive
+	rule.StopProcessing = req.StopProcessing
+	rule.DescriptionContains = req.DescriptionContains
+	rule.DescriptionRegex = req.DescriptionRegex
+	rule.MinAmount = req.MinAmount
+	rule.MaxAmount = req.MaxAmount
+	rule.AccountID = req.AccountID
+	rule.TransactionType = req.TransactionType
+	rule.Weekdays = strings.Join(req.Weekdays, ",")
+	rule.SetCategory = strings.TrimSpace(req.SetCategory)
+	rule.Tags = tags
+	rule.SetTaxCategoryID = req.SetTaxCategoryID
+	rule.SetDescription = strings.TrimSpace(req.SetDescription)
+	rule.MarkTransfer = req.MarkTransfer
+
+	if !rule.HasActions() {
+		return errors.New("rule has no actions")
+	}
+	return nil
+}
+
+// getRule gets a rule of a user, reporting a missing rule as not found
+func (s *RuleService) getRule(id uint, userID uint) (*models.Rule, error) {
+	rule, err := s.ruleRepo.GetByID(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("rule not found")
+	}
+	return rule, err
+}
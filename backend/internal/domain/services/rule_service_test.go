@@ -0,0 +1,171 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleService_ApplyOnCreateAndRerun(t *testing.T) {
+	// Setup - ordered rules with different conditions and actions
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := NewRuleService(repository.NewRuleRepository(db), repository.NewTagRepository(db),
+		repository.NewTransactionRepository(db), repository.NewAccountRepository(db), newTestTransactionService(db), db)
+	coffee, err := service.Create(user.ID, &models.RuleRequest{
+		Name: "Coffee", DescriptionContains: "starbucks", SetCategory: "Coffee", AddTags: []string{"caffeine"}, StopProcessing: true,
+	})
+	assert.NoError(t, err)
+	weekend, err := service.Create(user.ID, &models.RuleRequest{
+		Name: "Weekend", TransactionType: "expense", Weekdays: []string{"sat", "sun"}, AddTags: []string{"weekend"},
+	})
+	assert.NoError(t, err)
+	cards, err := service.Create(user.ID, &models.RuleRequest{Name: "Card payments", MinAmount: floatPtr(500), MarkTransfer: true})
+	assert.NoError(t, err)
+	inactive := false
+	rename, err := service.Create(user.ID, &models.RuleRequest{
+		Name: "Rename", DescriptionRegex: `^sbux\b`, SetDescription: "Starbucks", IsActive: &inactive,
+	})
+	assert.NoError(t, err)
+	assert.False(t, rename.IsActive)
+	assert.Equal(t, 3, rename.Position)
+
+	_, err = service.Create(user.ID, &models.RuleRequest{Name: "Broken", DescriptionRegex: "(", SetCategory: "X"})
+	assert.Error(t, err)
+	_, err = service.Create(user.ID, &models.RuleRequest{Name: "Idle", DescriptionContains: "x"})
+	assert.EqualError(t, err, "rule has no actions")
+
+	// Execute - rules fill in transactions as they are created
+	transactionService := newTestTransactionService(db)
+	saturday := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC)
+	create := func(amount float64, description, category string, date time.Time) *models.Transaction {
+		transaction, err := transactionService.Create(user.ID, &models.TransactionRequest{
+			Amount: amount, Type: "expense", Category: category, Description: description, AccountID: account.ID, Date: date,
+		})
+		assert.NoError(t, err)
+		return transaction
+	}
+	latte := create(5.0, "STARBUCKS #12", "", saturday)
+	groceries := create(40.0, "Groceries", "Food", saturday)
+	payment := create(600.0, "Card payment", "Bills", monday)
+	sbux := create(4.0, "SBUX 123", "Food", monday)
+
+	// Assert - the first matching rule stops the rest, inactive rules do not run
+	assert.Equal(t, "Coffee", latte.Category)
+	assert.Equal(t, []string{"caffeine"}, models.TagNames(latte.Tags))
+	assert.Equal(t, []string{"weekend"}, models.TagNames(groceries.Tags))
+	assert.Equal(t, models.TransferCategory, payment.Category)
+	assert.Equal(t, "expense", payment.Type)
+
+	// A transaction marked as a transfer is posted to transfer equity and is not spending
+	var posting models.Posting
+	db.Where("journal_entry_id = ? AND account_id IS NULL", *payment.JournalEntryID).First(&posting)
+	assert.Equal(t, models.LedgerEquityTransfers, posting.LedgerAccount)
+	spent, err := transactionService.transactionRepo.GetTotalSpentByCategory(user.ID, "", "USD",
+		saturday.AddDate(0, 0, -1), monday.AddDate(0, 0, 1), transactionService.currencyService.Rates())
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(49.0, "USD"), spent)
+	assert.Equal(t, "SBUX 123", sbux.Description)
+
+	// Run the weekend rule first over past transactions; a preview saves nothing
+	_, err = service.Reorder(user.ID, &models.ReorderRulesRequest{RuleIDs: []uint{coffee.ID}})
+	assert.EqualError(t, err, "every rule must be listed exactly once")
+	rules, err := service.Reorder(user.ID, &models.ReorderRulesRequest{RuleIDs: []uint{weekend.ID, coffee.ID, cards.ID, rename.ID}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Weekend", rules[0].Name)
+
+	preview, err := service.Run(user.ID, &models.RunRulesRequest{Preview: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, preview.Examined)
+	assert.Equal(t, 1, preview.Changed)
+	assert.Equal(t, latte.ID, preview.Changes[0].TransactionID)
+	assert.Equal(t, []string{"Weekend", "Coffee"}, preview.Changes[0].Rules)
+	assert.Equal(t, []models.RuleFieldChange{{Field: "tags", From: "caffeine", To: "caffeine,weekend"}}, preview.Changes[0].Fields)
+
+	unchanged, err := transactionService.GetByID(latte.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, unchanged.Tags, 1)
+
+	result, err := service.Run(user.ID, &models.RunRulesRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Changed)
+	updated, err := transactionService.GetByID(latte.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, updated.Tags, 2)
+
+	// Rules picked by ID run even when inactive
+	result, err = service.Run(user.ID, &models.RunRulesRequest{RuleIDs: []uint{rename.ID}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Changed)
+	renamed, err := transactionService.GetByID(sbux.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Starbucks", renamed.Description)
+}
+
+func TestRuleService_RunRepostsCategory(t *testing.T) {
+	// Setup - two expenses created before the rule, one of them reconciled
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	transactionService := newTestTransactionService(db)
+	service := NewRuleService(repository.NewRuleRepository(db), repository.NewTagRepository(db),
+		repository.NewTransactionRepository(db), repository.NewAccountRepository(db), transactionService, db)
+
+	var ids []uint
+	for _, amount := range []float64{5.0, 7.0} {
+		transaction, err := transactionService.Create(user.ID, &models.TransactionRequest{
+			Amount: amount, Type: "expense", Category: "Food", Description: "Starbucks", AccountID: account.ID, Date: time.Now(),
+		})
+		assert.NoError(t, err)
+		ids = append(ids, transaction.ID)
+	}
+	reconciledAt := time.Now()
+	db.Model(&models.Transaction{}).Where("id = ?", ids[1]).Update("reconciled_at", &reconciledAt)
+
+	_, err := service.Create(user.ID, &models.RuleRequest{Name: "Coffee", DescriptionContains: "starbucks", SetCategory: "Coffee"})
+	assert.NoError(t, err)
+
+	// Execute
+	result, err := service.Run(user.ID, &models.RunRulesRequest{})
+
+	// Assert - only the open expense changes and its postings follow the new category
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Changed)
+	assert.Equal(t, ids[0], result.Changes[0].TransactionID)
+
+	var changed, reconciled models.Transaction
+	db.First(&changed, ids[0])
+	db.First(&reconciled, ids[1])
+	assert.Equal(t, "Coffee", changed.Category)
+	assert.Equal(t, "Food", reconciled.Category)
+
+	var postings []models.Posting
+	db.Where("journal_entry_id = ?", *changed.JournalEntryID).Order("id").Find(&postings)
+	assert.Len(t, postings, 2)
+	for _, posting := range postings {
+		if posting.AccountID == nil {
+			assert.Equal(t, "expense:Coffee", posting.LedgerAccount)
+			assert.Equal(t, models.NewMoney(5.0, "USD"), posting.Amount)
+		}
+	}
+	var food int64
+	db.Model(&models.Posting{}).Where("ledger_account = ?", "expense:Food").Count(&food)
+	assert.Equal(t, int64(1), food)
+
+	report, err := transactionService.ledgerService.CheckIntegrity(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, report.Balanced)
+	assert.Empty(t, report.AccountMismatches)
+}
+
+// floatPtr returns a pointer to f
+func floatPtr(f float64) *float64 {
+	return &f
+}
@@ -117,7 +117,7 @@ func (s *SearchService) searchTransactions(userID uint, query string, limit int)
 			ID:          t.ID,
 			Title:       t.Description,
 			Description: t.Category + " • " + t.Type,
-			Amount:      t.Amount,
+			Amount:      t.Amount.Float(t.Currency),
 			Date:        t.Date.Format("2006-01-02"),
 			URL:         "/transactions",
 		})
@@ -142,7 +142,7 @@ func (s *SearchService) searchAccounts(userID uint, query string, limit int) ([]
 				ID:          a.ID,
 				Title:       a.Name,
 				Description: a.Type + " Account",
-				Amount:      a.Balance,
+				Amount:      a.Balance.Float(a.Currency),
 				URL:         "/accounts",
 			})
 		}
@@ -170,7 +170,7 @@ func (s *SearchService) searchBudgets(userID uint, query string, limit int) ([]S
 				ID:          b.ID,
 				Title:       b.Name,
 				Description: b.Category + " • " + b.Period,
-				Amount:      b.Amount,
+				Amount:      b.Amount.Float(b.Currency),
 				URL:         "/budgets",
 			})
 		}
@@ -198,7 +198,7 @@ func (s *SearchService) searchGoals(userID uint, query string, limit int) ([]Sea
 				ID:          g.ID,
 				Title:       g.Name,
 				Description: g.Category,
-				Amount:      g.TargetAmount,
+				Amount:      g.TargetAmount.Float(g.Currency),
 				Date:        g.TargetDate.Format("2006-01-02"),
 				URL:         "/goals",
 			})
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// TagService handles business logic for tags
+type TagService struct {
+	tagRepo *repository.TagRepository
+}
+
+// NewTagService creates a new tag service
+func NewTagService(tagRepo *repository.TagRepository) *TagService {
+	return &TagService{
+		tagRepo: tagRepo,
+	}
+}
+
+// GetAll gets all tags of a user with their usage
+func (s *TagService) GetAll(userID uint) ([]*models.TagResponse, error) {
+	tags, err := s.tagRepo.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := s.tagRepo.GetUsage(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.TagResponse, 0, len(tags))
+	for i := range tags {
+		responses = append(responses, tags[i].ToResponse(usage[tags[i].ID]))
+	}
+	return responses, nil
+}
+
+// GetByID gets a tag of a user with its usage
+func (s *TagService) GetByID(id uint, userID uint) (*models.TagResponse, error) {
+	tag, err := s.getTag(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(tag)
+}
+
+// Create creates a new tag
+func (s *TagService) Create(userID uint, req *models.TagRequest) (*models.TagResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("tag name is required")
+	}
+	if err := s.checkNameFree(userID, name, 0); err != nil {
+		return nil, err
+	}
+
+	tag := &models.Tag{
+		UserID: userID,
+		Name:   name,
+		Color:  req.Color,
+	}
+	if err := s.tagRepo.Create(tag); err != nil {
+		return nil, err
+	}
+	return tag.ToResponse(models.TagUsage{}), nil
+}
+
+// Update renames or recolors a tag. Everything tagged with it carries the new name.
+func (s *TagService) Update(id uint, userID uint, req *models.UpdateTagRequest) (*models.TagResponse, error) {
+	tag, err := s.getTag(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errors.New("tag name is required")
+		}
+		if err := s.checkNameFree(userID, name, tag.ID); err != nil {
+			return nil, err
+		}
+		tag.Name = name
+	}
+	if req.Color != nil {
+		tag.Color = *req.Color
+	}
+
+	if err := s.tagRepo.Update(tag); err != nil {
+		return nil, err
+	}
+	return s.toResponse(tag)
+}
+
+// Delete deletes a tag and removes it from everything it is attached to
+func (s *TagService) Delete(id uint, userID uint) error {
+	err := s.tagRepo.Delete(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("tag not found")
+	}
+	return err
+}
+
+// Merge moves everything tagged with the source tags to the target tag and
+// deletes the source tags
+func (s *TagService) Merge(userID uint, req *models.MergeTagsRequest) (*models.TagResponse, error) {
+	target, err := s.getTag(req.TargetID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceIDs := uniqueIDs(req.SourceIDs)
+	for _, id := range sourceIDs {
+		if id == target.ID {
+			return nil, errors.New("cannot merge a tag into itself")
+		}
+		if _, err := s.getTag(id, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.tagRepo.Merge(userID, sourceIDs, target.ID); err != nil {
+		return nil, err
+	}
+	return s.toResponse(target)
+}
+
+// getTag gets a tag of a user, reporting a missing tag as not found
+func (s *TagService) getTag(id uint, userID uint) (*models.Tag, error) {
+	tag, err := s.tagRepo.GetByID(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("tag not found")
+	}
+	return tag, err
+}
+
+// checkNameFree fails when another tag of the user already has the name
+func (s *TagService) checkNameFree(userID uint, name string, id uint) error {
+	existing, err := s.tagRepo.GetByName(userID, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != id {
+		return errors.New("tag already exists")
+	}
+	return nil
+}
+
+// toResponse converts a tag to a response with its current usage
+func (s *TagService) toResponse(tag *models.Tag) (*models.TagResponse, error) {
+	usage, err := s.tagRepo.GetUsage(tag.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return tag.ToResponse(usage[tag.ID]), nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagService_RenameMergeAndFilter(t *testing.T) {
+	// Setup - transactions tagged by name, reusing tags regardless of case
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	transactionService := newTestTransactionService(db)
+	create := func(amount float64, tags ...string) *models.Transaction {
+		transaction, err := transactionService.Create(user.ID, &models.TransactionRequest{
+			Amount: amount, Type: "expense", Category: "Food", AccountID: account.ID, Date: time.Now(), Tags: tags,
+		})
+		assert.NoError(t, err)
+		return transaction
+	}
+	lunch := create(10.0, "Lunch", "work")
+	dinner := create(20.0, "dinner", " Work ", "")
+	create(30.0, "groceries")
+
+	service := NewTagService(repository.NewTagRepository(db))
+	tags, err := service.GetAll(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, tags, 4)
+	assert.Equal(t, "Lunch", tags[0].Name)
+	assert.Equal(t, "dinner", tags[1].Name)
+	assert.Equal(t, "groceries", tags[2].Name)
+	assert.Equal(t, "work", tags[3].Name)
+	assert.Equal(t, int64(2), tags[3].Usage.Transactions)
+	work := tags[3]
+
+	_, err = service.Create(user.ID, &models.TagRequest{Name: "WORK"})
+	assert.EqualError(t, err, "tag already exists")
+
+	// Execute - rename a tag and merge two tags into it
+	renamed, err := service.Update(work.ID, user.ID, &models.UpdateTagRequest{Name: stringPtr("Business"), Color: stringPtr("#3B82F6")})
+	assert.NoError(t, err)
+	assert.Equal(t, "Business", renamed.Name)
+	assert.Equal(t, "#3B82F6", renamed.Color)
+
+	merged, err := service.Merge(user.ID, &models.MergeTagsRequest{SourceIDs: []uint{tags[0].ID, tags[1].ID}, TargetID: work.ID})
+	assert.NoError(t, err)
+
+	// Assert - both transactions carry the renamed tag once and the merged tags are gone
+	assert.Equal(t, int64(2), merged.Usage.Transactions)
+	updated, err := transactionService.GetByID(lunch.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Business"}, updated.ToResponse().Tags)
+
+	_, err = service.GetByID(tags[0].ID, user.ID)
+	assert.EqualError(t, err, "tag not found")
+	_, err = service.Merge(user.ID, &models.MergeTagsRequest{SourceIDs: []uint{work.ID}, TargetID: work.ID})
+	assert.EqualError(t, err, "cannot merge a tag into itself")
+
+	// Filtering by tag goes through the join table and matches every tag given
+	page, err := transactionService.GetPaginated(user.ID, &models.TransactionFilterRequest{Tags: []string{"business"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+
+	page, err = transactionService.GetPaginated(user.ID, &models.TransactionFilterRequest{Tags: []string{"Business", "groceries"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), page.Total)
+
+	// Updating a transaction replaces its tags
+	_, err = transactionService.Update(dinner.ID, user.ID, &models.TransactionRequest{
+		Amount: 20.0, Type: "expense", Category: "Food", AccountID: account.ID, Date: time.Now(), Tags: []string{"groceries"},
+	})
+	assert.NoError(t, err)
+	page, err = transactionService.GetPaginated(user.ID, &models.TransactionFilterRequest{Tags: []string{"groceries"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+
+	// Deleting a tag removes it from its transactions
+	assert.NoError(t, service.Delete(work.ID, user.ID))
+	updated, err = transactionService.GetByID(lunch.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, updated.Tags)
+	assert.EqualError(t, service.Delete(work.ID, user.ID), "tag not found")
+}
+
+func TestTagService_SplitTags(t *testing.T) {
+	// Setup - a receipt with a tagged split line and a transaction with a similar tag
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	transactionService := newTestTransactionService(db)
+	splitRequest := func(tags ...string) *models.TransactionRequest {
+		return &models.TransactionRequest{
+			Amount: 90.0, Type: "expense", Description: "Gas station", AccountID: account.ID, Date: time.Now(),
+			Splits: []models.TransactionSplitRequest{
+				{Amount: 60.0, Category: "Transportation", Tags: tags},
+				{Amount: 30.0, Category: "Food"},
+			},
+		}
+	}
+	receipt, err := transactionService.Create(user.ID, splitRequest("Car"))
+	assert.NoError(t, err)
+	_, err = transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 5.0, Type: "expense", Category: "Transportation", AccountID: account.ID, Date: time.Now(), Tags: []string{"carpool"},
+	})
+	assert.NoError(t, err)
+
+	// Execute - filter by the split tag
+	page, err := transactionService.GetPaginated(user.ID, &models.TransactionFilterRequest{Tags: []string{"car"}})
+
+	// Assert - only the receipt matches, not the tag containing the name
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, receipt.ID, page.Data[0].ID)
+
+	// Renaming the tag shows on the split line
+	service := NewTagService(repository.NewTagRepository(db))
+	car, err := repository.NewTagRepository(db).GetByName(user.ID, "car")
+	assert.NoError(t, err)
+	_, err = service.Update(car.ID, user.ID, &models.UpdateTagRequest{Name: stringPtr("Vehicle")})
+	assert.NoError(t, err)
+	updated, err := transactionService.GetByID(receipt.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Vehicle"}, updated.ToResponse().Splits[0].Tags)
+
+	// Replacing the split lines drops the tags of the old lines
+	_, err = transactionService.Update(receipt.ID, user.ID, splitRequest("fuel"))
+	assert.NoError(t, err)
+	var attached int64
+	db.Table("transaction_split_tags").Count(&attached)
+	assert.Equal(t, int64(1), attached)
+
+	// Deleting a tag removes it from split lines
+	fuel, err := repository.NewTagRepository(db).GetByName(user.ID, "fuel")
+	assert.NoError(t, err)
+	assert.NoError(t, service.Delete(fuel.ID, user.ID))
+	updated, err = transactionService.GetByID(receipt.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, updated.Splits[0].Tags)
+}
+
+// stringPtr returns a pointer to s
+func stringPtr(s string) *string {
+	return &s
+}
@@ -10,13 +10,15 @@ import (
 
 // TaxService handles tax category business logic
 type TaxService struct {
-	taxRepo *repository.TaxRepository
+	taxRepo         *repository.TaxRepository
+	currencyService *CurrencyService
 }
 
 // NewTaxService creates a new tax service
-func NewTaxService(taxRepo *repository.TaxRepository) *TaxService {
+func NewTaxService(taxRepo *repository.TaxRepository, currencyService *CurrencyService) *TaxService {
 	return &TaxService{
-		taxRepo: taxRepo,
+		taxRepo:         taxRepo,
+		currencyService: currencyService,
 	}
 }
 
@@ -108,5 +110,5 @@ func (s *TaxService) GetTaxReport(userID uint, year int) (*models.TaxReportRespo
 		return nil, errors.New("invalid year")
 	}
 
-	return s.taxRepo.GetTaxReport(userID, year)
+	return s.taxRepo.GetTaxReport(userID, year, s.currencyService.Rates())
 }
@@ -2,6 +2,8 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,6 +16,11 @@ import (
 type TransactionService struct {
 	transactionRepo *repository.TransactionRepository
 	accountRepo     *repository.AccountRepository
+	tagRepo         *repository.TagRepository
+	payeeRepo       *repository.PayeeRepository
+	ruleRepo        *repository.RuleRepository
+	ledgerService   *LedgerService
+	currencyService *CurrencyService
 	db              *gorm.DB
 }
 
@@ -21,11 +28,21 @@ type TransactionService struct {
 func NewTransactionService(
 	transactionRepo *repository.TransactionRepository,
 	accountRepo *repository.AccountRepository,
+	tagRepo *repository.TagRepository,
+	payeeRepo *repository.PayeeRepository,
+	ruleRepo *repository.RuleRepository,
+	ledgerService *LedgerService,
+	currencyService *CurrencyService,
 	db *gorm.DB,
 ) *TransactionService {
 	return &TransactionService{
 		transactionRepo: transactionRepo,
 		accountRepo:     accountRepo,
+		tagRepo:         tagRepo,
+		payeeRepo:       payeeRepo,
+		ruleRepo:        ruleRepo,
+		ledgerService:   ledgerService,
+		currencyService: currencyService,
 		db:              db,
 	}
 }
@@ -36,42 +53,84 @@ func (s *TransactionService) Create(userID uint, req *models.TransactionRequest)
 
 	// Start database transaction
 	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the account so concurrent changes to its balance queue behind this one
+		if err := s.ledgerService.LockAccounts(tx, req.AccountID); err != nil {
+			return err
+		}
+
 		// Check if account exists and belongs to user
-		account, err := s.accountRepo.GetByID(req.AccountID, userID)
+		account, err := s.accountRepo.WithTx(tx).GetByID(req.AccountID, userID)
+		if err != nil {
+			return err
+		}
+
+		// Convert amount to minor units of the account currency
+		entered, err := s.convertEnteredAmount(req, account.Currency)
+		if err != nil {
+			return err
+		}
+
+		// Build split lines, which must add up to the amount as entered
+		splits, err := s.buildSplits(tx, userID, entered.original, entered.currency, req.Splits)
+		if err != nil {
+			return err
+		}
+		models.ConvertTransactionSplits(splits, entered.currency, account.Currency, entered.rate, entered.amount)
+
+		tags, err := s.tagRepo.WithTx(tx).FindOrCreate(userID, req.Tags)
+		if err != nil {
+			return err
+		}
+
+		payee, err := s.resolvePayee(tx, userID, req)
 		if err != nil {
 			return err
 		}
 
 		// Create transaction
 		transaction = &models.Transaction{
-			UserID:      userID,
-			Amount:      req.Amount,
-			Description: req.Description,
-			Category:    req.Category,
-			Type:        req.Type,
-			Date:        req.Date,
-			AccountID:   account.ID,
-			Tags:        strings.Join(req.Tags, ","),
+			UserID:           userID,
+			Amount:           entered.amount,
+			Currency:         account.Currency,
+			OriginalAmount:   entered.original,
+			OriginalCurrency: entered.currency,
+			ExchangeRate:     entered.rate,
+			Description:      req.Description,
+			Category:         splitAwareCategory(payeeCategory(req.Category, payee, splits), splits),
+			Type:             req.Type,
+			Date:             req.Date,
+			AccountID:        account.ID,
+			PayeeID:          payeeID(payee),
+			Payee:            payee,
+			Tags:             tags,
+			Splits:           splits,
+			Status:           models.ResolveTransactionStatus(req.Status, req.Date, time.Now()),
 		}
 
-		// Save transaction
-		if err := tx.Create(transaction).Error; err != nil {
+		// Let the user's rules fill in the transaction before it is posted
+		rules, err := s.ruleRepo.WithTx(tx).GetActive(userID)
+		if err != nil {
 			return err
 		}
+		models.ApplyRules(rules, transaction)
 
-		// Update account balance
-		if req.Type == "income" {
-			account.Balance += req.Amount
-		} else {
-			account.Balance -= req.Amount
+		// Scheduled and void transactions are saved without touching the ledger
+		if !transaction.IsPosted() {
+			return tx.Create(transaction).Error
 		}
 
-		// Save account with updated balance
-		if err := tx.Save(account).Error; err != nil {
+		// Record the balanced journal entry
+		if err := s.ledgerService.PostTransaction(tx, transaction, models.JournalEntryTypeTransaction); err != nil {
 			return err
 		}
 
-		return nil
+		// Save transaction
+		if err := tx.Create(transaction).Error; err != nil {
+			return err
+		}
+
+		// Derive account balance from its postings
+		return s.ledgerService.SyncAccountBalance(tx, account, models.NewTransactionBalanceChange(transaction))
 	})
 
 	if err != nil {
@@ -163,74 +222,121 @@ func (s *TransactionService) Update(id uint, userID uint, req *models.Transactio
 
 	// Start database transaction
 	err := s.db.Transaction(func(tx *gorm.DB) error {
-		// Get existing transaction
-		transaction, err := s.transactionRepo.GetByID(id, userID)
+		// Lock the old and new account before either balance changes and get
+		// the existing transaction
+		transaction, err := s.lockTransaction(tx, id, userID, req.AccountID)
 		if err != nil {
 			return err
 		}
 
+		// Reconciled transactions are locked
+		if transaction.IsReconciled() {
+			return models.ErrTransactionReconciled
+		}
+
 		// Check if account exists and belongs to user
-		newAccount, err := s.accountRepo.GetByID(req.AccountID, userID)
+		newAccount, err := s.accountRepo.WithTx(tx).GetByID(req.AccountID, userID)
 		if err != nil {
 			return err
 		}
 
-		// Get old account if different
-		var oldAccount *models.Account
-		if transaction.AccountID != newAccount.ID {
-			oldAccount, err = s.accountRepo.GetByID(transaction.AccountID, userID)
-			if err != nil {
+		// Transfers are a single entry across two accounts and cannot be edited one leg at a time
+		entryType, err := s.postedEntryType(tx, transaction)
+		if err != nil {
+			return err
+		}
+		if entryType == models.JournalEntryTypeTransfer {
+			return errors.New("transfers cannot be edited, delete the transfer and create it again")
+		}
+
+		// Remove the ledger effect of the old transaction
+		oldAccountID := transaction.AccountID
+		if transaction.IsPosted() {
+			if err := s.ledgerService.ReverseTransaction(tx, transaction); err != nil {
 				return err
 			}
+			transaction.JournalEntryID = nil
 		}
 
-		// Calculate balance adjustments
-		if oldAccount != nil {
-			// Reverse the effect of the old transaction on old account
-			if transaction.Type == "income" {
-				oldAccount.Balance -= transaction.Amount
-			} else {
-				oldAccount.Balance += transaction.Amount
-			}
+		// Keep the current status unless a new one is requested; the date decides whether it is scheduled
+		status := req.Status
+		if status == "" {
+			status = transaction.Status
+		}
 
-			// Save old account
-			if err := tx.Save(oldAccount).Error; err != nil {
-				return err
-			}
-		} else {
-			// Same account, reverse old transaction first
-			if transaction.Type == "income" {
-				newAccount.Balance -= transaction.Amount
-			} else {
-				newAccount.Balance += transaction.Amount
-			}
+		// Convert amount to minor units of the new account currency
+		entered, err := s.convertEnteredAmount(req, newAccount.Currency)
+		if err != nil {
+			return err
 		}
 
-		// Apply the new transaction
-		if req.Type == "income" {
-			newAccount.Balance += req.Amount
-		} else {
-			newAccount.Balance -= req.Amount
+		// Replace split lines, which must add up to the amount as entered
+		splits, err := s.buildSplits(tx, userID, entered.original, entered.currency, req.Splits)
+		if err != nil {
+			return err
+		}
+		models.ConvertTransactionSplits(splits, entered.currency, newAccount.Currency, entered.rate, entered.amount)
+		if err := s.transactionRepo.WithTx(tx).DeleteSplits(transaction.ID); err != nil {
+			return err
+		}
+
+		tags, err := s.tagRepo.WithTx(tx).FindOrCreate(userID, req.Tags)
+		if err != nil {
+			return err
 		}
 
-		// Save new account
-		if err := tx.Save(newAccount).Error; err != nil {
+		payee, err := s.resolvePayee(tx, userID, req)
+		if err != nil {
 			return err
 		}
 
 		// Update transaction
-		transaction.Amount = req.Amount
+		transaction.Amount = entered.amount
+		transaction.Currency = newAccount.Currency
+		transaction.OriginalAmount = entered.original
+		transaction.OriginalCurrency = entered.currency
+		transaction.ExchangeRate = entered.rate
 		transaction.Description = req.Description
-		transaction.Category = req.Category
+		transaction.Category = splitAwareCategory(payeeCategory(req.Category, payee, splits), splits)
+		transaction.Splits = splits
 		transaction.Type = req.Type
 		transaction.Date = req.Date
 		transaction.AccountID = newAccount.ID
-		transaction.Tags = strings.Join(req.Tags, ",")
+		transaction.PayeeID = payeeID(payee)
+		transaction.Payee = payee
+		transaction.Tags = tags
+		transaction.Status = models.ResolveTransactionStatus(status, req.Date, time.Now())
+
+		// Record the journal entry for the updated transaction
+		if transaction.IsPosted() {
+			if err := s.ledgerService.PostTransaction(tx, transaction, entryType); err != nil {
+				return err
+			}
+		}
 
 		// Save transaction
 		if err := tx.Save(transaction).Error; err != nil {
 			return err
 		}
+		if err := tx.Model(transaction).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+
+		// Derive balances of the affected accounts from their postings. Edits are
+		// recorded as adjustments so they do not count as new income or expense.
+		change := models.BalanceChange{
+			ChangeType:    models.BalanceChangeAdjustment,
+			TransactionID: &transaction.ID,
+			Description:   "Updated transaction: " + transaction.Description,
+		}
+		if oldAccountID != newAccount.ID {
+			if err := s.ledgerService.SyncAccountBalanceByID(tx, oldAccountID, change); err != nil {
+				return err
+			}
+		}
+		if err := s.ledgerService.SyncAccountBalance(tx, newAccount, change); err != nil {
+			return err
+		}
 
 		updatedTransaction = transaction
 		return nil
@@ -243,41 +349,570 @@ func (s *TransactionService) Update(id uint, userID uint, req *models.Transactio
 	return updatedTransaction, nil
 }
 
-// Delete deletes a transaction atomically
+// Delete moves a transaction to the trash atomically and removes its ledger
+// effect. Trashed transactions keep the ID of their removed journal entry so
+// both legs of a transfer can be restored together.
 func (s *TransactionService) Delete(id uint, userID uint) error {
 	// Start database transaction
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		// Get transaction
-		transaction, err := s.transactionRepo.GetByID(id, userID)
+		transaction, err := s.lockTransaction(tx, id, userID)
 		if err != nil {
 			return err
 		}
 
-		// Get account
-		account, err := s.accountRepo.GetByID(transaction.AccountID, userID)
+		return s.deleteTransaction(tx, transaction)
+	})
+}
+
+// lockTransaction locks the accounts of a transaction and of the other leg
+// of a transfer together with accountIDs, then reads the transaction again
+// with a lock inside tx, so concurrent changes of it run one after another
+// and each sees what the last one left
+func (s *TransactionService) lockTransaction(tx *gorm.DB, id uint, userID uint, accountIDs ...uint) (*models.Transaction, error) {
+	transactionRepo := s.transactionRepo.WithTx(tx)
+	transaction, err := transactionRepo.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.lockTransactionAccounts(tx, transaction, accountIDs...); err != nil {
+		return nil, err
+	}
+	return transactionRepo.GetByIDForUpdate(id, userID)
+}
+
+// lockTransactionAccounts locks the account of a transaction, the accounts
+// of the other legs posted under its journal entry and accountIDs inside tx
+func (s *TransactionService) lockTransactionAccounts(tx *gorm.DB, transaction *models.Transaction, accountIDs ...uint) error {
+	accountIDs = append(accountIDs, transaction.AccountID)
+	if transaction.JournalEntryID != nil {
+		var legAccountIDs []uint
+		if err := tx.Unscoped().Model(&models.Transaction{}).
+			Where("journal_entry_id = ? AND user_id = ?", *transaction.JournalEntryID, transaction.UserID).
+			Pluck("account_id", &legAccountIDs).Error; err != nil {
+			return err
+		}
+		accountIDs = append(accountIDs, legAccountIDs...)
+	}
+	return s.ledgerService.LockAccounts(tx, accountIDs...)
+}
+
+// deleteTransaction moves a transaction to the trash inside tx together with
+// the other leg of a transfer and derives the balances of the affected
+// accounts from their postings
+func (s *TransactionService) deleteTransaction(tx *gorm.DB, transaction *models.Transaction) error {
+	transactions, err := s.trashTransaction(tx, transaction)
+	if err != nil {
+		return err
+	}
+
+	for i := range transactions {
+		t := &transactions[i]
+		change := models.BalanceChange{
+			ChangeType:    models.BalanceChangeAdjustment,
+			TransactionID: &t.ID,
+			Description:   "Deleted transaction: " + t.Description,
+		}
+		if err := s.ledgerService.SyncAccountBalanceByID(tx, t.AccountID, change); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trashTransaction moves a transaction to the trash inside tx and removes
+// its ledger effect without syncing account balances. Both legs of a
+// transfer share one journal entry and are removed together; the removed
+// transactions are returned.
+func (s *TransactionService) trashTransaction(tx *gorm.DB, transaction *models.Transaction) ([]models.Transaction, error) {
+	transactions := []models.Transaction{*transaction}
+	if transaction.JournalEntryID != nil {
+		if err := tx.Where("journal_entry_id = ? AND user_id = ?", *transaction.JournalEntryID, transaction.UserID).
+			Find(&transactions).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// Reconciled transactions are locked
+	for _, t := range transactions {
+		if t.IsReconciled() {
+			return nil, models.ErrTransactionReconciled
+		}
+	}
+
+	// Remove the ledger effect of the transaction
+	if transaction.IsPosted() {
+		if err := s.ledgerService.ReverseTransaction(tx, transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	// Move transactions to the trash, keeping their split lines
+	for _, t := range transactions {
+		if err := tx.Delete(&models.Transaction{}, t.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return transactions, nil
+}
+
+// Restore takes a transaction out of the trash and re-applies its balance
+// effect. Both legs of a trashed transfer are restored together.
+func (s *TransactionService) Restore(id uint, userID uint) (*models.Transaction, error) {
+	var restoredTransaction *models.Transaction
+
+	// Start database transaction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		transactionRepo := s.transactionRepo.WithTx(tx)
+
+		transaction, err := transactionRepo.GetDeletedByID(id, userID)
 		if err != nil {
+			return models.ErrTrashItemNotFound
+		}
+
+		// Lock the accounts and read the transaction again, so a concurrent
+		// restore of it waits and then finds it out of the trash
+		if err := s.lockTransactionAccounts(tx, transaction); err != nil {
 			return err
 		}
+		transaction, err = transactionRepo.GetDeletedByIDForUpdate(id, userID)
+		if err != nil {
+			return models.ErrTrashItemNotFound
+		}
 
-		// Adjust account balance (reverse the transaction)
-		if transaction.Type == "income" {
-			account.Balance -= transaction.Amount
-		} else {
-			account.Balance += transaction.Amount
+		transactions := []models.Transaction{*transaction}
+		if transaction.Type == "transfer" && transaction.JournalEntryID != nil {
+			transactions, err = transactionRepo.GetDeletedByJournalEntryID(*transaction.JournalEntryID, userID)
+			if err != nil {
+				return err
+			}
+		}
+
+		// The accounts have to be restored first
+		for _, t := range transactions {
+			if _, err := s.accountRepo.WithTx(tx).GetByID(t.AccountID, userID); err != nil {
+				return errors.New("account of the transaction is not found, restore it first")
+			}
+		}
+
+		for _, t := range transactions {
+			if err := transactionRepo.Restore(t.ID, userID); err != nil {
+				return err
+			}
+		}
+
+		// Post the transaction to the ledger again under a new journal entry
+		for i := range transactions {
+			transactions[i].JournalEntryID = nil
+		}
+		if transaction.IsPosted() {
+			switch {
+			case len(transactions) == 2:
+				err = s.ledgerService.PostTransfer(tx, &transactions[0], &transactions[1])
+			case transaction.Type == "transfer":
+				err = s.ledgerService.PostTransferLeg(tx, &transactions[0])
+			default:
+				err = s.ledgerService.PostTransaction(tx, &transactions[0], models.JournalEntryTypeTransaction)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for _, t := range transactions {
+			if err := tx.Model(&models.Transaction{}).Where("id = ?", t.ID).
+				Update("journal_entry_id", t.JournalEntryID).Error; err != nil {
+				return err
+			}
+		}
+
+		// Derive balances of the affected accounts from their postings
+		for i := range transactions {
+			t := &transactions[i]
+			change := models.BalanceChange{
+				ChangeType:    models.BalanceChangeAdjustment,
+				TransactionID: &t.ID,
+				Description:   "Restored transaction: " + t.Description,
+			}
+			if err := s.ledgerService.SyncAccountBalanceByID(tx, t.AccountID, change); err != nil {
+				return err
+			}
+			if t.ID == id {
+				restoredTransaction = t
+			}
 		}
 
-		// Save account
-		if err := tx.Save(account).Error; err != nil {
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return restoredTransaction, nil
+}
+
+// Purge permanently deletes a transaction in the trash. Both legs of a
+// trashed transfer are purged together.
+func (s *TransactionService) Purge(id uint, userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.purgeTransaction(tx, id, userID)
+	})
+}
+
+// purgeTransaction permanently deletes a transaction in the trash inside tx
+// together with the other leg of a transfer
+func (s *TransactionService) purgeTransaction(tx *gorm.DB, id uint, userID uint) error {
+	transactionRepo := s.transactionRepo.WithTx(tx)
+
+	transaction, err := transactionRepo.GetDeletedByID(id, userID)
+	if err != nil {
+		return models.ErrTrashItemNotFound
+	}
+
+	transactions := []models.Transaction{*transaction}
+	if transaction.Type == "transfer" && transaction.JournalEntryID != nil {
+		transactions, err = transactionRepo.GetDeletedByJournalEntryID(*transaction.JournalEntryID, userID)
+		if err != nil {
 			return err
 		}
+	}
 
-		// Delete transaction
-		if err := tx.Delete(&models.Transaction{}, id).Error; err != nil {
+	for _, t := range transactions {
+		if err := transactionRepo.Purge(t.ID, userID); err != nil {
 			return err
 		}
+	}
+	return nil
+}
+
+// PurgeAccount permanently deletes an account in the trash together with its
+// trashed transactions and the journal entries still posting to it
+func (s *TransactionService) PurgeAccount(id uint, userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		transactions, err := s.transactionRepo.WithTx(tx).GetDeletedByAccountID(id, userID)
+		if err != nil {
+			return err
+		}
+		for _, t := range transactions {
+			// The other leg of a transfer may already be gone with its first leg
+			if err := s.purgeTransaction(tx, t.ID, userID); err != nil && !errors.Is(err, models.ErrTrashItemNotFound) {
+				return err
+			}
+		}
+
+		return s.accountRepo.WithTx(tx).Purge(id, userID)
+	})
+}
+
+// UpdateStatus moves a transaction through its lifecycle. Posting a
+// scheduled or void transaction records it in the ledger, voiding a posted
+// one reverses it, and clearing only moves money from pending to current.
+// Voiding one leg of a transfer voids both.
+func (s *TransactionService) UpdateStatus(id uint, userID uint, status string) (*models.Transaction, error) {
+	var updatedTransaction *models.Transaction
+
+	// Start database transaction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Get existing transaction
+		transaction, err := s.lockTransaction(tx, id, userID)
+		if err != nil {
+			return err
+		}
+
+		// Reconciled transactions are locked
+		if transaction.IsReconciled() {
+			return models.ErrTransactionReconciled
+		}
+
+		// Future-dated transactions stay scheduled and due ones cannot be scheduled
+		newStatus := models.ResolveTransactionStatus(status, transaction.Date, time.Now())
+		if newStatus != status {
+			return fmt.Errorf("a transaction dated %s cannot be %s", transaction.Date.Format("2006-01-02"), status)
+		}
+		if newStatus == transaction.Status {
+			updatedTransaction = transaction
+			return nil
+		}
+
+		// Voided transfers lose their shared journal entry and cannot be posted again
+		if transaction.Type == "transfer" && !transaction.IsPosted() {
+			return errors.New("voided transfers cannot be restored, create the transfer again")
+		}
 
+		// Voiding a transfer leg voids both legs
+		transactions := []models.Transaction{*transaction}
+		if newStatus == models.TransactionStatusVoid && transaction.JournalEntryID != nil {
+			entryType, err := s.ledgerService.GetEntryType(tx, transaction)
+			if err != nil {
+				return err
+			}
+			if entryType == models.JournalEntryTypeTransfer {
+				if err := tx.Where("journal_entry_id = ? AND user_id = ?", *transaction.JournalEntryID, userID).
+					Find(&transactions).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// Post to or remove from the ledger when crossing between posted and unposted
+		change := models.BalanceChange{
+			ChangeType:    models.BalanceChangeAdjustment,
+			TransactionID: &transaction.ID,
+			Description:   "Transaction " + newStatus + ": " + transaction.Description,
+		}
+		wasPosted := transaction.IsPosted()
+		transaction.Status = newStatus
+		switch {
+		case wasPosted && !transaction.IsPosted():
+			if err := s.ledgerService.ReverseTransaction(tx, transaction); err != nil {
+				return err
+			}
+			transaction.JournalEntryID = nil
+		case !wasPosted && transaction.IsPosted():
+			if err := s.ledgerService.PostTransaction(tx, transaction, models.JournalEntryTypeTransaction); err != nil {
+				return err
+			}
+			change = models.NewTransactionBalanceChange(transaction)
+		}
+
+		// Save the new status of every affected leg
+		for _, t := range transactions {
+			if err := tx.Model(&models.Transaction{}).Where("id = ?", t.ID).Updates(map[string]interface{}{
+				"status":           newStatus,
+				"journal_entry_id": transaction.JournalEntryID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		// Derive balances of the affected accounts from their postings
+		for _, t := range transactions {
+			if err := s.ledgerService.SyncAccountBalanceByID(tx, t.AccountID, change); err != nil {
+				return err
+			}
+		}
+
+		updatedTransaction = transaction
 		return nil
 	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updatedTransaction, nil
+}
+
+// errNoLongerScheduled marks a scheduled transaction that was posted or
+// changed by someone else while it was being posted
+var errNoLongerScheduled = errors.New("transaction is no longer scheduled")
+
+// PostDueScheduled posts every scheduled transaction whose date has arrived
+// and returns how many were posted. Transactions that fail are left scheduled
+// for the next run and reported together.
+func (s *TransactionService) PostDueScheduled() (int, error) {
+	due, err := s.transactionRepo.GetDueScheduled(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	posted := 0
+	var failures []string
+	for i := range due {
+		transaction := &due[i]
+
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			transaction.Status = models.TransactionStatusPending
+			if err := s.ledgerService.PostTransaction(tx, transaction, models.JournalEntryTypeTransaction); err != nil {
+				return err
+			}
+
+			// Only claim the transaction if no one else posted it in the meantime
+			result := tx.Model(&models.Transaction{}).
+				Where("id = ? AND status = ?", transaction.ID, models.TransactionStatusScheduled).
+				Updates(map[string]interface{}{
+					"status":           transaction.Status,
+					"journal_entry_id": transaction.JournalEntryID,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errNoLongerScheduled
+			}
+
+			return s.ledgerService.SyncAccountBalanceByID(tx, transaction.AccountID, models.NewTransactionBalanceChange(transaction))
+		})
+		if errors.Is(err, errNoLongerScheduled) {
+			continue
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("transaction %d: %v", transaction.ID, err))
+			continue
+		}
+
+		posted++
+	}
+
+	if len(failures) > 0 {
+		return posted, fmt.Errorf("scheduled transactions failed: %s", strings.Join(failures, "; "))
+	}
+	return posted, nil
+}
+
+// enteredAmount is a transaction amount as entered and as booked in the account currency
+type enteredAmount struct {
+	original models.Money
+	currency string
+	rate     float64
+	amount   models.Money
+}
+
+// convertEnteredAmount converts a requested amount into the account currency
+// at the requested exchange rate, or at the rate in effect on the transaction
+// date when none is given
+func (s *TransactionService) convertEnteredAmount(req *models.TransactionRequest, accountCurrency string) (*enteredAmount, error) {
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = accountCurrency
+	}
+	if models.GetCurrencyByCode(currency) == nil {
+		return nil, errors.New("unsupported currency")
+	}
+
+	entered := &enteredAmount{
+		original: models.NewMoney(req.Amount, currency),
+		currency: currency,
+		rate:     1,
+	}
+	if currency == accountCurrency {
+		entered.amount = entered.original
+		return entered, nil
+	}
+
+	entered.rate = req.ExchangeRate
+	if entered.rate == 0 {
+		rate, err := s.currencyService.Rate(currency, accountCurrency, req.Date)
+		if err != nil {
+			return nil, err
+		}
+		entered.rate = rate
+	}
+	entered.amount = models.ConvertMoneyWithRate(entered.original, currency, accountCurrency, entered.rate)
+	return entered, nil
+}
+
+// convertTransferAmount returns the amount a transfer puts into the
+// destination account: the amount sent when both accounts share a currency,
+// else the requested amount received, or the amount sent converted at the
+// requested rate or the rate in effect on the transfer date
+func (s *TransactionService) convertTransferAmount(req *models.TransferRequest, amount models.Money, fromCurrency, toCurrency string) (*enteredAmount, error) {
+	received := &enteredAmount{original: amount, currency: fromCurrency, rate: 1, amount: amount}
+	if fromCurrency == toCurrency {
+		return received, nil
+	}
+
+	if req.ToAmount > 0 {
+		received.amount = models.NewMoney(req.ToAmount, toCurrency)
+		received.rate = req.ToAmount / amount.Float(fromCurrency)
+		return received, nil
+	}
+
+	received.rate = req.ExchangeRate
+	if received.rate == 0 {
+		rate, err := s.currencyService.Rate(fromCurrency, toCurrency, req.Date)
+		if err != nil {
+			return nil, err
+		}
+		received.rate = rate
+	}
+	received.amount = models.ConvertMoneyWithRate(amount, fromCurrency, toCurrency, received.rate)
+	return received, nil
+}
+
+// buildSplits converts split requests into split lines, finds or creates
+// their tags and checks that any tax categories on them belong to the user
+func (s *TransactionService) buildSplits(tx *gorm.DB, userID uint, amount models.Money, currency string, reqs []models.TransactionSplitRequest) ([]models.TransactionSplit, error) {
+	splits, err := models.BuildTransactionSplits(userID, amount, currency, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range splits {
+		tags, err := s.tagRepo.WithTx(tx).FindOrCreate(userID, reqs[i].Tags)
+		if err != nil {
+			return nil, err
+		}
+		splits[i].Tags = tags
+
+		if splits[i].TaxCategoryID == nil {
+			continue
+		}
+		if err := checkTaxCategory(tx, userID, *splits[i].TaxCategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	return splits, nil
+}
+
+// checkTaxCategory checks that a tax category belongs to the user
+func checkTaxCategory(tx *gorm.DB, userID uint, taxCategoryID uint) error {
+	var count int64
+	if err := tx.Model(&models.TaxCategory{}).
+		Where("id = ? AND user_id = ?", taxCategoryID, userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("tax category not found")
+	}
+	return nil
+}
+
+// resolvePayee finds the payee of a transaction request: the payee given by
+// ID, the payee given by name, or else the payee matching the description.
+// Transfers only get a payee when one is given.
+func (s *TransactionService) resolvePayee(tx *gorm.DB, userID uint, req *models.TransactionRequest) (*models.Payee, error) {
+	payeeRepo := s.payeeRepo.WithTx(tx)
+	if req.PayeeID != nil {
+		payee, err := payeeRepo.GetByID(*req.PayeeID, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrPayeeNotFound
+		}
+		return payee, err
+	}
+	if req.Type == "transfer" && req.Payee == "" {
+		return nil, nil
+	}
+	return payeeRepo.Resolve(userID, req.Payee, req.Description, req.Category)
+}
+
+// payeeCategory returns the category of a transaction, defaulting to the
+// default category of its payee when neither a category nor split lines are given
+func payeeCategory(category string, payee *models.Payee, splits []models.TransactionSplit) string {
+	if category == "" && len(splits) == 0 && payee != nil {
+		return payee.DefaultCategory
+	}
+	return category
+}
+
+// payeeID returns the ID of a payee, or nil without one
+func payeeID(payee *models.Payee) *uint {
+	if payee == nil {
+		return nil
+	}
+	return &payee.ID
+}
+
+// splitAwareCategory returns the parent category, defaulting to SplitCategory for split transactions
+func splitAwareCategory(category string, splits []models.TransactionSplit) string {
+	if category == "" && len(splits) > 0 {
+		return models.SplitCategory
+	}
+	return category
 }
 
 // GetCategories gets all transaction categories
@@ -300,8 +935,10 @@ func (s *TransactionService) GetCategories() []string {
 	}
 }
 
-// GetSummary gets a summary of transactions for a specific period
-func (s *TransactionService) GetSummary(userID uint, period string) (*models.TransactionSummary, error) {
+// GetSummary gets a summary of transactions for a specific period in the
+// user's preferred currency. Only posted transactions are counted unless
+// statuses are given.
+func (s *TransactionService) GetSummary(userID uint, period string, statuses []string) (*models.TransactionSummary, error) {
 	// Calculate start and end dates based on period
 	now := time.Now()
 	var startDate time.Time
@@ -317,7 +954,12 @@ func (s *TransactionService) GetSummary(userID uint, period string) (*models.Tra
 		startDate = now.AddDate(0, -1, 0)
 	}
 
-	return s.transactionRepo.GetSummary(userID, startDate, now)
+	if len(statuses) == 0 {
+		statuses = models.PostedTransactionStatuses
+	}
+
+	return s.transactionRepo.GetSummary(userID, startDate, now, statuses,
+		s.currencyService.PreferredCurrency(userID), s.currencyService.Rates())
 }
 
 // Transfer handles money transfer between two accounts atomically
@@ -329,25 +971,47 @@ func (s *TransactionService) Transfer(userID uint, req *models.TransferRequest)
 		return nil, errors.New("cannot transfer to the same account")
 	}
 
+	// Both legs post together, so transfers cannot wait for a future date
+	if req.Date.After(time.Now()) {
+		return nil, errors.New("transfers cannot be scheduled for a future date")
+	}
+
 	// Start database transaction for atomic transfer
 	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock both accounts in ID order so opposite transfers cannot deadlock,
+		// then read the balances the transfer is checked against
+		if err := s.ledgerService.LockAccounts(tx, req.FromAccountID, req.ToAccountID); err != nil {
+			return err
+		}
+
 		// Get source account
-		fromAccount, err := s.accountRepo.GetByID(req.FromAccountID, userID)
+		fromAccount, err := s.accountRepo.WithTx(tx).GetByID(req.FromAccountID, userID)
 		if err != nil {
 			return errors.New("source account not found")
 		}
 
 		// Get destination account
-		toAccount, err := s.accountRepo.GetByID(req.ToAccountID, userID)
+		toAccount, err := s.accountRepo.WithTx(tx).GetByID(req.ToAccountID, userID)
 		if err != nil {
 			return errors.New("destination account not found")
 		}
 
+		amount := models.NewMoney(req.Amount, fromAccount.Currency)
+		received, err := s.convertTransferAmount(req, amount, fromAccount.Currency, toAccount.Currency)
+		if err != nil {
+			return err
+		}
+
 		// Check if source account has sufficient balance
-		if fromAccount.Balance < req.Amount {
+		if fromAccount.Balance < amount {
 			return errors.New("insufficient balance in source account")
 		}
 
+		tags, err := s.tagRepo.WithTx(tx).FindOrCreate(userID, req.Tags)
+		if err != nil {
+			return err
+		}
+
 		// Create description if not provided
 		description := req.Description
 		if description == "" {
@@ -357,44 +1021,56 @@ func (s *TransactionService) Transfer(userID uint, req *models.TransferRequest)
 		// Create outgoing transaction (from source account)
 		fromTransaction := &models.Transaction{
 			UserID:      userID,
-			Amount:      req.Amount,
+			Amount:      amount,
+			Currency:    fromAccount.Currency,
 			Description: description,
-			Category:    "Transfer",
+			Category:    models.TransferCategory,
 			Type:        "transfer",
 			Date:        req.Date,
 			AccountID:   fromAccount.ID,
-			Tags:        strings.Join(req.Tags, ","),
-		}
-
-		if err := tx.Create(fromTransaction).Error; err != nil {
-			return errors.New("failed to create outgoing transaction")
+			Tags:        tags,
+			Status:      models.TransactionStatusPending,
 		}
 
-		// Create incoming transaction (to destination account)
+		// Create incoming transaction (to destination account), in the
+		// destination currency when it differs
 		toTransaction := &models.Transaction{
 			UserID:      userID,
-			Amount:      req.Amount,
+			Amount:      received.amount,
+			Currency:    toAccount.Currency,
 			Description: description,
-			Category:    "Transfer",
+			Category:    models.TransferCategory,
 			Type:        "transfer",
 			Date:        req.Date,
 			AccountID:   toAccount.ID,
-			Tags:        strings.Join(req.Tags, ","),
+			Tags:        tags,
+			Status:      models.TransactionStatusPending,
+		}
+		if toAccount.Currency != fromAccount.Currency {
+			toTransaction.OriginalAmount = amount
+			toTransaction.OriginalCurrency = fromAccount.Currency
+			toTransaction.ExchangeRate = received.rate
+		}
+
+		// Record both legs as a single balanced journal entry
+		if err := s.ledgerService.PostTransfer(tx, fromTransaction, toTransaction); err != nil {
+			return errors.New("failed to record transfer in ledger")
+		}
+
+		if err := tx.Create(fromTransaction).Error; err != nil {
+			return errors.New("failed to create outgoing transaction")
 		}
 
 		if err := tx.Create(toTransaction).Error; err != nil {
 			return errors.New("failed to create incoming transaction")
 		}
 
-		// Update account balances
-		fromAccount.Balance -= req.Amount
-		toAccount.Balance += req.Amount
-
-		if err := tx.Save(fromAccount).Error; err != nil {
+		// Derive account balances from their postings
+		if err := s.ledgerService.SyncAccountBalance(tx, fromAccount, models.NewTransactionBalanceChange(fromTransaction)); err != nil {
 			return errors.New("failed to update source account balance")
 		}
 
-		if err := tx.Save(toAccount).Error; err != nil {
+		if err := s.ledgerService.SyncAccountBalance(tx, toAccount, models.NewTransactionBalanceChange(toTransaction)); err != nil {
 			return errors.New("failed to update destination account balance")
 		}
 
@@ -413,3 +1089,323 @@ func (s *TransactionService) Transfer(userID uint, req *models.TransferRequest)
 
 	return response, nil
 }
+
+// errBulkDryRun rolls back a dry run of a bulk action
+var errBulkDryRun = errors.New("bulk dry run")
+
+// Bulk applies one action to a list of transactions or to every transaction
+// matching a filter in a single DB transaction. Transactions the action
+// cannot apply to are reported and skipped; balances of accounts whose
+// transactions moved or were deleted are synced once at the end. A dry run
+// goes through the same steps and rolls them back.
+func (s *TransactionService) Bulk(userID uint, req *models.BulkTransactionRequest) (*models.BulkTransactionResponse, error) {
+	if err := validateBulkRequest(req); err != nil {
+		return nil, err
+	}
+
+	// Resolve the transactions to change
+	ids := uniqueIDs(req.IDs)
+	if req.Filter != nil {
+		var err error
+		if ids, err = s.transactionRepo.GetIDsByFilter(userID, req.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if len(ids) > models.MaxBulkTransactions {
+		return nil, fmt.Errorf("at most %d transactions can be changed at once", models.MaxBulkTransactions)
+	}
+
+	response := &models.BulkTransactionResponse{
+		Action:  req.Action,
+		DryRun:  req.DryRun,
+		Matched: len(ids),
+		Results: make([]models.BulkTransactionResult, 0, len(ids)),
+	}
+	if len(ids) == 0 {
+		return response, nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		response.Results = response.Results[:0]
+
+		// Moves, deletes and new categories change the ledger, so lock every account involved up front
+		var target *models.Account
+		if req.Action == models.BulkActionMove || req.Action == models.BulkActionDelete || req.Action == models.BulkActionRecategorize {
+			accountIDs, err := s.transactionRepo.WithTx(tx).GetAccountIDs(userID, ids)
+			if err != nil {
+				return err
+			}
+			if req.Action == models.BulkActionMove {
+				accountIDs = append(accountIDs, req.AccountID)
+			}
+			if err := s.ledgerService.LockAccounts(tx, accountIDs...); err != nil {
+				return err
+			}
+		}
+		switch req.Action {
+		case models.BulkActionMove:
+			account, err := s.accountRepo.WithTx(tx).GetByID(req.AccountID, userID)
+			if err != nil {
+				return errors.New("account not found")
+			}
+			target = account
+		case models.BulkActionSetTaxCategory:
+			if req.TaxCategoryID != nil {
+				if err := checkTaxCategory(tx, userID, *req.TaxCategoryID); err != nil {
+					return err
+				}
+			}
+		}
+
+		affected := map[uint]bool{}
+		removed := map[uint]bool{}
+		for _, id := range ids {
+			// The other leg of a deleted transfer goes with it
+			if removed[id] {
+				response.Results = append(response.Results, models.BulkTransactionResult{ID: id, Success: true})
+				continue
+			}
+
+			transaction, err := s.transactionRepo.WithTx(tx).GetByID(id, userID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				response.Results = append(response.Results, models.BulkTransactionResult{ID: id, Error: "transaction not found"})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			skip, err := s.applyBulkAction(tx, req, transaction, target, affected, removed)
+			if err != nil {
+				return err
+			}
+			response.Results = append(response.Results, models.BulkTransactionResult{ID: id, Success: skip == "", Error: skip})
+		}
+
+		// Derive balances of the affected accounts from their postings
+		description := "Bulk move of transactions"
+		if req.Action == models.BulkActionDelete {
+			description = "Bulk delete of transactions"
+		}
+		for _, accountID := range sortedIDs(affected) {
+			if err := s.ledgerService.SyncAccountBalanceByID(tx, accountID, models.BalanceChange{
+				ChangeType:  models.BalanceChangeAdjustment,
+				Description: description,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if req.DryRun {
+			return errBulkDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBulkDryRun) {
+		return nil, err
+	}
+
+	for _, result := range response.Results {
+		if result.Success {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+	return response, nil
+}
+
+// applyBulkAction applies a bulk action to one transaction inside tx. It
+// returns why the action does not apply to the transaction, or an error
+// that aborts the whole bulk action. Accounts whose balance changed and
+// transactions removed with a transfer are added to affected and removed.
+func (s *TransactionService) applyBulkAction(tx *gorm.DB, req *models.BulkTransactionRequest, transaction *models.Transaction, target *models.Account, affected, removed map[uint]bool) (string, error) {
+	switch req.Action {
+	case models.BulkActionRecategorize:
+		if len(transaction.Splits) > 0 {
+			return "split transactions are categorized by their split lines", nil
+		}
+		if transaction.IsReconciled() {
+			return models.ErrTransactionReconciled.Error(), nil
+		}
+		entryType, err := s.postedEntryType(tx, transaction)
+		if err != nil {
+			return "", err
+		}
+		if entryType == models.JournalEntryTypeTransfer {
+			return "transfers cannot be recategorized", nil
+		}
+
+		// Repost the transaction against the new category
+		transaction.Category = req.Category
+		if err := s.repostTransaction(tx, transaction, entryType); err != nil {
+			return "", err
+		}
+		return "", tx.Model(transaction).UpdateColumns(map[string]interface{}{
+			"category":         transaction.Category,
+			"journal_entry_id": transaction.JournalEntryID,
+		}).Error
+
+	case models.BulkActionAddTags:
+		tags, err := s.tagRepo.WithTx(tx).FindOrCreate(transaction.UserID, req.Tags)
+		if err != nil {
+			return "", err
+		}
+		return "", tx.Model(transaction).Association("Tags").Append(tags)
+
+	case models.BulkActionRemoveTags:
+		tags, err := s.tagRepo.WithTx(tx).GetByNames(transaction.UserID, models.NormalizeTagNames(req.Tags))
+		if err != nil || len(tags) == 0 {
+			return "", err
+		}
+		return "", tx.Model(transaction).Association("Tags").Delete(tags)
+
+	case models.BulkActionSetTaxCategory:
+		if transaction.IsReconciled() {
+			return models.ErrTransactionReconciled.Error(), nil
+		}
+		entryType, err := s.postedEntryType(tx, transaction)
+		if err != nil {
+			return "", err
+		}
+		if entryType == models.JournalEntryTypeTransfer {
+			return "transfers cannot have a tax category", nil
+		}
+		return "", tx.Model(transaction).UpdateColumn("tax_category_id", req.TaxCategoryID).Error
+
+	case models.BulkActionMove:
+		if transaction.AccountID == target.ID {
+			return "", nil
+		}
+		if transaction.IsReconciled() {
+			return models.ErrTransactionReconciled.Error(), nil
+		}
+		if transaction.Currency != target.Currency {
+			return "cannot move a transaction to an account with a different currency", nil
+		}
+		entryType, err := s.postedEntryType(tx, transaction)
+		if err != nil {
+			return "", err
+		}
+		if entryType == models.JournalEntryTypeTransfer {
+			return "transfers cannot be moved", nil
+		}
+
+		// Repost the transaction against the new account
+		affected[transaction.AccountID] = true
+		affected[target.ID] = true
+		transaction.AccountID = target.ID
+		if err := s.repostTransaction(tx, transaction, entryType); err != nil {
+			return "", err
+		}
+		return "", tx.Model(transaction).UpdateColumns(map[string]interface{}{
+			"account_id":       transaction.AccountID,
+			"journal_entry_id": transaction.JournalEntryID,
+		}).Error
+
+	case models.BulkActionDelete:
+		transactions, err := s.trashTransaction(tx, transaction)
+		if errors.Is(err, models.ErrTransactionReconciled) {
+			return err.Error(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, t := range transactions {
+			affected[t.AccountID] = true
+			removed[t.ID] = true
+		}
+		return "", nil
+	}
+
+	return "", fmt.Errorf("unknown bulk action %q", req.Action)
+}
+
+// postedEntryType returns the type of the journal entry a transaction is
+// posted under, treating both legs of a transfer as a transfer
+func (s *TransactionService) postedEntryType(tx *gorm.DB, transaction *models.Transaction) (string, error) {
+	entryType, err := s.ledgerService.GetEntryType(tx, transaction)
+	if err != nil {
+		return "", err
+	}
+	if transaction.Type == "transfer" {
+		return models.JournalEntryTypeTransfer, nil
+	}
+	if entryType == "" {
+		entryType = models.JournalEntryTypeTransaction
+	}
+	return entryType, nil
+}
+
+// repostTransaction replaces the journal entry of a posted transaction
+// inside tx after its account or category changed
+func (s *TransactionService) repostTransaction(tx *gorm.DB, transaction *models.Transaction, entryType string) error {
+	if !transaction.IsPosted() {
+		return nil
+	}
+	if err := s.ledgerService.ReverseTransaction(tx, transaction); err != nil {
+		return err
+	}
+	transaction.JournalEntryID = nil
+	return s.ledgerService.PostTransaction(tx, transaction, entryType)
+}
+
+// repostCategory reposts a transaction inside tx after its category
+// changed. Transfers post between accounts only and keep their entry.
+func (s *TransactionService) repostCategory(tx *gorm.DB, transaction *models.Transaction) error {
+	entryType, err := s.postedEntryType(tx, transaction)
+	if err != nil {
+		return err
+	}
+	if entryType == models.JournalEntryTypeTransfer {
+		return nil
+	}
+	return s.repostTransaction(tx, transaction, entryType)
+}
+
+// validateBulkRequest checks that a bulk request selects transactions one
+// way and carries what its action needs
+func validateBulkRequest(req *models.BulkTransactionRequest) error {
+	if (len(req.IDs) > 0) == (req.Filter != nil) {
+		return errors.New("either ids or filter is required")
+	}
+
+	switch req.Action {
+	case models.BulkActionRecategorize:
+		if strings.TrimSpace(req.Category) == "" {
+			return errors.New("category is required")
+		}
+	case models.BulkActionAddTags, models.BulkActionRemoveTags:
+		if len(req.Tags) == 0 {
+			return errors.New("tags are required")
+		}
+	case models.BulkActionMove:
+		if req.AccountID == 0 {
+			return errors.New("account_id is required")
+		}
+	}
+	return nil
+}
+
+// uniqueIDs returns ids without repeats, keeping their order
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// sortedIDs returns the keys of a set of IDs in ascending order
+func sortedIDs(set map[uint]bool) []uint {
+	ids := make([]uint, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
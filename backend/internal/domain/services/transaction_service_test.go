@@ -1,25 +1,39 @@
 package services
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/database"
 	"github.com/quocdaijr/finance-management-backend/internal/repository"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// setupTestDB creates an in-memory SQLite database for testing
+// setupTestDB creates a SQLite database for testing in a temporary file,
+// so every pooled connection sees the same schema
 func setupTestDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	return openTestDB(t, sqlite.Open(database.SQLiteDSN(filepath.Join(t.TempDir(), "test.db"))))
+}
+
+// openTestDB opens and migrates a test database
+func openTestDB(t *testing.T, dialector gorm.Dialector) *gorm.DB {
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		t.Fatalf("Failed to connect to test database: %v", err)
 	}
 
 	// Auto-migrate models
-	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Transaction{})
+	err = db.AutoMigrate(testModels()...)
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -27,6 +41,70 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	return db
 }
 
+// runConcurrencyTest runs a test against a SQLite database file shared by
+// concurrent connections and, when TEST_POSTGRES_DSN is set, against a fresh
+// schema of that Postgres database, which locks rows instead of the database
+func runConcurrencyTest(t *testing.T, test func(t *testing.T, db *gorm.DB)) {
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, openTestDB(t, sqlite.Open(database.SQLiteDSN(filepath.Join(t.TempDir(), "concurrency.db")))))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("TEST_POSTGRES_DSN")
+		if dsn == "" {
+			t.Skip("TEST_POSTGRES_DSN is not set")
+		}
+		test(t, openPostgresTestDB(t, dsn))
+	})
+}
+
+// openPostgresTestDB creates a schema of its own in a Postgres database and
+// opens and migrates it as a test database. The schema is dropped afterwards.
+func openPostgresTestDB(t *testing.T, dsn string) *gorm.DB {
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to Postgres: %v", err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	// Point every pooled connection at the schema
+	separator := " "
+	if strings.Contains(dsn, "://") {
+		separator = "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+	}
+	db := openTestDB(t, postgres.Open(dsn+separator+"search_path="+schema))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// testModels returns the models migrated into test databases
+func testModels() []interface{} {
+	return []interface{}{
+		&models.User{}, &models.Account{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Comment{},
+		&models.JournalEntry{}, &models.Posting{}, &models.BalanceHistory{}, &models.ExchangeRate{},
+		&models.Tag{}, &models.Payee{}, &models.PayeeAlias{}, &models.Rule{}, &models.TaxCategory{},
+		&models.DuplicateDismissal{}, &models.ImportProfile{}, &models.Attachment{}, &models.Reconciliation{},
+		&models.CreditCard{}, &models.CreditCardStatement{}, &models.Loan{}, &models.LoanPayment{},
+		&models.Security{}, &models.SecurityPrice{}, &models.InvestmentTransaction{}, &models.Lot{}, &models.RealizedGain{},
+		&models.Notification{}, &models.Budget{}, &models.Goal{}, &models.RecurringTransaction{},
+	}
+}
+
 // createTestUser creates a test user in the database
 func createTestUser(t *testing.T, db *gorm.DB) *models.User {
 	user := &models.User{
@@ -47,26 +125,50 @@ func createTestAccount(t *testing.T, db *gorm.DB, userID uint, balance float64)
 		UserID:      userID,
 		Name:        "Test Account",
 		Type:        "checking",
-		Balance:     balance,
+		Balance:     models.NewMoney(balance, "USD"),
 		Currency:    "USD",
-		Description: "Test account",
 	}
 	result := db.Create(account)
 	if result.Error != nil {
 		t.Fatalf("Failed to create test account: %v", result.Error)
 	}
+
+	// Back the starting balance with an opening balance entry
+	ledgerService := newTestLedgerService(db)
+	if err := ledgerService.PostOpeningBalance(db, account, account.Balance); err != nil {
+		t.Fatalf("Failed to post opening balance: %v", err)
+	}
 	return account
 }
 
+// newTestLedgerService wires a ledger service against the test database
+func newTestLedgerService(db *gorm.DB) *LedgerService {
+	return NewLedgerService(repository.NewLedgerRepository(db), repository.NewAccountRepository(db), repository.NewBalanceHistoryRepository(db))
+}
+
+// newTestCurrencyService wires a currency service without rate providers against the test database
+func newTestCurrencyService(db *gorm.DB) *CurrencyService {
+	return NewCurrencyService(repository.NewUserRepository(db), repository.NewExchangeRateRepository(db))
+}
+
+// newTestTransactionService wires a transaction service against the test database
+func newTestTransactionService(db *gorm.DB) *TransactionService {
+	return newTestTransactionServiceWithCurrency(db, newTestCurrencyService(db))
+}
+
+// newTestTransactionServiceWithCurrency wires a transaction service converting with the given currency service
+func newTestTransactionServiceWithCurrency(db *gorm.DB, currencyService *CurrencyService) *TransactionService {
+	return NewTransactionService(repository.NewTransactionRepository(db), repository.NewAccountRepository(db), repository.NewTagRepository(db), repository.NewPayeeRepository(db), repository.NewRuleRepository(db),
+		newTestLedgerService(db), currencyService, db)
+}
+
 func TestTransactionService_Create(t *testing.T) {
 	// Setup
 	db := setupTestDB(t)
 	user := createTestUser(t, db)
 	account := createTestAccount(t, db, user.ID, 1000.0)
 
-	transactionRepo := repository.NewTransactionRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	service := NewTransactionService(transactionRepo, accountRepo, db)
+	service := newTestTransactionService(db)
 
 	// Test data
 	req := &models.TransactionRequest{
@@ -86,14 +188,14 @@ func TestTransactionService_Create(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, transaction)
 	assert.Equal(t, user.ID, transaction.UserID)
-	assert.Equal(t, float64(100.0), transaction.Amount)
+	assert.Equal(t, models.NewMoney(100.0, "USD"), transaction.Amount)
 	assert.Equal(t, "Food & Dining", transaction.Category)
 	assert.Equal(t, "expense", transaction.Type)
 
 	// Verify account balance was updated
 	var updatedAccount models.Account
 	db.First(&updatedAccount, account.ID)
-	assert.Equal(t, float64(900.0), updatedAccount.Balance) // 1000 - 100
+	assert.Equal(t, models.NewMoney(900.0, "USD"), updatedAccount.Balance) // 1000 - 100
 }
 
 func TestTransactionService_Create_Income(t *testing.T) {
@@ -102,9 +204,7 @@ func TestTransactionService_Create_Income(t *testing.T) {
 	user := createTestUser(t, db)
 	account := createTestAccount(t, db, user.ID, 1000.0)
 
-	transactionRepo := repository.NewTransactionRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	service := NewTransactionService(transactionRepo, accountRepo, db)
+	service := newTestTransactionService(db)
 
 	// Test data - income transaction
 	req := &models.TransactionRequest{
@@ -128,7 +228,232 @@ func TestTransactionService_Create_Income(t *testing.T) {
 	// Verify account balance was updated
 	var updatedAccount models.Account
 	db.First(&updatedAccount, account.ID)
-	assert.Equal(t, float64(1500.0), updatedAccount.Balance) // 1000 + 500
+	assert.Equal(t, models.NewMoney(1500.0, "USD"), updatedAccount.Balance) // 1000 + 500
+}
+
+func TestTransactionService_Create_Split(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := newTestTransactionService(db)
+
+	// Test data - one receipt split across two categories
+	req := &models.TransactionRequest{
+		Amount:      150.0,
+		Description: "Costco",
+		Type:        "expense",
+		Date:        time.Now(),
+		AccountID:   account.ID,
+		Splits: []models.TransactionSplitRequest{
+			{Amount: 100.0, Category: "Food & Dining", Tags: []string{"groceries"}},
+			{Amount: 50.0, Category: "Shopping"},
+		},
+	}
+
+	// Execute
+	transaction, err := service.Create(user.ID, req)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.SplitCategory, transaction.Category)
+	assert.Len(t, transaction.Splits, 2)
+
+	var updatedAccount models.Account
+	db.First(&updatedAccount, account.ID)
+	assert.Equal(t, models.NewMoney(850.0, "USD"), updatedAccount.Balance)
+
+	// Budget spend only counts the split line in the category
+	spent, err := service.transactionRepo.GetTotalSpentByCategory(user.ID, "Shopping", "USD",
+		time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 1), service.currencyService.Rates())
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(50.0, "USD"), spent)
+}
+
+func TestTransactionService_Create_SplitSumMismatch(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := newTestTransactionService(db)
+
+	// Test data - lines do not add up to the amount
+	req := &models.TransactionRequest{
+		Amount:    150.0,
+		Type:      "expense",
+		Date:      time.Now(),
+		AccountID: account.ID,
+		Splits: []models.TransactionSplitRequest{
+			{Amount: 100.0, Category: "Food & Dining"},
+			{Amount: 40.0, Category: "Shopping"},
+		},
+	}
+
+	// Execute
+	transaction, err := service.Create(user.ID, req)
+
+	// Assert
+	assert.ErrorIs(t, err, models.ErrSplitSumMismatch)
+	assert.Nil(t, transaction)
+}
+
+func TestTransactionService_Create_ForeignCurrency(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := newTestTransactionService(db)
+
+	// Test data - a EUR purchase on a USD account at the rate charged by the card
+	req := &models.TransactionRequest{
+		Amount:       50.0,
+		Currency:     "EUR",
+		ExchangeRate: 1.1,
+		Description:  "Paris cafe",
+		Type:         "expense",
+		Date:         time.Now(),
+		AccountID:    account.ID,
+		Splits: []models.TransactionSplitRequest{
+			{Amount: 33.33, Category: "Food & Dining"},
+			{Amount: 16.67, Category: "Travel"},
+		},
+	}
+
+	// Execute
+	transaction, err := service.Create(user.ID, req)
+
+	// Assert - the original amount is kept and the account is charged the converted amount
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(55.0, "USD"), transaction.Amount)
+	assert.Equal(t, "USD", transaction.Currency)
+	assert.Equal(t, models.NewMoney(50.0, "EUR"), transaction.OriginalAmount)
+	assert.Equal(t, "EUR", transaction.OriginalCurrency)
+	assert.Equal(t, 1.1, transaction.ExchangeRate)
+	assert.True(t, transaction.IsForeignCurrency())
+
+	// Split lines are converted and still add up to the converted amount
+	assert.Equal(t, models.NewMoney(36.66, "USD"), transaction.Splits[0].Amount)
+	assert.Equal(t, models.NewMoney(18.34, "USD"), transaction.Splits[1].Amount)
+
+	var updatedAccount models.Account
+	db.First(&updatedAccount, account.ID)
+	assert.Equal(t, models.NewMoney(945.0, "USD"), updatedAccount.Balance)
+
+	// Without a rate the one in effect on the transaction date is used
+	transaction, err = service.Create(user.ID, &models.TransactionRequest{
+		Amount: 50.0, Currency: "EUR", Type: "expense", Category: "Travel", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(54.35, "USD"), transaction.Amount)
+
+	// Transactions in the account currency record themselves as the original amount
+	transaction, err = service.Create(user.ID, &models.TransactionRequest{
+		Amount: 10.0, Type: "expense", Category: "Travel", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, transaction.Amount, transaction.OriginalAmount)
+	assert.Equal(t, "USD", transaction.OriginalCurrency)
+	assert.Equal(t, 1.0, transaction.ExchangeRate)
+
+	_, err = service.Create(user.ID, &models.TransactionRequest{
+		Amount: 10.0, Currency: "XYZ", Type: "expense", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.Error(t, err)
+}
+
+func TestTransactionService_RawModelJSON(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := newTestTransactionService(db)
+	transaction, err := service.Create(user.ID, &models.TransactionRequest{
+		Amount: 50.0, Currency: "EUR", ExchangeRate: 1.1, Type: "expense", Category: "Travel", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+	db.First(account, account.ID)
+
+	// Execute
+	var transactionJSON, accountJSON map[string]interface{}
+	data, err := json.Marshal(transaction)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &transactionJSON))
+	data, err = json.Marshal(account)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &accountJSON))
+
+	// Assert - raw models keep their major unit amounts under the original names
+	assert.Equal(t, 55.0, transactionJSON["amount"])
+	assert.Equal(t, 50.0, transactionJSON["original_amount"])
+	assert.NotContains(t, transactionJSON, "amount_minor")
+	assert.Equal(t, "Travel", transactionJSON["category"])
+	assert.Equal(t, 945.0, accountJSON["balance"])
+	assert.NotContains(t, accountJSON, "balance_minor")
+}
+
+func TestTransactionService_GetSummary_PreferredCurrency(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	db.Model(user).Update("preferred_currency", "EUR")
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := newTestTransactionService(db)
+
+	_, err := service.Create(user.ID, &models.TransactionRequest{
+		Amount: 100.0, Type: "income", Category: "Income", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+
+	// Execute
+	summary, err := service.GetSummary(user.ID, "month", nil)
+
+	// Assert - USD income is reported in EUR
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR", summary.Currency)
+	assert.Equal(t, 92.0, summary.Income)
+	assert.Equal(t, 92.0, summary.Balance)
+}
+
+func TestTransactionService_RecordsBalanceHistory(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := newTestTransactionService(db)
+
+	req := &models.TransactionRequest{
+		Amount:      100.0,
+		Description: "Grocery shopping",
+		Category:    "Food & Dining",
+		Type:        "expense",
+		Date:        time.Now(),
+		AccountID:   account.ID,
+	}
+
+	// Execute
+	transaction, err := service.Create(user.ID, req)
+	assert.NoError(t, err)
+	assert.NoError(t, service.Delete(transaction.ID, user.ID))
+
+	// Assert
+	var history []models.BalanceHistory
+	db.Where("account_id = ?", account.ID).Order("id").Find(&history)
+	assert.Len(t, history, 2)
+
+	assert.Equal(t, models.BalanceChangeExpense, history[0].ChangeType)
+	assert.Equal(t, models.NewMoney(-100.0, "USD"), history[0].ChangeAmount)
+	assert.Equal(t, models.NewMoney(900.0, "USD"), history[0].Balance)
+	assert.Equal(t, transaction.ID, *history[0].TransactionID)
+
+	assert.Equal(t, models.BalanceChangeAdjustment, history[1].ChangeType)
+	assert.Equal(t, models.NewMoney(100.0, "USD"), history[1].ChangeAmount)
+	assert.Equal(t, models.NewMoney(1000.0, "USD"), history[1].Balance)
 }
 
 func TestTransactionService_Create_AccountNotFound(t *testing.T) {
@@ -136,9 +461,7 @@ func TestTransactionService_Create_AccountNotFound(t *testing.T) {
 	db := setupTestDB(t)
 	user := createTestUser(t, db)
 
-	transactionRepo := repository.NewTransactionRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	service := NewTransactionService(transactionRepo, accountRepo, db)
+	service := newTestTransactionService(db)
 
 	// Test data with non-existent account
 	req := &models.TransactionRequest{
@@ -165,9 +488,7 @@ func TestTransactionService_GetByID(t *testing.T) {
 	user := createTestUser(t, db)
 	account := createTestAccount(t, db, user.ID, 1000.0)
 
-	transactionRepo := repository.NewTransactionRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	service := NewTransactionService(transactionRepo, accountRepo, db)
+	service := newTestTransactionService(db)
 
 	// Create a transaction
 	req := &models.TransactionRequest{
@@ -197,9 +518,7 @@ func TestTransactionService_GetAll(t *testing.T) {
 	user := createTestUser(t, db)
 	account := createTestAccount(t, db, user.ID, 1000.0)
 
-	transactionRepo := repository.NewTransactionRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	service := NewTransactionService(transactionRepo, accountRepo, db)
+	service := newTestTransactionService(db)
 
 	// Create multiple transactions
 	for i := 0; i < 3; i++ {
@@ -229,9 +548,7 @@ func TestTransactionService_Update(t *testing.T) {
 	user := createTestUser(t, db)
 	account := createTestAccount(t, db, user.ID, 1000.0)
 
-	transactionRepo := repository.NewTransactionRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	service := NewTransactionService(transactionRepo, accountRepo, db)
+	service := newTestTransactionService(db)
 
 	// Create initial transaction
 	req := &models.TransactionRequest{
@@ -262,7 +579,7 @@ func TestTransactionService_Update(t *testing.T) {
 	// Assert
 	assert.NoError(t, err)
 	assert.NotNil(t, updatedTransaction)
-	assert.Equal(t, float64(150.0), updatedTransaction.Amount)
+	assert.Equal(t, models.NewMoney(150.0, "USD"), updatedTransaction.Amount)
 	assert.Equal(t, "Updated transaction", updatedTransaction.Description)
 	assert.Equal(t, "Shopping", updatedTransaction.Category)
 
@@ -272,7 +589,7 @@ func TestTransactionService_Update(t *testing.T) {
 	// Original: 1000 - 100 = 900
 	// Update reverses: 900 + 100 = 1000
 	// Apply new: 1000 - 150 = 850
-	assert.Equal(t, float64(850.0), updatedAccount.Balance)
+	assert.Equal(t, models.NewMoney(850.0, "USD"), updatedAccount.Balance)
 }
 
 func TestTransactionService_Delete(t *testing.T) {
@@ -281,9 +598,7 @@ func TestTransactionService_Delete(t *testing.T) {
 	user := createTestUser(t, db)
 	account := createTestAccount(t, db, user.ID, 1000.0)
 
-	transactionRepo := repository.NewTransactionRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	service := NewTransactionService(transactionRepo, accountRepo, db)
+	service := newTestTransactionService(db)
 
 	// Create transaction
 	req := &models.TransactionRequest{
@@ -312,7 +627,7 @@ func TestTransactionService_Delete(t *testing.T) {
 	// Verify account balance was restored
 	var updatedAccount models.Account
 	db.First(&updatedAccount, account.ID)
-	assert.Equal(t, float64(1000.0), updatedAccount.Balance) // Back to original
+	assert.Equal(t, models.NewMoney(1000.0, "USD"), updatedAccount.Balance) // Back to original
 }
 
 func TestTransactionService_Transfer(t *testing.T) {
@@ -322,9 +637,7 @@ func TestTransactionService_Transfer(t *testing.T) {
 	fromAccount := createTestAccount(t, db, user.ID, 1000.0)
 	toAccount := createTestAccount(t, db, user.ID, 500.0)
 
-	transactionRepo := repository.NewTransactionRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	service := NewTransactionService(transactionRepo, accountRepo, db)
+	service := newTestTransactionService(db)
 
 	// Test data
 	req := &models.TransferRequest{
@@ -352,8 +665,114 @@ func TestTransactionService_Transfer(t *testing.T) {
 	db.First(&updatedFromAccount, fromAccount.ID)
 	db.First(&updatedToAccount, toAccount.ID)
 
-	assert.Equal(t, float64(800.0), updatedFromAccount.Balance)  // 1000 - 200
-	assert.Equal(t, float64(700.0), updatedToAccount.Balance)    // 500 + 200
+	assert.Equal(t, models.NewMoney(800.0, "USD"), updatedFromAccount.Balance)  // 1000 - 200
+	assert.Equal(t, models.NewMoney(700.0, "USD"), updatedToAccount.Balance)    // 500 + 200
+
+	// Verify both legs share one balanced journal entry
+	assert.NotNil(t, response.FromTransaction.JournalEntryID)
+	assert.Equal(t, response.FromTransaction.JournalEntryID, response.ToTransaction.JournalEntryID)
+
+	report, err := service.ledgerService.CheckIntegrity(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, report.Balanced)
+	assert.Empty(t, report.AccountMismatches)
+}
+
+func TestTransactionService_Delete_TransferLeg(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	fromAccount := createTestAccount(t, db, user.ID, 1000.0)
+	toAccount := createTestAccount(t, db, user.ID, 500.0)
+
+	service := newTestTransactionService(db)
+
+	response, err := service.Transfer(user.ID, &models.TransferRequest{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        200.0,
+		Date:          time.Now(),
+	})
+	assert.NoError(t, err)
+
+	// Execute - deleting the incoming leg removes the whole transfer
+	err = service.Delete(response.ToTransaction.ID, user.ID)
+
+	// Assert
+	assert.NoError(t, err)
+
+	var count int64
+	db.Model(&models.Transaction{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	var updatedFromAccount models.Account
+	var updatedToAccount models.Account
+	db.First(&updatedFromAccount, fromAccount.ID)
+	db.First(&updatedToAccount, toAccount.ID)
+	assert.Equal(t, models.NewMoney(1000.0, "USD"), updatedFromAccount.Balance)
+	assert.Equal(t, models.NewMoney(500.0, "USD"), updatedToAccount.Balance)
+
+	report, err := service.ledgerService.CheckIntegrity(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, report.Balanced)
+}
+
+func TestTransactionService_DeleteRestore_LegacyTransferLegs(t *testing.T) {
+	// Setup - a transfer recorded before the ledger, part of the opening balances
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	fromAccount := createTestAccount(t, db, user.ID, 800.0)
+	toAccount := createTestAccount(t, db, user.ID, 700.0)
+
+	service := newTestTransactionService(db)
+
+	legs := []*models.Transaction{
+		{UserID: user.ID, AccountID: fromAccount.ID, Amount: 20000, Currency: "USD", Type: "transfer",
+			Category: "Transfer", Description: "Old transfer", Date: time.Now(), Status: models.TransactionStatusCleared},
+		{UserID: user.ID, AccountID: toAccount.ID, Amount: 20000, Currency: "USD", Type: "transfer",
+			Category: "Transfer", Description: "Old transfer", Date: time.Now(), Status: models.TransactionStatusCleared},
+	}
+	for _, leg := range legs {
+		assert.NoError(t, db.Create(leg).Error)
+	}
+
+	balances := func() (models.Money, models.Money) {
+		var from, to models.Account
+		db.First(&from, fromAccount.ID)
+		db.First(&to, toAccount.ID)
+		return from.Balance, to.Balance
+	}
+
+	// Execute - trashing the incoming leg takes the money out of its account
+	assert.NoError(t, service.Delete(legs[1].ID, user.ID))
+	from, to := balances()
+	assert.Equal(t, models.NewMoney(800.0, "USD"), from)
+	assert.Equal(t, models.NewMoney(500.0, "USD"), to)
+
+	// Trashing the outgoing leg puts the money back into its account
+	assert.NoError(t, service.Delete(legs[0].ID, user.ID))
+	from, to = balances()
+	assert.Equal(t, models.NewMoney(1000.0, "USD"), from)
+	assert.Equal(t, models.NewMoney(500.0, "USD"), to)
+
+	// Restoring each leg posts it on the side of the transfer it was on
+	_, err := service.Restore(legs[1].ID, user.ID)
+	assert.NoError(t, err)
+	_, err = service.Restore(legs[0].ID, user.ID)
+	assert.NoError(t, err)
+	from, to = balances()
+	assert.Equal(t, models.NewMoney(800.0, "USD"), from)
+	assert.Equal(t, models.NewMoney(700.0, "USD"), to)
+
+	// Assert - nothing was posted as spending
+	var expenses int64
+	db.Model(&models.Posting{}).Where("ledger_account LIKE ?", "expense:%").Count(&expenses)
+	assert.Equal(t, int64(0), expenses)
+
+	report, err := service.ledgerService.CheckIntegrity(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, report.Balanced)
+	assert.Empty(t, report.AccountMismatches)
 }
 
 func TestTransactionService_Transfer_SameAccount(t *testing.T) {
@@ -362,9 +781,7 @@ func TestTransactionService_Transfer_SameAccount(t *testing.T) {
 	user := createTestUser(t, db)
 	account := createTestAccount(t, db, user.ID, 1000.0)
 
-	transactionRepo := repository.NewTransactionRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	service := NewTransactionService(transactionRepo, accountRepo, db)
+	service := newTestTransactionService(db)
 
 	// Test data - same account
 	req := &models.TransferRequest{
@@ -385,6 +802,56 @@ func TestTransactionService_Transfer_SameAccount(t *testing.T) {
 	assert.Contains(t, err.Error(), "cannot transfer to the same account")
 }
 
+func TestTransactionService_Transfer_CrossCurrency(t *testing.T) {
+	// Setup - a USD account and an empty EUR account
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	checking := createTestAccount(t, db, user.ID, 1000.0)
+	euros := &models.Account{UserID: user.ID, Name: "Euro Account", Type: "savings", Currency: "EUR"}
+	assert.NoError(t, db.Create(euros).Error)
+
+	service := newTestTransactionService(db)
+
+	// Execute - one transfer with the amount received, one at a rate
+	response, err := service.Transfer(user.ID, &models.TransferRequest{
+		FromAccountID: checking.ID, ToAccountID: euros.ID, Amount: 100.0, ToAmount: 92.0, Date: time.Now(),
+	})
+	assert.NoError(t, err)
+	_, err = service.Transfer(user.ID, &models.TransferRequest{
+		FromAccountID: checking.ID, ToAccountID: euros.ID, Amount: 10.0, ExchangeRate: 0.9, Date: time.Now(),
+	})
+	assert.NoError(t, err)
+
+	// Assert - each leg is booked in the currency of its account
+	assert.Equal(t, "USD", response.FromTransaction.Currency)
+	assert.Equal(t, 100.0, response.FromTransaction.Amount)
+	assert.Equal(t, "EUR", response.ToTransaction.Currency)
+	assert.Equal(t, 92.0, response.ToTransaction.Amount)
+	assert.Equal(t, 0.92, response.ToTransaction.ExchangeRate)
+
+	db.First(checking, checking.ID)
+	db.First(euros, euros.ID)
+	assert.Equal(t, models.NewMoney(890.0, "USD"), checking.Balance)
+	assert.Equal(t, models.NewMoney(101.0, "EUR"), euros.Balance)
+
+	// Both currencies balance against conversion equity
+	var conversions []models.Posting
+	db.Where("ledger_account = ?", models.LedgerEquityConversions).Order("id").Find(&conversions)
+	assert.Len(t, conversions, 4)
+	report, err := service.ledgerService.CheckIntegrity(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, report.Balanced)
+
+	// Without a rate the one in effect on the transfer date is used
+	rate, err := newTestCurrencyService(db).Rate("USD", "EUR", time.Now())
+	assert.NoError(t, err)
+	response, err = service.Transfer(user.ID, &models.TransferRequest{
+		FromAccountID: checking.ID, ToAccountID: euros.ID, Amount: 10.0, Date: time.Now(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, rate, response.ToTransaction.ExchangeRate)
+}
+
 func TestTransactionService_Transfer_InsufficientBalance(t *testing.T) {
 	// Setup
 	db := setupTestDB(t)
@@ -392,9 +859,7 @@ func TestTransactionService_Transfer_InsufficientBalance(t *testing.T) {
 	fromAccount := createTestAccount(t, db, user.ID, 100.0)
 	toAccount := createTestAccount(t, db, user.ID, 500.0)
 
-	transactionRepo := repository.NewTransactionRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	service := NewTransactionService(transactionRepo, accountRepo, db)
+	service := newTestTransactionService(db)
 
 	// Test data - insufficient balance
 	req := &models.TransferRequest{
@@ -415,12 +880,246 @@ func TestTransactionService_Transfer_InsufficientBalance(t *testing.T) {
 	assert.Contains(t, err.Error(), "insufficient balance")
 }
 
+func TestTransactionService_ConcurrentBalanceUpdates(t *testing.T) {
+	runConcurrencyTest(t, func(t *testing.T, db *gorm.DB) {
+		// Setup - balances changed by concurrent connections
+		user := createTestUser(t, db)
+		checking := createTestAccount(t, db, user.ID, 1000.0)
+		savings := createTestAccount(t, db, user.ID, 1000.0)
+
+		service := newTestTransactionService(db)
+
+		// Execute - expenses, incomes and transfers in both directions at once
+		const workers = 20
+		const rounds = 5
+		var wg sync.WaitGroup
+		errs := make(chan error, workers*rounds*3)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for r := 0; r < rounds; r++ {
+					_, err := service.Create(user.ID, &models.TransactionRequest{
+						Amount: 5.0, Type: "expense", Category: "Food", AccountID: checking.ID, Date: time.Now(),
+					})
+					errs <- err
+					_, err = service.Create(user.ID, &models.TransactionRequest{
+						Amount: 1.5, Type: "income", Category: "Interest", AccountID: savings.ID, Date: time.Now(),
+					})
+					errs <- err
+					from, to := checking.ID, savings.ID
+					if r%2 == 1 {
+						from, to = to, from
+					}
+					_, err = service.Transfer(user.ID, &models.TransferRequest{
+						Amount: 3.0, FromAccountID: from, ToAccountID: to, Date: time.Now(),
+					})
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		// Assert - every change is reflected in the stored balances
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+
+		var updatedChecking, updatedSavings models.Account
+		db.First(&updatedChecking, checking.ID)
+		db.First(&updatedSavings, savings.ID)
+		assert.Equal(t, models.NewMoney(1000.0-100*5.0-20*3.0, "USD"), updatedChecking.Balance)
+		assert.Equal(t, models.NewMoney(1000.0+100*1.5+20*3.0, "USD"), updatedSavings.Balance)
+
+		var count int64
+		db.Model(&models.Transaction{}).Count(&count)
+		assert.Equal(t, int64(workers*rounds*4), count)
+
+		report, err := service.ledgerService.CheckIntegrity(user.ID)
+		assert.NoError(t, err)
+		assert.True(t, report.Balanced)
+		assert.Empty(t, report.AccountMismatches)
+	})
+}
+
+func TestTransactionService_ConcurrentUpdates(t *testing.T) {
+	runConcurrencyTest(t, func(t *testing.T, db *gorm.DB) {
+		// Setup - one transaction edited by concurrent connections
+		user := createTestUser(t, db)
+		checking := createTestAccount(t, db, user.ID, 1000.0)
+		savings := createTestAccount(t, db, user.ID, 1000.0)
+
+		service := newTestTransactionService(db)
+		transaction, err := service.Create(user.ID, &models.TransactionRequest{
+			Amount: 10.0, Type: "expense", Category: "Food", AccountID: checking.ID, Date: time.Now(),
+		})
+		assert.NoError(t, err)
+
+		// Execute - every worker moves it between the accounts with its own amount
+		const workers = 20
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				accountID := checking.ID
+				if w%2 == 1 {
+					accountID = savings.ID
+				}
+				_, err := service.Update(transaction.ID, user.ID, &models.TransactionRequest{
+					Amount: float64(w + 1), Type: "expense", Category: "Food", AccountID: accountID, Date: time.Now(),
+				})
+				errs <- err
+			}(w)
+		}
+		wg.Wait()
+		close(errs)
+
+		// Assert - the last edit alone is posted and both balances agree with it
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+
+		var updated models.Transaction
+		db.First(&updated, transaction.ID)
+		var entries int64
+		db.Model(&models.JournalEntry{}).Where("type = ?", models.JournalEntryTypeTransaction).Count(&entries)
+		assert.Equal(t, int64(1), entries)
+
+		var updatedChecking, updatedSavings models.Account
+		db.First(&updatedChecking, checking.ID)
+		db.First(&updatedSavings, savings.ID)
+		spent := models.NewMoney(1000.0, "USD") - updatedChecking.Balance + models.NewMoney(1000.0, "USD") - updatedSavings.Balance
+		assert.Equal(t, updated.Amount, spent)
+
+		report, err := service.ledgerService.CheckIntegrity(user.ID)
+		assert.NoError(t, err)
+		assert.True(t, report.Balanced)
+		assert.Empty(t, report.AccountMismatches)
+	})
+}
+
+func TestTransactionService_Bulk(t *testing.T) {
+	// Setup - three imported expenses and a transfer
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	checking := createTestAccount(t, db, user.ID, 1000.0)
+	savings := createTestAccount(t, db, user.ID, 500.0)
+
+	service := newTestTransactionService(db)
+	var ids []uint
+	for _, amount := range []float64{10.0, 20.0, 30.0} {
+		transaction, err := service.Create(user.ID, &models.TransactionRequest{
+			Amount: amount, Type: "expense", Category: "Uncategorized", AccountID: checking.ID, Date: time.Now(), Tags: []string{"imported"},
+		})
+		assert.NoError(t, err)
+		ids = append(ids, transaction.ID)
+	}
+	transfer, err := service.Transfer(user.ID, &models.TransferRequest{
+		Amount: 100.0, FromAccountID: checking.ID, ToAccountID: savings.ID, Date: time.Now(),
+	})
+	assert.NoError(t, err)
+
+	// Execute - recategorize by filter and retag by ID
+	response, err := service.Bulk(user.ID, &models.BulkTransactionRequest{
+		Action: models.BulkActionRecategorize, Category: "Groceries",
+		Filter: &models.TransactionFilterRequest{Category: "Uncategorized"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, response.Matched)
+	assert.Equal(t, 3, response.Succeeded)
+
+	_, err = service.Bulk(user.ID, &models.BulkTransactionRequest{
+		Action: models.BulkActionAddTags, IDs: ids[:2], Tags: []string{"reviewed", "imported"},
+	})
+	assert.NoError(t, err)
+	_, err = service.Bulk(user.ID, &models.BulkTransactionRequest{
+		Action: models.BulkActionRemoveTags, IDs: ids, Tags: []string{"imported"},
+	})
+	assert.NoError(t, err)
+
+	var first models.Transaction
+	db.Preload("Tags").First(&first, ids[0])
+	assert.Equal(t, "Groceries", first.Category)
+	assert.Equal(t, []string{"reviewed"}, models.TagNames(first.Tags))
+
+	// Recategorized expenses are posted to the new category
+	var groceries, uncategorized int64
+	db.Model(&models.Posting{}).Where("ledger_account = ?", "expense:Groceries").Count(&groceries)
+	db.Model(&models.Posting{}).Where("ledger_account = ?", "expense:Uncategorized").Count(&uncategorized)
+	assert.Equal(t, int64(3), groceries)
+	assert.Equal(t, int64(0), uncategorized)
+
+	// Transfers and reconciled transactions keep their category and tax category
+	reconciledAt := time.Now()
+	db.Model(&models.Transaction{}).Where("id = ?", ids[2]).Update("reconciled_at", &reconciledAt)
+	for _, action := range []string{models.BulkActionRecategorize, models.BulkActionSetTaxCategory} {
+		response, err = service.Bulk(user.ID, &models.BulkTransactionRequest{
+			Action: action, Category: "Shopping", IDs: []uint{transfer.FromTransaction.ID, ids[2]},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, response.Failed)
+		assert.Equal(t, models.ErrTransactionReconciled.Error(), response.Results[1].Error)
+	}
+	assert.Equal(t, "transfers cannot have a tax category", response.Results[0].Error)
+	db.Model(&models.Transaction{}).Where("id = ?", ids[2]).Update("reconciled_at", nil)
+
+	// A dry run reports what would move without changing anything
+	move := &models.BulkTransactionRequest{
+		Action: models.BulkActionMove, AccountID: savings.ID, DryRun: true,
+		IDs: []uint{ids[0], ids[1], transfer.FromTransaction.ID, 9999},
+	}
+	response, err = service.Bulk(user.ID, move)
+	assert.NoError(t, err)
+	assert.True(t, response.DryRun)
+	assert.Equal(t, 4, response.Matched)
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Equal(t, "transfers cannot be moved", response.Results[2].Error)
+	assert.Equal(t, "transaction not found", response.Results[3].Error)
+
+	var updatedChecking, updatedSavings models.Account
+	db.First(&updatedChecking, checking.ID)
+	assert.Equal(t, models.NewMoney(840.0, "USD"), updatedChecking.Balance)
+
+	// Moving reposts the expenses against the other account
+	move.DryRun = false
+	response, err = service.Bulk(user.ID, move)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Equal(t, 2, response.Failed)
+
+	db.First(&updatedChecking, checking.ID)
+	db.First(&updatedSavings, savings.ID)
+	assert.Equal(t, models.NewMoney(870.0, "USD"), updatedChecking.Balance)
+	assert.Equal(t, models.NewMoney(570.0, "USD"), updatedSavings.Balance)
+
+	// Deleting one leg of a transfer removes both
+	response, err = service.Bulk(user.ID, &models.BulkTransactionRequest{
+		Action: models.BulkActionDelete, IDs: []uint{transfer.ToTransaction.ID, transfer.FromTransaction.ID, ids[2]},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, response.Succeeded)
+
+	db.First(&updatedChecking, checking.ID)
+	db.First(&updatedSavings, savings.ID)
+	assert.Equal(t, models.NewMoney(1000.0, "USD"), updatedChecking.Balance)
+	assert.Equal(t, models.NewMoney(470.0, "USD"), updatedSavings.Balance)
+
+	report, err := service.ledgerService.CheckIntegrity(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, report.Balanced)
+
+	// Requests must select transactions one way
+	_, err = service.Bulk(user.ID, &models.BulkTransactionRequest{Action: models.BulkActionDelete})
+	assert.EqualError(t, err, "either ids or filter is required")
+}
+
 func TestTransactionService_GetCategories(t *testing.T) {
 	// Setup
 	db := setupTestDB(t)
-	transactionRepo := repository.NewTransactionRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	service := NewTransactionService(transactionRepo, accountRepo, db)
+	service := newTestTransactionService(db)
 
 	// Execute
 	categories := service.GetCategories()
@@ -431,3 +1130,97 @@ func TestTransactionService_GetCategories(t *testing.T) {
 	assert.Contains(t, categories, "Shopping")
 	assert.Contains(t, categories, "Income")
 }
+
+func TestTransactionService_ScheduledLifecycle(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := newTestTransactionService(db)
+
+	req := &models.TransactionRequest{
+		Amount:      100.0,
+		Description: "Rent",
+		Category:    "Housing",
+		Type:        "expense",
+		Date:        time.Now().AddDate(0, 0, 7),
+		AccountID:   account.ID,
+	}
+
+	// A future-dated transaction is scheduled and leaves the balance alone
+	transaction, err := service.Create(user.ID, req)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionStatusScheduled, transaction.Status)
+	assert.Nil(t, transaction.JournalEntryID)
+
+	var updatedAccount models.Account
+	db.First(&updatedAccount, account.ID)
+	assert.Equal(t, 1000.0, updatedAccount.Balance.Float("USD"))
+
+	// Once the date arrives it is posted as pending
+	db.Model(&models.Transaction{}).Where("id = ?", transaction.ID).Update("date", time.Now().AddDate(0, 0, -1))
+	posted, err := service.PostDueScheduled()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, posted)
+
+	db.First(&updatedAccount, account.ID)
+	assert.Equal(t, 900.0, updatedAccount.Balance.Float("USD"))
+	assert.Equal(t, 1000.0, updatedAccount.CurrentBalance.Float("USD"))
+	assert.Equal(t, 900.0, updatedAccount.AvailableBalance.Float("USD"))
+
+	// Clearing moves it into the current balance
+	_, err = service.UpdateStatus(transaction.ID, user.ID, models.TransactionStatusCleared)
+	assert.NoError(t, err)
+
+	db.First(&updatedAccount, account.ID)
+	assert.Equal(t, 900.0, updatedAccount.CurrentBalance.Float("USD"))
+
+	// Voiding takes it off the books
+	voided, err := service.UpdateStatus(transaction.ID, user.ID, models.TransactionStatusVoid)
+	assert.NoError(t, err)
+	assert.Nil(t, voided.JournalEntryID)
+
+	db.First(&updatedAccount, account.ID)
+	assert.Equal(t, 1000.0, updatedAccount.Balance.Float("USD"))
+	assert.Equal(t, 1000.0, updatedAccount.CurrentBalance.Float("USD"))
+	assert.Equal(t, 1000.0, updatedAccount.AvailableBalance.Float("USD"))
+
+	// A due transaction cannot go back to scheduled
+	_, err = service.UpdateStatus(transaction.ID, user.ID, models.TransactionStatusScheduled)
+	assert.Error(t, err)
+}
+
+func TestTransactionService_PostDueScheduled_Failures(t *testing.T) {
+	// Setup - two due transactions, one of them in an account that is gone
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	service := newTestTransactionService(db)
+	schedule := func(description string) *models.Transaction {
+		transaction, err := service.Create(user.ID, &models.TransactionRequest{
+			Amount: 100.0, Description: description, Category: "Housing", Type: "expense",
+			Date: time.Now().AddDate(0, 0, 7), AccountID: account.ID,
+		})
+		assert.NoError(t, err)
+		db.Model(&models.Transaction{}).Where("id = ?", transaction.ID).Update("date", time.Now().AddDate(0, 0, -1))
+		return transaction
+	}
+	rent := schedule("Rent")
+	broken := schedule("Broken")
+	db.Model(&models.Transaction{}).Where("id = ?", broken.ID).Update("account_id", 9999)
+
+	// Execute
+	posted, err := service.PostDueScheduled()
+
+	// Assert - the good one is posted and the failure is reported, not swallowed
+	assert.Equal(t, 1, posted)
+	assert.ErrorContains(t, err, fmt.Sprintf("scheduled transactions failed: transaction %d: ", broken.ID))
+
+	var postedRent, skipped models.Transaction
+	db.First(&postedRent, rent.ID)
+	assert.Equal(t, models.TransactionStatusPending, postedRent.Status)
+	db.First(&skipped, broken.ID)
+	assert.Equal(t, models.TransactionStatusScheduled, skipped.Status)
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// TrashService handles business logic for soft-deleted records. Deleted
+// transactions, accounts, budgets, goals and recurring transactions stay in
+// the trash for the retention period and can be restored until then.
+type TrashService struct {
+	transactionService *TransactionService
+	attachmentService  *AttachmentService
+	transactionRepo    *repository.TransactionRepository
+	accountRepo        *repository.AccountRepository
+	budgetRepo         *repository.BudgetRepository
+	goalRepo           *repository.GoalRepository
+	recurringRepo      *repository.RecurringTransactionRepository
+	retention          time.Duration
+}
+
+// NewTrashService creates a new trash service
+func NewTrashService(
+	transactionService *TransactionService,
+	attachmentService *AttachmentService,
+	transactionRepo *repository.TransactionRepository,
+	accountRepo *repository.AccountRepository,
+	budgetRepo *repository.BudgetRepository,
+	goalRepo *repository.GoalRepository,
+	recurringRepo *repository.RecurringTransactionRepository,
+	retention time.Duration,
+) *TrashService {
+	return &TrashService{
+		transactionService: transactionService,
+		attachmentService:  attachmentService,
+		transactionRepo:    transactionRepo,
+		accountRepo:        accountRepo,
+		budgetRepo:         budgetRepo,
+		goalRepo:           goalRepo,
+		recurringRepo:      recurringRepo,
+		retention:          retention,
+	}
+}
+
+// GetTrash lists everything a user has in the trash, most recently deleted first
+func (s *TrashService) GetTrash(userID uint) (*models.TrashResponse, error) {
+	items := []*models.TrashItem{}
+
+	transactions, err := s.transactionRepo.GetDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transactions {
+		items = append(items, s.newItem(models.TrashTypeTransaction, t.ID, t.Description, t.DeletedAt,
+			t.Amount.Float(t.Currency), t.Currency))
+	}
+
+	accounts, err := s.accountRepo.GetDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range accounts {
+		items = append(items, s.newItem(models.TrashTypeAccount, a.ID, a.Name, a.DeletedAt,
+			a.Balance.Float(a.Currency), a.Currency))
+	}
+
+	budgets, err := s.budgetRepo.GetDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range budgets {
+		items = append(items, s.newItem(models.TrashTypeBudget, b.ID, b.Name, b.DeletedAt,
+			b.Amount.Float(b.Currency), b.Currency))
+	}
+
+	goals, err := s.goalRepo.GetDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range goals {
+		items = append(items, s.newItem(models.TrashTypeGoal, g.ID, g.Name, g.DeletedAt,
+			g.TargetAmount.Float(g.Currency), g.Currency))
+	}
+
+	recurring, err := s.recurringRepo.GetDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range recurring {
+		items = append(items, s.newItem(models.TrashTypeRecurringTransaction, r.ID, r.Description, r.DeletedAt,
+			r.Amount.Float(r.Currency), r.Currency))
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	return &models.TrashResponse{
+		Items:         items,
+		RetentionDays: int(s.retention.Hours() / 24),
+	}, nil
+}
+
+// newItem builds a trash listing entry
+func (s *TrashService) newItem(itemType string, id uint, name string, deletedAt gorm.DeletedAt, amount float64, currency string) *models.TrashItem {
+	return &models.TrashItem{
+		Type:      itemType,
+		ID:        id,
+		Name:      name,
+		Amount:    amount,
+		Currency:  currency,
+		DeletedAt: deletedAt.Time,
+		PurgeAt:   deletedAt.Time.Add(s.retention),
+	}
+}
+
+// Restore takes an item out of the trash. Restored transactions get their
+// balance effect back.
+func (s *TrashService) Restore(itemType string, id uint, userID uint) error {
+	var err error
+	switch itemType {
+	case models.TrashTypeTransaction:
+		_, err = s.transactionService.Restore(id, userID)
+	case models.TrashTypeAccount:
+		err = s.accountRepo.Restore(id, userID)
+	case models.TrashTypeBudget:
+		err = s.budgetRepo.Restore(id, userID)
+	case models.TrashTypeGoal:
+		err = s.goalRepo.Restore(id, userID)
+	case models.TrashTypeRecurringTransaction:
+		err = s.recurringRepo.Restore(id, userID)
+	default:
+		return models.ErrInvalidTrashType
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ErrTrashItemNotFound
+	}
+	return err
+}
+
+// Purge permanently deletes an item in the trash. Attachments of purged
+// transactions are removed with them.
+func (s *TrashService) Purge(itemType string, id uint, userID uint) error {
+	var err error
+	switch itemType {
+	case models.TrashTypeTransaction:
+		err = s.transactionService.Purge(id, userID)
+	case models.TrashTypeAccount:
+		err = s.transactionService.PurgeAccount(id, userID)
+	case models.TrashTypeBudget:
+		err = s.budgetRepo.Purge(id, userID)
+	case models.TrashTypeGoal:
+		err = s.goalRepo.Purge(id, userID)
+	case models.TrashTypeRecurringTransaction:
+		err = s.recurringRepo.Purge(id, userID)
+	default:
+		return models.ErrInvalidTrashType
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ErrTrashItemNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// Purging a transaction or an account can leave attachments without a transaction
+	if itemType == models.TrashTypeTransaction || itemType == models.TrashTypeAccount {
+		_, err = s.attachmentService.PurgeOrphaned()
+	}
+	return err
+}
+
+// PurgeExpired permanently deletes everything that has been in the trash for
+// longer than the retention period and returns how many items were purged.
+// Items that fail to purge are left for the next run.
+func (s *TrashService) PurgeExpired() (int, error) {
+	cutoff := time.Now().Add(-s.retention)
+	purged, failed := 0, 0
+
+	purge := func(itemType string, id uint, userID uint) {
+		err := s.Purge(itemType, id, userID)
+		switch {
+		case err == nil:
+			purged++
+		case errors.Is(err, models.ErrTrashItemNotFound):
+			// Already purged together with the other leg of a transfer
+		default:
+			failed++
+		}
+	}
+
+	transactions, err := s.transactionRepo.GetDeletedBefore(cutoff)
+	if err != nil {
+		return purged, err
+	}
+	for _, t := range transactions {
+		purge(models.TrashTypeTransaction, t.ID, t.UserID)
+	}
+
+	budgets, err := s.budgetRepo.GetDeletedBefore(cutoff)
+	if err != nil {
+		return purged, err
+	}
+	for _, b := range budgets {
+		purge(models.TrashTypeBudget, b.ID, b.UserID)
+	}
+
+	goals, err := s.goalRepo.GetDeletedBefore(cutoff)
+	if err != nil {
+		return purged, err
+	}
+	for _, g := range goals {
+		purge(models.TrashTypeGoal, g.ID, g.UserID)
+	}
+
+	recurring, err := s.recurringRepo.GetDeletedBefore(cutoff)
+	if err != nil {
+		return purged, err
+	}
+	for _, r := range recurring {
+		purge(models.TrashTypeRecurringTransaction, r.ID, r.UserID)
+	}
+
+	// Accounts go last so their trashed transactions are gone first
+	accounts, err := s.accountRepo.GetDeletedBefore(cutoff)
+	if err != nil {
+		return purged, err
+	}
+	for _, a := range accounts {
+		purge(models.TrashTypeAccount, a.ID, a.UserID)
+	}
+
+	if failed > 0 {
+		return purged, fmt.Errorf("%d trashed items could not be purged", failed)
+	}
+	return purged, nil
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newTestTrashService wires a trash service with a 30 day retention against the test database
+func newTestTrashService(t *testing.T, db *gorm.DB) *TrashService {
+	return NewTrashService(
+		newTestTransactionService(db),
+		newTestAttachmentService(t, db),
+		repository.NewTransactionRepository(db),
+		repository.NewAccountRepository(db),
+		repository.NewBudgetRepository(db),
+		repository.NewGoalRepository(db),
+		repository.NewRecurringTransactionRepository(db),
+		30*24*time.Hour,
+	)
+}
+
+func TestTrashService_RestoreTransfer(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	fromAccount := createTestAccount(t, db, user.ID, 1000.0)
+	toAccount := createTestAccount(t, db, user.ID, 500.0)
+
+	transactionService := newTestTransactionService(db)
+	service := newTestTrashService(t, db)
+
+	transfer, err := transactionService.Transfer(user.ID, &models.TransferRequest{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        200.0,
+		Date:          time.Now(),
+	})
+	assert.NoError(t, err)
+
+	assert.NotNil(t, transfer)
+	var fromLeg models.Transaction
+	db.Where("account_id = ?", fromAccount.ID).First(&fromLeg)
+
+	// Deleting one leg trashes both and undoes the transfer
+	assert.NoError(t, transactionService.Delete(fromLeg.ID, user.ID))
+
+	trash, err := service.GetTrash(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, trash.Items, 2)
+	assert.Equal(t, 30, trash.RetentionDays)
+
+	var updatedFrom, updatedTo models.Account
+	db.First(&updatedFrom, fromAccount.ID)
+	db.First(&updatedTo, toAccount.ID)
+	assert.Equal(t, 1000.0, updatedFrom.Balance.Float("USD"))
+	assert.Equal(t, 500.0, updatedTo.Balance.Float("USD"))
+
+	// Restoring brings both legs and their balance effect back
+	assert.NoError(t, service.Restore(models.TrashTypeTransaction, fromLeg.ID, user.ID))
+
+	db.First(&updatedFrom, fromAccount.ID)
+	db.First(&updatedTo, toAccount.ID)
+	assert.Equal(t, 800.0, updatedFrom.Balance.Float("USD"))
+	assert.Equal(t, 700.0, updatedTo.Balance.Float("USD"))
+
+	var legs []models.Transaction
+	db.Where("type = ?", "transfer").Order("id").Find(&legs)
+	assert.Len(t, legs, 2)
+	assert.NotNil(t, legs[0].JournalEntryID)
+	assert.Equal(t, *legs[0].JournalEntryID, *legs[1].JournalEntryID)
+
+	trash, err = service.GetTrash(user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, trash.Items)
+}
+
+func TestTrashService_RestoreNeedsAccount(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	transactionService := newTestTransactionService(db)
+	service := newTestTrashService(t, db)
+
+	transaction, err := transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 100.0, Type: "expense", Category: "Shopping", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, transactionService.Delete(transaction.ID, user.ID))
+	assert.NoError(t, repository.NewAccountRepository(db).Delete(account.ID, user.ID))
+
+	// The transaction cannot come back before its account
+	assert.Error(t, service.Restore(models.TrashTypeTransaction, transaction.ID, user.ID))
+
+	assert.NoError(t, service.Restore(models.TrashTypeAccount, account.ID, user.ID))
+	assert.NoError(t, service.Restore(models.TrashTypeTransaction, transaction.ID, user.ID))
+
+	var updatedAccount models.Account
+	db.First(&updatedAccount, account.ID)
+	assert.Equal(t, 900.0, updatedAccount.Balance.Float("USD"))
+
+	// Restoring twice finds nothing in the trash
+	assert.ErrorIs(t, service.Restore(models.TrashTypeTransaction, transaction.ID, user.ID), models.ErrTrashItemNotFound)
+	assert.ErrorIs(t, service.Restore("invoice", transaction.ID, user.ID), models.ErrInvalidTrashType)
+}
+
+func TestTrashService_PurgeExpired(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+
+	transactionService := newTestTransactionService(db)
+	service := newTestTrashService(t, db)
+
+	expired, err := transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 100.0, Type: "expense", Category: "Shopping", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+	recent, err := transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 50.0, Type: "expense", Category: "Shopping", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, transactionService.Delete(expired.ID, user.ID))
+	assert.NoError(t, transactionService.Delete(recent.ID, user.ID))
+	db.Unscoped().Model(&models.Transaction{}).Where("id = ?", expired.ID).
+		Update("deleted_at", time.Now().AddDate(0, 0, -31))
+
+	// Execute
+	purged, err := service.PurgeExpired()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	var count int64
+	db.Unscoped().Model(&models.Transaction{}).Where("id = ?", expired.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Unscoped().Model(&models.Transaction{}).Where("id = ?", recent.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestTrashService_PurgeAccount(t *testing.T) {
+	// Setup
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	account := createTestAccount(t, db, user.ID, 1000.0)
+	otherAccount := createTestAccount(t, db, user.ID, 500.0)
+
+	transactionService := newTestTransactionService(db)
+	accountService := NewAccountService(repository.NewAccountRepository(db), repository.NewLedgerRepository(db),
+		newTestLedgerService(db), newTestCurrencyService(db), db)
+	service := newTestTrashService(t, db)
+
+	expense, err := transactionService.Create(user.ID, &models.TransactionRequest{
+		Amount: 100.0, Type: "expense", Category: "Shopping", Date: time.Now(), AccountID: account.ID,
+	})
+	assert.NoError(t, err)
+	_, err = transactionService.Transfer(user.ID, &models.TransferRequest{
+		FromAccountID: account.ID, ToAccountID: otherAccount.ID, Amount: 200.0, Date: time.Now(),
+	})
+	assert.NoError(t, err)
+
+	// An account with transactions cannot go to the trash
+	assert.ErrorIs(t, accountService.Delete(account.ID, user.ID), models.ErrAccountHasTransactions)
+
+	var transferLeg models.Transaction
+	db.Where("account_id = ? AND type = ?", account.ID, "transfer").First(&transferLeg)
+	assert.NoError(t, transactionService.Delete(expense.ID, user.ID))
+	assert.NoError(t, transactionService.Delete(transferLeg.ID, user.ID))
+	assert.NoError(t, accountService.Delete(account.ID, user.ID))
+
+	// Execute - trash, purge, list
+	assert.NoError(t, service.Purge(models.TrashTypeAccount, account.ID, user.ID))
+	trash, err := service.GetTrash(user.ID)
+
+	// Assert - the account goes with its trashed transactions, both transfer legs and its journal entries
+	assert.NoError(t, err)
+	assert.Empty(t, trash.Items)
+
+	var count int64
+	db.Unscoped().Model(&models.Transaction{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&models.Posting{}).Where("account_id = ?", account.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&models.BalanceHistory{}).Where("account_id = ?", account.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	unbalanced, err := repository.NewLedgerRepository(db).GetUnbalancedEntryIDs(user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, unbalanced)
+
+	var updatedOther models.Account
+	db.First(&updatedOther, otherAccount.ID)
+	assert.Equal(t, 500.0, updatedOther.Balance.Float("USD"))
+
+	// Purging again finds nothing in the trash
+	assert.ErrorIs(t, service.Purge(models.TrashTypeAccount, account.ID, user.ID), models.ErrTrashItemNotFound)
+}
@@ -10,13 +10,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// SQLiteDSN returns the DSN of a SQLite database file with writers serialized
+func SQLiteDSN(path string) string {
+	return path + "?_txlock=immediate&_busy_timeout=10000"
+}
+
 func NewPostgresDB(cfg *config.Config) (*gorm.DB, error) {
 	var db *gorm.DB
 	var err error
 
 	if cfg.UseSQLite {
-		// Use SQLite for development/testing
-		db, err = gorm.Open(sqlite.Open("finance-management.db"), &gorm.Config{})
+		// Use SQLite for development/testing. Transactions take the write lock
+		// when they begin, since SQLite has no row locks to order balance
+		// changes, and wait for it instead of failing while it is held.
+		db, err = gorm.Open(sqlite.Open(SQLiteDSN("finance-management.db")), &gorm.Config{})
 		if err != nil {
 			return nil, fmt.Errorf("failed to connect to SQLite database: %w", err)
 		}
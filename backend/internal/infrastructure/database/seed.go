@@ -65,7 +65,7 @@ func SeedDatabase(db *gorm.DB) error {
 			UserID:    testUser.ID,
 			Name:      "Checking Account",
 			Type:      "checking",
-			Balance:   1500.00,
+			Balance:   models.NewMoney(1500.00, "USD"),
 			Currency:  "USD",
 			IsDefault: true,
 		},
@@ -73,14 +73,14 @@ func SeedDatabase(db *gorm.DB) error {
 			UserID:   testUser.ID,
 			Name:     "Savings Account",
 			Type:     "savings",
-			Balance:  5000.00,
+			Balance:  models.NewMoney(5000.00, "USD"),
 			Currency: "USD",
 		},
 		{
 			UserID:   testUser.ID,
 			Name:     "Credit Card",
 			Type:     "credit",
-			Balance:  -250.00,
+			Balance:  models.NewMoney(-250.00, "USD"),
 			Currency: "USD",
 		},
 	}
@@ -97,7 +97,7 @@ func SeedDatabase(db *gorm.DB) error {
 			UserID:    dainqUser.ID,
 			Name:      "Main Checking",
 			Type:      "checking",
-			Balance:   3500.00,
+			Balance:   models.NewMoney(3500.00, "USD"),
 			Currency:  "USD",
 			IsDefault: true,
 		},
@@ -105,14 +105,14 @@ func SeedDatabase(db *gorm.DB) error {
 			UserID:   dainqUser.ID,
 			Name:     "Emergency Fund",
 			Type:     "savings",
-			Balance:  10000.00,
+			Balance:  models.NewMoney(10000.00, "USD"),
 			Currency: "USD",
 		},
 		{
 			UserID:   dainqUser.ID,
 			Name:     "Visa Card",
 			Type:     "credit",
-			Balance:  -500.00,
+			Balance:  models.NewMoney(-500.00, "USD"),
 			Currency: "USD",
 		},
 	}
@@ -128,53 +128,53 @@ func SeedDatabase(db *gorm.DB) error {
 	transactions := []*models.Transaction{
 		{
 			UserID:      testUser.ID,
-			Amount:      -85.50,
+			Amount:      models.NewMoney(-85.50, "USD"),
 			Description: "Grocery shopping",
 			Category:    "Food & Dining",
 			Type:        "expense",
 			Date:        now.AddDate(0, 0, -1),
 			AccountID:   accounts[0].ID,
-			Tags:        "groceries,food",
+			Tags:        seedTags(db, testUser.ID, "groceries", "food"),
 		},
 		{
 			UserID:      testUser.ID,
-			Amount:      -45.00,
+			Amount:      models.NewMoney(-45.00, "USD"),
 			Description: "Gas station",
 			Category:    "Transportation",
 			Type:        "expense",
 			Date:        now.AddDate(0, 0, -2),
 			AccountID:   accounts[0].ID,
-			Tags:        "gas,transportation",
+			Tags:        seedTags(db, testUser.ID, "gas", "transportation"),
 		},
 		{
 			UserID:      testUser.ID,
-			Amount:      2500.00,
+			Amount:      models.NewMoney(2500.00, "USD"),
 			Description: "Salary deposit",
 			Category:    "Income",
 			Type:        "income",
 			Date:        now.AddDate(0, 0, -3),
 			AccountID:   accounts[0].ID,
-			Tags:        "salary,income",
+			Tags:        seedTags(db, testUser.ID, "salary", "income"),
 		},
 		{
 			UserID:      testUser.ID,
-			Amount:      -120.00,
+			Amount:      models.NewMoney(-120.00, "USD"),
 			Description: "Electric bill",
 			Category:    "Bills & Utilities",
 			Type:        "expense",
 			Date:        now.AddDate(0, 0, -5),
 			AccountID:   accounts[0].ID,
-			Tags:        "utilities,bills",
+			Tags:        seedTags(db, testUser.ID, "utilities", "bills"),
 		},
 		{
 			UserID:      testUser.ID,
-			Amount:      -35.99,
+			Amount:      models.NewMoney(-35.99, "USD"),
 			Description: "Netflix subscription",
 			Category:    "Entertainment",
 			Type:        "expense",
 			Date:        now.AddDate(0, 0, -7),
 			AccountID:   accounts[0].ID,
-			Tags:        "subscription,entertainment",
+			Tags:        seedTags(db, testUser.ID, "subscription", "entertainment"),
 		},
 	}
 
@@ -188,43 +188,43 @@ func SeedDatabase(db *gorm.DB) error {
 	dainqTransactions := []*models.Transaction{
 		{
 			UserID:      dainqUser.ID,
-			Amount:      -150.00,
+			Amount:      models.NewMoney(-150.00, "USD"),
 			Description: "Restaurant dinner",
 			Category:    "Food & Dining",
 			Type:        "expense",
 			Date:        now.AddDate(0, 0, -1),
 			AccountID:   dainqAccounts[0].ID,
-			Tags:        "dining,food",
+			Tags:        seedTags(db, dainqUser.ID, "dining", "food"),
 		},
 		{
 			UserID:      dainqUser.ID,
-			Amount:      -60.00,
+			Amount:      models.NewMoney(-60.00, "USD"),
 			Description: "Uber rides",
 			Category:    "Transportation",
 			Type:        "expense",
 			Date:        now.AddDate(0, 0, -2),
 			AccountID:   dainqAccounts[0].ID,
-			Tags:        "uber,transportation",
+			Tags:        seedTags(db, dainqUser.ID, "uber", "transportation"),
 		},
 		{
 			UserID:      dainqUser.ID,
-			Amount:      5000.00,
+			Amount:      models.NewMoney(5000.00, "USD"),
 			Description: "Monthly salary",
 			Category:    "Income",
 			Type:        "income",
 			Date:        now.AddDate(0, 0, -3),
 			AccountID:   dainqAccounts[0].ID,
-			Tags:        "salary,income",
+			Tags:        seedTags(db, dainqUser.ID, "salary", "income"),
 		},
 		{
 			UserID:      dainqUser.ID,
-			Amount:      -200.00,
+			Amount:      models.NewMoney(-200.00, "USD"),
 			Description: "Internet bill",
 			Category:    "Bills & Utilities",
 			Type:        "expense",
 			Date:        now.AddDate(0, 0, -5),
 			AccountID:   dainqAccounts[0].ID,
-			Tags:        "utilities,bills",
+			Tags:        seedTags(db, dainqUser.ID, "utilities", "bills"),
 		},
 	}
 
@@ -242,8 +242,8 @@ func SeedDatabase(db *gorm.DB) error {
 		{
 			UserID:    testUser.ID,
 			Name:      "Food Budget",
-			Amount:    500.00,
-			Spent:     85.50,
+			Amount:    models.NewMoney(500.00, "USD"),
+			Spent:     models.NewMoney(85.50, "USD"),
 			Category:  "Food & Dining",
 			Period:    "monthly",
 			StartDate: startOfMonth,
@@ -252,8 +252,8 @@ func SeedDatabase(db *gorm.DB) error {
 		{
 			UserID:    testUser.ID,
 			Name:      "Transportation Budget",
-			Amount:    200.00,
-			Spent:     45.00,
+			Amount:    models.NewMoney(200.00, "USD"),
+			Spent:     models.NewMoney(45.00, "USD"),
 			Category:  "Transportation",
 			Period:    "monthly",
 			StartDate: startOfMonth,
@@ -262,8 +262,8 @@ func SeedDatabase(db *gorm.DB) error {
 		{
 			UserID:    testUser.ID,
 			Name:      "Entertainment Budget",
-			Amount:    150.00,
-			Spent:     35.99,
+			Amount:    models.NewMoney(150.00, "USD"),
+			Spent:     models.NewMoney(35.99, "USD"),
 			Category:  "Entertainment",
 			Period:    "monthly",
 			StartDate: startOfMonth,
@@ -279,3 +279,14 @@ func SeedDatabase(db *gorm.DB) error {
 
 	return nil
 }
+
+// seedTags gets or creates the tags of a user with the given names
+func seedTags(db *gorm.DB, userID uint, names ...string) []models.Tag {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tag := models.Tag{UserID: userID, Name: name}
+		db.Where(&tag).FirstOrCreate(&tag)
+		tags = append(tags, tag)
+	}
+	return tags
+}
@@ -0,0 +1,36 @@
+package exchangerates
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+)
+
+// FileProvider reads rates from a local CSV or ECB XML file, for example one
+// downloaded from the ECB or exported from another system
+type FileProvider struct {
+	path string
+}
+
+// NewFileProvider creates a provider that reads rates from path
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Name returns the source recorded on rates read from the file
+func (p *FileProvider) Name() string {
+	return "file:" + filepath.Base(p.path)
+}
+
+// Fetch reads every rate in the file
+func (p *FileProvider) Fetch(ctx context.Context) ([]models.ExchangeRate, error) {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file, p.Name())
+}
@@ -0,0 +1,59 @@
+package exchangerates
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+)
+
+// ECBDailyURL publishes the latest ECB reference rates
+const ECBDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+// maxResponseSize limits how much of a provider response is read
+const maxResponseSize = 10 * 1024 * 1024
+
+// HTTPProvider downloads rates in CSV or ECB XML format from a URL
+type HTTPProvider struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPProvider creates a provider that downloads rates from url
+func NewHTTPProvider(url string) *HTTPProvider {
+	return &HTTPProvider{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the source recorded on downloaded rates
+func (p *HTTPProvider) Name() string {
+	if p.url == ECBDailyURL {
+		return "ecb"
+	}
+	return "http"
+}
+
+// Fetch downloads and parses the published rates
+func (p *HTTPProvider) Fetch(ctx context.Context) ([]models.ExchangeRate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchange rate provider returned %s", resp.Status)
+	}
+
+	return Parse(io.LimitReader(resp.Body, maxResponseSize), p.Name())
+}
@@ -0,0 +1,153 @@
+package exchangerates
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+)
+
+// ErrInvalidRates is returned for rate data that cannot be parsed
+var ErrInvalidRates = errors.New("invalid exchange rate data")
+
+// Provider supplies dated exchange rates from an external source
+type Provider interface {
+	// Name identifies the provider and is recorded as the source of its rates
+	Name() string
+	// Fetch returns the rates the provider currently publishes
+	Fetch(ctx context.Context) ([]models.ExchangeRate, error)
+}
+
+// ecbEnvelope is the layout of the European Central Bank reference rate
+// files, which quote every currency against one euro
+type ecbEnvelope struct {
+	Cube struct {
+		Days []struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string `xml:"currency,attr"`
+				Rate     string `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// ParseECBXML parses an ECB reference rate file such as eurofxref-daily.xml
+// or eurofxref-hist.xml
+func ParseECBXML(r io.Reader, source string) ([]models.ExchangeRate, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRates, err)
+	}
+
+	var rates []models.ExchangeRate
+	for _, day := range envelope.Cube.Days {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid date %q", ErrInvalidRates, day.Time)
+		}
+		for _, quote := range day.Rates {
+			rate, err := newRate("EUR", quote.Currency, date, quote.Rate, source)
+			if err != nil {
+				return nil, err
+			}
+			rates = append(rates, *rate)
+		}
+	}
+
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("%w: no rates found", ErrInvalidRates)
+	}
+	return rates, nil
+}
+
+// ParseCSV parses rates from CSV with a header row naming the date, base,
+// quote and rate columns, e.g.
+//
+//	date,base,quote,rate
+//	2024-01-02,USD,EUR,0.9127
+func ParseCSV(r io.Reader, source string) ([]models.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRates, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "base", "quote", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing %s column", ErrInvalidRates, name)
+		}
+	}
+
+	var rates []models.ExchangeRate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRates, err)
+		}
+
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[columns["date"]]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid date", ErrInvalidRates, line)
+		}
+		rate, err := newRate(record[columns["base"]], record[columns["quote"]], date, record[columns["rate"]], source)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, *rate)
+	}
+
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("%w: no rates found", ErrInvalidRates)
+	}
+	return rates, nil
+}
+
+// Parse detects whether data is ECB XML or CSV and parses it
+func Parse(r io.Reader, source string) ([]models.ExchangeRate, error) {
+	buffered := bufio.NewReader(r)
+	head, _ := buffered.Peek(64)
+	if bytes.HasPrefix(bytes.TrimSpace(head), []byte("<")) {
+		return ParseECBXML(buffered, source)
+	}
+	return ParseCSV(buffered, source)
+}
+
+// newRate validates and builds a single rate
+func newRate(base, quote string, date time.Time, value string, source string) (*models.ExchangeRate, error) {
+	base = strings.ToUpper(strings.TrimSpace(base))
+	quote = strings.ToUpper(strings.TrimSpace(quote))
+	if len(base) != 3 || len(quote) != 3 || base == quote {
+		return nil, fmt.Errorf("%w: invalid currency pair %s/%s", ErrInvalidRates, base, quote)
+	}
+
+	rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || rate <= 0 {
+		return nil, fmt.Errorf("%w: invalid rate %q for %s/%s", ErrInvalidRates, value, base, quote)
+	}
+
+	return &models.ExchangeRate{
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+		Date:          models.RateDate(date),
+		Rate:          rate,
+		Source:        source,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+)
+
+// DBStore is a Store backed by the idempotency_keys table. It is used when
+// Redis is not available.
+type DBStore struct {
+	repo *repository.IdempotencyRepository
+}
+
+// NewDBStore creates an idempotency store backed by the database
+func NewDBStore(repo *repository.IdempotencyRepository) *DBStore {
+	return &DBStore{repo: repo}
+}
+
+// Reserve claims a key with a unique insert
+func (s *DBStore) Reserve(_ context.Context, key *models.IdempotencyKey) (*models.IdempotencyKey, bool, error) {
+	reserved, err := s.repo.Reserve(key)
+	if err != nil || reserved {
+		return nil, reserved, err
+	}
+
+	existing, err := s.repo.GetByKey(key.UserID, key.Key)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+// Complete stores the response of a reserved key
+func (s *DBStore) Complete(_ context.Context, key *models.IdempotencyKey) error {
+	return s.repo.Complete(key)
+}
+
+// Release deletes a reserved key
+func (s *DBStore) Release(_ context.Context, userID uint, key string) error {
+	return s.repo.Delete(userID, key)
+}
+
+// PurgeExpired deletes expired keys and returns how many were removed
+func (s *DBStore) PurgeExpired() (int, error) {
+	purged, err := s.repo.DeleteExpired(time.Now())
+	return int(purged), err
+}
@@ -0,0 +1,101 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore is a Store that keeps keys in Redis and lets them expire there
+type RedisStore struct {
+	client *infrastructure.RedisClient
+}
+
+// redisRecord is the JSON value stored under a key
+type redisRecord struct {
+	Fingerprint string    `json:"fingerprint"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type"`
+	Response    []byte    `json:"response"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// NewRedisStore creates an idempotency store backed by Redis
+func NewRedisStore(client *infrastructure.RedisClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Reserve claims a key with SET NX. A key that expires between the claim and
+// the lookup of the existing value is claimed again.
+func (s *RedisStore) Reserve(ctx context.Context, key *models.IdempotencyKey) (*models.IdempotencyKey, bool, error) {
+	value, err := encodeRecord(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, err := s.client.SetNX(ctx, redisKey(key.UserID, key.Key), value, time.Until(key.ExpiresAt))
+		if err != nil || reserved {
+			return nil, reserved, err
+		}
+
+		stored, err := s.client.Get(ctx, redisKey(key.UserID, key.Key))
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		var record redisRecord
+		if err := json.Unmarshal([]byte(stored), &record); err != nil {
+			return nil, false, err
+		}
+		return &models.IdempotencyKey{
+			UserID:      key.UserID,
+			Key:         key.Key,
+			Fingerprint: record.Fingerprint,
+			StatusCode:  record.StatusCode,
+			ContentType: record.ContentType,
+			Response:    record.Response,
+			ExpiresAt:   record.ExpiresAt,
+		}, false, nil
+	}
+	return nil, false, fmt.Errorf("idempotency key %q could not be reserved", key.Key)
+}
+
+// Complete overwrites a reserved key with its response, keeping its expiry
+func (s *RedisStore) Complete(ctx context.Context, key *models.IdempotencyKey) error {
+	value, err := encodeRecord(key)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisKey(key.UserID, key.Key), value, time.Until(key.ExpiresAt))
+}
+
+// Release deletes a reserved key
+func (s *RedisStore) Release(ctx context.Context, userID uint, key string) error {
+	return s.client.Del(ctx, redisKey(userID, key))
+}
+
+// redisKey returns the Redis key of an idempotency key of a user
+func redisKey(userID uint, key string) string {
+	return fmt.Sprintf("idempotency:%d:%s", userID, key)
+}
+
+// encodeRecord encodes an idempotency key together with its response
+func encodeRecord(key *models.IdempotencyKey) ([]byte, error) {
+	return json.Marshal(&redisRecord{
+		Fingerprint: key.Fingerprint,
+		StatusCode:  key.StatusCode,
+		ContentType: key.ContentType,
+		Response:    key.Response,
+		ExpiresAt:   key.ExpiresAt,
+	})
+}
@@ -0,0 +1,19 @@
+package idempotency
+
+import (
+	"context"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+)
+
+// Store keeps idempotency keys with the responses they were used for until
+// they expire. Implementations must be safe for concurrent use.
+type Store interface {
+	// Reserve claims key.Key for a new request. When the user already holds
+	// the key it returns the stored key and false instead.
+	Reserve(ctx context.Context, key *models.IdempotencyKey) (*models.IdempotencyKey, bool, error)
+	// Complete stores the response of the request a key was reserved for
+	Complete(ctx context.Context, key *models.IdempotencyKey) error
+	// Release frees a reserved key so that the request can be tried again
+	Release(ctx context.Context, userID uint, key string) error
+}
@@ -0,0 +1,221 @@
+package migrations
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// balanceEvent is one historical change of an account balance
+type balanceEvent struct {
+	date          time.Time
+	amount        models.Money
+	changeType    string
+	transactionID *uint
+	description   string
+}
+
+// entryEffect is the net effect of a journal entry on one account
+type entryEffect struct {
+	JournalEntryID uint
+	Type           string
+	Description    string
+	Date           time.Time
+	Amount         models.Money
+}
+
+// BackfillBalanceHistory rebuilds the balance history of every account (or
+// only the accounts of userID when it is non-zero) from existing transactions
+// and manual adjustments. Existing history rows of those accounts are
+// replaced. The replayed history always ends at the current stored balance;
+// whatever the transactions do not explain becomes an opening balance row.
+// It returns the number of history rows written.
+func BackfillBalanceHistory(db *gorm.DB, userID uint) (int, error) {
+	log.Println("Backfilling balance history...")
+
+	query := db.Order("id")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var accounts []models.Account
+	if err := query.Find(&accounts).Error; err != nil {
+		log.Printf("Error backfilling balance history: %v", err)
+		return 0, err
+	}
+
+	written := 0
+	for _, account := range accounts {
+		count, err := backfillAccountHistory(db, &account)
+		if err != nil {
+			log.Printf("Error backfilling balance history for account %d: %v", account.ID, err)
+			return written, err
+		}
+		written += count
+	}
+
+	log.Printf("✓ Balance history backfilled for %d accounts (%d rows)", len(accounts), written)
+	return written, nil
+}
+
+// backfillAccountHistory replaces the balance history of a single account
+func backfillAccountHistory(db *gorm.DB, account *models.Account) (int, error) {
+	events, err := accountBalanceEvents(db, account)
+	if err != nil {
+		return 0, err
+	}
+
+	// Work back from the current balance to the balance before the first event
+	opening := account.Balance
+	for _, event := range events {
+		opening -= event.amount
+	}
+
+	openingDate := account.CreatedAt
+	if len(events) > 0 && events[0].date.Before(openingDate) {
+		openingDate = events[0].date
+	}
+
+	history := []models.BalanceHistory{}
+	if opening != 0 {
+		history = append(history, models.BalanceHistory{
+			UserID:       account.UserID,
+			AccountID:    account.ID,
+			Balance:      opening,
+			ChangeAmount: opening,
+			ChangeType:   models.BalanceChangeAdjustment,
+			Description:  "Opening balance",
+			RecordedAt:   openingDate,
+		})
+	}
+
+	balance := opening
+	for _, event := range events {
+		balance += event.amount
+		history = append(history, models.BalanceHistory{
+			UserID:        account.UserID,
+			AccountID:     account.ID,
+			Balance:       balance,
+			ChangeAmount:  event.amount,
+			ChangeType:    event.changeType,
+			TransactionID: event.transactionID,
+			Description:   event.description,
+			RecordedAt:    event.date,
+		})
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", account.ID).Delete(&models.BalanceHistory{}).Error; err != nil {
+			return err
+		}
+		if len(history) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(history, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(history), nil
+}
+
+// accountBalanceEvents lists the transactions and manual adjustments of an
+// account in the order they happened, with their signed effect on its balance
+func accountBalanceEvents(db *gorm.DB, account *models.Account) ([]balanceEvent, error) {
+	var transactions []models.Transaction
+	if err := db.Where("account_id = ?", account.ID).Order("date ASC, id ASC").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	// Net effect of each journal entry on this account
+	var effects []entryEffect
+	err := db.Table("postings").
+		Select("postings.journal_entry_id, journal_entries.type, journal_entries.description, journal_entries.date, "+
+			"COALESCE(SUM(postings.amount_minor), 0) AS amount").
+		Joins("JOIN journal_entries ON journal_entries.id = postings.journal_entry_id").
+		Where("postings.account_id = ?", account.ID).
+		Group("postings.journal_entry_id, journal_entries.type, journal_entries.description, journal_entries.date").
+		Scan(&effects).Error
+	if err != nil {
+		return nil, err
+	}
+	effectByEntry := make(map[uint]entryEffect, len(effects))
+	for _, effect := range effects {
+		effectByEntry[effect.JournalEntryID] = effect
+	}
+
+	events := make([]balanceEvent, 0, len(transactions))
+	linked := make(map[uint]bool, len(transactions))
+	for i := range transactions {
+		t := &transactions[i]
+		if !t.IsPosted() {
+			// Scheduled and void transactions never touched the balance
+			continue
+		}
+		change := models.NewTransactionBalanceChange(t)
+
+		var amount models.Money
+		if t.JournalEntryID != nil {
+			linked[*t.JournalEntryID] = true
+			amount = effectByEntry[*t.JournalEntryID].Amount
+		} else {
+			amount, err = legacyTransactionEffect(db, t)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		events = append(events, balanceEvent{
+			date:          t.Date,
+			amount:        amount,
+			changeType:    change.ChangeType,
+			transactionID: change.TransactionID,
+			description:   change.Description,
+		})
+	}
+
+	// Manual adjustments and reversals are not tied to a transaction. Opening
+	// balance entries are left out because the replay derives its own.
+	for _, effect := range effects {
+		if linked[effect.JournalEntryID] || effect.Type == models.JournalEntryTypeOpeningBalance || effect.Amount == 0 {
+			continue
+		}
+		events = append(events, balanceEvent{
+			date:        effect.Date,
+			amount:      effect.Amount,
+			changeType:  models.BalanceChangeAdjustment,
+			description: effect.Description,
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].date.Before(events[j].date)
+	})
+
+	return events, nil
+}
+
+// legacyTransactionEffect returns the balance effect of a transaction recorded
+// before the ledger existed
+func legacyTransactionEffect(db *gorm.DB, t *models.Transaction) (models.Money, error) {
+	switch t.Type {
+	case "income":
+		return t.Amount, nil
+	case "transfer":
+		outgoing, err := repository.NewTransactionRepository(db).IsOutgoingLegacyTransfer(t)
+		if err != nil {
+			return 0, err
+		}
+		if outgoing {
+			return -t.Amount, nil
+		}
+		return t.Amount, nil
+	default:
+		return -t.Amount, nil
+	}
+}
@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	"log"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// RunLedgerMigrations backfills the double-entry ledger for accounts that
+// existed before it. Each account without postings gets an opening balance
+// entry equal to its stored balance, so balances derived from postings match
+// what users already see. Transactions recorded before the ledger keep a nil
+// JournalEntryID and are reversed with an adjustment entry if deleted. The
+// opening balance is dated when the account was created, or at its earliest
+// transaction if that is older, so balances as of past dates include it.
+func RunLedgerMigrations(db *gorm.DB) error {
+	log.Println("Running ledger migrations...")
+
+	var accounts []models.Account
+	err := db.Where("balance_minor <> 0").
+		Where("id NOT IN (?)", db.Model(&models.Posting{}).Select("account_id").Where("account_id IS NOT NULL")).
+		Find(&accounts).Error
+	if err != nil {
+		log.Printf("Error running ledger migrations: %v", err)
+		return err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, account := range accounts {
+			openedAt, err := accountOpenedAt(tx, &account)
+			if err != nil {
+				return err
+			}
+
+			entry := &models.JournalEntry{
+				UserID:      account.UserID,
+				Type:        models.JournalEntryTypeOpeningBalance,
+				Description: "Opening balance",
+				Date:        openedAt,
+				Postings: []models.Posting{
+					models.NewAccountPosting(account.UserID, account.ID, account.Balance, account.Currency),
+					models.NewLedgerPosting(account.UserID, models.LedgerEquityOpeningBalances, -account.Balance, account.Currency),
+				},
+			}
+			if err := tx.Create(entry).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("Error running ledger migrations: %v", err)
+		return err
+	}
+
+	log.Printf("✓ Ledger migrations completed successfully (%d opening balances)", len(accounts))
+	return nil
+}
+
+// accountOpenedAt returns when an account was created, or the date of its
+// earliest transaction if that is older
+func accountOpenedAt(db *gorm.DB, account *models.Account) (time.Time, error) {
+	var earliest models.Transaction
+	err := db.Unscoped().Where("account_id = ?", account.ID).Order("date ASC").Limit(1).Find(&earliest).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	openedAt := account.CreatedAt
+	if earliest.ID != 0 && earliest.Date.Before(openedAt) {
+		openedAt = earliest.Date
+	}
+	if openedAt.IsZero() {
+		openedAt = time.Now()
+	}
+	return openedAt, nil
+}
@@ -0,0 +1,129 @@
+package migrations
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// moneyColumn describes a legacy floating point amount column and the
+// integer minor-unit column that replaces it
+type moneyColumn struct {
+	Table    string
+	Legacy   string
+	Minor    string
+	Currency string // SQL expression giving the currency of a row; the currency column when empty
+}
+
+// moneyColumns lists every amount column converted to exact minor units
+var moneyColumns = []moneyColumn{
+	{Table: "accounts", Legacy: "balance", Minor: "balance_minor"},
+	{Table: "transactions", Legacy: "amount", Minor: "amount_minor"},
+	{Table: "recurring_transactions", Legacy: "amount", Minor: "amount_minor"},
+	{Table: "budgets", Legacy: "amount", Minor: "amount_minor"},
+	{Table: "budgets", Legacy: "spent", Minor: "spent_minor"},
+	{Table: "goals", Legacy: "target_amount", Minor: "target_amount_minor"},
+	{Table: "goals", Legacy: "current_amount", Minor: "current_amount_minor"},
+	{Table: "balance_histories", Legacy: "balance", Minor: "balance_minor", Currency: balanceHistoryCurrency},
+	{Table: "balance_histories", Legacy: "change_amount", Minor: "change_amount_minor", Currency: balanceHistoryCurrency},
+}
+
+// balanceHistoryCurrency is the currency of a balance history row, which is
+// that of its account
+const balanceHistoryCurrency = "(SELECT accounts.currency FROM accounts WHERE accounts.id = balance_histories.account_id)"
+
+// RunMoneyMigrations converts legacy float64 amount columns into integer
+// minor-unit columns. It must run before AutoMigrate so the new NOT NULL
+// columns are added with a default and backfilled from the old values.
+// Tables that are missing or already converted are skipped.
+func RunMoneyMigrations(db *gorm.DB) error {
+	log.Println("Running money migrations...")
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Transactions and recurring transactions take their currency from the account
+		if err := addCurrencyColumn(tx, "transactions", "amount", "(SELECT accounts.currency FROM accounts WHERE accounts.id = transactions.account_id)"); err != nil {
+			return err
+		}
+		if err := addCurrencyColumn(tx, "recurring_transactions", "amount", "(SELECT accounts.currency FROM accounts WHERE accounts.id = recurring_transactions.account_id)"); err != nil {
+			return err
+		}
+		// Budgets had no currency and were always entered in USD
+		if err := addCurrencyColumn(tx, "budgets", "amount", "'USD'"); err != nil {
+			return err
+		}
+
+		for _, col := range moneyColumns {
+			if err := convertMoneyColumn(tx, col); err != nil {
+				return fmt.Errorf("failed to convert %s.%s: %w", col.Table, col.Legacy, err)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("Error running money migrations: %v", err)
+		return err
+	}
+
+	log.Println("✓ Money migrations completed successfully")
+	return nil
+}
+
+// addCurrencyColumn adds a currency column to a table that still has its
+// legacy amount column, filling it from the given SQL expression
+func addCurrencyColumn(tx *gorm.DB, table, legacyColumn, source string) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable(table) || !migrator.HasColumn(table, legacyColumn) || migrator.HasColumn(table, "currency") {
+		return nil
+	}
+
+	if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD'", table)).Error; err != nil {
+		return err
+	}
+	return tx.Exec(fmt.Sprintf("UPDATE %s SET currency = COALESCE(%s, 'USD')", table, source)).Error
+}
+
+// convertMoneyColumn adds the minor-unit column, backfills it by scaling the
+// legacy value with the currency's minor unit and drops the legacy column
+func convertMoneyColumn(tx *gorm.DB, col moneyColumn) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable(col.Table) || !migrator.HasColumn(col.Table, col.Legacy) {
+		return nil
+	}
+
+	if !migrator.HasColumn(col.Table, col.Minor) {
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s BIGINT NOT NULL DEFAULT 0", col.Table, col.Minor)).Error; err != nil {
+			return err
+		}
+	}
+
+	currency := col.Currency
+	if currency == "" {
+		currency = "currency"
+	}
+	update := fmt.Sprintf("UPDATE %s SET %s = CAST(ROUND(COALESCE(%s, 0) * %s) AS BIGINT)",
+		col.Table, col.Minor, col.Legacy, minorUnitScaleSQL(currency))
+	if err := tx.Exec(update).Error; err != nil {
+		return err
+	}
+
+	return tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", col.Table, col.Legacy)).Error
+}
+
+// minorUnitScaleSQL builds a CASE expression returning the number of minor
+// units per major unit for the currency in currencyExpr
+func minorUnitScaleSQL(currencyExpr string) string {
+	var b strings.Builder
+	b.WriteString("CASE " + currencyExpr)
+	for _, currency := range models.SupportedCurrencies() {
+		if currency.Decimals == 2 {
+			continue
+		}
+		fmt.Fprintf(&b, " WHEN '%s' THEN %s", currency.Code, "1"+strings.Repeat("0", currency.Decimals))
+	}
+	b.WriteString(" ELSE 100 END")
+	return b.String()
+}
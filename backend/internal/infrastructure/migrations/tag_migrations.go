@@ -0,0 +1,95 @@
+package migrations
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// taggedTable is a table whose comma-separated tags column moves to a join table
+type taggedTable struct {
+	model     interface{}
+	table     string
+	joinTable string
+	column    string // Column of the join table holding the ID of the tagged record
+}
+
+// RunTagMigrations moves the comma-separated tags of transactions, recurring
+// transactions and split lines to tags and their join tables, then drops the
+// old columns. Must run after AutoMigrate has created the tag tables.
+func RunTagMigrations(db *gorm.DB) error {
+	log.Println("Running tag migrations...")
+
+	tables := []taggedTable{
+		{model: &models.Transaction{}, table: "transactions", joinTable: "transaction_tags", column: "transaction_id"},
+		{model: &models.RecurringTransaction{}, table: "recurring_transactions", joinTable: "recurring_transaction_tags", column: "recurring_transaction_id"},
+		{model: &models.TransactionSplit{}, table: "transaction_splits", joinTable: "transaction_split_tags", column: "transaction_split_id"},
+	}
+
+	converted := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, t := range tables {
+			// Tag filters look records up by tag
+			if err := tx.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%[1]s_tag_id ON %[1]s (tag_id)", t.joinTable)).Error; err != nil {
+				return err
+			}
+
+			count, err := migrateTagColumn(tx, t)
+			if err != nil {
+				return err
+			}
+			converted += count
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error running tag migrations: %v", err)
+		return err
+	}
+
+	log.Printf("✓ Tag migrations completed successfully (%d records converted)", converted)
+	return nil
+}
+
+// migrateTagColumn attaches tags to the records of a table from its old tags
+// column and drops the column. It returns how many records had tags.
+func migrateTagColumn(tx *gorm.DB, t taggedTable) (int, error) {
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(t.model, "tags") {
+		return 0, nil
+	}
+
+	// Trashed records keep their tags for when they are restored
+	var rows []struct {
+		ID     uint
+		UserID uint
+		Tags   string
+	}
+	err := tx.Table(t.table).
+		Select("id, user_id, tags").
+		Where("tags IS NOT NULL AND tags <> ''").
+		Scan(&rows).Error
+	if err != nil {
+		return 0, err
+	}
+
+	tagRepo := repository.NewTagRepository(tx)
+	insert := fmt.Sprintf("INSERT INTO %s (%s, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING", t.joinTable, t.column)
+	for _, row := range rows {
+		tags, err := tagRepo.FindOrCreate(row.UserID, strings.Split(row.Tags, ","))
+		if err != nil {
+			return 0, err
+		}
+		for _, tag := range tags {
+			if err := tx.Exec(insert, row.ID, tag.ID).Error; err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return len(rows), migrator.DropColumn(t.model, "tags")
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// RunTransactionCurrencyMigrations records the booked amount and currency as
+// the original amount of transactions created before the original currency
+// was tracked. Must run after AutoMigrate has added the original amount columns.
+func RunTransactionCurrencyMigrations(db *gorm.DB) error {
+	log.Println("Running transaction currency migrations...")
+
+	result := db.Unscoped().Model(&models.Transaction{}).
+		Where("original_currency IS NULL OR original_currency = ''").
+		Updates(map[string]interface{}{
+			"original_amount_minor": gorm.Expr("amount_minor"),
+			"original_currency":     gorm.Expr("currency"),
+			"exchange_rate":         1,
+		})
+	if result.Error != nil {
+		log.Printf("Error running transaction currency migrations: %v", result.Error)
+		return result.Error
+	}
+
+	log.Printf("✓ Transaction currency migrations completed successfully (%d transactions updated)", result.RowsAffected)
+	return nil
+}
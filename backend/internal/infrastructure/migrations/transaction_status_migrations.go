@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// RunTransactionStatusMigrations fills in the current and available
+// balances of accounts that existed before they were tracked. Must run
+// after AutoMigrate has added the balance columns.
+func RunTransactionStatusMigrations(db *gorm.DB) error {
+	log.Println("Running transaction status migrations...")
+
+	// Accounts that have never been synced since the columns were added
+	var accountIDs []uint
+	err := db.Model(&models.Account{}).
+		Where("balance_minor <> 0 AND current_balance_minor = 0 AND available_balance_minor = 0").
+		Pluck("id", &accountIDs).Error
+	if err != nil {
+		log.Printf("Error running transaction status migrations: %v", err)
+		return err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		ledgerRepo := repository.NewLedgerRepository(tx)
+		for _, id := range accountIDs {
+			if _, _, err := ledgerRepo.SyncAccountBalanceByID(id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error running transaction status migrations: %v", err)
+		return err
+	}
+
+	log.Printf("✓ Transaction status migrations completed successfully (%d accounts synced)", len(accountIDs))
+	return nil
+}
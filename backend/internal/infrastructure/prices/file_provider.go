@@ -0,0 +1,36 @@
+package prices
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+)
+
+// FileProvider reads closing prices from a local CSV file, for example one
+// exported from a broker or a market data service
+type FileProvider struct {
+	path string
+}
+
+// NewFileProvider creates a provider that reads prices from path
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Name returns the source recorded on prices read from the file
+func (p *FileProvider) Name() string {
+	return "file:" + filepath.Base(p.path)
+}
+
+// Fetch reads every price in the file
+func (p *FileProvider) Fetch(ctx context.Context) ([]models.SecurityPrice, error) {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseCSV(file, p.Name())
+}
@@ -0,0 +1,90 @@
+package prices
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+)
+
+// ErrInvalidPrices is returned for price data that cannot be parsed
+var ErrInvalidPrices = errors.New("invalid security price data")
+
+// Provider supplies dated security prices from an external source
+type Provider interface {
+	// Name identifies the provider and is recorded as the source of its prices
+	Name() string
+	// Fetch returns the prices the provider currently publishes
+	Fetch(ctx context.Context) ([]models.SecurityPrice, error)
+}
+
+// ParseCSV parses closing prices from CSV with a header row naming the date,
+// symbol, price and currency columns, e.g.
+//
+//	date,symbol,price,currency
+//	2024-01-02,AAPL,185.64,USD
+func ParseCSV(r io.Reader, source string) ([]models.SecurityPrice, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrices, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "symbol", "price", "currency"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing %s column", ErrInvalidPrices, name)
+		}
+	}
+
+	var prices []models.SecurityPrice
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPrices, err)
+		}
+
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[columns["date"]]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid date", ErrInvalidPrices, line)
+		}
+		symbol := strings.ToUpper(strings.TrimSpace(record[columns["symbol"]]))
+		if symbol == "" {
+			return nil, fmt.Errorf("%w: line %d: missing symbol", ErrInvalidPrices, line)
+		}
+		currency := strings.ToUpper(strings.TrimSpace(record[columns["currency"]]))
+		if len(currency) != 3 {
+			return nil, fmt.Errorf("%w: line %d: invalid currency %q", ErrInvalidPrices, line, currency)
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[columns["price"]]), 64)
+		if err != nil || price <= 0 {
+			return nil, fmt.Errorf("%w: line %d: invalid price", ErrInvalidPrices, line)
+		}
+
+		prices = append(prices, models.SecurityPrice{
+			Symbol:   symbol,
+			Currency: currency,
+			Date:     models.RateDate(date),
+			Price:    price,
+			Source:   source,
+		})
+	}
+
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("%w: no prices found", ErrInvalidPrices)
+	}
+	return prices, nil
+}
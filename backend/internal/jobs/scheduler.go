@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// JobFunc runs one pass of a background job and returns how many items it processed
+type JobFunc func() (int, error)
+
+// job is a named function run on a fixed interval
+type job struct {
+	name     string
+	interval time.Duration
+	run      JobFunc
+}
+
+// Scheduler runs registered jobs in the background on fixed intervals
+type Scheduler struct {
+	jobs []job
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		stop: make(chan struct{}),
+	}
+}
+
+// Register adds a job that runs every interval once the scheduler is started
+func (s *Scheduler) Register(name string, interval time.Duration, run JobFunc) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+// Start runs every registered job once and then on its interval until Stop is called
+func (s *Scheduler) Start() {
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+}
+
+// Stop stops all jobs and waits for running passes to finish
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// loop runs a job on its interval
+func (s *Scheduler) loop(j job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(j)
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce runs a single pass of a job and logs the outcome
+func (s *Scheduler) runOnce(j job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %q panicked: %v", j.name, r)
+		}
+	}()
+
+	count, err := j.run()
+	if err != nil {
+		log.Printf("Job %q failed: %v", j.name, err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Job %q processed %d items", j.name, count)
+	}
+}
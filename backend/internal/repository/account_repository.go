@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
 	"gorm.io/gorm"
 )
@@ -15,6 +17,11 @@ func NewAccountRepository(db *gorm.DB) *AccountRepository {
 	return &AccountRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *AccountRepository) WithTx(tx *gorm.DB) *AccountRepository {
+	return &AccountRepository{db: tx}
+}
+
 // Create creates a new account
 func (r *AccountRepository) Create(account *models.Account) error {
 	// If this account is set as default, update other accounts
@@ -55,6 +62,20 @@ func (r *AccountRepository) GetAll(userID uint) ([]models.Account, error) {
 	return accounts, nil
 }
 
+// FindAll gets the accounts of a user, or of every user when userID is zero, ordered by ID
+func (r *AccountRepository) FindAll(userID uint) ([]models.Account, error) {
+	query := r.db.Order("id ASC")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var accounts []models.Account
+	if err := query.Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
 // Update updates an account
 func (r *AccountRepository) Update(account *models.Account) error {
 	// If this account is being set as default, update other accounts
@@ -68,7 +89,7 @@ func (r *AccountRepository) Update(account *models.Account) error {
 	return r.db.Save(account).Error
 }
 
-// Delete deletes an account
+// Delete moves an account to the trash
 func (r *AccountRepository) Delete(id uint, userID uint) error {
 	// Check if this is the default account
 	var account models.Account
@@ -80,11 +101,76 @@ func (r *AccountRepository) Delete(id uint, userID uint) error {
 		return gorm.ErrInvalidTransaction // Cannot delete default account
 	}
 
+	// Transactions cannot be left on an account in the trash
+	var count int64
+	if err := r.db.Model(&models.Transaction{}).Where("account_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return models.ErrAccountHasTransactions
+	}
+
 	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Account{}).Error
 }
 
-// GetSummary gets a summary of accounts for a user
-func (r *AccountRepository) GetSummary(userID uint) (*models.AccountSummary, error) {
+// GetDeleted gets the accounts of a user that are in the trash
+func (r *AccountRepository) GetDeleted(userID uint) ([]models.Account, error) {
+	var items []models.Account
+	if err := findDeleted(r.db, &items, userID); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetDeletedBefore gets every account that has been in the trash since before cutoff
+func (r *AccountRepository) GetDeletedBefore(cutoff time.Time) ([]models.Account, error) {
+	var items []models.Account
+	if err := findDeletedBefore(r.db, &items, cutoff); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Restore takes an account out of the trash
+func (r *AccountRepository) Restore(id uint, userID uint) error {
+	return restoreDeleted(r.db, &models.Account{}, id, userID)
+}
+
+// Purge permanently deletes an account that is in the trash together with
+// its balance history and the journal entries still posting to it, such as
+// its opening balance
+func (r *AccountRepository) Purge(id uint, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var account models.Account
+		if err := tx.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+			First(&account).Error; err != nil {
+			return err
+		}
+
+		var entryIDs []uint
+		if err := tx.Model(&models.Posting{}).Where("account_id = ?", id).
+			Distinct().Pluck("journal_entry_id", &entryIDs).Error; err != nil {
+			return err
+		}
+		if len(entryIDs) > 0 {
+			if err := tx.Where("journal_entry_id IN ?", entryIDs).Delete(&models.Posting{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", entryIDs).Delete(&models.JournalEntry{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("account_id = ?", id).Delete(&models.BalanceHistory{}).Error; err != nil {
+			return err
+		}
+		return purgeDeleted(tx, &models.Account{}, id, userID)
+	})
+}
+
+// GetSummary gets a summary of accounts for a user with balances converted
+// into currency at today's rates
+func (r *AccountRepository) GetSummary(userID uint, currency string, rates models.ExchangeRateSource) (*models.AccountSummary, error) {
 	// Get all accounts for the user
 	accounts, err := r.GetAll(userID)
 	if err != nil {
@@ -99,17 +185,26 @@ func (r *AccountRepository) GetSummary(userID uint) (*models.AccountSummary, err
 		NetWorth:         0,
 	}
 
-	// Calculate totals
+	// Calculate totals in minor units of the reporting currency
+	now := time.Now()
+	var totalAssets, totalLiabilities models.Money
 	for _, a := range accounts {
-		if a.Balance > 0 {
-			summary.TotalAssets += a.Balance
+		balance, err := models.ConvertMoneyAt(rates, a.Balance, a.Currency, currency, now)
+		if err != nil {
+			return nil, err
+		}
+		if balance > 0 {
+			totalAssets += balance
 		} else {
-			summary.TotalLiabilities += -a.Balance
+			totalLiabilities -= balance
 		}
 	}
+	summary.Currency = currency
+	summary.TotalAssets = totalAssets.Float(currency)
+	summary.TotalLiabilities = totalLiabilities.Float(currency)
 
 	// Calculate net worth
-	summary.NetWorth = summary.TotalAssets - summary.TotalLiabilities
+	summary.NetWorth = (totalAssets - totalLiabilities).Float(currency)
 
 	return summary, nil
 }
@@ -120,6 +215,7 @@ func (r *AccountRepository) GetAccountTypes() []models.AccountType {
 		{ID: "checking", Name: "Checking Account"},
 		{ID: "savings", Name: "Savings Account"},
 		{ID: "credit", Name: "Credit Card"},
+		{ID: "loan", Name: "Loan / Mortgage"},
 		{ID: "investment", Name: "Investment Account"},
 		{ID: "cash", Name: "Cash"},
 		{ID: "other", Name: "Other"},
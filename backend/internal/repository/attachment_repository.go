@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// AttachmentRepository handles database operations for transaction attachments
+type AttachmentRepository struct {
+	db *gorm.DB
+}
+
+// NewAttachmentRepository creates a new attachment repository
+func NewAttachmentRepository(db *gorm.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+// Create creates a new attachment
+func (r *AttachmentRepository) Create(attachment *models.Attachment) error {
+	return r.db.Create(attachment).Error
+}
+
+// GetByID gets an attachment by ID
+func (r *AttachmentRepository) GetByID(id uint, userID uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&attachment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// FindByID gets an attachment by ID regardless of its owner. Callers must
+// authorize access themselves, e.g. with a signed download link.
+func (r *AttachmentRepository) FindByID(id uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := r.db.First(&attachment, id).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// GetByTransactionID gets the attachments of a transaction, oldest first
+func (r *AttachmentRepository) GetByTransactionID(transactionID uint, userID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.Where("transaction_id = ? AND user_id = ?", transactionID, userID).
+		Order("created_at ASC, id ASC").
+		Find(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// GetByUserID gets all attachments of a user's active transactions
+func (r *AttachmentRepository) GetByUserID(userID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.Where("user_id = ?", userID).
+		Where("transaction_id IN (?)", r.db.Model(&models.Transaction{}).Select("id").Where("user_id = ?", userID)).
+		Order("transaction_id ASC, id ASC").
+		Find(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// GetOrphaned gets attachments whose transaction has been permanently deleted
+func (r *AttachmentRepository) GetOrphaned() ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.
+		Where("transaction_id NOT IN (?)", r.db.Unscoped().Model(&models.Transaction{}).Select("id")).
+		Find(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Delete deletes an attachment record
+func (r *AttachmentRepository) Delete(id uint) error {
+	return r.db.Delete(&models.Attachment{}, id).Error
+}
@@ -17,6 +17,11 @@ func NewBalanceHistoryRepository(db *gorm.DB) *BalanceHistoryRepository {
 	return &BalanceHistoryRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *BalanceHistoryRepository) WithTx(tx *gorm.DB) *BalanceHistoryRepository {
+	return &BalanceHistoryRepository{db: tx}
+}
+
 // Create creates a new balance history record
 func (r *BalanceHistoryRepository) Create(history *models.BalanceHistory) error {
 	return r.db.Create(history).Error
@@ -52,9 +57,18 @@ func (r *BalanceHistoryRepository) GetByDateRange(userID uint, accountID uint, s
 	return history, nil
 }
 
-// GetDailyBalances gets daily aggregated balances for an account
-func (r *BalanceHistoryRepository) GetDailyBalances(userID uint, accountID uint, days int) ([]models.DailyBalance, error) {
-	var results []models.DailyBalance
+// dailyBalanceRow is a day of balance history in minor units of currency
+type dailyBalanceRow struct {
+	Date     string
+	Currency string
+	Balance  models.Money
+	Income   models.Money
+	Expense  models.Money
+}
+
+// GetDailyBalances gets daily aggregated balances for an account in its currency
+func (r *BalanceHistoryRepository) GetDailyBalances(userID uint, accountID uint, currency string, days int) ([]models.DailyBalance, error) {
+	var rows []dailyBalanceRow
 	
 	startDate := time.Now().AddDate(0, 0, -days)
 	
@@ -62,68 +76,95 @@ func (r *BalanceHistoryRepository) GetDailyBalances(userID uint, accountID uint,
 	err := r.db.Raw(`
 		SELECT 
 			DATE(recorded_at) as date,
-			(SELECT balance FROM balance_histories bh2 
+			(SELECT balance_minor FROM balance_histories bh2 
 			 WHERE bh2.account_id = balance_histories.account_id 
 			 AND DATE(bh2.recorded_at) = DATE(balance_histories.recorded_at)
 			 ORDER BY bh2.recorded_at DESC LIMIT 1) as balance,
-			COALESCE(SUM(CASE WHEN change_type = 'income' THEN change_amount ELSE 0 END), 0) as income,
-			COALESCE(SUM(CASE WHEN change_type = 'expense' THEN ABS(change_amount) ELSE 0 END), 0) as expense
+			COALESCE(SUM(CASE WHEN change_type = 'income' THEN change_amount_minor ELSE 0 END), 0) as income,
+			COALESCE(SUM(CASE WHEN change_type = 'expense' THEN ABS(change_amount_minor) ELSE 0 END), 0) as expense
 		FROM balance_histories
 		WHERE user_id = ? AND account_id = ? AND recorded_at >= ?
 		GROUP BY DATE(recorded_at)
 		ORDER BY date ASC
-	`, userID, accountID, startDate).Scan(&results).Error
+	`, userID, accountID, startDate).Scan(&rows).Error
 	
 	if err != nil {
 		return nil, err
 	}
 	
-	return results, nil
+	for i := range rows {
+		rows[i].Currency = currency
+	}
+	return sumDailyBalances(rows), nil
 }
 
 // GetAllAccountsDailyBalances gets daily balances for all accounts
 func (r *BalanceHistoryRepository) GetAllAccountsDailyBalances(userID uint, days int) ([]models.DailyBalance, error) {
-	var results []models.DailyBalance
+	var rows []dailyBalanceRow
 	
 	startDate := time.Now().AddDate(0, 0, -days)
 	
+	// Amounts are summed per currency and converted to major units afterwards
 	err := r.db.Raw(`
 		SELECT 
 			DATE(recorded_at) as date,
-			SUM(balance) as balance,
-			COALESCE(SUM(CASE WHEN change_type = 'income' THEN change_amount ELSE 0 END), 0) as income,
-			COALESCE(SUM(CASE WHEN change_type = 'expense' THEN ABS(change_amount) ELSE 0 END), 0) as expense
+			accounts.currency as currency,
+			SUM(daily_balances.balance_minor) as balance,
+			COALESCE(SUM(CASE WHEN change_type = 'income' THEN change_amount_minor ELSE 0 END), 0) as income,
+			COALESCE(SUM(CASE WHEN change_type = 'expense' THEN ABS(change_amount_minor) ELSE 0 END), 0) as expense
 		FROM (
 			SELECT DISTINCT ON (account_id, DATE(recorded_at))
-				account_id, DATE(recorded_at) as recorded_at, balance, change_type, change_amount
+				account_id, DATE(recorded_at) as recorded_at, balance_minor, change_type, change_amount_minor
 			FROM balance_histories
 			WHERE user_id = ? AND recorded_at >= ?
 			ORDER BY account_id, DATE(recorded_at), recorded_at DESC
 		) daily_balances
-		GROUP BY DATE(recorded_at)
+		JOIN accounts ON accounts.id = daily_balances.account_id
+		GROUP BY DATE(recorded_at), accounts.currency
 		ORDER BY date ASC
-	`, userID, startDate).Scan(&results).Error
+	`, userID, startDate).Scan(&rows).Error
 	
 	if err != nil {
 		// Fallback for SQLite (which doesn't support DISTINCT ON)
 		err = r.db.Raw(`
 			SELECT 
 				DATE(recorded_at) as date,
-				SUM(balance) as balance,
-				COALESCE(SUM(CASE WHEN change_type = 'income' THEN change_amount ELSE 0 END), 0) as income,
-				COALESCE(SUM(CASE WHEN change_type = 'expense' THEN ABS(change_amount) ELSE 0 END), 0) as expense
+				accounts.currency as currency,
+				SUM(balance_histories.balance_minor) as balance,
+				COALESCE(SUM(CASE WHEN change_type = 'income' THEN change_amount_minor ELSE 0 END), 0) as income,
+				COALESCE(SUM(CASE WHEN change_type = 'expense' THEN ABS(change_amount_minor) ELSE 0 END), 0) as expense
 			FROM balance_histories
-			WHERE user_id = ? AND recorded_at >= ?
-			GROUP BY DATE(recorded_at)
+			JOIN accounts ON accounts.id = balance_histories.account_id
+			WHERE balance_histories.user_id = ? AND recorded_at >= ?
+			GROUP BY DATE(recorded_at), accounts.currency
 			ORDER BY date ASC
-		`, userID, startDate).Scan(&results).Error
+		`, userID, startDate).Scan(&rows).Error
+	}
+	if err != nil {
+		return nil, err
 	}
 	
-	return results, err
+	return sumDailyBalances(rows), nil
+}
+
+// sumDailyBalances converts daily rows to major units and adds up the rows
+// of each day, keeping the days in order
+func sumDailyBalances(rows []dailyBalanceRow) []models.DailyBalance {
+	balances := []models.DailyBalance{}
+	for _, row := range rows {
+		if n := len(balances); n == 0 || balances[n-1].Date != row.Date {
+			balances = append(balances, models.DailyBalance{Date: row.Date})
+		}
+		day := &balances[len(balances)-1]
+		day.Balance += row.Balance.Float(row.Currency)
+		day.Income += row.Income.Float(row.Currency)
+		day.Expense += row.Expense.Float(row.Currency)
+	}
+	return balances
 }
 
 // RecordBalanceChange records a balance change
-func (r *BalanceHistoryRepository) RecordBalanceChange(userID uint, accountID uint, newBalance float64, changeAmount float64, changeType string, transactionID *uint, description string) error {
+func (r *BalanceHistoryRepository) RecordBalanceChange(userID uint, accountID uint, newBalance models.Money, changeAmount models.Money, changeType string, transactionID *uint, description string) error {
 	history := &models.BalanceHistory{
 		UserID:        userID,
 		AccountID:     accountID,
@@ -137,3 +178,25 @@ func (r *BalanceHistoryRepository) RecordBalanceChange(userID uint, accountID ui
 	return r.Create(history)
 }
 
+
+// RecordCheckpoint records a balance reported by the bank at a point in
+// time, unless the same checkpoint was recorded before
+func (r *BalanceHistoryRepository) RecordCheckpoint(userID uint, accountID uint, balance models.Money, recordedAt time.Time, description string) error {
+	var count int64
+	err := r.db.Model(&models.BalanceHistory{}).
+		Where("account_id = ? AND change_type = ? AND recorded_at = ? AND balance_minor = ?",
+			accountID, models.BalanceChangeCheckpoint, recordedAt, balance).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+
+	return r.Create(&models.BalanceHistory{
+		UserID:      userID,
+		AccountID:   accountID,
+		Balance:     balance,
+		ChangeType:  models.BalanceChangeCheckpoint,
+		Description: description,
+		RecordedAt:  recordedAt,
+	})
+}
@@ -62,11 +62,39 @@ func (r *BudgetRepository) Update(budget *models.Budget) error {
 	return r.db.Save(budget).Error
 }
 
-// Delete deletes a budget
+// Delete moves a budget to the trash
 func (r *BudgetRepository) Delete(id uint, userID uint) error {
 	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Budget{}).Error
 }
 
+// GetDeleted gets the budgets of a user that are in the trash
+func (r *BudgetRepository) GetDeleted(userID uint) ([]models.Budget, error) {
+	var items []models.Budget
+	if err := findDeleted(r.db, &items, userID); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetDeletedBefore gets every budget that has been in the trash since before cutoff
+func (r *BudgetRepository) GetDeletedBefore(cutoff time.Time) ([]models.Budget, error) {
+	var items []models.Budget
+	if err := findDeletedBefore(r.db, &items, cutoff); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Restore takes a budget out of the trash
+func (r *BudgetRepository) Restore(id uint, userID uint) error {
+	return restoreDeleted(r.db, &models.Budget{}, id, userID)
+}
+
+// Purge permanently deletes a budget that is in the trash
+func (r *BudgetRepository) Purge(id uint, userID uint) error {
+	return purgeDeleted(r.db, &models.Budget{}, id, userID)
+}
+
 // GetBudgetPeriods gets all budget periods
 func (r *BudgetRepository) GetBudgetPeriods() []models.BudgetPeriod {
 	return []models.BudgetPeriod{
@@ -81,8 +109,10 @@ func (r *BudgetRepository) GetByHousehold(householdID uint, budgets *[]models.Bu
 	return r.db.Where("household_id = ?", householdID).Find(budgets).Error
 }
 
-// GetSummary gets a summary of budgets for a user
-func (r *BudgetRepository) GetSummary(userID uint) (*models.BudgetSummary, error) {
+// GetSummary gets a summary of budgets for a user. Budget amounts are
+// converted into currency at the rate in effect at the end of their period,
+// or today for current budgets.
+func (r *BudgetRepository) GetSummary(userID uint, currency string, rates models.ExchangeRateSource) (*models.BudgetSummary, error) {
 	// Get all budgets for the user
 	budgets, err := r.GetAll(userID)
 	if err != nil {
@@ -100,15 +130,29 @@ func (r *BudgetRepository) GetSummary(userID uint) (*models.BudgetSummary, error
 		BudgetsOverLimit: 0,
 	}
 
-	// Calculate totals
+	// Calculate totals in minor units of the reporting currency
+	now := time.Now()
+	var totalBudgeted, totalSpent models.Money
 	for _, b := range budgets {
-		summary.TotalBudgeted += b.Amount
-		summary.TotalSpent += b.Spent
+		date := b.EndDate
+		if date.After(now) {
+			date = now
+		}
+		amount, err := models.ConvertMoneyAt(rates, b.Amount, b.Currency, currency, date)
+		if err != nil {
+			return nil, err
+		}
+		spentAmount, err := models.ConvertMoneyAt(rates, b.Spent, b.Currency, currency, date)
+		if err != nil {
+			return nil, err
+		}
+		totalBudgeted += amount
+		totalSpent += spentAmount
 
 		// Check if budget is near or over limit
 		percentSpent := 0
 		if b.Amount > 0 {
-			percentSpent = int((b.Spent / b.Amount) * 100)
+			percentSpent = int(b.Spent * 100 / b.Amount)
 		}
 
 		if percentSpent >= 100 {
@@ -118,10 +162,14 @@ func (r *BudgetRepository) GetSummary(userID uint) (*models.BudgetSummary, error
 		}
 	}
 
+	summary.Currency = currency
+	summary.TotalBudgeted = totalBudgeted.Float(currency)
+	summary.TotalSpent = totalSpent.Float(currency)
+
 	// Calculate remaining and overall progress
-	summary.TotalRemaining = summary.TotalBudgeted - summary.TotalSpent
-	if summary.TotalBudgeted > 0 {
-		summary.OverallProgress = int((summary.TotalSpent / summary.TotalBudgeted) * 100)
+	summary.TotalRemaining = (totalBudgeted - totalSpent).Float(currency)
+	if totalBudgeted > 0 {
+		summary.OverallProgress = int(totalSpent * 100 / totalBudgeted)
 		if summary.OverallProgress > 100 {
 			summary.OverallProgress = 100
 		}
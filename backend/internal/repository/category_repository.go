@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
 	"gorm.io/gorm"
 )
@@ -126,3 +129,80 @@ func (r *CategoryRepository) GetUserCategories(userID uint) ([]models.Category,
 	return categories, nil
 }
 
+
+// FindOrCreatePath gets the category at the end of a path of category names
+// separated by colons, e.g. Food:Coffee, creating the levels that do not
+// exist yet as subcategories of the level before them
+func (r *CategoryRepository) FindOrCreatePath(userID uint, path string, categoryType models.CategoryType) (*models.Category, error) {
+	var parent *models.Category
+	for _, name := range strings.Split(path, ":") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		query := r.db.Where("(user_id = ? OR is_system = ?) AND name = ? AND is_active = ?", userID, true, name, true)
+		if parent == nil {
+			query = query.Where("parent_id IS NULL")
+		} else {
+			query = query.Where("parent_id = ?", parent.ID)
+		}
+
+		var category models.Category
+		err := query.First(&category).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			category = models.Category{UserID: userID, Name: name, Type: categoryType, IsActive: true}
+			if parent != nil {
+				category.ParentID = &parent.ID
+			}
+			err = r.db.Create(&category).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent = &category
+	}
+
+	if parent == nil {
+		return nil, errors.New("category path is empty")
+	}
+	return parent, nil
+}
+
+// GetPaths gets the path of each active category of a user, including
+// system categories, keyed by category name; a path lists the names from
+// the top-level category down separated by colons, e.g. Food:Coffee
+func (r *CategoryRepository) GetPaths(userID uint) (map[string]string, error) {
+	var categories []models.Category
+	err := r.db.Where("(user_id = ? OR is_system = ?) AND is_active = ?", userID, true, true).
+		Order("user_id DESC, id ASC").
+		Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]*models.Category, len(categories))
+	for i := range categories {
+		byID[categories[i].ID] = &categories[i]
+	}
+
+	paths := make(map[string]string, len(categories))
+	for i := range categories {
+		if _, ok := paths[categories[i].Name]; ok {
+			continue
+		}
+		path := categories[i].Name
+		seen := map[uint]bool{categories[i].ID: true}
+		for parentID := categories[i].ParentID; parentID != nil && !seen[*parentID]; {
+			parent, ok := byID[*parentID]
+			if !ok {
+				break
+			}
+			seen[parent.ID] = true
+			path = parent.Name + ":" + path
+			parentID = parent.ParentID
+		}
+		paths[categories[i].Name] = path
+	}
+	return paths, nil
+}
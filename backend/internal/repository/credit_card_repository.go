@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// CreditCardRepository handles database operations for credit cards and their statements
+type CreditCardRepository struct {
+	db *gorm.DB
+}
+
+// NewCreditCardRepository creates a new credit card repository
+func NewCreditCardRepository(db *gorm.DB) *CreditCardRepository {
+	return &CreditCardRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *CreditCardRepository) WithTx(tx *gorm.DB) *CreditCardRepository {
+	return &CreditCardRepository{db: tx}
+}
+
+// Save creates or updates the card details of an account
+func (r *CreditCardRepository) Save(card *models.CreditCard) error {
+	return r.db.Save(card).Error
+}
+
+// GetByAccountID gets the card details of an account
+func (r *CreditCardRepository) GetByAccountID(accountID uint, userID uint) (*models.CreditCard, error) {
+	var card models.CreditCard
+	err := r.db.Where("account_id = ? AND user_id = ?", accountID, userID).First(&card).Error
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// GetActive gets the cards of every account that is not in the trash
+func (r *CreditCardRepository) GetActive() ([]models.CreditCard, error) {
+	var cards []models.CreditCard
+	err := r.db.Where("account_id IN (?)", r.db.Model(&models.Account{}).Select("id")).
+		Order("id ASC").
+		Find(&cards).Error
+	if err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// Delete deletes the card details of an account together with its statements
+func (r *CreditCardRepository) Delete(accountID uint, userID uint) error {
+	if err := r.db.Where("account_id = ? AND user_id = ?", accountID, userID).
+		Delete(&models.CreditCardStatement{}).Error; err != nil {
+		return err
+	}
+	return r.db.Where("account_id = ? AND user_id = ?", accountID, userID).
+		Delete(&models.CreditCard{}).Error
+}
+
+// CreateStatement creates a new statement
+func (r *CreditCardRepository) CreateStatement(statement *models.CreditCardStatement) error {
+	return r.db.Create(statement).Error
+}
+
+// UpdateStatement updates a statement
+func (r *CreditCardRepository) UpdateStatement(statement *models.CreditCardStatement) error {
+	return r.db.Save(statement).Error
+}
+
+// GetStatements gets the statements of an account, most recent first
+func (r *CreditCardRepository) GetStatements(accountID uint, userID uint) ([]models.CreditCardStatement, error) {
+	var statements []models.CreditCardStatement
+	err := r.db.Where("account_id = ? AND user_id = ?", accountID, userID).
+		Order("closing_date DESC").
+		Find(&statements).Error
+	if err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+// GetLatestStatement gets the most recently closed statement of an account
+func (r *CreditCardRepository) GetLatestStatement(accountID uint) (*models.CreditCardStatement, error) {
+	var statement models.CreditCardStatement
+	err := r.db.Where("account_id = ?", accountID).
+		Order("closing_date DESC").
+		First(&statement).Error
+	if err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// GetUnsettledStatements gets the statements of an account that are not paid in full, oldest first
+func (r *CreditCardRepository) GetUnsettledStatements(accountID uint) ([]models.CreditCardStatement, error) {
+	var statements []models.CreditCardStatement
+	err := r.db.Where("account_id = ? AND status <> ?", accountID, models.StatementStatusPaid).
+		Order("closing_date ASC").
+		Find(&statements).Error
+	if err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
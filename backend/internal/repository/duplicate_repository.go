@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DuplicateRepository handles database operations for reviewed duplicate transactions
+type DuplicateRepository struct {
+	db *gorm.DB
+}
+
+// NewDuplicateRepository creates a new duplicate repository
+func NewDuplicateRepository(db *gorm.DB) *DuplicateRepository {
+	return &DuplicateRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *DuplicateRepository) WithTx(tx *gorm.DB) *DuplicateRepository {
+	return &DuplicateRepository{db: tx}
+}
+
+// Dismiss records that two transactions of a user are not duplicates
+func (r *DuplicateRepository) Dismiss(userID uint, transactionID, duplicateID uint) error {
+	if transactionID > duplicateID {
+		transactionID, duplicateID = duplicateID, transactionID
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.DuplicateDismissal{
+		UserID:        userID,
+		TransactionID: transactionID,
+		DuplicateID:   duplicateID,
+	}).Error
+}
+
+// GetDismissed gets the pairs of transactions a user dismissed as not
+// duplicates, keyed by their IDs with the lower ID first
+func (r *DuplicateRepository) GetDismissed(userID uint) (map[[2]uint]bool, error) {
+	var dismissals []models.DuplicateDismissal
+	if err := r.db.Where("user_id = ?", userID).Find(&dismissals).Error; err != nil {
+		return nil, err
+	}
+
+	dismissed := make(map[[2]uint]bool, len(dismissals))
+	for _, d := range dismissals {
+		dismissed[[2]uint{d.TransactionID, d.DuplicateID}] = true
+	}
+	return dismissed, nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExchangeRateRepository handles database operations for dated exchange rates
+type ExchangeRateRepository struct {
+	db *gorm.DB
+}
+
+// NewExchangeRateRepository creates a new exchange rate repository
+func NewExchangeRateRepository(db *gorm.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db: db}
+}
+
+// Upsert saves rates, replacing any existing rate for the same pair and date
+func (r *ExchangeRateRepository) Upsert(rates []models.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base_currency"}, {Name: "quote_currency"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).CreateInBatches(rates, 500).Error
+}
+
+// Find gets the rate from one currency to another in effect on date, which is
+// the one for the nearest earlier day that has a rate. Inverse pairs and cross
+// rates through a shared base currency are used when the pair itself is not
+// stored. Returns models.ErrExchangeRateNotFound when no rate is known.
+func (r *ExchangeRateRepository) Find(fromCurrency, toCurrency string, date time.Time) (*models.ExchangeRate, error) {
+	date = models.RateDate(date)
+
+	// Direct and inverse pair
+	rate, err := r.findPair(fromCurrency, toCurrency, date)
+	if err == nil {
+		return rate, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	rate, err = r.findPair(toCurrency, fromCurrency, date)
+	if err == nil {
+		return rate.Inverse(), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Cross rate through a base both currencies are quoted against, e.g. EUR for ECB rates
+	var bases []string
+	err = r.db.Model(&models.ExchangeRate{}).
+		Distinct("base_currency").
+		Where("quote_currency IN ? AND date <= ?", []string{fromCurrency, toCurrency}, date).
+		Pluck("base_currency", &bases).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, base := range bases {
+		fromLeg, err := r.findPair(base, fromCurrency, date)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		toLeg, err := r.findPair(base, toCurrency, date)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// The cross rate is only as recent as its older leg
+		rateDate := fromLeg.Date
+		if toLeg.Date.Before(rateDate) {
+			rateDate = toLeg.Date
+		}
+		return &models.ExchangeRate{
+			BaseCurrency:  fromCurrency,
+			QuoteCurrency: toCurrency,
+			Date:          rateDate,
+			Rate:          toLeg.Rate / fromLeg.Rate,
+			Source:        fromLeg.Source,
+		}, nil
+	}
+
+	return nil, models.ErrExchangeRateNotFound
+}
+
+// Rate returns the rate from one currency to another in effect on date
+func (r *ExchangeRateRepository) Rate(fromCurrency, toCurrency string, date time.Time) (float64, error) {
+	if fromCurrency == toCurrency {
+		return 1, nil
+	}
+	rate, err := r.Find(fromCurrency, toCurrency, date)
+	if err != nil {
+		return 0, err
+	}
+	return rate.Rate, nil
+}
+
+// GetByDate gets the rates from a base currency in effect on date
+func (r *ExchangeRateRepository) GetByDate(baseCurrency string, date time.Time) ([]models.ExchangeRate, error) {
+	date = models.RateDate(date)
+
+	// Latest date at or before the requested one
+	var latest models.ExchangeRate
+	err := r.db.Where("base_currency = ? AND date <= ?", baseCurrency, date).
+		Order("date DESC").
+		First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []models.ExchangeRate{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rates []models.ExchangeRate
+	err = r.db.Where("base_currency = ? AND date = ?", baseCurrency, latest.Date).
+		Order("quote_currency ASC").
+		Find(&rates).Error
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// findPair gets the most recent stored rate for a pair at or before date
+func (r *ExchangeRateRepository) findPair(baseCurrency, quoteCurrency string, date time.Time) (*models.ExchangeRate, error) {
+	if baseCurrency == quoteCurrency {
+		return &models.ExchangeRate{
+			BaseCurrency:  baseCurrency,
+			QuoteCurrency: quoteCurrency,
+			Date:          date,
+			Rate:          1,
+		}, nil
+	}
+
+	var rate models.ExchangeRate
+	err := r.db.Where("base_currency = ? AND quote_currency = ? AND date <= ?", baseCurrency, quoteCurrency, date).
+		Order("date DESC").
+		First(&rate).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
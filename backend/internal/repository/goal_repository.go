@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
 	"gorm.io/gorm"
 )
@@ -71,11 +73,39 @@ func (r *GoalRepository) Update(goal *models.Goal) error {
 	return r.db.Save(goal).Error
 }
 
-// Delete deletes a goal
+// Delete moves a goal to the trash
 func (r *GoalRepository) Delete(id uint, userID uint) error {
 	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Goal{}).Error
 }
 
+// GetDeleted gets the goals of a user that are in the trash
+func (r *GoalRepository) GetDeleted(userID uint) ([]models.Goal, error) {
+	var items []models.Goal
+	if err := findDeleted(r.db, &items, userID); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetDeletedBefore gets every goal that has been in the trash since before cutoff
+func (r *GoalRepository) GetDeletedBefore(cutoff time.Time) ([]models.Goal, error) {
+	var items []models.Goal
+	if err := findDeletedBefore(r.db, &items, cutoff); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Restore takes a goal out of the trash
+func (r *GoalRepository) Restore(id uint, userID uint) error {
+	return restoreDeleted(r.db, &models.Goal{}, id, userID)
+}
+
+// Purge permanently deletes a goal that is in the trash
+func (r *GoalRepository) Purge(id uint, userID uint) error {
+	return purgeDeleted(r.db, &models.Goal{}, id, userID)
+}
+
 // GetByHousehold gets all goals for a household
 func (r *GoalRepository) GetByHousehold(householdID uint, goals *[]models.Goal) error {
 	return r.db.Where("household_id = ?", householdID).
@@ -99,9 +129,12 @@ func (r *GoalRepository) GetSummary(userID uint) (*models.GoalSummary, error) {
 		OverallProgress:   0,
 	}
 
+	// Calculate totals per currency in minor units
+	target := models.MoneyTotals{}
+	saved := models.MoneyTotals{}
 	for _, g := range goals {
-		summary.TotalTargetAmount += g.TargetAmount
-		summary.TotalSavedAmount += g.CurrentAmount
+		target.Add(g.Currency, g.TargetAmount)
+		saved.Add(g.Currency, g.CurrentAmount)
 
 		if g.IsCompleted {
 			summary.CompletedGoals++
@@ -110,8 +143,16 @@ func (r *GoalRepository) GetSummary(userID uint) (*models.GoalSummary, error) {
 		}
 	}
 
-	if summary.TotalTargetAmount > 0 {
-		summary.OverallProgress = (summary.TotalSavedAmount / summary.TotalTargetAmount) * 100
+	// Convert totals into a single reporting currency
+	currency := models.ReportingCurrency(target, saved)
+	totalTarget := target.Sum(currency)
+	totalSaved := saved.Sum(currency)
+	summary.Currency = currency
+	summary.TotalTargetAmount = totalTarget.Float(currency)
+	summary.TotalSavedAmount = totalSaved.Float(currency)
+
+	if totalTarget > 0 {
+		summary.OverallProgress = (float64(totalSaved) / float64(totalTarget)) * 100
 	}
 
 	return summary, nil
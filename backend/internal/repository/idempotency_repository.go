@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyRepository handles database operations for idempotency keys
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository creates a new idempotency repository
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve saves a new idempotency key unless the user already holds an
+// unexpired key with the same value. It reports whether the key was saved.
+func (r *IdempotencyRepository) Reserve(key *models.IdempotencyKey) (bool, error) {
+	// An expired key can be used again
+	if err := r.db.Where("user_id = ? AND idempotency_key = ? AND expires_at <= ?", key.UserID, key.Key, time.Now()).
+		Delete(&models.IdempotencyKey{}).Error; err != nil {
+		return false, err
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetByKey gets an idempotency key of a user
+func (r *IdempotencyRepository) GetByKey(userID uint, key string) (*models.IdempotencyKey, error) {
+	var idempotencyKey models.IdempotencyKey
+	err := r.db.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&idempotencyKey).Error
+	if err != nil {
+		return nil, err
+	}
+	return &idempotencyKey, nil
+}
+
+// Complete stores the response of the request an idempotency key was reserved for
+func (r *IdempotencyRepository) Complete(key *models.IdempotencyKey) error {
+	return r.db.Model(&models.IdempotencyKey{}).
+		Where("user_id = ? AND idempotency_key = ?", key.UserID, key.Key).
+		Updates(map[string]interface{}{
+			"status_code":  key.StatusCode,
+			"content_type": key.ContentType,
+			"response":     key.Response,
+		}).Error
+}
+
+// Delete removes an idempotency key of a user
+func (r *IdempotencyRepository) Delete(userID uint, key string) error {
+	return r.db.Where("user_id = ? AND idempotency_key = ?", userID, key).Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpired removes idempotency keys that expired before now
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// ImportProfileRepository handles database operations for CSV import profiles
+type ImportProfileRepository struct {
+	db *gorm.DB
+}
+
+// NewImportProfileRepository creates a new import profile repository
+func NewImportProfileRepository(db *gorm.DB) *ImportProfileRepository {
+	return &ImportProfileRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *ImportProfileRepository) WithTx(tx *gorm.DB) *ImportProfileRepository {
+	return &ImportProfileRepository{db: tx}
+}
+
+// Create creates a new import profile
+func (r *ImportProfileRepository) Create(profile *models.ImportProfile) error {
+	return r.db.Create(profile).Error
+}
+
+// GetByID gets an import profile of a user by ID
+func (r *ImportProfileRepository) GetByID(id uint, userID uint) (*models.ImportProfile, error) {
+	var profile models.ImportProfile
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&profile).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetAll gets all import profiles of a user by name
+func (r *ImportProfileRepository) GetAll(userID uint) ([]models.ImportProfile, error) {
+	var profiles []models.ImportProfile
+	err := r.db.Where("user_id = ?", userID).Order("name ASC, id ASC").Find(&profiles).Error
+	if err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// Update updates an import profile
+func (r *ImportProfileRepository) Update(profile *models.ImportProfile) error {
+	return r.db.Save(profile).Error
+}
+
+// Delete deletes an import profile of a user
+func (r *ImportProfileRepository) Delete(id uint, userID uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.ImportProfile{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
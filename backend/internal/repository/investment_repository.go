@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// InvestmentRepository handles database operations for securities, investment
+// transactions, lots and realized gains
+type InvestmentRepository struct {
+	db *gorm.DB
+}
+
+// NewInvestmentRepository creates a new investment repository
+func NewInvestmentRepository(db *gorm.DB) *InvestmentRepository {
+	return &InvestmentRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *InvestmentRepository) WithTx(tx *gorm.DB) *InvestmentRepository {
+	return &InvestmentRepository{db: tx}
+}
+
+// CreateSecurity creates a new security
+func (r *InvestmentRepository) CreateSecurity(security *models.Security) error {
+	return r.db.Create(security).Error
+}
+
+// GetSecurityByID gets a security of a user
+func (r *InvestmentRepository) GetSecurityByID(id uint, userID uint) (*models.Security, error) {
+	var security models.Security
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&security).Error
+	if err != nil {
+		return nil, err
+	}
+	return &security, nil
+}
+
+// GetSecurityBySymbol gets a security of a user by its symbol
+func (r *InvestmentRepository) GetSecurityBySymbol(symbol string, userID uint) (*models.Security, error) {
+	var security models.Security
+	err := r.db.Where("symbol = ? AND user_id = ?", symbol, userID).First(&security).Error
+	if err != nil {
+		return nil, err
+	}
+	return &security, nil
+}
+
+// GetSecurities gets the securities of a user ordered by symbol
+func (r *InvestmentRepository) GetSecurities(userID uint) ([]models.Security, error) {
+	var securities []models.Security
+	err := r.db.Where("user_id = ?", userID).
+		Order("symbol ASC").
+		Find(&securities).Error
+	if err != nil {
+		return nil, err
+	}
+	return securities, nil
+}
+
+// DeleteSecurity deletes a security
+func (r *InvestmentRepository) DeleteSecurity(security *models.Security) error {
+	return r.db.Delete(&models.Security{}, security.ID).Error
+}
+
+// CountTransactions counts the investment transactions recorded for a security
+func (r *InvestmentRepository) CountTransactions(securityID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.InvestmentTransaction{}).
+		Where("security_id = ?", securityID).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateTransaction records an investment transaction
+func (r *InvestmentRepository) CreateTransaction(transaction *models.InvestmentTransaction) error {
+	return r.db.Create(transaction).Error
+}
+
+// UpdateTransaction saves changes to an investment transaction
+func (r *InvestmentRepository) UpdateTransaction(transaction *models.InvestmentTransaction) error {
+	return r.db.Save(transaction).Error
+}
+
+// GetTransactions gets the investment transactions of a user, optionally for
+// a single account, most recent first
+func (r *InvestmentRepository) GetTransactions(userID uint, accountID *uint) ([]models.InvestmentTransaction, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if accountID != nil {
+		query = query.Where("account_id = ?", *accountID)
+	}
+
+	var transactions []models.InvestmentTransaction
+	err := query.Preload("Security").
+		Order("date DESC, id DESC").
+		Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// GetLastTrade gets the most recent buy or sell of a security at or before date
+func (r *InvestmentRepository) GetLastTrade(securityID uint, date time.Time) (*models.InvestmentTransaction, error) {
+	var transaction models.InvestmentTransaction
+	err := r.db.Where("security_id = ? AND type IN ? AND date <= ?", securityID,
+		[]string{models.InvestmentTypeBuy, models.InvestmentTypeSell}, date).
+		Order("date DESC, id DESC").
+		First(&transaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// GetSplits gets the splits of a security recorded in an account after date
+func (r *InvestmentRepository) GetSplits(accountID, securityID uint, after time.Time) ([]models.InvestmentTransaction, error) {
+	var splits []models.InvestmentTransaction
+	err := r.db.Where("account_id = ? AND security_id = ? AND type = ? AND date > ?",
+		accountID, securityID, models.InvestmentTypeSplit, after).
+		Find(&splits).Error
+	if err != nil {
+		return nil, err
+	}
+	return splits, nil
+}
+
+// CreateLot records a new lot
+func (r *InvestmentRepository) CreateLot(lot *models.Lot) error {
+	return r.db.Create(lot).Error
+}
+
+// GetOpenLots gets the lots of a holding with shares left that were acquired
+// at or before date, oldest first
+func (r *InvestmentRepository) GetOpenLots(accountID, securityID uint, date time.Time) ([]models.Lot, error) {
+	var lots []models.Lot
+	err := r.db.Where("account_id = ? AND security_id = ? AND remaining_micro > 0 AND acquired_at <= ?", accountID, securityID, date).
+		Order("acquired_at ASC, id ASC").
+		Find(&lots).Error
+	if err != nil {
+		return nil, err
+	}
+	return lots, nil
+}
+
+// GetUserOpenLots gets the lots of a user with shares left, optionally for a
+// single account, oldest first. Lots of accounts in the trash are left out.
+func (r *InvestmentRepository) GetUserOpenLots(userID uint, accountID *uint) ([]models.Lot, error) {
+	query := r.db.Where("user_id = ? AND remaining_micro > 0", userID).
+		Where("account_id IN (?)", r.db.Model(&models.Account{}).Select("id"))
+	if accountID != nil {
+		query = query.Where("account_id = ?", *accountID)
+	}
+
+	var lots []models.Lot
+	err := query.Order("acquired_at ASC, id ASC").Find(&lots).Error
+	if err != nil {
+		return nil, err
+	}
+	return lots, nil
+}
+
+// UpdateLotRemaining sets the shares and cost left in a lot, provided its
+// remaining shares are still what the caller read. Returns false when
+// another sale or split changed the lot first.
+func (r *InvestmentRepository) UpdateLotRemaining(lot *models.Lot, quantity, remaining models.Shares, remainingCost models.Money) (bool, error) {
+	result := r.db.Model(&models.Lot{}).
+		Where("id = ? AND remaining_micro = ?", lot.ID, lot.Remaining).
+		Updates(map[string]interface{}{
+			"quantity_micro":       quantity,
+			"remaining_micro":      remaining,
+			"remaining_cost_minor": remainingCost,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CreateRealizedGain records the gain of a sale from one lot
+func (r *InvestmentRepository) CreateRealizedGain(gain *models.RealizedGain) error {
+	return r.db.Create(gain).Error
+}
+
+// GetRealizedGains gets the realized gains of a user for sales between start
+// and end, oldest first
+func (r *InvestmentRepository) GetRealizedGains(userID uint, start, end time.Time) ([]models.RealizedGain, error) {
+	var gains []models.RealizedGain
+	err := r.db.Where("user_id = ? AND sold_at >= ? AND sold_at <= ?", userID, start, end).
+		Preload("Security").
+		Order("sold_at ASC, id ASC").
+		Find(&gains).Error
+	if err != nil {
+		return nil, err
+	}
+	return gains, nil
+}
@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockAccountRow locks account rows against concurrent balance changes. NO KEY
+// UPDATE still lets other transactions insert rows referencing the account.
+// SQLite has no row locks and serializes writers instead.
+var lockAccountRow = clause.Locking{Strength: "NO KEY UPDATE"}
+
+// lockRow locks a row read to be changed, so a concurrent change of it waits
+// and then reads it as committed
+var lockRow = clause.Locking{Strength: clause.LockingStrengthUpdate}
+
+// LedgerRepository handles database operations for journal entries and postings
+type LedgerRepository struct {
+	db *gorm.DB
+}
+
+// NewLedgerRepository creates a new ledger repository
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *LedgerRepository) WithTx(tx *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{db: tx}
+}
+
+// CreateEntry validates and saves a journal entry together with its postings
+func (r *LedgerRepository) CreateEntry(entry *models.JournalEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	return r.db.Create(entry).Error
+}
+
+// PostTransaction records the journal entry for an income or expense
+// transaction and links it through JournalEntryID. It must be called before
+// the transaction row is saved.
+func (r *LedgerRepository) PostTransaction(t *models.Transaction, entryType string) error {
+	entry := models.NewTransactionJournalEntry(t, entryType)
+	if err := r.CreateEntry(entry); err != nil {
+		return err
+	}
+	t.JournalEntryID = &entry.ID
+	return nil
+}
+
+// GetEntryByID gets a journal entry with its postings
+func (r *LedgerRepository) GetEntryByID(id uint, userID uint) (*models.JournalEntry, error) {
+	var entry models.JournalEntry
+	err := r.db.Preload("Postings").
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetEntries gets the most recent journal entries for a user, optionally
+// limited to entries that touch a specific account
+func (r *LedgerRepository) GetEntries(userID uint, accountID *uint, limit int) ([]models.JournalEntry, error) {
+	var entries []models.JournalEntry
+	query := r.db.Preload("Postings").Where("user_id = ?", userID)
+
+	if accountID != nil {
+		query = query.Where("id IN (?)", r.db.Model(&models.Posting{}).
+			Select("journal_entry_id").
+			Where("account_id = ?", *accountID))
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	err := query.Order("date DESC, id DESC").Find(&entries).Error
+	return entries, err
+}
+
+// DeleteEntry deletes a journal entry and its postings
+func (r *LedgerRepository) DeleteEntry(id uint, userID uint) error {
+	if err := r.db.Where("journal_entry_id = ? AND user_id = ?", id, userID).Delete(&models.Posting{}).Error; err != nil {
+		return err
+	}
+	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.JournalEntry{}).Error
+}
+
+// CountAccountPostings counts the postings made to an account
+func (r *LedgerRepository) CountAccountPostings(accountID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Posting{}).Where("account_id = ?", accountID).Count(&count).Error
+	return count, err
+}
+
+// GetAccountBalance sums the postings of an account
+func (r *LedgerRepository) GetAccountBalance(accountID uint) (models.Money, error) {
+	var balance models.Money
+	err := r.db.Model(&models.Posting{}).
+		Select("COALESCE(SUM(amount_minor), 0)").
+		Where("account_id = ?", accountID).
+		Scan(&balance).Error
+	return balance, err
+}
+
+// AccountEntryEffect is the net effect of one journal entry on an account
+type AccountEntryEffect struct {
+	JournalEntryID uint
+	Type           string
+	Amount         models.Money
+	CreatedAt      time.Time
+}
+
+// GetAccountEntryEffects sums the postings of an account per journal entry
+func (r *LedgerRepository) GetAccountEntryEffects(accountID uint) ([]AccountEntryEffect, error) {
+	var effects []AccountEntryEffect
+	err := r.db.Table("postings").
+		Select("postings.journal_entry_id, journal_entries.type, journal_entries.created_at, "+
+			"COALESCE(SUM(postings.amount_minor), 0) AS amount").
+		Joins("JOIN journal_entries ON journal_entries.id = postings.journal_entry_id").
+		Where("postings.account_id = ?", accountID).
+		Group("postings.journal_entry_id, journal_entries.type, journal_entries.created_at").
+		Order("postings.journal_entry_id").
+		Scan(&effects).Error
+	return effects, err
+}
+
+// GetAccountBalanceBefore sums the postings of an account dated before a cutoff
+func (r *LedgerRepository) GetAccountBalanceBefore(accountID uint, cutoff time.Time) (models.Money, error) {
+	var balance models.Money
+	err := r.db.Table("postings").
+		Select("COALESCE(SUM(postings.amount_minor), 0)").
+		Joins("JOIN journal_entries ON journal_entries.id = postings.journal_entry_id").
+		Where("postings.account_id = ? AND journal_entries.date < ?", accountID, cutoff).
+		Scan(&balance).Error
+	return balance, err
+}
+
+// GetAccountInflows sums the money paid into an account by postings dated
+// from start up to but not including end
+func (r *LedgerRepository) GetAccountInflows(accountID uint, start, end time.Time) (models.Money, error) {
+	var inflows models.Money
+	err := r.db.Table("postings").
+		Select("COALESCE(SUM(postings.amount_minor), 0)").
+		Joins("JOIN journal_entries ON journal_entries.id = postings.journal_entry_id").
+		Where("postings.account_id = ? AND postings.amount_minor > 0 AND journal_entries.date >= ? AND journal_entries.date < ?",
+			accountID, start, end).
+		Scan(&inflows).Error
+	return inflows, err
+}
+
+// GetPendingTotals sums the postings of an account made by pending
+// transactions, in total and for money coming in only
+func (r *LedgerRepository) GetPendingTotals(accountID uint) (pending models.Money, pendingIncome models.Money, err error) {
+	var totals struct {
+		Pending       models.Money
+		PendingIncome models.Money
+	}
+	err = r.db.Table("postings").
+		Select("COALESCE(SUM(postings.amount_minor), 0) AS pending, "+
+			"COALESCE(SUM(CASE WHEN postings.amount_minor > 0 THEN postings.amount_minor ELSE 0 END), 0) AS pending_income").
+		Joins("JOIN transactions ON transactions.journal_entry_id = postings.journal_entry_id AND transactions.account_id = postings.account_id").
+		Where("postings.account_id = ? AND transactions.status = ?", accountID, models.TransactionStatusPending).
+		Scan(&totals).Error
+	return totals.Pending, totals.PendingIncome, err
+}
+
+// LockAccounts locks account rows in ascending ID order until the transaction
+// ends, so transactions touching the same accounts queue instead of deadlocking
+func (r *LedgerRepository) LockAccounts(accountIDs ...uint) error {
+	var locked []uint
+	return r.db.Model(&models.Account{}).Clauses(lockAccountRow).
+		Where("id IN ?", accountIDs).
+		Order("id ASC").
+		Pluck("id", &locked).Error
+}
+
+// SyncAccountBalance derives the account balances from its postings, stores
+// them and returns how much the stored balance changed
+func (r *LedgerRepository) SyncAccountBalance(account *models.Account) (models.Money, error) {
+	// Lock the account so the postings are summed after any concurrent change has committed
+	var stored models.Account
+	if err := r.db.Clauses(lockAccountRow).Select("id, user_id, currency, balance_minor").First(&stored, account.ID).Error; err != nil {
+		return 0, err
+	}
+
+	balance, err := r.GetAccountBalance(account.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	pending, pendingIncome, err := r.GetPendingTotals(account.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := r.db.Model(&models.Account{}).
+		Where("id = ?", account.ID).
+		UpdateColumns(map[string]interface{}{
+			"balance_minor":           balance,
+			"current_balance_minor":   balance - pending,
+			"available_balance_minor": balance - pendingIncome,
+		}).Error; err != nil {
+		return 0, err
+	}
+
+	account.UserID = stored.UserID
+	account.Currency = stored.Currency
+	account.Balance = balance
+	account.CurrentBalance = balance - pending
+	account.AvailableBalance = balance - pendingIncome
+	return balance - stored.Balance, nil
+}
+
+// SyncAccountBalanceByID derives and stores the balance of an account by ID
+func (r *LedgerRepository) SyncAccountBalanceByID(accountID uint) (*models.Account, models.Money, error) {
+	account := &models.Account{ID: accountID}
+	delta, err := r.SyncAccountBalance(account)
+	if err != nil {
+		return nil, 0, err
+	}
+	return account, delta, nil
+}
+
+// LedgerCurrencyTotal holds raw debit and credit sums for a currency
+type LedgerCurrencyTotal struct {
+	Currency string
+	Debits   models.Money
+	Credits  models.Money
+}
+
+// GetCurrencyTotals sums all debits and credits of a user per currency
+func (r *LedgerRepository) GetCurrencyTotals(userID uint) ([]LedgerCurrencyTotal, error) {
+	var totals []LedgerCurrencyTotal
+	err := r.db.Model(&models.Posting{}).
+		Select("currency, "+
+			"COALESCE(SUM(CASE WHEN amount_minor > 0 THEN amount_minor ELSE 0 END), 0) AS debits, "+
+			"COALESCE(SUM(CASE WHEN amount_minor < 0 THEN -amount_minor ELSE 0 END), 0) AS credits").
+		Where("user_id = ?", userID).
+		Group("currency").
+		Order("currency").
+		Scan(&totals).Error
+	return totals, err
+}
+
+// GetUnbalancedEntryIDs returns the IDs of entries whose postings do not sum to zero
+func (r *LedgerRepository) GetUnbalancedEntryIDs(userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Posting{}).
+		Where("user_id = ?", userID).
+		Group("journal_entry_id, currency").
+		Having("SUM(amount_minor) <> 0").
+		Distinct().
+		Pluck("journal_entry_id", &ids).Error
+	return ids, err
+}
+
+// CountEntries counts the journal entries of a user
+func (r *LedgerRepository) CountEntries(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.JournalEntry{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// GetPostedBalances sums postings per account for a user
+func (r *LedgerRepository) GetPostedBalances(userID uint) (map[uint]models.Money, error) {
+	var rows []struct {
+		AccountID uint
+		Total     models.Money
+	}
+	err := r.db.Model(&models.Posting{}).
+		Select("account_id, COALESCE(SUM(amount_minor), 0) AS total").
+		Where("user_id = ? AND account_id IS NOT NULL", userID).
+		Group("account_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[uint]models.Money, len(rows))
+	for _, row := range rows {
+		balances[row.AccountID] = row.Total
+	}
+	return balances, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// LoanRepository handles database operations for loans and their payments
+type LoanRepository struct {
+	db *gorm.DB
+}
+
+// NewLoanRepository creates a new loan repository
+func NewLoanRepository(db *gorm.DB) *LoanRepository {
+	return &LoanRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *LoanRepository) WithTx(tx *gorm.DB) *LoanRepository {
+	return &LoanRepository{db: tx}
+}
+
+// Save creates or updates the terms of a loan
+func (r *LoanRepository) Save(loan *models.Loan) error {
+	return r.db.Save(loan).Error
+}
+
+// GetByAccountID gets the loan of an account
+func (r *LoanRepository) GetByAccountID(accountID uint, userID uint) (*models.Loan, error) {
+	var loan models.Loan
+	err := r.db.Where("account_id = ? AND user_id = ?", accountID, userID).First(&loan).Error
+	if err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+// GetDue gets the active loans with a payment due by now whose account is not in the trash
+func (r *LoanRepository) GetDue(now time.Time) ([]models.Loan, error) {
+	var loans []models.Loan
+	err := r.db.Where("status = ? AND next_payment_date <= ?", models.LoanStatusActive, now).
+		Where("account_id IN (?)", r.db.Model(&models.Account{}).Select("id")).
+		Order("next_payment_date ASC").
+		Find(&loans).Error
+	if err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+// Delete deletes the loan of an account together with its payment records
+func (r *LoanRepository) Delete(loan *models.Loan) error {
+	if err := r.db.Where("loan_id = ?", loan.ID).Delete(&models.LoanPayment{}).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(&models.Loan{}, loan.ID).Error
+}
+
+// CreatePayment records a posted payment
+func (r *LoanRepository) CreatePayment(payment *models.LoanPayment) error {
+	return r.db.Create(payment).Error
+}
+
+// GetPayments gets the posted payments of a loan, most recent first
+func (r *LoanRepository) GetPayments(loanID uint) ([]models.LoanPayment, error) {
+	var payments []models.LoanPayment
+	err := r.db.Where("loan_id = ?", loanID).
+		Order("date DESC, id DESC").
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
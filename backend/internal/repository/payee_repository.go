@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PayeeRepository handles database operations for payees
+type PayeeRepository struct {
+	db *gorm.DB
+}
+
+// NewPayeeRepository creates a new payee repository
+func NewPayeeRepository(db *gorm.DB) *PayeeRepository {
+	return &PayeeRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *PayeeRepository) WithTx(tx *gorm.DB) *PayeeRepository {
+	return &PayeeRepository{db: tx}
+}
+
+// Create creates a new payee with its aliases
+func (r *PayeeRepository) Create(payee *models.Payee) error {
+	return r.db.Create(payee).Error
+}
+
+// GetByID gets a payee of a user by ID with its aliases
+func (r *PayeeRepository) GetByID(id uint, userID uint) (*models.Payee, error) {
+	var payee models.Payee
+	err := r.db.Preload("Aliases").Where("id = ? AND user_id = ?", id, userID).First(&payee).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payee, nil
+}
+
+// GetByName gets a payee of a user by name, ignoring case
+func (r *PayeeRepository) GetByName(userID uint, name string) (*models.Payee, error) {
+	var payee models.Payee
+	err := r.db.Preload("Aliases").Where("user_id = ? AND LOWER(name) = ?", userID, strings.ToLower(name)).First(&payee).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payee, nil
+}
+
+// GetAll gets all payees of a user with their aliases ordered by name
+func (r *PayeeRepository) GetAll(userID uint) ([]models.Payee, error) {
+	var payees []models.Payee
+	err := r.db.Preload("Aliases").Where("user_id = ?", userID).Order("name ASC").Find(&payees).Error
+	if err != nil {
+		return nil, err
+	}
+	return payees, nil
+}
+
+// Update updates a payee and replaces its aliases
+func (r *PayeeRepository) Update(payee *models.Payee) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Aliases").Save(payee).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("payee_id = ?", payee.ID).Delete(&models.PayeeAlias{}).Error; err != nil {
+			return err
+		}
+		for i := range payee.Aliases {
+			payee.Aliases[i].ID = 0
+			payee.Aliases[i].PayeeID = payee.ID
+		}
+		if len(payee.Aliases) == 0 {
+			return nil
+		}
+		return tx.Create(&payee.Aliases).Error
+	})
+}
+
+// Delete deletes a payee of a user with its aliases. Its transactions,
+// including those in the trash, are left without a payee.
+func (r *PayeeRepository) Delete(id uint, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := r.WithTx(tx).GetByID(id, userID); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Transaction{}).
+			Where("user_id = ? AND payee_id = ?", userID, id).
+			Update("payee_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("payee_id = ?", id).Delete(&models.PayeeAlias{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Payee{}).Error
+	})
+}
+
+// Merge moves the transactions and aliases of the source payees of a user to
+// the target payee and deletes the source payees. The names of the source
+// payees become exact aliases of the target so that they keep matching.
+func (r *PayeeRepository) Merge(userID uint, sourceIDs []uint, targetID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var sources []models.Payee
+		if err := tx.Where("id IN ? AND user_id = ?", sourceIDs, userID).Find(&sources).Error; err != nil {
+			return err
+		}
+
+		// Transactions in the trash move too so they come back with the target payee
+		if err := tx.Unscoped().Model(&models.Transaction{}).
+			Where("user_id = ? AND payee_id IN ?", userID, sourceIDs).
+			Update("payee_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PayeeAlias{}).
+			Where("payee_id IN ?", sourceIDs).
+			Update("payee_id", targetID).Error; err != nil {
+			return err
+		}
+		for _, source := range sources {
+			alias := &models.PayeeAlias{PayeeID: targetID, Pattern: source.Name, MatchType: models.PayeeMatchExact}
+			if err := tx.Create(alias).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("id IN ? AND user_id = ?", sourceIDs, userID).Delete(&models.Payee{}).Error
+	})
+}
+
+// Resolve finds the payee of a transaction of a user. A payee name is looked
+// up and created when missing. Without a name the description is matched
+// against the payees and their aliases, and a payee named after the
+// description is created when none matches. New payees take category as
+// their default category. Returns nil when neither gives a payee name.
+func (r *PayeeRepository) Resolve(userID uint, name, description, category string) (*models.Payee, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		payees, err := r.GetAll(userID)
+		if err != nil {
+			return nil, err
+		}
+		if payee := models.MatchPayee(payees, description); payee != nil {
+			return payee, nil
+		}
+		name = models.PayeeDisplayName(description)
+		if name == "" {
+			return nil, nil
+		}
+	}
+
+	payee, err := r.GetByName(userID, name)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return payee, err
+	}
+
+	// A payee created concurrently under the same name is picked up below
+	err = r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Payee{UserID: userID, Name: name, DefaultCategory: category}).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByName(userID, name)
+}
+
+// CountTransactions counts the transactions of each payee of a user.
+// Transactions in the trash are not counted.
+func (r *PayeeRepository) CountTransactions(userID uint) (map[uint]int64, error) {
+	var counts []struct {
+		PayeeID uint
+		Count   int64
+	}
+	err := r.db.Model(&models.Transaction{}).
+		Select("payee_id, COUNT(*) AS count").
+		Where("user_id = ? AND payee_id IS NOT NULL", userID).
+		Group("payee_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		result[c.PayeeID] = c.Count
+	}
+	return result, nil
+}
+
+// GetTransactionsForSpending gets the posted income and expense transactions
+// of a user with a payee in a period, optionally of a single payee, with
+// their payee loaded. Transfers are left out.
+func (r *PayeeRepository) GetTransactionsForSpending(userID uint, payeeID *uint, startDate, endDate time.Time) ([]models.Transaction, error) {
+	query := excludeTransfers(r.db.Preload("Payee")).
+		Where("user_id = ? AND payee_id IS NOT NULL AND type IN ? AND status IN ? AND date BETWEEN ? AND ?",
+			userID, []string{"income", "expense"}, models.PostedTransactionStatuses, startDate, endDate)
+	if payeeID != nil {
+		query = query.Where("payee_id = ?", *payeeID)
+	}
+
+	var transactions []models.Transaction
+	if err := query.Order("date ASC").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// ReconciliationRepository handles database operations for reconciliations
+type ReconciliationRepository struct {
+	db *gorm.DB
+}
+
+// NewReconciliationRepository creates a new reconciliation repository
+func NewReconciliationRepository(db *gorm.DB) *ReconciliationRepository {
+	return &ReconciliationRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *ReconciliationRepository) WithTx(tx *gorm.DB) *ReconciliationRepository {
+	return &ReconciliationRepository{db: tx}
+}
+
+// Create creates a new reconciliation
+func (r *ReconciliationRepository) Create(reconciliation *models.Reconciliation) error {
+	return r.db.Create(reconciliation).Error
+}
+
+// Update updates a reconciliation
+func (r *ReconciliationRepository) Update(reconciliation *models.Reconciliation) error {
+	return r.db.Save(reconciliation).Error
+}
+
+// Delete deletes a reconciliation and releases the transactions it cleared
+func (r *ReconciliationRepository) Delete(reconciliation *models.Reconciliation) error {
+	if err := r.db.Model(&models.Transaction{}).
+		Where("reconciliation_id = ? AND reconciled_at IS NULL", reconciliation.ID).
+		Update("reconciliation_id", nil).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(&models.Reconciliation{}, reconciliation.ID).Error
+}
+
+// GetByID gets a reconciliation by ID
+func (r *ReconciliationRepository) GetByID(id uint, userID uint) (*models.Reconciliation, error) {
+	var reconciliation models.Reconciliation
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&reconciliation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reconciliation, nil
+}
+
+// GetByIDForUpdate gets a reconciliation by ID and locks it until the
+// transaction ends
+func (r *ReconciliationRepository) GetByIDForUpdate(id uint, userID uint) (*models.Reconciliation, error) {
+	var reconciliation models.Reconciliation
+	err := r.db.Clauses(lockRow).Where("id = ? AND user_id = ?", id, userID).First(&reconciliation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reconciliation, nil
+}
+
+// GetByAccountID gets all reconciliations of an account, most recent statement first
+func (r *ReconciliationRepository) GetByAccountID(accountID uint, userID uint) ([]models.Reconciliation, error) {
+	var reconciliations []models.Reconciliation
+	err := r.db.Where("account_id = ? AND user_id = ?", accountID, userID).
+		Order("statement_date DESC, id DESC").
+		Find(&reconciliations).Error
+	if err != nil {
+		return nil, err
+	}
+	return reconciliations, nil
+}
+
+// CountInProgress counts the open reconciliations of an account
+func (r *ReconciliationRepository) CountInProgress(accountID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Reconciliation{}).
+		Where("account_id = ? AND status = ?", accountID, models.ReconciliationStatusInProgress).
+		Count(&count).Error
+	return count, err
+}
+
+// GetOpenTransactions gets the posted, unreconciled transactions of an account dated before cutoff
+func (r *ReconciliationRepository) GetOpenTransactions(accountID uint, cutoff time.Time) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.Preload("Splits").
+		Where("account_id = ? AND status IN ? AND reconciled_at IS NULL AND date < ?",
+			accountID, models.PostedTransactionStatuses, cutoff).
+		Order("date ASC, id ASC").
+		Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// SetCleared ticks or unticks posted, unreconciled transactions of an
+// account dated before cutoff and returns how many were changed. Ticked
+// transactions become cleared and unticked ones go back to pending.
+func (r *ReconciliationRepository) SetCleared(reconciliation *models.Reconciliation, transactionIDs []uint, cleared bool, cutoff time.Time) (int64, error) {
+	var reconciliationID *uint
+	status := models.TransactionStatusPending
+	if cleared {
+		reconciliationID = &reconciliation.ID
+		status = models.TransactionStatusCleared
+	}
+
+	result := r.db.Model(&models.Transaction{}).
+		Where("id IN ? AND user_id = ? AND account_id = ? AND status IN ? AND reconciled_at IS NULL AND date < ?",
+			transactionIDs, reconciliation.UserID, reconciliation.AccountID, models.PostedTransactionStatuses, cutoff).
+		Updates(map[string]interface{}{
+			"status":            status,
+			"reconciliation_id": reconciliationID,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// GetClearedBalance returns the account balance without pending
+// transactions. Opening balances, adjustments and transactions recorded
+// before the ledger always count as cleared.
+func (r *ReconciliationRepository) GetClearedBalance(accountID uint) (models.Money, error) {
+	var balance models.Money
+	err := r.db.Model(&models.Posting{}).
+		Select("COALESCE(SUM(amount_minor), 0)").
+		Where("account_id = ?", accountID).
+		Scan(&balance).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var uncleared models.Money
+	err = r.db.Table("postings").
+		Select("COALESCE(SUM(postings.amount_minor), 0)").
+		Joins("JOIN transactions ON transactions.journal_entry_id = postings.journal_entry_id AND transactions.account_id = postings.account_id").
+		Where("postings.account_id = ? AND transactions.status = ?", accountID, models.TransactionStatusPending).
+		Scan(&uncleared).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return balance - uncleared, nil
+}
+
+// CountCleared counts the transactions ticked in a reconciliation
+func (r *ReconciliationRepository) CountCleared(reconciliationID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Transaction{}).
+		Where("reconciliation_id = ? AND status = ?", reconciliationID, models.TransactionStatusCleared).
+		Count(&count).Error
+	return count, err
+}
+
+// LockCleared marks every cleared, unreconciled transaction of the account
+// dated before cutoff as reconciled by the given reconciliation
+func (r *ReconciliationRepository) LockCleared(reconciliation *models.Reconciliation, cutoff time.Time, reconciledAt time.Time) (int64, error) {
+	result := r.db.Model(&models.Transaction{}).
+		Where("account_id = ? AND status = ? AND reconciled_at IS NULL AND date < ?",
+			reconciliation.AccountID, models.TransactionStatusCleared, cutoff).
+		Updates(map[string]interface{}{
+			"reconciliation_id": reconciliation.ID,
+			"reconciled_at":     reconciledAt,
+		})
+	return result.RowsAffected, result.Error
+}
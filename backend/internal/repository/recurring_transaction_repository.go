@@ -25,7 +25,7 @@ func (r *RecurringTransactionRepository) Create(recurring *models.RecurringTrans
 // GetByID gets a recurring transaction by ID
 func (r *RecurringTransactionRepository) GetByID(id uint, userID uint) (*models.RecurringTransaction, error) {
 	var recurring models.RecurringTransaction
-	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&recurring).Error
+	err := r.db.Preload("Tags").Where("id = ? AND user_id = ?", id, userID).First(&recurring).Error
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +35,7 @@ func (r *RecurringTransactionRepository) GetByID(id uint, userID uint) (*models.
 // GetAll gets all recurring transactions for a user
 func (r *RecurringTransactionRepository) GetAll(userID uint) ([]models.RecurringTransaction, error) {
 	var recurrings []models.RecurringTransaction
-	err := r.db.Where("user_id = ?", userID).Order("next_run_date ASC").Find(&recurrings).Error
+	err := r.db.Preload("Tags").Where("user_id = ?", userID).Order("next_run_date ASC").Find(&recurrings).Error
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +45,7 @@ func (r *RecurringTransactionRepository) GetAll(userID uint) ([]models.Recurring
 // GetActive gets all active recurring transactions for a user
 func (r *RecurringTransactionRepository) GetActive(userID uint) ([]models.RecurringTransaction, error) {
 	var recurrings []models.RecurringTransaction
-	err := r.db.Where("user_id = ? AND is_active = ?", userID, true).
+	err := r.db.Preload("Tags").Where("user_id = ? AND is_active = ?", userID, true).
 		Order("next_run_date ASC").Find(&recurrings).Error
 	if err != nil {
 		return nil, err
@@ -57,7 +57,7 @@ func (r *RecurringTransactionRepository) GetActive(userID uint) ([]models.Recurr
 func (r *RecurringTransactionRepository) GetDue() ([]models.RecurringTransaction, error) {
 	var recurrings []models.RecurringTransaction
 	now := time.Now()
-	err := r.db.Where("is_active = ? AND next_run_date <= ?", true, now).
+	err := r.db.Preload("Tags").Where("is_active = ? AND next_run_date <= ?", true, now).
 		Find(&recurrings).Error
 	if err != nil {
 		return nil, err
@@ -65,16 +65,59 @@ func (r *RecurringTransactionRepository) GetDue() ([]models.RecurringTransaction
 	return recurrings, nil
 }
 
-// Update updates a recurring transaction
+// Update updates a recurring transaction and replaces its tags
 func (r *RecurringTransactionRepository) Update(recurring *models.RecurringTransaction) error {
-	return r.db.Save(recurring).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(recurring).Error; err != nil {
+			return err
+		}
+		return tx.Model(recurring).Association("Tags").Replace(recurring.Tags)
+	})
 }
 
-// Delete deletes a recurring transaction
+// Delete moves a recurring transaction to the trash
 func (r *RecurringTransactionRepository) Delete(id uint, userID uint) error {
 	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.RecurringTransaction{}).Error
 }
 
+// GetDeleted gets the recurring transactions of a user that are in the trash
+func (r *RecurringTransactionRepository) GetDeleted(userID uint) ([]models.RecurringTransaction, error) {
+	var items []models.RecurringTransaction
+	if err := findDeleted(r.db, &items, userID); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetDeletedBefore gets every recurring transaction that has been in the trash since before cutoff
+func (r *RecurringTransactionRepository) GetDeletedBefore(cutoff time.Time) ([]models.RecurringTransaction, error) {
+	var items []models.RecurringTransaction
+	if err := findDeletedBefore(r.db, &items, cutoff); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Restore takes a recurring transaction out of the trash
+func (r *RecurringTransactionRepository) Restore(id uint, userID uint) error {
+	return restoreDeleted(r.db, &models.RecurringTransaction{}, id, userID)
+}
+
+// Purge permanently deletes a recurring transaction that is in the trash together with its tags
+func (r *RecurringTransactionRepository) Purge(id uint, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var recurring models.RecurringTransaction
+		err := tx.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).First(&recurring).Error
+		if err != nil {
+			return err
+		}
+		if err := detachTags(tx, tagJoinTables[1], id); err != nil {
+			return err
+		}
+		return purgeDeleted(tx, &models.RecurringTransaction{}, id, userID)
+	})
+}
+
 // Deactivate deactivates a recurring transaction
 func (r *RecurringTransactionRepository) Deactivate(id uint, userID uint) error {
 	return r.db.Model(&models.RecurringTransaction{}).
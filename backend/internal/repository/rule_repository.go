@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// RuleRepository handles database operations for rules
+type RuleRepository struct {
+	db *gorm.DB
+}
+
+// NewRuleRepository creates a new rule repository
+func NewRuleRepository(db *gorm.DB) *RuleRepository {
+	return &RuleRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *RuleRepository) WithTx(tx *gorm.DB) *RuleRepository {
+	return &RuleRepository{db: tx}
+}
+
+// Create creates a new rule with its tags after the last rule of the user
+func (r *RuleRepository) Create(rule *models.Rule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var last struct{ Position *int }
+		if err := tx.Model(&models.Rule{}).Select("MAX(position) AS position").
+			Where("user_id = ?", rule.UserID).Scan(&last).Error; err != nil {
+			return err
+		}
+		rule.Position = 0
+		if last.Position != nil {
+			rule.Position = *last.Position + 1
+		}
+
+		// The active column defaults to true, so inactive rules are saved explicitly
+		active := rule.IsActive
+		if err := tx.Create(rule).Error; err != nil {
+			return err
+		}
+		if !active {
+			rule.IsActive = false
+			return tx.Model(rule).Update("is_active", false).Error
+		}
+		return nil
+	})
+}
+
+// GetByID gets a rule of a user by ID with its tags
+func (r *RuleRepository) GetByID(id uint, userID uint) (*models.Rule, error) {
+	var rule models.Rule
+	err := r.db.Preload("Tags").Where("id = ? AND user_id = ?", id, userID).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetAll gets all rules of a user with their tags in the order they run
+func (r *RuleRepository) GetAll(userID uint) ([]models.Rule, error) {
+	var rules []models.Rule
+	err := r.db.Preload("Tags").Where("user_id = ?", userID).Order("position ASC, id ASC").Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetActive gets the active rules of a user with their tags in the order they run
+func (r *RuleRepository) GetActive(userID uint) ([]models.Rule, error) {
+	var rules []models.Rule
+	err := r.db.Preload("Tags").Where("user_id = ? AND is_active = ?", userID, true).
+		Order("position ASC, id ASC").Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Update updates a rule and replaces its tags
+func (r *RuleRepository) Update(rule *models.Rule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tags").Save(rule).Error; err != nil {
+			return err
+		}
+		return tx.Model(rule).Association("Tags").Replace(rule.Tags)
+	})
+}
+
+// Delete deletes a rule of a user
+func (r *RuleRepository) Delete(id uint, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := r.WithTx(tx).GetByID(id, userID); err != nil {
+			return err
+		}
+		if err := detachTags(tx, tagJoinTables[2], id); err != nil {
+			return err
+		}
+		return tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Rule{}).Error
+	})
+}
+
+// Reorder sets the position of each rule of a user to its index in ids
+func (r *RuleRepository) Reorder(userID uint, ids []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range ids {
+			if err := tx.Model(&models.Rule{}).
+				Where("id = ? AND user_id = ?", id, userID).
+				Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SecurityPriceRepository handles database operations for dated security prices
+type SecurityPriceRepository struct {
+	db *gorm.DB
+}
+
+// NewSecurityPriceRepository creates a new security price repository
+func NewSecurityPriceRepository(db *gorm.DB) *SecurityPriceRepository {
+	return &SecurityPriceRepository{db: db}
+}
+
+// Upsert saves prices, replacing any existing price for the same symbol, currency and date
+func (r *SecurityPriceRepository) Upsert(prices []models.SecurityPrice) error {
+	if len(prices) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "symbol"}, {Name: "currency"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price", "source", "updated_at"}),
+	}).CreateInBatches(prices, 500).Error
+}
+
+// Latest gets the most recent price of a symbol in a currency at or before date
+func (r *SecurityPriceRepository) Latest(symbol, currency string, date time.Time) (*models.SecurityPrice, error) {
+	var price models.SecurityPrice
+	err := r.db.Where("symbol = ? AND currency = ? AND date <= ?", symbol, currency, models.RateDate(date)).
+		Order("date DESC").
+		First(&price).Error
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tagJoinTable is a join table between tags and the records they are attached to
+type tagJoinTable struct {
+	name   string
+	owner  string // Table of the tagged records
+	column string // Column holding the ID of the tagged record
+}
+
+// tagJoinTables lists every join table that references tags
+var tagJoinTables = []tagJoinTable{
+	{name: "transaction_tags", owner: "transactions", column: "transaction_id"},
+	{name: "recurring_transaction_tags", owner: "recurring_transactions", column: "recurring_transaction_id"},
+	{name: "transaction_split_tags", owner: "transaction_splits", column: "transaction_split_id"},
+	{name: "rule_tags", owner: "rules", column: "rule_id"},
+}
+
+// TagRepository handles database operations for tags
+type TagRepository struct {
+	db *gorm.DB
+}
+
+// NewTagRepository creates a new tag repository
+func NewTagRepository(db *gorm.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *TagRepository) WithTx(tx *gorm.DB) *TagRepository {
+	return &TagRepository{db: tx}
+}
+
+// Create creates a new tag
+func (r *TagRepository) Create(tag *models.Tag) error {
+	return r.db.Create(tag).Error
+}
+
+// GetByID gets a tag of a user by ID
+func (r *TagRepository) GetByID(id uint, userID uint) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetByName gets a tag of a user by name, ignoring case
+func (r *TagRepository) GetByName(userID uint, name string) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.Where("user_id = ? AND LOWER(name) = ?", userID, strings.ToLower(name)).First(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetByNames gets the tags of a user with any of the names, ignoring case,
+// in the order the names are given. Names without a tag are left out.
+func (r *TagRepository) GetByNames(userID uint, names []string) ([]models.Tag, error) {
+	if len(names) == 0 {
+		return []models.Tag{}, nil
+	}
+
+	lowered := make([]string, len(names))
+	for i, name := range names {
+		lowered[i] = strings.ToLower(name)
+	}
+
+	var found []models.Tag
+	if err := r.db.Where("user_id = ? AND LOWER(name) IN ?", userID, lowered).Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]models.Tag, len(found))
+	for _, tag := range found {
+		byName[strings.ToLower(tag.Name)] = tag
+	}
+	tags := make([]models.Tag, 0, len(found))
+	for _, name := range lowered {
+		if tag, ok := byName[name]; ok {
+			tags = append(tags, tag)
+			delete(byName, name)
+		}
+	}
+	return tags, nil
+}
+
+// GetAll gets all tags of a user ordered by name
+func (r *TagRepository) GetAll(userID uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.Where("user_id = ?", userID).Order("name ASC").Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// FindOrCreate gets the tags of a user with the given names and creates the
+// ones that do not exist yet. Names are normalized first.
+func (r *TagRepository) FindOrCreate(userID uint, names []string) ([]models.Tag, error) {
+	names = models.NormalizeTagNames(names)
+	tags, err := r.GetByNames(userID, names)
+	if err != nil || len(tags) == len(names) {
+		return tags, err
+	}
+
+	existing := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		existing[strings.ToLower(tag.Name)] = true
+	}
+	for _, name := range names {
+		if existing[strings.ToLower(name)] {
+			continue
+		}
+		// A tag created concurrently under the same name is picked up below
+		if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.Tag{UserID: userID, Name: name}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return r.GetByNames(userID, names)
+}
+
+// Update updates a tag
+func (r *TagRepository) Update(tag *models.Tag) error {
+	return r.db.Save(tag).Error
+}
+
+// Delete deletes a tag of a user and detaches it from everything it is attached to
+func (r *TagRepository) Delete(id uint, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := r.WithTx(tx).GetByID(id, userID); err != nil {
+			return err
+		}
+		for _, join := range tagJoinTables {
+			if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE tag_id = ?", join.name), id).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Tag{}).Error
+	})
+}
+
+// Merge moves everything tagged with the source tags of a user to the
+// target tag and deletes the source tags
+func (r *TagRepository) Merge(userID uint, sourceIDs []uint, targetID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, join := range tagJoinTables {
+			// Attach the target to records that have a source tag but not the target yet
+			err := tx.Exec(fmt.Sprintf(
+				"INSERT INTO %[1]s (%[2]s, tag_id) SELECT DISTINCT %[2]s, ? FROM %[1]s WHERE tag_id IN ? AND %[2]s NOT IN (SELECT %[2]s FROM %[1]s WHERE tag_id = ?)",
+				join.name, join.column), targetID, sourceIDs, targetID).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE tag_id IN ?", join.name), sourceIDs).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("id IN ? AND user_id = ?", sourceIDs, userID).Delete(&models.Tag{}).Error
+	})
+}
+
+// GetUsage counts the transactions and recurring transactions each tag of a
+// user is attached to. Records in the trash are not counted.
+func (r *TagRepository) GetUsage(userID uint) (map[uint]models.TagUsage, error) {
+	type tagCount struct {
+		TagID uint
+		Count int64
+	}
+
+	count := func(join tagJoinTable) ([]tagCount, error) {
+		var counts []tagCount
+		err := r.db.Table(join.name).
+			Select(join.name+".tag_id, COUNT(*) AS count").
+			Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.id = %[2]s.%[3]s AND %[1]s.deleted_at IS NULL", join.owner, join.name, join.column)).
+			Joins("JOIN tags ON tags.id = "+join.name+".tag_id").
+			Where("tags.user_id = ?", userID).
+			Group(join.name + ".tag_id").
+			Scan(&counts).Error
+		return counts, err
+	}
+
+	usage := make(map[uint]models.TagUsage)
+	transactions, err := count(tagJoinTables[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range transactions {
+		u := usage[c.TagID]
+		u.Transactions = c.Count
+		usage[c.TagID] = u
+	}
+
+	recurring, err := count(tagJoinTables[1])
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range recurring {
+		u := usage[c.TagID]
+		u.RecurringTransactions = c.Count
+		usage[c.TagID] = u
+	}
+	return usage, nil
+}
+
+// detachTags removes every tag from a record of a tag join table
+func detachTags(db *gorm.DB, join tagJoinTable, id uint) error {
+	return db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", join.name, join.column), id).Error
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
 	"sort"
 	"time"
 
@@ -18,6 +20,11 @@ func NewTaxRepository(db *gorm.DB) *TaxRepository {
 	return &TaxRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *TaxRepository) WithTx(tx *gorm.DB) *TaxRepository {
+	return &TaxRepository{db: tx}
+}
+
 // CreateCategory creates a new tax category
 func (r *TaxRepository) CreateCategory(category *models.TaxCategory) error {
 	return r.db.Create(category).Error
@@ -52,15 +59,71 @@ func (r *TaxRepository) DeleteCategory(id, userID uint) error {
 	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.TaxCategory{}).Error
 }
 
-// GetTaxReport generates annual tax report data
-func (r *TaxRepository) GetTaxReport(userID uint, year int) (*models.TaxReportResponse, error) {
+// FindOrCreateCategory gets the tax category of a user with the given name
+// and tax type, creating it when the user has none
+func (r *TaxRepository) FindOrCreateCategory(userID uint, name, taxType string) (*models.TaxCategory, error) {
+	var category models.TaxCategory
+	err := r.db.Where("user_id = ? AND name = ? AND tax_type = ?", userID, name, taxType).
+		Order("id ASC").
+		First(&category).Error
+	if err == nil {
+		return &category, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	category = models.TaxCategory{
+		UserID:  userID,
+		Name:    name,
+		TaxType: taxType,
+	}
+	if err := r.db.Create(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// taxLine is an amount attributed to a tax category, either a whole
+// transaction or one line of a split transaction
+type taxLine struct {
+	TransactionID uint
+	Date          time.Time
+	Description   string
+	Category      string
+	Amount        models.Money
+	Currency      string
+	TaxCategoryID uint
+	TaxCategory   *models.TaxCategory
+	Realized      bool // A gain realized by selling investment lots
+}
+
+// countsTowardsTaxTotal reports whether a line adds to the income, deduction
+// or capital gain total of its tax type
+func countsTowardsTaxTotal(line taxLine) bool {
+	switch line.TaxCategory.TaxType {
+	case "income", "deduction":
+		return true
+	case models.TaxTypeCapitalGain:
+		return line.Realized
+	}
+	return false
+}
+
+// GetTaxReport generates annual tax report data. Split transactions are
+// reported per line using the tax category of each line. Capital gains are
+// the gains realized by selling investment lots; transactions tagged by hand
+// with a capital_gain category are listed but not added to the total.
+// Totals are converted at the rate in effect on the date of each line.
+func (r *TaxRepository) GetTaxReport(userID uint, year int, rates models.ExchangeRateSource) (*models.TaxReportResponse, error) {
 	startDate := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
 	endDate := time.Date(year, 12, 31, 23, 59, 59, 0, time.UTC)
 
-	// Get all transactions with tax categories for the year
+	// Get all unsplit transactions with tax categories for the year
 	var transactions []models.Transaction
-	err := r.db.Where("user_id = ? AND date >= ? AND date <= ? AND tax_category_id IS NOT NULL",
-		userID, startDate, endDate).
+	err := r.db.Where("user_id = ? AND date >= ? AND date <= ? AND tax_category_id IS NOT NULL AND status IN ?",
+		userID, startDate, endDate, models.PostedTransactionStatuses).
+		Where("id NOT IN (?)", r.db.Model(&models.TransactionSplit{}).Select("transaction_id")).
 		Preload("TaxCategory").
 		Find(&transactions).Error
 
@@ -68,23 +131,121 @@ func (r *TaxRepository) GetTaxReport(userID uint, year int) (*models.TaxReportRe
 		return nil, err
 	}
 
-	// Aggregate data
-	var totalIncome, totalDeductions, capitalGains float64
-	categoryMap := make(map[uint]*models.TaxCategorySummary)
-	var taxTransactions []models.TaxTransactionSummary
-
+	var lines []taxLine
 	for _, txn := range transactions {
 		if txn.TaxCategoryID == nil || txn.TaxCategory == nil {
 			continue
 		}
+		lines = append(lines, taxLine{
+			TransactionID: txn.ID,
+			Date:          txn.Date,
+			Description:   txn.Description,
+			Category:      txn.Category,
+			Amount:        txn.Amount,
+			Currency:      txn.Currency,
+			TaxCategoryID: *txn.TaxCategoryID,
+			TaxCategory:   txn.TaxCategory,
+		})
+	}
+
+	// Get split lines with tax categories for the year
+	var splitTransactions []models.Transaction
+	err = r.db.Where("user_id = ? AND date >= ? AND date <= ? AND status IN ?",
+		userID, startDate, endDate, models.PostedTransactionStatuses).
+		Where("id IN (?)", r.db.Model(&models.TransactionSplit{}).
+			Select("transaction_id").
+			Where("tax_category_id IS NOT NULL")).
+		Preload("Splits", "tax_category_id IS NOT NULL").
+		Preload("Splits.TaxCategory").
+		Find(&splitTransactions).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, txn := range splitTransactions {
+		for _, split := range txn.Splits {
+			if split.TaxCategoryID == nil || split.TaxCategory == nil {
+				continue
+			}
+			description := txn.Description
+			if split.Description != "" {
+				description = split.Description
+			}
+			lines = append(lines, taxLine{
+				TransactionID: txn.ID,
+				Date:          txn.Date,
+				Description:   description,
+				Category:      split.Category,
+				Amount:        split.Amount,
+				Currency:      txn.Currency,
+				TaxCategoryID: *split.TaxCategoryID,
+				TaxCategory:   split.TaxCategory,
+			})
+		}
+	}
+
+	// Get gains realized by selling investment lots during the year
+	var gains []models.RealizedGain
+	err = r.db.Where("user_id = ? AND sold_at >= ? AND sold_at <= ?", userID, startDate, endDate).
+		Preload("Security").
+		Preload("TaxCategory").
+		Find(&gains).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, gain := range gains {
+		if gain.TaxCategory == nil {
+			continue
+		}
+		description := "Sale of investment"
+		if gain.Security != nil {
+			description = fmt.Sprintf("Sale of %s %s", gain.Quantity.String(), gain.Security.Symbol)
+		}
+		lines = append(lines, taxLine{
+			TransactionID: gain.SellTransactionID,
+			Date:          gain.SoldAt,
+			Description:   description,
+			Category:      models.GainCategory(gain.Term),
+			Amount:        gain.Gain,
+			Currency:      gain.Currency,
+			TaxCategoryID: gain.TaxCategoryID,
+			TaxCategory:   gain.TaxCategory,
+			Realized:      true,
+		})
+	}
+
+	// Pick the reporting currency from the currencies of the counted lines
+	currencies := models.MoneyTotals{}
+	for _, line := range lines {
+		if countsTowardsTaxTotal(line) {
+			currencies.Add(line.Currency, line.Amount)
+		}
+	}
+	currency := models.ReportingCurrency(currencies)
 
+	// Aggregate in minor units of the reporting currency, converting each
+	// line at the rate in effect on its date
+	var totalIncome, totalDeductions, capitalGains models.Money
+	categoryMap := make(map[uint]*models.TaxCategorySummary)
+	categoryTotals := make(map[uint]models.Money)
+	var taxTransactions []models.TaxTransactionSummary
+
+	for _, line := range lines {
 		// Use the already preloaded TaxCategory to avoid N+1 query
-		taxCategory := txn.TaxCategory
+		taxCategory := line.TaxCategory
+
+		amount, err := models.ConvertMoneyAt(rates, line.Amount, line.Currency, currency, line.Date)
+		if err != nil {
+			return nil, err
+		}
 
 		// Aggregate by category
-		if _, exists := categoryMap[*txn.TaxCategoryID]; !exists {
-			categoryMap[*txn.TaxCategoryID] = &models.TaxCategorySummary{
-				CategoryID:   *txn.TaxCategoryID,
+		if _, exists := categoryMap[line.TaxCategoryID]; !exists {
+			categoryMap[line.TaxCategoryID] = &models.TaxCategorySummary{
+				CategoryID:   line.TaxCategoryID,
 				CategoryName: taxCategory.Name,
 				TaxType:      taxCategory.TaxType,
 				TotalAmount:  0,
@@ -92,26 +253,29 @@ func (r *TaxRepository) GetTaxReport(userID uint, year int) (*models.TaxReportRe
 			}
 		}
 
-		categoryMap[*txn.TaxCategoryID].TotalAmount += txn.Amount
-		categoryMap[*txn.TaxCategoryID].Count++
+		categoryTotals[line.TaxCategoryID] += amount
+		categoryMap[line.TaxCategoryID].Count++
 
 		// Aggregate by tax type
-		switch taxCategory.TaxType {
-		case "income":
-			totalIncome += txn.Amount
-		case "deduction":
-			totalDeductions += txn.Amount
-		case "capital_gain":
-			capitalGains += txn.Amount
+		if countsTowardsTaxTotal(line) {
+			switch taxCategory.TaxType {
+			case "income":
+				totalIncome += amount
+			case "deduction":
+				totalDeductions += amount
+			case models.TaxTypeCapitalGain:
+				capitalGains += amount
+			}
 		}
 
 		// Add to transaction summary
 		taxTransactions = append(taxTransactions, models.TaxTransactionSummary{
-			ID:              txn.ID,
-			Date:            txn.Date.Format("2006-01-02"),
-			Description:     txn.Description,
-			Amount:          txn.Amount,
-			Category:        txn.Category,
+			ID:              line.TransactionID,
+			Date:            line.Date.Format("2006-01-02"),
+			Description:     line.Description,
+			Amount:          line.Amount.Float(line.Currency),
+			Currency:        line.Currency,
+			Category:        line.Category,
 			TaxCategoryName: taxCategory.Name,
 			TaxType:         taxCategory.TaxType,
 		})
@@ -119,7 +283,8 @@ func (r *TaxRepository) GetTaxReport(userID uint, year int) (*models.TaxReportRe
 
 	// Convert map to slice
 	var byCategory []models.TaxCategorySummary
-	for _, summary := range categoryMap {
+	for id, summary := range categoryMap {
+		summary.TotalAmount = categoryTotals[id].Float(currency)
 		byCategory = append(byCategory, *summary)
 	}
 
@@ -129,15 +294,16 @@ func (r *TaxRepository) GetTaxReport(userID uint, year int) (*models.TaxReportRe
 	})
 
 	// Sort transactions by date for deterministic output
-	sort.Slice(taxTransactions, func(i, j int) bool {
+	sort.SliceStable(taxTransactions, func(i, j int) bool {
 		return taxTransactions[i].Date < taxTransactions[j].Date
 	})
 
 	return &models.TaxReportResponse{
 		Year:            year,
-		TotalIncome:     totalIncome,
-		TotalDeductions: totalDeductions,
-		CapitalGains:    capitalGains,
+		TotalIncome:     totalIncome.Float(currency),
+		TotalDeductions: totalDeductions.Float(currency),
+		CapitalGains:    capitalGains.Float(currency),
+		Currency:        currency,
 		ByCategory:      byCategory,
 		Transactions:    taxTransactions,
 	}, nil
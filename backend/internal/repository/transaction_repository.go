@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"sort"
 	"strings"
 	"time"
 
@@ -8,6 +9,15 @@ import (
 	"gorm.io/gorm"
 )
 
+// transactionSortColumns maps the sortable API fields to their columns
+var transactionSortColumns = map[string]string{
+	"date":       "date",
+	"amount":     "amount_minor",
+	"category":   "category",
+	"type":       "type",
+	"created_at": "created_at",
+}
+
 // TransactionRepository handles database operations for transactions
 type TransactionRepository struct {
 	db *gorm.DB
@@ -18,6 +28,11 @@ func NewTransactionRepository(db *gorm.DB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given database transaction
+func (r *TransactionRepository) WithTx(tx *gorm.DB) *TransactionRepository {
+	return &TransactionRepository{db: tx}
+}
+
 // Create creates a new transaction
 func (r *TransactionRepository) Create(transaction *models.Transaction) error {
 	return r.db.Create(transaction).Error
@@ -26,37 +41,176 @@ func (r *TransactionRepository) Create(transaction *models.Transaction) error {
 // GetByID gets a transaction by ID
 func (r *TransactionRepository) GetByID(id uint, userID uint) (*models.Transaction, error) {
 	var transaction models.Transaction
-	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&transaction).Error
+	err := r.db.Preload("Splits.Tags").Preload("Tags").Preload("Payee").Where("id = ? AND user_id = ?", id, userID).First(&transaction).Error
 	if err != nil {
 		return nil, err
 	}
 	return &transaction, nil
 }
 
+// GetByIDForUpdate gets a transaction by ID and locks it until the
+// transaction ends
+func (r *TransactionRepository) GetByIDForUpdate(id uint, userID uint) (*models.Transaction, error) {
+	// Lock the row alone, the preloaded associations are read after it
+	var locked models.Transaction
+	if err := r.db.Clauses(lockRow).Select("id").Where("id = ? AND user_id = ?", id, userID).
+		First(&locked).Error; err != nil {
+		return nil, err
+	}
+	return r.GetByID(id, userID)
+}
+
 // GetAll gets all transactions for a user
 func (r *TransactionRepository) GetAll(userID uint) ([]models.Transaction, error) {
 	var transactions []models.Transaction
-	err := r.db.Where("user_id = ?", userID).Order("date DESC").Find(&transactions).Error
+	err := r.db.Preload("Splits.Tags").Preload("Tags").Preload("Payee").Where("user_id = ?", userID).Order("date DESC").Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// GetPostedByAccount gets the posted transactions of an account in the order they were recorded
+func (r *TransactionRepository) GetPostedByAccount(accountID uint) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.Where("account_id = ? AND status IN ?", accountID, models.PostedTransactionStatuses).
+		Order("id ASC").
+		Find(&transactions).Error
 	if err != nil {
 		return nil, err
 	}
 	return transactions, nil
 }
 
+// IsOutgoingLegacyTransfer reports whether a transfer leg recorded before the
+// ledger existed is the outgoing side. Both legs were created back to back,
+// so a leg followed by its matching leg is the outgoing one. The matching leg
+// counts even when it is in the trash.
+func (r *TransactionRepository) IsOutgoingLegacyTransfer(t *models.Transaction) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&models.Transaction{}).
+		Where("id = ? AND user_id = ? AND type = ? AND amount_minor = ? AND description = ?",
+			t.ID+1, t.UserID, t.Type, t.Amount, t.Description).
+		Count(&count).Error
+	return count > 0, err
+}
+
 // Update updates a transaction
 func (r *TransactionRepository) Update(transaction *models.Transaction) error {
 	return r.db.Save(transaction).Error
 }
 
-// Delete deletes a transaction
+// Delete moves a transaction to the trash. Its split lines are kept so it can be restored.
 func (r *TransactionRepository) Delete(id uint, userID uint) error {
 	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Transaction{}).Error
 }
 
+// GetDeleted gets the transactions of a user that are in the trash
+func (r *TransactionRepository) GetDeleted(userID uint) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	if err := findDeleted(r.db, &transactions, userID); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// GetDeletedBefore gets every transaction that has been in the trash since before cutoff
+func (r *TransactionRepository) GetDeletedBefore(cutoff time.Time) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	if err := findDeletedBefore(r.db, &transactions, cutoff); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// GetDeletedByID gets a transaction in the trash by ID
+func (r *TransactionRepository) GetDeletedByID(id uint, userID uint) (*models.Transaction, error) {
+	var transaction models.Transaction
+	err := r.db.Unscoped().Preload("Splits").
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		First(&transaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// GetDeletedByIDForUpdate gets a transaction in the trash by ID and locks it
+// until the transaction ends
+func (r *TransactionRepository) GetDeletedByIDForUpdate(id uint, userID uint) (*models.Transaction, error) {
+	var locked models.Transaction
+	if err := r.db.Unscoped().Clauses(lockRow).Select("id").
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		First(&locked).Error; err != nil {
+		return nil, err
+	}
+	return r.GetDeletedByID(id, userID)
+}
+
+// GetDeletedByJournalEntryID gets the transactions in the trash that were
+// posted under a journal entry, such as both legs of a transfer
+func (r *TransactionRepository) GetDeletedByJournalEntryID(journalEntryID uint, userID uint) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.Unscoped().Preload("Splits").
+		Where("journal_entry_id = ? AND user_id = ? AND deleted_at IS NOT NULL", journalEntryID, userID).
+		Order("id ASC").
+		Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// GetDeletedByAccountID gets the transactions of an account that are in the trash
+func (r *TransactionRepository) GetDeletedByAccountID(accountID uint, userID uint) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.Unscoped().
+		Where("account_id = ? AND user_id = ? AND deleted_at IS NOT NULL", accountID, userID).
+		Order("id ASC").
+		Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// Restore takes a transaction out of the trash
+func (r *TransactionRepository) Restore(id uint, userID uint) error {
+	return restoreDeleted(r.db, &models.Transaction{}, id, userID)
+}
+
+// Purge permanently deletes a transaction in the trash together with its split lines, tags and comments
+func (r *TransactionRepository) Purge(id uint, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := r.WithTx(tx).GetDeletedByID(id, userID); err != nil {
+			return err
+		}
+		if err := detachTags(tx, tagJoinTables[0], id); err != nil {
+			return err
+		}
+		if err := r.WithTx(tx).DeleteSplits(id); err != nil {
+			return err
+		}
+		if err := tx.Where("transaction_id = ?", id).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		return purgeDeleted(tx, &models.Transaction{}, id, userID)
+	})
+}
+
+// DeleteSplits deletes the split lines of a transaction together with their tags
+func (r *TransactionRepository) DeleteSplits(transactionID uint) error {
+	splitIDs := r.db.Model(&models.TransactionSplit{}).Select("id").Where("transaction_id = ?", transactionID)
+	if err := r.db.Exec("DELETE FROM transaction_split_tags WHERE transaction_split_id IN (?)", splitIDs).Error; err != nil {
+		return err
+	}
+	return r.db.Where("transaction_id = ?", transactionID).Delete(&models.TransactionSplit{}).Error
+}
+
 // GetByPeriod gets transactions for a specific period
 func (r *TransactionRepository) GetByPeriod(userID uint, startDate, endDate time.Time) ([]models.Transaction, error) {
 	var transactions []models.Transaction
-	err := r.db.Where("user_id = ? AND date BETWEEN ? AND ?", userID, startDate, endDate).
+	err := r.db.Preload("Splits.Tags").Preload("Tags").Preload("Payee").Where("user_id = ? AND date BETWEEN ? AND ?", userID, startDate, endDate).
 		Order("date DESC").Find(&transactions).Error
 	if err != nil {
 		return nil, err
@@ -64,10 +218,83 @@ func (r *TransactionRepository) GetByPeriod(userID uint, startDate, endDate time
 	return transactions, nil
 }
 
-// GetSummary gets a summary of transactions for a specific period
-func (r *TransactionRepository) GetSummary(userID uint, startDate, endDate time.Time) (*models.TransactionSummary, error) {
+// GetDueScheduled gets scheduled transactions of all users dated at or before now
+func (r *TransactionRepository) GetDueScheduled(now time.Time) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.Preload("Splits").
+		Where("status = ? AND date <= ?", models.TransactionStatusScheduled, now).
+		Order("date ASC, id ASC").
+		Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// FindProbableDuplicates gets the transactions of the user of t that are
+// probably duplicates of it, leaving out t itself and void transactions
+func (r *TransactionRepository) FindProbableDuplicates(t *models.Transaction) ([]models.Transaction, error) {
+	var candidates []models.Transaction
+	err := r.db.Preload("Tags").Preload("Payee").
+		Where("user_id = ? AND account_id = ? AND type = ? AND amount_minor = ? AND currency = ? AND status <> ? AND id <> ?",
+			t.UserID, t.AccountID, t.Type, t.Amount, t.Currency, models.TransactionStatusVoid, t.ID).
+		Where("date BETWEEN ? AND ?", t.Date.Add(-models.DuplicateDateWindow), t.Date.Add(models.DuplicateDateWindow)).
+		Order("date ASC, id ASC").Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := make([]models.Transaction, 0, len(candidates))
+	for i := range candidates {
+		if models.IsProbableDuplicate(t, &candidates[i]) {
+			duplicates = append(duplicates, candidates[i])
+		}
+	}
+	return duplicates, nil
+}
+
+// GetExternalIDs gets the external IDs of the transactions of an account,
+// including those in the trash, so a statement entry is imported only once
+func (r *TransactionRepository) GetExternalIDs(accountID uint) (map[string]bool, error) {
+	var ids []string
+	err := r.db.Unscoped().Model(&models.Transaction{}).
+		Where("account_id = ? AND external_id <> ''", accountID).
+		Pluck("external_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+// GetDuplicateCandidates gets the transactions of a user dated on or after
+// since that are not void, grouped by account, type and amount and ordered
+// by date within each group, so that probable duplicates are near each other
+func (r *TransactionRepository) GetDuplicateCandidates(userID uint, since time.Time) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.Preload("Tags").Preload("Payee").
+		Where("user_id = ? AND status <> ? AND date >= ?", userID, models.TransactionStatusVoid, since).
+		Order("account_id ASC, type ASC, currency ASC, amount_minor ASC, date ASC, id ASC").
+		Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// GetSummary gets a summary of transactions with the given statuses for a
+// specific period. Amounts are converted into currency at the rate in effect
+// on each transaction's date. Transfers are left out.
+func (r *TransactionRepository) GetSummary(userID uint, startDate, endDate time.Time, statuses []string, currency string, rates models.ExchangeRateSource) (*models.TransactionSummary, error) {
 	// Get transactions for the period
-	transactions, err := r.GetByPeriod(userID, startDate, endDate)
+	var transactions []models.Transaction
+	err := excludeTransfers(r.db.Preload("Splits")).
+		Where("user_id = ? AND date BETWEEN ? AND ? AND status IN ?", userID, startDate, endDate, statuses).
+		Order("date DESC").Find(&transactions).Error
 	if err != nil {
 		return nil, err
 	}
@@ -77,49 +304,109 @@ func (r *TransactionRepository) GetSummary(userID uint, startDate, endDate time.
 		Income:     0,
 		Expenses:   0,
 		Balance:    0,
+		Currency:   currency,
 		Count:      len(transactions),
 		ByCategory: []models.CategorySummary{},
 	}
 
+	// Totals are kept in minor units of the reporting currency so the sums stay exact
+	var income, expenses models.Money
+	categoryTotals := make(map[string]models.Money)
+
 	// Map to store category summaries
 	categoryMap := make(map[string]*models.CategorySummary)
 
 	// Calculate totals and category summaries
 	for _, t := range transactions {
+		amount, err := models.ConvertMoneyAt(rates, t.Amount, t.Currency, currency, t.Date)
+		if err != nil {
+			return nil, err
+		}
 		if t.Type == "income" {
-			summary.Income += t.Amount
+			income += amount
 		} else {
-			summary.Expenses += t.Amount
+			expenses += amount
 		}
 
-		// Update category summary
-		if _, ok := categoryMap[t.Category]; !ok {
-			categoryMap[t.Category] = &models.CategorySummary{
-				Category: t.Category,
-				Amount:   0,
-				Count:    0,
+		// Update category summaries, one per split line for split transactions
+		for _, line := range categoryLines(&t) {
+			if _, ok := categoryMap[line.Category]; !ok {
+				categoryMap[line.Category] = &models.CategorySummary{
+					Category: line.Category,
+					Amount:   0,
+					Count:    0,
+				}
 			}
-		}
 
-		if t.Type == "income" {
-			categoryMap[t.Category].Amount += t.Amount
-		} else {
-			categoryMap[t.Category].Amount -= t.Amount
+			lineAmount, err := models.ConvertMoneyAt(rates, line.Amount, t.Currency, currency, t.Date)
+			if err != nil {
+				return nil, err
+			}
+			if t.Type == "income" {
+				categoryTotals[line.Category] += lineAmount
+			} else {
+				categoryTotals[line.Category] -= lineAmount
+			}
+			categoryMap[line.Category].Count++
 		}
-		categoryMap[t.Category].Count++
 	}
 
+	summary.Income = income.Float(currency)
+	summary.Expenses = expenses.Float(currency)
+
 	// Calculate balance
-	summary.Balance = summary.Income - summary.Expenses
+	summary.Balance = (income - expenses).Float(currency)
 
 	// Convert category map to slice
-	for _, cs := range categoryMap {
+	for category, cs := range categoryMap {
+		cs.Amount = categoryTotals[category].Float(currency)
 		summary.ByCategory = append(summary.ByCategory, *cs)
 	}
 
 	return summary, nil
 }
 
+// categoryLine is an amount attributed to a single category
+type categoryLine struct {
+	Category string
+	Amount   models.Money
+}
+
+// categoryLines returns the per-category amounts of a transaction: its split
+// lines when it is split, otherwise the whole amount under its own category
+func categoryLines(t *models.Transaction) []categoryLine {
+	if !t.IsSplit() {
+		return []categoryLine{{Category: t.Category, Amount: t.Amount}}
+	}
+
+	lines := make([]categoryLine, 0, len(t.Splits))
+	for _, split := range t.Splits {
+		lines = append(lines, categoryLine{Category: split.Category, Amount: split.Amount})
+	}
+	return lines
+}
+
+// splitSubquery selects the IDs of transactions with a split line matching the condition
+func (r *TransactionRepository) splitSubquery(condition string, args ...interface{}) *gorm.DB {
+	return r.db.Model(&models.TransactionSplit{}).Select("transaction_id").Where(condition, args...)
+}
+
+// taggedSubquery selects the IDs of transactions carrying a tag of the user whose name matches the condition
+func (r *TransactionRepository) taggedSubquery(userID uint, condition string, args ...interface{}) *gorm.DB {
+	return r.db.Table("transaction_tags").Select("transaction_id").Where("tag_id IN (?)", r.tagIDs(userID, condition, args...))
+}
+
+// splitTaggedSubquery selects the IDs of transactions with a split line carrying a tag of the user whose name matches the condition
+func (r *TransactionRepository) splitTaggedSubquery(userID uint, condition string, args ...interface{}) *gorm.DB {
+	splitIDs := r.db.Table("transaction_split_tags").Select("transaction_split_id").Where("tag_id IN (?)", r.tagIDs(userID, condition, args...))
+	return r.splitSubquery("id IN (?)", splitIDs)
+}
+
+// tagIDs selects the IDs of the tags of a user whose name matches the condition
+func (r *TransactionRepository) tagIDs(userID uint, condition string, args ...interface{}) *gorm.DB {
+	return r.db.Model(&models.Tag{}).Select("id").Where("user_id = ?", userID).Where(condition, args...)
+}
+
 // GetFiltered gets transactions with filters and pagination
 func (r *TransactionRepository) GetFiltered(userID uint, filter *models.TransactionFilter) ([]models.Transaction, int64, error) {
 	var transactions []models.Transaction
@@ -128,21 +415,28 @@ func (r *TransactionRepository) GetFiltered(userID uint, filter *models.Transact
 	// Build query
 	query := r.db.Model(&models.Transaction{}).Where("user_id = ?", userID)
 
-	// Apply filters
+	// Apply filters, matching split lines as well as the transaction itself
 	if filter.Search != "" {
 		searchTerm := "%" + strings.ToLower(filter.Search) + "%"
-		query = query.Where("LOWER(description) LIKE ? OR LOWER(category) LIKE ? OR LOWER(tags) LIKE ?",
-			searchTerm, searchTerm, searchTerm)
+		query = query.Where("LOWER(description) LIKE ? OR LOWER(category) LIKE ? OR id IN (?) OR id IN (?) OR id IN (?)",
+			searchTerm, searchTerm,
+			r.taggedSubquery(userID, "LOWER(name) LIKE ?", searchTerm),
+			r.splitTaggedSubquery(userID, "LOWER(name) LIKE ?", searchTerm),
+			r.splitSubquery("LOWER(description) LIKE ? OR LOWER(category) LIKE ?", searchTerm, searchTerm))
 	}
 
 	if filter.Category != "" {
-		query = query.Where("category = ?", filter.Category)
+		query = query.Where("category = ? OR id IN (?)", filter.Category, r.splitSubquery("category = ?", filter.Category))
 	}
 
 	if filter.Type != "" {
 		query = query.Where("type = ?", filter.Type)
 	}
 
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
 	if filter.AccountID > 0 {
 		query = query.Where("account_id = ?", filter.AccountID)
 	}
@@ -160,16 +454,17 @@ func (r *TransactionRepository) GetFiltered(userID uint, filter *models.Transact
 	}
 
 	if filter.MinAmount > 0 {
-		query = query.Where("amount >= ?", filter.MinAmount)
+		cond, args := amountBoundCondition(">=", filter.MinAmount)
+		query = query.Where(cond, args...)
 	}
 
 	if filter.MaxAmount > 0 {
-		query = query.Where("amount <= ?", filter.MaxAmount)
+		cond, args := amountBoundCondition("<=", filter.MaxAmount)
+		query = query.Where(cond, args...)
 	}
 
-	if filter.Tags != "" {
-		tagTerm := "%" + filter.Tags + "%"
-		query = query.Where("tags LIKE ?", tagTerm)
+	for _, tag := range models.NormalizeTagNames(strings.Split(filter.Tags, ",")) {
+		query = r.whereTagged(query, userID, tag)
 	}
 
 	// Get total count
@@ -178,11 +473,8 @@ func (r *TransactionRepository) GetFiltered(userID uint, filter *models.Transact
 	// Apply sorting
 	sortBy := "date"
 	if filter.SortBy != "" {
-		allowedSortFields := map[string]bool{
-			"date": true, "amount": true, "category": true, "type": true, "created_at": true,
-		}
-		if allowedSortFields[filter.SortBy] {
-			sortBy = filter.SortBy
+		if column, ok := transactionSortColumns[filter.SortBy]; ok {
+			sortBy = column
 		}
 	}
 
@@ -208,7 +500,7 @@ func (r *TransactionRepository) GetFiltered(userID uint, filter *models.Transact
 	query = query.Offset(offset).Limit(pageSize)
 
 	// Execute query
-	err := query.Find(&transactions).Error
+	err := query.Preload("Splits.Tags").Preload("Tags").Preload("Payee").Find(&transactions).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -216,36 +508,82 @@ func (r *TransactionRepository) GetFiltered(userID uint, filter *models.Transact
 	return transactions, total, nil
 }
 
-// GetTotalSpentByCategory calculates total spent for a category within a date range
-func (r *TransactionRepository) GetTotalSpentByCategory(userID uint, category string, startDate, endDate time.Time) (float64, error) {
-	var total float64
+// GetTotalSpentByCategory calculates total spent for a category within a date range,
+// converted into the given currency at the rate in effect on each transaction's
+// date. Split transactions count only the lines in the category. Transfers,
+// scheduled and void transactions are not counted.
+func (r *TransactionRepository) GetTotalSpentByCategory(userID uint, category string, currency string, startDate, endDate time.Time, rates models.ExchangeRateSource) (models.Money, error) {
+	query := excludeTransfers(r.db.Model(&models.Transaction{})).
+		Where("user_id = ? AND type = ? AND date >= ? AND date <= ? AND status IN ?",
+			userID, "expense", startDate, endDate, models.PostedTransactionStatuses)
 
-	query := r.db.Model(&models.Transaction{}).
-		Where("user_id = ? AND type = ? AND date >= ? AND date <= ?", userID, "expense", startDate, endDate)
+	if category == "" {
+		return r.sumConverted(query.Select("currency, date, amount_minor"), currency, rates)
+	}
 
-	if category != "" {
-		query = query.Where("category = ?", category)
+	// Unsplit transactions in the category
+	total, err := r.sumConverted(query.
+		Select("currency, date, amount_minor").
+		Where("category = ?", category).
+		Where("id NOT IN (?)", r.db.Model(&models.TransactionSplit{}).Select("transaction_id")), currency, rates)
+	if err != nil {
+		return 0, err
 	}
 
-	err := query.Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	// Split lines in the category
+	lines, err := r.sumConverted(r.db.Table("transaction_splits").
+		Select("transactions.currency AS currency, transactions.date AS date, transaction_splits.amount_minor AS amount_minor").
+		Joins("JOIN transactions ON transactions.id = transaction_splits.transaction_id AND transactions.deleted_at IS NULL").
+		Where("transactions.user_id = ? AND transactions.type = ? AND transactions.date >= ? AND transactions.date <= ? AND transactions.status IN ?",
+			userID, "expense", startDate, endDate, models.PostedTransactionStatuses).
+		Where("transaction_splits.category = ?", category), currency, rates)
 	if err != nil {
 		return 0, err
 	}
 
-	return total, nil
+	return total + lines, nil
 }
 
-// GetTotalIncomeByPeriod calculates total income within a date range
-func (r *TransactionRepository) GetTotalIncomeByPeriod(userID uint, startDate, endDate time.Time) (float64, error) {
-	var total float64
+// GetTotalIncomeByPeriod calculates total posted income within a date range,
+// converted into the given currency at the rate in effect on each transaction's
+// date. Transfers are not counted.
+func (r *TransactionRepository) GetTotalIncomeByPeriod(userID uint, currency string, startDate, endDate time.Time, rates models.ExchangeRateSource) (models.Money, error) {
+	query := excludeTransfers(r.db.Model(&models.Transaction{})).
+		Select("currency, date, amount_minor").
+		Where("user_id = ? AND type = ? AND date >= ? AND date <= ? AND status IN ?",
+			userID, "income", startDate, endDate, models.PostedTransactionStatuses)
 
-	err := r.db.Model(&models.Transaction{}).
-		Where("user_id = ? AND type = ? AND date >= ? AND date <= ?", userID, "income", startDate, endDate).
-		Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
-	if err != nil {
+	return r.sumConverted(query, currency, rates)
+}
+
+// excludeTransfers leaves out transactions that move money between the
+// user's own accounts: transfer legs and transactions filed under
+// TransferCategory. They are neither income nor spending.
+func excludeTransfers(query *gorm.DB) *gorm.DB {
+	return query.Where("type <> ? AND category <> ?", "transfer", models.TransferCategory)
+}
+
+// sumConverted sums the currency, date and amount_minor rows selected by the
+// query, converting each row into currency at the rate in effect on its date
+func (r *TransactionRepository) sumConverted(query *gorm.DB, currency string, rates models.ExchangeRateSource) (models.Money, error) {
+	var rows []struct {
+		Currency    string
+		Date        time.Time
+		AmountMinor int64
+	}
+	if err := query.Scan(&rows).Error; err != nil {
 		return 0, err
 	}
 
+	var total models.Money
+	for _, row := range rows {
+		amount, err := models.ConvertMoneyAt(rates, models.Money(row.AmountMinor), row.Currency, currency, row.Date)
+		if err != nil {
+			return 0, err
+		}
+		total += amount
+	}
+
 	return total, nil
 }
 
@@ -258,8 +596,72 @@ func (r *TransactionRepository) GetPaginated(
 	var total int64
 
 	// Base query
-	query := r.db.Model(&models.Transaction{}).Where("user_id = ?", userID)
+	query := r.applyFilterRequest(r.db.Model(&models.Transaction{}).Where("user_id = ?", userID), userID, filter)
+
+	// Count total matching records
+	countQuery := query
+	if err := countQuery.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Apply sorting
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = "date"
+	}
+
+	// Validate sortBy to prevent SQL injection
+	column, ok := transactionSortColumns[sortBy]
+	if !ok {
+		column = "date"
+	}
+	sortBy = column
+
+	sortOrder := strings.ToUpper(filter.SortOrder)
+	if sortOrder != "ASC" && sortOrder != "DESC" {
+		sortOrder = "DESC"
+	}
+
+	query = query.Order(sortBy + " " + sortOrder)
+
+	// Apply pagination
+	offset := filter.GetOffset()
+	query = query.Offset(offset).Limit(filter.PageSize)
+
+	// Execute query
+	if err := query.Preload("Splits.Tags").Preload("Tags").Preload("Payee").Find(&transactions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return transactions, total, nil
+}
+
+// GetIDsByFilter gets the IDs of all transactions matching a filter, ignoring pagination
+func (r *TransactionRepository) GetIDsByFilter(userID uint, filter *models.TransactionFilterRequest) ([]uint, error) {
+	var ids []uint
+	err := r.applyFilterRequest(r.db.Model(&models.Transaction{}).Where("user_id = ?", userID), userID, filter).
+		Order("id ASC").
+		Pluck("id", &ids).Error
+	return ids, err
+}
 
+// GetAccountIDs gets the accounts of transactions and of the other legs of
+// transfers among them, in ascending order
+func (r *TransactionRepository) GetAccountIDs(userID uint, ids []uint) ([]uint, error) {
+	var accountIDs []uint
+	entries := r.db.Model(&models.Transaction{}).
+		Select("journal_entry_id").
+		Where("user_id = ? AND id IN ? AND journal_entry_id IS NOT NULL", userID, ids)
+	err := r.db.Model(&models.Transaction{}).
+		Distinct("account_id").
+		Where("user_id = ? AND (id IN ? OR journal_entry_id IN (?))", userID, ids, entries).
+		Order("account_id ASC").
+		Pluck("account_id", &accountIDs).Error
+	return accountIDs, err
+}
+
+// applyFilterRequest narrows a transaction query by the filters of a request
+func (r *TransactionRepository) applyFilterRequest(query *gorm.DB, userID uint, filter *models.TransactionFilterRequest) *gorm.DB {
 	// Apply date filters
 	if filter.StartDate != nil {
 		query = query.Where("date >= ?", filter.StartDate)
@@ -270,17 +672,19 @@ func (r *TransactionRepository) GetPaginated(
 
 	// Apply amount filters
 	if filter.MinAmount != nil {
-		query = query.Where("amount >= ?", *filter.MinAmount)
+		cond, args := amountBoundCondition(">=", *filter.MinAmount)
+		query = query.Where(cond, args...)
 	}
 	if filter.MaxAmount != nil {
-		query = query.Where("amount <= ?", *filter.MaxAmount)
+		cond, args := amountBoundCondition("<=", *filter.MaxAmount)
+		query = query.Where(cond, args...)
 	}
 
-	// Apply category filters (support both single and multiple categories)
+	// Apply category filters (support both single and multiple categories, including split lines)
 	if len(filter.Categories) > 0 {
-		query = query.Where("category IN ?", filter.Categories)
+		query = query.Where("category IN ? OR id IN (?)", filter.Categories, r.splitSubquery("category IN ?", filter.Categories))
 	} else if filter.Category != "" {
-		query = query.Where("category = ?", filter.Category)
+		query = query.Where("category = ? OR id IN (?)", filter.Category, r.splitSubquery("category = ?", filter.Category))
 	}
 
 	// Apply type filter
@@ -288,63 +692,79 @@ func (r *TransactionRepository) GetPaginated(
 		query = query.Where("type = ?", filter.Type)
 	}
 
+	// Apply status filter
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+
 	// Apply account filter
 	if filter.AccountID != nil {
 		query = query.Where("account_id = ?", *filter.AccountID)
 	}
 
-	// Apply search filter (search in description, category, and tags)
+	// Apply payee filter
+	if filter.PayeeID != nil {
+		query = query.Where("payee_id = ?", *filter.PayeeID)
+	}
+
+	// Apply search filter (search in description, category, payee, and tags of the transaction and its split lines)
 	if filter.Search != "" {
 		searchTerm := "%" + strings.ToLower(filter.Search) + "%"
 		query = query.Where(
-			"LOWER(description) LIKE ? OR LOWER(category) LIKE ? OR LOWER(tags) LIKE ?",
-			searchTerm, searchTerm, searchTerm,
+			"LOWER(description) LIKE ? OR LOWER(category) LIKE ? OR payee_id IN (?) OR id IN (?) OR id IN (?) OR id IN (?)",
+			searchTerm, searchTerm,
+			r.db.Model(&models.Payee{}).Select("id").Where("user_id = ? AND LOWER(name) LIKE ?", userID, searchTerm),
+			r.taggedSubquery(userID, "LOWER(name) LIKE ?", searchTerm),
+			r.splitTaggedSubquery(userID, "LOWER(name) LIKE ?", searchTerm),
+			r.splitSubquery("LOWER(description) LIKE ? OR LOWER(category) LIKE ?", searchTerm, searchTerm),
 		)
 	}
 
-	// Apply tags filter
-	if len(filter.Tags) > 0 {
-		for _, tag := range filter.Tags {
-			tagTerm := "%" + tag + "%"
-			query = query.Where("tags LIKE ?", tagTerm)
-		}
+	// Apply tags filter; every tag must be present
+	for _, tag := range models.NormalizeTagNames(filter.Tags) {
+		query = r.whereTagged(query, userID, tag)
 	}
 
-	// Count total matching records
-	countQuery := query
-	if err := countQuery.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
+	return query
+}
 
-	// Apply sorting
-	sortBy := filter.SortBy
-	if sortBy == "" {
-		sortBy = "date"
-	}
+// whereTagged narrows a transaction query to transactions carrying a tag
+// themselves or on one of their split lines
+func (r *TransactionRepository) whereTagged(query *gorm.DB, userID uint, tag string) *gorm.DB {
+	name := strings.ToLower(tag)
+	return query.Where("id IN (?) OR id IN (?)",
+		r.taggedSubquery(userID, "LOWER(name) = ?", name),
+		r.splitTaggedSubquery(userID, "LOWER(name) = ?", name))
+}
 
-	// Validate sortBy to prevent SQL injection
-	allowedSortFields := map[string]bool{
-		"date": true, "amount": true, "category": true, "type": true, "created_at": true,
-	}
-	if !allowedSortFields[sortBy] {
-		sortBy = "date"
+// amountBoundCondition builds a condition comparing amount_minor against an
+// amount in major units. The bound is scaled per currency so that rows in
+// zero-decimal currencies (JPY, VND, ...) are compared correctly.
+func amountBoundCondition(op string, amount float64) (string, []interface{}) {
+	codesByDecimals := make(map[int][]string)
+	var known []string
+	for _, c := range models.SupportedCurrencies() {
+		codesByDecimals[c.Decimals] = append(codesByDecimals[c.Decimals], c.Code)
+		known = append(known, c.Code)
 	}
 
-	sortOrder := strings.ToUpper(filter.SortOrder)
-	if sortOrder != "ASC" && sortOrder != "DESC" {
-		sortOrder = "DESC"
+	decimals := make([]int, 0, len(codesByDecimals))
+	for d := range codesByDecimals {
+		decimals = append(decimals, d)
 	}
+	sort.Ints(decimals)
 
-	query = query.Order(sortBy + " " + sortOrder)
-
-	// Apply pagination
-	offset := filter.GetOffset()
-	query = query.Offset(offset).Limit(filter.PageSize)
-
-	// Execute query
-	if err := query.Find(&transactions).Error; err != nil {
-		return nil, 0, err
+	var clauses []string
+	var args []interface{}
+	for _, d := range decimals {
+		codes := codesByDecimals[d]
+		clauses = append(clauses, "(currency IN ? AND amount_minor "+op+" ?)")
+		args = append(args, codes, int64(models.NewMoney(amount, codes[0])))
 	}
 
-	return transactions, total, nil
+	// Unknown currencies fall back to two decimals
+	clauses = append(clauses, "(currency NOT IN ? AND amount_minor "+op+" ?)")
+	args = append(args, known, int64(models.NewMoney(amount, "")))
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args
 }
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// findDeleted loads the trashed records of a user into dest, most recently deleted first
+func findDeleted(db *gorm.DB, dest interface{}, userID uint) error {
+	return db.Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(dest).Error
+}
+
+// findDeletedBefore loads every record into dest that has been in the trash since before cutoff
+func findDeletedBefore(db *gorm.DB, dest interface{}, cutoff time.Time) error {
+	return db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Find(dest).Error
+}
+
+// restoreDeleted takes a record of a user out of the trash
+func restoreDeleted(db *gorm.DB, model interface{}, id uint, userID uint) error {
+	result := db.Unscoped().Model(model).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// purgeDeleted permanently deletes a trashed record of a user
+func purgeDeleted(db *gorm.DB, model interface{}, id uint, userID uint) error {
+	result := db.Unscoped().
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		Delete(model)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
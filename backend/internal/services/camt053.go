@@ -0,0 +1,303 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// camtDocument is the part of an ISO 20022 camt.053 bank to customer
+// statement that is imported. Elements match in any namespace, so every
+// version of the message reads the same.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	ID      string        `xml:"Id"`
+	Account camtAccount   `xml:"Acct"`
+	Balance []camtBalance `xml:"Bal"`
+	Entries []camtEntry   `xml:"Ntry"`
+}
+
+type camtAccount struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Type     string `xml:"Tp>Cd"`
+	Currency string `xml:"Ccy"`
+	Name     string `xml:"Nm"`
+	BIC      string `xml:"Svcr>FinInstnId>BIC"`
+	BICFI    string `xml:"Svcr>FinInstnId>BICFI"`
+}
+
+type camtBalance struct {
+	Type   string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount camtAmount `xml:"Amt"`
+	Credit string     `xml:"CdtDbtInd"`
+	Date   camtDate   `xml:"Dt"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtDate is a date given either as a date or as a date and time
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtEntry struct {
+	Reference      string            `xml:"NtryRef"`
+	Amount         camtAmount        `xml:"Amt"`
+	Credit         string            `xml:"CdtDbtInd"`
+	Status         camtStatus        `xml:"Sts"`
+	BookingDate    camtDate          `xml:"BookgDt"`
+	ValueDate      camtDate          `xml:"ValDt"`
+	ServicerRef    string            `xml:"AcctSvcrRef"`
+	Details        []camtTransaction `xml:"NtryDtls>TxDtls"`
+	AdditionalInfo string            `xml:"AddtlNtryInf"`
+}
+
+type camtTransaction struct {
+	Amount        camtAmount `xml:"Amt"`
+	TxAmount      camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	Credit        string     `xml:"CdtDbtInd"`
+	EndToEndID    string     `xml:"Refs>EndToEndId"`
+	ServicerRef   string     `xml:"Refs>AcctSvcrRef"`
+	Creditor      camtParty  `xml:"RltdPties>Cdtr"`
+	Debtor        camtParty  `xml:"RltdPties>Dbtr"`
+	Unstructured  []string   `xml:"RmtInf>Ustrd"`
+	CreditorRefs  []string   `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	AdditionalInf string     `xml:"AddtlTxInf"`
+}
+
+// camtStatus is the status of an entry, given as a code element in newer
+// versions of the message
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+// camtParty is a creditor or debtor; newer versions wrap the party in Pty
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+// camtAccountTypes maps ISO 20022 cash account types to account types
+var camtAccountTypes = map[string]string{
+	"CACC": "checking",
+	"TRAN": "checking",
+	"SVGS": "savings",
+	"MOMA": "savings",
+	"CARD": "credit",
+	"LOAN": "loan",
+}
+
+// ParseCAMT053 parses the statements of an ISO 20022 camt.053 file. Only
+// booked entries are read; an entry with several transactions is read as
+// one entry per transaction when they give their amounts.
+func ParseCAMT053(data io.Reader) ([]BankStatement, error) {
+	var document camtDocument
+	if err := xml.NewDecoder(data).Decode(&document); err != nil {
+		return nil, errors.New("not a camt.053 file")
+	}
+	if len(document.Statements) == 0 {
+		return nil, errors.New("no statements found in camt.053 file")
+	}
+
+	statements := make([]BankStatement, 0, len(document.Statements))
+	for i := range document.Statements {
+		statement, err := parseCAMTStatement(&document.Statements[i])
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, *statement)
+	}
+	return statements, nil
+}
+
+// parseCAMTStatement converts a Stmt element to a statement
+func parseCAMTStatement(stmt *camtStatement) (*BankStatement, error) {
+	statement := &BankStatement{
+		Format:        "camt.053",
+		BankID:        firstNonEmpty(stmt.Account.BICFI, stmt.Account.BIC),
+		AccountNumber: normalizeAccountNumber(firstNonEmpty(stmt.Account.IBAN, stmt.Account.Other)),
+		AccountName:   strings.TrimSpace(stmt.Account.Name),
+		AccountType:   camtAccountTypes[strings.ToUpper(stmt.Account.Type)],
+		Currency:      strings.ToUpper(stmt.Account.Currency),
+	}
+	if statement.AccountNumber == "" {
+		return nil, fmt.Errorf("camt.053 statement %s has no account", stmt.ID)
+	}
+
+	// Opening balances are booked (OPBD) or carried over from the previous
+	// statement (PRCD); the closing balance is the booked one (CLBD)
+	for _, balance := range stmt.Balance {
+		date, err := balance.Date.parse()
+		if err != nil {
+			return nil, err
+		}
+		amount := camtSigned(balance.Amount.Value, balance.Credit)
+		if statement.Currency == "" {
+			statement.Currency = strings.ToUpper(balance.Amount.Currency)
+		}
+		switch strings.ToUpper(balance.Type) {
+		case "OPBD", "PRCD":
+			if statement.OpeningBalance == "" || balance.Type == "OPBD" {
+				statement.OpeningBalance = amount
+				statement.OpeningDate = date
+			}
+		case "CLBD":
+			statement.LedgerBalance = amount
+			statement.BalanceDate = date
+		}
+	}
+
+	for i := range stmt.Entries {
+		entries, err := parseCAMTEntry(&stmt.Entries[i])
+		if err != nil {
+			return nil, err
+		}
+		statement.Entries = append(statement.Entries, entries...)
+	}
+	return statement, nil
+}
+
+// parseCAMTEntry converts a booked Ntry element to statement entries
+func parseCAMTEntry(ntry *camtEntry) ([]StatementEntry, error) {
+	status := strings.ToUpper(strings.TrimSpace(firstNonEmpty(ntry.Status.Code, ntry.Status.Value)))
+	if status != "" && status != "BOOK" {
+		return nil, nil
+	}
+
+	// Entries are dated when booked, or else when the money is available
+	date, err := ntry.BookingDate.parse()
+	if err == nil && date.IsZero() {
+		date, err = ntry.ValueDate.parse()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if date.IsZero() {
+		return nil, fmt.Errorf("camt.053 entry %s has no booking or value date", ntry.ServicerRef)
+	}
+
+	reference := firstNonEmpty(ntry.ServicerRef, ntry.Reference)
+	entry := StatementEntry{
+		ExternalID: reference,
+		Date:       date,
+		Amount:     camtSigned(ntry.Amount.Value, ntry.Credit),
+		Name:       strings.TrimSpace(ntry.AdditionalInfo),
+	}
+	if len(ntry.Details) == 0 {
+		return []StatementEntry{entry}, nil
+	}
+
+	// Batch bookings list their transactions, each with its own amount
+	split := len(ntry.Details) > 1
+	for _, tx := range ntry.Details {
+		if firstNonEmpty(tx.TxAmount.Value, tx.Amount.Value) == "" {
+			split = false
+		}
+	}
+	if !split {
+		return []StatementEntry{camtTransactionEntry(entry, &ntry.Details[0], ntry.Credit)}, nil
+	}
+
+	entries := make([]StatementEntry, 0, len(ntry.Details))
+	for i := range ntry.Details {
+		tx := &ntry.Details[i]
+		txEntry := entry
+		txEntry.ExternalID = ""
+		txEntry.Amount = camtSigned(firstNonEmpty(tx.TxAmount.Value, tx.Amount.Value), firstNonEmpty(tx.Credit, ntry.Credit))
+		txEntry = camtTransactionEntry(txEntry, tx, ntry.Credit)
+
+		// Transactions without references of their own are numbered within
+		// the entry
+		if txEntry.ExternalID == "" && reference != "" {
+			txEntry.ExternalID = fmt.Sprintf("%s-%d", reference, i+1)
+		}
+		entries = append(entries, txEntry)
+	}
+	return entries, nil
+}
+
+// camtTransactionEntry fills an entry from the details of its transaction:
+// the remittance information as the description and the other party as
+// the payee
+func camtTransactionEntry(entry StatementEntry, tx *camtTransaction, credit string) StatementEntry {
+	if entry.ExternalID == "" {
+		endToEnd := tx.EndToEndID
+		if endToEnd == "NOTPROVIDED" {
+			endToEnd = ""
+		}
+		entry.ExternalID = firstNonEmpty(tx.ServicerRef, endToEnd)
+	}
+
+	remittance := strings.TrimSpace(strings.Join(tx.Unstructured, " "))
+	if remittance == "" {
+		remittance = strings.TrimSpace(strings.Join(tx.CreditorRefs, " "))
+	}
+	entry.Name = firstNonEmpty(remittance, strings.TrimSpace(tx.AdditionalInf), entry.Name)
+
+	// Money coming in was paid by the debtor; money going out, to the creditor
+	party := tx.Creditor
+	if strings.ToUpper(firstNonEmpty(tx.Credit, credit)) == "CRDT" {
+		party = tx.Debtor
+	}
+	entry.Payee = strings.TrimSpace(firstNonEmpty(party.PartyName, party.Name))
+	return entry
+}
+
+// parse returns the date, or the zero time when none is given. Dates
+// without a time are midnight UTC.
+func (d camtDate) parse() (time.Time, error) {
+	if value := strings.TrimSpace(d.Date); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid camt.053 date: %s", value)
+		}
+		return date, nil
+	}
+	if value := strings.TrimSpace(d.DateTime); value != "" {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05"} {
+			if date, err := time.Parse(layout, value); err == nil {
+				return date, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid camt.053 date: %s", value)
+	}
+	return time.Time{}, nil
+}
+
+// camtSigned returns an amount signed by its credit or debit indicator;
+// debits leave the account
+func camtSigned(amount, credit string) string {
+	amount = strings.TrimSpace(amount)
+	if amount != "" && strings.ToUpper(strings.TrimSpace(credit)) == "DBIT" {
+		return "-" + amount
+	}
+	return amount
+}
+
+// normalizeAccountNumber strips the spaces banks and users put in account
+// numbers and IBANs, so they compare equal however they are written
+func normalizeAccountNumber(number string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(number), ""))
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
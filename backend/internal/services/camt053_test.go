@@ -0,0 +1,269 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// camtSingle is a camt.053.001.02 statement with one transaction per entry,
+// an entry without details and a pending entry
+const camtSingle = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>053D2024020106000</MsgId><CreDtTm>2024-02-01T06:00:00</CreDtTm></GrpHdr>
+    <Stmt>
+      <Id>2024-01</Id>
+      <Acct>
+        <Id><IBAN>DE89 3704 0044 0532 0130 00</IBAN></Id>
+        <Tp><Cd>CACC</Cd></Tp>
+        <Ccy>EUR</Ccy>
+        <Nm>Girokonto</Nm>
+        <Svcr><FinInstnId><BIC>COBADEFFXXX</BIC></FinInstnId></Svcr>
+      </Acct>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="EUR">1000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-01-01</Dt></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="EUR">887.50</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-01-31</Dt></Dt>
+      </Bal>
+      <Ntry>
+        <Amt Ccy="EUR">12.50</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><Dt>2024-01-03</Dt></BookgDt><ValDt><Dt>2024-01-02</Dt></ValDt>
+        <AcctSvcrRef>2024010300001</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs>
+            <RltdPties><Cdtr><Nm>Coffee Shop GmbH</Nm></Cdtr></RltdPties>
+            <RmtInf><Ustrd>Card payment 1234</Ustrd><Ustrd>Coffee</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <NtryRef>R4</NtryRef>
+        <Amt Ccy="EUR">100.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+        <ValDt><Dt>2024-01-28</Dt></ValDt>
+        <AddtlNtryInf>Account fee</AddtlNtryInf>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">50.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>PDNG</Sts>
+        <BookgDt><Dt>2024-01-31</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+// camtBatched is a camt.053.001.08 statement with batch bookings, one
+// listing the amount of each transaction and one that does not
+const camtBatched = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>B-2024-02</MsgId><CreDtTm>2024-03-01T05:30:00+01:00</CreDtTm></GrpHdr>
+    <Stmt>
+      <Id>B-2024-02</Id>
+      <Acct>
+        <Id><Othr><Id>0532013000</Id></Othr></Id>
+        <Tp><Cd>SVGS</Cd></Tp>
+        <Svcr><FinInstnId><BICFI>COBADEFFXXX</BICFI></FinInstnId></Svcr>
+      </Acct>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>PRCD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="EUR">250.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Dt><DtTm>2024-02-01T00:00:00</DtTm></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="EUR">2705.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-02-29</Dt></Dt>
+      </Bal>
+      <Ntry>
+        <Amt Ccy="EUR">3000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2024-02-15</Dt></BookgDt>
+        <AcctSvcrRef>B-77</AcctSvcrRef>
+        <NtryDtls>
+          <Btch><NbOfTxs>3</NbOfTxs></Btch>
+          <TxDtls>
+            <Refs><EndToEndId>SAL-2024-02</EndToEndId></Refs>
+            <Amt Ccy="EUR">1500.00</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+            <RltdPties><Dbtr><Pty><Nm>ACME AG</Nm></Pty></Dbtr></RltdPties>
+            <RmtInf><Ustrd>Salary February</Ustrd></RmtInf>
+          </TxDtls>
+          <TxDtls>
+            <Refs><AcctSvcrRef>TX-2</AcctSvcrRef></Refs>
+            <AmtDtls><TxAmt><Amt Ccy="EUR">1000.00</Amt></TxAmt></AmtDtls>
+            <RltdPties><Dbtr><Pty><Nm>Jane Doe</Nm></Pty></Dbtr></RltdPties>
+            <RmtInf><Strd><CdtrRefInf><Ref>RF18539007547034</Ref></CdtrRefInf></Strd></RmtInf>
+          </TxDtls>
+          <TxDtls>
+            <Amt Ccy="EUR">500.00</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+            <RltdPties><Dbtr><Nm>John Roe</Nm></Dbtr></RltdPties>
+            <AddtlTxInf>Rent share</AddtlTxInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">45.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2024-02-20</Dt></BookgDt>
+        <AcctSvcrRef>B-78</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <RltdPties><Cdtr><Nm>Utility Co</Nm></Cdtr></RltdPties>
+            <RmtInf><Ustrd>Electricity</Ustrd></RmtInf>
+          </TxDtls>
+          <TxDtls>
+            <RltdPties><Cdtr><Nm>Utility Co</Nm></Cdtr></RltdPties>
+            <RmtInf><Ustrd>Gas</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+func TestParseCAMT053(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []BankStatement
+	}{
+		{
+			name: "single transactions, entries without details and pending entries",
+			data: camtSingle,
+			expected: []BankStatement{{
+				Format:         "camt.053",
+				BankID:         "COBADEFFXXX",
+				AccountNumber:  "DE89370400440532013000",
+				AccountName:    "Girokonto",
+				AccountType:    "checking",
+				Currency:       "EUR",
+				LedgerBalance:  "887.50",
+				BalanceDate:    time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+				OpeningBalance: "1000.00",
+				OpeningDate:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Entries: []StatementEntry{
+					{ExternalID: "2024010300001", Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), Amount: "-12.50",
+						Name: "Card payment 1234 Coffee", Payee: "Coffee Shop GmbH"},
+					{ExternalID: "R4", Date: time.Date(2024, 1, 28, 0, 0, 0, 0, time.UTC), Amount: "-100.00", Name: "Account fee"},
+				},
+			}},
+		},
+		{
+			name: "batched transactions",
+			data: camtBatched,
+			expected: []BankStatement{{
+				Format:         "camt.053",
+				BankID:         "COBADEFFXXX",
+				AccountNumber:  "0532013000",
+				AccountType:    "savings",
+				Currency:       "EUR",
+				LedgerBalance:  "2705.00",
+				BalanceDate:    time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+				OpeningBalance: "-250.00",
+				OpeningDate:    time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				Entries: []StatementEntry{
+					{ExternalID: "SAL-2024-02", Date: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), Amount: "1500.00",
+						Name: "Salary February", Payee: "ACME AG"},
+					{ExternalID: "TX-2", Date: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), Amount: "1000.00",
+						Name: "RF18539007547034", Payee: "Jane Doe"},
+					{ExternalID: "B-77-3", Date: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), Amount: "500.00",
+						Name: "Rent share", Payee: "John Roe"},
+					{ExternalID: "B-78", Date: time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC), Amount: "-45.00",
+						Name: "Electricity", Payee: "Utility Co"},
+				},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, err := ParseCAMT053(strings.NewReader(tt.data))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, statements)
+		})
+	}
+}
+
+func TestParseCAMT053_Invalid(t *testing.T) {
+	// statement wraps the elements of a statement in a camt.053 document
+	statement := func(elements string) string {
+		return `<Document><BkToCstmrStmt><Stmt><Id>S1</Id>` + elements + `</Stmt></BkToCstmrStmt></Document>`
+	}
+	account := `<Acct><Id><IBAN>DE89370400440532013000</IBAN></Id></Acct>`
+
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "not XML", data: "Buchungstag;Betrag\n03.01.2024;-12,50\n", err: "not a camt.053 file"},
+		{name: "no statements", data: `<Document><BkToCstmrStmt><GrpHdr><MsgId>1</MsgId></GrpHdr></BkToCstmrStmt></Document>`,
+			err: "no statements found in camt.053 file"},
+		{name: "no account", data: statement(`<Acct><Ccy>EUR</Ccy></Acct>`), err: "camt.053 statement S1 has no account"},
+		{name: "invalid date", data: statement(account + `<Ntry><Amt>1.00</Amt><CdtDbtInd>DBIT</CdtDbtInd>` +
+			`<BookgDt><Dt>03.01.2024</Dt></BookgDt></Ntry>`), err: "invalid camt.053 date: 03.01.2024"},
+		{name: "entry without date", data: statement(account + `<Ntry><Amt>1.00</Amt><CdtDbtInd>DBIT</CdtDbtInd>` +
+			`<AcctSvcrRef>REF9</AcctSvcrRef></Ntry>`), err: "camt.053 entry REF9 has no booking or value date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCAMT053(strings.NewReader(tt.data))
+
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestImportStatements_CAMT053(t *testing.T) {
+	// Setup - an account whose IBAN is written in groups
+	db, importService, _ := setupImportTest(t)
+	user := createImportTestUser(t, db, "camt")
+	account := &models.Account{UserID: user.ID, Name: "Girokonto", Type: "checking", Currency: "EUR",
+		ExternalNumber: "de89 3704 0044 0532 0130 00"}
+	assert.NoError(t, db.Create(account).Error)
+
+	statements, err := ParseCAMT053(strings.NewReader(camtSingle))
+	assert.NoError(t, err)
+
+	// Execute
+	results, err := importService.ImportStatements(user.ID, statements, ImportOptions{})
+
+	// Assert - the statement goes into the account with its IBAN
+	assert.NoError(t, err)
+	result := results[0]
+	assert.Equal(t, account.ID, *result.AccountID)
+	assert.Equal(t, 2, result.Imported)
+
+	var transaction models.Transaction
+	assert.NoError(t, db.Where("account_id = ? AND external_id = ?", account.ID, "2024010300001").First(&transaction).Error)
+	assert.Equal(t, "Card payment 1234 Coffee", transaction.Description)
+	assert.True(t, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC).Equal(transaction.Date))
+
+	// Both balances are checkpoints, and the missing opening balance shows as a difference
+	var checkpoints []models.BalanceHistory
+	db.Where("account_id = ? AND change_type = ?", account.ID, models.BalanceChangeCheckpoint).Order("recorded_at").Find(&checkpoints)
+	assert.Len(t, checkpoints, 2)
+	assert.Equal(t, models.NewMoney(1000.0, "EUR"), checkpoints[0].Balance)
+	assert.True(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Equal(checkpoints[0].RecordedAt))
+	assert.Equal(t, models.NewMoney(887.50, "EUR"), checkpoints[1].Balance)
+	assert.True(t, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC).Equal(checkpoints[1].RecordedAt))
+	assert.Equal(t, 1000.0, *result.BalanceDifference)
+
+	// Importing the statement again skips its entries by their bank references
+	results, err = importService.ImportStatements(user.ID, statements, ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, results[0].Imported)
+	assert.Equal(t, 2, results[0].Existing)
+
+	var count int64
+	db.Model(&models.Transaction{}).Where("account_id = ?", account.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+	db.Model(&models.BalanceHistory{}).Where("account_id = ? AND change_type = ?", account.ID, models.BalanceChangeCheckpoint).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
@@ -1,13 +1,18 @@
 package services
 
 import (
+	"archive/zip"
 	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/storage"
 	"github.com/quocdaijr/finance-management-backend/internal/repository"
 )
 
@@ -16,6 +21,10 @@ type ExportService struct {
 	transactionRepo *repository.TransactionRepository
 	accountRepo     *repository.AccountRepository
 	budgetRepo      *repository.BudgetRepository
+	attachmentRepo  *repository.AttachmentRepository
+	categoryRepo    *repository.CategoryRepository
+	investmentRepo  *repository.InvestmentRepository
+	store           storage.BlobStore
 }
 
 // NewExportService creates a new export service
@@ -23,11 +32,19 @@ func NewExportService(
 	transactionRepo *repository.TransactionRepository,
 	accountRepo *repository.AccountRepository,
 	budgetRepo *repository.BudgetRepository,
+	attachmentRepo *repository.AttachmentRepository,
+	categoryRepo *repository.CategoryRepository,
+	investmentRepo *repository.InvestmentRepository,
+	store storage.BlobStore,
 ) *ExportService {
 	return &ExportService{
 		transactionRepo: transactionRepo,
 		accountRepo:     accountRepo,
 		budgetRepo:      budgetRepo,
+		attachmentRepo:  attachmentRepo,
+		categoryRepo:    categoryRepo,
+		investmentRepo:  investmentRepo,
+		store:           store,
 	}
 }
 
@@ -59,31 +76,55 @@ func (s *ExportService) ExportTransactionsCSV(userID uint, startDate, endDate *t
 	writer := csv.NewWriter(&buf)
 
 	// Write header
-	header := []string{"ID", "Date", "Type", "Category", "Description", "Amount", "Account", "Tags", "Created At"}
+	header := []string{"ID", "Date", "Type", "Category", "Description", "Payee", "Amount", "Currency", "Account", "Tags", "Split", "Created At"}
 	if err := writer.Write(header); err != nil {
 		return nil, err
 	}
 
-	// Write transactions
+	// Write transactions, one row per line for split transactions
 	for _, t := range transactions {
 		accountName := accountMap[t.AccountID]
 		if accountName == "" {
 			accountName = fmt.Sprintf("Account #%d", t.AccountID)
 		}
+		var payee string
+		if t.Payee != nil {
+			payee = t.Payee.Name
+		}
 
-		row := []string{
-			fmt.Sprintf("%d", t.ID),
-			t.Date.Format("2006-01-02"),
-			t.Type,
-			t.Category,
-			t.Description,
-			fmt.Sprintf("%.2f", t.Amount),
-			accountName,
-			t.Tags,
-			t.CreatedAt.Format("2006-01-02 15:04:05"),
+		row := func(category, description string, amount models.Money, tags, split string) []string {
+			return []string{
+				fmt.Sprintf("%d", t.ID),
+				t.Date.Format("2006-01-02"),
+				t.Type,
+				category,
+				description,
+				payee,
+				amount.Format(t.Currency),
+				t.Currency,
+				accountName,
+				tags,
+				split,
+				t.CreatedAt.Format("2006-01-02 15:04:05"),
+			}
 		}
-		if err := writer.Write(row); err != nil {
-			return nil, err
+
+		if !t.IsSplit() {
+			if err := writer.Write(row(t.Category, t.Description, t.Amount, strings.Join(models.TagNames(t.Tags), ","), "")); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		for i, split := range t.Splits {
+			description := t.Description
+			if split.Description != "" {
+				description = split.Description
+			}
+			position := fmt.Sprintf("%d/%d", i+1, len(t.Splits))
+			if err := writer.Write(row(split.Category, description, split.Amount, strings.Join(models.TagNames(split.Tags), ","), position)); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -95,6 +136,105 @@ func (s *ExportService) ExportTransactionsCSV(userID uint, startDate, endDate *t
 	return buf.Bytes(), nil
 }
 
+// ExportTransactionsQIF exports transactions to QIF format, one section per
+// account, with the category list and, for investment accounts, the
+// securities traded and their trades. ImportService.ImportQIF reads it back.
+func (s *ExportService) ExportTransactionsQIF(userID uint, startDate, endDate *time.Time) ([]byte, error) {
+	var transactions []models.Transaction
+	var err error
+
+	if startDate != nil && endDate != nil {
+		transactions, err = s.transactionRepo.GetByPeriod(userID, *startDate, *endDate)
+	} else {
+		transactions, err = s.transactionRepo.GetAll(userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.accountRepo.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := s.categoryRepo.GetPaths(userID)
+	if err != nil {
+		return nil, err
+	}
+	investments, err := s.investmentRepo.GetTransactions(userID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Oldest first, the order QIF files are usually in
+	sort.SliceStable(transactions, func(i, j int) bool { return transactions[i].Date.Before(transactions[j].Date) })
+	sort.SliceStable(investments, func(i, j int) bool { return investments[i].Date.Before(investments[j].Date) })
+
+	// The category list holds the categories used, flagged as income when
+	// used by income
+	byAccount := make(map[uint][]models.Transaction)
+	categories := make(map[string]bool)
+	for _, t := range transactions {
+		byAccount[t.AccountID] = append(byAccount[t.AccountID], t)
+		names := []string{t.Category}
+		for _, split := range t.Splits {
+			names = append(names, split.Category)
+		}
+		for _, name := range names {
+			if name != "" && name != models.TransferCategory && name != models.SplitCategory {
+				categories[name] = categories[name] || t.Type == "income"
+			}
+		}
+	}
+
+	// Dividends are written with their investment transaction rather than
+	// as cash
+	tradesByAccount := make(map[uint][]models.InvestmentTransaction)
+	dividendCash := make(map[uint]bool)
+	securities := make(map[uint]*models.Security)
+	for _, t := range investments {
+		if (startDate != nil && t.Date.Before(*startDate)) || (endDate != nil && t.Date.After(*endDate)) {
+			continue
+		}
+		tradesByAccount[t.AccountID] = append(tradesByAccount[t.AccountID], t)
+		if t.TransactionID != nil {
+			dividendCash[*t.TransactionID] = true
+		}
+		if t.Security != nil {
+			securities[t.SecurityID] = t.Security
+		}
+	}
+
+	w := &qifWriter{}
+	w.categories(categories, paths)
+	w.securities(securities)
+
+	for _, account := range accounts {
+		accountTransactions := byAccount[account.ID]
+		trades := tradesByAccount[account.ID]
+		if len(accountTransactions) == 0 && len(trades) == 0 {
+			continue
+		}
+
+		section := qifSectionType(account.Type)
+		w.account(&account, section)
+		for i := range accountTransactions {
+			t := &accountTransactions[i]
+			switch {
+			case dividendCash[t.ID]:
+			case section == "Invst":
+				w.investmentCash(t, paths)
+			default:
+				w.transaction(t, paths)
+			}
+		}
+		for i := range trades {
+			w.trade(&trades[i])
+		}
+	}
+
+	return w.buf.Bytes(), nil
+}
+
 // ExportTransactionsJSON exports transactions to JSON format
 func (s *ExportService) ExportTransactionsJSON(userID uint, startDate, endDate *time.Time) ([]byte, error) {
 	// Get transactions
@@ -111,16 +251,99 @@ func (s *ExportService) ExportTransactionsJSON(userID uint, startDate, endDate *
 		return nil, err
 	}
 
+	// Group attachment metadata by transaction
+	attachments, err := s.attachmentRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	attachmentMap := make(map[uint][]*models.AttachmentResponse)
+	for i := range attachments {
+		a := &attachments[i]
+		attachmentMap[a.TransactionID] = append(attachmentMap[a.TransactionID], a.ToResponse())
+	}
+
 	// Convert to response format
 	var response []*models.TransactionResponse
 	for _, t := range transactions {
-		response = append(response, t.ToResponse())
+		r := t.ToResponse()
+		r.Attachments = attachmentMap[t.ID]
+		response = append(response, r)
 	}
 
 	// Marshal to JSON
 	return json.MarshalIndent(response, "", "  ")
 }
 
+// ExportArchive exports all of a user's data as a zip archive containing the
+// transactions, the accounts and the attached files
+func (s *ExportService) ExportArchive(userID uint) ([]byte, error) {
+	transactionsJSON, err := s.ExportTransactionsJSON(userID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	accountsCSV, err := s.ExportAccountsCSV(userID)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := s.attachmentRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	// Write the data files
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"transactions.json", transactionsJSON},
+		{"accounts.csv", accountsCSV},
+	}
+	for _, f := range files {
+		w, err := archive.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+
+	// Copy the attached files, prefixed with their ID so names stay unique
+	for i := range attachments {
+		if err := s.addAttachment(archive, &attachments[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// addAttachment copies an attached file from the blob store into the archive
+func (s *ExportService) addAttachment(archive *zip.Writer, a *models.Attachment) error {
+	file, err := s.store.Get(a.StorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to read attachment %d: %w", a.ID, err)
+	}
+	defer file.Close()
+
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     fmt.Sprintf("attachments/%d/%d-%s", a.TransactionID, a.ID, a.FileName),
+		Method:   zip.Deflate,
+		Modified: a.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
+
 // ExportAccountsCSV exports accounts to CSV format
 func (s *ExportService) ExportAccountsCSV(userID uint) ([]byte, error) {
 	accounts, err := s.accountRepo.GetAll(userID)
@@ -143,7 +366,7 @@ func (s *ExportService) ExportAccountsCSV(userID uint) ([]byte, error) {
 			fmt.Sprintf("%d", a.ID),
 			a.Name,
 			a.Type,
-			fmt.Sprintf("%.2f", a.Balance),
+			a.Balance.Format(a.Currency),
 			a.Currency,
 			a.CreatedAt.Format("2006-01-02 15:04:05"),
 		}
@@ -155,4 +378,3 @@ func (s *ExportService) ExportAccountsCSV(userID uint) ([]byte, error) {
 	writer.Flush()
 	return buf.Bytes(), writer.Error()
 }
-
@@ -1,52 +1,143 @@
 package services
 
 import (
+	"bytes"
 	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
 	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"gorm.io/gorm"
 )
 
 // ImportService handles data import operations
 type ImportService struct {
-	transactionRepo *repository.TransactionRepository
-	accountRepo     *repository.AccountRepository
+	transactionRepo    *repository.TransactionRepository
+	accountRepo        *repository.AccountRepository
+	tagRepo            *repository.TagRepository
+	payeeRepo          *repository.PayeeRepository
+	ruleRepo           *repository.RuleRepository
+	categoryRepo       *repository.CategoryRepository
+	profileRepo        *repository.ImportProfileRepository
+	ledgerRepo         *repository.LedgerRepository
+	balanceHistoryRepo *repository.BalanceHistoryRepository
+	investments        InvestmentRecorder
+	db                 *gorm.DB
+}
+
+// InvestmentRecorder records trades of securities with their lots and
+// ledger entries; the investment service implements it
+type InvestmentRecorder interface {
+	GetSecurities(userID uint) ([]models.Security, error)
+	CreateSecurity(userID uint, req *models.SecurityRequest) (*models.Security, error)
+	GetTransactions(userID uint, accountID *uint) ([]models.InvestmentTransaction, error)
+	RecordTransaction(userID uint, req *models.InvestmentTransactionRequest) (*models.InvestmentTransaction, error)
 }
 
 // NewImportService creates a new import service
 func NewImportService(
 	transactionRepo *repository.TransactionRepository,
 	accountRepo *repository.AccountRepository,
+	tagRepo *repository.TagRepository,
+	payeeRepo *repository.PayeeRepository,
+	ruleRepo *repository.RuleRepository,
+	categoryRepo *repository.CategoryRepository,
+	profileRepo *repository.ImportProfileRepository,
+	ledgerRepo *repository.LedgerRepository,
+	balanceHistoryRepo *repository.BalanceHistoryRepository,
+	investments InvestmentRecorder,
+	db *gorm.DB,
 ) *ImportService {
 	return &ImportService{
-		transactionRepo: transactionRepo,
-		accountRepo:     accountRepo,
+		transactionRepo:    transactionRepo,
+		accountRepo:        accountRepo,
+		tagRepo:            tagRepo,
+		payeeRepo:          payeeRepo,
+		ruleRepo:           ruleRepo,
+		categoryRepo:       categoryRepo,
+		profileRepo:        profileRepo,
+		ledgerRepo:         ledgerRepo,
+		balanceHistoryRepo: balanceHistoryRepo,
+		investments:        investments,
+		db:                 db,
 	}
 }
 
 // ImportResult contains the result of an import operation
 type ImportResult struct {
-	TotalRows    int      `json:"total_rows"`
-	Imported     int      `json:"imported"`
-	Skipped      int      `json:"skipped"`
-	Errors       []string `json:"errors"`
-	Transactions []uint   `json:"transaction_ids"`
+	TotalRows    int               `json:"total_rows"`
+	Imported     int               `json:"imported"`
+	Skipped      int               `json:"skipped"`
+	Errors       []string          `json:"errors"`
+	Transactions []uint            `json:"transaction_ids"`
+	Duplicates   []ImportDuplicate `json:"duplicates"`           // Rows that are probably already recorded
+	ProfileID    *uint             `json:"profile_id,omitempty"` // Import profile a CSV file was read with; nil for the template layout
+}
+
+// ImportDuplicate is an import row that is a probable duplicate of an
+// existing transaction
+type ImportDuplicate struct {
+	Row          int    `json:"row"`
+	Description  string `json:"description"`
+	DuplicateIDs []uint `json:"duplicate_ids"` // Existing transactions the row probably duplicates
+	Imported     bool   `json:"imported"`      // Whether the row was imported anyway
 }
 
-// ImportTransactionsCSV imports transactions from CSV data
-func (s *ImportService) ImportTransactionsCSV(userID uint, data io.Reader) (*ImportResult, error) {
-	reader := csv.NewReader(data)
+// ImportOptions changes how rows are imported
+type ImportOptions struct {
+	AllowDuplicates bool            // Import probable duplicates instead of skipping them
+	ProfileID       uint            // Import profile to read CSV files with; detected from the header when zero
+	AccountMap      map[string]uint // Accounts to import bank statements into, by statement account number or name
+	CreateAccounts  bool            // Create an account for each bank statement that matches none
+}
 
-	// Read header
-	header, err := reader.Read()
+// StatementImportResult contains the result of importing one bank statement
+type StatementImportResult struct {
+	ImportResult
+	Format            string                 `json:"format"`
+	AccountNumber     string                 `json:"account_number,omitempty"`
+	AccountName       string                 `json:"account_name,omitempty"`
+	AccountType       string                 `json:"account_type"`
+	Currency          string                 `json:"currency"`
+	AccountID         *uint                  `json:"account_id"` // Account the statement was imported into; nil when it matched none
+	AccountCreated    bool                   `json:"account_created"`
+	SuggestedAccount  *models.AccountRequest `json:"suggested_account,omitempty"`          // Account to create, or map the statement to, when it matched none
+	Existing          int                    `json:"existing"`                             // Entries skipped because they were imported before
+	Investments       []uint                 `json:"investment_transaction_ids,omitempty"` // Investment transactions recorded
+	LedgerBalance     *float64               `json:"ledger_balance,omitempty"`             // Closing balance reported by the bank
+	BalanceDate       *time.Time             `json:"balance_date,omitempty"`
+	OpeningBalance    *float64               `json:"opening_balance,omitempty"` // Balance at the start of the statement reported by the bank
+	OpeningDate       *time.Time             `json:"opening_date,omitempty"`
+	BalanceDifference *float64               `json:"balance_difference,omitempty"` // LedgerBalance less the account balance after the import
+}
+
+// importedRow is a parsed CSV row: the transaction and the names of its
+// tags and payee, which are resolved when the row is saved
+type importedRow struct {
+	transaction *models.Transaction
+	tags        []string
+	payee       string
+	description string // Description as given, to match the payee by
+}
+
+// ImportTransactionsCSV imports transactions from CSV data laid out as the
+// import profile opts names, else as the user's profile the header matches,
+// else as the import template. Rows that are probable duplicates of
+// transactions recorded before the import are skipped unless opts allows
+// them.
+func (s *ImportService) ImportTransactionsCSV(userID uint, data io.Reader, opts ImportOptions) (*ImportResult, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return nil, errors.New("failed to read CSV file")
+	}
+
+	profile, reader, header, err := s.csvProfile(userID, content, opts.ProfileID)
 	if err != nil {
-		return nil, errors.New("failed to read CSV header")
+		return nil, err
 	}
 
 	// Map column names to indices
@@ -56,11 +147,8 @@ func (s *ImportService) ImportTransactionsCSV(userID uint, data io.Reader) (*Imp
 	}
 
 	// Required columns
-	required := []string{"amount", "type", "date"}
-	for _, req := range required {
-		if _, ok := colMap[req]; !ok {
-			return nil, fmt.Errorf("missing required column: %s", req)
-		}
+	if missing := models.MissingColumn(header, profile.RequiredColumns()); missing != "" {
+		return nil, fmt.Errorf("missing required column: %s", missing)
 	}
 
 	// Get user's accounts for validation
@@ -69,24 +157,30 @@ func (s *ImportService) ImportTransactionsCSV(userID uint, data io.Reader) (*Imp
 		return nil, errors.New("failed to get user accounts")
 	}
 
-	// Create account name to ID map
-	accountMap := make(map[string]uint)
-	var defaultAccountID uint
-	for _, acc := range accounts {
-		accountMap[strings.ToLower(acc.Name)] = acc.ID
-		if defaultAccountID == 0 {
-			defaultAccountID = acc.ID
+	// Create account name to account map; rows naming no account go into
+	// the account of the profile, else the first account
+	accountMap := make(map[string]*models.Account)
+	var defaultAccount *models.Account
+	for i := range accounts {
+		accountMap[strings.ToLower(accounts[i].Name)] = &accounts[i]
+		if defaultAccount == nil || (profile.AccountID != nil && accounts[i].ID == *profile.AccountID) {
+			defaultAccount = &accounts[i]
 		}
 	}
 
-	if defaultAccountID == 0 {
+	if defaultAccount == nil {
 		return nil, errors.New("no accounts found - please create an account first")
 	}
 
 	result := &ImportResult{
 		Errors:       []string{},
 		Transactions: []uint{},
+		Duplicates:   []ImportDuplicate{},
 	}
+	if profile.ID != 0 {
+		result.ProfileID = &profile.ID
+	}
+	imported := make(map[uint]bool)
 
 	// Process rows
 	for {
@@ -104,112 +198,1050 @@ func (s *ImportService) ImportTransactionsCSV(userID uint, data io.Reader) (*Imp
 		result.TotalRows++
 
 		// Parse transaction
-		transaction, parseErr := s.parseTransactionRow(record, colMap, accountMap, defaultAccountID, userID)
+		row, parseErr := s.parseTransactionRow(record, colMap, profile, accountMap, defaultAccount, userID)
 		if parseErr != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: %s", result.TotalRows, parseErr.Error()))
 			result.Skipped++
 			continue
 		}
 
-		// Save transaction
-		if err := s.transactionRepo.Create(transaction); err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: failed to save", result.TotalRows))
-			result.Skipped++
+		s.importRow(result, row, imported, opts)
+	}
+
+	return result, nil
+}
+
+// csvProfile returns the import profile to read CSV data with and a reader
+// of the data positioned after its header, with the header. Without a
+// profile ID the header is matched against each profile of the user, the
+// one reading the most columns winning; data matching none is read as the
+// import template.
+func (s *ImportService) csvProfile(userID uint, content []byte, profileID uint) (*models.ImportProfile, *csv.Reader, []string, error) {
+	if profileID != 0 {
+		profile, err := s.profileRepo.GetByID(profileID, userID)
+		if err != nil {
+			return nil, nil, nil, errors.New("import profile not found")
+		}
+		reader, header, err := csvReader(content, profile)
+		return profile, reader, header, err
+	}
+
+	profiles, err := s.profileRepo.GetAll(userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var detected *models.ImportProfile
+	for i := range profiles {
+		profile := &profiles[i]
+		_, header, err := csvReader(content, profile)
+		if err != nil || !profile.Matches(header) {
 			continue
 		}
+		if detected == nil || len(profile.Columns()) > len(detected.Columns()) {
+			detected = profile
+		}
+	}
+	if detected == nil {
+		detected = models.DefaultImportProfile()
+	}
 
-		// Update account balance
-		account, _ := s.accountRepo.GetByID(transaction.AccountID, userID)
-		if account != nil {
-			if transaction.Type == "income" {
-				account.Balance += transaction.Amount
-			} else {
-				account.Balance -= transaction.Amount
+	reader, header, err := csvReader(content, detected)
+	return detected, reader, header, err
+}
+
+// csvReader returns a reader of CSV data in the layout of a profile,
+// positioned after the header, with the header. Preamble lines are skipped
+// as text, as they are often not valid CSV.
+func csvReader(content []byte, profile *models.ImportProfile) (*csv.Reader, []string, error) {
+	text := strings.TrimPrefix(string(content), "\ufeff")
+	for i := 0; i < profile.SkipRows; i++ {
+		_, rest, found := strings.Cut(text, "\n")
+		if !found {
+			text = ""
+			break
+		}
+		text = rest
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = rune(profile.Delimiter[0])
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, errors.New("failed to read CSV header")
+	}
+	return reader, header, nil
+}
+
+// CSVTemplate returns a sample CSV file in the layout of an import profile
+// of the user, with a placeholder for each preamble line and sample rows
+// written as the bank would write them
+func (s *ImportService) CSVTemplate(userID uint, profileID uint) ([]byte, error) {
+	profile, err := s.profileRepo.GetByID(profileID, userID)
+	if err != nil {
+		return nil, errors.New("import profile not found")
+	}
+
+	accountName := "Main Account"
+	currency := "USD"
+	if profile.AccountID != nil {
+		if account, err := s.accountRepo.GetByID(*profile.AccountID, userID); err == nil {
+			accountName, currency = account.Name, account.Currency
+		}
+	}
+
+	var buf bytes.Buffer
+	for i := 1; i <= profile.SkipRows; i++ {
+		fmt.Fprintf(&buf, "Statement preamble line %d\n", i)
+	}
+
+	writer := csv.NewWriter(&buf)
+	writer.Comma = rune(profile.Delimiter[0])
+	if err := writer.Write(profile.Columns()); err != nil {
+		return nil, err
+	}
+
+	layout := "2006-01-02"
+	if profile.DateFormat != "" {
+		layout = profile.DateLayout()
+	}
+	samples := []struct {
+		day                          int
+		amount                       float64
+		transType                    string
+		category, description, payee string
+		tags                         string
+	}{
+		{15, 1500, "income", "Salary", "Monthly salary", "ACME Corp", "work"},
+		{16, 1234.5, "expense", "Housing", "Rent", "Landlord", "home"},
+		{17, 50, "expense", "Food & Dining", "Lunch with colleagues", "Corner Cafe", "food"},
+	}
+	for _, sample := range samples {
+		amount := models.NewMoney(sample.amount, currency)
+		formatted := amount.Format(currency)
+		if profile.DecimalSeparator == "," {
+			formatted = strings.Replace(formatted, ".", ",", 1)
+		}
+		signed := formatted
+		if (sample.transType == "expense") != (profile.SignConvention == models.ImportSignNegativeIncome) {
+			signed = "-" + formatted
+		}
+
+		values := map[string]string{
+			profile.DateColumn:        time.Date(2024, time.January, sample.day, 0, 0, 0, 0, time.UTC).Format(layout),
+			profile.DescriptionColumn: sample.description,
+			profile.PayeeColumn:       sample.payee,
+			profile.CategoryColumn:    sample.category,
+			profile.AccountColumn:     accountName,
+			profile.TagsColumn:        sample.tags,
+		}
+		switch profile.SignConvention {
+		case models.ImportSignTypeColumn:
+			values[profile.AmountColumn] = formatted
+			values[profile.TypeColumn] = sample.transType
+		case models.ImportSignDebitCredit:
+			values[profile.DebitColumn], values[profile.CreditColumn] = formatted, ""
+			if sample.transType == "income" {
+				values[profile.DebitColumn], values[profile.CreditColumn] = "", formatted
 			}
-			s.accountRepo.Update(account)
+		default:
+			values[profile.AmountColumn] = signed
 		}
 
-		result.Imported++
-		result.Transactions = append(result.Transactions, transaction.ID)
+		row := make([]string, 0, len(profile.Columns()))
+		for _, column := range profile.Columns() {
+			row = append(row, values[column])
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, err
+		}
 	}
 
-	return result, nil
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// importRow saves a parsed row as the row numbered result.TotalRows and
+// records the outcome in result. Rows repeated within one import are kept;
+// only transactions that existed before the import count as duplicates, and
+// probable duplicates are skipped unless opts allows them.
+func (s *ImportService) importRow(result *ImportResult, row *importedRow, imported map[uint]bool, opts ImportOptions) bool {
+	duplicate, err := s.findDuplicate(row.transaction, imported)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Row %d: failed to check for duplicates", result.TotalRows))
+		result.Skipped++
+		return false
+	}
+	if duplicate != nil {
+		duplicate.Row = result.TotalRows
+		duplicate.Imported = opts.AllowDuplicates
+		result.Duplicates = append(result.Duplicates, *duplicate)
+		if !opts.AllowDuplicates {
+			result.Skipped++
+			return false
+		}
+	}
+
+	// Save transaction with its journal entry and derive the account balance
+	if err := s.saveTransaction(row); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Row %d: failed to save", result.TotalRows))
+		result.Skipped++
+		return false
+	}
+
+	result.Imported++
+	result.Transactions = append(result.Transactions, row.transaction.ID)
+	imported[row.transaction.ID] = true
+	return true
+}
+
+// findDuplicate returns the probable duplicates of an import row among the
+// user's transactions, leaving out those imported in the same run, or nil
+// when there are none
+func (s *ImportService) findDuplicate(transaction *models.Transaction, imported map[uint]bool) (*ImportDuplicate, error) {
+	duplicates, err := s.transactionRepo.FindProbableDuplicates(transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for _, d := range duplicates {
+		if !imported[d.ID] {
+			ids = append(ids, d.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return &ImportDuplicate{Description: transaction.Description, DuplicateIDs: ids}, nil
 }
 
-func (s *ImportService) parseTransactionRow(record []string, colMap map[string]int, accountMap map[string]uint, defaultAccountID uint, userID uint) (*models.Transaction, error) {
+// saveTransaction runs the user's rules over an imported transaction and
+// saves it with its tags and payee together with its journal entry, derives
+// the account balance from the ledger and records the change in balance
+// history in one DB transaction
+func (s *ImportService) saveTransaction(row *importedRow) error {
+	transaction := row.transaction
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		tags, err := s.tagRepo.WithTx(tx).FindOrCreate(transaction.UserID, row.tags)
+		if err != nil {
+			return err
+		}
+		transaction.Tags = tags
+
+		// Rows without a category take the default category of their payee
+		payee, err := s.payeeRepo.WithTx(tx).Resolve(transaction.UserID, row.payee, row.description, transaction.Category)
+		if err != nil {
+			return err
+		}
+		if payee != nil {
+			transaction.PayeeID = &payee.ID
+			transaction.Payee = payee
+			if transaction.Category == "" {
+				transaction.Category = payee.DefaultCategory
+			}
+		}
+
+		// The user's rules run before rows still without a category fall back to Other
+		rules, err := s.ruleRepo.WithTx(tx).GetActive(transaction.UserID)
+		if err != nil {
+			return err
+		}
+		models.ApplyRules(rules, transaction)
+		if transaction.Category == "" {
+			transaction.Category = "Other"
+		}
+
+		// Future-dated rows are stored as scheduled and posted when due
+		if !transaction.IsPosted() {
+			return tx.Create(transaction).Error
+		}
+
+		ledgerRepo := s.ledgerRepo.WithTx(tx)
+
+		if err := ledgerRepo.PostTransaction(transaction, models.JournalEntryTypeTransaction); err != nil {
+			return err
+		}
+
+		if err := tx.Create(transaction).Error; err != nil {
+			return err
+		}
+
+		account, delta, err := ledgerRepo.SyncAccountBalanceByID(transaction.AccountID)
+		if err != nil {
+			return err
+		}
+		if delta == 0 {
+			return nil
+		}
+
+		change := models.NewTransactionBalanceChange(transaction)
+		return s.balanceHistoryRepo.WithTx(tx).RecordBalanceChange(
+			account.UserID,
+			account.ID,
+			account.Balance,
+			delta,
+			change.ChangeType,
+			change.TransactionID,
+			"Imported: "+change.Description,
+		)
+	})
+}
+
+// parseTransactionRow converts a CSV row read with a profile to an import row
+func (s *ImportService) parseTransactionRow(record []string, colMap map[string]int, profile *models.ImportProfile, accountMap map[string]*models.Account, defaultAccount *models.Account, userID uint) (*importedRow, error) {
 	getValue := func(col string) string {
-		if idx, ok := colMap[col]; ok && idx < len(record) {
+		if col == "" {
+			return ""
+		}
+		if idx, ok := colMap[strings.ToLower(col)]; ok && idx < len(record) {
 			return strings.TrimSpace(record[idx])
 		}
 		return ""
 	}
 
-	// Parse amount
-	amountStr := getValue("amount")
-	amount, err := strconv.ParseFloat(strings.ReplaceAll(amountStr, ",", ""), 64)
+	// Parse account
+	account := defaultAccount
+	if accName := getValue(profile.AccountColumn); accName != "" {
+		if acc, ok := accountMap[strings.ToLower(accName)]; ok {
+			account = acc
+		}
+	}
+
+	// Parse amount exactly in the account's currency, telling income from
+	// expense by the sign convention of the profile
+	amount, transType, err := rowAmount(profile, getValue, account.Currency)
 	if err != nil {
-		return nil, fmt.Errorf("invalid amount: %s", amountStr)
+		return nil, err
 	}
-	if amount < 0 {
+
+	// Parse date
+	date, err := profile.ParseDate(getValue(profile.DateColumn))
+	if err != nil {
+		return nil, err
+	}
+
+	// Get optional fields; a missing category is filled in from the payee when saving
+	description := getValue(profile.DescriptionColumn)
+	category := getValue(profile.CategoryColumn)
+
+	// Tags are separated by commas
+	tags := models.NormalizeTagNames(strings.Split(getValue(profile.TagsColumn), ","))
+
+	transaction := &models.Transaction{
+		UserID:      userID,
+		Amount:      amount,
+		Currency:    account.Currency,
+		Description: description,
+		Category:    category,
+		Type:        transType,
+		Date:        date,
+		AccountID:   account.ID,
+		Status:      models.ResolveTransactionStatus("", date, time.Now()),
+	}
+	if transaction.Description == "" {
+		transaction.Description = "Imported transaction"
+	}
+
+	return &importedRow{
+		transaction: transaction,
+		tags:        tags,
+		payee:       getValue(profile.PayeeColumn),
+		description: description,
+	}, nil
+}
+
+// rowAmount reads the unsigned amount and the type, income or expense, of
+// a CSV row by the sign convention of a profile
+func rowAmount(profile *models.ImportProfile, getValue func(string) string, currency string) (models.Money, string, error) {
+	var amount models.Money
+	switch profile.SignConvention {
+	case models.ImportSignDebitCredit:
+		// Debits and credits may both be given; the row is what they net to
+		for _, column := range []string{profile.CreditColumn, profile.DebitColumn} {
+			value := getValue(column)
+			if value == "" {
+				continue
+			}
+			parsed, err := profile.ParseAmount(value, currency)
+			if err != nil {
+				return 0, "", err
+			}
+			if column == profile.DebitColumn {
+				amount -= parsed.Abs()
+			} else {
+				amount += parsed.Abs()
+			}
+		}
+	default:
+		amountStr := getValue(profile.AmountColumn)
+		parsed, err := profile.ParseAmount(amountStr, currency)
+		if err != nil {
+			return 0, "", err
+		}
+		amount = parsed
+	}
+
+	switch profile.SignConvention {
+	case models.ImportSignTypeColumn:
+		transType := strings.ToLower(getValue(profile.TypeColumn))
+		switch transType {
+		case "income", "credit", "cr":
+			return amount.Abs(), "income", nil
+		case "expense", "debit", "dr":
+			return amount.Abs(), "expense", nil
+		}
+		return 0, "", fmt.Errorf("invalid type: %s (must be 'income' or 'expense')", transType)
+	case models.ImportSignNegativeIncome:
 		amount = -amount
 	}
 
-	// Parse type
-	transType := strings.ToLower(getValue("type"))
-	if transType != "income" && transType != "expense" {
-		return nil, fmt.Errorf("invalid type: %s (must be 'income' or 'expense')", transType)
+	if amount == 0 {
+		return 0, "", errors.New("amount is zero")
+	}
+	if amount < 0 {
+		return amount.Abs(), "expense", nil
 	}
+	return amount, "income", nil
+}
 
-	// Parse date
-	dateStr := getValue("date")
-	var date time.Time
-	dateFormats := []string{
-		"2006-01-02",
-		"01/02/2006",
-		"02/01/2006",
-		"2006-01-02T15:04:05Z",
-		"2006-01-02 15:04:05",
-	}
-	for _, format := range dateFormats {
-		if parsed, err := time.Parse(format, dateStr); err == nil {
-			date = parsed
-			break
+// ImportStatements imports parsed bank statements. Each statement goes into
+// the account opts maps its account number to, else the account with that
+// external number, else a new account when opts allows it; statements that
+// match no account are left out with a suggested account to create. Entries
+// whose bank ID was imported into the account before are skipped, and the
+// balance the bank reports is recorded in balance history as a checkpoint.
+func (s *ImportService) ImportStatements(userID uint, statements []BankStatement, opts ImportOptions) ([]*StatementImportResult, error) {
+	results := make([]*StatementImportResult, 0, len(statements))
+	for i := range statements {
+		result, err := s.importStatement(userID, &statements[i], opts)
+		if err != nil {
+			return nil, err
 		}
+		results = append(results, result)
 	}
-	if date.IsZero() {
-		return nil, fmt.Errorf("invalid date format: %s", dateStr)
+	return results, nil
+}
+
+// importStatement imports the entries of one bank statement
+func (s *ImportService) importStatement(userID uint, statement *BankStatement, opts ImportOptions) (*StatementImportResult, error) {
+	result := &StatementImportResult{
+		ImportResult: ImportResult{
+			Errors:       []string{},
+			Transactions: []uint{},
+			Duplicates:   []ImportDuplicate{},
+		},
+		Format:        statement.Format,
+		AccountNumber: statement.AccountNumber,
+		AccountName:   statement.AccountName,
+		AccountType:   statement.AccountType,
+		Currency:      statement.Currency,
 	}
 
-	// Parse account
-	accountID := defaultAccountID
-	if accName := getValue("account"); accName != "" {
-		if id, ok := accountMap[strings.ToLower(accName)]; ok {
-			accountID = id
+	balance := func(name, value string, date time.Time) (*float64, *time.Time) {
+		if value == "" {
+			return nil, nil
+		}
+		amount, err := models.ParseMoney(value, statement.Currency)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid %s balance: %s", name, value))
+			return nil, nil
+		}
+		amountFloat := amount.Float(statement.Currency)
+		if date.IsZero() {
+			return &amountFloat, nil
 		}
+		return &amountFloat, &date
 	}
+	result.LedgerBalance, result.BalanceDate = balance("ledger", statement.LedgerBalance, statement.BalanceDate)
+	result.OpeningBalance, result.OpeningDate = balance("opening", statement.OpeningBalance, statement.OpeningDate)
 
-	// Get optional fields
-	description := getValue("description")
-	if description == "" {
-		description = "Imported transaction"
+	skipAll := func(reason string) (*StatementImportResult, error) {
+		result.TotalRows = len(statement.Entries) + len(statement.Investments)
+		result.Skipped = result.TotalRows
+		if reason != "" {
+			result.Errors = append(result.Errors, reason)
+		}
+		return result, nil
+	}
+
+	account, created, err := s.statementAccount(userID, statement, opts)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		result.SuggestedAccount = suggestedAccount(statement)
+		return skipAll("")
+	}
+	result.AccountID = &account.ID
+	result.AccountCreated = created
+	if statement.Currency != "" && statement.Currency != account.Currency {
+		return skipAll(fmt.Sprintf("statement currency %s does not match account currency %s", statement.Currency, account.Currency))
+	}
+
+	existing, err := s.transactionRepo.GetExternalIDs(account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := make(map[uint]bool)
+	categories := make(map[string]string)
+	for _, entry := range statement.Entries {
+		result.TotalRows++
+
+		if entry.ExternalID != "" && existing[entry.ExternalID] {
+			result.Existing++
+			result.Skipped++
+			continue
+		}
+
+		row, err := statementRow(userID, account, &entry)
+		if err == nil {
+			err = s.categorize(row, &entry, categories)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: %s", result.TotalRows, err.Error()))
+			result.Skipped++
+			continue
+		}
+
+		if s.importRow(&result.ImportResult, row, imported, opts) && entry.ExternalID != "" {
+			existing[entry.ExternalID] = true
+		}
+	}
+
+	if len(statement.Investments) > 0 {
+		if err := s.importInvestments(userID, account, statement, result); err != nil {
+			return nil, err
+		}
+	}
+
+	// Record the balances the bank reports as checkpoints to compare against
+	if result.OpeningBalance != nil && result.OpeningDate != nil {
+		if err := s.balanceHistoryRepo.RecordCheckpoint(userID, account.ID, models.NewMoney(*result.OpeningBalance, account.Currency), *result.OpeningDate,
+			statement.Format+" statement opening balance"); err != nil {
+			return nil, err
+		}
+	}
+	if result.LedgerBalance != nil {
+		recordedAt := statement.BalanceDate
+		if recordedAt.IsZero() {
+			recordedAt = time.Now()
+		}
+		if err := s.balanceHistoryRepo.RecordCheckpoint(userID, account.ID, models.NewMoney(*result.LedgerBalance, account.Currency), recordedAt,
+			statement.Format+" statement balance"); err != nil {
+			return nil, err
+		}
+
+		updated, err := s.accountRepo.GetByID(account.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		ledgerBalance, _ := models.ParseMoney(statement.LedgerBalance, account.Currency)
+		difference := (ledgerBalance - updated.Balance).Float(account.Currency)
+		result.BalanceDifference = &difference
+	}
+
+	return result, nil
+}
+
+// statementAccount returns the account to import a statement into and
+// whether it was created, or nil when the statement matches no account
+func (s *ImportService) statementAccount(userID uint, statement *BankStatement, opts ImportOptions) (*models.Account, bool, error) {
+	if accountID, ok := opts.AccountMap[statement.Key()]; ok {
+		account, err := s.accountRepo.GetByID(accountID, userID)
+		if err != nil {
+			return nil, false, fmt.Errorf("account %d for statement %s not found", accountID, statement.Key())
+		}
+
+		// Remember the account number so later statements match by themselves
+		if account.ExternalNumber == "" && statement.AccountNumber != "" {
+			account.ExternalNumber = statement.AccountNumber
+			if err := s.db.Model(account).Update("external_number", account.ExternalNumber).Error; err != nil {
+				return nil, false, err
+			}
+		}
+		return account, false, nil
+	}
+
+	accounts, err := s.accountRepo.GetAll(userID)
+	if err != nil {
+		return nil, false, errors.New("failed to get user accounts")
+	}
+	for i := range accounts {
+		if statement.AccountNumber != "" && normalizeAccountNumber(accounts[i].ExternalNumber) == normalizeAccountNumber(statement.AccountNumber) {
+			return &accounts[i], false, nil
+		}
+	}
+
+	// Files without account numbers, like QIF, name their accounts instead
+	if statement.AccountNumber == "" && statement.AccountName != "" {
+		for i := range accounts {
+			if strings.EqualFold(accounts[i].Name, statement.AccountName) {
+				return &accounts[i], false, nil
+			}
+		}
+	}
+
+	if !opts.CreateAccounts {
+		return nil, false, nil
+	}
+	account, err := s.createStatementAccount(userID, statement)
+	if err != nil {
+		return nil, false, err
+	}
+	return account, true, nil
+}
+
+// createStatementAccount creates the suggested account of a statement with
+// its opening balance in the ledger
+func (s *ImportService) createStatementAccount(userID uint, statement *BankStatement) (*models.Account, error) {
+	req := suggestedAccount(statement)
+	account := &models.Account{
+		UserID:         userID,
+		Name:           req.Name,
+		Type:           req.Type,
+		Currency:       req.Currency,
+		ExternalNumber: req.ExternalNumber,
+	}
+	openingBalance := models.NewMoney(req.Balance, req.Currency)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.accountRepo.WithTx(tx).Create(account); err != nil {
+			return err
+		}
+		if openingBalance == 0 {
+			return nil
+		}
+
+		ledgerRepo := s.ledgerRepo.WithTx(tx)
+		if err := ledgerRepo.CreateEntry(&models.JournalEntry{
+			UserID:      userID,
+			Type:        models.JournalEntryTypeOpeningBalance,
+			Description: "Opening balance",
+			Date:        time.Now(),
+			Postings: []models.Posting{
+				models.NewAccountPosting(userID, account.ID, openingBalance, account.Currency),
+				models.NewLedgerPosting(userID, models.LedgerEquityOpeningBalances, -openingBalance, account.Currency),
+			},
+		}); err != nil {
+			return err
+		}
+
+		synced, delta, err := ledgerRepo.SyncAccountBalanceByID(account.ID)
+		if err != nil {
+			return err
+		}
+		*account = *synced
+		return s.balanceHistoryRepo.WithTx(tx).RecordBalanceChange(
+			userID,
+			account.ID,
+			account.Balance,
+			delta,
+			models.BalanceChangeAdjustment,
+			nil,
+			"Opening balance",
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// suggestedAccount returns the account to create for a statement, opened
+// with the opening balance of the statement, or else the ledger balance
+// less its entries
+func suggestedAccount(statement *BankStatement) *models.AccountRequest {
+	currency := statement.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+	accountType := statement.AccountType
+	if accountType == "" {
+		accountType = "checking"
+	}
+
+	number := statement.AccountNumber
+	if len(number) > 4 {
+		number = number[len(number)-4:]
+	}
+	name := statement.AccountName
+	if name == "" {
+		name = strings.ToUpper(accountType[:1]) + accountType[1:]
+		if number != "" {
+			name += " ending " + number
+		}
+	}
+
+	var openingBalance models.Money
+	if balance, err := models.ParseMoney(statement.OpeningBalance, currency); err == nil {
+		openingBalance = balance
+	} else if balance, err := models.ParseMoney(statement.LedgerBalance, currency); err == nil {
+		openingBalance = balance
+		for _, entry := range statement.Entries {
+			if amount, err := models.ParseMoney(entry.Amount, currency); err == nil {
+				openingBalance -= amount
+			}
+		}
+	}
+
+	return &models.AccountRequest{
+		Name:           name,
+		Type:           accountType,
+		Balance:        openingBalance.Float(currency),
+		Currency:       currency,
+		ExternalNumber: statement.AccountNumber,
 	}
+}
 
-	category := getValue("category")
-	if category == "" {
-		category = "Other"
+// statementRow converts a bank statement entry to an import row; money
+// leaving the account is an expense and money coming in is income
+func statementRow(userID uint, account *models.Account, entry *StatementEntry) (*importedRow, error) {
+	amount, err := models.ParseMoney(entry.Amount, account.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount: %s", entry.Amount)
+	}
+	if amount == 0 {
+		return nil, errors.New("amount is zero")
+	}
+	transType := "income"
+	if amount < 0 {
+		transType = "expense"
 	}
 
-	tags := getValue("tags")
+	description := entry.Name
+	if description == "" {
+		description = entry.Memo
+	}
 
-	return &models.Transaction{
+	transaction := &models.Transaction{
 		UserID:      userID,
-		Amount:      amount,
+		Amount:      amount.Abs(),
+		Currency:    account.Currency,
 		Description: description,
-		Category:    category,
 		Type:        transType,
-		Date:        date,
-		AccountID:   accountID,
-		Tags:        tags,
+		Date:        entry.Date,
+		AccountID:   account.ID,
+		ExternalID:  entry.ExternalID,
+		Status:      models.ResolveTransactionStatus("", entry.Date, time.Now()),
+	}
+	if transaction.Description == "" {
+		transaction.Description = "Imported transaction"
+	}
+
+	return &importedRow{
+		transaction: transaction,
+		tags:        entry.Tags,
+		payee:       entry.Payee,
+		description: description,
 	}, nil
 }
 
+// ImportQIF imports a parsed QIF file: the categories of its category list,
+// then the statements of its accounts
+func (s *ImportService) ImportQIF(userID uint, file *QIFFile, opts ImportOptions) ([]*StatementImportResult, error) {
+	for _, category := range file.Categories {
+		categoryType := models.CategoryTypeExpense
+		if category.Income {
+			categoryType = models.CategoryTypeIncome
+		}
+		if _, err := s.categoryRepo.FindOrCreatePath(userID, category.Path, categoryType); err != nil {
+			return nil, err
+		}
+	}
+	return s.ImportStatements(userID, file.Statements, opts)
+}
+
+// categorize sets the category or split lines of a statement entry on its
+// import row. Category paths map to categories and their subcategories,
+// which are created when missing; the transaction gets the name of the last
+// level. names caches the names of paths already resolved.
+func (s *ImportService) categorize(row *importedRow, entry *StatementEntry, names map[string]string) error {
+	transaction := row.transaction
+	categoryType := models.CategoryTypeIncome
+	if transaction.Type == "expense" {
+		categoryType = models.CategoryTypeExpense
+	}
+
+	categoryName := func(path string) (string, error) {
+		if path == "" || path == models.TransferCategory {
+			return path, nil
+		}
+		if name, ok := names[path]; ok {
+			return name, nil
+		}
+		category, err := s.categoryRepo.FindOrCreatePath(transaction.UserID, path, categoryType)
+		if err != nil {
+			return "", err
+		}
+		names[path] = category.Name
+		return category.Name, nil
+	}
+
+	if len(entry.Splits) == 0 {
+		name, err := categoryName(entry.Category)
+		transaction.Category = name
+		return err
+	}
+
+	var total models.Money
+	splits := make([]models.TransactionSplit, 0, len(entry.Splits))
+	for _, split := range entry.Splits {
+		amount, err := models.ParseMoney(split.Amount, transaction.Currency)
+		if err != nil {
+			return fmt.Errorf("invalid split amount: %s", split.Amount)
+		}
+		if amount == 0 || (amount < 0) != (transaction.Type == "expense") {
+			return errors.New("split lines must have the sign of the transaction")
+		}
+		name, err := categoryName(split.Category)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			name = "Other"
+		}
+		total += amount.Abs()
+		splits = append(splits, models.TransactionSplit{
+			UserID:      transaction.UserID,
+			Amount:      amount.Abs(),
+			Category:    name,
+			Description: split.Memo,
+		})
+	}
+	if total != transaction.Amount {
+		return models.ErrSplitSumMismatch
+	}
+
+	// A single line is just the category of the transaction
+	if len(splits) == 1 {
+		transaction.Category = splits[0].Category
+		return nil
+	}
+	transaction.Category = models.SplitCategory
+	transaction.Splits = splits
+	return nil
+}
+
+// importInvestments records the trades and distributions of a statement
+// through the investment service, skipping ones recorded before. Cash that
+// comes from or goes to another account is recorded as a transfer in or out
+// of the investment account around the trade.
+func (s *ImportService) importInvestments(userID uint, account *models.Account, statement *BankStatement, result *StatementImportResult) error {
+	if s.investments == nil {
+		return errors.New("investment import is not available")
+	}
+	securities, err := s.investments.GetSecurities(userID)
+	if err != nil {
+		return err
+	}
+	existing, err := s.investments.GetTransactions(userID, &account.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range statement.Investments {
+		entry := &statement.Investments[i]
+		result.TotalRows++
+		rowError := func(err error) {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: %s", result.TotalRows, err.Error()))
+			result.Skipped++
+		}
+
+		security, err := s.investmentSecurity(userID, account, entry, &securities)
+		if err != nil {
+			rowError(err)
+			continue
+		}
+		reqs := investmentRequests(account.ID, security.ID, entry)
+		if investmentRecorded(existing, security.ID, reqs[0]) {
+			result.Existing++
+			result.Skipped++
+			continue
+		}
+
+		cash := models.NewMoney(entry.Amount, account.Currency).Abs()
+		if entry.Transfer && entry.Action == models.InvestmentTypeBuy {
+			if cash == 0 {
+				cash = models.NewMoney(entry.Quantity*entry.Price+entry.Fees, account.Currency)
+			}
+			if err := s.saveTransfer(account, entry, "income", cash); err != nil {
+				rowError(err)
+				continue
+			}
+		}
+
+		var recordErr error
+		for _, req := range reqs {
+			recorded, err := s.investments.RecordTransaction(userID, req)
+			if err != nil {
+				recordErr = err
+				break
+			}
+			result.Investments = append(result.Investments, recorded.ID)
+		}
+		if recordErr != nil {
+			rowError(recordErr)
+			continue
+		}
+
+		if entry.Transfer && entry.Action != models.InvestmentTypeBuy && cash != 0 {
+			if err := s.saveTransfer(account, entry, "expense", cash); err != nil {
+				rowError(err)
+				continue
+			}
+		}
+		result.Imported++
+	}
+	return nil
+}
+
+// investmentSecurity returns the security of an investment entry, matched
+// by symbol or name, creating it in the account currency when missing
+func (s *ImportService) investmentSecurity(userID uint, account *models.Account, entry *InvestmentEntry, securities *[]models.Security) (*models.Security, error) {
+	symbol := strings.ToUpper(entry.Symbol)
+	if symbol == "" {
+		symbol = securitySymbol(entry.Security)
+	}
+	for i := range *securities {
+		security := &(*securities)[i]
+		if security.Symbol == symbol || strings.EqualFold(security.Name, entry.Security) {
+			return security, nil
+		}
+	}
+	if symbol == "" {
+		return nil, errors.New("security has no name or symbol")
+	}
+
+	security, err := s.investments.CreateSecurity(userID, &models.SecurityRequest{
+		Symbol:   symbol,
+		Name:     entry.Security,
+		Currency: account.Currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	*securities = append(*securities, *security)
+	return security, nil
+}
+
+// saveTransfer records cash moving between an investment account and
+// another account for a trade as a transfer transaction of the account
+func (s *ImportService) saveTransfer(account *models.Account, entry *InvestmentEntry, transType string, amount models.Money) error {
+	transaction := &models.Transaction{
+		UserID:      account.UserID,
+		Amount:      amount,
+		Currency:    account.Currency,
+		Description: fmt.Sprintf("Transfer for %s of %s", entry.Action, entry.Security),
+		Category:    models.TransferCategory,
+		Type:        transType,
+		Date:        entry.Date,
+		AccountID:   account.ID,
+		Status:      models.ResolveTransactionStatus("", entry.Date, time.Now()),
+	}
+	return s.saveTransaction(&importedRow{transaction: transaction})
+}
+
+// investmentRequests converts an investment entry to the requests recording
+// it; a reinvested distribution is a dividend followed by a buy
+func investmentRequests(accountID, securityID uint, entry *InvestmentEntry) []*models.InvestmentTransactionRequest {
+	req := func(investmentType string) *models.InvestmentTransactionRequest {
+		return &models.InvestmentTransactionRequest{
+			AccountID:  accountID,
+			SecurityID: securityID,
+			Type:       investmentType,
+			Date:       entry.Date,
+			Notes:      entry.Memo,
+		}
+	}
+
+	// Files often give the total of a trade rather than the price
+	price := entry.Price
+	if price == 0 && entry.Quantity != 0 {
+		switch entry.Action {
+		case models.InvestmentTypeBuy:
+			price = (entry.Amount - entry.Fees) / entry.Quantity
+		case models.InvestmentTypeSell:
+			price = (entry.Amount + entry.Fees) / entry.Quantity
+		case investmentActionReinvest:
+			price = entry.Amount / entry.Quantity
+		}
+	}
+
+	switch entry.Action {
+	case models.InvestmentTypeBuy, models.InvestmentTypeSell:
+		trade := req(entry.Action)
+		trade.Quantity = entry.Quantity
+		trade.Price = price
+		trade.Fees = entry.Fees
+		return []*models.InvestmentTransactionRequest{trade}
+	case investmentActionReinvest:
+		dividend := req(models.InvestmentTypeDividend)
+		dividend.Amount = entry.Amount
+		buy := req(models.InvestmentTypeBuy)
+		buy.Quantity = entry.Quantity
+		buy.Price = price
+		return []*models.InvestmentTransactionRequest{dividend, buy}
+	case models.InvestmentTypeSplit:
+		split := req(models.InvestmentTypeSplit)
+		split.SplitRatio = entry.SplitRatio
+		return []*models.InvestmentTransactionRequest{split}
+	default:
+		dividend := req(models.InvestmentTypeDividend)
+		dividend.Amount = entry.Amount
+		return []*models.InvestmentTransactionRequest{dividend}
+	}
+}
+
+// investmentRecorded reports whether an investment transaction like req
+// was recorded before on the same day
+func investmentRecorded(existing []models.InvestmentTransaction, securityID uint, req *models.InvestmentTransactionRequest) bool {
+	for _, t := range existing {
+		if t.SecurityID != securityID || t.Type != req.Type || t.Date.Format("2006-01-02") != req.Date.Format("2006-01-02") {
+			continue
+		}
+		switch req.Type {
+		case models.InvestmentTypeBuy, models.InvestmentTypeSell:
+			quantity := t.Quantity
+			if quantity < 0 {
+				quantity = -quantity
+			}
+			if quantity == models.NewShares(req.Quantity) {
+				return true
+			}
+		case models.InvestmentTypeDividend:
+			if t.Amount == models.NewMoney(req.Amount, t.Currency) {
+				return true
+			}
+		case models.InvestmentTypeSplit:
+			if t.SplitRatio == req.SplitRatio {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// securitySymbol derives a symbol from a security name: the name itself
+// when it looks like a ticker, else its letters and digits
+func securitySymbol(name string) string {
+	symbol := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return -1
+	}, name)
+	if len(symbol) > 20 {
+		symbol = symbol[:20]
+	}
+	return symbol
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// mt940Field is a tagged field of an MT940 message, e.g. :61: with its
+// continuation lines
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+var (
+	// mt940Tag matches the tag starting a field, e.g. :60F:
+	mt940Tag = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):`)
+
+	// mt940Line matches a statement line: value date, optional booking
+	// date, debit or credit mark, optional funds code, amount, transaction
+	// type, customer reference, bank reference and supplementary details
+	mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?([0-9]+,[0-9]*)([NFS][A-Z0-9]{3})?([^/\n]*)(?://([^\n]*))?(?:\n([\s\S]*))?$`)
+
+	// mt940Balance matches a balance: debit or credit mark, date, currency
+	// and amount
+	mt940Balance = regexp.MustCompile(`^(C|D)(\d{6})([A-Z]{3})([0-9]+,[0-9]*)`)
+
+	// mt940Subfield matches the ?NN subfields of structured German :86: info
+	mt940Subfield = regexp.MustCompile(`\?(\d{2})`)
+
+	// mt940SEPAKey matches the keys of the SEPA purpose in German :86: info
+	mt940SEPAKey = regexp.MustCompile(`(EREF|KREF|MREF|CRED|DEBT|COAM|OAMT|SVWZ|ABWA|ABWE)\+`)
+
+	// mt940Code matches the /CODE/ keys of :86: info structured by slashes
+	mt940Code = regexp.MustCompile(`/(TRTP|IBAN|BIC|NAME|REMI|EREF|MARF|CSID|ORDP|BENM|CDTRREFTP|CDTRREF|ISDT|RTRN|PREF|ULTC|ULTD|PURP|SVCL)/`)
+)
+
+// ParseMT940 parses the statements of a SWIFT MT940 file, with or without
+// the SWIFT message blocks around each statement
+func ParseMT940(data io.Reader) ([]BankStatement, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return nil, errors.New("failed to read MT940 file")
+	}
+	text := strings.ReplaceAll(strings.TrimPrefix(string(content), "\ufeff"), "\r\n", "\n")
+
+	var statements []BankStatement
+	var statement *BankStatement
+	var entry *StatementEntry
+
+	// finish adds the statement read so far to the statements
+	finish := func() {
+		if statement == nil {
+			return
+		}
+		if entry != nil {
+			statement.Entries = append(statement.Entries, *entry)
+			entry = nil
+		}
+
+		// Some banks put the currency after the account number
+		number := normalizeAccountNumber(statement.AccountNumber)
+		if statement.Currency != "" && len(number) > 3 && strings.HasSuffix(number, statement.Currency) {
+			number = number[:len(number)-3]
+		}
+		statement.AccountNumber = number
+		statements = append(statements, *statement)
+		statement = nil
+	}
+
+	for _, field := range mt940Fields(text) {
+		if field.tag != "20" && statement == nil {
+			continue
+		}
+		switch field.tag {
+		case "20":
+			finish()
+			statement = &BankStatement{Format: "MT940", AccountType: "checking"}
+		case "25":
+			statement.AccountNumber = field.value
+			if bank, account, ok := strings.Cut(field.value, "/"); ok {
+				statement.BankID, statement.AccountNumber = strings.TrimSpace(bank), account
+			}
+		case "60F", "60M":
+			if statement.OpeningBalance != "" {
+				continue
+			}
+			amount, date, currency, err := parseMT940Balance(field.value)
+			if err != nil {
+				return nil, err
+			}
+			statement.OpeningBalance, statement.OpeningDate, statement.Currency = amount, date, currency
+		case "62F", "62M":
+			amount, date, currency, err := parseMT940Balance(field.value)
+			if err != nil {
+				return nil, err
+			}
+			statement.LedgerBalance, statement.BalanceDate = amount, date
+			if statement.Currency == "" {
+				statement.Currency = currency
+			}
+		case "61":
+			if entry != nil {
+				statement.Entries = append(statement.Entries, *entry)
+			}
+			entry, err = parseMT940Line(field.value)
+			if err != nil {
+				return nil, err
+			}
+		case "86":
+			if entry != nil {
+				parseMT940Info(entry, field.value)
+				statement.Entries = append(statement.Entries, *entry)
+				entry = nil
+			}
+		}
+	}
+	finish()
+
+	if len(statements) == 0 {
+		return nil, errors.New("no statements found in MT940 file")
+	}
+	for _, statement := range statements {
+		if statement.AccountNumber == "" {
+			return nil, errors.New("MT940 statement has no account")
+		}
+	}
+	return statements, nil
+}
+
+// mt940Fields splits the text of MT940 messages into fields. Lines that do
+// not start a field continue the one before; block markers are dropped.
+func mt940Fields(text string) []mt940Field {
+	var fields []mt940Field
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t")
+
+		// Messages are wrapped as {1:...}{2:...}{4: and end with -}
+		if i := strings.Index(line, "{4:"); i >= 0 {
+			line = line[i+3:]
+		}
+		if line == "-}" || line == "-" || strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		if match := mt940Tag.FindStringSubmatch(line); match != nil {
+			fields = append(fields, mt940Field{tag: match[1], value: line[len(match[0]):]})
+			continue
+		}
+		if n := len(fields); n > 0 && line != "" {
+			fields[n-1].value += "\n" + line
+		}
+	}
+	return fields
+}
+
+// parseMT940Balance parses an opening or closing balance such as
+// C240131EUR1234,56 into a signed decimal, its date and currency
+func parseMT940Balance(value string) (string, time.Time, string, error) {
+	match := mt940Balance.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return "", time.Time{}, "", fmt.Errorf("invalid MT940 balance: %s", value)
+	}
+	date, err := parseMT940Date(match[2])
+	if err != nil {
+		return "", time.Time{}, "", err
+	}
+	amount := mt940Amount(match[4])
+	if match[1] == "D" {
+		amount = "-" + amount
+	}
+	return amount, date, match[3], nil
+}
+
+// parseMT940Line parses a :61: statement line into an entry. The entry is
+// dated when it was booked, or when the money is available if the line
+// gives no booking date.
+func parseMT940Line(value string) (*StatementEntry, error) {
+	match := mt940Line.FindStringSubmatch(value)
+	if match == nil {
+		return nil, fmt.Errorf("invalid MT940 statement line: %s", strings.SplitN(value, "\n", 2)[0])
+	}
+
+	date, err := parseMT940Date(match[1])
+	if err != nil {
+		return nil, err
+	}
+	if match[2] != "" {
+		booked, err := time.Parse("0102", match[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid MT940 date: %s", match[2])
+		}
+
+		// The booking date can be in the year before or after the value date
+		year := date.Year()
+		switch {
+		case booked.Month() == time.December && date.Month() == time.January:
+			year--
+		case booked.Month() == time.January && date.Month() == time.December:
+			year++
+		}
+		date = time.Date(year, booked.Month(), booked.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	// Reversals of credits take money out; reversals of debits put it back
+	amount := mt940Amount(match[5])
+	if match[3] == "D" || match[3] == "RC" {
+		amount = "-" + amount
+	}
+
+	entry := &StatementEntry{
+		Date:   date,
+		Amount: amount,
+		Name:   strings.TrimSpace(strings.ReplaceAll(match[9], "\n", " ")),
+	}
+	entry.ExternalID = strings.TrimSpace(match[8])
+	if reference := strings.TrimSpace(match[7]); entry.ExternalID == "" && reference != "NONREF" {
+		entry.ExternalID = reference
+	}
+	return entry, nil
+}
+
+// parseMT940Info reads the description and payee of an entry from its :86:
+// information, which banks structure with ?NN subfields, with /CODE/ keys
+// or not at all
+func parseMT940Info(entry *StatementEntry, value string) {
+	switch {
+	case mt940Subfield.MatchString(value):
+		var purpose, name strings.Builder
+		text := strings.ReplaceAll(value, "\n", "")
+		indexes := mt940Subfield.FindAllStringSubmatchIndex(text, -1)
+		for i, index := range indexes {
+			end := len(text)
+			if i+1 < len(indexes) {
+				end = indexes[i+1][0]
+			}
+			code, content := text[index[2]:index[3]], text[index[1]:end]
+			switch {
+			case code >= "20" && code <= "29", code >= "60" && code <= "63":
+				purpose.WriteString(content)
+			case code == "32" || code == "33":
+				name.WriteString(content)
+			}
+		}
+		entry.Payee = strings.TrimSpace(name.String())
+		description := purpose.String()
+		if keys := mt940Keyed(description, mt940SEPAKey); keys != nil {
+			description = firstNonEmpty(keys["SVWZ"], keys[""])
+		}
+		entry.Name = firstNonEmpty(strings.TrimSpace(description), entry.Name)
+	case mt940Code.MatchString(value):
+		keys := mt940Keyed(strings.ReplaceAll(value, "\n", ""), mt940Code)
+		remittance := strings.TrimPrefix(keys["REMI"], "USTD//")
+		entry.Name = firstNonEmpty(strings.Trim(remittance, "/ "), entry.Name)
+		entry.Payee = strings.Trim(keys["NAME"], "/ ")
+	default:
+		entry.Name = firstNonEmpty(strings.Join(strings.Fields(value), " "), entry.Name)
+	}
+}
+
+// mt940Keyed splits text into the values following each key matched by
+// pattern; text before the first key has the empty key. It returns nil
+// when no key matches.
+func mt940Keyed(text string, pattern *regexp.Regexp) map[string]string {
+	indexes := pattern.FindAllStringSubmatchIndex(text, -1)
+	if indexes == nil {
+		return nil
+	}
+	keys := map[string]string{"": strings.TrimSpace(text[:indexes[0][0]])}
+	for i, index := range indexes {
+		end := len(text)
+		if i+1 < len(indexes) {
+			end = indexes[i+1][0]
+		}
+		key := text[index[2]:index[3]]
+		if _, ok := keys[key]; !ok {
+			keys[key] = strings.TrimSpace(text[index[1]:end])
+		}
+	}
+	return keys
+}
+
+// mt940Amount converts an MT940 amount, which has a decimal comma, to a
+// decimal with a point
+func mt940Amount(value string) string {
+	value = strings.Replace(value, ",", ".", 1)
+	if strings.HasSuffix(value, ".") {
+		value += "0"
+	}
+	return value
+}
+
+// parseMT940Date parses a YYMMDD date; years are in the 2000s
+func parseMT940Date(value string) (time.Time, error) {
+	date, err := time.Parse("20060102", "20"+value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid MT940 date: %s", value)
+	}
+	return date, nil
+}
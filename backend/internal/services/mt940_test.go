@@ -0,0 +1,222 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// mt940German is a German bank statement in SWIFT message blocks with
+// ?NN subfields, /CODE/ keys and plain text in its :86: information
+const mt940German = `{1:F01COBADEFFAXXX0000000000}{2:O9400000000000COBADEFFXXXX00000000000000000000N}{4:
+:20:STARTUMSE
+:25:37040044/0532013000
+:28C:00001/001
+:60F:C231231EUR1000,00
+:61:2401020103DR12,50NMSCNONREF//BANKREF1
+:86:106?00KARTENZAHLUNG?20EREF+123?21SVWZ+Coffee Shop Berlin?2
+2 Card 1234?32COFFEE SHOP GMBH
+:61:240125C2000,NTRFNONREF
+:86:/TRTP/SEPA CREDIT TRANSFER/NAME/ACME AG/REMI/USTD//Salary January/EREF/NOTPROVIDED
+:61:2312290102D5,00NCHGNONREF
+:86:Account fee
+ December
+:62F:C240131EUR2982,50
+-}
+`
+
+// mt940Dutch holds two Dutch statements without SWIFT message blocks, one
+// with the currency after the IBAN
+const mt940Dutch = `:20:940S240201
+:25:NL91ABNA0417164300EUR
+:28C:1/1
+:60F:C240131EUR500,00
+:61:240201D25,00NTRFEREF1//SVC123
+Invoice payment
+:86:/EREF/EREF1/BENM//NAME/Energy Supplier BV/REMI/USTD//Invoice 2024-001/
+:61:240202C100,00NTRFNONREF
+Refund order 77
+:86:/TRTP/SEPA OVERBOEKING/NAME/Webshop BV/
+:62F:C240202EUR575,00
+-
+:20:940S240201
+:25:NL20INGB0001234567
+:28C:1/1
+:60M:D240131EUR10,00
+:61:240201RD10,00NMSC//REV1
+:86:Reversal
+:62M:C240201EUR0,00
+-
+`
+
+func TestParseMT940(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []BankStatement
+	}{
+		{
+			name: "subfields, codes and plain text",
+			data: mt940German,
+			expected: []BankStatement{{
+				Format:         "MT940",
+				BankID:         "37040044",
+				AccountNumber:  "0532013000",
+				AccountType:    "checking",
+				Currency:       "EUR",
+				LedgerBalance:  "2982.50",
+				BalanceDate:    time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+				OpeningBalance: "1000.00",
+				OpeningDate:    time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+				Entries: []StatementEntry{
+					{ExternalID: "BANKREF1", Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), Amount: "-12.50",
+						Name: "Coffee Shop Berlin Card 1234", Payee: "COFFEE SHOP GMBH"},
+					{Date: time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC), Amount: "2000.0", Name: "Salary January", Payee: "ACME AG"},
+					{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: "-5.00", Name: "Account fee December"},
+				},
+			}},
+		},
+		{
+			name: "codes with supplementary details and reversals",
+			data: mt940Dutch,
+			expected: []BankStatement{
+				{
+					Format:         "MT940",
+					AccountNumber:  "NL91ABNA0417164300",
+					AccountType:    "checking",
+					Currency:       "EUR",
+					LedgerBalance:  "575.00",
+					BalanceDate:    time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC),
+					OpeningBalance: "500.00",
+					OpeningDate:    time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+					Entries: []StatementEntry{
+						{ExternalID: "SVC123", Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Amount: "-25.00",
+							Name: "Invoice 2024-001", Payee: "Energy Supplier BV"},
+						{Date: time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC), Amount: "100.00", Name: "Refund order 77", Payee: "Webshop BV"},
+					},
+				},
+				{
+					Format:         "MT940",
+					AccountNumber:  "NL20INGB0001234567",
+					AccountType:    "checking",
+					Currency:       "EUR",
+					LedgerBalance:  "0.00",
+					BalanceDate:    time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+					OpeningBalance: "-10.00",
+					OpeningDate:    time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+					Entries: []StatementEntry{
+						{ExternalID: "REV1", Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Amount: "10.00", Name: "Reversal"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, err := ParseMT940(strings.NewReader(tt.data))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, statements)
+		})
+	}
+}
+
+func TestParseMT940Info(t *testing.T) {
+	tests := []struct {
+		name         string
+		info         string
+		expectedName string
+		payee        string
+	}{
+		{name: "subfields with SEPA purpose", info: "166?00SEPA-UEBERWEISUNG?20EREF+INV-42?21SVWZ+Invoice 42?32Jane Doe",
+			expectedName: "Invoice 42", payee: "Jane Doe"},
+		{name: "subfields without SEPA keys", info: "005?00LASTSCHRIFT?20Monthly fee?21 March?32BANK",
+			expectedName: "Monthly fee March", payee: "BANK"},
+		{name: "subfields without purpose", info: "835?00ENTGELT?32BANK", expectedName: "Card payment", payee: "BANK"},
+		{name: "codes", info: "/TRTP/SEPA CREDIT TRANSFER/BIC/ABNANL2A/NAME/John Roe/REMI/Rent March/",
+			expectedName: "Rent March", payee: "John Roe"},
+		{name: "plain text over lines", info: "Cash withdrawal\n ATM 0042", expectedName: "Cash withdrawal ATM 0042"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &StatementEntry{Name: "Card payment"}
+
+			parseMT940Info(entry, tt.info)
+
+			assert.Equal(t, tt.expectedName, entry.Name)
+			assert.Equal(t, tt.payee, entry.Payee)
+		})
+	}
+}
+
+func TestParseMT940_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "not MT940", data: "Date,Amount\n2024-01-15,-42.50\n", err: "no statements found in MT940 file"},
+		{name: "no account", data: ":20:X\n:60F:C240101EUR1,00\n", err: "MT940 statement has no account"},
+		{name: "invalid balance", data: ":20:X\n:25:123\n:60F:C2401EUR1,00\n", err: "invalid MT940 balance: C2401EUR1,00"},
+		{name: "invalid statement line", data: ":20:X\n:25:123\n:61:hello\n", err: "invalid MT940 statement line: hello"},
+		{name: "invalid date", data: ":20:X\n:25:123\n:61:241399C1,00NMSCNONREF\n", err: "invalid MT940 date: 241399"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMT940(strings.NewReader(tt.data))
+
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestImportStatements_MT940(t *testing.T) {
+	// Setup - an account for the second statement only
+	db, importService, _ := setupImportTest(t)
+	user := createImportTestUser(t, db, "mt940")
+	existing := &models.Account{UserID: user.ID, Name: "ING", Type: "checking", Currency: "EUR", ExternalNumber: "NL20 INGB 0001 2345 67"}
+	assert.NoError(t, db.Create(existing).Error)
+
+	statements, err := ParseMT940(strings.NewReader(mt940Dutch))
+	assert.NoError(t, err)
+
+	// Execute
+	results, err := importService.ImportStatements(user.ID, statements, ImportOptions{CreateAccounts: true})
+
+	// Assert - the first statement opens a new account at its opening balance
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.True(t, results[0].AccountCreated)
+	assert.Equal(t, 2, results[0].Imported)
+	assert.Equal(t, 0.0, *results[0].BalanceDifference)
+
+	var created models.Account
+	db.First(&created, *results[0].AccountID)
+	assert.Equal(t, "NL91ABNA0417164300", created.ExternalNumber)
+	assert.Equal(t, "EUR", created.Currency)
+	assert.Equal(t, models.NewMoney(575.0, "EUR"), created.Balance)
+
+	// The second goes into the account with its IBAN
+	assert.False(t, results[1].AccountCreated)
+	assert.Equal(t, existing.ID, *results[1].AccountID)
+	assert.Equal(t, 1, results[1].Imported)
+
+	var checkpoints []models.BalanceHistory
+	db.Where("account_id = ? AND change_type = ?", existing.ID, models.BalanceChangeCheckpoint).Order("recorded_at").Find(&checkpoints)
+	assert.Len(t, checkpoints, 2)
+	assert.Equal(t, models.NewMoney(-10.0, "EUR"), checkpoints[0].Balance)
+	assert.Equal(t, models.Money(0), checkpoints[1].Balance)
+
+	// Entries with a bank reference are skipped when imported again
+	results, err = importService.ImportStatements(user.ID, statements, ImportOptions{CreateAccounts: true})
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, *results[0].AccountID)
+	assert.Equal(t, 1, results[0].Existing)
+	assert.Equal(t, 1, results[1].Existing)
+}
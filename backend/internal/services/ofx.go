@@ -0,0 +1,254 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ofxNode is an element of an OFX document: a leaf when it has a value,
+// otherwise an aggregate of child elements
+type ofxNode struct {
+	name     string
+	value    string
+	children []*ofxNode
+}
+
+// ofxAccountTypes maps OFX account types to account types
+var ofxAccountTypes = map[string]string{
+	"CHECKING":   "checking",
+	"SAVINGS":    "savings",
+	"MONEYMRKT":  "savings",
+	"CD":         "savings",
+	"CREDITLINE": "credit",
+}
+
+// ofxUnescaper replaces the character entities allowed in OFX values
+var ofxUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ", "&amp;", "&")
+
+// ParseOFX parses the bank and credit card statements of an OFX or QFX
+// file, either OFX 1.x SGML, which leaves leaf elements unclosed, or OFX
+// 2.x XML
+func ParseOFX(data io.Reader) ([]BankStatement, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return nil, errors.New("failed to read OFX file")
+	}
+
+	// Skip the SGML header or XML declaration before the document
+	text := string(content)
+	start := strings.Index(text, "<OFX>")
+	if start < 0 {
+		return nil, errors.New("not an OFX file")
+	}
+	root, err := parseOFXElements(text[start:])
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []BankStatement
+	for _, name := range []string{"STMTRS", "CCSTMTRS"} {
+		for _, node := range root.findAll(name) {
+			statement, err := parseOFXStatement(node)
+			if err != nil {
+				return nil, err
+			}
+			statements = append(statements, *statement)
+		}
+	}
+	if len(statements) == 0 {
+		return nil, errors.New("no statements found in OFX file")
+	}
+	return statements, nil
+}
+
+// parseOFXElements builds the element tree of an OFX document. Text up to
+// the next tag is the value of an element; elements without a value are
+// aggregates and stay open until their closing tag, which also closes any
+// SGML leaf elements inside them.
+func parseOFXElements(text string) (*ofxNode, error) {
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+
+	for {
+		open := strings.IndexByte(text, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(text[open:], '>')
+		if end < 0 {
+			return nil, errors.New("malformed OFX tag")
+		}
+		tag := strings.TrimSpace(text[open+1 : open+end])
+		text = text[open+end+1:]
+
+		next := strings.IndexByte(text, '<')
+		if next < 0 {
+			next = len(text)
+		}
+		value := ofxUnescaper.Replace(strings.TrimSpace(text[:next]))
+		text = text[next:]
+
+		switch {
+		case tag == "" || tag[0] == '?' || tag[0] == '!':
+			// Processing instructions and comments
+		case tag[0] == '/':
+			// Closing tags of XML leaf elements match no open aggregate
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		default:
+			selfClosing := strings.HasSuffix(tag, "/")
+			name := strings.ToUpper(strings.Fields(strings.TrimSuffix(tag, "/"))[0])
+			node := &ofxNode{name: name, value: value}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
+			if value == "" && !selfClosing {
+				stack = append(stack, node)
+			}
+		}
+	}
+
+	return root, nil
+}
+
+// parseOFXStatement converts a STMTRS or CCSTMTRS aggregate to a statement
+func parseOFXStatement(node *ofxNode) (*BankStatement, error) {
+	statement := &BankStatement{
+		Format:   "OFX",
+		Currency: strings.ToUpper(node.field("CURDEF")),
+	}
+
+	if account := node.find("BANKACCTFROM"); account != nil {
+		statement.BankID = account.field("BANKID")
+		statement.AccountNumber = account.field("ACCTID")
+		statement.AccountType = ofxAccountTypes[strings.ToUpper(account.field("ACCTTYPE"))]
+	} else if account := node.find("CCACCTFROM"); account != nil {
+		statement.AccountNumber = account.field("ACCTID")
+		statement.AccountType = "credit"
+	}
+	if statement.AccountNumber == "" {
+		return nil, errors.New("OFX statement has no account number")
+	}
+
+	if ledger := node.find("LEDGERBAL"); ledger != nil {
+		statement.LedgerBalance = ofxAmount(ledger.field("BALAMT"))
+		if asOf := ledger.field("DTASOF"); asOf != "" {
+			date, err := parseOFXDate(asOf)
+			if err != nil {
+				return nil, err
+			}
+			statement.BalanceDate = date
+		}
+	}
+
+	for _, trn := range node.findAll("STMTTRN") {
+		date, err := parseOFXDate(trn.field("DTPOSTED"))
+		if err != nil {
+			return nil, err
+		}
+		statement.Entries = append(statement.Entries, StatementEntry{
+			ExternalID:  trn.field("FITID"),
+			Date:        date,
+			Amount:      ofxAmount(trn.field("TRNAMT")),
+			Name:        trn.field("NAME"),
+			Memo:        trn.field("MEMO"),
+			CheckNumber: trn.field("CHECKNUM"),
+		})
+	}
+
+	return statement, nil
+}
+
+// find returns the first descendant element with the given name, or nil
+func (n *ofxNode) find(name string) *ofxNode {
+	for _, child := range n.children {
+		if child.name == name {
+			return child
+		}
+		if found := child.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns the descendant elements with the given name, not looking
+// inside the ones found
+func (n *ofxNode) findAll(name string) []*ofxNode {
+	var found []*ofxNode
+	for _, child := range n.children {
+		if child.name == name {
+			found = append(found, child)
+			continue
+		}
+		found = append(found, child.findAll(name)...)
+	}
+	return found
+}
+
+// field returns the value of the first descendant element with the given name
+func (n *ofxNode) field(name string) string {
+	if found := n.find(name); found != nil {
+		return found.value
+	}
+	return ""
+}
+
+// ofxAmount normalizes an OFX amount to a decimal with a point, as some
+// banks write amounts with a decimal comma
+func ofxAmount(value string) string {
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	if !strings.Contains(value, ".") {
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	return value
+}
+
+// parseOFXDate parses an OFX date such as 20240115, 20240115120000 or
+// 20240115120000.000[-5:EST]; dates without a time zone are UTC
+func parseOFXDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	raw := value
+
+	offset := 0
+	if i := strings.IndexByte(value, '['); i >= 0 {
+		zone, _, _ := strings.Cut(strings.TrimSuffix(value[i+1:], "]"), ":")
+		hours, err := strconv.ParseFloat(zone, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid OFX date: %s", raw)
+		}
+		offset = int(hours * 3600)
+		value = value[:i]
+	}
+	value, _, _ = strings.Cut(value, ".")
+
+	var layout string
+	switch len(value) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("invalid OFX date: %s", raw)
+	}
+
+	location := time.UTC
+	if offset != 0 {
+		location = time.FixedZone("", offset)
+	}
+	date, err := time.ParseInLocation(layout, value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid OFX date: %s", raw)
+	}
+	return date, nil
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// ofxSGML is an OFX 1.x bank statement as US banks send it: an SGML header
+// and leaf elements without closing tags
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20240301083000<LANGUAGE>ENG</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<TRNUID>1
+<STATUS><CODE>0<SEVERITY>INFO</STATUS>
+<STMTRS>
+<CURDEF>USD
+<BANKACCTFROM>
+<BANKID>121000248
+<ACCTID>0001234567
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240101
+<DTEND>20240229
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240115120000.000[-5:EST]
+<TRNAMT>-42.50
+<FITID>202401151
+<NAME>STARBUCKS #1234
+<MEMO>Coffee &amp; cake
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CHECK
+<DTPOSTED>20240118
+<TRNAMT>-250.00
+<FITID>202401182
+<CHECKNUM>1042
+<NAME>CHECK 1042
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240131
+<TRNAMT>1500,00
+<FITID>202401313
+<NAME>ACME PAYROLL
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL>
+<BALAMT>2457.50
+<DTASOF>20240301
+</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+// ofxXML is the statement of ofxSGML as an OFX 2.x XML document
+const ofxXML = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <SIGNONMSGSRSV1>
+    <SONRS>
+      <STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+      <DTSERVER>20240301083000</DTSERVER>
+      <LANGUAGE>ENG</LANGUAGE>
+    </SONRS>
+  </SIGNONMSGSRSV1>
+  <BANKMSGSRSV1>
+    <STMTTRNRS>
+      <TRNUID>1</TRNUID>
+      <STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+      <STMTRS>
+        <CURDEF>USD</CURDEF>
+        <BANKACCTFROM>
+          <BANKID>121000248</BANKID>
+          <ACCTID>0001234567</ACCTID>
+          <ACCTTYPE>CHECKING</ACCTTYPE>
+        </BANKACCTFROM>
+        <BANKTRANLIST>
+          <DTSTART>20240101</DTSTART>
+          <DTEND>20240229</DTEND>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240115120000.000[-5:EST]</DTPOSTED>
+            <TRNAMT>-42.50</TRNAMT>
+            <FITID>202401151</FITID>
+            <NAME>STARBUCKS #1234</NAME>
+            <MEMO>Coffee &amp; cake</MEMO>
+          </STMTTRN>
+          <STMTTRN>
+            <TRNTYPE>CHECK</TRNTYPE>
+            <DTPOSTED>20240118</DTPOSTED>
+            <TRNAMT>-250.00</TRNAMT>
+            <FITID>202401182</FITID>
+            <CHECKNUM>1042</CHECKNUM>
+            <NAME>CHECK 1042</NAME>
+          </STMTTRN>
+          <STMTTRN>
+            <TRNTYPE>CREDIT</TRNTYPE>
+            <DTPOSTED>20240131</DTPOSTED>
+            <TRNAMT>1500,00</TRNAMT>
+            <FITID>202401313</FITID>
+            <NAME>ACME PAYROLL</NAME>
+          </STMTTRN>
+        </BANKTRANLIST>
+        <LEDGERBAL>
+          <BALAMT>2457.50</BALAMT>
+          <DTASOF>20240301</DTASOF>
+        </LEDGERBAL>
+      </STMTRS>
+    </STMTTRNRS>
+  </BANKMSGSRSV1>
+</OFX>
+`
+
+// ofxCreditCard is a QFX download with a credit card statement
+const ofxCreditCard = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<CREDITCARDMSGSRSV1><CCSTMTTRNRS><TRNUID>0
+<CCSTMTRS><CURDEF>usd
+<CCACCTFROM><ACCTID>4111111111111111</CCACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>202402031530<TRNAMT>-1 234.56<FITID>CC1<NAME>AIRLINE TICKETS</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240210<TRNAMT>500.00<FITID>CC2<NAME>PAYMENT - THANK YOU</STMTTRN>
+</BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	checking := BankStatement{
+		Format:        "OFX",
+		BankID:        "121000248",
+		AccountNumber: "0001234567",
+		AccountType:   "checking",
+		Currency:      "USD",
+		LedgerBalance: "2457.50",
+		BalanceDate:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Entries: []StatementEntry{
+			{ExternalID: "202401151", Date: time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC), Amount: "-42.50",
+				Name: "STARBUCKS #1234", Memo: "Coffee & cake"},
+			{ExternalID: "202401182", Date: time.Date(2024, 1, 18, 0, 0, 0, 0, time.UTC), Amount: "-250.00",
+				Name: "CHECK 1042", CheckNumber: "1042"},
+			{ExternalID: "202401313", Date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), Amount: "1500.00",
+				Name: "ACME PAYROLL"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []BankStatement
+	}{
+		{name: "SGML bank statement", data: ofxSGML, expected: []BankStatement{checking}},
+		{name: "XML bank statement", data: ofxXML, expected: []BankStatement{checking}},
+		{name: "SGML credit card statement", data: ofxCreditCard, expected: []BankStatement{{
+			Format:        "OFX",
+			AccountNumber: "4111111111111111",
+			AccountType:   "credit",
+			Currency:      "USD",
+			Entries: []StatementEntry{
+				{ExternalID: "CC1", Date: time.Date(2024, 2, 3, 15, 30, 0, 0, time.UTC), Amount: "-1234.56", Name: "AIRLINE TICKETS"},
+				{ExternalID: "CC2", Date: time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), Amount: "500.00", Name: "PAYMENT - THANK YOU"},
+			},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, err := ParseOFX(strings.NewReader(tt.data))
+
+			assert.NoError(t, err)
+			for i := range statements {
+				for j := range statements[i].Entries {
+					statements[i].Entries[j].Date = statements[i].Entries[j].Date.UTC()
+				}
+			}
+			assert.Equal(t, tt.expected, statements)
+		})
+	}
+}
+
+func TestParseOFX_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "not OFX", data: "Date,Amount\n2024-01-15,-42.50\n", err: "not an OFX file"},
+		{name: "no statements", data: "<OFX><SIGNONMSGSRSV1><SONRS><DTSERVER>20240301</SONRS></SIGNONMSGSRSV1></OFX>",
+			err: "no statements found in OFX file"},
+		{name: "no account", data: "<OFX><STMTRS><CURDEF>USD<BANKTRANLIST></BANKTRANLIST></STMTRS></OFX>",
+			err: "OFX statement has no account number"},
+		{name: "bad date", data: "<OFX><STMTRS><BANKACCTFROM><ACCTID>1</BANKACCTFROM>" +
+			"<STMTTRN><DTPOSTED>2024-01-15<TRNAMT>-1.00</STMTTRN></STMTRS></OFX>", err: "invalid OFX date: 2024-01-15"},
+		{name: "unclosed tag", data: "<OFX><STMTRS", err: "malformed OFX tag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseOFX(strings.NewReader(tt.data))
+
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestParseOFXDate(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{value: "20240115", expected: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "202401151230", expected: time.Date(2024, 1, 15, 12, 30, 0, 0, time.UTC)},
+		{value: "20240115123045", expected: time.Date(2024, 1, 15, 12, 30, 45, 0, time.UTC)},
+		{value: "20240115123045.123", expected: time.Date(2024, 1, 15, 12, 30, 45, 0, time.UTC)},
+		{value: "20240115120000.000[-5:EST]", expected: time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC)},
+		{value: "20240115120000[+5.5:IST]", expected: time.Date(2024, 1, 15, 6, 30, 0, 0, time.UTC)},
+		{value: "20240115120000[0:GMT]", expected: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			date, err := parseOFXDate(tt.value)
+
+			assert.NoError(t, err)
+			assert.True(t, tt.expected.Equal(date), "got %s", date)
+		})
+	}
+
+	for _, value := range []string{"", "2024011", "20241315", "20240115[EST]"} {
+		_, err := parseOFXDate(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestImportStatements_OFX(t *testing.T) {
+	// Setup
+	db, importService, _ := setupImportTest(t)
+	user := createImportTestUser(t, db, "ofx")
+
+	statements, err := ParseOFX(strings.NewReader(ofxSGML))
+	assert.NoError(t, err)
+
+	// A statement matching no account is left out with an account to create
+	results, err := importService.ImportStatements(user.ID, statements, ImportOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, results[0].AccountID)
+	assert.Equal(t, 3, results[0].Skipped)
+	assert.Equal(t, &models.AccountRequest{
+		Name: "Checking ending 4567", Type: "checking", Balance: 1250.0, Currency: "USD", ExternalNumber: "0001234567",
+	}, results[0].SuggestedAccount)
+
+	// Execute - import it into a new account
+	results, err = importService.ImportStatements(user.ID, statements, ImportOptions{CreateAccounts: true})
+
+	// Assert - the account ends at the balance the bank reports
+	assert.NoError(t, err)
+	result := results[0]
+	assert.True(t, result.AccountCreated)
+	assert.Equal(t, 3, result.Imported)
+	assert.Equal(t, 0.0, *result.BalanceDifference)
+
+	var account models.Account
+	db.First(&account, *result.AccountID)
+	assert.Equal(t, "0001234567", account.ExternalNumber)
+	assert.Equal(t, models.NewMoney(2457.50, "USD"), account.Balance)
+
+	var checkpoints []models.BalanceHistory
+	db.Where("account_id = ? AND change_type = ?", account.ID, models.BalanceChangeCheckpoint).Find(&checkpoints)
+	assert.Len(t, checkpoints, 1)
+	assert.Equal(t, models.NewMoney(2457.50, "USD"), checkpoints[0].Balance)
+	assert.True(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC).Equal(checkpoints[0].RecordedAt))
+
+	// The same statement as OFX 2.x goes into the account by its number and
+	// every FITID is skipped as imported before
+	statements, err = ParseOFX(strings.NewReader(ofxXML))
+	assert.NoError(t, err)
+	results, err = importService.ImportStatements(user.ID, statements, ImportOptions{CreateAccounts: true})
+	assert.NoError(t, err)
+	assert.False(t, results[0].AccountCreated)
+	assert.Equal(t, account.ID, *results[0].AccountID)
+	assert.Equal(t, 0, results[0].Imported)
+	assert.Equal(t, 3, results[0].Existing)
+	assert.Empty(t, results[0].Duplicates)
+
+	var count int64
+	db.Model(&models.Transaction{}).Where("account_id = ?", account.ID).Count(&count)
+	assert.Equal(t, int64(3), count)
+	db.Model(&models.BalanceHistory{}).Where("account_id = ? AND change_type = ?", account.ID, models.BalanceChangeCheckpoint).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,674 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+)
+
+// QIFFile is the content of a QIF file: the statements of its accounts and
+// its category list
+type QIFFile struct {
+	Statements []BankStatement
+	Categories []QIFCategory
+}
+
+// QIFCategory is an entry of the category list of a QIF file
+type QIFCategory struct {
+	Path   string // Category names separated by colons, e.g. Food:Coffee
+	Income bool
+}
+
+// qifAccountTypes maps QIF account types to account types
+var qifAccountTypes = map[string]string{
+	"bank":  "checking",
+	"cash":  "cash",
+	"ccard": "credit",
+	"invst": models.AccountTypeInvestment,
+	"oth a": "other",
+	"oth l": models.AccountTypeLoan,
+}
+
+// qifInvestmentActions maps QIF investment actions on a security to
+// investment entry actions; actions ending in X move the cash to or from
+// another account
+var qifInvestmentActions = map[string]string{
+	"buy":      models.InvestmentTypeBuy,
+	"buyx":     models.InvestmentTypeBuy,
+	"sell":     models.InvestmentTypeSell,
+	"sellx":    models.InvestmentTypeSell,
+	"div":      models.InvestmentTypeDividend,
+	"divx":     models.InvestmentTypeDividend,
+	"cglong":   models.InvestmentTypeDividend,
+	"cglongx":  models.InvestmentTypeDividend,
+	"cgshort":  models.InvestmentTypeDividend,
+	"cgshortx": models.InvestmentTypeDividend,
+	"reinvdiv": investmentActionReinvest,
+	"reinvint": investmentActionReinvest,
+	"reinvlg":  investmentActionReinvest,
+	"reinvsh":  investmentActionReinvest,
+	"stksplit": models.InvestmentTypeSplit,
+}
+
+// qifCashActions maps the QIF investment actions that move cash without a
+// security to the sign of the amount; zero keeps the sign as written
+var qifCashActions = map[string]int{
+	"xin":     1,
+	"xout":    -1,
+	"intinc":  1,
+	"miscinc": 1,
+	"miscexp": -1,
+	"cash":    0,
+}
+
+// investmentActionReinvest is a distribution spent on shares of the security
+const investmentActionReinvest = "reinvest"
+
+// ParseQIF parses a QIF file with bank, cash, credit card, other and
+// investment sections. Sections follow the !Account record of their
+// account; without one each section type is a statement of its own. Dates
+// are read month first unless dayFirst is set.
+func ParseQIF(data io.Reader, dayFirst bool) (*QIFFile, error) {
+	file := &QIFFile{}
+	var statements []*BankStatement
+	securities := make(map[string]string)
+
+	var section string
+	var current *BankStatement
+	var record []string
+	lineNumber := 0
+
+	// statementFor returns the statement of the account a section belongs to
+	statementFor := func(accountType string) *BankStatement {
+		if current == nil || (current.AccountName == "" && current.AccountType != accountType) {
+			current = &BankStatement{Format: "QIF", AccountType: accountType}
+			statements = append(statements, current)
+		}
+		if current.AccountType == "" {
+			current.AccountType = accountType
+		}
+		return current
+	}
+
+	endRecord := func() error {
+		if len(record) == 0 {
+			return nil
+		}
+		fields := record
+		record = nil
+
+		switch section {
+		case "account":
+			name, accountType := qifField(fields, 'N'), qifAccountTypes[strings.ToLower(qifField(fields, 'T'))]
+			current = nil
+			for _, statement := range statements {
+				if statement.AccountName == name {
+					current = statement
+				}
+			}
+			if current == nil {
+				current = &BankStatement{Format: "QIF", AccountName: name, AccountType: accountType}
+				statements = append(statements, current)
+			}
+		case "cat":
+			file.Categories = append(file.Categories, QIFCategory{
+				Path:   qifField(fields, 'N'),
+				Income: qifHasField(fields, 'I'),
+			})
+		case "security":
+			if name := qifField(fields, 'N'); name != "" {
+				securities[name] = qifField(fields, 'S')
+			}
+		case "bank", "cash", "ccard", "oth a", "oth l":
+			entry, err := parseQIFEntry(fields, dayFirst)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			statement := statementFor(qifAccountTypes[section])
+			statement.Entries = append(statement.Entries, *entry)
+		case "invst":
+			entry, investment, err := parseQIFInvestment(fields, dayFirst)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			statement := statementFor(models.AccountTypeInvestment)
+			if entry != nil {
+				statement.Entries = append(statement.Entries, *entry)
+			}
+			if investment != nil {
+				statement.Investments = append(statement.Investments, *investment)
+			}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(data)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), " \t\r")
+		switch {
+		case line == "":
+		case line[0] == '!':
+			if err := endRecord(); err != nil {
+				return nil, err
+			}
+			header := strings.ToLower(strings.TrimSpace(line[1:]))
+			switch {
+			case header == "account":
+				section = "account"
+			case strings.HasPrefix(header, "type:"):
+				section = strings.TrimSpace(strings.TrimPrefix(header, "type:"))
+			}
+			// Options such as !Option:AutoSwitch do not start a section
+		case line[0] == '^':
+			if err := endRecord(); err != nil {
+				return nil, err
+			}
+		default:
+			record = append(record, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("failed to read QIF file")
+	}
+	if err := endRecord(); err != nil {
+		return nil, err
+	}
+
+	// Account lists name accounts without entries; leave them out
+	for _, statement := range statements {
+		if len(statement.Entries) == 0 && len(statement.Investments) == 0 {
+			continue
+		}
+		for i := range statement.Investments {
+			statement.Investments[i].Symbol = securities[statement.Investments[i].Security]
+		}
+		file.Statements = append(file.Statements, *statement)
+	}
+	if len(file.Statements) == 0 && len(file.Categories) == 0 {
+		return nil, errors.New("no transactions found in QIF file")
+	}
+	return file, nil
+}
+
+// parseQIFEntry converts the fields of a bank, cash, credit card or other
+// account record to a statement entry
+func parseQIFEntry(fields []string, dayFirst bool) (*StatementEntry, error) {
+	entry := &StatementEntry{}
+	var date string
+	for _, field := range fields {
+		value := strings.TrimSpace(field[1:])
+		switch field[0] {
+		case 'D':
+			date = value
+		case 'T', 'U':
+			if entry.Amount == "" {
+				entry.Amount = qifAmount(value)
+			}
+		case 'P':
+			entry.Payee = value
+		case 'M':
+			entry.Memo = value
+		case 'N':
+			entry.CheckNumber = value
+		case 'L':
+			entry.Category, entry.Tags = qifCategory(value)
+		case 'S':
+			category, _ := qifCategory(value)
+			entry.Splits = append(entry.Splits, StatementSplit{Category: category})
+		case 'E':
+			if n := len(entry.Splits); n > 0 {
+				entry.Splits[n-1].Memo = value
+			}
+		case '$':
+			if n := len(entry.Splits); n > 0 {
+				entry.Splits[n-1].Amount = qifAmount(value)
+			}
+		}
+	}
+
+	parsed, err := parseQIFDate(date, dayFirst)
+	if err != nil {
+		return nil, err
+	}
+	entry.Date = parsed
+	if entry.Amount == "" {
+		return nil, errors.New("missing amount")
+	}
+
+	// The memo describes the entry best; the payee names who it was with
+	entry.Name = entry.Memo
+	if entry.Name == "" {
+		entry.Name = entry.Payee
+	}
+	return entry, nil
+}
+
+// parseQIFInvestment converts the fields of an investment account record
+// to an investment entry, or to a statement entry when it only moves cash
+func parseQIFInvestment(fields []string, dayFirst bool) (*StatementEntry, *InvestmentEntry, error) {
+	parsed, err := parseQIFDate(qifField(fields, 'D'), dayFirst)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	action := strings.ToLower(qifField(fields, 'N'))
+	amountField := qifField(fields, 'T')
+	if amountField == "" {
+		amountField = qifField(fields, 'U')
+	}
+
+	if sign, ok := qifCashActions[action]; ok {
+		amount := qifAmount(amountField)
+		if amount == "" {
+			return nil, nil, errors.New("missing amount")
+		}
+		if sign != 0 {
+			amount = strings.TrimLeft(amount, "+-")
+			if sign < 0 {
+				amount = "-" + amount
+			}
+		}
+		entry := &StatementEntry{
+			Date:   parsed,
+			Amount: amount,
+			Payee:  qifField(fields, 'P'),
+			Memo:   qifField(fields, 'M'),
+		}
+		entry.Category, entry.Tags = qifCategory(qifField(fields, 'L'))
+		if entry.Category == "" && (action == "xin" || action == "xout") {
+			entry.Category = models.TransferCategory
+		}
+		entry.Name = entry.Memo
+		if entry.Name == "" {
+			entry.Name = entry.Payee
+		}
+		return entry, nil, nil
+	}
+
+	mapped, ok := qifInvestmentActions[action]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported investment action: %s", qifField(fields, 'N'))
+	}
+
+	investment := &InvestmentEntry{
+		Date:     parsed,
+		Action:   mapped,
+		Security: qifField(fields, 'Y'),
+		Transfer: strings.HasSuffix(action, "x"),
+		Memo:     qifField(fields, 'M'),
+	}
+	if investment.Security == "" {
+		return nil, nil, errors.New("missing security")
+	}
+	numbers := []struct {
+		code   byte
+		target *float64
+	}{
+		{'Q', &investment.Quantity},
+		{'I', &investment.Price},
+		{'O', &investment.Fees},
+	}
+	for _, n := range numbers {
+		if value := qifField(fields, n.code); value != "" {
+			if *n.target, err = qifNumber(value); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if amountField != "" {
+		if investment.Amount, err = qifNumber(amountField); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Splits give the new shares per ten old shares as the quantity
+	if mapped == models.InvestmentTypeSplit {
+		investment.SplitRatio = investment.Quantity / 10
+		investment.Quantity = 0
+	}
+	return nil, investment, nil
+}
+
+// qifField returns the value of the first field of a record with the given code
+func qifField(fields []string, code byte) string {
+	for _, field := range fields {
+		if field[0] == code {
+			return strings.TrimSpace(field[1:])
+		}
+	}
+	return ""
+}
+
+// qifHasField reports whether a record has a field with the given code
+func qifHasField(fields []string, code byte) bool {
+	for _, field := range fields {
+		if field[0] == code {
+			return true
+		}
+	}
+	return false
+}
+
+// qifCategory splits a QIF category field into the category path and the
+// class, which becomes a tag. Transfers name the other account in brackets
+// and get the transfer category.
+func qifCategory(value string) (string, []string) {
+	category, class, _ := strings.Cut(value, "/")
+	category = strings.TrimSpace(category)
+	if strings.HasPrefix(category, "[") && strings.HasSuffix(category, "]") {
+		category = models.TransferCategory
+	}
+
+	var tags []string
+	if class = strings.TrimSpace(class); class != "" {
+		tags = []string{class}
+	}
+	return category, tags
+}
+
+// qifAmount normalizes a QIF amount to a decimal without thousands separators
+func qifAmount(value string) string {
+	return strings.NewReplacer(",", "", " ", "", "$", "").Replace(value)
+}
+
+// qifNumber parses a QIF quantity, price or amount
+func qifNumber(value string) (float64, error) {
+	number, err := strconv.ParseFloat(qifAmount(value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number: %s", value)
+	}
+	return number, nil
+}
+
+// parseQIFDate parses a QIF date such as 01/15/2024, 1/15/24, 1/15'24 or
+// 2024-01-15. Two-digit years after an apostrophe are in the 2000s; others
+// below 50 are too.
+func parseQIFDate(value string, dayFirst bool) (time.Time, error) {
+	raw := value
+	if value == "" {
+		return time.Time{}, errors.New("missing date")
+	}
+
+	value = strings.ReplaceAll(value, " ", "")
+	century := 0
+	if strings.Contains(value, "'") {
+		century = 2000
+		value = strings.ReplaceAll(value, "'", "/")
+	}
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == '/' || r == '-' || r == '.' })
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid date: %s", raw)
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date: %s", raw)
+		}
+		numbers[i] = n
+	}
+
+	var year, month, day int
+	switch {
+	case len(parts[0]) == 4:
+		year, month, day = numbers[0], numbers[1], numbers[2]
+	case dayFirst:
+		day, month, year = numbers[0], numbers[1], numbers[2]
+	default:
+		month, day, year = numbers[0], numbers[1], numbers[2]
+	}
+	if year < 100 {
+		switch {
+		case century != 0:
+			year += century
+		case year < 50:
+			year += 2000
+		default:
+			year += 1900
+		}
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Month() != time.Month(month) || date.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date: %s", raw)
+	}
+	return date, nil
+}
+
+// qifSectionTypes maps account types to QIF section types; accounts of
+// other types are bank accounts
+var qifSectionTypes = map[string]string{
+	"cash":                       "Cash",
+	models.AccountTypeCredit:     "CCard",
+	models.AccountTypeInvestment: "Invst",
+	models.AccountTypeLoan:       "Oth L",
+	"other":                      "Oth A",
+}
+
+// qifTradeActions maps investment transaction types to QIF actions
+var qifTradeActions = map[string]string{
+	models.InvestmentTypeBuy:      "Buy",
+	models.InvestmentTypeSell:     "Sell",
+	models.InvestmentTypeDividend: "Div",
+	models.InvestmentTypeSplit:    "StkSplit",
+}
+
+// qifSectionType returns the QIF section type for an account type
+func qifSectionType(accountType string) string {
+	if section, ok := qifSectionTypes[accountType]; ok {
+		return section
+	}
+	return "Bank"
+}
+
+// qifWriter writes the records of a QIF file
+type qifWriter struct {
+	buf bytes.Buffer
+}
+
+// field writes a field of the current record; empty values are left out
+func (w *qifWriter) field(code byte, value string) {
+	if value == "" {
+		return
+	}
+	// Values are single lines
+	value = strings.Join(strings.Fields(value), " ")
+	w.buf.WriteByte(code)
+	w.buf.WriteString(value)
+	w.buf.WriteByte('\n')
+}
+
+// end ends the current record
+func (w *qifWriter) end() {
+	w.buf.WriteString("^\n")
+}
+
+// categories writes the category list of the given category names, keyed
+// to whether they are income categories, with their paths
+func (w *qifWriter) categories(names map[string]bool, paths map[string]string) {
+	if len(names) == 0 {
+		return
+	}
+
+	// Parents come before their subcategories
+	income := make(map[string]bool)
+	for name, isIncome := range names {
+		path := paths[name]
+		if path == "" {
+			path = name
+		}
+		parts := strings.Split(path, ":")
+		for i := range parts {
+			parent := strings.Join(parts[:i+1], ":")
+			income[parent] = income[parent] || isIncome
+		}
+	}
+	sorted := make([]string, 0, len(income))
+	for path := range income {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	w.buf.WriteString("!Type:Cat\n")
+	for _, path := range sorted {
+		w.field('N', path)
+		if income[path] {
+			w.buf.WriteString("I\n")
+		} else {
+			w.buf.WriteString("E\n")
+		}
+		w.end()
+	}
+}
+
+// securities writes the security list
+func (w *qifWriter) securities(securities map[uint]*models.Security) {
+	if len(securities) == 0 {
+		return
+	}
+	sorted := make([]*models.Security, 0, len(securities))
+	for _, security := range securities {
+		sorted = append(sorted, security)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Symbol < sorted[j].Symbol })
+
+	for _, security := range sorted {
+		w.buf.WriteString("!Type:Security\n")
+		w.field('N', security.Name)
+		w.field('S', security.Symbol)
+		w.end()
+	}
+}
+
+// account starts the section of an account
+func (w *qifWriter) account(account *models.Account, section string) {
+	w.buf.WriteString("!Account\n")
+	w.field('N', account.Name)
+	w.field('T', section)
+	w.end()
+	w.buf.WriteString("!Type:" + section + "\n")
+}
+
+// transaction writes a bank, cash, credit card or other account transaction
+func (w *qifWriter) transaction(t *models.Transaction, paths map[string]string) {
+	amount := t.Amount
+	if t.Type == "expense" {
+		amount = -amount
+	}
+
+	w.field('D', t.Date.Format("01/02/2006"))
+	w.field('T', amount.Format(t.Currency))
+	w.payee(t)
+	switch {
+	case t.ReconciledAt != nil:
+		w.field('C', "X")
+	case t.Status == models.TransactionStatusCleared:
+		w.field('C', "*")
+	}
+	if !t.IsSplit() {
+		w.field('L', qifCategoryField(t.Category, paths, models.TagNames(t.Tags)))
+	}
+	for _, split := range t.Splits {
+		lineAmount := split.Amount
+		if t.Type == "expense" {
+			lineAmount = -lineAmount
+		}
+		w.field('S', qifCategoryField(split.Category, paths, nil))
+		w.field('E', split.Description)
+		w.field('$', lineAmount.Format(t.Currency))
+	}
+	w.end()
+}
+
+// investmentCash writes a cash transaction of an investment account: a
+// transfer in or out, or miscellaneous income or expense
+func (w *qifWriter) investmentCash(t *models.Transaction, paths map[string]string) {
+	action := "MiscExp"
+	switch {
+	case t.Category == models.TransferCategory && t.Type == "income":
+		action = "XIn"
+	case t.Category == models.TransferCategory:
+		action = "XOut"
+	case t.Type == "income":
+		action = "MiscInc"
+	}
+
+	w.field('D', t.Date.Format("01/02/2006"))
+	w.field('N', action)
+	w.field('T', t.Amount.Format(t.Currency))
+	w.payee(t)
+	if t.Category != models.TransferCategory {
+		w.field('L', qifCategoryField(t.Category, paths, models.TagNames(t.Tags)))
+	}
+	w.end()
+}
+
+// trade writes a buy, sell, dividend or split of an investment account
+func (w *qifWriter) trade(t *models.InvestmentTransaction) {
+	w.field('D', t.Date.Format("01/02/2006"))
+	w.field('N', qifTradeActions[t.Type])
+	if t.Security != nil {
+		w.field('Y', t.Security.Name)
+	}
+
+	quantity := t.Quantity
+	if quantity < 0 {
+		quantity = -quantity
+	}
+	switch t.Type {
+	case models.InvestmentTypeBuy, models.InvestmentTypeSell:
+		// The amount is the cash paid or received after fees
+		total := t.Amount + t.Fees
+		if t.Type == models.InvestmentTypeSell {
+			total = t.Amount - t.Fees
+		}
+		w.field('I', strconv.FormatFloat(t.Price, 'f', -1, 64))
+		w.field('Q', quantity.String())
+		w.field('T', total.Format(t.Currency))
+		if t.Fees != 0 {
+			w.field('O', t.Fees.Format(t.Currency))
+		}
+	case models.InvestmentTypeDividend:
+		w.field('T', t.Amount.Format(t.Currency))
+	case models.InvestmentTypeSplit:
+		// Splits give the new shares per ten old shares
+		w.field('Q', strconv.FormatFloat(t.SplitRatio*10, 'f', -1, 64))
+	}
+	w.field('M', t.Notes)
+	w.end()
+}
+
+// payee writes the payee and memo of a transaction; the description is the
+// memo unless it is just the payee name
+func (w *qifWriter) payee(t *models.Transaction) {
+	payee := t.Description
+	if t.Payee != nil {
+		payee = t.Payee.Name
+	}
+	w.field('P', payee)
+	if t.Description != payee {
+		w.field('M', t.Description)
+	}
+}
+
+// qifCategoryField formats a category and the first tag, as the class, for
+// an L or S field
+func qifCategoryField(category string, paths map[string]string, tags []string) string {
+	if path, ok := paths[category]; ok && category != models.TransferCategory {
+		category = path
+	}
+	if len(tags) > 0 {
+		category += "/" + tags[0]
+	}
+	return category
+}
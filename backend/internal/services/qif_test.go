@@ -0,0 +1,457 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocdaijr/finance-management-backend/internal/domain/models"
+	"github.com/quocdaijr/finance-management-backend/internal/infrastructure/database"
+	"github.com/quocdaijr/finance-management-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupImportTest opens a SQLite database in a temporary file and wires the
+// import and export services against it
+func setupImportTest(t *testing.T) (*gorm.DB, *ImportService, *ExportService) {
+	db, err := gorm.Open(sqlite.Open(database.SQLiteDSN(filepath.Join(t.TempDir(), "test.db"))), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	err = db.AutoMigrate(
+		&models.User{}, &models.Account{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Comment{},
+		&models.JournalEntry{}, &models.Posting{}, &models.BalanceHistory{}, &models.Category{},
+		&models.Tag{}, &models.Payee{}, &models.PayeeAlias{}, &models.Rule{}, &models.TaxCategory{},
+		&models.DuplicateDismissal{}, &models.ImportProfile{}, &models.Attachment{}, &models.Budget{},
+		&models.Security{}, &models.InvestmentTransaction{},
+	)
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	transactionRepo := repository.NewTransactionRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	importService := NewImportService(transactionRepo, accountRepo, repository.NewTagRepository(db), repository.NewPayeeRepository(db),
+		repository.NewRuleRepository(db), categoryRepo, repository.NewImportProfileRepository(db), repository.NewLedgerRepository(db),
+		repository.NewBalanceHistoryRepository(db), nil, db)
+	exportService := NewExportService(transactionRepo, accountRepo, repository.NewBudgetRepository(db), repository.NewAttachmentRepository(db),
+		categoryRepo, repository.NewInvestmentRepository(db), nil)
+	return db, importService, exportService
+}
+
+// createImportTestUser creates a user to import for
+func createImportTestUser(t *testing.T, db *gorm.DB, username string) *models.User {
+	user := &models.User{Username: username, Email: username + "@example.com", Password: "hashedpassword"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	return user
+}
+
+func TestParseQIF(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		dayFirst bool
+		expected *QIFFile
+	}{
+		{
+			name: "bank section with splits, classes and transfers",
+			data: `!Type:Bank
+D01/05/2024
+T-1,234.50
+N1042
+PLandlord
+MJanuary rent
+LHousing:Rent/home
+C*
+^
+D1/6/24
+T-40.00
+PGrocer
+LFood
+SFood:Groceries
+EVeg
+$-30.00
+SFood:Coffee
+$-10.00
+^
+D01/08'24
+T-500.00
+PTransfer
+L[Savings]
+^
+`,
+			expected: &QIFFile{Statements: []BankStatement{{
+				Format:      "QIF",
+				AccountType: "checking",
+				Entries: []StatementEntry{
+					{Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Amount: "-1234.50", CheckNumber: "1042",
+						Payee: "Landlord", Memo: "January rent", Name: "January rent", Category: "Housing:Rent", Tags: []string{"home"}},
+					{Date: time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC), Amount: "-40.00", Payee: "Grocer", Name: "Grocer", Category: "Food",
+						Splits: []StatementSplit{
+							{Category: "Food:Groceries", Memo: "Veg", Amount: "-30.00"},
+							{Category: "Food:Coffee", Amount: "-10.00"},
+						}},
+					{Date: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), Amount: "-500.00", Payee: "Transfer", Name: "Transfer",
+						Category: models.TransferCategory},
+				},
+			}}},
+		},
+		{
+			name: "account list, category list and day first dates",
+			data: `!Type:Cat
+NFood
+E
+^
+NFood:Coffee
+E
+^
+NSalary
+I
+^
+!Option:AutoSwitch
+!Account
+NChecking
+TBank
+^
+NVisa
+TCCard
+^
+!Clear:AutoSwitch
+!Account
+NChecking
+TBank
+^
+!Type:Bank
+D31.01.2024
+T3,000.00
+PACME Corp
+LSalary
+^
+!Account
+NVisa
+TCCard
+^
+!Type:CCard
+D01/02/2024
+T-12.99
+PNetflix
+LEntertainment
+^
+`,
+			dayFirst: true,
+			expected: &QIFFile{
+				Statements: []BankStatement{
+					{Format: "QIF", AccountName: "Checking", AccountType: "checking", Entries: []StatementEntry{
+						{Date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), Amount: "3000.00", Payee: "ACME Corp", Name: "ACME Corp", Category: "Salary"},
+					}},
+					{Format: "QIF", AccountName: "Visa", AccountType: "credit", Entries: []StatementEntry{
+						{Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Amount: "-12.99", Payee: "Netflix", Name: "Netflix", Category: "Entertainment"},
+					}},
+				},
+				Categories: []QIFCategory{{Path: "Food"}, {Path: "Food:Coffee"}, {Path: "Salary", Income: true}},
+			},
+		},
+		{
+			name: "investment account",
+			data: `!Type:Security
+NApple Inc
+SAAPL
+TStock
+^
+!Account
+NBrokerage
+TInvst
+^
+!Type:Invst
+D1/2'24
+NXIn
+T1,000.00
+PFrom checking
+^
+D1/3'24
+NBuyX
+YApple Inc
+I185.5
+Q5
+T932.50
+O5.00
+L[Checking]
+^
+D2/15'24
+NDiv
+YApple Inc
+T1.20
+^
+D2/16'24
+NReinvDiv
+YApple Inc
+I180
+Q0.01
+T1.80
+^
+D3/1'24
+NStkSplit
+YApple Inc
+Q20
+^
+D3/5'24
+NSell
+YApple Inc
+I190
+Q2
+T375
+O5
+^
+D3/31'24
+NMiscExp
+T2.50
+MAccount fee
+LBank Charges
+^
+`,
+			expected: &QIFFile{Statements: []BankStatement{{
+				Format:      "QIF",
+				AccountName: "Brokerage",
+				AccountType: models.AccountTypeInvestment,
+				Entries: []StatementEntry{
+					{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: "1000.00", Payee: "From checking", Name: "From checking",
+						Category: models.TransferCategory},
+					{Date: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), Amount: "-2.50", Memo: "Account fee", Name: "Account fee",
+						Category: "Bank Charges"},
+				},
+				Investments: []InvestmentEntry{
+					{Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), Action: models.InvestmentTypeBuy, Security: "Apple Inc", Symbol: "AAPL",
+						Quantity: 5, Price: 185.5, Amount: 932.5, Fees: 5, Transfer: true},
+					{Date: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), Action: models.InvestmentTypeDividend, Security: "Apple Inc", Symbol: "AAPL",
+						Amount: 1.2},
+					{Date: time.Date(2024, 2, 16, 0, 0, 0, 0, time.UTC), Action: investmentActionReinvest, Security: "Apple Inc", Symbol: "AAPL",
+						Quantity: 0.01, Price: 180, Amount: 1.8},
+					{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Action: models.InvestmentTypeSplit, Security: "Apple Inc", Symbol: "AAPL",
+						SplitRatio: 2},
+					{Date: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), Action: models.InvestmentTypeSell, Security: "Apple Inc", Symbol: "AAPL",
+						Quantity: 2, Price: 190, Amount: 375, Fees: 5},
+				},
+			}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := ParseQIF(strings.NewReader(tt.data), tt.dayFirst)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, file)
+		})
+	}
+}
+
+func TestParseQIF_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "missing amount", data: "!Type:Bank\nD01/05/2024\nPShop\n^\n", err: "line 4: missing amount"},
+		{name: "missing date", data: "!Type:Bank\nT-1.00\n^\n", err: "line 3: missing date"},
+		{name: "invalid date", data: "!Type:Bank\nD13/45/2024\nT-1.00\n^\n", err: "line 4: invalid date: 13/45/2024"},
+		{name: "unsupported action", data: "!Type:Invst\nD01/05/2024\nNShtSell\nYApple Inc\n^\n",
+			err: "line 5: unsupported investment action: ShtSell"},
+		{name: "missing security", data: "!Type:Invst\nD01/05/2024\nNBuy\nQ1\n^\n", err: "line 5: missing security"},
+		{name: "no transactions", data: "!Type:Bank\n", err: "no transactions found in QIF file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQIF(strings.NewReader(tt.data), false)
+
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestParseQIFDate(t *testing.T) {
+	tests := []struct {
+		value    string
+		dayFirst bool
+		expected time.Time
+	}{
+		{value: "01/15/2024", expected: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "1/15/24", expected: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "1/15/98", expected: time.Date(1998, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "1/15'24", expected: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "1/15' 4", expected: time.Date(2004, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-01-15", expected: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "15.01.2024", dayFirst: true, expected: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "15/01/99", dayFirst: true, expected: time.Date(1999, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-01-15", dayFirst: true, expected: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			date, err := parseQIFDate(tt.value, tt.dayFirst)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, date)
+		})
+	}
+
+	for _, value := range []string{"", "01/15", "2/30/2024", "15/01/2024", "Jan 15 2024"} {
+		_, err := parseQIFDate(value, false)
+		assert.Error(t, err, value)
+	}
+}
+
+// qifRoundTrip is a QIF file with a category list, a checking account with
+// a class and a split, and a credit card
+const qifRoundTrip = `!Type:Cat
+NFood
+E
+^
+NFood:Coffee
+E
+^
+NFood:Groceries
+E
+^
+NHousing
+E
+^
+NSalary
+I
+^
+!Account
+NChecking
+TBank
+^
+!Type:Bank
+D01/05/2024
+T-1,234.50
+PLandlord
+MJanuary rent
+LHousing/home
+^
+D01/06/2024
+T-40.00
+PGrocer
+SFood:Groceries
+EVeg
+$-30.00
+SFood:Coffee
+$-10.00
+^
+D01/31/2024
+T3,000.00
+PACME Corp
+LSalary
+^
+!Account
+NVisa
+TCCard
+^
+!Type:CCard
+D02/01/2024
+T-12.99
+PCorner Cafe
+LFood:Coffee
+^
+`
+
+func TestQIFExportImportRoundTrip(t *testing.T) {
+	// Setup - transactions imported from a QIF file
+	db, importService, exportService := setupImportTest(t)
+	user := createImportTestUser(t, db, "original")
+	copyUser := createImportTestUser(t, db, "copy")
+
+	file, err := ParseQIF(strings.NewReader(qifRoundTrip), false)
+	assert.NoError(t, err)
+	results, err := importService.ImportQIF(user.ID, file, ImportOptions{CreateAccounts: true})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, 3, results[0].Imported)
+	assert.Equal(t, 1, results[1].Imported)
+
+	// Execute - export them and import the export for another user
+	exported, err := exportService.ExportTransactionsQIF(user.ID, nil, nil)
+	assert.NoError(t, err)
+
+	file, err = ParseQIF(strings.NewReader(string(exported)), false)
+	assert.NoError(t, err)
+	results, err = importService.ImportQIF(copyUser.ID, file, ImportOptions{CreateAccounts: true})
+	assert.NoError(t, err)
+
+	// Assert - the copy has the same accounts, categories, splits and classes
+	for _, result := range results {
+		assert.Empty(t, result.Errors, result.AccountName)
+		assert.True(t, result.AccountCreated, result.AccountName)
+	}
+	assert.Contains(t, string(exported), "!Type:Cat\nNFood\nE\n^\n")
+	assert.Contains(t, string(exported), "LHousing/home\n")
+	assert.Contains(t, string(exported), "SFood:Groceries\nEVeg\n$-30.00\n")
+
+	reexported, err := exportService.ExportTransactionsQIF(copyUser.ID, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, string(exported), string(reexported))
+
+	var count int64
+	db.Model(&models.Transaction{}).Where("user_id = ?", copyUser.ID).Count(&count)
+	assert.Equal(t, int64(4), count)
+}
+
+func TestImportQIF_Accounts(t *testing.T) {
+	// Setup - a checking account named like the QIF account and a card to map the other one to
+	db, importService, _ := setupImportTest(t)
+	user := createImportTestUser(t, db, "qif")
+	checking := &models.Account{UserID: user.ID, Name: "checking", Type: "checking", Currency: "USD"}
+	card := &models.Account{UserID: user.ID, Name: "Rewards card", Type: "credit", Currency: "USD"}
+	assert.NoError(t, db.Create(checking).Error)
+	assert.NoError(t, db.Create(card).Error)
+
+	file, err := ParseQIF(strings.NewReader(qifRoundTrip), false)
+	assert.NoError(t, err)
+
+	// Execute
+	results, err := importService.ImportQIF(user.ID, file, ImportOptions{AccountMap: map[string]uint{"Visa": card.ID}})
+
+	// Assert - QIF accounts match by name, ignoring case, or as mapped
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, checking.ID, *results[0].AccountID)
+	assert.False(t, results[0].AccountCreated)
+	assert.Equal(t, 3, results[0].Imported)
+	assert.Equal(t, card.ID, *results[1].AccountID)
+	assert.Equal(t, 1, results[1].Imported)
+
+	var updated models.Account
+	db.First(&updated, checking.ID)
+	assert.Equal(t, models.NewMoney(1725.50, "USD"), updated.Balance)
+
+	// Split lines go to subcategories under their parent category
+	var split models.Transaction
+	assert.NoError(t, db.Preload("Splits").Where("account_id = ? AND category = ?", checking.ID, models.SplitCategory).First(&split).Error)
+	assert.Len(t, split.Splits, 2)
+	assert.Equal(t, "Groceries", split.Splits[0].Category)
+	assert.Equal(t, "Veg", split.Splits[0].Description)
+	assert.Equal(t, models.NewMoney(30.0, "USD"), split.Splits[0].Amount)
+
+	var food, groceries models.Category
+	assert.NoError(t, db.Where("user_id = ? AND name = ?", user.ID, "Food").First(&food).Error)
+	assert.NoError(t, db.Where("user_id = ? AND name = ?", user.ID, "Groceries").First(&groceries).Error)
+	assert.Equal(t, food.ID, *groceries.ParentID)
+
+	// QIF has no bank IDs, so importing the file again skips its entries as probable duplicates
+	results, err = importService.ImportQIF(user.ID, file, ImportOptions{AccountMap: map[string]uint{"Visa": card.ID}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, results[0].Imported)
+	assert.Len(t, results[0].Duplicates, 3)
+	assert.Equal(t, 0, results[1].Imported)
+
+	var count int64
+	db.Model(&models.Transaction{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(4), count)
+}
@@ -0,0 +1,67 @@
+package services
+
+import "time"
+
+// BankStatement is the statement of one bank account parsed from a file
+// downloaded from the bank
+type BankStatement struct {
+	Format         string    // File format the statement was read from, e.g. OFX
+	BankID         string    // Bank or routing number or BIC, when given
+	AccountNumber  string    // Account number at the bank, e.g. the IBAN
+	AccountName    string    // Name of the account in the file, e.g. the QIF account name
+	AccountType    string    // Account type as used by accounts, e.g. checking
+	Currency       string    // ISO 4217 code; empty when the file does not say
+	LedgerBalance  string    // Closing balance reported by the bank as a decimal; empty when not given
+	BalanceDate    time.Time // Date LedgerBalance was reported for
+	OpeningBalance string    // Balance at the start of the statement as a decimal; empty when not given
+	OpeningDate    time.Time // Date OpeningBalance was reported for
+	Entries        []StatementEntry
+	Investments    []InvestmentEntry
+}
+
+// Key returns what identifies the account of a statement in an import: the
+// account number, else the account name
+func (s *BankStatement) Key() string {
+	if s.AccountNumber != "" {
+		return s.AccountNumber
+	}
+	return s.AccountName
+}
+
+// StatementEntry is a booked entry of a bank statement
+type StatementEntry struct {
+	ExternalID  string // ID the bank gave the entry, e.g. the OFX FITID
+	Date        time.Time
+	Amount      string // Signed decimal; negative amounts leave the account
+	Name        string // Description, usually the payee or counterparty
+	Memo        string
+	Payee       string // Payee name when the file gives one apart from Name
+	CheckNumber string
+	Category    string   // Category path with levels separated by colons, e.g. Food:Coffee
+	Tags        []string // Tags, e.g. the QIF class
+	Splits      []StatementSplit
+}
+
+// StatementSplit is a category line of a split statement entry
+type StatementSplit struct {
+	Category string // Category path as in StatementEntry
+	Memo     string
+	Amount   string // Signed decimal like the entry amount
+}
+
+// InvestmentEntry is a trade or distribution of a security in an
+// investment account statement. Cash moving in and out of the account
+// without a security is a StatementEntry instead.
+type InvestmentEntry struct {
+	Date       time.Time
+	Action     string // buy, sell, dividend, reinvest or split
+	Security   string // Security name
+	Symbol     string // Security symbol, when known
+	Quantity   float64
+	Price      float64
+	Amount     float64 // Total cash paid, received or distributed
+	Fees       float64
+	SplitRatio float64 // New shares per old share of a split
+	Transfer   bool    // Whether the cash comes from or goes to another account
+	Memo       string
+}